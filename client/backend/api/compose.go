@@ -139,12 +139,7 @@ func removeDotenvEnvFileRefsFromCompose(composeContent string) (string, error) {
 }
 
 func dockerComposeCmd(projectDir string, args ...string) *exec.Cmd {
-	base := []string{"compose"}
-	envPath := filepath.Join(projectDir, ".env")
-	if _, err := os.Stat(envPath); err == nil {
-		base = append(base, "--env-file", envPath)
-	}
-	base = append(base, args...)
+	base := withComposeProjectArgs(projectDir, append([]string{"compose"}, args...))
 	cmd := exec.Command("docker", base...)
 	cmd.Dir = projectDir
 	return cmd
@@ -634,6 +629,9 @@ type ComposeProject struct {
 	UpdateCount     int       `json:"updateCount"`
 	CreateTime      time.Time `json:"createTime"`
 	IsSelf          bool      `json:"isSelf"`
+
+	// Git 来源项目的仓库与已部署提交
+	Git *gitStackSummary `json:"git,omitempty"`
 }
 
 func setSSEHeaders(c *gin.Context) {
@@ -766,7 +764,7 @@ func withComposeEnvFile(projectDir string, args []string) []string {
 
 func runComposeStreamLines(ctx context.Context, projectDir string, args []string, onLine func(string)) error {
 	env := []string{"COMPOSE_PROGRESS=plain", "COMPOSE_NO_COLOR=1"}
-	return runCommandStreamLines(ctx, projectDir, env, withComposeEnvFile(projectDir, args), onLine)
+	return runCommandStreamLines(ctx, projectDir, env, withComposeProjectArgs(projectDir, args), onLine)
 }
 
 // upsertDotenvKeyValue 在 dotenv 文本中更新/插入 KEY=VALUE（尽量保留原注释与格式）
@@ -805,6 +803,11 @@ func upsertDotenvKeyValue(dotenvText string, key string, val string) string {
 
 // findComposeFile 在项目目录中查找可用的 compose 配置文件
 func findComposeFile(projectDir string) (string, error) {
+	// 项目配置了多个 compose 文件时以第一个（基础文件）为准
+	if path, ok, err := configuredComposeFile(projectDir); ok {
+		return path, err
+	}
+
 	// 优先匹配常见的 docker compose 文件名
 	candidates := []string{
 		"docker-compose.yaml",
@@ -853,6 +856,13 @@ func RegisterComposeRoutes(r *gin.RouterGroup) {
 		group.GET("/list", listProjects)
 		group.GET("/deploy/events", deployEvents)
 		group.POST("/deploy", deployComposeTask)
+		group.GET("/graph", getComposeGraph)
+		group.POST("/graph/:action", runComposeGraphAction)
+		group.POST("/validate", validateCompose)
+		group.GET("/git", listGitStacks)
+		group.POST("/git", createGitStack)
+		group.POST("/from-containers/preview", previewComposeFromContainers)
+		group.POST("/from-containers", createComposeFromContainers)
 		group.GET("/tasks", listComposeTasks)
 		group.GET("/tasks/:id", getComposeTask)
 		group.GET("/tasks/:id/events", composeTaskEvents)
@@ -873,6 +883,27 @@ func RegisterComposeRoutes(r *gin.RouterGroup) {
 		group.GET("/:name/env", getProjectEnv)       // 添加获取 .env 路由
 		group.POST("/:name/yaml", saveProjectYaml)   // 添加保存 YAML 路由
 		group.POST("/:name/env", saveProjectEnv)     // 添加保存 .env 路由
+		group.POST("/:name/plan", planCompose)
+		group.GET("/:name/options", getComposeOptions)
+		group.PUT("/:name/options", updateComposeOptions)
+		group.PUT("/:name/dependencies", updateComposeDependencies)
+		group.GET("/:name/logs/merged", getComposeMergedLogs)
+		group.GET("/:name/logs/export", exportComposeLogs)
+		group.GET("/:name/versions", listComposeVersions)
+		group.GET("/:name/versions/:version", getComposeVersion)
+		group.GET("/:name/versions/:version/diff", diffComposeVersion)
+		group.POST("/:name/versions/:version/rollback", rollbackComposeVersion)
+		group.GET("/:name/git", getGitStack)
+		group.PUT("/:name/git", updateGitStack)
+		group.DELETE("/:name/git", detachGitStack)
+		group.POST("/:name/git/sync", syncGitStackNow)
+		group.GET("/:name/webhooks", listComposeWebhooks)
+		group.POST("/:name/webhooks", createComposeWebhook)
+		group.PUT("/:name/webhooks/:id", updateComposeWebhook)
+		group.DELETE("/:name/webhooks/:id", deleteComposeWebhook)
+		group.POST("/:name/sbom", generateProjectSBOM)
+		group.GET("/:name/sbom", listProjectSBOM)
+		group.GET("/:name/sbom/:file", downloadProjectSBOM)
 	}
 }
 
@@ -882,6 +913,7 @@ type composeDeployRequest struct {
 	Dotenv    string `json:"dotenv"`
 	Env       string `json:"env"`
 	AutoStart *bool  `json:"autoStart"`
+	Force     bool   `json:"force"` // 忽略校验错误强制部署
 }
 
 func deployComposeTask(c *gin.Context) {
@@ -907,6 +939,22 @@ func deployComposeTask(c *gin.Context) {
 		return
 	}
 
+	// 部署前校验：此时项目目录尚未创建，变量取自请求中的 dotenv 与 env
+	lintEnv := parseDotenvToMap(strings.ReplaceAll(req.Dotenv, "\r\n", "\n"))
+	if strings.TrimSpace(req.Env) != "" {
+		var envMap map[string]string
+		if json.Unmarshal([]byte(req.Env), &envMap) == nil {
+			for k, v := range envMap {
+				lintEnv[k] = v
+			}
+		}
+	}
+	diags := lintCompose(req.Compose, composeLintOptionsForProject(c.Request.Context(), projectName, "", lintEnv))
+	if composeDiagnosticsHaveErrors(diags) && !req.Force {
+		respondComposeDiagnostics(c, diags)
+		return
+	}
+
 	autoStart := true
 	if req.AutoStart != nil {
 		autoStart = *req.AutoStart
@@ -915,15 +963,16 @@ func deployComposeTask(c *gin.Context) {
 	taskID := fmt.Sprintf("%d", time.Now().UnixNano())
 	_ = database.UpsertTask(taskID, "compose_deploy", "pending")
 
-	go runComposeDeployTask(taskID, projectName, req.Compose, req.Dotenv, req.Env, autoStart)
+	go runComposeDeployTask(taskID, projectName, req.Compose, req.Dotenv, req.Env, autoStart, c.GetString("username"))
 
 	c.JSON(http.StatusOK, gin.H{
-		"message": "部署任务已提交",
-		"taskId":  taskID,
+		"message":     "部署任务已提交",
+		"taskId":      taskID,
+		"diagnostics": diags,
 	})
 }
 
-func runComposeDeployTask(taskID string, projectName string, compose string, dotenvRaw string, envRaw string, autoStart bool) {
+func runComposeDeployTask(taskID string, projectName string, compose string, dotenvRaw string, envRaw string, autoStart bool, author string) {
 	seq := int64(0)
 	appendLog := func(logType string, message string) {
 		seq++
@@ -1027,6 +1076,7 @@ func runComposeDeployTask(taskID string, projectName string, compose string, dot
 	}
 
 	appendLog("success", "配置已保存")
+	recordComposeVersionAfterSave(projectName, "deploy", author, "初始部署")
 	if !autoStart {
 		finish("success", gin.H{"project": projectName, "autoStart": false}, "")
 		return
@@ -1231,7 +1281,7 @@ func startProject(c *gin.Context) {
 	// 异步执行启动命令
 	go func() {
 		// 使用 docker compose up 命令启动项目
-		args := withComposeEnvFile(projectDir, []string{"compose", "up", "-d"})
+		args := withComposeProjectArgs(projectDir, []string{"compose", "up", "-d"})
 		cmd := exec.Command("docker", args...)
 		cmd.Dir = projectDir
 
@@ -1258,7 +1308,7 @@ func stopProject(c *gin.Context) {
 	// 异步执行停止命令
 	go func() {
 		// 使用 docker compose stop 命令停止项目，添加 -t 2 缩短超时
-		args := withComposeEnvFile(projectDir, []string{"compose", "stop", "-t", "2"})
+		args := withComposeProjectArgs(projectDir, []string{"compose", "stop", "-t", "2"})
 		cmd := exec.Command("docker", args...)
 		cmd.Dir = projectDir
 
@@ -1285,7 +1335,7 @@ func restartProject(c *gin.Context) {
 	// 异步执行重启命令
 	go func() {
 		// 使用 docker compose restart 命令重启项目，添加 -t 2 缩短超时
-		args := withComposeEnvFile(projectDir, []string{"compose", "restart", "-t", "2"})
+		args := withComposeProjectArgs(projectDir, []string{"compose", "restart", "-t", "2"})
 		cmd := exec.Command("docker", args...)
 		cmd.Dir = projectDir
 
@@ -1600,24 +1650,13 @@ func updateProjectEvents(c *gin.Context) {
 			}
 		}
 
-		if _, err := os.Stat(projectDir); err != nil {
-			send("error: 项目目录不存在")
-			return
-		}
-
-		send("info: 开始拉取最新镜像...")
-		if err := runComposeStreamLines(ctx, projectDir, []string{"compose", "pull"}, send); err != nil {
-			send(fmt.Sprintf("error: 拉取镜像失败: %s", err.Error()))
-			return
-		}
-
-		send("info: 开始重建并启动服务...")
-		if err := runComposeStreamLines(ctx, projectDir, []string{"compose", "up", "-d", "--remove-orphans"}, send); err != nil {
-			send(fmt.Sprintf("error: 启动失败: %s", err.Error()))
-			return
-		}
-
-		send("success: 项目更新完成")
+		_ = runComposeUpdate(ctx, projectDir, nil, func(level, msg string) {
+			if level == "" {
+				send(msg)
+				return
+			}
+			send(level + ": " + msg)
+		})
 	}()
 
 	// 发送事件
@@ -1636,6 +1675,31 @@ func updateProjectEvents(c *gin.Context) {
 	})
 }
 
+// runComposeUpdate 拉取最新镜像并重建启动服务，services 为空时处理全部服务。
+// logf 的 level 为空表示 compose 命令的原始输出。
+func runComposeUpdate(ctx context.Context, projectDir string, services []string, logf func(level, msg string)) error {
+	if _, err := os.Stat(projectDir); err != nil {
+		logf("error", "项目目录不存在")
+		return err
+	}
+	output := func(line string) { logf("", line) }
+
+	logf("info", "开始拉取最新镜像...")
+	if err := runComposeStreamLines(ctx, projectDir, append([]string{"compose", "pull"}, services...), output); err != nil {
+		logf("error", fmt.Sprintf("拉取镜像失败: %s", err.Error()))
+		return err
+	}
+
+	logf("info", "开始重建并启动服务...")
+	if err := runComposeStreamLines(ctx, projectDir, append([]string{"compose", "up", "-d", "--remove-orphans"}, services...), output); err != nil {
+		logf("error", fmt.Sprintf("启动失败: %s", err.Error()))
+		return err
+	}
+
+	logf("success", "项目更新完成")
+	return nil
+}
+
 // buildProject 构建项目
 func buildProject(c *gin.Context) {
 	name, ok := validateComposeProjectName(c.Param("name"))
@@ -1650,7 +1714,7 @@ func buildProject(c *gin.Context) {
 
 	// 使用 docker compose build 命令构建项目
 	// 可以添加 --pull 选项确保拉取最新基础镜像，但这可能会慢
-	args := withComposeEnvFile(projectDir, []string{"compose", "build"})
+	args := withComposeProjectArgs(projectDir, []string{"compose", "build"})
 	cmd := exec.Command("docker", args...)
 	cmd.Dir = projectDir
 
@@ -1979,8 +2043,10 @@ func listProjects(c *gin.Context) {
 	result := make([]*ComposeProject, 0, len(projects))
 	// projectRoot := settings.GetProjectRoot() // 不再使用 projectRoot 进行相对路径计算，而是使用 CWD
 	projectRoot := getProjectsBaseDir()
+	gitStacks := loadGitStackSummaries()
 
 	for _, project := range projects {
+		project.Git = gitStacks[project.Name]
 		if composePath, err := findComposeFile(project.Path); err == nil {
 			if data, err := os.ReadFile(composePath); err == nil {
 				project.Compose = string(data)
@@ -2258,7 +2324,7 @@ func cleanProjectResources(name string) error {
 
 	// 1. 尝试使用 docker compose down 命令停止并删除容器
 	if _, err := os.Stat(projectDir); err == nil {
-		args := withComposeEnvFile(projectDir, []string{"compose", "down"})
+		args := withComposeProjectArgs(projectDir, []string{"compose", "down"})
 		cmd := exec.Command("docker", args...)
 		cmd.Dir = projectDir
 
@@ -2406,6 +2472,11 @@ func removeProject(c *gin.Context) {
 		respondError(c, http.StatusInternalServerError, "删除项目目录失败", err)
 		return
 	}
+	deleteComposeHistory(name)
+	_ = database.DeleteGitStack(name)
+	_ = database.DeleteComposeWebhooks(name)
+	_ = database.DeleteComposeProjectOptions(name)
+	_ = database.DeleteComposeDependencies(name)
 
 	c.JSON(http.StatusOK, gin.H{"message": "项目已删除"})
 }
@@ -2431,7 +2502,7 @@ func getComposeLogs(c *gin.Context) {
 
 	go func() {
 		defer close(lines)
-		_ = runComposeStreamLines(ctx, projectDir, []string{"compose", "logs", "-f", "--tail", "200"}, func(line string) {
+		_ = runComposeStreamLines(ctx, projectDir, []string{"compose", "logs", "-f", "--tail", normalizeLogTail(c.DefaultQuery("tail", logDefaultTail))}, func(line string) {
 			select {
 			case <-ctx.Done():
 				return
@@ -2533,6 +2604,7 @@ func saveProjectEnv(c *gin.Context) {
 
 	var data struct {
 		Content string `json:"content"`
+		Message string `json:"message"` // 版本说明
 	}
 	if err := c.BindJSON(&data); err != nil {
 		respondError(c, http.StatusBadRequest, "无效的请求数据", err)
@@ -2545,14 +2617,16 @@ func saveProjectEnv(c *gin.Context) {
 		return
 	}
 
+	ensureComposeBaseline(name)
 	envPath := filepath.Join(projectDir, ".env")
 	content := strings.ReplaceAll(data.Content, "\r\n", "\n")
 	if err := os.WriteFile(envPath, []byte(content), 0644); err != nil {
 		respondError(c, http.StatusInternalServerError, "保存 .env 文件失败", err)
 		return
 	}
+	version := recordComposeVersionAfterSave(name, "save_env", c.GetString("username"), data.Message)
 
-	c.JSON(http.StatusOK, gin.H{"message": ".env 已保存"})
+	c.JSON(http.StatusOK, gin.H{"message": ".env 已保存", "version": version})
 }
 
 // 添加保存 YAML 配置的处理函数
@@ -2571,6 +2645,8 @@ func saveProjectYaml(c *gin.Context) {
 	}
 	var data struct {
 		Content string `json:"content"`
+		Force   bool   `json:"force"`   // 忽略校验错误强制保存
+		Message string `json:"message"` // 版本说明
 	}
 
 	if err := c.BindJSON(&data); err != nil {
@@ -2579,6 +2655,12 @@ func saveProjectYaml(c *gin.Context) {
 	}
 
 	projectDir := filepath.Join(getProjectsBaseDir(), name)
+	diags := lintCompose(data.Content, composeLintOptionsForProject(c.Request.Context(), name, projectDir, readProjectDotenv(projectDir)))
+	diags = relaxCrossFileDiagnostics(diags, composeOptionsForDir(projectDir))
+	if composeDiagnosticsHaveErrors(diags) && !data.Force {
+		respondComposeDiagnostics(c, diags)
+		return
+	}
 	yamlPath, err := findComposeFile(projectDir)
 	if err != nil {
 		if os.IsNotExist(err) {
@@ -2591,12 +2673,14 @@ func saveProjectYaml(c *gin.Context) {
 	}
 
 	// 保存 YAML 文件
+	ensureComposeBaseline(name)
 	if err := os.WriteFile(yamlPath, []byte(data.Content), 0644); err != nil {
 		respondError(c, http.StatusInternalServerError, "保存配置文件失败", err)
 		return
 	}
+	version := recordComposeVersionAfterSave(name, "save_yaml", c.GetString("username"), data.Message)
 
-	c.JSON(http.StatusOK, gin.H{"message": "配置已保存"})
+	c.JSON(http.StatusOK, gin.H{"message": "配置已保存", "diagnostics": diags, "version": version})
 }
 
 // 移除底部重复的 RegisterComposeRoutes
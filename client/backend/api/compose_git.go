@@ -0,0 +1,672 @@
+package api
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"dockerpanel/backend/pkg/composehistory"
+	"dockerpanel/backend/pkg/database"
+	"dockerpanel/backend/pkg/gitsource"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	gitStackDefaultBranch   = "main"
+	gitStackDefaultInterval = 300
+	gitStackMinInterval     = 60
+	gitStackPollEvery       = 30 * time.Second
+	gitStackTimeout         = 10 * time.Minute
+)
+
+// errComposeProjectBusy 项目正在执行同步或部署
+var errComposeProjectBusy = errors.New("项目正在同步或部署中，请稍后再试")
+
+// composeProjectLocks 每个项目一把锁，同一项目目录上由后台发起的 git 与部署操作串行执行
+var composeProjectLocks sync.Map // project -> *sync.Mutex
+
+func composeProjectLock(project string) *sync.Mutex {
+	mu, _ := composeProjectLocks.LoadOrStore(project, &sync.Mutex{})
+	return mu.(*sync.Mutex)
+}
+
+func gitStackAuth(s database.GitStack) gitsource.Auth {
+	return gitsource.Auth{Username: s.Username, Password: s.Password}
+}
+
+func newWebhookToken() string {
+	var b [24]byte
+	_, _ = rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+// gitStackComposeCandidates 未指定 compose 路径时按顺序在仓库根目录查找
+var gitStackComposeCandidates = []string{"docker-compose.yaml", "docker-compose.yml", "compose.yaml", "compose.yml"}
+
+// validGitStackComposePath compose 文件为仓库内的相对路径，可位于子目录；不允许 ..、绝对路径与隐藏文件/目录
+func validGitStackComposePath(p string) bool {
+	ext := strings.ToLower(path.Ext(p))
+	if p == "" || strings.Contains(p, `\`) || path.IsAbs(p) || path.Clean(p) != p || (ext != ".yml" && ext != ".yaml") {
+		return false
+	}
+	for _, seg := range strings.Split(p, "/") {
+		if strings.HasPrefix(seg, ".") {
+			return false
+		}
+	}
+	return true
+}
+
+// ensureGitStackComposeOptions compose 文件位于仓库子目录时，将其设为项目的基础 compose 文件，
+// 之后的 compose 命令均以 -p 项目名 --project-directory 项目目录 -f 该文件执行
+func ensureGitStackComposeOptions(s database.GitStack, logf func(level, msg string)) error {
+	if !strings.Contains(s.ComposePath, "/") {
+		return nil
+	}
+	o, err := database.GetComposeProjectOptions(s.Project)
+	if err != nil {
+		return fmt.Errorf("读取项目参数失败: %w", err)
+	}
+	if len(o.ComposeFiles) > 0 && o.ComposeFiles[0] == s.ComposePath {
+		return nil
+	}
+	o.Project = s.Project
+	if len(o.ComposeFiles) == 0 {
+		o.ComposeFiles = []string{s.ComposePath}
+	} else {
+		o.ComposeFiles = append([]string{s.ComposePath}, o.ComposeFiles[1:]...)
+	}
+	if err := database.SaveComposeProjectOptions(&o); err != nil {
+		return fmt.Errorf("保存项目参数失败: %w", err)
+	}
+	logf("info", fmt.Sprintf("项目基础 compose 文件已设置为 %s", s.ComposePath))
+	return nil
+}
+
+// gitStackResponse 返回给前端的来源配置，不包含密码；Webhook 令牌只保存哈希，仅在生成时返回一次
+type gitStackResponse struct {
+	database.GitStack
+	HasPassword     bool   `json:"hasPassword"`
+	HasWebhookToken bool   `json:"hasWebhookToken"`
+	WebhookPath     string `json:"webhookPath"`
+	WebhookToken    string `json:"webhookToken,omitempty"`
+}
+
+func newGitStackResponse(s database.GitStack, token string) gitStackResponse {
+	return gitStackResponse{
+		GitStack:        s,
+		HasPassword:     s.Password != "",
+		HasWebhookToken: s.WebhookTokenHash != "",
+		WebhookPath:     "/api/hooks/git/" + s.Project,
+		WebhookToken:    token,
+	}
+}
+
+// gitStackSummary 项目列表中展示的 Git 来源信息
+type gitStackSummary struct {
+	RepoURL        string     `json:"repoUrl"`
+	Branch         string     `json:"branch"`
+	Commit         string     `json:"commit"`
+	DeployedAt     *time.Time `json:"deployedAt"`
+	LastSyncStatus string     `json:"lastSyncStatus"`
+	LastSyncError  string     `json:"lastSyncError"`
+}
+
+// loadGitStackSummaries 按项目名索引全部 Git 来源，读取失败时返回空表
+func loadGitStackSummaries() map[string]*gitStackSummary {
+	out := make(map[string]*gitStackSummary)
+	stacks, err := database.ListGitStacks()
+	if err != nil {
+		return out
+	}
+	for _, s := range stacks {
+		out[s.Project] = &gitStackSummary{
+			RepoURL:        s.RepoURL,
+			Branch:         s.Branch,
+			Commit:         s.DeployedCommit,
+			DeployedAt:     s.DeployedAt,
+			LastSyncStatus: s.LastSyncStatus,
+			LastSyncError:  s.LastSyncError,
+		}
+	}
+	return out
+}
+
+type gitStackRequest struct {
+	Name            string  `json:"name"`
+	RepoURL         string  `json:"repoUrl"`
+	Branch          string  `json:"branch"`
+	ComposePath     string  `json:"composePath"`
+	Username        string  `json:"username"`
+	Password        *string `json:"password"` // 更新时为 null 表示保留原密码
+	AutoSync        *bool   `json:"autoSync"`
+	SyncInterval    int     `json:"syncInterval"`
+	RegenerateToken bool    `json:"regenerateToken"`
+}
+
+// applyGitStackRequest 将请求合并到来源配置并校验，生成了新的 Webhook 令牌时返回其明文
+func applyGitStackRequest(s *database.GitStack, req gitStackRequest) (string, fieldErrors) {
+	var errs fieldErrors
+	s.RepoURL = strings.TrimSpace(req.RepoURL)
+	if s.RepoURL == "" {
+		errs.add("repoUrl", "仓库地址不能为空")
+	} else if err := gitsource.ValidateRepoURL(s.RepoURL); err != nil {
+		errs.add("repoUrl", "%s", err.Error())
+	}
+	s.Branch = strings.TrimSpace(req.Branch)
+	if s.Branch == "" {
+		s.Branch = gitStackDefaultBranch
+	}
+	if err := gitsource.ValidateBranch(s.Branch); err != nil {
+		errs.add("branch", "%s", err.Error())
+	}
+	s.ComposePath = strings.TrimPrefix(strings.TrimSpace(req.ComposePath), "./")
+	if s.ComposePath != "" && !validGitStackComposePath(s.ComposePath) {
+		errs.add("composePath", "compose 文件需为仓库内的 .yml/.yaml 相对路径，且不能包含 .. 或隐藏目录")
+	}
+	s.Username = strings.TrimSpace(req.Username)
+	if req.Password != nil {
+		s.Password = *req.Password
+	}
+	if req.AutoSync != nil {
+		s.AutoSync = *req.AutoSync
+	}
+	s.SyncInterval = req.SyncInterval
+	if s.SyncInterval == 0 {
+		s.SyncInterval = gitStackDefaultInterval
+	} else if s.SyncInterval < gitStackMinInterval {
+		errs.add("syncInterval", "同步间隔不能小于 %d 秒", gitStackMinInterval)
+	}
+	token := ""
+	if s.WebhookTokenHash == "" || req.RegenerateToken {
+		token = newWebhookToken()
+		s.WebhookTokenHash = hashWebhookToken(token)
+	}
+	return token, errs
+}
+
+// gitStackComposeAt 读取某次提交中的 compose 文件，返回文件名与内容
+func gitStackComposeAt(ctx context.Context, dir, rev, composePath string) (string, []byte, error) {
+	candidates := gitStackComposeCandidates
+	if composePath != "" {
+		candidates = []string{composePath}
+	}
+	for _, name := range candidates {
+		content, err := gitsource.Show(ctx, dir, rev, name)
+		if err == nil {
+			return name, content, nil
+		}
+		if !os.IsNotExist(err) {
+			return "", nil, err
+		}
+	}
+	return "", nil, fmt.Errorf("仓库中未找到 compose 文件（%s）", strings.Join(candidates, "、"))
+}
+
+func composeSnapshotHash(snap *composeSnapshot) string {
+	if snap == nil {
+		return ""
+	}
+	h := composehistory.Hash(snap.Compose) + ":" + composehistory.Hash(snap.Env) + ":" + snap.ComposeFile
+	extra := composeExtraHashes(snap.Extra)
+	paths := make([]string, 0, len(extra))
+	for rel := range extra {
+		paths = append(paths, rel)
+	}
+	sort.Strings(paths)
+	for _, rel := range paths {
+		h += ":" + rel + "=" + extra[rel]
+	}
+	return h
+}
+
+type gitSyncResult struct {
+	Commit   gitsource.Commit `json:"commit"`
+	Previous string           `json:"previous"`
+	Changed  bool             `json:"changed"`  // compose 或 .env 内容有变化
+	Deployed bool             `json:"deployed"` // 执行了 docker compose up
+}
+
+// syncGitStack 拉取远端分支；有新提交时先校验新的 compose，再检出并在 compose/.env 变化时重新部署。
+// force 为 true 时即使没有新提交也重新部署。调用方需持有项目锁。
+// 取得远端提交后出错时仍返回 res，其中的 Commit 即同步失败的提交。
+func syncGitStack(ctx context.Context, s database.GitStack, force bool, logf func(level, msg string)) (*gitSyncResult, error) {
+	dir := filepath.Join(getProjectsBaseDir(), s.Project)
+	logf("info", fmt.Sprintf("正在拉取 %s (%s)...", s.RepoURL, s.Branch))
+	latest, err := gitsource.Fetch(ctx, dir, s.Branch, gitStackAuth(s))
+	if err != nil {
+		return nil, fmt.Errorf("拉取仓库失败: %w", err)
+	}
+	commit, err := gitsource.CommitInfo(ctx, dir, latest)
+	if err != nil {
+		return nil, err
+	}
+	res := &gitSyncResult{Commit: commit, Previous: s.DeployedCommit}
+	logf("info", fmt.Sprintf("远端最新提交 %s %s（%s）", commit.Short(), commit.Subject, commit.Author))
+	if latest == s.DeployedCommit && !force {
+		logf("info", "已是最新，无需同步")
+		return res, nil
+	}
+
+	// 检出前先校验新版本的 compose，存在错误时保持当前部署不变
+	composeName, content, err := gitStackComposeAt(ctx, dir, latest, s.ComposePath)
+	if err != nil {
+		return res, err
+	}
+	env := readProjectDotenv(dir)
+	if raw, err := gitsource.Show(ctx, dir, latest, ".env"); err == nil {
+		env = parseDotenvToMap(strings.ReplaceAll(string(raw), "\r\n", "\n"))
+	}
+	diags := lintCompose(string(content), composeLintOptionsForProject(ctx, s.Project, "", env))
+	if composeDiagnosticsHaveErrors(diags) {
+		for _, d := range diags {
+			if d.Severity == "error" {
+				logf("error", fmt.Sprintf("%s:%d: %s", composeName, d.Line, d.Message))
+			}
+		}
+		return res, fmt.Errorf("提交 %s 中的 %s 校验失败", commit.Short(), composeName)
+	}
+
+	before, _ := readComposeSnapshot(dir)
+	if s.DeployedCommit != "" {
+		ensureComposeBaseline(s.Project)
+	}
+	if err := gitsource.Checkout(ctx, dir, s.Branch, latest); err != nil {
+		return res, fmt.Errorf("检出提交失败: %w", err)
+	}
+	if err := ensureGitStackComposeOptions(s, logf); err != nil {
+		return res, err
+	}
+	composePath, err := findComposeFile(dir)
+	if err != nil {
+		return res, err
+	}
+	if rel, _ := filepath.Rel(dir, composePath); filepath.ToSlash(rel) != composeName {
+		return res, fmt.Errorf("项目实际使用的 compose 文件是 %s 而不是 %s，请检查项目参数或仓库根目录中的 YAML 文件", filepath.ToSlash(rel), composeName)
+	}
+	after, err := readComposeSnapshot(dir)
+	if err != nil {
+		return res, err
+	}
+	res.Changed = before == nil || composeSnapshotHash(before) != composeSnapshotHash(after)
+	recordComposeVersionAfterSave(s.Project, "git", commit.Author, fmt.Sprintf("%s %s", commit.Short(), commit.Subject))
+
+	if !res.Changed && !force && s.DeployedCommit != "" {
+		logf("info", "compose 与 .env 未变化，无需重新部署")
+		return res, nil
+	}
+	logf("info", "正在部署...")
+	if err := runComposeStreamLines(ctx, dir, []string{"compose", "up", "-d", "--remove-orphans"}, func(line string) {
+		msgType := "info"
+		if strings.Contains(line, "error") || strings.Contains(line, "Error") {
+			msgType = "error"
+		}
+		logf(msgType, line)
+	}); err != nil {
+		return res, fmt.Errorf("部署失败: %w", err)
+	}
+	res.Deployed = true
+	logf("success", fmt.Sprintf("已部署提交 %s", commit.Short()))
+	return res, nil
+}
+
+// startGitStackTask 以任务形式执行克隆（clone 为 true 时）与同步，返回任务 ID；项目正在同步时返回 errComposeProjectBusy
+func startGitStackTask(s database.GitStack, clone, force bool, trigger string) (string, error) {
+	mu := composeProjectLock(s.Project)
+	if !mu.TryLock() {
+		return "", errComposeProjectBusy
+	}
+	taskType := "compose_git_sync"
+	if clone {
+		taskType = "compose_git_deploy"
+	}
+	taskID := fmt.Sprintf("%d", time.Now().UnixNano())
+	_ = database.UpsertTask(taskID, taskType, "pending")
+
+	go func() {
+		defer mu.Unlock()
+		seq := int64(0)
+		appendLog := func(logType string, message string) {
+			seq++
+			_ = database.AppendTaskLogWithSeq(taskID, seq, time.Now(), logType, message)
+		}
+		_ = database.UpsertTask(taskID, taskType, "running")
+		appendLog("info", fmt.Sprintf("开始同步项目 %s（触发方式：%s）", s.Project, trigger))
+
+		ctx, cancel := context.WithTimeout(context.Background(), gitStackTimeout)
+		defer cancel()
+
+		if clone {
+			dir := filepath.Join(getProjectsBaseDir(), s.Project)
+			appendLog("info", fmt.Sprintf("正在克隆 %s (%s)...", s.RepoURL, s.Branch))
+			if err := gitsource.Clone(ctx, s.RepoURL, s.Branch, dir, gitStackAuth(s)); err != nil {
+				_ = os.RemoveAll(dir)
+				_ = database.DeleteGitStack(s.Project)
+				appendLog("error", "克隆仓库失败: "+err.Error())
+				_ = database.FinishTask(taskID, "error", nil, err.Error())
+				_ = database.SaveNotification(&database.Notification{
+					Type:    "error",
+					Message: fmt.Sprintf("Git 项目 %s 部署失败：%s", s.Project, err.Error()),
+				})
+				return
+			}
+		}
+
+		res, err := syncGitStack(ctx, s, force || clone, appendLog)
+		if err != nil {
+			failedCommit := ""
+			if res != nil {
+				failedCommit = res.Commit.Hash
+			}
+			appendLog("error", err.Error())
+			_ = database.MarkGitStackSyncFailed(s.Project, err.Error(), failedCommit)
+			_ = database.FinishTask(taskID, "error", nil, err.Error())
+			if shouldNotifyGitSyncFailure(s, failedCommit) {
+				_ = database.SaveNotification(&database.Notification{
+					Type:    "error",
+					Message: fmt.Sprintf("Git 项目 %s 同步失败：%s", s.Project, err.Error()),
+				})
+			}
+			return
+		}
+
+		status := "unchanged"
+		if res.Deployed {
+			status = "success"
+		}
+		_ = database.UpdateGitStackSync(s.Project, status, "", res.Commit.Hash)
+		_ = database.FinishTask(taskID, "success", res, "")
+		if res.Deployed {
+			_ = database.SaveNotification(&database.Notification{
+				Type:    "success",
+				Message: fmt.Sprintf("Git 项目 %s 已部署提交 %s：%s", s.Project, res.Commit.Short(), res.Commit.Subject),
+			})
+		}
+	}()
+	return taskID, nil
+}
+
+// shouldNotifyGitSyncFailure 自动同步会反复重试同一个失败的提交，每个提交只通知一次；
+// 未取得提交（如拉取失败）时仅在状态由正常变为失败时通知
+func shouldNotifyGitSyncFailure(s database.GitStack, failedCommit string) bool {
+	if failedCommit == "" {
+		return s.LastSyncStatus != "error"
+	}
+	return failedCommit != s.FailedCommit
+}
+
+// StartGitStackSyncer 定期检查开启自动同步的 Git 项目，远端有新提交时发起同步任务
+func StartGitStackSyncer() {
+	go func() {
+		ticker := time.NewTicker(gitStackPollEvery)
+		defer ticker.Stop()
+		for range ticker.C {
+			pollGitStacks()
+		}
+	}()
+}
+
+func pollGitStacks() {
+	stacks, err := database.ListGitStacks()
+	if err != nil {
+		return
+	}
+	now := time.Now()
+	for _, s := range stacks {
+		if !s.AutoSync || (s.LastSyncAt != nil && now.Sub(*s.LastSyncAt) < time.Duration(s.SyncInterval)*time.Second) {
+			continue
+		}
+		// 克隆中的项目还没有工作区
+		if _, err := os.Stat(filepath.Join(getProjectsBaseDir(), s.Project, ".git")); err != nil {
+			continue
+		}
+		mu := composeProjectLock(s.Project)
+		if !mu.TryLock() {
+			continue
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), gitStackTimeout)
+		latest, err := gitsource.Fetch(ctx, filepath.Join(getProjectsBaseDir(), s.Project), s.Branch, gitStackAuth(s))
+		cancel()
+		mu.Unlock()
+
+		if err != nil {
+			// 仅在状态由正常变为失败时通知，避免每次轮询重复提醒
+			if s.LastSyncStatus != "error" {
+				_ = database.SaveNotification(&database.Notification{
+					Type:    "warning",
+					Message: fmt.Sprintf("Git 项目 %s 自动同步失败：%s", s.Project, err.Error()),
+				})
+			}
+			_ = database.UpdateGitStackSync(s.Project, "error", err.Error(), "")
+			continue
+		}
+		if latest == s.DeployedCommit {
+			_ = database.UpdateGitStackSync(s.Project, "unchanged", "", "")
+			continue
+		}
+		if _, err := startGitStackTask(s, false, false, "自动同步"); err != nil && !errors.Is(err, errComposeProjectBusy) {
+			log.Printf("发起 Git 项目 %s 同步失败: %v", s.Project, err)
+		}
+	}
+}
+
+// lookupGitStack 解析路径中的项目名并读取其 Git 来源，失败时已写入响应
+func lookupGitStack(c *gin.Context) (database.GitStack, bool) {
+	name, ok := validateComposeProjectName(c.Param("name"))
+	if !ok {
+		respondError(c, http.StatusBadRequest, "项目名不合法：仅支持小写字母/数字，且可包含 _ -，并以字母或数字开头", nil)
+		return database.GitStack{}, false
+	}
+	s, err := database.GetGitStack(name)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			respondError(c, http.StatusNotFound, "该项目不是 Git 来源项目", nil)
+		} else {
+			respondError(c, http.StatusInternalServerError, "获取 Git 来源失败", err)
+		}
+		return database.GitStack{}, false
+	}
+	return s, true
+}
+
+func listGitStacks(c *gin.Context) {
+	stacks, err := database.ListGitStacks()
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "获取 Git 项目失败", err)
+		return
+	}
+	out := make([]gitStackResponse, 0, len(stacks))
+	for _, s := range stacks {
+		out = append(out, newGitStackResponse(s, ""))
+	}
+	c.JSON(http.StatusOK, out)
+}
+
+// createGitStack 从 Git 仓库创建项目：保存来源配置后以任务形式克隆并部署
+func createGitStack(c *gin.Context) {
+	var req gitStackRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, "无效的请求数据", err)
+		return
+	}
+	name, ok := validateComposeProjectName(strings.TrimSpace(req.Name))
+	if !ok {
+		respondError(c, http.StatusBadRequest, "项目名不合法：仅支持小写字母/数字，且可包含 _ -，并以字母或数字开头", nil)
+		return
+	}
+	if forbidIfSelfProject(c, name) {
+		return
+	}
+	s := database.GitStack{Project: name, AutoSync: true}
+	token, errs := applyGitStackRequest(&s, req)
+	if len(errs) > 0 {
+		respondFieldErrors(c, errs)
+		return
+	}
+	if _, err := os.Stat(filepath.Join(getProjectsBaseDir(), name)); err == nil {
+		respondError(c, http.StatusConflict, fmt.Sprintf("项目 '%s' 已存在", name), nil)
+		return
+	} else if !os.IsNotExist(err) {
+		respondError(c, http.StatusInternalServerError, "检查项目目录失败", err)
+		return
+	}
+	if _, err := database.GetGitStack(name); err == nil {
+		respondError(c, http.StatusConflict, fmt.Sprintf("项目 '%s' 正在创建", name), nil)
+		return
+	}
+	if err := database.SaveGitStack(&s); err != nil {
+		respondError(c, http.StatusInternalServerError, "保存 Git 来源失败", err)
+		return
+	}
+	taskID, err := startGitStackTask(s, true, true, "创建项目")
+	if err != nil {
+		_ = database.DeleteGitStack(name)
+		respondError(c, http.StatusConflict, err.Error(), nil)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "部署任务已提交", "taskId": taskID, "stack": newGitStackResponse(s, token)})
+}
+
+func getGitStack(c *gin.Context) {
+	s, ok := lookupGitStack(c)
+	if !ok {
+		return
+	}
+	resp := gin.H{"stack": newGitStackResponse(s, "")}
+	if head, err := gitsource.Head(c.Request.Context(), filepath.Join(getProjectsBaseDir(), s.Project)); err == nil {
+		resp["head"] = head
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+func updateGitStack(c *gin.Context) {
+	s, ok := lookupGitStack(c)
+	if !ok {
+		return
+	}
+	if forbidIfSelfProject(c, s.Project) {
+		return
+	}
+	var req gitStackRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, "无效的请求数据", err)
+		return
+	}
+	oldURL := s.RepoURL
+	token, errs := applyGitStackRequest(&s, req)
+	if len(errs) > 0 {
+		respondFieldErrors(c, errs)
+		return
+	}
+
+	mu := composeProjectLock(s.Project)
+	if !mu.TryLock() {
+		respondError(c, http.StatusConflict, errComposeProjectBusy.Error(), nil)
+		return
+	}
+	defer mu.Unlock()
+	if s.RepoURL != oldURL {
+		if err := gitsource.SetRemoteURL(c.Request.Context(), filepath.Join(getProjectsBaseDir(), s.Project), s.RepoURL); err != nil {
+			respondError(c, http.StatusInternalServerError, "修改仓库地址失败", err)
+			return
+		}
+	}
+	if err := database.SaveGitStack(&s); err != nil {
+		respondError(c, http.StatusInternalServerError, "保存 Git 来源失败", err)
+		return
+	}
+	c.JSON(http.StatusOK, newGitStackResponse(s, token))
+}
+
+// syncGitStackNow 立即同步，force 为 true 时即使没有新提交也重新部署
+func syncGitStackNow(c *gin.Context) {
+	s, ok := lookupGitStack(c)
+	if !ok {
+		return
+	}
+	if forbidIfSelfProject(c, s.Project) {
+		return
+	}
+	var req struct {
+		Force bool `json:"force"`
+	}
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			respondError(c, http.StatusBadRequest, "无效的请求数据", err)
+			return
+		}
+	}
+	taskID, err := startGitStackTask(s, false, req.Force, "手动同步")
+	if err != nil {
+		respondError(c, http.StatusConflict, err.Error(), nil)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "同步任务已提交", "taskId": taskID})
+}
+
+// detachGitStack 取消项目与仓库的关联，保留项目文件与运行中的容器
+func detachGitStack(c *gin.Context) {
+	s, ok := lookupGitStack(c)
+	if !ok {
+		return
+	}
+	if err := database.DeleteGitStack(s.Project); err != nil {
+		respondError(c, http.StatusInternalServerError, "取消 Git 关联失败", err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "已取消 Git 关联"})
+}
+
+// RegisterWebhookRoutes 注册外部系统调用的回调路由，这些路由不经过登录认证，由各自的令牌校验
+func RegisterWebhookRoutes(r *gin.Engine) {
+	group := r.Group("/api/hooks")
+	{
+		group.POST("/git/:name", gitStackWebhook)
+		group.POST("/compose/:name", composeWebhookTrigger)
+	}
+}
+
+// webhookToken 从 X-Webhook-Token（或 GitLab 的 X-Gitlab-Token）请求头、token 查询参数读取令牌
+func webhookToken(c *gin.Context) string {
+	for _, h := range []string{"X-Webhook-Token", "X-Gitlab-Token"} {
+		if t := strings.TrimSpace(c.GetHeader(h)); t != "" {
+			return t
+		}
+	}
+	return strings.TrimSpace(c.Query("token"))
+}
+
+// gitStackWebhook 仓库推送后由代码托管平台调用，触发一次同步
+func gitStackWebhook(c *gin.Context) {
+	name, ok := validateComposeProjectName(c.Param("name"))
+	if !ok {
+		respondError(c, http.StatusUnauthorized, "令牌无效", nil)
+		return
+	}
+	s, err := database.GetGitStack(name)
+	token := webhookToken(c)
+	if err != nil || token == "" || s.WebhookTokenHash == "" || !hmac.Equal([]byte(hashWebhookToken(token)), []byte(s.WebhookTokenHash)) {
+		respondError(c, http.StatusUnauthorized, "令牌无效", nil)
+		return
+	}
+	taskID, err := startGitStackTask(s, false, false, "Webhook")
+	if err != nil {
+		respondError(c, http.StatusConflict, err.Error(), nil)
+		return
+	}
+	c.JSON(http.StatusAccepted, gin.H{"message": "同步任务已提交", "taskId": taskID})
+}
@@ -0,0 +1,151 @@
+package api
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"dockerpanel/backend/pkg/database"
+	"dockerpanel/backend/pkg/gitsource"
+)
+
+func TestValidGitStackComposePath(t *testing.T) {
+	for p, want := range map[string]bool{
+		"docker-compose.yml":      true,
+		"deploy/compose.yaml":     true,
+		"stacks/prod/compose.yml": true,
+		"../compose.yml":          false,
+		"/etc/compose.yml":        false,
+		"deploy//compose.yml":     false,
+		".github/compose.yml":     false,
+		`deploy\compose.yml`:      false,
+		"deploy/compose.json":     false,
+	} {
+		if got := validGitStackComposePath(p); got != want {
+			t.Errorf("validGitStackComposePath(%q)=%v want %v", p, got, want)
+		}
+	}
+}
+
+func TestApplyGitStackRequest(t *testing.T) {
+	var s database.GitStack
+	_, errs := applyGitStackRequest(&s, gitStackRequest{RepoURL: "ssh://git@host/repo", Branch: "a..b", ComposePath: "../compose.yml", SyncInterval: 10})
+	fields := map[string]bool{}
+	for _, e := range errs {
+		fields[e.Field] = true
+	}
+	for _, want := range []string{"repoUrl", "branch", "composePath", "syncInterval"} {
+		if !fields[want] {
+			t.Errorf("missing error for %s: %v", want, errs)
+		}
+	}
+
+	pw := "token"
+	s = database.GitStack{WebhookTokenHash: "keep"}
+	token, errs := applyGitStackRequest(&s, gitStackRequest{RepoURL: "https://git.example.com/ops/stack.git", ComposePath: "./deploy/stack.yml", Password: &pw})
+	if len(errs) > 0 {
+		t.Fatal(errs)
+	}
+	if s.Branch != "main" || s.ComposePath != "deploy/stack.yml" || s.SyncInterval != gitStackDefaultInterval || s.WebhookTokenHash != "keep" || token != "" || s.Password != "token" {
+		t.Fatalf("defaults: %+v", s)
+	}
+	// 未提供密码时保留原值；重新生成的令牌只保存哈希
+	token, _ = applyGitStackRequest(&s, gitStackRequest{RepoURL: s.RepoURL, RegenerateToken: true})
+	if s.Password != "token" || len(token) != 48 || s.WebhookTokenHash != hashWebhookToken(token) {
+		t.Fatalf("update: %+v", s)
+	}
+	if resp := newGitStackResponse(s, ""); resp.WebhookToken != "" || !resp.HasWebhookToken {
+		t.Fatalf("response should not expose the token: %+v", resp)
+	}
+}
+
+func TestSyncGitStackValidatesBeforeCheckout(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	root := t.TempDir()
+	git := func(dir string, args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		cmd.Env = append(os.Environ(), "GIT_AUTHOR_NAME=Dev", "GIT_AUTHOR_EMAIL=dev@example.com",
+			"GIT_COMMITTER_NAME=Dev", "GIT_COMMITTER_EMAIL=dev@example.com")
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+	bare, work := filepath.Join(root, "origin.git"), filepath.Join(root, "work")
+	git(root, "init", "--bare", "-b", "main", bare)
+	git(root, "init", "-b", "main", work)
+	commit := func(compose, msg string) {
+		if err := os.WriteFile(filepath.Join(work, "docker-compose.yml"), []byte(compose), 0644); err != nil {
+			t.Fatal(err)
+		}
+		git(work, "add", "-A")
+		git(work, "commit", "-m", msg)
+		git(work, "push", bare, "main")
+	}
+	good := "services:\n  web:\n    image: nginx:1.25\n"
+	commit(good, "initial")
+
+	// 项目根目录由工作目录推导
+	cwd, _ := os.Getwd()
+	if err := os.Chdir(root); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(cwd)
+
+	ctx := context.Background()
+	s := database.GitStack{Project: "shop", RepoURL: "file://" + bare, Branch: "main"}
+	dir := filepath.Join(getProjectsBaseDir(), s.Project)
+	if err := gitsource.Clone(ctx, s.RepoURL, s.Branch, dir, gitsource.Auth{}); err != nil {
+		t.Fatal(err)
+	}
+	head, _ := gitsource.Head(ctx, dir)
+	s.DeployedCommit = head.Hash
+
+	commit("services:\n  web:\n    image: nginx:1.25\n    command: @start\n", "break it")
+	var logs []string
+	logf := func(level, msg string) { logs = append(logs, level+": "+msg) }
+	failed, err := syncGitStack(ctx, s, false, logf)
+	if err == nil || !strings.Contains(err.Error(), "校验失败") {
+		t.Fatalf("invalid compose should abort sync, got %v\n%s", err, strings.Join(logs, "\n"))
+	}
+	if failed == nil || failed.Commit.Subject != "break it" {
+		t.Fatalf("failed sync should report the commit: %+v", failed)
+	}
+	if b, _ := os.ReadFile(filepath.Join(dir, "docker-compose.yml")); string(b) != good {
+		t.Fatalf("working tree must stay on the deployed commit, got %q", b)
+	}
+
+	// 已部署最新提交时不做任何改动
+	latest, err := gitsource.RevParse(ctx, dir, "refs/remotes/origin/main")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.DeployedCommit = latest
+	res, err := syncGitStack(ctx, s, false, logf)
+	if err != nil || res.Deployed || res.Changed || res.Commit.Subject != "break it" {
+		t.Fatalf("up-to-date sync: %+v %v", res, err)
+	}
+}
+
+func TestShouldNotifyGitSyncFailure(t *testing.T) {
+	s := database.GitStack{LastSyncStatus: "success"}
+	if !shouldNotifyGitSyncFailure(s, "abc") || !shouldNotifyGitSyncFailure(s, "") {
+		t.Fatal("first failure should notify")
+	}
+	s = database.GitStack{LastSyncStatus: "error", FailedCommit: "abc"}
+	if shouldNotifyGitSyncFailure(s, "abc") {
+		t.Fatal("retrying the same commit should not notify again")
+	}
+	if !shouldNotifyGitSyncFailure(s, "def") {
+		t.Fatal("a new failing commit should notify")
+	}
+	if shouldNotifyGitSyncFailure(s, "") {
+		t.Fatal("repeated fetch errors should not notify")
+	}
+}
@@ -0,0 +1,650 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"dockerpanel/backend/pkg/database"
+	"dockerpanel/backend/pkg/docker"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/gin-gonic/gin"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	composeGraphDefaultHealthTimeout = 120 * time.Second
+	composeGraphMaxHealthTimeout     = 30 * time.Minute
+	composeGraphPollInterval         = 2 * time.Second
+	composeGraphStepTimeout          = 30 * time.Minute
+)
+
+// composeResources 项目创建的网络/卷与引用的外部网络/卷（均为实际名称）
+type composeResources struct {
+	Networks         []string `json:"networks"`
+	Volumes          []string `json:"volumes"`
+	ExternalNetworks []string `json:"externalNetworks"`
+	ExternalVolumes  []string `json:"externalVolumes"`
+}
+
+type composeResourceSpec struct {
+	Name     string    `yaml:"name"`
+	External yaml.Node `yaml:"external"`
+}
+
+// external 解析 external 字段，兼容 `external: true` 与旧版的 `external: {name: xxx}`
+func (s *composeResourceSpec) external() (bool, string) {
+	switch s.External.Kind {
+	case yaml.ScalarNode:
+		var b bool
+		_ = s.External.Decode(&b)
+		return b, ""
+	case yaml.MappingNode:
+		var v struct {
+			Name string `yaml:"name"`
+		}
+		_ = s.External.Decode(&v)
+		return true, v.Name
+	}
+	return false, ""
+}
+
+// composeLinkResources 解析项目（可能由多个文件合并）的顶层 networks/volumes。
+// 非外部资源按 compose 规则命名为 <项目>_<键>，除非显式指定 name；默认网络 <项目>_default 总是视为由项目创建
+func composeLinkResources(project string, docs [][]byte, env map[string]string) composeResources {
+	networks := make(map[string]*composeResourceSpec)
+	volumes := make(map[string]*composeResourceSpec)
+	for _, data := range docs {
+		var root struct {
+			Networks map[string]*composeResourceSpec `yaml:"networks"`
+			Volumes  map[string]*composeResourceSpec `yaml:"volumes"`
+		}
+		if err := yaml.Unmarshal(data, &root); err != nil {
+			continue
+		}
+		for k, v := range root.Networks {
+			networks[k] = v
+		}
+		for k, v := range root.Volumes {
+			volumes[k] = v
+		}
+	}
+
+	var res composeResources
+	collect := func(specs map[string]*composeResourceSpec, own, external *[]string) {
+		for key, spec := range specs {
+			if spec == nil {
+				spec = &composeResourceSpec{}
+			}
+			name := interpolateCompose(spec.Name, env)
+			if ext, extName := spec.external(); ext {
+				if extName != "" {
+					name = interpolateCompose(extName, env)
+				}
+				if name == "" {
+					name = key
+				}
+				*external = append(*external, name)
+				continue
+			}
+			if name == "" {
+				name = project + "_" + key
+			}
+			*own = append(*own, name)
+		}
+	}
+	collect(networks, &res.Networks, &res.ExternalNetworks)
+	collect(volumes, &res.Volumes, &res.ExternalVolumes)
+	if _, ok := networks["default"]; !ok {
+		res.Networks = append(res.Networks, project+"_default")
+	}
+	for _, list := range []*[]string{&res.Networks, &res.Volumes, &res.ExternalNetworks, &res.ExternalVolumes} {
+		if *list == nil {
+			*list = []string{}
+		}
+		sort.Strings(*list)
+	}
+	return res
+}
+
+// composeGraphEdge From 依赖 To（To 需先启动、后停止）
+type composeGraphEdge struct {
+	From     string `json:"from"`
+	To       string `json:"to"`
+	Source   string `json:"source"` // manual / network / volume
+	Resource string `json:"resource,omitempty"`
+}
+
+type composeGraphNode struct {
+	Name      string           `json:"name"`
+	IsSelf    bool             `json:"isSelf"`
+	Resources composeResources `json:"resources"`
+	DependsOn []string         `json:"dependsOn"` // 手动声明的依赖
+}
+
+type composeGraph struct {
+	Nodes []composeGraphNode `json:"nodes"`
+	Edges []composeGraphEdge `json:"edges"`
+	Order []string           `json:"order"`           // 启动顺序，依赖在前
+	Cycle []string           `json:"cycle,omitempty"` // 存在循环依赖时涉及的项目
+}
+
+// buildComposeGraph 合并手动依赖与通过外部网络/卷自动识别的依赖，并计算启动顺序
+func buildComposeGraph(resources map[string]composeResources, manual []database.ComposeDependency) composeGraph {
+	g := composeGraph{Nodes: []composeGraphNode{}, Edges: []composeGraphEdge{}}
+	names := make([]string, 0, len(resources))
+	for name := range resources {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	providers := make(map[string]string)
+	for _, name := range names {
+		for _, n := range resources[name].Networks {
+			providers["network:"+n] = name
+		}
+		for _, v := range resources[name].Volumes {
+			providers["volume:"+v] = name
+		}
+	}
+
+	seen := make(map[composeGraphEdge]bool)
+	addEdge := func(e composeGraphEdge) {
+		if e.From == e.To || seen[e] {
+			return
+		}
+		seen[e] = true
+		g.Edges = append(g.Edges, e)
+	}
+	manualByProject := make(map[string][]string)
+	for _, d := range manual {
+		if _, ok := resources[d.Project]; !ok {
+			continue
+		}
+		if _, ok := resources[d.DependsOn]; !ok {
+			continue
+		}
+		manualByProject[d.Project] = append(manualByProject[d.Project], d.DependsOn)
+		addEdge(composeGraphEdge{From: d.Project, To: d.DependsOn, Source: "manual"})
+	}
+	for _, name := range names {
+		res := resources[name]
+		for _, n := range res.ExternalNetworks {
+			if p, ok := providers["network:"+n]; ok {
+				addEdge(composeGraphEdge{From: name, To: p, Source: "network", Resource: n})
+			}
+		}
+		for _, v := range res.ExternalVolumes {
+			if p, ok := providers["volume:"+v]; ok {
+				addEdge(composeGraphEdge{From: name, To: p, Source: "volume", Resource: v})
+			}
+		}
+		deps := manualByProject[name]
+		if deps == nil {
+			deps = []string{}
+		}
+		sort.Strings(deps)
+		g.Nodes = append(g.Nodes, composeGraphNode{Name: name, IsSelf: isSelfProjectName(name), Resources: res, DependsOn: deps})
+	}
+	g.Order, g.Cycle = composeDependencyOrder(names, g.Edges)
+	return g
+}
+
+// composeDependencyOrder 拓扑排序（同层按名称排序），存在环时返回无法排序的项目
+func composeDependencyOrder(names []string, edges []composeGraphEdge) (order []string, cycle []string) {
+	pending := make(map[string]int, len(names))
+	dependents := make(map[string][]string)
+	counted := make(map[[2]string]bool)
+	for _, n := range names {
+		pending[n] = 0
+	}
+	for _, e := range edges {
+		key := [2]string{e.From, e.To}
+		if counted[key] {
+			continue
+		}
+		counted[key] = true
+		pending[e.From]++
+		dependents[e.To] = append(dependents[e.To], e.From)
+	}
+	var ready []string
+	for _, n := range names {
+		if pending[n] == 0 {
+			ready = append(ready, n)
+		}
+	}
+	order = []string{}
+	for len(ready) > 0 {
+		sort.Strings(ready)
+		n := ready[0]
+		ready = ready[1:]
+		order = append(order, n)
+		for _, d := range dependents[n] {
+			pending[d]--
+			if pending[d] == 0 {
+				ready = append(ready, d)
+			}
+		}
+	}
+	for _, n := range names {
+		if pending[n] > 0 {
+			cycle = append(cycle, n)
+		}
+	}
+	return order, cycle
+}
+
+// composeGraphSelect 计算批量操作涉及的项目及执行顺序：
+// 启动/更新时连带被选项目的依赖并按依赖在前执行，停止时连带依赖它们的项目并按相反顺序执行。未指定项目时作用于全部项目
+func composeGraphSelect(g composeGraph, selected []string, action string) []string {
+	order := g.Order
+	if action == "stop" {
+		order = make([]string, len(g.Order))
+		for i, n := range g.Order {
+			order[len(g.Order)-1-i] = n
+		}
+	}
+	if len(selected) == 0 {
+		return order
+	}
+	next := make(map[string][]string)
+	for _, e := range g.Edges {
+		if action == "stop" {
+			next[e.To] = append(next[e.To], e.From)
+		} else {
+			next[e.From] = append(next[e.From], e.To)
+		}
+	}
+	include := make(map[string]bool)
+	queue := append([]string(nil), selected...)
+	for len(queue) > 0 {
+		n := queue[0]
+		queue = queue[1:]
+		if include[n] {
+			continue
+		}
+		include[n] = true
+		queue = append(queue, next[n]...)
+	}
+	out := []string{}
+	for _, n := range order {
+		if include[n] {
+			out = append(out, n)
+		}
+	}
+	return out
+}
+
+// composeGraphProjects 列出项目根目录下包含 compose 文件的项目
+func composeGraphProjects() ([]string, error) {
+	entries, err := os.ReadDir(getProjectsBaseDir())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []string{}, nil
+		}
+		return nil, err
+	}
+	names := []string{}
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		name, ok := validateComposeProjectName(e.Name())
+		if !ok {
+			continue
+		}
+		if _, err := findComposeFile(filepath.Join(getProjectsBaseDir(), name)); err == nil {
+			names = append(names, name)
+		}
+	}
+	return names, nil
+}
+
+// readProjectComposeDocs 读取项目参与合并的全部 compose 文件
+func readProjectComposeDocs(projectDir string) [][]byte {
+	var paths []string
+	for _, f := range composeOptionsForDir(projectDir).ComposeFiles {
+		paths = append(paths, filepath.Join(projectDir, f))
+	}
+	if len(paths) == 0 {
+		if p, err := findComposeFile(projectDir); err == nil {
+			paths = append(paths, p)
+		}
+	}
+	var docs [][]byte
+	for _, p := range paths {
+		if data, err := os.ReadFile(p); err == nil {
+			docs = append(docs, data)
+		}
+	}
+	return docs
+}
+
+// loadComposeGraph 扫描全部项目生成依赖图；overrides 用于在保存前以新的手动依赖校验
+func loadComposeGraph(overrides map[string][]string) (composeGraph, error) {
+	names, err := composeGraphProjects()
+	if err != nil {
+		return composeGraph{}, err
+	}
+	manual, err := database.ListComposeDependencies()
+	if err != nil {
+		return composeGraph{}, err
+	}
+	if len(overrides) > 0 {
+		kept := manual[:0]
+		for _, d := range manual {
+			if _, ok := overrides[d.Project]; !ok {
+				kept = append(kept, d)
+			}
+		}
+		manual = kept
+		for project, deps := range overrides {
+			for _, dep := range deps {
+				manual = append(manual, database.ComposeDependency{Project: project, DependsOn: dep})
+			}
+		}
+	}
+	resources := make(map[string]composeResources, len(names))
+	for _, name := range names {
+		dir := filepath.Join(getProjectsBaseDir(), name)
+		resources[name] = composeLinkResources(name, readProjectComposeDocs(dir), readProjectDotenv(dir))
+	}
+	return buildComposeGraph(resources, manual), nil
+}
+
+func getComposeGraph(c *gin.Context) {
+	g, err := loadComposeGraph(nil)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "生成项目依赖图失败", err)
+		return
+	}
+	c.JSON(http.StatusOK, g)
+}
+
+type composeDependenciesRequest struct {
+	DependsOn []string `json:"dependsOn"`
+}
+
+// updateComposeDependencies 替换项目手动声明的依赖，形成循环依赖时拒绝保存
+func updateComposeDependencies(c *gin.Context) {
+	name, ok := validateComposeProjectName(c.Param("name"))
+	if !ok {
+		respondError(c, http.StatusBadRequest, "项目名不合法：仅支持小写字母/数字，且可包含 _ -，并以字母或数字开头", nil)
+		return
+	}
+	var req composeDependenciesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, "无效的请求数据", err)
+		return
+	}
+	projects, err := composeGraphProjects()
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "获取项目列表失败", err)
+		return
+	}
+	exists := make(map[string]bool, len(projects))
+	for _, p := range projects {
+		exists[p] = true
+	}
+	if !exists[name] {
+		respondError(c, http.StatusNotFound, "项目不存在", nil)
+		return
+	}
+
+	var errs fieldErrors
+	deps := []string{}
+	seen := make(map[string]bool)
+	for i, raw := range req.DependsOn {
+		dep := strings.TrimSpace(raw)
+		field := fmt.Sprintf("dependsOn[%d]", i)
+		switch {
+		case dep == name:
+			errs.add(field, "项目不能依赖自身")
+		case !exists[dep]:
+			errs.add(field, "项目 %q 不存在", raw)
+		case !seen[dep]:
+			seen[dep] = true
+			deps = append(deps, dep)
+		}
+	}
+	if len(errs) > 0 {
+		respondFieldErrors(c, errs)
+		return
+	}
+
+	g, err := loadComposeGraph(map[string][]string{name: deps})
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "生成项目依赖图失败", err)
+		return
+	}
+	if len(g.Cycle) > 0 {
+		respondError(c, http.StatusBadRequest, "存在循环依赖: "+strings.Join(g.Cycle, ", "), nil)
+		return
+	}
+	if err := database.SetComposeDependencies(name, deps); err != nil {
+		respondError(c, http.StatusInternalServerError, "保存项目依赖失败", err)
+		return
+	}
+	c.JSON(http.StatusOK, g)
+}
+
+// composeContainersReady 判断项目容器是否都已就绪：运行中且健康检查（若有）通过；正常退出（退出码 0）的一次性容器视为完成。
+// 返回尚未就绪的容器说明；已退出且不会重启的失败容器直接返回错误
+func composeContainersReady(infos []types.ContainerJSON) (bool, []string, error) {
+	var waiting []string
+	for _, info := range infos {
+		if info.ContainerJSONBase == nil || info.State == nil {
+			continue
+		}
+		if info.Config != nil && info.Config.Labels["com.docker.compose.oneoff"] == "True" {
+			continue
+		}
+		name := strings.TrimPrefix(info.Name, "/")
+		st := info.State
+		switch {
+		case st.Running && !st.Restarting:
+			if st.Health != nil && st.Health.Status != "healthy" {
+				waiting = append(waiting, fmt.Sprintf("%s（%s）", name, st.Health.Status))
+			}
+		case st.Status == "exited" && st.ExitCode == 0:
+		case st.Status == "exited" || st.Status == "dead":
+			policy := ""
+			if info.HostConfig != nil {
+				policy = info.HostConfig.RestartPolicy.Name
+			}
+			if policy == "" || policy == "no" {
+				return false, nil, fmt.Errorf("容器 %s 已退出（退出码 %d）", name, st.ExitCode)
+			}
+			waiting = append(waiting, fmt.Sprintf("%s（%s）", name, st.Status))
+		default:
+			waiting = append(waiting, fmt.Sprintf("%s（%s）", name, st.Status))
+		}
+	}
+	sort.Strings(waiting)
+	return len(waiting) == 0, waiting, nil
+}
+
+// waitComposeProjectReady 等待项目的全部容器就绪
+func waitComposeProjectReady(ctx context.Context, project string, timeout time.Duration, logf func(level, msg string)) error {
+	cli, err := docker.NewDockerClient()
+	if err != nil {
+		return err
+	}
+	defer cli.Close()
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	lastWaiting := ""
+	for {
+		list, err := cli.ContainerList(ctx, types.ContainerListOptions{
+			All:     true,
+			Filters: filters.NewArgs(filters.Arg("label", "com.docker.compose.project="+project)),
+		})
+		if err != nil {
+			return err
+		}
+		infos := make([]types.ContainerJSON, 0, len(list))
+		for _, ct := range list {
+			if info, err := cli.ContainerInspect(ctx, ct.ID); err == nil {
+				infos = append(infos, info)
+			}
+		}
+		ready, waiting, err := composeContainersReady(infos)
+		if err != nil {
+			return err
+		}
+		if ready {
+			return nil
+		}
+		if w := strings.Join(waiting, ", "); w != lastWaiting {
+			lastWaiting = w
+			logf("info", "等待容器就绪: "+w)
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("等待项目 %s 就绪超时: %s", project, lastWaiting)
+		case <-time.After(composeGraphPollInterval):
+		}
+	}
+}
+
+type composeGraphActionRequest struct {
+	Projects      []string `json:"projects"`      // 为空时作用于全部项目
+	HealthTimeout int      `json:"healthTimeout"` // 秒，等待每个项目就绪的超时时间
+}
+
+// runComposeGraphStep 在项目上执行一步操作，启动/更新后等待容器就绪
+func runComposeGraphStep(project, action string, healthTimeout time.Duration, logf func(level, msg string)) error {
+	mu := composeProjectLock(project)
+	if !mu.TryLock() {
+		return errComposeProjectBusy
+	}
+	defer mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), composeGraphStepTimeout)
+	defer cancel()
+	projectDir := filepath.Join(getProjectsBaseDir(), project)
+	output := func(line string) { logf("", line) }
+	switch action {
+	case "start":
+		if err := runComposeStreamLines(ctx, projectDir, []string{"compose", "up", "-d"}, output); err != nil {
+			return err
+		}
+	case "stop":
+		return runComposeStreamLines(ctx, projectDir, []string{"compose", "stop", "-t", "2"}, output)
+	case "update":
+		if err := runComposeUpdate(ctx, projectDir, nil, logf); err != nil {
+			return err
+		}
+	}
+	return waitComposeProjectReady(ctx, project, healthTimeout, logf)
+}
+
+// runComposeGraphAction 按依赖顺序批量启动、停止或更新项目，作为一个任务执行，进度可通过 /compose/tasks/:id/events 查看
+func runComposeGraphAction(c *gin.Context) {
+	action := c.Param("action")
+	labels := map[string]string{"start": "启动", "stop": "停止", "update": "更新"}
+	label, ok := labels[action]
+	if !ok {
+		respondError(c, http.StatusBadRequest, "不支持的操作，仅支持 start / stop / update", nil)
+		return
+	}
+	var req composeGraphActionRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			respondError(c, http.StatusBadRequest, "无效的请求数据", err)
+			return
+		}
+	}
+	healthTimeout := composeGraphDefaultHealthTimeout
+	if req.HealthTimeout > 0 {
+		healthTimeout = time.Duration(req.HealthTimeout) * time.Second
+	}
+	if healthTimeout > composeGraphMaxHealthTimeout {
+		respondError(c, http.StatusBadRequest, "healthTimeout 不能超过 1800 秒", nil)
+		return
+	}
+
+	g, err := loadComposeGraph(nil)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "生成项目依赖图失败", err)
+		return
+	}
+	if len(g.Cycle) > 0 {
+		respondError(c, http.StatusBadRequest, "存在循环依赖: "+strings.Join(g.Cycle, ", "), nil)
+		return
+	}
+	known := make(map[string]bool, len(g.Order))
+	for _, n := range g.Order {
+		known[n] = true
+	}
+	for _, p := range req.Projects {
+		if !known[p] {
+			respondError(c, http.StatusBadRequest, fmt.Sprintf("项目 %q 不存在", p), nil)
+			return
+		}
+	}
+	plan := composeGraphSelect(g, req.Projects, action)
+	if len(plan) == 0 {
+		respondError(c, http.StatusBadRequest, "没有可操作的项目", nil)
+		return
+	}
+
+	taskType := "compose_graph_" + action
+	taskID := fmt.Sprintf("%d", time.Now().UnixNano())
+	_ = database.UpsertTask(taskID, taskType, "pending")
+	go func() {
+		seq := int64(0)
+		appendLog := func(level string, message string) {
+			if level == "" {
+				level = "info"
+				if strings.Contains(message, "error") || strings.Contains(message, "Error") {
+					level = "error"
+				}
+			}
+			seq++
+			_ = database.AppendTaskLogWithSeq(taskID, seq, time.Now(), level, message)
+		}
+		_ = database.UpsertTask(taskID, taskType, "running")
+		appendLog("info", fmt.Sprintf("按依赖顺序%s项目: %s", label, strings.Join(plan, " → ")))
+
+		done := []string{}
+		skipped := []string{}
+		for i, project := range plan {
+			if isSelfProjectName(project) {
+				appendLog("warning", fmt.Sprintf("[%d/%d] 跳过自身项目 %s", i+1, len(plan), project))
+				skipped = append(skipped, project)
+				continue
+			}
+			appendLog("info", fmt.Sprintf("[%d/%d] 开始%s项目 %s", i+1, len(plan), label, project))
+			if err := runComposeGraphStep(project, action, healthTimeout, appendLog); err != nil {
+				msg := fmt.Sprintf("项目 %s %s失败: %s", project, label, err.Error())
+				appendLog("error", msg+"，后续项目未执行")
+				_ = database.FinishTask(taskID, "error", gin.H{"plan": plan, "done": done, "skipped": skipped, "failed": project}, msg)
+				_ = database.SaveNotification(&database.Notification{Type: "error", Message: "批量" + label + "中止：" + msg})
+				return
+			}
+			done = append(done, project)
+			appendLog("success", fmt.Sprintf("[%d/%d] 项目 %s 已%s", i+1, len(plan), project, label))
+		}
+		_ = database.FinishTask(taskID, "success", gin.H{"plan": plan, "done": done, "skipped": skipped}, "")
+		_ = database.SaveNotification(&database.Notification{
+			Type:    "success",
+			Message: fmt.Sprintf("已按依赖顺序%s %d 个项目", label, len(done)),
+		})
+	}()
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"message":    "任务已提交",
+		"taskId":     taskID,
+		"plan":       plan,
+		"eventsPath": "/api/compose/tasks/" + taskID + "/events",
+	})
+}
@@ -0,0 +1,134 @@
+package api
+
+import (
+	"strings"
+	"testing"
+
+	"dockerpanel/backend/pkg/database"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+)
+
+func TestComposeLinkResources(t *testing.T) {
+	base := []byte(`
+services:
+  app:
+    image: app
+networks:
+  proxy:
+    external: true
+    name: ${PROXY_NET}
+  legacy:
+    external:
+      name: legacy_net
+  backend: {}
+volumes:
+  pgdata:
+  shared:
+    name: shared_files
+  media:
+    external: true
+`)
+	override := []byte(`
+volumes:
+  pgdata:
+    external: true
+    name: db_pgdata
+`)
+	res := composeLinkResources("shop", [][]byte{base, override}, map[string]string{"PROXY_NET": "proxy_default"})
+	if got := strings.Join(res.Networks, ","); got != "shop_backend,shop_default" {
+		t.Fatalf("networks: %s", got)
+	}
+	if got := strings.Join(res.ExternalNetworks, ","); got != "legacy_net,proxy_default" {
+		t.Fatalf("external networks: %s", got)
+	}
+	if got := strings.Join(res.Volumes, ","); got != "shared_files" {
+		t.Fatalf("volumes: %s", got)
+	}
+	if got := strings.Join(res.ExternalVolumes, ","); got != "db_pgdata,media" {
+		t.Fatalf("external volumes: %s", got)
+	}
+}
+
+func TestBuildComposeGraphOrder(t *testing.T) {
+	resources := map[string]composeResources{
+		"proxy": composeLinkResources("proxy", nil, nil),
+		"db":    composeLinkResources("db", [][]byte{[]byte("volumes:\n  pgdata:\n")}, nil),
+		"shop": composeLinkResources("shop", [][]byte{[]byte(`
+networks:
+  proxy:
+    external: true
+    name: proxy_default
+volumes:
+  pgdata:
+    external: true
+    name: db_pgdata
+`)}, nil),
+		"worker": composeLinkResources("worker", nil, nil),
+	}
+	manual := []database.ComposeDependency{
+		{Project: "worker", DependsOn: "shop"},
+		{Project: "worker", DependsOn: "gone"},
+	}
+	g := buildComposeGraph(resources, manual)
+	if len(g.Cycle) > 0 {
+		t.Fatalf("unexpected cycle: %v", g.Cycle)
+	}
+	if got := strings.Join(g.Order, ","); got != "db,proxy,shop,worker" {
+		t.Fatalf("order: %s", got)
+	}
+	if len(g.Edges) != 3 {
+		t.Fatalf("edges: %+v", g.Edges)
+	}
+
+	if got := strings.Join(composeGraphSelect(g, []string{"shop"}, "start"), ","); got != "db,proxy,shop" {
+		t.Fatalf("start selection: %s", got)
+	}
+	if got := strings.Join(composeGraphSelect(g, []string{"db"}, "stop"), ","); got != "worker,shop,db" {
+		t.Fatalf("stop selection: %s", got)
+	}
+	if got := strings.Join(composeGraphSelect(g, nil, "stop"), ","); got != "worker,shop,proxy,db" {
+		t.Fatalf("stop all: %s", got)
+	}
+
+	g = buildComposeGraph(resources, append(manual, database.ComposeDependency{Project: "db", DependsOn: "worker"}))
+	if got := strings.Join(g.Cycle, ","); got != "db,shop,worker" {
+		t.Fatalf("cycle: %s", got)
+	}
+}
+
+func TestComposeContainersReady(t *testing.T) {
+	ct := func(name, status string, running bool, exitCode int, health string, restart string) types.ContainerJSON {
+		state := &types.ContainerState{Status: status, Running: running, ExitCode: exitCode}
+		if health != "" {
+			state.Health = &types.Health{Status: health}
+		}
+		return types.ContainerJSON{ContainerJSONBase: &types.ContainerJSONBase{
+			Name:       "/" + name,
+			State:      state,
+			HostConfig: &container.HostConfig{RestartPolicy: container.RestartPolicy{Name: restart}},
+		}}
+	}
+
+	ready, waiting, err := composeContainersReady([]types.ContainerJSON{
+		ct("web", "running", true, 0, "", ""),
+		ct("db", "running", true, 0, "healthy", "always"),
+		ct("migrate", "exited", false, 0, "", ""),
+	})
+	if !ready || len(waiting) != 0 || err != nil {
+		t.Fatalf("expected ready: %v %v %v", ready, waiting, err)
+	}
+
+	ready, waiting, err = composeContainersReady([]types.ContainerJSON{
+		ct("db", "running", true, 0, "starting", "always"),
+		ct("api", "exited", false, 1, "", "always"),
+	})
+	if ready || err != nil || len(waiting) != 2 {
+		t.Fatalf("expected waiting: %v %v %v", ready, waiting, err)
+	}
+
+	if _, _, err := composeContainersReady([]types.ContainerJSON{ct("api", "exited", false, 1, "", "no")}); err == nil {
+		t.Fatal("failed container without restart policy should be an error")
+	}
+}
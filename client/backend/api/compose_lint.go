@@ -0,0 +1,850 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"dockerpanel/backend/pkg/docker"
+	"dockerpanel/backend/pkg/settings"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/gin-gonic/gin"
+	"gopkg.in/yaml.v3"
+)
+
+// composeDiagnostic 一条 compose 校验结果，Line / Column 从 1 开始，0 表示无法定位
+type composeDiagnostic struct {
+	Severity string `json:"severity"` // error / warning / info
+	Code     string `json:"code"`
+	Message  string `json:"message"`
+	Line     int    `json:"line"`
+	Column   int    `json:"column"`
+	Path     string `json:"path,omitempty"`
+}
+
+// composeLintOptions 校验所需的外部信息，缺省时跳过对应检查
+type composeLintOptions struct {
+	ProjectDir    string            // 项目目录，用于解析相对路径；为空时跳过相对路径检查
+	Env           map[string]string // 插值可用的变量（项目 .env）
+	CheckAbsPaths bool              // 是否检查绝对路径的绑定挂载源（面板运行在容器内时无法访问宿主机路径）
+	// PortUsage 返回主机端口占用，OwnContainers 为项目自身的容器名（其占用的端口不视为冲突）
+	PortUsage     func() (map[int]PortUsage, map[int]PortUsage, error)
+	OwnContainers map[string]bool
+	StatPath      func(string) error
+}
+
+var composeTopLevelKeys = map[string]bool{
+	"version": true, "name": true, "services": true, "networks": true, "volumes": true,
+	"configs": true, "secrets": true, "include": true,
+}
+
+var composeServiceKeys = map[string]bool{}
+
+func init() {
+	for _, k := range strings.Fields(`annotations attach blkio_config build cap_add cap_drop cgroup cgroup_parent command configs
+        container_name cpu_count cpu_percent cpu_period cpu_quota cpu_rt_period cpu_rt_runtime cpu_shares cpus cpuset
+        credential_spec depends_on deploy develop device_cgroup_rules devices dns dns_opt dns_search domainname entrypoint
+        env_file environment expose extends external_links extra_hosts gpus group_add healthcheck hostname image init ipc
+        isolation labels links logging mac_address mem_limit mem_reservation mem_swappiness memswap_limit network_mode
+        networks oom_kill_disable oom_score_adj pid pids_limit platform ports post_start pre_stop privileged profiles
+        pull_policy read_only restart runtime scale secrets security_opt shm_size stdin_open stop_grace_period stop_signal
+        storage_opt sysctls tmpfs tty ulimits user userns_mode uts volumes volumes_from working_dir`) {
+		composeServiceKeys[k] = true
+	}
+}
+
+var (
+	yamlErrorLinePattern  = regexp.MustCompile(`line (\d+)(?:, column (\d+))?:\s*(.*)`)
+	composeRestartPattern = regexp.MustCompile(`^(no|always|unless-stopped|on-failure(:\d+)?)$`)
+)
+
+type composePortBinding struct {
+	ip    string
+	proto string
+	node  *yaml.Node
+	svc   string
+}
+
+type composeLinter struct {
+	opts     composeLintOptions
+	diags    []composeDiagnostic
+	services map[string]*yaml.Node
+	networks map[string]bool
+	volumes  map[string]bool
+	ports    map[string]composePortBinding // proto/port -> 首次声明
+	tcpUsage map[int]PortUsage
+	udpUsage map[int]PortUsage
+	usageErr error
+	usageHit bool
+}
+
+func (l *composeLinter) add(severity string, code string, n *yaml.Node, path string, format string, args ...any) {
+	d := composeDiagnostic{Severity: severity, Code: code, Message: fmt.Sprintf(format, args...), Path: path}
+	if n != nil {
+		d.Line, d.Column = n.Line, n.Column
+	}
+	l.diags = append(l.diags, d)
+}
+
+// lintCompose 校验 compose 内容，返回按位置排序的诊断结果
+func lintCompose(content string, opts composeLintOptions) []composeDiagnostic {
+	l := &composeLinter{
+		opts:     opts,
+		services: make(map[string]*yaml.Node),
+		networks: map[string]bool{"default": true},
+		volumes:  make(map[string]bool),
+		ports:    make(map[string]composePortBinding),
+	}
+	if l.opts.StatPath == nil {
+		l.opts.StatPath = func(p string) error { _, err := os.Stat(p); return err }
+	}
+	l.run(content)
+	sort.SliceStable(l.diags, func(i, j int) bool {
+		if l.diags[i].Line != l.diags[j].Line {
+			return l.diags[i].Line < l.diags[j].Line
+		}
+		return l.diags[i].Column < l.diags[j].Column
+	})
+	return l.diags
+}
+
+func (l *composeLinter) run(content string) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal([]byte(content), &doc); err != nil {
+		l.addYAMLError(err)
+		return
+	}
+	if len(doc.Content) == 0 {
+		l.add("error", "empty", nil, "", "配置内容为空")
+		return
+	}
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		l.add("error", "schema", root, "", "顶层必须是映射（key: value）")
+		return
+	}
+
+	l.checkDuplicateKeys(root)
+	l.checkInterpolation(root)
+
+	var servicesNode *yaml.Node
+	hasInclude := false
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		k, v := root.Content[i], root.Content[i+1]
+		switch {
+		case k.Value == "version":
+			l.add("info", "obsolete-version", k, "version", "version 字段已废弃，Compose V2 会忽略它，可以删除")
+		case k.Value == "services":
+			servicesNode = v
+		case k.Value == "networks":
+			l.collectDeclared(v, l.networks, "networks")
+		case k.Value == "volumes":
+			l.collectDeclared(v, l.volumes, "volumes")
+		case k.Value == "include":
+			hasInclude = true
+		case composeTopLevelKeys[k.Value] || strings.HasPrefix(k.Value, "x-"):
+		default:
+			l.add("warning", "unknown-key", k, k.Value, "未知的顶层字段 %s", k.Value)
+		}
+	}
+
+	if servicesNode == nil || (servicesNode.Kind == yaml.MappingNode && len(servicesNode.Content) == 0) {
+		// 服务可以全部来自 include 的文件
+		if hasInclude {
+			return
+		}
+		l.add("error", "no-services", servicesNode, "services", "未定义任何服务（services）")
+		return
+	}
+	if servicesNode.Kind != yaml.MappingNode {
+		l.add("error", "schema", servicesNode, "services", "services 必须是映射")
+		return
+	}
+	for i := 0; i+1 < len(servicesNode.Content); i += 2 {
+		l.services[servicesNode.Content[i].Value] = servicesNode.Content[i+1]
+	}
+	for i := 0; i+1 < len(servicesNode.Content); i += 2 {
+		l.checkService(servicesNode.Content[i].Value, servicesNode.Content[i], servicesNode.Content[i+1])
+	}
+}
+
+// addYAMLError 从 yaml.v3 的错误信息中提取行列号
+func (l *composeLinter) addYAMLError(err error) {
+	matches := yamlErrorLinePattern.FindAllStringSubmatch(err.Error(), -1)
+	if len(matches) == 0 {
+		l.add("error", "yaml", nil, "", "YAML 解析失败: %v", err)
+		return
+	}
+	for _, m := range matches {
+		line, _ := strconv.Atoi(m[1])
+		col, _ := strconv.Atoi(m[2])
+		l.diags = append(l.diags, composeDiagnostic{
+			Severity: "error", Code: "yaml", Message: "YAML 语法错误: " + strings.TrimSpace(m[3]), Line: line, Column: col,
+		})
+	}
+}
+
+func (l *composeLinter) collectDeclared(n *yaml.Node, into map[string]bool, path string) {
+	if n.Kind == yaml.ScalarNode && n.Tag == "!!null" {
+		return
+	}
+	if n.Kind != yaml.MappingNode {
+		l.add("error", "schema", n, path, "%s 必须是映射", path)
+		return
+	}
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		into[n.Content[i].Value] = true
+	}
+}
+
+// checkDuplicateKeys 解析为 yaml.Node 时不会报告重复的键，这里单独检查（重复时 compose 会拒绝加载）
+func (l *composeLinter) checkDuplicateKeys(n *yaml.Node) {
+	if n.Kind == yaml.MappingNode {
+		seen := make(map[string]*yaml.Node)
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			k := n.Content[i]
+			if prev, ok := seen[k.Value]; ok && k.Kind == yaml.ScalarNode && k.Value != "<<" {
+				l.add("error", "duplicate-key", k, "", "键 %s 重复定义（首次出现在第 %d 行）", k.Value, prev.Line)
+			}
+			seen[k.Value] = k
+		}
+	}
+	for _, child := range n.Content {
+		l.checkDuplicateKeys(child)
+	}
+}
+
+// composeVarUse 一处变量引用
+type composeVarUse struct {
+	Name      string
+	Op        string // "" / ":-" / "-" / ":?" / "?" / ":+" / "+"
+	Offset    int
+	Raw       string
+	Malformed bool
+}
+
+// scanComposeVarRefs 找出字符串中的 $VAR 与 ${VAR...} 引用，$$ 为转义
+func scanComposeVarRefs(s string) []composeVarUse {
+	var out []composeVarUse
+	for i := 0; i < len(s); i++ {
+		if s[i] != '$' || i+1 >= len(s) {
+			continue
+		}
+		switch next := s[i+1]; {
+		case next == '$':
+			i++
+		case next == '{':
+			end := strings.IndexByte(s[i+2:], '}')
+			if end < 0 {
+				out = append(out, composeVarUse{Offset: i, Raw: s[i:], Malformed: true})
+				return out
+			}
+			inner := s[i+2 : i+2+end]
+			use := composeVarUse{Name: inner, Offset: i, Raw: s[i : i+3+end]}
+			// 以第一个运算符为准：:- :? :+ - ? +
+			if idx := strings.IndexAny(inner, ":-?+"); idx > 0 {
+				use.Name, use.Op = inner[:idx], inner[idx:idx+1]
+				if inner[idx] == ':' && idx+1 < len(inner) && strings.ContainsRune("-?+", rune(inner[idx+1])) {
+					use.Op = inner[idx : idx+2]
+				}
+			}
+			use.Malformed = !isLikelyEnvKey(use.Name) || use.Op == ":"
+			out = append(out, use)
+			i += 2 + end
+		case (next >= 'A' && next <= 'Z') || (next >= 'a' && next <= 'z') || next == '_':
+			j := i + 1
+			for j < len(s) && (s[j] == '_' || (s[j] >= 'A' && s[j] <= 'Z') || (s[j] >= 'a' && s[j] <= 'z') || (s[j] >= '0' && s[j] <= '9')) {
+				j++
+			}
+			out = append(out, composeVarUse{Name: s[i+1 : j], Offset: i, Raw: s[i:j]})
+			i = j - 1
+		}
+	}
+	return out
+}
+
+// interpolateCompose 按 compose 规则替换变量，用于检查端口、路径等需要实际值的字段
+func interpolateCompose(s string, env map[string]string) string {
+	uses := scanComposeVarRefs(s)
+	if len(uses) == 0 {
+		return strings.ReplaceAll(s, "$$", "$")
+	}
+	var b strings.Builder
+	last := 0
+	for _, u := range uses {
+		b.WriteString(strings.ReplaceAll(s[last:u.Offset], "$$", "$"))
+		last = u.Offset + len(u.Raw)
+		if u.Malformed {
+			b.WriteString(u.Raw)
+			continue
+		}
+		val, set := env[u.Name]
+		arg := ""
+		if u.Op != "" {
+			arg = u.Raw[2+len(u.Name)+len(u.Op) : len(u.Raw)-1]
+		}
+		switch u.Op {
+		case ":-":
+			if val == "" {
+				val = arg
+			}
+		case "-":
+			if !set {
+				val = arg
+			}
+		case ":+":
+			if val != "" {
+				val = arg
+			}
+		case "+":
+			if set {
+				val = arg
+			}
+		}
+		b.WriteString(val)
+	}
+	b.WriteString(strings.ReplaceAll(s[last:], "$$", "$"))
+	return b.String()
+}
+
+// checkInterpolation 检查所有标量中的变量引用是否能在 .env 中找到
+func (l *composeLinter) checkInterpolation(n *yaml.Node) {
+	if n.Kind == yaml.ScalarNode {
+		for _, u := range scanComposeVarRefs(n.Value) {
+			pos := &yaml.Node{Line: n.Line, Column: n.Column}
+			if !strings.Contains(n.Value, "\n") && (n.Style == 0 || n.Style == yaml.DoubleQuotedStyle || n.Style == yaml.SingleQuotedStyle) {
+				pos.Column += u.Offset
+				if n.Style != 0 {
+					pos.Column++
+				}
+			}
+			_, defined := l.opts.Env[u.Name]
+			switch {
+			case u.Malformed:
+				l.add("error", "invalid-interpolation", pos, "", "无效的变量引用 %s", u.Raw)
+			case defined || u.Op == "-" || u.Op == ":-" || u.Op == "+" || u.Op == ":+":
+			case u.Op == "?" || u.Op == ":?":
+				l.add("error", "required-variable", pos, "", "必需的变量 %s 未在 .env 中定义", u.Name)
+			default:
+				l.add("warning", "undefined-variable", pos, "", "变量 %s 未在 .env 中定义，将被替换为空字符串", u.Name)
+			}
+		}
+		return
+	}
+	for _, child := range n.Content {
+		l.checkInterpolation(child)
+	}
+}
+
+func (l *composeLinter) checkService(name string, keyNode *yaml.Node, svc *yaml.Node) {
+	path := "services." + name
+	if svc.Kind != yaml.MappingNode {
+		l.add("error", "schema", svc, path, "服务 %s 的定义必须是映射", name)
+		return
+	}
+	fields := make(map[string]*yaml.Node)
+	for i := 0; i+1 < len(svc.Content); i += 2 {
+		k := svc.Content[i]
+		fields[k.Value] = svc.Content[i+1]
+		if !composeServiceKeys[k.Value] && !strings.HasPrefix(k.Value, "x-") {
+			l.add("warning", "unknown-key", k, path+"."+k.Value, "服务 %s 中存在未知字段 %s", name, k.Value)
+		}
+	}
+
+	if fields["image"] == nil && fields["build"] == nil && fields["extends"] == nil {
+		l.add("error", "missing-image", keyNode, path, "服务 %s 必须指定 image 或 build", name)
+	}
+	if img := fields["image"]; img != nil {
+		l.checkImage(name, img, path+".image")
+	}
+	if r := fields["restart"]; r != nil {
+		if v := interpolateCompose(r.Value, l.opts.Env); !composeRestartPattern.MatchString(v) {
+			l.add("error", "invalid-restart", r, path+".restart", "restart 取值应为 no / always / on-failure[:次数] / unless-stopped")
+		}
+	} else if mappingGetValue(fields["deploy"], "restart_policy") == nil {
+		l.add("info", "no-restart", keyNode, path, "服务 %s 未设置 restart，宿主机重启或进程退出后不会自动恢复", name)
+	}
+	if p := fields["privileged"]; p != nil && p.Value == "true" {
+		l.add("warning", "privileged", p, path+".privileged", "服务 %s 以特权模式运行，拥有宿主机的全部设备权限", name)
+	}
+	if cn := fields["container_name"]; cn != nil {
+		l.add("info", "container-name", cn, path+".container_name", "设置 container_name 后该服务无法扩容为多个副本")
+	}
+
+	networkMode := ""
+	if nm := fields["network_mode"]; nm != nil {
+		networkMode = interpolateCompose(nm.Value, l.opts.Env)
+		if target, ok := strings.CutPrefix(networkMode, "service:"); ok && l.services[target] == nil {
+			l.add("error", "undefined-service", nm, path+".network_mode", "network_mode 引用的服务 %s 不存在", target)
+		}
+		if fields["networks"] != nil {
+			l.add("error", "schema", nm, path+".network_mode", "network_mode 与 networks 不能同时使用")
+		}
+	}
+	if ports := fields["ports"]; ports != nil {
+		if networkMode == "host" {
+			l.add("warning", "ports-ignored", ports, path+".ports", "network_mode: host 下 ports 映射不会生效")
+		}
+		l.checkPorts(name, ports, path+".ports")
+	}
+	if vols := fields["volumes"]; vols != nil {
+		l.checkVolumes(name, vols, path+".volumes")
+	}
+	if nets := fields["networks"]; nets != nil {
+		for _, ref := range composeNameRefs(nets) {
+			if !l.networks[ref.Value] {
+				l.add("error", "undefined-network", ref, path+".networks", "网络 %s 未在顶层 networks 中声明", ref.Value)
+			}
+		}
+	}
+	if deps := fields["depends_on"]; deps != nil {
+		for _, ref := range composeNameRefs(deps) {
+			switch {
+			case ref.Value == name:
+				l.add("error", "self-dependency", ref, path+".depends_on", "服务 %s 不能依赖自身", name)
+			case l.services[ref.Value] == nil:
+				l.add("error", "undefined-service", ref, path+".depends_on", "依赖的服务 %s 不存在", ref.Value)
+			}
+		}
+	}
+	if envFile := fields["env_file"]; envFile != nil {
+		l.checkEnvFiles(envFile, path+".env_file")
+	}
+	if env := fields["environment"]; env != nil && env.Kind != yaml.MappingNode && env.Kind != yaml.SequenceNode {
+		l.add("error", "schema", env, path+".environment", "environment 必须是映射或列表")
+	}
+}
+
+// composeNameRefs 兼容列表与映射两种写法，返回引用的名称节点
+func composeNameRefs(n *yaml.Node) []*yaml.Node {
+	var out []*yaml.Node
+	switch n.Kind {
+	case yaml.SequenceNode:
+		for _, it := range n.Content {
+			if it.Kind == yaml.ScalarNode {
+				out = append(out, it)
+			}
+		}
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			out = append(out, n.Content[i])
+		}
+	}
+	return out
+}
+
+func (l *composeLinter) checkImage(name string, n *yaml.Node, path string) {
+	ref := interpolateCompose(n.Value, l.opts.Env)
+	if ref == "" {
+		l.add("error", "missing-image", n, path, "服务 %s 的 image 为空", name)
+		return
+	}
+	if strings.Contains(ref, "@") {
+		return
+	}
+	tag := ""
+	if idx := strings.LastIndex(ref, ":"); idx > strings.LastIndex(ref, "/") {
+		tag = ref[idx+1:]
+	}
+	if tag == "" || tag == "latest" {
+		l.add("warning", "image-latest", n, path, "镜像 %s 未固定版本，重新拉取时可能引入不兼容的变更", ref)
+	}
+}
+
+// parseComposePortSpec 解析端口短语法 [ip:]host[-range]:container[-range][/proto]，返回主机端口列表
+func parseComposePortSpec(spec string) (ip string, hostPorts []int, proto string, err error) {
+	proto = "tcp"
+	if s, p, ok := strings.Cut(spec, "/"); ok {
+		spec, proto = s, strings.ToLower(p)
+	}
+	if proto != "tcp" && proto != "udp" && proto != "sctp" {
+		return "", nil, "", fmt.Errorf("不支持的协议 %s", proto)
+	}
+	if strings.HasPrefix(spec, "[") {
+		end := strings.Index(spec, "]")
+		if end < 0 {
+			return "", nil, "", fmt.Errorf("IPv6 地址缺少 ]")
+		}
+		ip, spec = spec[1:end], strings.TrimPrefix(spec[end+1:], ":")
+	}
+	parts := strings.Split(spec, ":")
+	switch len(parts) {
+	case 1:
+		_, err = parseComposePortRange(parts[0])
+		return ip, nil, proto, err
+	case 2:
+	case 3:
+		if ip != "" {
+			return "", nil, "", fmt.Errorf("端口格式不正确")
+		}
+		ip, parts = parts[0], parts[1:]
+	default:
+		return "", nil, "", fmt.Errorf("端口格式不正确")
+	}
+	if _, err = parseComposePortRange(parts[1]); err != nil {
+		return "", nil, "", err
+	}
+	if parts[0] == "" {
+		return ip, nil, proto, nil
+	}
+	hostPorts, err = parseComposePortRange(parts[0])
+	return ip, hostPorts, proto, err
+}
+
+// composePortCheckMaxRange 端口范围超过该数量时不再逐个检查冲突
+const composePortCheckMaxRange = 1000
+
+func parseComposePortRange(s string) ([]int, error) {
+	lo, hi, isRange := strings.Cut(strings.TrimSpace(s), "-")
+	start, err := strconv.Atoi(lo)
+	if err != nil || start < 1 || start > 65535 {
+		return nil, fmt.Errorf("无效的端口 %s", s)
+	}
+	end := start
+	if isRange {
+		if end, err = strconv.Atoi(hi); err != nil || end < start || end > 65535 {
+			return nil, fmt.Errorf("无效的端口范围 %s", s)
+		}
+	}
+	out := make([]int, 0, end-start+1)
+	for p := start; p <= end; p++ {
+		out = append(out, p)
+	}
+	return out, nil
+}
+
+func (l *composeLinter) checkPorts(svc string, ports *yaml.Node, path string) {
+	if ports.Kind != yaml.SequenceNode {
+		l.add("error", "schema", ports, path, "ports 必须是列表")
+		return
+	}
+	for i, item := range ports.Content {
+		itemPath := fmt.Sprintf("%s[%d]", path, i)
+		var (
+			ip, proto string
+			hostPorts []int
+			err       error
+		)
+		switch item.Kind {
+		case yaml.ScalarNode:
+			ip, hostPorts, proto, err = parseComposePortSpec(interpolateCompose(item.Value, l.opts.Env))
+		case yaml.MappingNode:
+			proto = "tcp"
+			if p := mappingGetValue(item, "protocol"); p != nil {
+				proto = strings.ToLower(interpolateCompose(p.Value, l.opts.Env))
+			}
+			if h := mappingGetValue(item, "host_ip"); h != nil {
+				ip = interpolateCompose(h.Value, l.opts.Env)
+			}
+			if t := mappingGetValue(item, "target"); t == nil {
+				err = fmt.Errorf("缺少 target")
+			} else if _, err = parseComposePortRange(interpolateCompose(t.Value, l.opts.Env)); err == nil {
+				if pub := mappingGetValue(item, "published"); pub != nil && pub.Value != "" {
+					hostPorts, err = parseComposePortRange(interpolateCompose(pub.Value, l.opts.Env))
+				}
+			}
+		default:
+			err = fmt.Errorf("端口必须是字符串或映射")
+		}
+		if err != nil {
+			l.add("error", "invalid-port", item, itemPath, "端口配置无效: %v", err)
+			continue
+		}
+		if len(hostPorts) > composePortCheckMaxRange {
+			l.add("info", "port-check-skipped", item, itemPath, "端口范围包含 %d 个端口，已跳过逐个端口的冲突检查", len(hostPorts))
+			continue
+		}
+		for _, p := range hostPorts {
+			l.checkHostPort(svc, ip, proto, p, item, itemPath)
+		}
+	}
+}
+
+func composeWildcardIP(ip string) bool {
+	return ip == "" || ip == "0.0.0.0" || ip == "::"
+}
+
+func (l *composeLinter) checkHostPort(svc string, ip string, proto string, port int, n *yaml.Node, path string) {
+	key := fmt.Sprintf("%s/%d", proto, port)
+	if prev, ok := l.ports[key]; ok && (composeWildcardIP(ip) || composeWildcardIP(prev.ip) || ip == prev.ip) {
+		l.add("error", "duplicate-port", n, path, "主机端口 %d/%s 已被服务 %s（第 %d 行）使用", port, proto, prev.svc, prev.node.Line)
+		return
+	}
+	l.ports[key] = composePortBinding{ip: ip, proto: proto, node: n, svc: svc}
+
+	if l.opts.PortUsage == nil || proto == "sctp" {
+		return
+	}
+	if !l.usageHit {
+		l.usageHit = true
+		l.tcpUsage, l.udpUsage, l.usageErr = l.opts.PortUsage()
+		if l.usageErr != nil {
+			l.add("info", "port-check-skipped", nil, "", "无法获取主机端口占用，已跳过端口冲突检查: %v", l.usageErr)
+		}
+	}
+	if l.usageErr != nil {
+		return
+	}
+	usage := l.tcpUsage[port]
+	if proto == "udp" {
+		usage = l.udpUsage[port]
+	}
+	if !usage.Used || (usage.Type == "Container" && l.opts.OwnContainers[usage.ServiceName]) {
+		return
+	}
+	owner := usage.ServiceName
+	if owner == "" {
+		owner = "未知进程"
+	}
+	if usage.ServiceName == "docker-proxy" {
+		l.add("warning", "port-conflict", n, path, "主机端口 %d/%s 已被其它容器占用，如属于本项目可忽略", port, proto)
+		return
+	}
+	kind := "宿主机进程"
+	if usage.Type == "Container" {
+		kind = "容器"
+	}
+	l.add("error", "port-conflict", n, path, "主机端口 %d/%s 已被%s %s 占用", port, proto, kind, owner)
+}
+
+func (l *composeLinter) checkVolumes(svc string, vols *yaml.Node, path string) {
+	if vols.Kind != yaml.SequenceNode {
+		l.add("error", "schema", vols, path, "volumes 必须是列表")
+		return
+	}
+	for i, item := range vols.Content {
+		itemPath := fmt.Sprintf("%s[%d]", path, i)
+		var source, kind string
+		longSyntax := false
+		switch item.Kind {
+		case yaml.ScalarNode:
+			parts := strings.Split(interpolateCompose(item.Value, l.opts.Env), ":")
+			if len(parts) < 2 {
+				continue // 匿名卷
+			}
+			source = parts[0]
+			kind = "volume"
+			if strings.HasPrefix(source, "/") || strings.HasPrefix(source, ".") || strings.HasPrefix(source, "~") {
+				kind = "bind"
+			}
+		case yaml.MappingNode:
+			longSyntax = true
+			if t := mappingGetValue(item, "type"); t != nil {
+				kind = t.Value
+			}
+			if s := mappingGetValue(item, "source"); s != nil {
+				source = interpolateCompose(s.Value, l.opts.Env)
+			}
+			if mappingGetValue(item, "target") == nil {
+				l.add("error", "schema", item, itemPath, "挂载缺少 target")
+				continue
+			}
+		default:
+			l.add("error", "schema", item, itemPath, "挂载必须是字符串或映射")
+			continue
+		}
+
+		switch kind {
+		case "volume":
+			if source != "" && !l.volumes[source] {
+				l.add("error", "undefined-volume", item, itemPath, "卷 %s 未在顶层 volumes 中声明", source)
+			}
+		case "bind":
+			if strings.HasSuffix(source, "docker.sock") {
+				l.add("warning", "docker-socket", item, itemPath, "服务 %s 挂载了 Docker socket，等同于授予宿主机 root 权限", svc)
+			}
+			l.checkBindSource(source, item, itemPath, longSyntax)
+		}
+	}
+}
+
+// checkBindSource 检查绑定挂载源是否存在：短语法下 Docker 会自动创建空目录（警告），长语法会直接报错
+func (l *composeLinter) checkBindSource(source string, n *yaml.Node, path string, longSyntax bool) {
+	if source == "" || strings.HasPrefix(source, "~") {
+		return
+	}
+	full := source
+	if !filepath.IsAbs(source) {
+		if l.opts.ProjectDir == "" {
+			return
+		}
+		full = filepath.Join(l.opts.ProjectDir, source)
+	} else if !l.opts.CheckAbsPaths {
+		return
+	}
+	if l.opts.StatPath(full) == nil {
+		return
+	}
+	if longSyntax {
+		l.add("error", "missing-bind-source", n, path, "绑定挂载的源路径 %s 不存在", source)
+		return
+	}
+	l.add("warning", "missing-bind-source", n, path, "绑定挂载的源路径 %s 不存在，启动时将被创建为空目录", source)
+}
+
+func (l *composeLinter) checkEnvFiles(n *yaml.Node, path string) {
+	var items []*yaml.Node
+	switch n.Kind {
+	case yaml.ScalarNode:
+		items = []*yaml.Node{n}
+	case yaml.SequenceNode:
+		items = n.Content
+	default:
+		l.add("error", "schema", n, path, "env_file 必须是字符串或列表")
+		return
+	}
+	for _, it := range items {
+		file, required := it.Value, true
+		if it.Kind == yaml.MappingNode {
+			if p := mappingGetValue(it, "path"); p != nil {
+				file = p.Value
+			}
+			if r := mappingGetValue(it, "required"); r != nil && r.Value == "false" {
+				required = false
+			}
+		}
+		file = interpolateCompose(file, l.opts.Env)
+		if file == "" || !required {
+			continue
+		}
+		full := file
+		if !filepath.IsAbs(file) {
+			if l.opts.ProjectDir == "" {
+				continue
+			}
+			full = filepath.Join(l.opts.ProjectDir, file)
+		} else if !l.opts.CheckAbsPaths {
+			continue
+		}
+		if l.opts.StatPath(full) != nil {
+			l.add("error", "missing-env-file", it, path, "env_file %s 不存在", file)
+		}
+	}
+}
+
+func composeDiagnosticsHaveErrors(diags []composeDiagnostic) bool {
+	for _, d := range diags {
+		if d.Severity == "error" {
+			return true
+		}
+	}
+	return false
+}
+
+// respondComposeDiagnostics 校验存在错误时返回 400 与全部诊断结果
+func respondComposeDiagnostics(c *gin.Context, diags []composeDiagnostic) {
+	c.JSON(http.StatusBadRequest, gin.H{
+		"code":        errorCodeFromStatus(http.StatusBadRequest),
+		"message":     "Compose 配置校验失败",
+		"error":       "Compose 配置校验失败，请根据诊断信息修改后重试",
+		"diagnostics": diags,
+	})
+}
+
+// composeLintOptionsForProject 构造项目的校验选项：读取项目 .env（dotenv 非空时以其为准）、项目自身容器与主机端口占用
+func composeLintOptionsForProject(ctx context.Context, project string, projectDir string, env map[string]string) composeLintOptions {
+	opts := composeLintOptions{
+		ProjectDir:    projectDir,
+		Env:           env,
+		CheckAbsPaths: settings.GetHostProjectRoot() == "",
+		PortUsage:     gatherDetailedPortUsage,
+		OwnContainers: make(map[string]bool),
+	}
+	if project == "" {
+		return opts
+	}
+	cli, err := docker.NewDockerClient()
+	if err != nil {
+		return opts
+	}
+	defer cli.Close()
+	list, err := cli.ContainerList(ctx, types.ContainerListOptions{
+		All:     true,
+		Filters: filters.NewArgs(filters.Arg("label", "com.docker.compose.project="+project)),
+	})
+	if err == nil {
+		for _, ct := range list {
+			opts.OwnContainers[containerDisplayName(ct)] = true
+		}
+	}
+	return opts
+}
+
+// readProjectDotenv 读取项目的 env 文件（默认 .env），不存在时返回空
+func readProjectDotenv(projectDir string) map[string]string {
+	files := composeOptionsForDir(projectDir).EnvFiles
+	if len(files) == 0 {
+		files = []string{".env"}
+	}
+	// 多个 env 文件时后面的覆盖前面的，与 docker compose 一致
+	env := map[string]string{}
+	for _, f := range files {
+		raw, err := os.ReadFile(filepath.Join(projectDir, f))
+		if err != nil {
+			continue
+		}
+		for k, v := range parseDotenvToMap(strings.ReplaceAll(string(raw), "\r\n", "\n")) {
+			env[k] = v
+		}
+	}
+	return env
+}
+
+type composeValidateRequest struct {
+	Name    string  `json:"name"`    // 已有项目名，可选；指定后按项目目录解析相对路径并读取 .env
+	Content string  `json:"content"` // compose YAML
+	Dotenv  *string `json:"dotenv"`  // 覆盖项目 .env 内容
+}
+
+// validateCompose 校验 compose 配置，返回带行列号的诊断结果，供编辑器标注
+func validateCompose(c *gin.Context) {
+	var req composeValidateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, "无效的请求参数", err)
+		return
+	}
+
+	projectDir := ""
+	project := ""
+	if raw := strings.TrimSpace(req.Name); raw != "" {
+		name, ok := validateComposeProjectName(raw)
+		if !ok {
+			respondError(c, http.StatusBadRequest, "项目名不合法：仅支持小写字母/数字，且可包含 _ -，并以字母或数字开头", nil)
+			return
+		}
+		project = name
+		if dir := filepath.Join(getProjectsBaseDir(), name); dirExists(dir) {
+			projectDir = dir
+		}
+	}
+	env := map[string]string{}
+	if req.Dotenv != nil {
+		env = parseDotenvToMap(strings.ReplaceAll(*req.Dotenv, "\r\n", "\n"))
+	} else if projectDir != "" {
+		env = readProjectDotenv(projectDir)
+	}
+
+	diags := relaxCrossFileDiagnostics(lintCompose(req.Content, composeLintOptionsForProject(c.Request.Context(), project, projectDir, env)), composeOptionsForDir(projectDir))
+	counts := map[string]int{"error": 0, "warning": 0, "info": 0}
+	for _, d := range diags {
+		counts[d.Severity]++
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"valid":       counts["error"] == 0,
+		"diagnostics": diags,
+		"summary":     counts,
+	})
+}
+
+func dirExists(path string) bool {
+	st, err := os.Stat(path)
+	return err == nil && st.IsDir()
+}
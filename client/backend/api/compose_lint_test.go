@@ -0,0 +1,243 @@
+package api
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func findDiagnostic(diags []composeDiagnostic, code string) *composeDiagnostic {
+	for i := range diags {
+		if diags[i].Code == code {
+			return &diags[i]
+		}
+	}
+	return nil
+}
+
+func TestLintComposeYAMLError(t *testing.T) {
+	diags := lintCompose("services:\n  web:\n    image: nginx\n    command: @start\n", composeLintOptions{})
+	d := findDiagnostic(diags, "yaml")
+	if d == nil || d.Line != 4 || d.Severity != "error" {
+		t.Fatalf("expected yaml error on line 4, got %+v", diags)
+	}
+
+	diags = lintCompose("services:\n  web:\n    image: a:1\n    image: b:1\n", composeLintOptions{})
+	if d := findDiagnostic(diags, "duplicate-key"); d == nil || d.Line != 4 || d.Severity != "error" {
+		t.Fatalf("duplicate key should be reported on line 4, got %+v", diags)
+	}
+}
+
+func TestLintComposeSchemaAndReferences(t *testing.T) {
+	content := `version: "3.8"
+services:
+  web:
+    image: nginx
+    restart: sometimes
+    depends_on: [db, cache]
+    networks: [front, back]
+    volumes:
+      - data:/data
+      - logs:/var/log
+    colour: blue
+  db:
+    restart: always
+    network_mode: service:missing
+volumes:
+  data:
+networks:
+  front:
+`
+	diags := lintCompose(content, composeLintOptions{})
+	want := map[string]int{ // code -> line
+		"obsolete-version":  1,
+		"image-latest":      4,
+		"invalid-restart":   5,
+		"undefined-service": 6,
+		"undefined-network": 7,
+		"undefined-volume":  10,
+		"unknown-key":       11,
+		"missing-image":     12,
+	}
+	for code, line := range want {
+		d := findDiagnostic(diags, code)
+		if d == nil || d.Line != line {
+			t.Errorf("%s: expected line %d, got %+v", code, line, d)
+		}
+	}
+	if d := findDiagnostic(diags, "undefined-network"); d == nil || !strings.Contains(d.Message, "back") {
+		t.Errorf("undefined network should mention back: %+v", d)
+	}
+	for i := 1; i < len(diags); i++ {
+		if diags[i].Line < diags[i-1].Line {
+			t.Fatal("diagnostics should be sorted by line")
+		}
+	}
+}
+
+func TestLintComposeInterpolation(t *testing.T) {
+	content := "services:\n  app:\n    image: \"app:${TAG}\"\n    environment:\n      A: ${DEFINED}\n      B: ${WITH_DEFAULT:-x}\n      C: ${REQUIRED:?must be set}\n      D: $$ESCAPED\n      E: ${BAD\n"
+	diags := lintCompose(content, composeLintOptions{Env: map[string]string{"DEFINED": "1"}})
+
+	d := findDiagnostic(diags, "undefined-variable")
+	if d == nil || d.Line != 3 || d.Column != 17 || !strings.Contains(d.Message, "TAG") {
+		t.Fatalf("undefined TAG at 3:17, got %+v", d)
+	}
+	if d := findDiagnostic(diags, "required-variable"); d == nil || d.Line != 7 || d.Severity != "error" {
+		t.Fatalf("required variable, got %+v", d)
+	}
+	if d := findDiagnostic(diags, "invalid-interpolation"); d == nil || d.Line != 9 {
+		t.Fatalf("malformed reference, got %+v", d)
+	}
+	count := 0
+	for _, d := range diags {
+		if d.Code == "undefined-variable" {
+			count++
+		}
+	}
+	if count != 1 {
+		t.Fatalf("only TAG should be undefined, got %d: %+v", count, diags)
+	}
+}
+
+func TestInterpolateCompose(t *testing.T) {
+	env := map[string]string{"PORT": "8080", "EMPTY": ""}
+	cases := map[string]string{
+		"${PORT}:80":          "8080:80",
+		"${MISSING:-9000}:80": "9000:80",
+		"${EMPTY:-1}":         "1",
+		"${EMPTY-1}":          "",
+		"${PORT:+on}":         "on",
+		"$PORT/$$HOME":        "8080/$HOME",
+	}
+	for in, want := range cases {
+		if got := interpolateCompose(in, env); got != want {
+			t.Errorf("interpolateCompose(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestParseComposePortSpec(t *testing.T) {
+	ip, ports, proto, err := parseComposePortSpec("127.0.0.1:8080-8081:80-81/udp")
+	if err != nil || ip != "127.0.0.1" || proto != "udp" || len(ports) != 2 || ports[1] != 8081 {
+		t.Fatalf("got %q %v %q %v", ip, ports, proto, err)
+	}
+	if _, ports, _, err := parseComposePortSpec("80"); err != nil || ports != nil {
+		t.Fatalf("container-only port: %v %v", ports, err)
+	}
+	if ip, ports, _, err := parseComposePortSpec("[::1]:53:53"); err != nil || ip != "::1" || ports[0] != 53 {
+		t.Fatalf("ipv6: %q %v %v", ip, ports, err)
+	}
+	for _, bad := range []string{"0:80", "70000:80", "a:b", "80:80/icmp", "1:2:3:4"} {
+		if _, _, _, err := parseComposePortSpec(bad); err == nil {
+			t.Errorf("%q should be rejected", bad)
+		}
+	}
+}
+
+func TestLintComposePorts(t *testing.T) {
+	content := `services:
+  a:
+    image: a:1
+    ports:
+      - "${PORT}:80"
+      - "9000:90"
+      - target: 53
+        published: 5353
+        protocol: udp
+  b:
+    image: b:1
+    ports:
+      - "8080:8080"
+      - "7000:70"
+`
+	usage := func() (map[int]PortUsage, map[int]PortUsage, error) {
+		return map[int]PortUsage{
+			9000: {Used: true, Type: "Host", ServiceName: "nginx"},
+			7000: {Used: true, Type: "Container", ServiceName: "proj-b-1"},
+		}, map[int]PortUsage{
+			5353: {Used: true, Type: "Host", ServiceName: "avahi"},
+		}, nil
+	}
+	diags := lintCompose(content, composeLintOptions{
+		Env:           map[string]string{"PORT": "8080"},
+		PortUsage:     usage,
+		OwnContainers: map[string]bool{"proj-b-1": true},
+	})
+	var conflicts []int
+	for _, d := range diags {
+		if d.Code == "port-conflict" {
+			conflicts = append(conflicts, d.Line)
+		}
+	}
+	if len(conflicts) != 2 || conflicts[0] != 6 || conflicts[1] != 7 {
+		t.Fatalf("expected conflicts on lines 6 and 7, got %v (%+v)", conflicts, diags)
+	}
+	if d := findDiagnostic(diags, "duplicate-port"); d == nil || d.Line != 13 {
+		t.Fatalf("expected duplicate port on line 13, got %+v", d)
+	}
+
+	diags = lintCompose(content, composeLintOptions{
+		Env:       map[string]string{"PORT": "8080"},
+		PortUsage: func() (map[int]PortUsage, map[int]PortUsage, error) { return nil, nil, errors.New("netstat missing") },
+	})
+	if findDiagnostic(diags, "port-check-skipped") == nil || findDiagnostic(diags, "port-conflict") != nil {
+		t.Fatalf("port check should be skipped: %+v", diags)
+	}
+
+	// 大端口范围是合法配置，只跳过逐个端口的检查
+	diags = lintCompose("services:\n  rtp:\n    image: rtp:1\n    ports:\n      - \"10000-20000:10000-20000/udp\"\n", composeLintOptions{PortUsage: usage})
+	if composeDiagnosticsHaveErrors(diags) {
+		t.Fatalf("large port range should not be an error: %+v", diags)
+	}
+	if d := findDiagnostic(diags, "port-check-skipped"); d == nil || d.Severity != "info" || d.Line != 5 {
+		t.Fatalf("expected skipped check on line 5, got %+v", diags)
+	}
+}
+
+func TestLintComposeIncludeOnly(t *testing.T) {
+	diags := lintCompose("include:\n  - ./db/compose.yml\n  - ./web/compose.yml\n", composeLintOptions{})
+	if d := findDiagnostic(diags, "no-services"); d != nil {
+		t.Fatalf("include-only file should not report no-services: %+v", d)
+	}
+	if diags := lintCompose("x-common: {}\n", composeLintOptions{}); findDiagnostic(diags, "no-services") == nil {
+		t.Fatal("file without services or include should report no-services")
+	}
+}
+
+func TestLintComposeBindMountsAndEnvFiles(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "conf"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	content := `services:
+  app:
+    image: app:1
+    env_file: [app.env]
+    volumes:
+      - ./conf:/etc/app
+      - ./missing:/data
+      - type: bind
+        source: ./gone
+        target: /gone
+      - /var/run/docker.sock:/var/run/docker.sock
+`
+	diags := lintCompose(content, composeLintOptions{ProjectDir: dir})
+	var missing []composeDiagnostic
+	for _, d := range diags {
+		if d.Code == "missing-bind-source" {
+			missing = append(missing, d)
+		}
+	}
+	if len(missing) != 2 || missing[0].Severity != "warning" || missing[1].Severity != "error" || missing[1].Line != 8 {
+		t.Fatalf("bind mount diagnostics: %+v", missing)
+	}
+	if d := findDiagnostic(diags, "missing-env-file"); d == nil || d.Line != 4 {
+		t.Fatalf("env file: %+v", d)
+	}
+	if findDiagnostic(diags, "docker-socket") == nil {
+		t.Fatal("docker socket mount should warn")
+	}
+}
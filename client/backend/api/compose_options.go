@@ -0,0 +1,328 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"dockerpanel/backend/pkg/database"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	composeOptionsMaxFiles = 10
+	composeOptionsTimeout  = 30 * time.Second
+)
+
+// 与 docker compose 对 profile 名的要求一致
+var composeProfilePattern = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.-]*$`)
+
+// composeProjectNameForDir 项目目录位于项目根目录下时返回项目名
+func composeProjectNameForDir(projectDir string) (string, bool) {
+	rel, err := filepath.Rel(getProjectsBaseDir(), projectDir)
+	if err != nil || rel == "." || rel == ".." || strings.ContainsRune(rel, filepath.Separator) {
+		return "", false
+	}
+	return validateComposeProjectName(rel)
+}
+
+// composeOptionsForDir 读取项目目录对应的 compose 参数，未配置或读取失败时返回空配置
+func composeOptionsForDir(projectDir string) database.ComposeProjectOptions {
+	name, ok := composeProjectNameForDir(projectDir)
+	if !ok || database.GetDB() == nil {
+		return database.ComposeProjectOptions{}
+	}
+	o, err := database.GetComposeProjectOptions(name)
+	if err != nil {
+		return database.ComposeProjectOptions{}
+	}
+	return o
+}
+
+// composeOptionArgs 生成 docker compose 的全局参数（-p / --project-directory / -f / --profile / --env-file）。
+// 始终固定项目名与项目目录：否则 compose 会以第一个 -f 文件所在的子目录推断项目名和相对路径。
+// 未配置 env 文件时沿用项目目录下的 .env（若存在）
+func composeOptionArgs(projectDir string, o database.ComposeProjectOptions) []string {
+	project := o.Project
+	if project == "" {
+		project = filepath.Base(projectDir)
+	}
+	args := []string{"-p", project, "--project-directory", projectDir}
+	for _, f := range o.ComposeFiles {
+		args = append(args, "-f", filepath.Join(projectDir, f))
+	}
+	for _, p := range o.Profiles {
+		args = append(args, "--profile", p)
+	}
+	if len(o.EnvFiles) == 0 {
+		if _, err := os.Stat(filepath.Join(projectDir, ".env")); err == nil {
+			args = append(args, "--env-file", filepath.Join(projectDir, ".env"))
+		}
+	}
+	for _, f := range o.EnvFiles {
+		args = append(args, "--env-file", filepath.Join(projectDir, f))
+	}
+	return args
+}
+
+// withComposeProjectArgs 为 docker compose 命令补充项目配置的 compose 文件、profile 与 env 文件；
+// 项目未配置时等同于 withComposeEnvFile
+func withComposeProjectArgs(projectDir string, args []string) []string {
+	if len(args) == 0 || args[0] != "compose" {
+		return args
+	}
+	o := composeOptionsForDir(projectDir)
+	if o.IsEmpty() {
+		return withComposeEnvFile(projectDir, args)
+	}
+	out := append([]string{"compose"}, composeOptionArgs(projectDir, o)...)
+	return append(out, args[1:]...)
+}
+
+// configuredComposeFile 项目配置了多个 compose 文件时，第一个作为基础文件（编辑器、历史版本等使用）
+func configuredComposeFile(projectDir string) (string, bool, error) {
+	o := composeOptionsForDir(projectDir)
+	if len(o.ComposeFiles) == 0 {
+		return "", false, nil
+	}
+	path := filepath.Join(projectDir, o.ComposeFiles[0])
+	if _, err := os.Stat(path); err != nil {
+		return "", true, fmt.Errorf("项目配置的 compose 文件 %s 不存在: %w", o.ComposeFiles[0], err)
+	}
+	return path, true, nil
+}
+
+// composeCrossFileCodes 引用可能由其它 compose 文件补全的诊断：单独校验基础文件时无法判断
+var composeCrossFileCodes = map[string]bool{
+	"no-services":       true,
+	"missing-image":     true,
+	"undefined-service": true,
+	"undefined-network": true,
+	"undefined-volume":  true,
+}
+
+// relaxCrossFileDiagnostics 项目配置了多个 compose 文件时，将跨文件引用类错误降级为警告，
+// 合并结果的最终校验由 docker compose config 负责
+func relaxCrossFileDiagnostics(diags []composeDiagnostic, o database.ComposeProjectOptions) []composeDiagnostic {
+	if len(o.ComposeFiles) < 2 {
+		return diags
+	}
+	for i := range diags {
+		if diags[i].Severity == "error" && composeCrossFileCodes[diags[i].Code] {
+			diags[i].Severity = "warning"
+			diags[i].Message += "（项目配置了多个 compose 文件，可能在其它文件中定义）"
+		}
+	}
+	return diags
+}
+
+// normalizeProjectRelPath 校验并规范化项目目录内的相对路径
+func normalizeProjectRelPath(projectDir, raw string) (string, error) {
+	p := strings.TrimSpace(raw)
+	if p == "" {
+		return "", errors.New("路径不能为空")
+	}
+	if filepath.IsAbs(p) {
+		return "", errors.New("必须是项目目录内的相对路径")
+	}
+	p = filepath.Clean(p)
+	if p == "." || p == ".." || strings.HasPrefix(p, ".."+string(filepath.Separator)) {
+		return "", errors.New("必须是项目目录内的相对路径")
+	}
+	info, err := os.Stat(filepath.Join(projectDir, p))
+	if err != nil {
+		return "", errors.New("文件不存在")
+	}
+	if !info.Mode().IsRegular() {
+		return "", errors.New("不是普通文件")
+	}
+	return filepath.ToSlash(p), nil
+}
+
+type composeOptionsRequest struct {
+	ComposeFiles []string `json:"composeFiles"`
+	Profiles     []string `json:"profiles"`
+	EnvFiles     []string `json:"envFiles"`
+}
+
+// buildComposeOptions 校验请求并生成项目的 compose 参数
+func buildComposeOptions(project, projectDir string, req composeOptionsRequest) (database.ComposeProjectOptions, fieldErrors) {
+	var errs fieldErrors
+	o := database.ComposeProjectOptions{Project: project, ComposeFiles: []string{}, Profiles: []string{}, EnvFiles: []string{}}
+
+	if len(req.ComposeFiles) > composeOptionsMaxFiles {
+		errs.add("composeFiles", "最多 %d 个文件", composeOptionsMaxFiles)
+	}
+	if len(req.EnvFiles) > composeOptionsMaxFiles {
+		errs.add("envFiles", "最多 %d 个文件", composeOptionsMaxFiles)
+	}
+	seen := make(map[string]bool)
+	for i, raw := range req.ComposeFiles {
+		field := fmt.Sprintf("composeFiles[%d]", i)
+		p, err := normalizeProjectRelPath(projectDir, raw)
+		if err != nil {
+			errs.add(field, "%s", err.Error())
+			continue
+		}
+		if lower := strings.ToLower(p); !strings.HasSuffix(lower, ".yml") && !strings.HasSuffix(lower, ".yaml") {
+			errs.add(field, "compose 文件必须是 .yml 或 .yaml")
+			continue
+		}
+		if seen[p] {
+			errs.add(field, "重复的文件 %s", p)
+			continue
+		}
+		seen[p] = true
+		o.ComposeFiles = append(o.ComposeFiles, p)
+	}
+
+	seen = make(map[string]bool)
+	for i, raw := range req.Profiles {
+		p := strings.TrimSpace(raw)
+		if !composeProfilePattern.MatchString(p) {
+			errs.add(fmt.Sprintf("profiles[%d]", i), "profile 名不合法: %q", raw)
+			continue
+		}
+		if !seen[p] {
+			seen[p] = true
+			o.Profiles = append(o.Profiles, p)
+		}
+	}
+
+	seen = make(map[string]bool)
+	for i, raw := range req.EnvFiles {
+		field := fmt.Sprintf("envFiles[%d]", i)
+		p, err := normalizeProjectRelPath(projectDir, raw)
+		if err != nil {
+			errs.add(field, "%s", err.Error())
+			continue
+		}
+		if seen[p] {
+			errs.add(field, "重复的文件 %s", p)
+			continue
+		}
+		seen[p] = true
+		o.EnvFiles = append(o.EnvFiles, p)
+	}
+	return o, errs
+}
+
+// listProjectFileCandidates 列出项目目录下可选的 compose 文件与 env 文件，供前端选择
+func listProjectFileCandidates(projectDir string) (composeFiles, envFiles []string) {
+	composeFiles, envFiles = []string{}, []string{}
+	entries, err := os.ReadDir(projectDir)
+	if err != nil {
+		return composeFiles, envFiles
+	}
+	for _, e := range entries {
+		if !e.Type().IsRegular() {
+			continue
+		}
+		name := e.Name()
+		lower := strings.ToLower(name)
+		switch {
+		case strings.HasSuffix(lower, ".yml") || strings.HasSuffix(lower, ".yaml"):
+			composeFiles = append(composeFiles, name)
+		case lower == ".env" || strings.HasPrefix(lower, ".env.") || strings.HasSuffix(lower, ".env"):
+			envFiles = append(envFiles, name)
+		}
+	}
+	sort.Strings(composeFiles)
+	sort.Strings(envFiles)
+	return composeFiles, envFiles
+}
+
+// checkComposeOptions 以新的参数执行 docker compose config，确认文件能正确合并
+func checkComposeOptions(ctx context.Context, projectDir string, o database.ComposeProjectOptions) error {
+	ctx, cancel := context.WithTimeout(ctx, composeOptionsTimeout)
+	defer cancel()
+	args := append([]string{"compose"}, composeOptionArgs(projectDir, o)...)
+	cmd := exec.CommandContext(ctx, "docker", append(args, "config", "--quiet")...)
+	cmd.Dir = projectDir
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return errors.New(msg)
+		}
+		return err
+	}
+	return nil
+}
+
+func composeOptionsResponse(projectDir string, o database.ComposeProjectOptions) gin.H {
+	composeFiles, envFiles := listProjectFileCandidates(projectDir)
+	return gin.H{
+		"options":    o,
+		"args":       composeOptionArgs(projectDir, o),
+		"candidates": gin.H{"composeFiles": composeFiles, "envFiles": envFiles},
+	}
+}
+
+func getComposeOptions(c *gin.Context) {
+	name, ok := validateComposeProjectName(c.Param("name"))
+	if !ok {
+		respondError(c, http.StatusBadRequest, "项目名不合法：仅支持小写字母/数字，且可包含 _ -，并以字母或数字开头", nil)
+		return
+	}
+	projectDir := filepath.Join(getProjectsBaseDir(), name)
+	if !dirExists(projectDir) {
+		respondError(c, http.StatusNotFound, "项目目录不存在", nil)
+		return
+	}
+	o, err := database.GetComposeProjectOptions(name)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "获取项目参数失败", err)
+		return
+	}
+	c.JSON(http.StatusOK, composeOptionsResponse(projectDir, o))
+}
+
+// updateComposeOptions 保存项目的 compose 文件、profile 与 env 文件，之后的 up/down/logs/build/update 等命令均按此执行；
+// 已运行的服务需重新部署后生效
+func updateComposeOptions(c *gin.Context) {
+	name, ok := validateComposeProjectName(c.Param("name"))
+	if !ok {
+		respondError(c, http.StatusBadRequest, "项目名不合法：仅支持小写字母/数字，且可包含 _ -，并以字母或数字开头", nil)
+		return
+	}
+	if forbidIfSelfProject(c, name) {
+		return
+	}
+	var req composeOptionsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, "无效的请求数据", err)
+		return
+	}
+	projectDir := filepath.Join(getProjectsBaseDir(), name)
+	if !dirExists(projectDir) {
+		respondError(c, http.StatusNotFound, "项目目录不存在", nil)
+		return
+	}
+	o, errs := buildComposeOptions(name, projectDir, req)
+	if len(errs) > 0 {
+		respondFieldErrors(c, errs)
+		return
+	}
+	if !o.IsEmpty() {
+		if err := checkComposeOptions(c.Request.Context(), projectDir, o); err != nil {
+			respondError(c, http.StatusBadRequest, "compose 配置合并失败", err)
+			return
+		}
+	}
+	if err := database.SaveComposeProjectOptions(&o); err != nil {
+		respondError(c, http.StatusInternalServerError, "保存项目参数失败", err)
+		return
+	}
+	c.JSON(http.StatusOK, composeOptionsResponse(projectDir, o))
+}
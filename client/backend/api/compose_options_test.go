@@ -0,0 +1,118 @@
+package api
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"dockerpanel/backend/pkg/database"
+)
+
+func TestBuildComposeOptions(t *testing.T) {
+	dir := t.TempDir()
+	for _, f := range []string{"docker-compose.yml", "docker-compose.prod.yml", ".env", ".env.prod", "notes.txt"} {
+		if err := os.WriteFile(filepath.Join(dir, f), []byte("x"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	o, errs := buildComposeOptions("shop", dir, composeOptionsRequest{
+		ComposeFiles: []string{"docker-compose.yml", " ./docker-compose.prod.yml "},
+		Profiles:     []string{"debug", "debug", "tools"},
+		EnvFiles:     []string{".env", ".env.prod"},
+	})
+	if len(errs) > 0 {
+		t.Fatalf("unexpected errors: %+v", errs)
+	}
+	if strings.Join(o.ComposeFiles, ",") != "docker-compose.yml,docker-compose.prod.yml" ||
+		strings.Join(o.Profiles, ",") != "debug,tools" || strings.Join(o.EnvFiles, ",") != ".env,.env.prod" {
+		t.Fatalf("options: %+v", o)
+	}
+
+	_, errs = buildComposeOptions("shop", dir, composeOptionsRequest{
+		ComposeFiles: []string{"../other.yml", "/etc/passwd", "missing.yml", "notes.txt", "docker-compose.yml", "docker-compose.yml"},
+		Profiles:     []string{"-bad"},
+		EnvFiles:     []string{"../.env"},
+	})
+	fields := make(map[string]bool)
+	for _, e := range errs {
+		fields[e.Field] = true
+	}
+	for _, f := range []string{"composeFiles[0]", "composeFiles[1]", "composeFiles[2]", "composeFiles[3]", "composeFiles[5]", "profiles[0]", "envFiles[0]"} {
+		if !fields[f] {
+			t.Fatalf("missing error for %s: %+v", f, errs)
+		}
+	}
+	if fields["composeFiles[4]"] {
+		t.Fatalf("valid file rejected: %+v", errs)
+	}
+}
+
+func TestComposeOptionArgs(t *testing.T) {
+	dir := t.TempDir()
+	o := database.ComposeProjectOptions{
+		Project:      "shop",
+		ComposeFiles: []string{"deploy/docker-compose.yml", "docker-compose.prod.yml"},
+		Profiles:     []string{"debug"},
+		EnvFiles:     []string{".env", ".env.prod"},
+	}
+	got := strings.Join(composeOptionArgs(dir, o), " ")
+	want := strings.Join([]string{
+		"-p", "shop", "--project-directory", dir,
+		"-f", filepath.Join(dir, "deploy/docker-compose.yml"), "-f", filepath.Join(dir, "docker-compose.prod.yml"),
+		"--profile", "debug", "--env-file", filepath.Join(dir, ".env"), "--env-file", filepath.Join(dir, ".env.prod"),
+	}, " ")
+	if got != want {
+		t.Fatalf("args:\n got %s\nwant %s", got, want)
+	}
+
+	// 未配置 env 文件时沿用存在的 .env
+	if args := composeOptionArgs(dir, database.ComposeProjectOptions{Profiles: []string{"debug"}}); len(args) != 6 || args[1] != filepath.Base(dir) {
+		t.Fatalf("args without .env: %v", args)
+	}
+	if err := os.WriteFile(filepath.Join(dir, ".env"), []byte("A=1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	args := composeOptionArgs(dir, database.ComposeProjectOptions{Profiles: []string{"debug"}})
+	if len(args) != 8 || args[6] != "--env-file" {
+		t.Fatalf("args with .env: %v", args)
+	}
+}
+
+func TestComposePlanConfigArgsKeepsOverrides(t *testing.T) {
+	o := database.ComposeProjectOptions{
+		ComposeFiles: []string{"docker-compose.yml", "docker-compose.prod.yml"},
+		Profiles:     []string{"tools"},
+		EnvFiles:     []string{".env", ".env.prod"},
+	}
+	got := strings.Join(composePlanConfigArgs("shop", "/p/shop", "/tmp/x/compose.yml", "/tmp/x/.env", o), " ")
+	want := "compose -p shop --project-directory /p/shop -f /tmp/x/compose.yml -f /p/shop/docker-compose.prod.yml " +
+		"--profile tools --env-file /tmp/x/.env --env-file /p/shop/.env.prod config"
+	if got != want {
+		t.Fatalf("args:\n got %s\nwant %s", got, want)
+	}
+}
+
+func TestRelaxCrossFileDiagnostics(t *testing.T) {
+	content := "services:\n  web:\n    build: .\n    depends_on: [db]\n    networks: [edge]\n"
+	diags := lintCompose(content, composeLintOptions{})
+	if !composeDiagnosticsHaveErrors(diags) {
+		t.Fatalf("expected errors for a standalone file: %+v", diags)
+	}
+
+	relaxed := relaxCrossFileDiagnostics(diags, database.ComposeProjectOptions{ComposeFiles: []string{"docker-compose.yml", "docker-compose.prod.yml"}})
+	if composeDiagnosticsHaveErrors(relaxed) {
+		t.Fatalf("cross-file references should be warnings: %+v", relaxed)
+	}
+
+	single := lintCompose(content, composeLintOptions{})
+	if !composeDiagnosticsHaveErrors(relaxCrossFileDiagnostics(single, database.ComposeProjectOptions{ComposeFiles: []string{"docker-compose.yml"}})) {
+		t.Fatalf("single compose file should keep errors")
+	}
+
+	invalid := lintCompose("services:\n  web:\n    image: nginx:1\n    restart: sometimes\n", composeLintOptions{})
+	if !composeDiagnosticsHaveErrors(relaxCrossFileDiagnostics(invalid, database.ComposeProjectOptions{ComposeFiles: []string{"a.yml", "b.yml"}})) {
+		t.Fatalf("in-file errors should not be relaxed")
+	}
+}
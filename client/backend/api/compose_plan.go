@@ -0,0 +1,628 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"dockerpanel/backend/pkg/database"
+	"dockerpanel/backend/pkg/docker"
+	"dockerpanel/backend/pkg/settings"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/gin-gonic/gin"
+)
+
+// composePlanConfig docker compose config --format json 输出中计划需要的部分
+type composePlanConfig struct {
+	Services map[string]composePlanService `json:"services"`
+	Volumes  map[string]struct {
+		Name string `json:"name"`
+	} `json:"volumes"`
+}
+
+type composePlanService struct {
+	Image       string             `json:"image"`
+	Build       json.RawMessage    `json:"build"`
+	Environment map[string]*string `json:"environment"`
+	Ports       []struct {
+		Target    int              `json:"target"`
+		Published composePlanValue `json:"published"`
+		HostIP    string           `json:"host_ip"`
+		Protocol  string           `json:"protocol"`
+	} `json:"ports"`
+	Volumes []struct {
+		Type     string `json:"type"`
+		Source   string `json:"source"`
+		Target   string `json:"target"`
+		ReadOnly bool   `json:"read_only"`
+	} `json:"volumes"`
+	Command    []string `json:"command"`
+	Entrypoint []string `json:"entrypoint"`
+	Restart    string   `json:"restart"`
+}
+
+// composePlanValue 兼容不同 compose 版本把端口输出为字符串或数字
+type composePlanValue string
+
+func (v *composePlanValue) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err == nil {
+		*v = composePlanValue(s)
+		return nil
+	}
+	var n json.Number
+	if err := json.Unmarshal(b, &n); err != nil {
+		return err
+	}
+	*v = composePlanValue(n.String())
+	return nil
+}
+
+// composePlanReason 需要重建的原因
+type composePlanReason struct {
+	Field   string `json:"field"` // image / image-updated / environment / ports / volumes / command / entrypoint / restart / config
+	Message string `json:"message"`
+}
+
+// composePlanItem 单个服务的计划
+type composePlanItem struct {
+	Service    string              `json:"service"`
+	Action     string              `json:"action"` // create / recreate / remove / unchanged
+	Containers []string            `json:"containers"`
+	Reasons    []composePlanReason `json:"reasons"`
+}
+
+// composePlanContainer 计划比较所需的容器信息
+type composePlanContainer struct {
+	Info     types.ContainerJSON
+	ImageEnv []string // 镜像自带的环境变量，用于区分容器中的默认值
+	// 按服务期望的镜像名解析到的本地镜像 ID，与容器使用的镜像 ID 不同说明本地已有更新的镜像
+	DesiredImageID string
+}
+
+var anonymousVolumeName = regexp.MustCompile(`^[0-9a-f]{64}$`)
+
+func envListToMap(list []string) map[string]string {
+	m := make(map[string]string, len(list))
+	for _, kv := range list {
+		k, v, _ := strings.Cut(kv, "=")
+		m[k] = v
+	}
+	return m
+}
+
+// diffComposeEnv 比较期望与容器中的环境变量，只返回变量名，避免在计划中暴露取值
+func diffComposeEnv(desired map[string]*string, containerEnv, imageEnv []string) (added, removed, changed []string) {
+	cur := envListToMap(containerEnv)
+	img := envListToMap(imageEnv)
+	for k, v := range desired {
+		if v == nil {
+			continue
+		}
+		if old, ok := cur[k]; !ok {
+			added = append(added, k)
+		} else if old != *v {
+			changed = append(changed, k)
+		}
+	}
+	for k, v := range cur {
+		if _, ok := desired[k]; ok {
+			continue
+		}
+		if def, ok := img[k]; ok && def == v {
+			continue
+		}
+		removed = append(removed, k)
+	}
+	sort.Strings(added)
+	sort.Strings(removed)
+	sort.Strings(changed)
+	return added, removed, changed
+}
+
+func normalizePlanHostIP(ip string) string {
+	if ip == "0.0.0.0" || ip == "::" {
+		return ""
+	}
+	return ip
+}
+
+func desiredPortKeys(svc composePlanService) []string {
+	keys := make([]string, 0, len(svc.Ports))
+	for _, p := range svc.Ports {
+		proto := p.Protocol
+		if proto == "" {
+			proto = "tcp"
+		}
+		keys = append(keys, fmt.Sprintf("%s:%s->%d/%s", normalizePlanHostIP(p.HostIP), p.Published, p.Target, proto))
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func containerPortKeys(info types.ContainerJSON) []string {
+	var keys []string
+	if info.ContainerJSONBase == nil || info.HostConfig == nil {
+		return keys
+	}
+	for port, bindings := range info.HostConfig.PortBindings {
+		for _, b := range bindings {
+			keys = append(keys, fmt.Sprintf("%s:%s->%s/%s", normalizePlanHostIP(b.HostIP), b.HostPort, port.Port(), port.Proto()))
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// desiredVolumeKeys 期望的挂载，命名卷解析为实际卷名，绑定挂载的来源转换为宿主机路径
+func desiredVolumeKeys(project string, svc composePlanService, volumes map[string]string, hostPath func(string) string) []string {
+	keys := make([]string, 0, len(svc.Volumes))
+	for _, v := range svc.Volumes {
+		ro := ""
+		if v.ReadOnly {
+			ro = ":ro"
+		}
+		switch v.Type {
+		case "bind":
+			keys = append(keys, "bind:"+hostPath(v.Source)+":"+v.Target+ro)
+		case "volume":
+			name := "<anonymous>"
+			if v.Source != "" {
+				name = volumes[v.Source]
+				if name == "" {
+					name = project + "_" + v.Source
+				}
+			}
+			keys = append(keys, "volume:"+name+":"+v.Target+ro)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func containerVolumeKeys(info types.ContainerJSON, desired []string) []string {
+	targets := make(map[string]bool)
+	for _, k := range desired {
+		parts := strings.Split(k, ":")
+		if len(parts) >= 3 {
+			targets[parts[2]] = true
+		}
+	}
+	var keys []string
+	for _, m := range info.Mounts {
+		ro := ""
+		if !m.RW {
+			ro = ":ro"
+		}
+		switch m.Type {
+		case "bind":
+			keys = append(keys, "bind:"+m.Source+":"+m.Destination+ro)
+		case "volume":
+			name := m.Name
+			if anonymousVolumeName.MatchString(name) {
+				// 镜像 VOLUME 声明产生的匿名卷不在 compose 中，仅在期望中也有该挂载点时参与比较
+				if !targets[m.Destination] {
+					continue
+				}
+				name = "<anonymous>"
+			}
+			keys = append(keys, "volume:"+name+":"+m.Destination+ro)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// diffStringSets 返回 want 中新增与 have 中多出的项
+func diffStringSets(want, have []string) (added, removed []string) {
+	inHave := make(map[string]bool, len(have))
+	for _, h := range have {
+		inHave[h] = true
+	}
+	inWant := make(map[string]bool, len(want))
+	for _, w := range want {
+		inWant[w] = true
+		if !inHave[w] {
+			added = append(added, w)
+		}
+	}
+	for _, h := range have {
+		if !inWant[h] {
+			removed = append(removed, h)
+		}
+	}
+	return added, removed
+}
+
+func describeSetChange(added, removed []string) string {
+	var parts []string
+	if len(added) > 0 {
+		parts = append(parts, "新增 "+strings.Join(added, ", "))
+	}
+	if len(removed) > 0 {
+		parts = append(parts, "移除 "+strings.Join(removed, ", "))
+	}
+	return strings.Join(parts, "；")
+}
+
+func sameStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func composePlanImage(project, service string, svc composePlanService) string {
+	if svc.Image != "" {
+		return svc.Image
+	}
+	return project + "-" + service
+}
+
+// explainComposeRecreate 比较服务期望的配置与容器当前配置，给出会导致重建的差异
+func explainComposeRecreate(project, service string, svc composePlanService, volumes map[string]string, ct composePlanContainer, hostPath func(string) string) []composePlanReason {
+	var reasons []composePlanReason
+	info := ct.Info
+	if info.Config == nil || info.ContainerJSONBase == nil {
+		return reasons
+	}
+
+	wantImage := composePlanImage(project, service, svc)
+	if info.Config.Image != wantImage {
+		reasons = append(reasons, composePlanReason{"image", fmt.Sprintf("镜像 %s → %s", info.Config.Image, wantImage)})
+	} else if ct.DesiredImageID != "" && ct.DesiredImageID != info.Image {
+		reasons = append(reasons, composePlanReason{"image-updated", fmt.Sprintf("本地镜像 %s 已更新", wantImage)})
+	}
+
+	added, removed, changed := diffComposeEnv(svc.Environment, info.Config.Env, ct.ImageEnv)
+	if len(added)+len(removed)+len(changed) > 0 {
+		var parts []string
+		if len(added) > 0 {
+			parts = append(parts, "新增 "+strings.Join(added, ", "))
+		}
+		if len(changed) > 0 {
+			parts = append(parts, "修改 "+strings.Join(changed, ", "))
+		}
+		if len(removed) > 0 {
+			parts = append(parts, "移除 "+strings.Join(removed, ", "))
+		}
+		reasons = append(reasons, composePlanReason{"environment", "环境变量" + strings.Join(parts, "；")})
+	}
+
+	if a, r := diffStringSets(desiredPortKeys(svc), containerPortKeys(info)); len(a)+len(r) > 0 {
+		reasons = append(reasons, composePlanReason{"ports", "端口" + describeSetChange(a, r)})
+	}
+
+	wantVolumes := desiredVolumeKeys(project, svc, volumes, hostPath)
+	if a, r := diffStringSets(wantVolumes, containerVolumeKeys(info, wantVolumes)); len(a)+len(r) > 0 {
+		reasons = append(reasons, composePlanReason{"volumes", "挂载" + describeSetChange(a, r)})
+	}
+
+	// 未设置 command/entrypoint 时容器使用镜像默认值，无法直接比较
+	if svc.Command != nil && !sameStrings(svc.Command, info.Config.Cmd) {
+		reasons = append(reasons, composePlanReason{"command", fmt.Sprintf("命令 %q → %q", []string(info.Config.Cmd), svc.Command)})
+	}
+	if svc.Entrypoint != nil && !sameStrings(svc.Entrypoint, info.Config.Entrypoint) {
+		reasons = append(reasons, composePlanReason{"entrypoint", fmt.Sprintf("入口 %q → %q", []string(info.Config.Entrypoint), svc.Entrypoint)})
+	}
+
+	wantRestart := svc.Restart
+	if wantRestart == "" {
+		wantRestart = "no"
+	}
+	haveRestart := "no"
+	if info.HostConfig != nil && info.HostConfig.RestartPolicy.Name != "" {
+		haveRestart = info.HostConfig.RestartPolicy.Name
+		if haveRestart == "on-failure" && info.HostConfig.RestartPolicy.MaximumRetryCount > 0 {
+			haveRestart += ":" + strconv.Itoa(info.HostConfig.RestartPolicy.MaximumRetryCount)
+		}
+	}
+	if wantRestart != haveRestart {
+		reasons = append(reasons, composePlanReason{"restart", fmt.Sprintf("重启策略 %s → %s", haveRestart, wantRestart)})
+	}
+	return reasons
+}
+
+// buildComposePlan 根据规范化后的配置、各服务的配置哈希与项目现有容器生成计划。
+// 与 docker compose 一致，能取得期望哈希与容器的 config-hash 标签时由哈希（以及本地镜像是否更新）决定是否重建，
+// 字段差异只用于说明原因；取不到哈希时才凭字段差异判断。需要重建但未找到具体差异时归为其他配置变更。
+func buildComposePlan(project string, cfg composePlanConfig, hashes map[string]string, containers []composePlanContainer, hostPath func(string) string) []composePlanItem {
+	volumes := make(map[string]string, len(cfg.Volumes))
+	for key, v := range cfg.Volumes {
+		volumes[key] = v.Name
+	}
+	byService := make(map[string][]composePlanContainer)
+	for _, ct := range containers {
+		if ct.Info.Config == nil {
+			continue
+		}
+		svc := ct.Info.Config.Labels["com.docker.compose.service"]
+		byService[svc] = append(byService[svc], ct)
+	}
+
+	var items []composePlanItem
+	for name, svc := range cfg.Services {
+		item := composePlanItem{Service: name, Action: "unchanged", Containers: []string{}, Reasons: []composePlanReason{}}
+		cts := byService[name]
+		if len(cts) == 0 {
+			item.Action = "create"
+			items = append(items, item)
+			continue
+		}
+		seen := make(map[string]bool)
+		recreate := false
+		for _, ct := range cts {
+			item.Containers = append(item.Containers, strings.TrimPrefix(ct.Info.Name, "/"))
+			reasons := explainComposeRecreate(project, name, svc, volumes, ct, hostPath)
+			ctRecreate := len(reasons) > 0
+			want, have := hashes[name], ct.Info.Config.Labels["com.docker.compose.config-hash"]
+			if want != "" && have != "" {
+				ctRecreate = want != have || hasComposePlanReason(reasons, "image-updated")
+			}
+			if !ctRecreate {
+				continue
+			}
+			recreate = true
+			for _, r := range reasons {
+				if !seen[r.Field+r.Message] {
+					seen[r.Field+r.Message] = true
+					item.Reasons = append(item.Reasons, r)
+				}
+			}
+		}
+		if recreate {
+			item.Action = "recreate"
+			if len(item.Reasons) == 0 {
+				item.Reasons = append(item.Reasons, composePlanReason{"config", "其他配置变更（如标签、网络、资源限制等）"})
+			}
+		}
+		sort.Strings(item.Containers)
+		items = append(items, item)
+	}
+	for name, cts := range byService {
+		if _, ok := cfg.Services[name]; ok {
+			continue
+		}
+		item := composePlanItem{Service: name, Action: "remove", Containers: []string{}, Reasons: []composePlanReason{}}
+		for _, ct := range cts {
+			item.Containers = append(item.Containers, strings.TrimPrefix(ct.Info.Name, "/"))
+		}
+		sort.Strings(item.Containers)
+		items = append(items, item)
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].Service < items[j].Service })
+	return items
+}
+
+func hasComposePlanReason(reasons []composePlanReason, field string) bool {
+	for _, r := range reasons {
+		if r.Field == field {
+			return true
+		}
+	}
+	return false
+}
+
+// composePlanHostPath 容器化部署时，将项目目录下的路径转换为宿主机路径，与容器挂载信息中的来源一致
+func composePlanHostPath(p string) string {
+	hostRoot := settings.GetHostProjectRoot()
+	if hostRoot == "" {
+		return p
+	}
+	if rel, err := filepath.Rel(getProjectsBaseDir(), p); err == nil && rel != ".." && !strings.HasPrefix(rel, "../") {
+		return filepath.Join(hostRoot, rel)
+	}
+	return p
+}
+
+// composePlanConfigArgs 新内容替换基础 compose 文件与 .env，项目配置的覆盖文件、profile 与其他 env 文件照常参与合并
+func composePlanConfigArgs(project, projectDir, composePath, envPath string, o database.ComposeProjectOptions) []string {
+	args := []string{"compose", "-p", project, "--project-directory", projectDir, "-f", composePath}
+	if len(o.ComposeFiles) > 1 {
+		for _, f := range o.ComposeFiles[1:] {
+			args = append(args, "-f", filepath.Join(projectDir, f))
+		}
+	}
+	for _, p := range o.Profiles {
+		args = append(args, "--profile", p)
+	}
+	if len(o.EnvFiles) == 0 {
+		return append(args, "--env-file", envPath, "config")
+	}
+	for _, f := range o.EnvFiles {
+		if f == ".env" {
+			args = append(args, "--env-file", envPath)
+		} else {
+			args = append(args, "--env-file", filepath.Join(projectDir, f))
+		}
+	}
+	return append(args, "config")
+}
+
+// runComposeConfig 以临时文件执行 docker compose config，相对路径按项目目录解析
+func runComposeConfig(ctx context.Context, project, projectDir, content, dotenv string, args ...string) ([]byte, error) {
+	tmp, err := os.MkdirTemp("", "compose-plan-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tmp)
+	composePath := filepath.Join(tmp, "docker-compose.yml")
+	if err := os.WriteFile(composePath, []byte(content), 0600); err != nil {
+		return nil, err
+	}
+	envPath := filepath.Join(tmp, ".env")
+	if err := os.WriteFile(envPath, []byte(dotenv), 0600); err != nil {
+		return nil, err
+	}
+	base := composePlanConfigArgs(project, projectDir, composePath, envPath, composeOptionsForDir(projectDir))
+	cmd := exec.CommandContext(ctx, "docker", append(base, args...)...)
+	cmd.Dir = projectDir
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("%s", msg)
+		}
+		return nil, err
+	}
+	return stdout.Bytes(), nil
+}
+
+// parseComposeConfigHashes 解析 docker compose config --hash 的 "服务 哈希" 输出
+func parseComposeConfigHashes(out []byte) map[string]string {
+	hashes := make(map[string]string)
+	for _, line := range strings.Split(string(out), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 2 {
+			hashes[fields[0]] = fields[1]
+		}
+	}
+	return hashes
+}
+
+type composePlanRequest struct {
+	Content *string `json:"content"` // 新的 compose，为空时使用项目当前文件（即检查配置漂移）
+	Dotenv  *string `json:"dotenv"`  // 新的 .env，为空时使用项目当前 .env
+}
+
+// planCompose 对比新的 compose 与项目当前运行的容器，列出将要创建、重建（及原因）、删除的服务
+func planCompose(c *gin.Context) {
+	name, ok := validateComposeProjectName(c.Param("name"))
+	if !ok {
+		respondError(c, http.StatusBadRequest, "项目名不合法：仅支持小写字母/数字，且可包含 _ -，并以字母或数字开头", nil)
+		return
+	}
+	var req composePlanRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			respondError(c, http.StatusBadRequest, "无效的请求数据", err)
+			return
+		}
+	}
+	projectDir := filepath.Join(getProjectsBaseDir(), name)
+	if !dirExists(projectDir) {
+		respondError(c, http.StatusBadRequest, "项目目录不存在", nil)
+		return
+	}
+
+	var content string
+	if req.Content != nil {
+		content = *req.Content
+	} else {
+		composePath, err := findComposeFile(projectDir)
+		if err != nil {
+			respondError(c, http.StatusBadRequest, "未找到项目配置文件", err)
+			return
+		}
+		raw, err := os.ReadFile(composePath)
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, "读取配置文件失败", err)
+			return
+		}
+		content = string(raw)
+	}
+	var dotenv string
+	if req.Dotenv != nil {
+		dotenv = strings.ReplaceAll(*req.Dotenv, "\r\n", "\n")
+	} else if raw, err := os.ReadFile(filepath.Join(projectDir, ".env")); err == nil {
+		dotenv = string(raw)
+	}
+
+	ctx := c.Request.Context()
+	diags := lintCompose(content, composeLintOptionsForProject(ctx, name, projectDir, parseDotenvToMap(dotenv)))
+	diags = relaxCrossFileDiagnostics(diags, composeOptionsForDir(projectDir))
+	if composeDiagnosticsHaveErrors(diags) {
+		respondComposeDiagnostics(c, diags)
+		return
+	}
+
+	out, err := runComposeConfig(ctx, name, projectDir, content, dotenv, "--format", "json")
+	if err != nil {
+		respondError(c, http.StatusBadRequest, "解析 compose 配置失败", err)
+		return
+	}
+	var cfg composePlanConfig
+	if err := json.Unmarshal(out, &cfg); err != nil {
+		respondError(c, http.StatusInternalServerError, "解析 compose 配置失败", err)
+		return
+	}
+	// 旧版 compose 不支持 --hash，此时仅按字段差异判断
+	var hashes map[string]string
+	if out, err := runComposeConfig(ctx, name, projectDir, content, dotenv, "--hash", "*"); err == nil {
+		hashes = parseComposeConfigHashes(out)
+	}
+
+	cli, err := docker.NewDockerClient()
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "创建 Docker 客户端失败", err)
+		return
+	}
+	defer cli.Close()
+	list, err := cli.ContainerList(ctx, types.ContainerListOptions{
+		All:     true,
+		Filters: filters.NewArgs(filters.Arg("label", "com.docker.compose.project="+name)),
+	})
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "获取项目容器失败", err)
+		return
+	}
+	imageIDs := make(map[string]string)
+	imageID := func(ref string) string {
+		if id, ok := imageIDs[ref]; ok {
+			return id
+		}
+		id := ""
+		if img, _, err := cli.ImageInspectWithRaw(ctx, ref); err == nil {
+			id = img.ID
+		}
+		imageIDs[ref] = id
+		return id
+	}
+	var containers []composePlanContainer
+	for _, ct := range list {
+		if ct.Labels["com.docker.compose.oneoff"] == "True" {
+			continue
+		}
+		info, err := cli.ContainerInspect(ctx, ct.ID)
+		if err != nil {
+			continue
+		}
+		pc := composePlanContainer{Info: info}
+		if img, _, err := cli.ImageInspectWithRaw(ctx, info.Image); err == nil && img.Config != nil {
+			pc.ImageEnv = img.Config.Env
+		}
+		if svc, ok := cfg.Services[ct.Labels["com.docker.compose.service"]]; ok {
+			pc.DesiredImageID = imageID(composePlanImage(name, ct.Labels["com.docker.compose.service"], svc))
+		}
+		containers = append(containers, pc)
+	}
+
+	items := buildComposePlan(name, cfg, hashes, containers, composePlanHostPath)
+	summary := map[string]int{"create": 0, "recreate": 0, "remove": 0, "unchanged": 0}
+	for _, it := range items {
+		summary[it.Action]++
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"project":     name,
+		"services":    items,
+		"summary":     summary,
+		"diagnostics": diags,
+	})
+}
@@ -0,0 +1,170 @@
+package api
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/go-connections/nat"
+)
+
+const planTestConfig = `{
+  "services": {
+    "db": {
+      "image": "postgres:16",
+      "environment": {"POSTGRES_PASSWORD": "secret", "POSTGRES_DB": "app"},
+      "ports": [{"target": 5432, "published": "5432", "protocol": "tcp"}],
+      "volumes": [{"type": "volume", "source": "data", "target": "/var/lib/postgresql/data"}],
+      "restart": "always"
+    },
+    "web": {
+      "image": "nginx:1.27",
+      "ports": [{"target": 80, "published": 8080}],
+      "volumes": [{"type": "bind", "source": "/srv/project/shop/html", "target": "/usr/share/nginx/html", "read_only": true}]
+    },
+    "worker": {"image": "busybox", "command": ["sleep", "infinity"]}
+  },
+  "volumes": {"data": {"name": "shop_data"}}
+}`
+
+func planTestContainer(service, image string, env []string, ports nat.PortMap, mounts []types.MountPoint, restart string) composePlanContainer {
+	return composePlanContainer{
+		Info: types.ContainerJSON{
+			ContainerJSONBase: &types.ContainerJSONBase{
+				Name:       "/shop-" + service + "-1",
+				Image:      "sha256:" + service,
+				HostConfig: &container.HostConfig{PortBindings: ports, RestartPolicy: container.RestartPolicy{Name: restart}},
+			},
+			Mounts: mounts,
+			Config: &container.Config{
+				Image:  image,
+				Env:    env,
+				Labels: map[string]string{"com.docker.compose.service": service, "com.docker.compose.config-hash": "h-" + service},
+			},
+		},
+		ImageEnv:       []string{"PATH=/usr/bin", "PGDATA=/var/lib/postgresql/data"},
+		DesiredImageID: "sha256:" + service,
+	}
+}
+
+func planTestDB() composePlanContainer {
+	return planTestContainer("db", "postgres:16",
+		[]string{"POSTGRES_PASSWORD=secret", "POSTGRES_DB=app", "PATH=/usr/bin", "PGDATA=/var/lib/postgresql/data"},
+		nat.PortMap{"5432/tcp": {{HostIP: "", HostPort: "5432"}}},
+		[]types.MountPoint{{Type: "volume", Name: "shop_data", Destination: "/var/lib/postgresql/data", RW: true}},
+		"always")
+}
+
+func planItems(t *testing.T, hashes map[string]string, containers ...composePlanContainer) map[string]composePlanItem {
+	t.Helper()
+	var cfg composePlanConfig
+	if err := json.Unmarshal([]byte(planTestConfig), &cfg); err != nil {
+		t.Fatal(err)
+	}
+	identity := func(p string) string { return p }
+	out := make(map[string]composePlanItem)
+	for _, it := range buildComposePlan("shop", cfg, hashes, containers, identity) {
+		out[it.Service] = it
+	}
+	return out
+}
+
+func TestBuildComposePlanActions(t *testing.T) {
+	web := planTestContainer("web", "nginx:1.27", nil,
+		nat.PortMap{"80/tcp": {{HostIP: "0.0.0.0", HostPort: "8080"}}},
+		[]types.MountPoint{{Type: "bind", Source: "/srv/project/shop/html", Destination: "/usr/share/nginx/html", RW: false}},
+		"")
+	old := planTestContainer("cache", "redis:7", nil, nil, nil, "")
+
+	items := planItems(t, map[string]string{"db": "h-db", "web": "h-web"}, planTestDB(), web, old)
+	if items["db"].Action != "unchanged" || items["web"].Action != "unchanged" {
+		t.Fatalf("expected unchanged, got %+v / %+v", items["db"], items["web"])
+	}
+	if items["worker"].Action != "create" {
+		t.Fatalf("worker: %+v", items["worker"])
+	}
+	if it := items["cache"]; it.Action != "remove" || len(it.Containers) != 1 || it.Containers[0] != "shop-cache-1" {
+		t.Fatalf("cache: %+v", it)
+	}
+}
+
+func TestBuildComposePlanRecreateReasons(t *testing.T) {
+	db := planTestDB()
+	db.Info.Config.Image = "postgres:15"
+	db.Info.Config.Env = []string{"POSTGRES_PASSWORD=old", "LEGACY=1", "PATH=/usr/bin", "PGDATA=/var/lib/postgresql/data"}
+	db.Info.HostConfig.PortBindings = nat.PortMap{"5432/tcp": {{HostPort: "15432"}}}
+	db.Info.Mounts = []types.MountPoint{{Type: "volume", Name: "other_data", Destination: "/var/lib/postgresql/data", RW: true}}
+
+	it := planItems(t, nil, db)["db"]
+	if it.Action != "recreate" {
+		t.Fatalf("db: %+v", it)
+	}
+	fields := make(map[string]string)
+	for _, r := range it.Reasons {
+		fields[r.Field] = r.Message
+	}
+	for _, f := range []string{"image", "environment", "ports", "volumes"} {
+		if fields[f] == "" {
+			t.Fatalf("missing reason %s in %+v", f, it.Reasons)
+		}
+	}
+	env := fields["environment"]
+	if !strings.Contains(env, "POSTGRES_DB") || !strings.Contains(env, "POSTGRES_PASSWORD") || !strings.Contains(env, "LEGACY") {
+		t.Fatalf("environment reason: %s", env)
+	}
+	if strings.Contains(env, "secret") || strings.Contains(env, "old") || strings.Contains(env, "PGDATA") {
+		t.Fatalf("environment reason leaks values or image defaults: %s", env)
+	}
+}
+
+func TestBuildComposePlanHashAndImageUpdate(t *testing.T) {
+	db := planTestDB()
+	it := planItems(t, map[string]string{"db": "changed"}, db)["db"]
+	if it.Action != "recreate" || len(it.Reasons) != 1 || it.Reasons[0].Field != "config" {
+		t.Fatalf("hash change: %+v", it)
+	}
+
+	db.DesiredImageID = "sha256:newer"
+	it = planItems(t, map[string]string{"db": "h-db"}, db)["db"]
+	if it.Action != "recreate" || it.Reasons[0].Field != "image-updated" {
+		t.Fatalf("image update: %+v", it)
+	}
+
+	// 哈希一致且镜像未更新时，compose 不会重建，字段比较的误差不影响结果
+	db = planTestDB()
+	db.Info.Config.Env = append(db.Info.Config.Env, "INJECTED=1")
+	it = planItems(t, map[string]string{"db": "h-db"}, db)["db"]
+	if it.Action != "unchanged" || len(it.Reasons) != 0 {
+		t.Fatalf("matching hash: %+v", it)
+	}
+
+	// 哈希不同时字段差异作为原因说明
+	it = planItems(t, map[string]string{"db": "changed"}, db)["db"]
+	if it.Action != "recreate" || len(it.Reasons) != 1 || it.Reasons[0].Field != "environment" {
+		t.Fatalf("hash change with field diff: %+v", it)
+	}
+}
+
+func TestContainerVolumeKeysIgnoresImageAnonymousVolumes(t *testing.T) {
+	info := types.ContainerJSON{Mounts: []types.MountPoint{
+		{Type: "volume", Name: strings.Repeat("a", 64), Destination: "/data", RW: true},
+		{Type: "bind", Source: "/host/conf", Destination: "/etc/app", RW: true},
+	}}
+	got := containerVolumeKeys(info, []string{"bind:/host/conf:/etc/app"})
+	if len(got) != 1 || got[0] != "bind:/host/conf:/etc/app" {
+		t.Fatalf("keys: %v", got)
+	}
+	got = containerVolumeKeys(info, []string{"volume:<anonymous>:/data"})
+	if len(got) != 2 || got[1] != "volume:<anonymous>:/data" {
+		t.Fatalf("keys: %v", got)
+	}
+}
+
+func TestParseComposeConfigHashes(t *testing.T) {
+	got := parseComposeConfigHashes([]byte("db 1f2e\nweb abcd\n\n"))
+	if len(got) != 2 || got["db"] != "1f2e" || got["web"] != "abcd" {
+		t.Fatalf("hashes: %v", got)
+	}
+}
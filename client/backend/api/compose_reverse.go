@@ -0,0 +1,303 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"dockerpanel/backend/pkg/database"
+	"dockerpanel/backend/pkg/docker"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/gin-gonic/gin"
+)
+
+// composeFromContainersRequest 由一组 docker run 创建的容器反向生成 compose 项目
+type composeFromContainersRequest struct {
+	Project    string            `json:"project"`
+	Containers []string          `json:"containers"` // 容器 ID 或名称
+	Services   map[string]string `json:"services"`   // 可选，容器 -> 服务名
+	SplitEnv   *bool             `json:"split_env"`  // 环境变量拆分到 .env，默认开启
+
+	// 以下字段仅创建时使用
+	Compose  string `json:"compose"` // 可选，前端编辑后的配置
+	Dotenv   string `json:"dotenv"`
+	Recreate bool   `json:"recreate"` // 用 compose 重建容器，使其由项目管理
+}
+
+// adoptTarget 需要由 compose 接管的原容器
+type adoptTarget struct {
+	ID      string
+	Name    string
+	Running bool
+}
+
+// inspectComposeSources 读取待转换的容器及其镜像配置，并分配不重复的服务名。
+// services 可按容器 ID/名称指定服务名，未指定时由容器名推导。
+func inspectComposeSources(ctx context.Context, cli *docker.Client, ids []string, services map[string]string) ([]containerComposeSource, error) {
+	sources := make([]containerComposeSource, 0, len(ids))
+	used := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		id = strings.TrimSpace(id)
+		if id == "" {
+			continue
+		}
+		ctr, err := cli.ContainerInspect(ctx, id)
+		if err != nil {
+			return nil, fmt.Errorf("容器 %s 不存在: %v", id, err)
+		}
+		name := strings.TrimPrefix(ctr.Name, "/")
+		if isSelfOrProtectedContainer(ctr.ID, name, ctr.Config.Image, ctr.Config.Labels) {
+			return nil, fmt.Errorf("禁止转换自身容器 %s", name)
+		}
+		if project := strings.TrimSpace(ctr.Config.Labels[composeLabelPrefix+"project"]); project != "" {
+			return nil, fmt.Errorf("容器 %s 已由 compose 项目 %s 管理", name, project)
+		}
+
+		service := strings.TrimSpace(services[id])
+		if service == "" {
+			service = strings.TrimSpace(services[name])
+		}
+		if service != "" {
+			if service != defaultComposeServiceName(service) {
+				return nil, fmt.Errorf("服务名 %s 不合法：仅支持小写字母、数字、_ . -", service)
+			}
+			if used[service] {
+				return nil, fmt.Errorf("服务名 %s 重复", service)
+			}
+		} else {
+			base := defaultComposeServiceName(name)
+			service = base
+			for i := 2; used[service]; i++ {
+				service = fmt.Sprintf("%s-%d", base, i)
+			}
+		}
+		used[service] = true
+
+		var imageCfg *container.Config
+		if img, _, err := cli.ImageInspectWithRaw(ctx, ctr.Image); err == nil {
+			imageCfg = img.Config
+		}
+		sources = append(sources, containerComposeSource{Container: ctr, ImageConfig: imageCfg, Service: service})
+	}
+	if len(sources) == 0 {
+		return nil, fmt.Errorf("未选择任何容器")
+	}
+	return sources, nil
+}
+
+func bindComposeFromContainersRequest(c *gin.Context) (*composeFromContainersRequest, bool) {
+	var req composeFromContainersRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, "无效的请求参数", err)
+		return nil, false
+	}
+	if len(req.Containers) == 0 {
+		respondError(c, http.StatusBadRequest, "请至少选择一个容器", nil)
+		return nil, false
+	}
+	return &req, true
+}
+
+// previewComposeFromContainers 预览由多个容器生成的 compose 项目（compose 文件与 .env）
+func previewComposeFromContainers(c *gin.Context) {
+	req, ok := bindComposeFromContainersRequest(c)
+	if !ok {
+		return
+	}
+
+	cli, ok := getDockerClient(c)
+	if !ok {
+		return
+	}
+	defer cli.Close()
+
+	sources, err := inspectComposeSources(c.Request.Context(), cli, req.Containers, req.Services)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, "生成 compose 配置失败", err)
+		return
+	}
+	conv, err := containersToCompose(sources, req.SplitEnv == nil || *req.SplitEnv)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, "生成 compose 配置失败", err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"services": conv.Services,
+		"compose":  conv.Compose,
+		"dotenv":   conv.Dotenv,
+		"warnings": conv.Warnings,
+	})
+}
+
+// createComposeFromContainers 由多个容器生成 compose 项目并保存到项目根目录，可选用 compose 重建这些容器
+func createComposeFromContainers(c *gin.Context) {
+	req, ok := bindComposeFromContainersRequest(c)
+	if !ok {
+		return
+	}
+	project, ok := validateComposeProjectName(req.Project)
+	if !ok {
+		respondError(c, http.StatusBadRequest, "项目名不合法：仅支持小写字母/数字，且可包含 _ -，并以字母或数字开头", nil)
+		return
+	}
+	if forbidIfSelfProject(c, project) {
+		return
+	}
+	projectDir := filepath.Join(getProjectsBaseDir(), project)
+	if _, err := os.Stat(projectDir); err == nil {
+		respondError(c, http.StatusConflict, fmt.Sprintf("项目 '%s' 已存在", project), nil)
+		return
+	}
+
+	cli, ok := getDockerClient(c)
+	if !ok {
+		return
+	}
+	defer cli.Close()
+
+	sources, err := inspectComposeSources(c.Request.Context(), cli, req.Containers, req.Services)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, "生成 compose 配置失败", err)
+		return
+	}
+	conv, err := containersToCompose(sources, req.SplitEnv == nil || *req.SplitEnv)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, "生成 compose 配置失败", err)
+		return
+	}
+	compose, dotenv := conv.Compose, conv.Dotenv
+	if strings.TrimSpace(req.Compose) != "" {
+		compose, dotenv = req.Compose, req.Dotenv
+	}
+
+	if err := os.MkdirAll(projectDir, 0755); err != nil {
+		respondError(c, http.StatusInternalServerError, "创建项目目录失败", err)
+		return
+	}
+	if err := os.WriteFile(filepath.Join(projectDir, "docker-compose.yml"), []byte(compose), 0644); err != nil {
+		_ = os.RemoveAll(projectDir)
+		respondError(c, http.StatusInternalServerError, "保存配置文件失败", err)
+		return
+	}
+	if strings.TrimSpace(dotenv) != "" {
+		if err := os.WriteFile(filepath.Join(projectDir, ".env"), []byte(dotenv), 0644); err != nil {
+			_ = os.RemoveAll(projectDir)
+			respondError(c, http.StatusInternalServerError, "保存 .env 文件失败", err)
+			return
+		}
+	}
+	log.Printf("由容器生成 compose 项目: project=%s services=%v", project, conv.Services)
+
+	if !req.Recreate {
+		c.JSON(http.StatusOK, gin.H{"message": "compose 项目已创建", "project": project, "services": conv.Services, "warnings": conv.Warnings})
+		return
+	}
+
+	targets := make([]adoptTarget, 0, len(sources))
+	for _, src := range sources {
+		targets = append(targets, adoptTarget{
+			ID:      src.Container.ID,
+			Name:    strings.TrimPrefix(src.Container.Name, "/"),
+			Running: src.Container.State != nil && src.Container.State.Running,
+		})
+	}
+	taskID := fmt.Sprintf("%d", time.Now().UnixNano())
+	_ = database.UpsertTask(taskID, "container_adopt", "pending")
+	go runContainerAdoptTask(taskID, project, targets)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":  "compose 项目已创建，正在重建容器",
+		"project":  project,
+		"services": conv.Services,
+		"taskId":   taskID,
+		"warnings": conv.Warnings,
+	})
+}
+
+// runContainerAdoptTask 停止并改名原容器后执行 compose up，失败时恢复原容器
+func runContainerAdoptTask(taskID string, project string, targets []adoptTarget) {
+	seq := int64(0)
+	appendLog := func(logType string, message string) {
+		seq++
+		_ = database.AppendTaskLogWithSeq(taskID, seq, time.Now(), logType, message)
+	}
+	_ = database.UpsertTask(taskID, "container_adopt", "running")
+
+	cli, err := docker.NewDockerClient()
+	if err != nil {
+		appendLog("error", "连接 Docker 失败: "+err.Error())
+		_ = database.FinishTask(taskID, "error", nil, err.Error())
+		return
+	}
+	defer cli.Close()
+
+	ctx := context.Background()
+	suffix := fmt.Sprintf("_backup_%d", time.Now().Unix())
+	moved := make([]adoptTarget, 0, len(targets))
+	restore := func() {
+		for _, t := range moved {
+			_ = cli.ContainerRename(ctx, t.ID, t.Name)
+			if t.Running {
+				_ = cli.ContainerStart(ctx, t.ID, types.ContainerStartOptions{})
+			}
+		}
+	}
+
+	timeout := 10
+	for _, t := range targets {
+		appendLog("info", fmt.Sprintf("停止并重命名原容器 %s -> %s", t.Name, t.Name+suffix))
+		if err := cli.ContainerRename(ctx, t.ID, t.Name+suffix); err != nil {
+			appendLog("error", fmt.Sprintf("重命名容器 %s 失败: %v", t.Name, err))
+			restore()
+			_ = database.FinishTask(taskID, "error", nil, err.Error())
+			return
+		}
+		moved = append(moved, t)
+		if err := cli.ContainerStop(ctx, t.ID, container.StopOptions{Timeout: &timeout}); err != nil {
+			appendLog("error", fmt.Sprintf("停止容器 %s 失败: %v", t.Name, err))
+			restore()
+			_ = database.FinishTask(taskID, "error", nil, err.Error())
+			return
+		}
+	}
+
+	projectDir := filepath.Join(getProjectsBaseDir(), project)
+	appendLog("info", "正在通过 compose 启动服务...")
+	err = runComposeStreamLines(ctx, projectDir, []string{"compose", "up", "-d"}, func(line string) {
+		msgType := "info"
+		if strings.Contains(line, "error") || strings.Contains(line, "Error") {
+			msgType = "error"
+		}
+		appendLog(msgType, line)
+	})
+	if err != nil {
+		appendLog("error", "compose 启动失败，正在恢复原容器: "+err.Error())
+		_ = runComposeStreamLines(ctx, projectDir, []string{"compose", "down"}, func(string) {})
+		restore()
+		_ = database.FinishTask(taskID, "error", nil, err.Error())
+		_ = database.SaveNotification(&database.Notification{
+			Type:    "error",
+			Message: fmt.Sprintf("容器转换为 compose 项目 %s 失败，已恢复原容器", project),
+		})
+		return
+	}
+
+	for _, t := range targets {
+		if err := cli.ContainerRemove(ctx, t.ID, types.ContainerRemoveOptions{}); err != nil {
+			appendLog("warning", fmt.Sprintf("移除原容器失败: %v（新服务已启动，可手动删除 %s）", err, t.Name+suffix))
+		}
+	}
+	appendLog("success", fmt.Sprintf("%d 个容器已由 compose 项目 %s 接管", len(targets), project))
+	_ = database.FinishTask(taskID, "success", gin.H{"project": project}, "")
+	_ = database.SaveNotification(&database.Notification{
+		Type:    "success",
+		Message: fmt.Sprintf("%d 个容器已转换为 compose 项目 %s", len(targets), project),
+	})
+}
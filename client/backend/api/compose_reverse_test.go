@@ -0,0 +1,92 @@
+package api
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"gopkg.in/yaml.v3"
+)
+
+func TestContainersToCompose(t *testing.T) {
+	db := types.ContainerJSON{
+		ContainerJSONBase: &types.ContainerJSONBase{
+			ID:         strings.Repeat("d", 64),
+			Name:       "/db",
+			HostConfig: &container.HostConfig{NetworkMode: "bridge"},
+		},
+		Config: &container.Config{
+			Image: "postgres:16",
+			Env:   []string{"POSTGRES_PASSWORD=p@ss word", "TZ=UTC", "LOG_LEVEL=warn"},
+		},
+	}
+	web := fixtureContainer()
+	web.Config.Env = []string{"TZ=UTC", "LOG_LEVEL=debug", "PRICE=$5", "odd-key=1"}
+	web.HostConfig.Links = []string{"/db:/My.Web/database"}
+	sidecar := types.ContainerJSON{
+		ContainerJSONBase: &types.ContainerJSONBase{
+			ID:         strings.Repeat("e", 64),
+			Name:       "/sidecar",
+			HostConfig: &container.HostConfig{NetworkMode: container.NetworkMode("container:" + fixtureContainerID), VolumesFrom: []string{"db:ro", "other"}},
+		},
+		Config: &container.Config{Image: "busybox"},
+	}
+
+	conv, err := containersToCompose([]containerComposeSource{
+		{Container: db, Service: "db"},
+		{Container: web, Service: "web"},
+		{Container: sidecar, Service: "sidecar"},
+	}, true)
+	if err != nil {
+		t.Fatalf("containersToCompose: %v", err)
+	}
+	if !reflect.DeepEqual(conv.Services, map[string]string{"db": "db", "My.Web": "web", "sidecar": "sidecar"}) {
+		t.Fatalf("services = %v", conv.Services)
+	}
+
+	var doc struct {
+		Services map[string]map[string]any `yaml:"services"`
+	}
+	if err := yaml.Unmarshal([]byte(conv.Compose), &doc); err != nil {
+		t.Fatalf("generated compose is not valid yaml: %v\n%s", err, conv.Compose)
+	}
+	if got := doc.Services["db"]["environment"]; !reflect.DeepEqual(got, []any{"POSTGRES_PASSWORD=${POSTGRES_PASSWORD}", "TZ=${TZ}", "LOG_LEVEL=${DB_LOG_LEVEL}"}) {
+		t.Fatalf("db environment = %v", got)
+	}
+	if got := doc.Services["web"]["environment"]; !reflect.DeepEqual(got, []any{"TZ=${TZ}", "LOG_LEVEL=${WEB_LOG_LEVEL}", "PRICE=${PRICE}", "odd-key=1"}) {
+		t.Fatalf("web environment = %v", got)
+	}
+	if !reflect.DeepEqual(doc.Services["web"]["links"], []any{"db:database"}) || !reflect.DeepEqual(doc.Services["web"]["depends_on"], []any{"db"}) {
+		t.Fatalf("web links = %v depends_on = %v", doc.Services["web"]["links"], doc.Services["web"]["depends_on"])
+	}
+	sc := doc.Services["sidecar"]
+	if sc["network_mode"] != "service:web" || !reflect.DeepEqual(sc["volumes_from"], []any{"db:ro", "container:other"}) || !reflect.DeepEqual(sc["depends_on"], []any{"db", "web"}) {
+		t.Fatalf("sidecar = %v", sc)
+	}
+
+	wantDotenv := "DB_LOG_LEVEL=warn\nPOSTGRES_PASSWORD='p@ss word'\nPRICE='$5'\nTZ=UTC\nWEB_LOG_LEVEL=debug\n"
+	if conv.Dotenv != wantDotenv {
+		t.Fatalf("dotenv = %q", conv.Dotenv)
+	}
+
+	if _, err := containersToCompose([]containerComposeSource{{Container: db, Service: "x"}, {Container: web, Service: "x"}}, false); err == nil {
+		t.Fatalf("duplicate service names should be rejected")
+	}
+}
+
+func TestQuoteDotenvValue(t *testing.T) {
+	cases := map[string]string{
+		"plain":      "plain",
+		"":           "",
+		"a b":        "'a b'",
+		"it's $HOME": `"it's \$HOME"`,
+		"line1\nx":   `"line1\nx"`,
+	}
+	for in, want := range cases {
+		if got := quoteDotenvValue(in); got != want {
+			t.Errorf("quoteDotenvValue(%q) = %s, want %s", in, got, want)
+		}
+	}
+}
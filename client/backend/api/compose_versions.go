@@ -0,0 +1,616 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"dockerpanel/backend/pkg/composehistory"
+	"dockerpanel/backend/pkg/database"
+	"dockerpanel/backend/pkg/settings"
+
+	"github.com/gin-gonic/gin"
+)
+
+const composeDiffContext = 3
+
+// composeHistoryMu 串行化版本记录与回滚，避免并发保存时版本号与文件内容错位
+var composeHistoryMu sync.Mutex
+
+func composeHistoryStore() *composehistory.Store {
+	return composehistory.NewStore(filepath.Join(settings.GetDataDir(), "compose_history"))
+}
+
+// composeSnapshot 项目目录中当前的 compose 与 .env 内容，以及项目配置的覆盖 compose 文件和额外 env 文件
+type composeSnapshot struct {
+	ComposeFile string
+	Compose     []byte
+	Env         []byte
+	HasEnv      bool
+	Extra       map[string][]byte // 相对路径 -> 内容，不存在的文件不记录
+}
+
+// composeSnapshotExtraFiles 除基础 compose 文件与 .env 外需要一并记录的文件
+func composeSnapshotExtraFiles(projectDir string) []string {
+	o := composeOptionsForDir(projectDir)
+	files := make([]string, 0, len(o.ComposeFiles)+len(o.EnvFiles))
+	if len(o.ComposeFiles) > 1 {
+		files = append(files, o.ComposeFiles[1:]...)
+	}
+	for _, f := range o.EnvFiles {
+		if f != ".env" {
+			files = append(files, f)
+		}
+	}
+	return files
+}
+
+func readComposeSnapshot(projectDir string) (*composeSnapshot, error) {
+	composePath, err := findComposeFile(projectDir)
+	if err != nil {
+		return nil, err
+	}
+	compose, err := os.ReadFile(composePath)
+	if err != nil {
+		return nil, err
+	}
+	snap := &composeSnapshot{ComposeFile: filepath.Base(composePath), Compose: compose}
+	env, err := os.ReadFile(filepath.Join(projectDir, ".env"))
+	if err == nil {
+		snap.Env, snap.HasEnv = env, true
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+	for _, rel := range composeSnapshotExtraFiles(projectDir) {
+		data, err := os.ReadFile(filepath.Join(projectDir, filepath.FromSlash(rel)))
+		if os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		if snap.Extra == nil {
+			snap.Extra = make(map[string][]byte)
+		}
+		snap.Extra[rel] = data
+	}
+	return snap, nil
+}
+
+// composeExtraHashes 计算额外文件的内容哈希
+func composeExtraHashes(extra map[string][]byte) map[string]string {
+	if len(extra) == 0 {
+		return nil
+	}
+	hashes := make(map[string]string, len(extra))
+	for rel, data := range extra {
+		hashes[rel] = composehistory.Hash(data)
+	}
+	return hashes
+}
+
+func sameExtraHashes(a, b map[string]string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for k, v := range a {
+		if b[k] != v {
+			return false
+		}
+	}
+	return true
+}
+
+// recordComposeVersion 将项目当前的文件记录为新版本；与最新版本内容相同时不新增，返回 nil
+func recordComposeVersion(project, source, author, message string) (*database.ComposeVersion, error) {
+	composeHistoryMu.Lock()
+	defer composeHistoryMu.Unlock()
+	return recordComposeVersionLocked(project, source, author, message)
+}
+
+func recordComposeVersionLocked(project, source, author, message string) (*database.ComposeVersion, error) {
+	snap, err := readComposeSnapshot(filepath.Join(getProjectsBaseDir(), project))
+	if err != nil {
+		return nil, err
+	}
+	store := composeHistoryStore()
+	v := &database.ComposeVersion{
+		Project:     project,
+		ComposeFile: snap.ComposeFile,
+		ComposeHash: composehistory.Hash(snap.Compose),
+		Author:      author,
+		Message:     strings.TrimSpace(message),
+		Source:      source,
+		ExtraFiles:  composeExtraHashes(snap.Extra),
+	}
+	if snap.HasEnv {
+		v.EnvHash = composehistory.Hash(snap.Env)
+	}
+	latest, err := database.GetLatestComposeVersion(project)
+	if err == nil && latest.ComposeHash == v.ComposeHash && latest.EnvHash == v.EnvHash && latest.ComposeFile == v.ComposeFile &&
+		sameExtraHashes(latest.ExtraFiles, v.ExtraFiles) {
+		return nil, nil
+	} else if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+
+	if _, err := store.Put(snap.Compose); err != nil {
+		return nil, err
+	}
+	if snap.HasEnv {
+		if _, err := store.Put(snap.Env); err != nil {
+			return nil, err
+		}
+	}
+	for _, data := range snap.Extra {
+		if _, err := store.Put(data); err != nil {
+			return nil, err
+		}
+	}
+	if err := database.AddComposeVersion(v); err != nil {
+		return nil, err
+	}
+	if v.Version%20 == 0 {
+		// 裁剪旧版本后回收不再被引用的内容
+		gcComposeHistoryLocked()
+	}
+	return v, nil
+}
+
+// ensureComposeBaseline 项目还没有任何版本时，在覆盖文件前先把现有内容记为基线版本
+func ensureComposeBaseline(project string) {
+	composeHistoryMu.Lock()
+	defer composeHistoryMu.Unlock()
+	if _, err := database.GetLatestComposeVersion(project); !errors.Is(err, sql.ErrNoRows) {
+		return
+	}
+	if _, err := recordComposeVersionLocked(project, "baseline", "", "修改前的原始版本"); err != nil && !os.IsNotExist(err) {
+		log.Printf("记录项目 %s 的基线版本失败: %v", project, err)
+	}
+}
+
+// recordComposeVersionAfterSave 保存文件后记录版本，失败只记日志不影响保存结果
+func recordComposeVersionAfterSave(project, source, author, message string) *database.ComposeVersion {
+	v, err := recordComposeVersion(project, source, author, message)
+	if err != nil {
+		log.Printf("记录项目 %s 的配置版本失败: %v", project, err)
+	}
+	return v
+}
+
+func gcComposeHistoryLocked() {
+	keep, err := database.ListComposeVersionHashes()
+	if err != nil {
+		log.Printf("回收 compose 历史版本失败: %v", err)
+		return
+	}
+	if _, err := composeHistoryStore().GC(keep); err != nil {
+		log.Printf("回收 compose 历史版本失败: %v", err)
+	}
+}
+
+// deleteComposeHistory 删除项目时清理其历史版本
+func deleteComposeHistory(project string) {
+	composeHistoryMu.Lock()
+	defer composeHistoryMu.Unlock()
+	if err := database.DeleteComposeVersions(project); err != nil {
+		log.Printf("删除项目 %s 的历史版本失败: %v", project, err)
+		return
+	}
+	gcComposeHistoryLocked()
+}
+
+// restoreComposeFiles 将版本内容写回项目目录：compose 写入当前使用的文件（不存在时按版本记录的文件名），
+// 版本没有 .env 时删除现有的 .env；extra 中的覆盖 compose 文件与 env 文件按相对路径写回。
+// current 为项目当前使用的额外文件，其中不属于该版本的会被删除，返回删除的相对路径
+func restoreComposeFiles(projectDir, composeFile string, compose, env []byte, hasEnv bool, extra map[string][]byte, current []string) ([]string, error) {
+	composePath, err := findComposeFile(projectDir)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			return nil, err
+		}
+		composePath = filepath.Join(projectDir, filepath.Base(composeFile))
+	}
+	for rel := range extra {
+		if !validComposeSnapshotPath(rel) {
+			return nil, fmt.Errorf("版本记录的文件路径不合法: %s", rel)
+		}
+	}
+	if err := os.WriteFile(composePath, compose, 0644); err != nil {
+		return nil, err
+	}
+	for rel, data := range extra {
+		p := filepath.Join(projectDir, filepath.FromSlash(rel))
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			return nil, err
+		}
+		if err := os.WriteFile(p, data, 0644); err != nil {
+			return nil, err
+		}
+	}
+	var removed []string
+	for _, rel := range current {
+		if _, ok := extra[rel]; ok || !validComposeSnapshotPath(rel) {
+			continue
+		}
+		if err := os.Remove(filepath.Join(projectDir, filepath.FromSlash(rel))); err != nil && !os.IsNotExist(err) {
+			return removed, err
+		}
+		removed = append(removed, rel)
+	}
+	envPath := filepath.Join(projectDir, ".env")
+	if hasEnv {
+		return removed, os.WriteFile(envPath, env, 0644)
+	}
+	if err := os.Remove(envPath); err != nil && !os.IsNotExist(err) {
+		return removed, err
+	}
+	return removed, nil
+}
+
+// validComposeSnapshotPath 版本中的文件路径必须位于项目目录内
+func validComposeSnapshotPath(rel string) bool {
+	p := filepath.Clean(filepath.FromSlash(rel))
+	return !filepath.IsAbs(p) && p != "." && p != ".." && !strings.HasPrefix(p, ".."+string(filepath.Separator))
+}
+
+// dropComposeOptionFiles 回滚删除了额外文件后，从项目参数中移除这些文件，避免 compose 命令引用不存在的文件
+func dropComposeOptionFiles(project string, removed []string) error {
+	if len(removed) == 0 {
+		return nil
+	}
+	gone := make(map[string]bool, len(removed))
+	for _, rel := range removed {
+		gone[rel] = true
+	}
+	o, err := database.GetComposeProjectOptions(project)
+	if err != nil {
+		return err
+	}
+	keep := func(files []string, from int) []string {
+		out := append([]string{}, files[:from]...)
+		for _, f := range files[from:] {
+			if !gone[f] {
+				out = append(out, f)
+			}
+		}
+		return out
+	}
+	if len(o.ComposeFiles) > 0 {
+		o.ComposeFiles = keep(o.ComposeFiles, 1)
+	}
+	o.EnvFiles = keep(o.EnvFiles, 0)
+	return database.SaveComposeProjectOptions(&o)
+}
+
+// loadComposeVersionContent 读取版本对应的文件内容
+func loadComposeVersionContent(v database.ComposeVersion) (compose, env []byte, err error) {
+	store := composeHistoryStore()
+	if compose, err = store.Get(v.ComposeHash); err != nil {
+		return nil, nil, err
+	}
+	if v.EnvHash != "" {
+		if env, err = store.Get(v.EnvHash); err != nil {
+			return nil, nil, err
+		}
+	}
+	return compose, env, nil
+}
+
+// loadComposeVersionExtra 读取版本记录的覆盖 compose 文件与额外 env 文件
+func loadComposeVersionExtra(v database.ComposeVersion) (map[string][]byte, error) {
+	if len(v.ExtraFiles) == 0 {
+		return nil, nil
+	}
+	store := composeHistoryStore()
+	extra := make(map[string][]byte, len(v.ExtraFiles))
+	for rel, hash := range v.ExtraFiles {
+		data, err := store.Get(hash)
+		if err != nil {
+			return nil, err
+		}
+		extra[rel] = data
+	}
+	return extra, nil
+}
+
+// lookupComposeVersion 解析路径中的项目名与版本号，失败时已写入响应
+func lookupComposeVersion(c *gin.Context) (string, database.ComposeVersion, bool) {
+	name, ok := validateComposeProjectName(c.Param("name"))
+	if !ok {
+		respondError(c, http.StatusBadRequest, "项目名不合法：仅支持小写字母/数字，且可包含 _ -，并以字母或数字开头", nil)
+		return "", database.ComposeVersion{}, false
+	}
+	num, err := strconv.Atoi(c.Param("version"))
+	if err != nil || num <= 0 {
+		respondError(c, http.StatusBadRequest, "版本号不合法", err)
+		return "", database.ComposeVersion{}, false
+	}
+	v, err := database.GetComposeVersion(name, num)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			respondError(c, http.StatusNotFound, "版本不存在", nil)
+		} else {
+			respondError(c, http.StatusInternalServerError, "获取版本失败", err)
+		}
+		return "", database.ComposeVersion{}, false
+	}
+	return name, v, true
+}
+
+func listComposeVersions(c *gin.Context) {
+	name, ok := validateComposeProjectName(c.Param("name"))
+	if !ok {
+		respondError(c, http.StatusBadRequest, "项目名不合法：仅支持小写字母/数字，且可包含 _ -，并以字母或数字开头", nil)
+		return
+	}
+	limit, _ := strconv.Atoi(c.Query("limit"))
+	list, err := database.ListComposeVersions(name, limit)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "获取版本历史失败", err)
+		return
+	}
+	c.JSON(http.StatusOK, list)
+}
+
+func getComposeVersion(c *gin.Context) {
+	_, v, ok := lookupComposeVersion(c)
+	if !ok {
+		return
+	}
+	compose, env, err := loadComposeVersionContent(v)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "读取版本内容失败", err)
+		return
+	}
+	extra, err := loadComposeVersionExtra(v)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "读取版本内容失败", err)
+		return
+	}
+	files := make(map[string]string, len(extra))
+	for rel, data := range extra {
+		files[rel] = string(data)
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"version": v,
+		"compose": string(compose),
+		"env":     string(env),
+		"files":   files,
+	})
+}
+
+// diffComposeVersion 对比两个版本：默认与上一版本对比，against=current 时与项目当前文件对比，
+// 结果为 against → 该版本的变化（与当前对比时即回滚将带来的变化）
+func diffComposeVersion(c *gin.Context) {
+	name, v, ok := lookupComposeVersion(c)
+	if !ok {
+		return
+	}
+	compose, env, err := loadComposeVersionContent(v)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "读取版本内容失败", err)
+		return
+	}
+	extra, err := loadComposeVersionExtra(v)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "读取版本内容失败", err)
+		return
+	}
+	toLabel := fmt.Sprintf("v%d", v.Version)
+
+	against := strings.TrimSpace(c.Query("against"))
+	fromLabel := "/dev/null"
+	var fromCompose, fromEnv []byte
+	var fromExtra map[string][]byte
+	switch {
+	case against == "current":
+		snap, err := readComposeSnapshot(filepath.Join(getProjectsBaseDir(), name))
+		if err != nil {
+			respondError(c, http.StatusBadRequest, "读取项目当前配置失败", err)
+			return
+		}
+		fromLabel, fromCompose, fromEnv, fromExtra = "current", snap.Compose, snap.Env, snap.Extra
+	default:
+		num := v.Version - 1
+		if against != "" {
+			if num, err = strconv.Atoi(against); err != nil || num <= 0 {
+				respondError(c, http.StatusBadRequest, "对比版本不合法：应为版本号或 current", err)
+				return
+			}
+		}
+		base, err := database.GetComposeVersion(name, num)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			respondError(c, http.StatusInternalServerError, "获取版本失败", err)
+			return
+		}
+		if err == nil {
+			if fromCompose, fromEnv, err = loadComposeVersionContent(base); err != nil {
+				respondError(c, http.StatusInternalServerError, "读取版本内容失败", err)
+				return
+			}
+			if fromExtra, err = loadComposeVersionExtra(base); err != nil {
+				respondError(c, http.StatusInternalServerError, "读取版本内容失败", err)
+				return
+			}
+			fromLabel = fmt.Sprintf("v%d", base.Version)
+		} else if against != "" {
+			respondError(c, http.StatusNotFound, "对比版本不存在", nil)
+			return
+		}
+	}
+
+	composeDiff, composeStats := composehistory.Unified(string(fromCompose), string(compose),
+		fromLabel+"/"+v.ComposeFile, toLabel+"/"+v.ComposeFile, composeDiffContext)
+	envDiff, envStats := composehistory.Unified(string(fromEnv), string(env),
+		fromLabel+"/.env", toLabel+"/.env", composeDiffContext)
+
+	// 覆盖 compose 文件与额外 env 文件只列出有变化的
+	paths := make([]string, 0, len(extra)+len(fromExtra))
+	for rel := range extra {
+		paths = append(paths, rel)
+	}
+	for rel := range fromExtra {
+		if _, ok := extra[rel]; !ok {
+			paths = append(paths, rel)
+		}
+	}
+	sort.Strings(paths)
+	files := make([]gin.H, 0, len(paths))
+	for _, rel := range paths {
+		if string(fromExtra[rel]) == string(extra[rel]) {
+			continue
+		}
+		diff, stats := composehistory.Unified(string(fromExtra[rel]), string(extra[rel]),
+			fromLabel+"/"+rel, toLabel+"/"+rel, composeDiffContext)
+		files = append(files, gin.H{"path": rel, "diff": diff, "stats": stats})
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"from":    fromLabel,
+		"to":      toLabel,
+		"compose": gin.H{"diff": composeDiff, "stats": composeStats},
+		"env":     gin.H{"diff": envDiff, "stats": envStats},
+		"files":   files,
+	})
+}
+
+// rollbackComposeVersion 将项目配置恢复到指定版本，并记录为新版本；redeploy 为 true 时随后执行 docker compose up -d
+func rollbackComposeVersion(c *gin.Context) {
+	name, v, ok := lookupComposeVersion(c)
+	if !ok {
+		return
+	}
+	if forbidIfSelfProject(c, name) {
+		return
+	}
+	if s, err := settings.GetSettings(); err != nil || !s.AdvancedMode {
+		respondError(c, http.StatusForbidden, "未开启高级模式，禁止回滚配置", nil)
+		return
+	}
+	var req struct {
+		Redeploy bool   `json:"redeploy"`
+		Message  string `json:"message"`
+	}
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			respondError(c, http.StatusBadRequest, "无效的请求数据", err)
+			return
+		}
+	}
+	projectDir := filepath.Join(getProjectsBaseDir(), name)
+	if _, err := os.Stat(projectDir); err != nil {
+		respondError(c, http.StatusBadRequest, "项目目录不存在", err)
+		return
+	}
+	compose, env, err := loadComposeVersionContent(v)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "读取版本内容失败", err)
+		return
+	}
+	extra, err := loadComposeVersionExtra(v)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "读取版本内容失败", err)
+		return
+	}
+
+	author := c.GetString("username")
+	message := strings.TrimSpace(req.Message)
+	if message == "" {
+		message = fmt.Sprintf("回滚到版本 v%d", v.Version)
+	}
+
+	// 与 Git 同步、Webhook、计划任务的部署互斥；需要重新部署时锁交由部署任务释放
+	mu := composeProjectLock(name)
+	if !mu.TryLock() {
+		respondError(c, http.StatusConflict, errComposeProjectBusy.Error(), nil)
+		return
+	}
+	handedOff := false
+	defer func() {
+		if !handedOff {
+			mu.Unlock()
+		}
+	}()
+
+	composeHistoryMu.Lock()
+	// 当前文件可能被手动修改过而没有记录，回滚前先留存，保证回滚本身可以撤销
+	if _, err := recordComposeVersionLocked(name, "baseline", "", "回滚前的配置"); err != nil && !os.IsNotExist(err) {
+		composeHistoryMu.Unlock()
+		respondError(c, http.StatusInternalServerError, "保存回滚前的配置失败", err)
+		return
+	}
+	removed, err := restoreComposeFiles(projectDir, v.ComposeFile, compose, env, v.EnvHash != "", extra, composeSnapshotExtraFiles(projectDir))
+	if err == nil {
+		err = dropComposeOptionFiles(name, removed)
+	}
+	if err != nil {
+		composeHistoryMu.Unlock()
+		respondError(c, http.StatusInternalServerError, "恢复配置文件失败", err)
+		return
+	}
+	created, err := recordComposeVersionLocked(name, "rollback", author, message)
+	composeHistoryMu.Unlock()
+	if err != nil {
+		log.Printf("记录项目 %s 的配置版本失败: %v", name, err)
+	}
+
+	resp := gin.H{"message": fmt.Sprintf("已回滚到版本 v%d", v.Version), "version": created}
+	if req.Redeploy {
+		taskID := fmt.Sprintf("%d", time.Now().UnixNano())
+		_ = database.UpsertTask(taskID, "compose_rollback", "pending")
+		handedOff = true
+		go func() {
+			defer mu.Unlock()
+			runComposeRollbackTask(taskID, name, v.Version)
+		}()
+		resp["taskId"] = taskID
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+func runComposeRollbackTask(taskID, projectName string, version int) {
+	seq := int64(0)
+	appendLog := func(logType string, message string) {
+		seq++
+		_ = database.AppendTaskLogWithSeq(taskID, seq, time.Now(), logType, message)
+	}
+	finish := func(status string, result any, errStr string) {
+		_ = database.FinishTask(taskID, status, result, errStr)
+		notification := &database.Notification{
+			Type:    "success",
+			Message: fmt.Sprintf("Compose 项目 %s 已回滚到版本 v%d 并重新部署", projectName, version),
+		}
+		if status != "success" {
+			notification.Type = "error"
+			notification.Message = fmt.Sprintf("Compose 项目 %s 回滚到版本 v%d 后重新部署失败：%s", projectName, version, errStr)
+		}
+		_ = database.SaveNotification(notification)
+	}
+
+	_ = database.UpsertTask(taskID, "compose_rollback", "running")
+	appendLog("info", fmt.Sprintf("项目 %s 已回滚到版本 v%d，正在重新部署...", projectName, version))
+
+	projectDir := filepath.Join(getProjectsBaseDir(), projectName)
+	if err := runComposeStreamLines(context.Background(), projectDir, []string{"compose", "up", "-d"}, func(line string) {
+		msgType := "info"
+		if strings.Contains(line, "error") || strings.Contains(line, "Error") {
+			msgType = "error"
+		}
+		appendLog(msgType, line)
+	}); err != nil {
+		appendLog("error", "重新部署失败: "+err.Error())
+		finish("error", nil, err.Error())
+		return
+	}
+	appendLog("success", "重新部署完成")
+	finish("success", gin.H{"project": projectName, "version": version}, "")
+}
@@ -0,0 +1,87 @@
+package api
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestComposeSnapshotAndRestore(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := readComposeSnapshot(dir); !os.IsNotExist(err) {
+		t.Fatalf("empty project should report missing compose file, got %v", err)
+	}
+
+	// 版本记录的是 docker-compose.yml，项目中不存在 compose 文件时按记录的文件名恢复
+	if _, err := restoreComposeFiles(dir, "docker-compose.yml", []byte("services: {}\n"), []byte("A=1\n"), true, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	snap, err := readComposeSnapshot(dir)
+	if err != nil || snap.ComposeFile != "docker-compose.yml" || string(snap.Compose) != "services: {}\n" || !snap.HasEnv || string(snap.Env) != "A=1\n" {
+		t.Fatalf("snapshot after restore: %+v %v", snap, err)
+	}
+
+	// 已改名为 compose.yaml 时写入当前使用的文件；版本没有 .env 时删除现有 .env
+	if err := os.Rename(filepath.Join(dir, "docker-compose.yml"), filepath.Join(dir, "compose.yaml")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := restoreComposeFiles(dir, "../docker-compose.yml", []byte("services:\n  web: {}\n"), nil, false, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	snap, err = readComposeSnapshot(dir)
+	if err != nil || snap.ComposeFile != "compose.yaml" || snap.HasEnv {
+		t.Fatalf("restore into renamed file: %+v %v", snap, err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "docker-compose.yml")); !os.IsNotExist(err) {
+		t.Fatal("restore should not create a second compose file")
+	}
+}
+
+func TestRestoreComposeExtraFiles(t *testing.T) {
+	dir := t.TempDir()
+	extra := map[string][]byte{
+		"docker-compose.prod.yml": []byte("services:\n  web:\n    image: nginx:1\n"),
+		"env/prod.env":            []byte("MODE=prod\n"),
+	}
+	if _, err := restoreComposeFiles(dir, "docker-compose.yml", []byte("services: {}\n"), nil, false, extra, nil); err != nil {
+		t.Fatal(err)
+	}
+	for rel, want := range extra {
+		got, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(rel)))
+		if err != nil || string(got) != string(want) {
+			t.Fatalf("%s = %q, %v", rel, got, err)
+		}
+	}
+
+	if _, err := restoreComposeFiles(dir, "docker-compose.yml", []byte("x"), nil, false, map[string][]byte{"../escape.yml": nil}, nil); err == nil {
+		t.Fatal("paths outside the project should be rejected")
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, "docker-compose.yml")); string(data) != "services: {}\n" {
+		t.Fatalf("rejected restore should not touch files, got %q", data)
+	}
+
+	// 当前使用但不属于目标版本的额外文件会被删除
+	current := []string{"docker-compose.prod.yml", "env/prod.env", "docker-compose.debug.yml"}
+	if err := os.WriteFile(filepath.Join(dir, "docker-compose.debug.yml"), []byte("services: {}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	removed, err := restoreComposeFiles(dir, "docker-compose.yml", []byte("services: {}\n"), nil, false, extra, current)
+	if err != nil || !reflect.DeepEqual(removed, []string{"docker-compose.debug.yml"}) {
+		t.Fatalf("removed = %v, %v", removed, err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "docker-compose.debug.yml")); !os.IsNotExist(err) {
+		t.Fatal("extra file absent from the version should be removed")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "env", "prod.env")); err != nil {
+		t.Fatalf("extra file in the version should be kept: %v", err)
+	}
+
+	hashes := composeExtraHashes(extra)
+	if len(hashes) != 2 || !sameExtraHashes(hashes, composeExtraHashes(extra)) {
+		t.Fatalf("hashes = %v", hashes)
+	}
+	if sameExtraHashes(hashes, nil) || !sameExtraHashes(nil, composeExtraHashes(nil)) {
+		t.Fatal("sameExtraHashes mismatch")
+	}
+}
@@ -0,0 +1,348 @@
+package api
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"dockerpanel/backend/pkg/database"
+
+	"github.com/gin-gonic/gin"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	composeWebhookMaxBody = 1 << 20
+	composeWebhookTimeout = 30 * time.Minute
+)
+
+func hashWebhookToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// verifyWebhookSignature 校验请求体的 HMAC-SHA256 签名，支持 "sha256=<hex>"（GitHub 格式）与纯十六进制
+func verifyWebhookSignature(secret string, body []byte, signature string) bool {
+	signature = strings.TrimPrefix(strings.TrimSpace(signature), "sha256=")
+	got, err := hex.DecodeString(signature)
+	if err != nil || len(got) != sha256.Size {
+		return false
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hmac.Equal(got, mac.Sum(nil))
+}
+
+func webhookSignature(c *gin.Context) string {
+	if s := c.GetHeader("X-Hub-Signature-256"); s != "" {
+		return s
+	}
+	return c.GetHeader("X-Webhook-Signature")
+}
+
+// composeServiceNames 读取项目 compose 中定义的服务名
+func composeServiceNames(projectDir string) (map[string]bool, error) {
+	composePath, err := findComposeFile(projectDir)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(composePath)
+	if err != nil {
+		return nil, err
+	}
+	var root struct {
+		Services map[string]yaml.Node `yaml:"services"`
+	}
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return nil, err
+	}
+	names := make(map[string]bool, len(root.Services))
+	for name := range root.Services {
+		names[name] = true
+	}
+	return names, nil
+}
+
+// parseWebhookServices 从 JSON 请求体中读取可选的 services 列表，其他格式的请求体（如代码托管平台的推送事件）忽略
+func parseWebhookServices(body []byte, known map[string]bool) ([]string, error) {
+	var payload struct {
+		Services []string `json:"services"`
+	}
+	if len(body) == 0 || json.Unmarshal(body, &payload) != nil {
+		return nil, nil
+	}
+	var services []string
+	seen := make(map[string]bool)
+	for _, s := range payload.Services {
+		s = strings.TrimSpace(s)
+		if s == "" || seen[s] {
+			continue
+		}
+		if !known[s] {
+			return nil, fmt.Errorf("服务 %q 不存在", s)
+		}
+		seen[s] = true
+		services = append(services, s)
+	}
+	return services, nil
+}
+
+// startComposeUpdateTask 以任务形式拉取镜像并重新部署，进度可通过 /compose/tasks/:id/events 查看；项目正忙时返回 errComposeProjectBusy
+func startComposeUpdateTask(project string, services []string, trigger string) (string, error) {
+	mu := composeProjectLock(project)
+	if !mu.TryLock() {
+		return "", errComposeProjectBusy
+	}
+	taskID := fmt.Sprintf("%d", time.Now().UnixNano())
+	_ = database.UpsertTask(taskID, "compose_update", "pending")
+
+	go func() {
+		defer mu.Unlock()
+		seq := int64(0)
+		appendLog := func(level string, message string) {
+			if level == "" {
+				level = "info"
+				if strings.Contains(message, "error") || strings.Contains(message, "Error") {
+					level = "error"
+				}
+			}
+			seq++
+			_ = database.AppendTaskLogWithSeq(taskID, seq, time.Now(), level, message)
+		}
+		_ = database.UpsertTask(taskID, "compose_update", "running")
+		target := "全部服务"
+		if len(services) > 0 {
+			target = strings.Join(services, ", ")
+		}
+		appendLog("info", fmt.Sprintf("开始更新项目 %s（%s，触发方式：%s）", project, target, trigger))
+
+		ctx, cancel := context.WithTimeout(context.Background(), composeWebhookTimeout)
+		defer cancel()
+		projectDir := filepath.Join(getProjectsBaseDir(), project)
+		if err := runComposeUpdate(ctx, projectDir, services, appendLog); err != nil {
+			_ = database.FinishTask(taskID, "error", nil, err.Error())
+			_ = database.SaveNotification(&database.Notification{
+				Type:    "error",
+				Message: fmt.Sprintf("Compose 项目 %s 更新失败（%s）：%s", project, trigger, err.Error()),
+			})
+			return
+		}
+		_ = database.FinishTask(taskID, "success", gin.H{"project": project, "services": services}, "")
+		_ = database.SaveNotification(&database.Notification{
+			Type:    "success",
+			Message: fmt.Sprintf("Compose 项目 %s 已更新（%s）", project, trigger),
+		})
+	}()
+	return taskID, nil
+}
+
+// composeWebhookTrigger 外部系统（如 CI）推送镜像后调用，拉取镜像并重新部署项目
+func composeWebhookTrigger(c *gin.Context) {
+	name, ok := validateComposeProjectName(c.Param("name"))
+	token := webhookToken(c)
+	if !ok || token == "" {
+		respondError(c, http.StatusUnauthorized, "令牌无效", nil)
+		return
+	}
+	hook, err := database.GetComposeWebhookByToken(name, hashWebhookToken(token))
+	if err != nil || !hook.Enabled {
+		respondError(c, http.StatusUnauthorized, "令牌无效", nil)
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, composeWebhookMaxBody+1))
+	if err != nil {
+		respondError(c, http.StatusBadRequest, "读取请求体失败", err)
+		return
+	}
+	if len(body) > composeWebhookMaxBody {
+		respondError(c, http.StatusRequestEntityTooLarge, "请求体过大", nil)
+		return
+	}
+	if hook.HMACSecret != "" && !verifyWebhookSignature(hook.HMACSecret, body, webhookSignature(c)) {
+		respondError(c, http.StatusUnauthorized, "签名校验失败", nil)
+		return
+	}
+	if isSelfProjectName(name) {
+		respondError(c, http.StatusForbidden, "容器化部署模式下，禁止管理自身项目", nil)
+		return
+	}
+
+	known, err := composeServiceNames(filepath.Join(getProjectsBaseDir(), name))
+	if err != nil {
+		respondError(c, http.StatusNotFound, "项目不存在或配置文件无法解析", err)
+		return
+	}
+	services, err := parseWebhookServices(body, known)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+
+	trigger := "Webhook"
+	if hook.Name != "" {
+		trigger = "Webhook " + hook.Name
+	}
+	taskID, err := startComposeUpdateTask(name, services, trigger)
+	if err != nil {
+		respondError(c, http.StatusConflict, err.Error(), nil)
+		return
+	}
+	_ = database.MarkComposeWebhookTriggered(hook.ID, taskID)
+	c.JSON(http.StatusAccepted, gin.H{
+		"message":    "更新任务已提交",
+		"taskId":     taskID,
+		"eventsPath": "/api/compose/tasks/" + taskID + "/events",
+	})
+}
+
+// composeWebhookResponse 回调配置，令牌仅在创建或重置时返回
+type composeWebhookResponse struct {
+	database.ComposeWebhook
+	Signed bool   `json:"signed"`
+	Path   string `json:"path"`
+	Token  string `json:"token,omitempty"`
+}
+
+func newComposeWebhookResponse(w database.ComposeWebhook, token string) composeWebhookResponse {
+	return composeWebhookResponse{ComposeWebhook: w, Signed: w.HMACSecret != "", Path: "/api/hooks/compose/" + w.Project, Token: token}
+}
+
+type composeWebhookRequest struct {
+	Name            string  `json:"name"`
+	Enabled         *bool   `json:"enabled"`
+	HMACSecret      *string `json:"hmacSecret"` // 为 null 时保持不变，空串表示取消签名校验
+	RegenerateToken bool    `json:"regenerateToken"`
+}
+
+// lookupComposeWebhook 解析路径中的项目名与回调 ID，失败时已写入响应
+func lookupComposeWebhook(c *gin.Context) (database.ComposeWebhook, bool) {
+	name, ok := validateComposeProjectName(c.Param("name"))
+	if !ok {
+		respondError(c, http.StatusBadRequest, "项目名不合法：仅支持小写字母/数字，且可包含 _ -，并以字母或数字开头", nil)
+		return database.ComposeWebhook{}, false
+	}
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, "无效的回调 ID", err)
+		return database.ComposeWebhook{}, false
+	}
+	w, err := database.GetComposeWebhook(name, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			respondError(c, http.StatusNotFound, "回调不存在", nil)
+		} else {
+			respondError(c, http.StatusInternalServerError, "获取回调失败", err)
+		}
+		return database.ComposeWebhook{}, false
+	}
+	return w, true
+}
+
+func listComposeWebhooks(c *gin.Context) {
+	name, ok := validateComposeProjectName(c.Param("name"))
+	if !ok {
+		respondError(c, http.StatusBadRequest, "项目名不合法：仅支持小写字母/数字，且可包含 _ -，并以字母或数字开头", nil)
+		return
+	}
+	list, err := database.ListComposeWebhooks(name)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "获取回调失败", err)
+		return
+	}
+	out := make([]composeWebhookResponse, 0, len(list))
+	for _, w := range list {
+		out = append(out, newComposeWebhookResponse(w, ""))
+	}
+	c.JSON(http.StatusOK, out)
+}
+
+func createComposeWebhook(c *gin.Context) {
+	name, ok := validateComposeProjectName(c.Param("name"))
+	if !ok {
+		respondError(c, http.StatusBadRequest, "项目名不合法：仅支持小写字母/数字，且可包含 _ -，并以字母或数字开头", nil)
+		return
+	}
+	if forbidIfSelfProject(c, name) {
+		return
+	}
+	var req composeWebhookRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			respondError(c, http.StatusBadRequest, "无效的请求数据", err)
+			return
+		}
+	}
+	if _, err := os.Stat(filepath.Join(getProjectsBaseDir(), name)); err != nil {
+		respondError(c, http.StatusBadRequest, "项目目录不存在", err)
+		return
+	}
+	token := newWebhookToken()
+	w := database.ComposeWebhook{
+		Project:   name,
+		Name:      strings.TrimSpace(req.Name),
+		TokenHash: hashWebhookToken(token),
+		Enabled:   req.Enabled == nil || *req.Enabled,
+	}
+	if req.HMACSecret != nil {
+		w.HMACSecret = *req.HMACSecret
+	}
+	if err := database.SaveComposeWebhook(&w); err != nil {
+		respondError(c, http.StatusInternalServerError, "保存回调失败", err)
+		return
+	}
+	c.JSON(http.StatusOK, newComposeWebhookResponse(w, token))
+}
+
+func updateComposeWebhook(c *gin.Context) {
+	w, ok := lookupComposeWebhook(c)
+	if !ok {
+		return
+	}
+	var req composeWebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, "无效的请求数据", err)
+		return
+	}
+	w.Name = strings.TrimSpace(req.Name)
+	if req.Enabled != nil {
+		w.Enabled = *req.Enabled
+	}
+	if req.HMACSecret != nil {
+		w.HMACSecret = *req.HMACSecret
+	}
+	token := ""
+	if req.RegenerateToken {
+		token = newWebhookToken()
+		w.TokenHash = hashWebhookToken(token)
+	}
+	if err := database.SaveComposeWebhook(&w); err != nil {
+		respondError(c, http.StatusInternalServerError, "保存回调失败", err)
+		return
+	}
+	c.JSON(http.StatusOK, newComposeWebhookResponse(w, token))
+}
+
+func deleteComposeWebhook(c *gin.Context) {
+	w, ok := lookupComposeWebhook(c)
+	if !ok {
+		return
+	}
+	if err := database.DeleteComposeWebhook(w.Project, w.ID); err != nil {
+		respondError(c, http.StatusInternalServerError, "删除回调失败", err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "回调已删除"})
+}
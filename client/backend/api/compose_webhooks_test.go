@@ -0,0 +1,75 @@
+package api
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestVerifyWebhookSignature(t *testing.T) {
+	body := []byte(`{"services":["api"]}`)
+	mac := hmac.New(sha256.New, []byte("s3cret"))
+	mac.Write(body)
+	sig := hex.EncodeToString(mac.Sum(nil))
+
+	if !verifyWebhookSignature("s3cret", body, "sha256="+sig) || !verifyWebhookSignature("s3cret", body, sig) {
+		t.Fatal("valid signature rejected")
+	}
+	if verifyWebhookSignature("other", body, sig) || verifyWebhookSignature("s3cret", []byte(`{}`), sig) {
+		t.Fatal("signature with wrong secret or body accepted")
+	}
+	if verifyWebhookSignature("s3cret", body, "") || verifyWebhookSignature("s3cret", body, "sha256=zz") {
+		t.Fatal("malformed signature accepted")
+	}
+}
+
+func TestParseWebhookServices(t *testing.T) {
+	known := map[string]bool{"api": true, "worker": true}
+	got, err := parseWebhookServices([]byte(`{"services":["api"," api ","worker"]}`), known)
+	if err != nil || len(got) != 2 || got[0] != "api" || got[1] != "worker" {
+		t.Fatalf("services: %v %v", got, err)
+	}
+	if _, err := parseWebhookServices([]byte(`{"services":["db"]}`), known); err == nil {
+		t.Fatal("unknown service should be rejected")
+	}
+	// 代码托管平台的推送事件等其他请求体视为更新全部服务
+	for _, body := range []string{"", `{"ref":"refs/heads/main"}`, "payload=x"} {
+		if got, err := parseWebhookServices([]byte(body), known); err != nil || got != nil {
+			t.Fatalf("%q: %v %v", body, got, err)
+		}
+	}
+}
+
+func TestComposeServiceNames(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "docker-compose.yml"), []byte("services:\n  api:\n    image: a\n  db:\n    image: b\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	names, err := composeServiceNames(dir)
+	if err != nil || len(names) != 2 || !names["api"] || !names["db"] {
+		t.Fatalf("names: %v %v", names, err)
+	}
+}
+
+func TestWebhookToken(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest("POST", "/api/hooks/compose/shop?token=q", nil)
+	if webhookToken(c) != "q" {
+		t.Fatal("query token")
+	}
+	c.Request.Header.Set("X-Gitlab-Token", "g")
+	if webhookToken(c) != "g" {
+		t.Fatal("gitlab header should take precedence over query")
+	}
+	c.Request.Header.Set("X-Webhook-Token", "h")
+	if webhookToken(c) != "h" {
+		t.Fatal("X-Webhook-Token header")
+	}
+}
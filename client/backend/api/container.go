@@ -21,7 +21,6 @@ import (
 	"github.com/docker/docker/api/types/container"
 	"github.com/docker/docker/api/types/filters"
 	"github.com/docker/docker/api/types/network"
-	"github.com/docker/go-connections/nat"
 	"github.com/gin-gonic/gin"
 )
 
@@ -97,8 +96,45 @@ func RegisterContainerRoutes(r *gin.RouterGroup) {
 	group := r.Group("/containers")
 	{
 		group.GET("", ListContainers)
+		group.GET("/filters", listContainerFilters)
+		group.POST("/filters", saveContainerFilter)
+		group.DELETE("/filters/:id", deleteContainerFilter)
+		group.GET("/health/events", listHealthEvents)
+		group.GET("/diagnostics", listContainerDiagnostics)
+		group.GET("/diagnostics/:id", getContainerDiagnostic)
+		group.DELETE("/diagnostics/:id", deleteContainerDiagnostic)
+		group.GET("/logs/archive/config", getLogArchiveConfig)
+		group.PUT("/logs/archive/config", updateLogArchiveConfig)
+		group.GET("/logs/archive/sources", listLogArchiveSources)
+		group.GET("/logs/archive/search", searchLogArchive)
+		group.GET("/logs/alerts", listLogAlertRules)
+		group.POST("/logs/alerts", createLogAlertRule)
+		group.GET("/logs/alerts/events", listLogAlertEvents)
+		group.PUT("/logs/alerts/:id", updateLogAlertRule)
+		group.DELETE("/logs/alerts/:id", deleteLogAlertRule)
 		group.GET("/:id", GetContainer) // 添加获取单个容器详情的路由
 		group.POST("/create", createContainer)
+		group.POST("/parse-run", parseRunCommand)
+		group.POST("/bulk", bulkContainers)
+		group.GET("/bulk/:id", getComposeTask)
+		group.GET("/bulk/:id/events", composeTaskEvents)
+		group.POST("/:id/resources", updateContainerResources)
+		group.POST("/:id/clone", cloneContainer)
+		group.GET("/:id/compose", previewContainerCompose)
+		group.POST("/:id/compose", adoptContainerToCompose)
+		group.GET("/:id/diff", getContainerDiff)
+		group.POST("/:id/commit", commitContainer)
+		group.GET("/:id/files", listContainerFiles)
+		group.GET("/:id/files/download", downloadContainerFiles)
+		group.POST("/:id/files/upload", uploadContainerFiles)
+		group.GET("/:id/files/content", getContainerFileContent)
+		group.PUT("/:id/files/content", saveContainerFileContent)
+		group.GET("/:id/processes", listContainerProcesses)
+		group.GET("/:id/processes/stream", streamContainerProcesses)
+		group.POST("/:id/processes/:pid/signal", signalContainerProcess)
+		group.GET("/:id/health", getContainerHealth)
+		group.GET("/:id/diagnostics", listContainerDiagnosticsByContainer)
+		group.POST("/:id/health/autoheal/reset", resetContainerAutoheal)
 		group.POST("/:id/rename", renameContainer) // 添加重命名容器路由（通过创建新容器实现）
 		group.POST("/:id/start", startContainer)
 		group.POST("/:id/stop", stopContainer)
@@ -111,9 +147,11 @@ func RegisterContainerRoutes(r *gin.RouterGroup) {
 		group.GET("/:id/update/events", updateContainerEvents)
 		group.GET("/:id/logs", getContainerLogs)
 		group.GET("/:id/logs/events", getContainerLogsEvents)
+		group.GET("/:id/logs/export", exportContainerLogs)
 		group.GET("/:id/terminal", containerTerminal)
 		group.GET("/:id/stats", getContainerStats)
 		group.GET("/:id/stats/stream", streamContainerStats)
+		group.GET("/:id/sbom", getContainerSBOM)
 	}
 }
 
@@ -146,6 +184,11 @@ func pruneContainers(c *gin.Context) {
 
 // 容器列表
 func ListContainers(c *gin.Context) {
+	lq, ok := parseContainerListQuery(c)
+	if !ok {
+		return
+	}
+
 	cli, ok := getDockerClient(c)
 	if !ok {
 		return
@@ -171,7 +214,17 @@ func ListContainers(c *gin.Context) {
 	}
 	updateMap := getCachedImageUpdateMap(updateTTL)
 
-	containersWithDetails := make([]gin.H, 0, len(containers))
+	// 先基于列表字段完成筛选、排序与分页，只对当前页执行 inspect
+	containers = filterContainers(containers, lq.Terms, updateMap)
+	_ = sortContainers(containers, lq.Sort)
+	c.Header("X-Total-Count", strconv.Itoa(len(containers)))
+	if lq.PageSize > 0 {
+		c.Header("X-Page", strconv.Itoa(lq.Page))
+		c.Header("X-Page-Size", strconv.Itoa(lq.PageSize))
+		containers = paginateContainers(containers, lq.Page, lq.PageSize)
+	}
+
+	containersWithDetails := make([]gin.H, len(containers))
 	ctx := context.Background()
 
 	inspectConcurrency := 8
@@ -185,14 +238,13 @@ func ListContainers(c *gin.Context) {
 	}
 
 	var wg sync.WaitGroup
-	var mu sync.Mutex
 	sem := make(chan struct{}, inspectConcurrency)
 
-	for _, container := range containers {
-		container := container
+	for i, container := range containers {
+		i, container := i, container
 
 		if inspectConcurrency == 0 || (inspectRunningOnly && strings.ToLower(container.State) != "running") {
-			containersWithDetails = append(containersWithDetails, buildContainerListItem(container, types.ContainerJSON{}, false, updateMap))
+			containersWithDetails[i] = buildContainerListItem(container, types.ContainerJSON{}, false, updateMap)
 			continue
 		}
 
@@ -203,10 +255,7 @@ func ListContainers(c *gin.Context) {
 			defer func() { <-sem }()
 			inspect, err := cli.ContainerInspect(ctx, container.ID)
 			inspectOk := err == nil
-			item := buildContainerListItem(container, inspect, inspectOk, updateMap)
-			mu.Lock()
-			containersWithDetails = append(containersWithDetails, item)
-			mu.Unlock()
+			containersWithDetails[i] = buildContainerListItem(container, inspect, inspectOk, updateMap)
 		}()
 	}
 	wg.Wait()
@@ -828,128 +877,6 @@ func removeContainer(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "容器已删除"})
 }
 
-// CreateContainerRequest 定义创建容器的请求结构
-type CreateContainerRequest struct {
-	Name          string   `json:"name"`
-	Image         string   `json:"image"`
-	Ports         []string `json:"ports"`          // 格式: "8080:80"
-	Env           []string `json:"env"`            // 格式: "KEY=VALUE"
-	Volumes       []string `json:"volumes"`        // 格式: "/host/path:/container/path"
-	NetworkMode   string   `json:"network_mode"`   // 网络模式
-	RestartPolicy string   `json:"restart_policy"` // 重启策略
-	Command       []string `json:"command"`        // 启动命令
-	Entrypoint    []string `json:"entrypoint"`     // 入口点
-	Privileged    bool     `json:"privileged"`     // 特权模式
-	Devices       []string `json:"devices"`        // 格式: "/dev/sda:/dev/xda:rwm"
-}
-
-// createContainer 创建容器
-func createContainer(c *gin.Context) {
-	var req CreateContainerRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		respondError(c, http.StatusBadRequest, "无效的请求参数", err)
-		return
-	}
-
-	cli, ok := getDockerClient(c)
-	if !ok {
-		return
-	}
-	defer cli.Close()
-
-	// 1. 拉取镜像（如果不存在）
-	// 这里简化处理，假设镜像已存在或自动拉取
-	// 实际生产中可能需要显式拉取镜像
-
-	// 2. 配置容器
-	// 如果前端传来的 Command 为空，不要强制设为空切片，让 Docker 使用镜像默认的 CMD
-	// 但是这里 req.Command 即使为空切片，赋给 Cmd 后，Docker API 可能会认为是要清空 CMD
-	// 所以如果为空，最好设为 nil
-	var cmd []string
-	if len(req.Command) > 0 {
-		cmd = req.Command
-	}
-
-	var entrypoint []string
-	if len(req.Entrypoint) > 0 {
-		entrypoint = req.Entrypoint
-	}
-
-	config := &container.Config{
-		Image:      req.Image,
-		Env:        req.Env,
-		Cmd:        cmd,
-		Entrypoint: entrypoint,
-	}
-
-	// 解析设备映射
-	var devices []container.DeviceMapping
-	for _, d := range req.Devices {
-		parts := strings.Split(d, ":")
-		if len(parts) >= 2 {
-			dev := container.DeviceMapping{
-				PathOnHost:        parts[0],
-				PathInContainer:   parts[1],
-				CgroupPermissions: "rwm",
-			}
-			if len(parts) > 2 {
-				dev.CgroupPermissions = parts[2]
-			}
-			devices = append(devices, dev)
-		}
-	}
-
-	hostConfig := &container.HostConfig{
-		RestartPolicy: container.RestartPolicy{
-			Name: req.RestartPolicy,
-		},
-		NetworkMode:  container.NetworkMode(req.NetworkMode),
-		Binds:        req.Volumes,
-		PortBindings: make(map[nat.Port][]nat.PortBinding),
-		Privileged:   req.Privileged,
-		Resources: container.Resources{
-			Devices: devices,
-		},
-	}
-
-	// 处理端口映射
-	for _, p := range req.Ports {
-		// 格式: "HostPort:ContainerPort" 或 "HostPort:ContainerPort/Protocol"
-		parts := strings.Split(p, ":")
-		if len(parts) >= 2 {
-			hostPort := parts[0]
-			containerPortRaw := parts[1]
-
-			// 解析协议
-			protocol := "tcp"
-			containerPort := containerPortRaw
-			if strings.Contains(containerPortRaw, "/") {
-				cpParts := strings.Split(containerPortRaw, "/")
-				containerPort = cpParts[0]
-				protocol = cpParts[1]
-			}
-
-			portKey, portErr := nat.NewPort(protocol, containerPort)
-			if portErr == nil {
-				hostConfig.PortBindings[portKey] = []nat.PortBinding{
-					{
-						HostPort: hostPort,
-					},
-				}
-			}
-		}
-	}
-
-	// 3. 创建容器
-	resp, err := cli.ContainerCreate(context.Background(), config, hostConfig, nil, nil, req.Name)
-	if err != nil {
-		respondError(c, http.StatusInternalServerError, "创建容器失败", err)
-		return
-	}
-
-	c.JSON(http.StatusOK, gin.H{"id": resp.ID, "message": "容器创建成功"})
-}
-
 // RenameContainerRequest 定义重命名容器的请求结构
 type RenameContainerRequest struct {
 	NewName string `json:"newName" binding:"required"`
@@ -1009,20 +936,27 @@ func updateContainerEvents(c *gin.Context) {
 	}
 	defer cli.Close()
 
-	ctx := c.Request.Context()
-
 	send := func(msg string) {
 		c.Writer.Write([]byte(fmt.Sprintf("event: log\ndata: %s\n\n", msg)))
 		c.Writer.Flush()
 	}
 
+	if _, err := recreateContainerWithLatestImage(c.Request.Context(), cli, id, send); err != nil {
+		send("error: " + err.Error())
+		return
+	}
+	send("success: 容器更新完成")
+}
+
+// recreateContainerWithLatestImage 拉取容器镜像的最新版本并以原配置重建容器，返回新容器 ID。
+// 旧容器先重命名备份，新容器启动失败时回滚；send 接收 "info: ..." / "warn: ..." 形式的进度
+func recreateContainerWithLatestImage(ctx context.Context, cli *docker.Client, id string, send func(string)) (string, error) {
 	send("info: 开始检查容器配置...")
 
 	// 1. Inspect old container
 	oldContainer, err := cli.ContainerInspect(ctx, id)
 	if err != nil {
-		send(fmt.Sprintf("error: 获取容器信息失败: %v", err))
-		return
+		return "", fmt.Errorf("获取容器信息失败: %v", err)
 	}
 
 	imageName := oldContainer.Config.Image
@@ -1033,8 +967,7 @@ func updateContainerEvents(c *gin.Context) {
 	// 2. Pull image
 	out, err := cli.ImagePull(ctx, imageName, types.ImagePullOptions{})
 	if err != nil {
-		send(fmt.Sprintf("error: 拉取镜像失败: %v", err))
-		return
+		return "", fmt.Errorf("拉取镜像失败: %v", err)
 	}
 	io.Copy(io.Discard, out)
 	out.Close()
@@ -1046,17 +979,15 @@ func updateContainerEvents(c *gin.Context) {
 	send(fmt.Sprintf("info: 重命名旧容器为 %s...", backupName))
 
 	if err := cli.ContainerRename(ctx, id, backupName); err != nil {
-		send(fmt.Sprintf("error: 重命名容器失败: %v", err))
-		return
+		return "", fmt.Errorf("重命名容器失败: %v", err)
 	}
 
 	// 4. Stop old container
 	send("info: 停止旧容器...")
 	timeout := 10
 	if err := cli.ContainerStop(ctx, id, container.StopOptions{Timeout: &timeout}); err != nil {
-		send(fmt.Sprintf("error: 停止容器失败: %v. 正在回滚...", err))
 		cli.ContainerRename(ctx, id, containerName)
-		return
+		return "", fmt.Errorf("停止容器失败: %v，已回滚", err)
 	}
 
 	// 5. Create new container
@@ -1077,20 +1008,18 @@ func updateContainerEvents(c *gin.Context) {
 
 	createdBody, err := cli.ContainerCreate(ctx, oldContainer.Config, oldContainer.HostConfig, networkingConfig, nil, containerName)
 	if err != nil {
-		send(fmt.Sprintf("error: 创建新容器失败: %v. 正在回滚...", err))
 		cli.ContainerRename(ctx, id, containerName)
 		cli.ContainerStart(ctx, id, types.ContainerStartOptions{})
-		return
+		return "", fmt.Errorf("创建新容器失败: %v，已回滚", err)
 	}
 
 	// 6. Start new container
 	send("info: 启动新容器...")
 	if err := cli.ContainerStart(ctx, createdBody.ID, types.ContainerStartOptions{}); err != nil {
-		send(fmt.Sprintf("error: 启动新容器失败: %v. 正在回滚...", err))
 		cli.ContainerRemove(ctx, createdBody.ID, types.ContainerRemoveOptions{Force: true})
 		cli.ContainerRename(ctx, id, containerName)
 		cli.ContainerStart(ctx, id, types.ContainerStartOptions{})
-		return
+		return "", fmt.Errorf("启动新容器失败: %v，已回滚", err)
 	}
 
 	// 7. Remove old container
@@ -1098,6 +1027,5 @@ func updateContainerEvents(c *gin.Context) {
 	if err := cli.ContainerRemove(ctx, id, types.ContainerRemoveOptions{Force: true}); err != nil {
 		send(fmt.Sprintf("warn: 移除旧容器失败: %v (新容器已正常运行)", err))
 	}
-
-	send("success: 容器更新完成")
+	return createdBody.ID, nil
 }
@@ -0,0 +1,349 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"dockerpanel/backend/pkg/database"
+	"dockerpanel/backend/pkg/docker"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	bulkDefaultConcurrency = 4
+	bulkMaxConcurrency     = 16
+)
+
+// bulkActions 批量操作支持的动作
+var bulkActions = map[string]bool{
+	"start": true, "stop": true, "restart": true, "pause": true,
+	"unpause": true, "kill": true, "remove": true, "update": true, "resources": true,
+}
+
+// BulkContainerRequest 批量容器操作，ids / labels / project 至少指定一种。
+// ids 命中的容器与 labels / project 筛选出的容器取并集；labels 与 project 同时指定时需同时满足（取交集）
+type BulkContainerRequest struct {
+	Action      string   `json:"action"`
+	IDs         []string `json:"ids"`     // 容器 ID（可为前缀）或名称
+	Labels      []string `json:"labels"`  // 标签选择器，"key" 或 "key=value"，多个条件需同时满足
+	Project     string   `json:"project"` // compose 项目名
+	Concurrency int      `json:"concurrency"`
+
+	Timeout       *int                            `json:"timeout"`        // stop / restart 等待秒数
+	Signal        string                          `json:"signal"`         // kill 使用的信号，默认 KILL
+	Force         bool                            `json:"force"`          // remove 时强制删除运行中的容器
+	RemoveVolumes bool                            `json:"remove_volumes"` // remove 时同时删除匿名卷
+	Resources     *ContainerResourceUpdateRequest `json:"resources"`      // resources 操作的资源参数（仅调整资源限制，不拉取镜像重建）
+}
+
+// bulkTarget 批量操作的目标容器
+type bulkTarget struct {
+	ID     string `json:"id"`
+	Name   string `json:"name"`
+	Reason string `json:"reason,omitempty"` // 跳过原因
+}
+
+// bulkItemResult 单个容器的执行结果
+type bulkItemResult struct {
+	ID       string   `json:"id"`
+	Name     string   `json:"name"`
+	Success  bool     `json:"success"`
+	Error    string   `json:"error,omitempty"`
+	Detail   gin.H    `json:"detail,omitempty"`
+	Elapsed  float64  `json:"elapsed"` // 秒
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// matchesLabelSelectors 判断标签是否满足全部选择器
+func matchesLabelSelectors(labels map[string]string, selectors []string) bool {
+	for _, sel := range selectors {
+		key, value, hasValue := strings.Cut(strings.TrimSpace(sel), "=")
+		v, ok := labels[strings.TrimSpace(key)]
+		if !ok || (hasValue && v != strings.TrimSpace(value)) {
+			return false
+		}
+	}
+	return true
+}
+
+// resolveBulkTargets 从容器列表中按条件选出目标，返回目标、被保护跳过的容器以及未匹配到的 ID
+func resolveBulkTargets(list []types.Container, req *BulkContainerRequest) (targets []bulkTarget, skipped []bulkTarget, missing []string) {
+	selected := make(map[string]bool)
+	pick := func(ct types.Container) {
+		if selected[ct.ID] {
+			return
+		}
+		selected[ct.ID] = true
+		name := ""
+		if len(ct.Names) > 0 {
+			name = strings.TrimPrefix(ct.Names[0], "/")
+		}
+		t := bulkTarget{ID: ct.ID, Name: name}
+		if isSelfOrProtectedContainer(ct.ID, name, ct.Image, ct.Labels) {
+			t.Reason = "禁止管理自身容器"
+			skipped = append(skipped, t)
+			return
+		}
+		targets = append(targets, t)
+	}
+
+	for _, raw := range req.IDs {
+		ref := strings.TrimPrefix(strings.TrimSpace(raw), "/")
+		if ref == "" {
+			continue
+		}
+		found := false
+		for _, ct := range list {
+			if ct.ID == ref || (len(ref) >= 4 && strings.HasPrefix(ct.ID, ref)) || containerHasName(ct, ref) {
+				pick(ct)
+				found = true
+				break
+			}
+		}
+		if !found {
+			missing = append(missing, ref)
+		}
+	}
+
+	project := strings.TrimSpace(req.Project)
+	if len(req.Labels) > 0 || project != "" {
+		for _, ct := range list {
+			if project != "" && ct.Labels["com.docker.compose.project"] != project {
+				continue
+			}
+			if len(req.Labels) > 0 && !matchesLabelSelectors(ct.Labels, req.Labels) {
+				continue
+			}
+			pick(ct)
+		}
+	}
+
+	sort.Slice(targets, func(i, j int) bool { return targets[i].Name < targets[j].Name })
+	return targets, skipped, missing
+}
+
+func containerHasName(ct types.Container, name string) bool {
+	for _, n := range ct.Names {
+		if strings.TrimPrefix(n, "/") == name {
+			return true
+		}
+	}
+	return false
+}
+
+// bulkContainers 批量执行容器操作，返回任务 ID，逐个容器的结果通过任务事件流推送
+func bulkContainers(c *gin.Context) {
+	var req BulkContainerRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, "无效的请求参数", err)
+		return
+	}
+
+	var errs fieldErrors
+	req.Action = strings.ToLower(strings.TrimSpace(req.Action))
+	if !bulkActions[req.Action] {
+		errs.add("action", "不支持的操作，可用 start / stop / restart / pause / unpause / kill / remove / update / resources")
+	}
+	if len(req.IDs) == 0 && len(req.Labels) == 0 && strings.TrimSpace(req.Project) == "" {
+		errs.add("ids", "请至少指定 ids、labels 或 project 之一")
+	}
+	if req.Concurrency == 0 {
+		req.Concurrency = bulkDefaultConcurrency
+	} else if req.Concurrency < 1 || req.Concurrency > bulkMaxConcurrency {
+		errs.add("concurrency", "并发数取值范围为 1-%d", bulkMaxConcurrency)
+	}
+	if req.Timeout != nil && *req.Timeout < 0 {
+		errs.add("timeout", "超时时间不能为负数")
+	}
+	if req.Action == "kill" {
+		sig, err := normalizeProcessSignal(req.Signal)
+		if err != nil {
+			errs.add("signal", "%v", err)
+		}
+		if strings.TrimSpace(req.Signal) == "" {
+			sig = "KILL"
+		}
+		req.Signal = sig
+	}
+	var updateCfg container.UpdateConfig
+	if req.Action == "resources" {
+		if req.Resources == nil {
+			errs.add("resources", "resources 操作需要提供 resources")
+		} else {
+			var uerrs fieldErrors
+			updateCfg, uerrs = buildContainerUpdateConfig(req.Resources)
+			for _, e := range uerrs {
+				errs = append(errs, fieldError{Field: "resources." + e.Field, Message: e.Message})
+			}
+		}
+	}
+	if len(errs) > 0 {
+		respondFieldErrors(c, errs)
+		return
+	}
+
+	cli, ok := getDockerClient(c)
+	if !ok {
+		return
+	}
+	defer cli.Close()
+
+	list, err := cli.ContainerList(c.Request.Context(), types.ContainerListOptions{All: true})
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "获取容器列表失败", err)
+		return
+	}
+	targets, skipped, missing := resolveBulkTargets(list, &req)
+	if len(targets) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "没有匹配到可操作的容器",
+			"skipped": skipped,
+			"missing": missing,
+		})
+		return
+	}
+
+	taskID := fmt.Sprintf("%d", time.Now().UnixNano())
+	_ = database.UpsertTask(taskID, "container_bulk", "pending")
+	go runContainerBulkTask(taskID, &req, updateCfg, targets)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "批量操作已开始",
+		"taskId":  taskID,
+		"total":   len(targets),
+		"targets": targets,
+		"skipped": skipped,
+		"missing": missing,
+	})
+}
+
+// runContainerBulkTask 以有限并发执行批量操作，每个容器完成后写入一条任务日志
+func runContainerBulkTask(taskID string, req *BulkContainerRequest, updateCfg container.UpdateConfig, targets []bulkTarget) {
+	var mu sync.Mutex
+	seq := int64(0)
+	appendLog := func(logType string, message string) {
+		mu.Lock()
+		defer mu.Unlock()
+		seq++
+		_ = database.AppendTaskLogWithSeq(taskID, seq, time.Now(), logType, message)
+	}
+	_ = database.UpsertTask(taskID, "container_bulk", "running")
+
+	cli, err := docker.NewDockerClient()
+	if err != nil {
+		appendLog("error", "连接 Docker 失败: "+err.Error())
+		_ = database.FinishTask(taskID, "error", nil, err.Error())
+		return
+	}
+	defer cli.Close()
+
+	appendLog("info", fmt.Sprintf("开始批量 %s，共 %d 个容器，并发 %d", req.Action, len(targets), req.Concurrency))
+
+	// 同一 compose 文件的写回需要串行，避免并发覆盖
+	var composeMu sync.Mutex
+	ctx := context.Background()
+	results := make([]bulkItemResult, len(targets))
+	sem := make(chan struct{}, req.Concurrency)
+	var wg sync.WaitGroup
+	for i, t := range targets {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, t bulkTarget) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			start := time.Now()
+			res := bulkItemResult{ID: t.ID, Name: t.Name}
+			detail, warnings, err := runBulkAction(ctx, cli, req, updateCfg, t, &composeMu)
+			res.Elapsed = time.Since(start).Seconds()
+			res.Detail = detail
+			res.Warnings = warnings
+			if err != nil {
+				res.Error = err.Error()
+				appendLog("error", fmt.Sprintf("%s: %s 失败: %v", t.Name, req.Action, err))
+			} else {
+				res.Success = true
+				appendLog("success", fmt.Sprintf("%s: %s 成功", t.Name, req.Action))
+			}
+			results[i] = res
+		}(i, t)
+	}
+	wg.Wait()
+
+	failed := 0
+	for _, r := range results {
+		if !r.Success {
+			failed++
+		}
+	}
+	summary := gin.H{"action": req.Action, "total": len(results), "failed": failed, "results": results}
+	log.Printf("批量容器操作完成: action=%s total=%d failed=%d", req.Action, len(results), failed)
+	if failed > 0 {
+		appendLog("warning", fmt.Sprintf("批量 %s 完成：成功 %d，失败 %d", req.Action, len(results)-failed, failed))
+		_ = database.FinishTask(taskID, "error", summary, fmt.Sprintf("%d 个容器操作失败", failed))
+		_ = database.SaveNotification(&database.Notification{
+			Type:    "error",
+			Message: fmt.Sprintf("批量 %s：%d 个容器中有 %d 个失败", req.Action, len(results), failed),
+		})
+		return
+	}
+	appendLog("success", fmt.Sprintf("批量 %s 完成：%d 个容器全部成功", req.Action, len(results)))
+	_ = database.FinishTask(taskID, "success", summary, "")
+}
+
+// runBulkAction 对单个容器执行动作
+func runBulkAction(ctx context.Context, cli *docker.Client, req *BulkContainerRequest, updateCfg container.UpdateConfig, t bulkTarget, composeMu *sync.Mutex) (gin.H, []string, error) {
+	switch req.Action {
+	case "start":
+		return nil, nil, cli.ContainerStart(ctx, t.ID, types.ContainerStartOptions{})
+	case "stop":
+		return nil, nil, cli.ContainerStop(ctx, t.ID, container.StopOptions{Timeout: req.Timeout})
+	case "restart":
+		return nil, nil, cli.ContainerRestart(ctx, t.ID, container.StopOptions{Timeout: req.Timeout})
+	case "pause":
+		return nil, nil, cli.ContainerPause(ctx, t.ID)
+	case "unpause":
+		return nil, nil, cli.ContainerUnpause(ctx, t.ID)
+	case "kill":
+		return nil, nil, cli.ContainerKill(ctx, t.ID, req.Signal)
+	case "remove":
+		return nil, nil, cli.ContainerRemove(ctx, t.ID, types.ContainerRemoveOptions{Force: req.Force, RemoveVolumes: req.RemoveVolumes})
+	case "update":
+		// 与单个容器的更新一致：拉取最新镜像并重建，进度中的警告计入结果
+		var warnings []string
+		newID, err := recreateContainerWithLatestImage(ctx, cli, t.ID, func(msg string) {
+			if w, ok := strings.CutPrefix(msg, "warn: "); ok {
+				warnings = append(warnings, w)
+			}
+		})
+		if err != nil {
+			return nil, warnings, err
+		}
+		return gin.H{"new_id": newID}, warnings, nil
+	case "resources":
+		resp, err := cli.ContainerUpdate(ctx, t.ID, updateCfg)
+		if err != nil {
+			return nil, nil, err
+		}
+		var detail gin.H
+		if req.Resources.WriteCompose {
+			inspect, err := cli.ContainerInspect(ctx, t.ID)
+			if err == nil && inspect.Config != nil {
+				composeMu.Lock()
+				detail = gin.H{"compose": writeResourceUpdateToCompose(inspect.Config.Labels, req.Resources)}
+				composeMu.Unlock()
+			}
+		}
+		return detail, resp.Warnings, nil
+	}
+	return nil, nil, fmt.Errorf("不支持的操作: %s", req.Action)
+}
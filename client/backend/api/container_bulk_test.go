@@ -0,0 +1,55 @@
+package api
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/docker/docker/api/types"
+)
+
+func TestMatchesLabelSelectors(t *testing.T) {
+	labels := map[string]string{"tier": "web", "com.docker.compose.project": "shop", "maintenance": ""}
+	if !matchesLabelSelectors(labels, []string{"tier=web", "maintenance"}) {
+		t.Fatalf("selectors should match")
+	}
+	if matchesLabelSelectors(labels, []string{"tier=db"}) || matchesLabelSelectors(labels, []string{"missing"}) {
+		t.Fatalf("selectors should not match")
+	}
+	if !matchesLabelSelectors(labels, nil) {
+		t.Fatalf("empty selector matches everything")
+	}
+}
+
+func TestResolveBulkTargets(t *testing.T) {
+	list := []types.Container{
+		{ID: strings.Repeat("a", 64), Names: []string{"/shop-web-1"}, Labels: map[string]string{"com.docker.compose.project": "shop", "tier": "web"}},
+		{ID: strings.Repeat("b", 64), Names: []string{"/shop-db-1"}, Labels: map[string]string{"com.docker.compose.project": "shop", "tier": "db"}},
+		{ID: strings.Repeat("c", 64), Names: []string{"/cache"}, Labels: map[string]string{"tier": "web"}},
+	}
+
+	targets, skipped, missing := resolveBulkTargets(list, &BulkContainerRequest{
+		IDs:     []string{"cache", "aaaa", "ghost"},
+		Project: "shop",
+	})
+	var names []string
+	for _, t := range targets {
+		names = append(names, t.Name)
+	}
+	if !reflect.DeepEqual(names, []string{"cache", "shop-db-1", "shop-web-1"}) {
+		t.Fatalf("targets = %v", names)
+	}
+	if len(skipped) != 0 || !reflect.DeepEqual(missing, []string{"ghost"}) {
+		t.Fatalf("skipped = %v missing = %v", skipped, missing)
+	}
+
+	targets, _, _ = resolveBulkTargets(list, &BulkContainerRequest{Project: "shop", Labels: []string{"tier=web"}})
+	if len(targets) != 1 || targets[0].Name != "shop-web-1" {
+		t.Fatalf("project and label selectors should intersect: %+v", targets)
+	}
+
+	// 过短的 ID 前缀不做模糊匹配
+	if _, _, missing := resolveBulkTargets(list, &BulkContainerRequest{IDs: []string{"a"}}); len(missing) != 1 {
+		t.Fatalf("short prefix should not match, missing = %v", missing)
+	}
+}
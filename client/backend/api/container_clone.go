@@ -0,0 +1,933 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"dockerpanel/backend/pkg/database"
+	"dockerpanel/backend/pkg/docker"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/mount"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/go-connections/nat"
+	"github.com/gin-gonic/gin"
+)
+
+// CloneContainerRequest 以现有容器为模板创建新容器，未提供的字段沿用原容器配置
+type CloneContainerRequest struct {
+	Name    string            `json:"name"`
+	Image   string            `json:"image"`   // 为空则沿用原镜像
+	Env     []string          `json:"env"`     // "KEY=VALUE" 覆盖或追加，"KEY" 表示删除该变量
+	Ports   *[]string         `json:"ports"`   // 不传沿用原端口映射，传空数组表示不发布端口
+	Labels  map[string]string `json:"labels"`  // 合并到原标签，值为空表示删除
+	Command []string          `json:"command"` // 为空则沿用原命令
+	Start   bool              `json:"start"`
+}
+
+var (
+	anonymousVolumeNamePattern  = regexp.MustCompile(`^[0-9a-f]{64}$`)
+	composeServiceNameSanitizer = regexp.MustCompile(`[^a-z0-9_.-]+`)
+)
+
+// composeLabelPrefix compose 写入的管理标签，克隆或转换时需要去掉，否则会被误认为属于原项目
+const composeLabelPrefix = "com.docker.compose."
+
+// buildCloneSpec 基于原容器的 inspect 结果生成新容器的创建参数
+func buildCloneSpec(src types.ContainerJSON, req *CloneContainerRequest) (*containerCreateSpec, fieldErrors) {
+	var errs fieldErrors
+	if src.Config == nil || src.HostConfig == nil {
+		errs.add("", "原容器配置不完整")
+		return nil, errs
+	}
+
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		errs.add("name", "新容器名不能为空")
+	} else if !containerNamePattern.MatchString(name) {
+		errs.add("name", "容器名仅支持字母、数字、_ . -，且以字母或数字开头")
+	} else if strings.TrimPrefix(name, "/") == strings.TrimPrefix(src.Name, "/") {
+		errs.add("name", "新容器名不能与原容器相同")
+	}
+
+	cfg := *src.Config
+	hostCfg := *src.HostConfig
+
+	if img := strings.TrimSpace(req.Image); img != "" {
+		cfg.Image = img
+	}
+	// 默认主机名等于容器短 ID，克隆时交给 Docker 重新生成
+	if len(src.ID) >= 12 && cfg.Hostname == src.ID[:12] {
+		cfg.Hostname = ""
+	}
+	cfg.MacAddress = ""
+
+	env := append([]string(nil), cfg.Env...)
+	for i, e := range req.Env {
+		key, _, hasValue := strings.Cut(e, "=")
+		if !envKeyPattern.MatchString(key) {
+			errs.add(fmt.Sprintf("env[%d]", i), "环境变量格式应为 KEY=VALUE")
+			continue
+		}
+		env = upsertEnvEntry(env, key, e, hasValue)
+	}
+	cfg.Env = env
+
+	labels := make(map[string]string, len(cfg.Labels)+len(req.Labels))
+	for k, v := range cfg.Labels {
+		if !strings.HasPrefix(k, composeLabelPrefix) {
+			labels[k] = v
+		}
+	}
+	for k, v := range req.Labels {
+		if strings.TrimSpace(k) == "" {
+			errs.add("labels", "标签名不能为空")
+			continue
+		}
+		if v == "" {
+			delete(labels, k)
+		} else {
+			labels[k] = v
+		}
+	}
+	cfg.Labels = labels
+
+	if len(req.Command) > 0 {
+		cfg.Cmd = req.Command
+	}
+
+	if req.Ports != nil {
+		exposed := nat.PortSet{}
+		for p := range cfg.ExposedPorts {
+			exposed[p] = struct{}{}
+		}
+		bindings := nat.PortMap{}
+		for i, p := range *req.Ports {
+			mappings, err := nat.ParsePortSpec(strings.TrimSpace(p))
+			if err != nil {
+				errs.add(fmt.Sprintf("ports[%d]", i), "端口映射格式错误: %v", err)
+				continue
+			}
+			for _, m := range mappings {
+				exposed[m.Port] = struct{}{}
+				bindings[m.Port] = append(bindings[m.Port], m.Binding)
+			}
+		}
+		cfg.ExposedPorts = exposed
+		hostCfg.PortBindings = bindings
+	}
+
+	spec := &containerCreateSpec{
+		Name:       name,
+		Config:     &cfg,
+		HostConfig: &hostCfg,
+	}
+
+	// 原容器接入的网络：主网络在创建时指定，其余网络创建后再 connect。
+	// 固定 IP 与原容器冲突，因此只保留别名。
+	if src.NetworkSettings != nil && !hostCfg.NetworkMode.IsContainer() {
+		names := make([]string, 0, len(src.NetworkSettings.Networks))
+		for n := range src.NetworkSettings.Networks {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		primary := string(hostCfg.NetworkMode)
+		if primary == "" || primary == "default" {
+			primary = "bridge"
+		}
+		spec.Networking = &network.NetworkingConfig{EndpointsConfig: map[string]*network.EndpointSettings{}}
+		for _, n := range names {
+			att := NetworkAttachment{Name: n, Aliases: userNetworkAliases(src, n)}
+			if n == primary {
+				spec.Networking.EndpointsConfig[n] = endpointSettingsFor(att)
+			} else if !hostCfg.NetworkMode.IsHost() && !hostCfg.NetworkMode.IsNone() {
+				spec.ExtraNetworks = append(spec.ExtraNetworks, att)
+			}
+		}
+	}
+	return spec, errs
+}
+
+// upsertEnvEntry 按变量名覆盖环境变量；keep 为 false 时删除该变量
+func upsertEnvEntry(env []string, key string, entry string, keep bool) []string {
+	out := env[:0]
+	replaced := false
+	for _, e := range env {
+		k, _, _ := strings.Cut(e, "=")
+		if k != key {
+			out = append(out, e)
+			continue
+		}
+		if keep && !replaced {
+			out = append(out, entry)
+			replaced = true
+		}
+	}
+	if keep && !replaced {
+		out = append(out, entry)
+	}
+	return out
+}
+
+// userNetworkAliases 返回用户定义的网络别名，去掉 Docker 自动添加的容器短 ID 与 compose 服务名
+func userNetworkAliases(src types.ContainerJSON, networkName string) []string {
+	ep := src.NetworkSettings.Networks[networkName]
+	if ep == nil {
+		return nil
+	}
+	shortID := ""
+	if len(src.ID) >= 12 {
+		shortID = src.ID[:12]
+	}
+	var service string
+	if src.Config != nil {
+		service = src.Config.Labels[composeLabelPrefix+"service"]
+	}
+	aliases := make([]string, 0, len(ep.Aliases))
+	for _, a := range ep.Aliases {
+		if a == shortID || a == service || a == strings.TrimPrefix(src.Name, "/") {
+			continue
+		}
+		aliases = append(aliases, a)
+	}
+	if len(aliases) == 0 {
+		return nil
+	}
+	return aliases
+}
+
+// cloneContainer 复制容器（镜像、环境变量、挂载、网络、标签）为新容器，可覆盖部分参数
+func cloneContainer(c *gin.Context) {
+	id := c.Param("id")
+	if forbidIfSelfContainer(c, id) {
+		return
+	}
+	var req CloneContainerRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, "无效的请求参数", err)
+		return
+	}
+
+	cli, ok := getDockerClient(c)
+	if !ok {
+		return
+	}
+	defer cli.Close()
+
+	ctx := context.Background()
+	src, err := cli.ContainerInspect(ctx, id)
+	if err != nil {
+		respondError(c, http.StatusNotFound, "容器不存在", err)
+		return
+	}
+	spec, errs := buildCloneSpec(src, &req)
+	if len(errs) > 0 {
+		respondFieldErrors(c, errs)
+		return
+	}
+
+	resp, err := cli.ContainerCreate(ctx, spec.Config, spec.HostConfig, spec.Networking, nil, spec.Name)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "克隆容器失败", err)
+		return
+	}
+	for _, n := range spec.ExtraNetworks {
+		if err := cli.NetworkConnect(ctx, n.Name, resp.ID, endpointSettingsFor(n)); err != nil {
+			_ = cli.ContainerRemove(ctx, resp.ID, types.ContainerRemoveOptions{Force: true})
+			respondError(c, http.StatusInternalServerError, fmt.Sprintf("接入网络 %s 失败", n.Name), err)
+			return
+		}
+	}
+
+	started := false
+	if req.Start {
+		if err := cli.ContainerStart(ctx, resp.ID, types.ContainerStartOptions{}); err != nil {
+			c.JSON(http.StatusOK, gin.H{"id": resp.ID, "message": "容器已克隆，但启动失败（请检查端口映射是否与原容器冲突）", "warnings": resp.Warnings, "startError": err.Error()})
+			return
+		}
+		started = true
+	}
+	log.Printf("克隆容器: %s -> %s", strings.TrimPrefix(src.Name, "/"), spec.Name)
+	c.JSON(http.StatusOK, gin.H{"id": resp.ID, "message": "容器克隆成功", "warnings": resp.Warnings, "started": started})
+}
+
+// composeConversion 由容器生成的 compose 文档及转换过程中的提示
+type composeConversion struct {
+	Service  string            // 单容器转换时的服务名
+	Services map[string]string // 容器名 -> 服务名
+	Compose  string
+	Dotenv   string // 拆分环境变量时生成的 .env 内容
+	Warnings []string
+}
+
+// containerComposeSource 待转换的容器；ImageConfig 为镜像自带配置，与之相同的环境变量、命令、标签等不会写入
+type containerComposeSource struct {
+	Container   types.ContainerJSON
+	ImageConfig *container.Config
+	Service     string
+}
+
+// escapeComposeValue compose 会对 $ 做变量插值，原样保留需要写成 $$
+func escapeComposeValue(s string) string {
+	return strings.ReplaceAll(s, "$", "$$")
+}
+
+func escapeComposeList(list []string) []string {
+	out := make([]string, len(list))
+	for i, s := range list {
+		out[i] = escapeComposeValue(s)
+	}
+	return out
+}
+
+// containerToCompose 将单个 docker run 创建的容器转换为 compose 项目，环境变量直接写在服务中
+func containerToCompose(ctr types.ContainerJSON, imageCfg *container.Config, service string) (*composeConversion, error) {
+	return containersToCompose([]containerComposeSource{{Container: ctr, ImageConfig: imageCfg, Service: service}}, false)
+}
+
+// composeServiceParts 单个容器转换后的服务定义及其引用的顶层网络、卷
+type composeServiceParts struct {
+	Service  map[string]any
+	Env      []string // 未转义的 KEY=VALUE，由调用方决定内联还是拆分到 .env
+	Networks map[string]any
+	Volumes  map[string]any
+}
+
+// composeServiceFromContainer 将容器配置转换为 compose 服务定义。
+// resolve 将容器名/ID 映射为同一项目中的服务名，用于改写 network_mode、links、volumes_from 引用。
+func composeServiceFromContainer(src containerComposeSource, resolve func(ref string) string, warn func(format string, args ...any)) (*composeServiceParts, error) {
+	ctr := src.Container
+	if ctr.ContainerJSONBase == nil || ctr.Config == nil || ctr.HostConfig == nil {
+		return nil, fmt.Errorf("容器配置不完整")
+	}
+	imageCfg := src.ImageConfig
+	if imageCfg == nil {
+		imageCfg = &container.Config{}
+	}
+	cfg := ctr.Config
+	hc := ctr.HostConfig
+
+	svc := map[string]any{"image": cfg.Image}
+	if name := strings.TrimPrefix(ctr.Name, "/"); name != "" {
+		svc["container_name"] = name
+	}
+	dependsOn := make(map[string]bool)
+
+	// 环境变量：去掉与镜像默认值完全一致的条目
+	imageEnv := make(map[string]bool, len(imageCfg.Env))
+	for _, e := range imageCfg.Env {
+		imageEnv[e] = true
+	}
+	env := make([]string, 0, len(cfg.Env))
+	for _, e := range cfg.Env {
+		if !imageEnv[e] {
+			env = append(env, e)
+		}
+	}
+
+	if !reflect.DeepEqual([]string(cfg.Entrypoint), []string(imageCfg.Entrypoint)) && len(cfg.Entrypoint) > 0 {
+		svc["entrypoint"] = escapeComposeList(cfg.Entrypoint)
+	}
+	if !reflect.DeepEqual([]string(cfg.Cmd), []string(imageCfg.Cmd)) && len(cfg.Cmd) > 0 {
+		svc["command"] = escapeComposeList(cfg.Cmd)
+	}
+	if cfg.WorkingDir != "" && cfg.WorkingDir != imageCfg.WorkingDir {
+		svc["working_dir"] = cfg.WorkingDir
+	}
+	if cfg.User != "" && cfg.User != imageCfg.User {
+		svc["user"] = cfg.User
+	}
+	if cfg.Hostname != "" && !(len(ctr.ID) >= 12 && cfg.Hostname == ctr.ID[:12]) {
+		svc["hostname"] = cfg.Hostname
+	}
+	if cfg.Domainname != "" {
+		svc["domainname"] = cfg.Domainname
+	}
+	if cfg.Tty {
+		svc["tty"] = true
+	}
+	if cfg.OpenStdin {
+		svc["stdin_open"] = true
+	}
+	if cfg.StopSignal != "" && cfg.StopSignal != imageCfg.StopSignal {
+		svc["stop_signal"] = cfg.StopSignal
+	}
+
+	labels := make(map[string]string)
+	for k, v := range cfg.Labels {
+		if strings.HasPrefix(k, composeLabelPrefix) || imageCfg.Labels[k] == v {
+			continue
+		}
+		labels[k] = escapeComposeValue(v)
+	}
+	if len(labels) > 0 {
+		svc["labels"] = labels
+	}
+
+	if h := cfg.Healthcheck; h != nil && !reflect.DeepEqual(h, imageCfg.Healthcheck) && len(h.Test) > 0 {
+		if h.Test[0] == "NONE" {
+			svc["healthcheck"] = map[string]any{"disable": true}
+		} else {
+			health := map[string]any{"test": escapeComposeList(h.Test)}
+			if h.Interval > 0 {
+				health["interval"] = h.Interval.String()
+			}
+			if h.Timeout > 0 {
+				health["timeout"] = h.Timeout.String()
+			}
+			if h.StartPeriod > 0 {
+				health["start_period"] = h.StartPeriod.String()
+			}
+			if h.Retries > 0 {
+				health["retries"] = h.Retries
+			}
+			svc["healthcheck"] = health
+		}
+	}
+
+	// 端口
+	portKeys := make([]string, 0, len(hc.PortBindings))
+	for p := range hc.PortBindings {
+		portKeys = append(portKeys, string(p))
+	}
+	sort.Strings(portKeys)
+	ports := make([]string, 0, len(portKeys))
+	for _, key := range portKeys {
+		p := nat.Port(key)
+		target := p.Port()
+		if p.Proto() != "tcp" {
+			target += "/" + p.Proto()
+		}
+		for _, b := range hc.PortBindings[p] {
+			switch {
+			case b.HostPort == "":
+				ports = append(ports, target)
+			case b.HostIP == "" || b.HostIP == "0.0.0.0":
+				ports = append(ports, b.HostPort+":"+target)
+			case strings.Contains(b.HostIP, ":"):
+				ports = append(ports, "["+b.HostIP+"]:"+b.HostPort+":"+target)
+			default:
+				ports = append(ports, b.HostIP+":"+b.HostPort+":"+target)
+			}
+		}
+	}
+	if len(ports) > 0 {
+		svc["ports"] = ports
+	}
+
+	// 挂载：命名卷作为 external 卷复用原有数据
+	topVolumes := make(map[string]any)
+	volumes := make([]string, 0, len(ctr.Mounts))
+	tmpfs := make([]string, 0)
+	for _, m := range ctr.Mounts {
+		suffix := ""
+		if !m.RW {
+			suffix = ":ro"
+		}
+		switch m.Type {
+		case mount.TypeBind:
+			volumes = append(volumes, m.Source+":"+m.Destination+suffix)
+		case mount.TypeVolume:
+			if m.Name == "" || anonymousVolumeNamePattern.MatchString(m.Name) {
+				volumes = append(volumes, m.Destination)
+				if m.Name != "" {
+					warn("匿名卷 %s 不会被复用，重建后 %s 中的数据将为空", m.Name[:12], m.Destination)
+				}
+				continue
+			}
+			volumes = append(volumes, m.Name+":"+m.Destination+suffix)
+			topVolumes[m.Name] = map[string]any{"external": true}
+		case mount.TypeTmpfs:
+			if _, ok := hc.Tmpfs[m.Destination]; !ok {
+				tmpfs = append(tmpfs, m.Destination)
+			}
+		default:
+			warn("不支持转换 %s 类型的挂载: %s", m.Type, m.Destination)
+		}
+	}
+	sort.Strings(volumes)
+	if len(volumes) > 0 {
+		svc["volumes"] = volumes
+	}
+	for dst, opts := range hc.Tmpfs {
+		if opts != "" {
+			dst += ":" + opts
+		}
+		tmpfs = append(tmpfs, dst)
+	}
+	sort.Strings(tmpfs)
+	if len(tmpfs) > 0 {
+		svc["tmpfs"] = tmpfs
+	}
+
+	// 网络：自定义网络作为 external 网络接入，默认 bridge 将改用项目默认网络
+	topNetworks := make(map[string]any)
+	mode := hc.NetworkMode
+	switch {
+	case mode.IsHost() || mode.IsNone():
+		svc["network_mode"] = string(mode)
+	case mode.IsContainer():
+		if target := resolve(mode.ConnectedContainer()); target != "" {
+			svc["network_mode"] = "service:" + target
+			dependsOn[target] = true
+		} else {
+			svc["network_mode"] = string(mode)
+			warn("network_mode %s 引用了其他容器，请确认目标容器在重建后仍然存在", mode)
+		}
+	default:
+		names := make([]string, 0)
+		if ctr.NetworkSettings != nil {
+			for n := range ctr.NetworkSettings.Networks {
+				names = append(names, n)
+			}
+		}
+		sort.Strings(names)
+		networks := make(map[string]any)
+		for _, n := range names {
+			if n == "bridge" {
+				warn("默认 bridge 网络将替换为项目默认网络，容器间请改用服务名互访")
+				continue
+			}
+			ep := ctr.NetworkSettings.Networks[n]
+			opts := make(map[string]any)
+			if aliases := userNetworkAliases(ctr, n); len(aliases) > 0 {
+				opts["aliases"] = aliases
+			}
+			if ep.IPAMConfig != nil && ep.IPAMConfig.IPv4Address != "" {
+				opts["ipv4_address"] = ep.IPAMConfig.IPv4Address
+			}
+			if ep.IPAMConfig != nil && ep.IPAMConfig.IPv6Address != "" {
+				opts["ipv6_address"] = ep.IPAMConfig.IPv6Address
+			}
+			if len(opts) > 0 {
+				networks[n] = opts
+			} else {
+				networks[n] = nil
+			}
+			topNetworks[n] = map[string]any{"external": true}
+		}
+		if len(networks) > 0 {
+			svc["networks"] = networks
+		}
+	}
+
+	if rp := hc.RestartPolicy; rp.Name != "" && rp.Name != "no" {
+		restart := string(rp.Name)
+		if rp.Name == "on-failure" && rp.MaximumRetryCount > 0 {
+			restart = fmt.Sprintf("on-failure:%d", rp.MaximumRetryCount)
+		}
+		svc["restart"] = restart
+	}
+	if hc.Privileged {
+		svc["privileged"] = true
+	}
+	if hc.ReadonlyRootfs {
+		svc["read_only"] = true
+	}
+	if hc.Init != nil && *hc.Init {
+		svc["init"] = true
+	}
+	if len(hc.CapAdd) > 0 {
+		svc["cap_add"] = []string(hc.CapAdd)
+	}
+	if len(hc.CapDrop) > 0 {
+		svc["cap_drop"] = []string(hc.CapDrop)
+	}
+	if len(hc.SecurityOpt) > 0 {
+		svc["security_opt"] = hc.SecurityOpt
+	}
+	if len(hc.Devices) > 0 {
+		devices := make([]string, 0, len(hc.Devices))
+		for _, d := range hc.Devices {
+			dev := d.PathOnHost + ":" + d.PathInContainer
+			if d.CgroupPermissions != "" && d.CgroupPermissions != "rwm" {
+				dev += ":" + d.CgroupPermissions
+			}
+			devices = append(devices, dev)
+		}
+		svc["devices"] = devices
+	}
+	if len(hc.DNS) > 0 {
+		svc["dns"] = hc.DNS
+	}
+	if len(hc.DNSSearch) > 0 {
+		svc["dns_search"] = hc.DNSSearch
+	}
+	if len(hc.DNSOptions) > 0 {
+		svc["dns_opt"] = hc.DNSOptions
+	}
+	if len(hc.ExtraHosts) > 0 {
+		svc["extra_hosts"] = hc.ExtraHosts
+	}
+	// --link 的格式为 "/目标容器:/本容器/别名"
+	links := make([]string, 0)
+	for _, l := range hc.Links {
+		target, alias, _ := strings.Cut(l, ":")
+		target = strings.TrimPrefix(target, "/")
+		alias = path.Base(alias)
+		svcName := resolve(target)
+		if svcName == "" {
+			warn("--link %s 指向未选择的容器，未转换", target)
+			continue
+		}
+		dependsOn[svcName] = true
+		if alias != "" && alias != svcName {
+			links = append(links, svcName+":"+alias)
+		}
+	}
+	if len(links) > 0 {
+		sort.Strings(links)
+		svc["links"] = links
+	}
+	if len(hc.VolumesFrom) > 0 {
+		from := make([]string, 0, len(hc.VolumesFrom))
+		for _, v := range hc.VolumesFrom {
+			target, access, hasAccess := strings.Cut(v, ":")
+			ref := "container:" + target
+			if svcName := resolve(target); svcName != "" {
+				ref = svcName
+				dependsOn[svcName] = true
+			}
+			if hasAccess {
+				ref += ":" + access
+			}
+			from = append(from, ref)
+		}
+		svc["volumes_from"] = from
+	}
+	if hc.ShmSize > 0 && hc.ShmSize != 64*1024*1024 {
+		svc["shm_size"] = hc.ShmSize
+	}
+
+	// 资源限制
+	if hc.NanoCPUs > 0 {
+		svc["cpus"] = float64(hc.NanoCPUs) / 1e9
+	}
+	if hc.CPUShares > 0 {
+		svc["cpu_shares"] = hc.CPUShares
+	}
+	if hc.CPUQuota > 0 {
+		svc["cpu_quota"] = hc.CPUQuota
+	}
+	if hc.CPUPeriod > 0 {
+		svc["cpu_period"] = hc.CPUPeriod
+	}
+	if hc.CpusetCpus != "" {
+		svc["cpuset"] = hc.CpusetCpus
+	}
+	if hc.Memory > 0 {
+		svc["mem_limit"] = hc.Memory
+	}
+	if hc.MemoryReservation > 0 {
+		svc["mem_reservation"] = hc.MemoryReservation
+	}
+	if hc.MemorySwap != 0 && hc.Memory > 0 {
+		svc["memswap_limit"] = hc.MemorySwap
+	}
+	if hc.PidsLimit != nil && *hc.PidsLimit > 0 {
+		svc["pids_limit"] = *hc.PidsLimit
+	}
+	if len(hc.Ulimits) > 0 {
+		ulimits := make(map[string]any, len(hc.Ulimits))
+		for _, u := range hc.Ulimits {
+			if u.Soft == u.Hard {
+				ulimits[u.Name] = u.Soft
+			} else {
+				ulimits[u.Name] = map[string]any{"soft": u.Soft, "hard": u.Hard}
+			}
+		}
+		svc["ulimits"] = ulimits
+	}
+	if lc := hc.LogConfig; lc.Type != "" && (lc.Type != "json-file" || len(lc.Config) > 0) {
+		logging := map[string]any{"driver": lc.Type}
+		if len(lc.Config) > 0 {
+			logging["options"] = lc.Config
+		}
+		svc["logging"] = logging
+	}
+
+	if len(dependsOn) > 0 {
+		deps := make([]string, 0, len(dependsOn))
+		for d := range dependsOn {
+			deps = append(deps, d)
+		}
+		sort.Strings(deps)
+		svc["depends_on"] = deps
+	}
+
+	return &composeServiceParts{Service: svc, Env: env, Networks: topNetworks, Volumes: topVolumes}, nil
+}
+
+var dotenvKeyPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// containersToCompose 将一组容器转换为一个 compose 项目。
+// splitEnv 为 true 时环境变量写入 .env，服务中以 ${VAR} 引用；不同服务同名变量取值不同时加服务名前缀区分。
+func containersToCompose(sources []containerComposeSource, splitEnv bool) (*composeConversion, error) {
+	conv := &composeConversion{Services: make(map[string]string, len(sources)), Warnings: make([]string, 0)}
+	if len(sources) == 0 {
+		return nil, fmt.Errorf("未选择任何容器")
+	}
+	if len(sources) == 1 {
+		conv.Service = sources[0].Service
+	}
+
+	refs := make(map[string]string)
+	for _, src := range sources {
+		if src.Container.ContainerJSONBase == nil {
+			return nil, fmt.Errorf("容器配置不完整")
+		}
+		if _, exists := conv.Services[strings.TrimPrefix(src.Container.Name, "/")]; exists {
+			return nil, fmt.Errorf("容器 %s 重复", src.Container.Name)
+		}
+		conv.Services[strings.TrimPrefix(src.Container.Name, "/")] = src.Service
+		refs[strings.TrimPrefix(src.Container.Name, "/")] = src.Service
+		refs[src.Container.ID] = src.Service
+		if len(src.Container.ID) >= 12 {
+			refs[src.Container.ID[:12]] = src.Service
+		}
+	}
+	resolve := func(ref string) string { return refs[strings.TrimPrefix(ref, "/")] }
+
+	services := make(map[string]any, len(sources))
+	topNetworks := make(map[string]any)
+	topVolumes := make(map[string]any)
+	parts := make([]*composeServiceParts, len(sources))
+	for i, src := range sources {
+		if _, exists := services[src.Service]; exists {
+			return nil, fmt.Errorf("服务名 %s 重复", src.Service)
+		}
+		prefix := ""
+		if len(sources) > 1 {
+			prefix = src.Service + ": "
+		}
+		warn := func(format string, args ...any) {
+			conv.Warnings = append(conv.Warnings, prefix+fmt.Sprintf(format, args...))
+		}
+		p, err := composeServiceFromContainer(src, resolve, warn)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", src.Container.Name, err)
+		}
+		parts[i] = p
+		services[src.Service] = p.Service
+		for k, v := range p.Networks {
+			topNetworks[k] = v
+		}
+		for k, v := range p.Volumes {
+			topVolumes[k] = v
+		}
+	}
+
+	// 同名变量在各服务中的取值，用于判断 .env 中是否需要加服务名前缀
+	values := make(map[string]map[string]bool)
+	if splitEnv {
+		for _, p := range parts {
+			for _, e := range p.Env {
+				k, v, _ := strings.Cut(e, "=")
+				if values[k] == nil {
+					values[k] = make(map[string]bool)
+				}
+				values[k][v] = true
+			}
+		}
+	}
+	dotenv := make(map[string]string)
+	for i, p := range parts {
+		if len(p.Env) == 0 {
+			continue
+		}
+		env := make([]string, 0, len(p.Env))
+		for _, e := range p.Env {
+			k, v, _ := strings.Cut(e, "=")
+			if !splitEnv || !dotenvKeyPattern.MatchString(k) {
+				env = append(env, escapeComposeValue(e))
+				continue
+			}
+			name := k
+			if len(values[k]) > 1 {
+				name = strings.ToUpper(strings.NewReplacer("-", "_", ".", "_").Replace(sources[i].Service)) + "_" + k
+			}
+			dotenv[name] = quoteDotenvValue(v)
+			env = append(env, k+"=${"+name+"}")
+		}
+		p.Service["environment"] = env
+	}
+	conv.Dotenv = renderDotenvFromMapStable(dotenv)
+
+	root := map[string]any{"services": services}
+	if len(topNetworks) > 0 {
+		root["networks"] = topNetworks
+	}
+	if len(topVolumes) > 0 {
+		root["volumes"] = topVolumes
+	}
+	out, err := marshalComposeYAMLOrdered(root)
+	if err != nil {
+		return nil, err
+	}
+	conv.Compose = out
+	return conv, nil
+}
+
+// quoteDotenvValue 按 .env 语法转义取值：含特殊字符时使用单引号（不做变量插值），
+// 含单引号或换行时退回双引号转义
+func quoteDotenvValue(v string) string {
+	if v == "" || !strings.ContainsAny(v, " \t#$'\"\\\n`") {
+		return v
+	}
+	if !strings.ContainsAny(v, "'\n") {
+		return "'" + v + "'"
+	}
+	r := strings.NewReplacer("\\", "\\\\", "\"", "\\\"", "\n", "\\n", "$", "\\$")
+	return "\"" + r.Replace(v) + "\""
+}
+
+// defaultComposeServiceName 由容器名推导服务名（compose 服务名只允许小写字母、数字、_ . -）
+func defaultComposeServiceName(containerName string) string {
+	name := strings.ToLower(strings.TrimPrefix(containerName, "/"))
+	name = composeServiceNameSanitizer.ReplaceAllString(name, "-")
+	name = strings.Trim(name, "-_.")
+	if name == "" {
+		return "app"
+	}
+	return name
+}
+
+// loadContainerCompose 读取容器及其镜像配置并转换为 compose
+func loadContainerCompose(ctx context.Context, cli *docker.Client, id string, service string) (types.ContainerJSON, *composeConversion, error) {
+	sources, err := inspectComposeSources(ctx, cli, []string{id}, map[string]string{id: service})
+	if err != nil {
+		return types.ContainerJSON{}, nil, err
+	}
+	conv, err := containersToCompose(sources, false)
+	return sources[0].Container, conv, err
+}
+
+// previewContainerCompose 预览由容器生成的 compose 配置
+func previewContainerCompose(c *gin.Context) {
+	id := c.Param("id")
+	if forbidIfSelfContainer(c, id) {
+		return
+	}
+	service := strings.TrimSpace(c.Query("service"))
+	if service != "" && service != defaultComposeServiceName(service) {
+		respondError(c, http.StatusBadRequest, "服务名仅支持小写字母、数字、_ . -", nil)
+		return
+	}
+
+	cli, ok := getDockerClient(c)
+	if !ok {
+		return
+	}
+	defer cli.Close()
+
+	ctr, conv, err := loadContainerCompose(c.Request.Context(), cli, id, service)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, "生成 compose 配置失败", err)
+		return
+	}
+	project, _ := validateComposeProjectName(strings.ReplaceAll(defaultComposeServiceName(ctr.Name), ".", "-"))
+	c.JSON(http.StatusOK, gin.H{
+		"project":  project,
+		"service":  conv.Service,
+		"compose":  conv.Compose,
+		"warnings": conv.Warnings,
+	})
+}
+
+type adoptContainerRequest struct {
+	Project string `json:"project"`
+	Service string `json:"service"`
+	Compose string `json:"compose"` // 可选，前端编辑后的配置；为空则使用自动生成的结果
+	// Recreate 为 true 时用 compose 重建：先停止并改名原容器，up 成功后删除，失败则恢复
+	Recreate bool `json:"recreate"`
+}
+
+// adoptContainerToCompose 将 docker run 创建的容器转换为 compose 项目，保存到项目根目录下
+func adoptContainerToCompose(c *gin.Context) {
+	id := c.Param("id")
+	if forbidIfSelfContainer(c, id) {
+		return
+	}
+	var req adoptContainerRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, "无效的请求参数", err)
+		return
+	}
+	project, ok := validateComposeProjectName(req.Project)
+	if !ok {
+		respondError(c, http.StatusBadRequest, "项目名不合法：仅支持小写字母/数字，且可包含 _ -，并以字母或数字开头", nil)
+		return
+	}
+	if forbidIfSelfProject(c, project) {
+		return
+	}
+	service := strings.TrimSpace(req.Service)
+	if service != "" && service != defaultComposeServiceName(service) {
+		respondError(c, http.StatusBadRequest, "服务名仅支持小写字母、数字、_ . -", nil)
+		return
+	}
+
+	projectDir := filepath.Join(getProjectsBaseDir(), project)
+	if _, err := os.Stat(projectDir); err == nil {
+		respondError(c, http.StatusConflict, fmt.Sprintf("项目 '%s' 已存在", project), nil)
+		return
+	}
+
+	cli, ok := getDockerClient(c)
+	if !ok {
+		return
+	}
+	defer cli.Close()
+
+	ctr, conv, err := loadContainerCompose(c.Request.Context(), cli, id, service)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, "生成 compose 配置失败", err)
+		return
+	}
+	compose := conv.Compose
+	if strings.TrimSpace(req.Compose) != "" {
+		compose = req.Compose
+	}
+
+	if err := os.MkdirAll(projectDir, 0755); err != nil {
+		respondError(c, http.StatusInternalServerError, "创建项目目录失败", err)
+		return
+	}
+	if err := os.WriteFile(filepath.Join(projectDir, "docker-compose.yml"), []byte(compose), 0644); err != nil {
+		_ = os.RemoveAll(projectDir)
+		respondError(c, http.StatusInternalServerError, "保存配置文件失败", err)
+		return
+	}
+	log.Printf("容器已转换为 compose 项目: container=%s project=%s", strings.TrimPrefix(ctr.Name, "/"), project)
+
+	if !req.Recreate {
+		c.JSON(http.StatusOK, gin.H{"message": "compose 项目已创建", "project": project, "warnings": conv.Warnings})
+		return
+	}
+
+	taskID := fmt.Sprintf("%d", time.Now().UnixNano())
+	_ = database.UpsertTask(taskID, "container_adopt", "pending")
+	go runContainerAdoptTask(taskID, project, []adoptTarget{{
+		ID:      ctr.ID,
+		Name:    strings.TrimPrefix(ctr.Name, "/"),
+		Running: ctr.State != nil && ctr.State.Running,
+	}})
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":  "compose 项目已创建，正在重建容器",
+		"project":  project,
+		"taskId":   taskID,
+		"warnings": conv.Warnings,
+	})
+}
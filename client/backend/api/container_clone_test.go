@@ -0,0 +1,167 @@
+package api
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/mount"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/go-connections/nat"
+	"gopkg.in/yaml.v3"
+)
+
+const fixtureContainerID = "0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+
+func fixtureContainer() types.ContainerJSON {
+	return types.ContainerJSON{
+		ContainerJSONBase: &types.ContainerJSONBase{
+			ID:   fixtureContainerID,
+			Name: "/My.Web",
+			HostConfig: &container.HostConfig{
+				NetworkMode: "appnet",
+				PortBindings: nat.PortMap{
+					"80/tcp":  {{HostIP: "", HostPort: "8080"}},
+					"53/udp":  {{HostIP: "127.0.0.1", HostPort: "5353"}},
+					"443/tcp": {{HostIP: "", HostPort: ""}},
+				},
+				RestartPolicy: container.RestartPolicy{Name: "on-failure", MaximumRetryCount: 3},
+				Resources:     container.Resources{Memory: 256 * 1024 * 1024, NanoCPUs: 500000000},
+				LogConfig:     container.LogConfig{Type: "json-file"},
+				ShmSize:       64 * 1024 * 1024,
+				Tmpfs:         map[string]string{"/run": "size=64m"},
+			},
+		},
+		Mounts: []types.MountPoint{
+			{Type: mount.TypeBind, Source: "/srv/html", Destination: "/usr/share/nginx/html", RW: false},
+			{Type: mount.TypeVolume, Name: "webdata", Destination: "/data", RW: true},
+			{Type: mount.TypeVolume, Name: strings.Repeat("a", 64), Destination: "/cache", RW: true},
+		},
+		Config: &container.Config{
+			Hostname: fixtureContainerID[:12],
+			Image:    "nginx:1.25",
+			Env:      []string{"PATH=/usr/bin", "TZ=Asia/Shanghai", "PRICE=$5"},
+			Cmd:      []string{"nginx", "-g", "daemon off;"},
+			Labels:   map[string]string{"app": "web", "maintainer": "nginx", "com.docker.compose.project": "old"},
+		},
+		NetworkSettings: &types.NetworkSettings{
+			Networks: map[string]*network.EndpointSettings{
+				"appnet": {Aliases: []string{fixtureContainerID[:12], "web"}, IPAMConfig: &network.EndpointIPAMConfig{IPv4Address: "172.20.0.10"}},
+				"bridge": {},
+			},
+		},
+	}
+}
+
+func TestContainerToCompose(t *testing.T) {
+	imageCfg := &container.Config{
+		Env:    []string{"PATH=/usr/bin"},
+		Cmd:    []string{"nginx", "-g", "daemon off;"},
+		Labels: map[string]string{"maintainer": "nginx"},
+	}
+	ctr := fixtureContainer()
+	ctr.Config.Healthcheck = &container.HealthConfig{Test: []string{"CMD-SHELL", "curl -f http://localhost/"}, Interval: 30 * time.Second, Retries: 3}
+
+	conv, err := containerToCompose(ctr, imageCfg, "web")
+	if err != nil {
+		t.Fatalf("containerToCompose: %v", err)
+	}
+	if !strings.HasPrefix(conv.Compose, "services:\n    web:\n        image: nginx:1.25\n        ports:") {
+		t.Fatalf("unexpected ordering:\n%s", conv.Compose)
+	}
+
+	var doc struct {
+		Services map[string]map[string]any `yaml:"services"`
+		Networks map[string]map[string]any `yaml:"networks"`
+		Volumes  map[string]map[string]any `yaml:"volumes"`
+	}
+	if err := yaml.Unmarshal([]byte(conv.Compose), &doc); err != nil {
+		t.Fatalf("generated compose is not valid yaml: %v\n%s", err, conv.Compose)
+	}
+	svc := doc.Services["web"]
+	if svc["container_name"] != "My.Web" || svc["restart"] != "on-failure:3" || svc["cpus"] != 0.5 || svc["mem_limit"] != 268435456 {
+		t.Fatalf("unexpected service: %+v", svc)
+	}
+	if _, ok := svc["command"]; ok {
+		t.Fatalf("command equal to image default should be omitted")
+	}
+	if _, ok := svc["hostname"]; ok {
+		t.Fatalf("default hostname should be omitted")
+	}
+	if _, ok := svc["logging"]; ok {
+		t.Fatalf("default logging should be omitted")
+	}
+	if !reflect.DeepEqual(svc["environment"], []any{"TZ=Asia/Shanghai", "PRICE=$$5"}) {
+		t.Fatalf("environment = %v", svc["environment"])
+	}
+	if !reflect.DeepEqual(svc["labels"], map[string]any{"app": "web"}) {
+		t.Fatalf("labels = %v", svc["labels"])
+	}
+	if !reflect.DeepEqual(svc["ports"], []any{"443", "127.0.0.1:5353:53/udp", "8080:80"}) {
+		t.Fatalf("ports = %v", svc["ports"])
+	}
+	if !reflect.DeepEqual(svc["volumes"], []any{"/cache", "/srv/html:/usr/share/nginx/html:ro", "webdata:/data"}) {
+		t.Fatalf("volumes = %v", svc["volumes"])
+	}
+	nets := svc["networks"].(map[string]any)
+	appnet := nets["appnet"].(map[string]any)
+	if !reflect.DeepEqual(appnet["aliases"], []any{"web"}) || appnet["ipv4_address"] != "172.20.0.10" {
+		t.Fatalf("networks = %v", nets)
+	}
+	if doc.Networks["appnet"]["external"] != true || doc.Volumes["webdata"]["external"] != true {
+		t.Fatalf("external resources = %v %v", doc.Networks, doc.Volumes)
+	}
+	if _, ok := doc.Volumes[strings.Repeat("a", 64)]; ok {
+		t.Fatalf("anonymous volume should not be declared")
+	}
+	if health := svc["healthcheck"].(map[string]any); health["interval"] != "30s" || health["retries"] != 3 {
+		t.Fatalf("healthcheck = %v", health)
+	}
+	if len(conv.Warnings) != 2 {
+		t.Fatalf("warnings = %q", conv.Warnings)
+	}
+}
+
+func TestBuildCloneSpec(t *testing.T) {
+	src := fixtureContainer()
+	ports := []string{"9090:80"}
+	spec, errs := buildCloneSpec(src, &CloneContainerRequest{
+		Name:   "web-copy",
+		Env:    []string{"TZ=UTC", "PRICE", "NEW=1"},
+		Ports:  &ports,
+		Labels: map[string]string{"app": "", "role": "copy"},
+	})
+	if len(errs) > 0 {
+		t.Fatalf("unexpected field errors: %+v", errs)
+	}
+	if spec.Config.Hostname != "" || spec.Config.Image != "nginx:1.25" {
+		t.Fatalf("unexpected config: %+v", spec.Config)
+	}
+	if !reflect.DeepEqual(spec.Config.Env, []string{"PATH=/usr/bin", "TZ=UTC", "NEW=1"}) {
+		t.Fatalf("env = %q", spec.Config.Env)
+	}
+	if !reflect.DeepEqual(spec.Config.Labels, map[string]string{"maintainer": "nginx", "role": "copy"}) {
+		t.Fatalf("labels = %v", spec.Config.Labels)
+	}
+	if len(spec.HostConfig.PortBindings) != 1 || spec.HostConfig.PortBindings["80/tcp"][0].HostPort != "9090" {
+		t.Fatalf("port bindings = %v", spec.HostConfig.PortBindings)
+	}
+	if len(src.HostConfig.PortBindings) != 3 || !reflect.DeepEqual(src.Config.Env, []string{"PATH=/usr/bin", "TZ=Asia/Shanghai", "PRICE=$5"}) {
+		t.Fatalf("source container config must not be modified")
+	}
+	ep := spec.Networking.EndpointsConfig["appnet"]
+	if ep == nil || ep.IPAMConfig != nil || !reflect.DeepEqual(ep.Aliases, []string{"web"}) {
+		t.Fatalf("primary endpoint = %+v", ep)
+	}
+	if len(spec.ExtraNetworks) != 1 || spec.ExtraNetworks[0].Name != "bridge" {
+		t.Fatalf("extra networks = %+v", spec.ExtraNetworks)
+	}
+
+	_, errs = buildCloneSpec(src, &CloneContainerRequest{Name: "My.Web", Env: []string{"=x"}})
+	if len(errs) != 2 {
+		t.Fatalf("expected name and env errors, got %+v", errs)
+	}
+}
@@ -0,0 +1,1120 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/api/types/strslice"
+	"github.com/docker/go-connections/nat"
+	"github.com/docker/go-units"
+	"github.com/gin-gonic/gin"
+)
+
+// CreateContainerRequest 定义创建容器的请求结构
+type CreateContainerRequest struct {
+	Name          string   `json:"name"`
+	Image         string   `json:"image"`
+	Ports         []string `json:"ports"`          // 格式: "8080:80"、"127.0.0.1:8080:80/udp"
+	Env           []string `json:"env"`            // 格式: "KEY=VALUE"
+	Volumes       []string `json:"volumes"`        // 格式: "/host/path:/container/path[:ro]"
+	NetworkMode   string   `json:"network_mode"`   // 网络模式
+	RestartPolicy string   `json:"restart_policy"` // 重启策略
+	Command       []string `json:"command"`        // 启动命令
+	Entrypoint    []string `json:"entrypoint"`     // 入口点
+	Privileged    bool     `json:"privileged"`     // 特权模式
+	Devices       []string `json:"devices"`        // 格式: "/dev/sda:/dev/xda:rwm"
+
+	RestartMaxRetries int               `json:"restart_max_retries"` // 仅 on-failure 有效
+	Labels            map[string]string `json:"labels"`
+	WorkingDir        string            `json:"working_dir"`
+	User              string            `json:"user"`
+	Hostname          string            `json:"hostname"`
+	Tty               bool              `json:"tty"`
+	OpenStdin         bool              `json:"open_stdin"`
+	ReadOnly          bool              `json:"read_only"`
+	Init              *bool             `json:"init"`
+
+	// 资源限制
+	CPUs              float64 `json:"cpus"`               // 等价于 --cpus
+	CPUShares         int64   `json:"cpu_shares"`         // 相对权重
+	CpusetCpus        string  `json:"cpuset_cpus"`        // 例如 "0-2,4"
+	Memory            string  `json:"memory"`             // 例如 "512m"
+	MemoryReservation string  `json:"memory_reservation"` // 软限制
+	MemorySwap        string  `json:"memory_swap"`        // "-1" 表示不限制
+	PidsLimit         *int64  `json:"pids_limit"`         // -1 表示不限制
+	ShmSize           string  `json:"shm_size"`
+
+	Healthcheck *HealthcheckSpec `json:"healthcheck"`
+
+	CapAdd      []string `json:"cap_add"`
+	CapDrop     []string `json:"cap_drop"`
+	SecurityOpt []string `json:"security_opt"`
+	Ulimits     []string `json:"ulimits"` // 格式: "nofile=1024:2048"
+
+	LogDriver  string            `json:"log_driver"`
+	LogOptions map[string]string `json:"log_options"`
+
+	Networks   []NetworkAttachment `json:"networks"`    // 多网络接入，第一个作为创建时的主网络
+	DNS        []string            `json:"dns"`         // DNS 服务器
+	DNSSearch  []string            `json:"dns_search"`  // 搜索域
+	DNSOptions []string            `json:"dns_options"` // resolv.conf options
+	ExtraHosts []string            `json:"extra_hosts"` // 格式: "host:ip"
+	Tmpfs      []string            `json:"tmpfs"`       // 格式: "/run:rw,size=64m"
+
+	Start bool `json:"start"` // 创建后立即启动（相当于 docker run）
+
+	// RunCommand 粘贴的 docker run 命令；非空时以解析结果作为创建参数
+	RunCommand string `json:"run_command"`
+}
+
+// HealthcheckSpec 健康检查配置，时长使用 Go 时长格式（例如 "30s"、"1m30s"）
+type HealthcheckSpec struct {
+	Test        []string `json:"test"` // ["CMD", ...] / ["CMD-SHELL", "..."]；只有一个元素时视为 CMD-SHELL
+	Interval    string   `json:"interval"`
+	Timeout     string   `json:"timeout"`
+	StartPeriod string   `json:"start_period"`
+	Retries     int      `json:"retries"`
+	Disable     bool     `json:"disable"`
+}
+
+// NetworkAttachment 容器接入的网络
+type NetworkAttachment struct {
+	Name        string   `json:"name"`
+	IPv4Address string   `json:"ipv4_address"`
+	IPv6Address string   `json:"ipv6_address"`
+	Aliases     []string `json:"aliases"`
+}
+
+// fieldError 字段级校验错误
+type fieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+type fieldErrors []fieldError
+
+func (e *fieldErrors) add(field string, format string, args ...any) {
+	*e = append(*e, fieldError{Field: field, Message: fmt.Sprintf(format, args...)})
+}
+
+// respondFieldErrors 返回 400，并在 fields 中给出逐字段的错误
+func respondFieldErrors(c *gin.Context, errs fieldErrors) {
+	msgs := make([]string, 0, len(errs))
+	for _, e := range errs {
+		msgs = append(msgs, e.Field+": "+e.Message)
+	}
+	c.JSON(http.StatusBadRequest, gin.H{
+		"code":    errorCodeFromStatus(http.StatusBadRequest),
+		"message": "参数校验失败",
+		"error":   "参数校验失败: " + strings.Join(msgs, "; "),
+		"fields":  errs,
+	})
+}
+
+// containerCreateSpec 校验后可直接交给 Docker API 的创建参数
+type containerCreateSpec struct {
+	Name       string
+	Config     *container.Config
+	HostConfig *container.HostConfig
+	// Networking 仅包含主网络，其余网络在创建后再 connect（旧版本 API 只允许一个）
+	Networking    *network.NetworkingConfig
+	ExtraNetworks []NetworkAttachment
+}
+
+var (
+	containerNamePattern = regexp.MustCompile(`^/?[a-zA-Z0-9][a-zA-Z0-9_.-]+$`)
+	capabilityPattern    = regexp.MustCompile(`^(CAP_)?[A-Z][A-Z0-9_]*$`)
+	logDriverPattern     = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.:/-]*$`)
+	envKeyPattern        = regexp.MustCompile(`^[^=\s]+$`)
+	cpusetPattern        = regexp.MustCompile(`^\d+(-\d+)?(,\d+(-\d+)?)*$`)
+)
+
+var validRestartPolicies = map[string]bool{"": true, "no": true, "always": true, "unless-stopped": true, "on-failure": true}
+
+// buildContainerCreateSpec 校验请求并转换为 Docker API 参数，所有错误一次性返回
+func buildContainerCreateSpec(req *CreateContainerRequest) (*containerCreateSpec, fieldErrors) {
+	var errs fieldErrors
+
+	if strings.TrimSpace(req.Image) == "" {
+		errs.add("image", "镜像不能为空")
+	}
+	if req.Name != "" && !containerNamePattern.MatchString(req.Name) {
+		errs.add("name", "容器名仅支持字母、数字、_ . -，且以字母或数字开头")
+	}
+
+	// 如果前端传来的 Command 为空，不要强制设为空切片，让 Docker 使用镜像默认的 CMD
+	var cmd strslice.StrSlice
+	if len(req.Command) > 0 {
+		cmd = req.Command
+	}
+	var entrypoint strslice.StrSlice
+	if len(req.Entrypoint) > 0 {
+		entrypoint = req.Entrypoint
+	}
+
+	for i, e := range req.Env {
+		key := e
+		if k, _, ok := strings.Cut(e, "="); ok {
+			key = k
+		}
+		if !envKeyPattern.MatchString(key) {
+			errs.add(fmt.Sprintf("env[%d]", i), "环境变量格式应为 KEY=VALUE")
+		}
+	}
+	for k := range req.Labels {
+		if strings.TrimSpace(k) == "" {
+			errs.add("labels", "标签名不能为空")
+			break
+		}
+	}
+
+	config := &container.Config{
+		Image:        strings.TrimSpace(req.Image),
+		Env:          req.Env,
+		Cmd:          cmd,
+		Entrypoint:   entrypoint,
+		Labels:       req.Labels,
+		WorkingDir:   req.WorkingDir,
+		User:         req.User,
+		Hostname:     req.Hostname,
+		Tty:          req.Tty,
+		OpenStdin:    req.OpenStdin,
+		ExposedPorts: nat.PortSet{},
+	}
+	if req.WorkingDir != "" && !path.IsAbs(req.WorkingDir) {
+		errs.add("working_dir", "工作目录必须是绝对路径")
+	}
+
+	hostConfig := &container.HostConfig{
+		NetworkMode:    container.NetworkMode(req.NetworkMode),
+		PortBindings:   nat.PortMap{},
+		Privileged:     req.Privileged,
+		ReadonlyRootfs: req.ReadOnly,
+		Init:           req.Init,
+		SecurityOpt:    req.SecurityOpt,
+	}
+
+	// 重启策略
+	if !validRestartPolicies[req.RestartPolicy] {
+		errs.add("restart_policy", "重启策略仅支持 no / always / unless-stopped / on-failure")
+	}
+	if req.RestartMaxRetries < 0 {
+		errs.add("restart_max_retries", "重试次数不能为负数")
+	} else if req.RestartMaxRetries > 0 && req.RestartPolicy != "on-failure" {
+		errs.add("restart_max_retries", "仅 on-failure 策略支持最大重试次数")
+	}
+	hostConfig.RestartPolicy = container.RestartPolicy{Name: req.RestartPolicy, MaximumRetryCount: req.RestartMaxRetries}
+
+	// 端口映射
+	for i, p := range req.Ports {
+		mappings, err := nat.ParsePortSpec(strings.TrimSpace(p))
+		if err != nil {
+			errs.add(fmt.Sprintf("ports[%d]", i), "端口映射格式错误: %v", err)
+			continue
+		}
+		for _, m := range mappings {
+			if hp := m.Binding.HostPort; hp != "" {
+				if n, err := strconv.Atoi(hp); err != nil || n < 1 || n > 65535 {
+					errs.add(fmt.Sprintf("ports[%d]", i), "宿主机端口超出范围: %s", hp)
+					continue
+				}
+			}
+			config.ExposedPorts[m.Port] = struct{}{}
+			hostConfig.PortBindings[m.Port] = append(hostConfig.PortBindings[m.Port], m.Binding)
+		}
+	}
+
+	// 挂载
+	for i, v := range req.Volumes {
+		parts := strings.Split(v, ":")
+		field := fmt.Sprintf("volumes[%d]", i)
+		if len(parts) < 2 || len(parts) > 3 || parts[0] == "" {
+			errs.add(field, "挂载格式应为 源:容器路径[:ro|rw]")
+			continue
+		}
+		if !path.IsAbs(parts[1]) {
+			errs.add(field, "容器内路径必须是绝对路径")
+		}
+		if len(parts) == 3 {
+			for _, opt := range strings.Split(parts[2], ",") {
+				switch opt {
+				case "ro", "rw", "z", "Z", "shared", "rshared", "slave", "rslave", "private", "rprivate", "nocopy":
+				default:
+					errs.add(field, "不支持的挂载选项: %s", opt)
+				}
+			}
+		}
+	}
+	hostConfig.Binds = req.Volumes
+
+	// 设备
+	for i, d := range req.Devices {
+		dev, err := parseDeviceMapping(d)
+		if err != nil {
+			errs.add(fmt.Sprintf("devices[%d]", i), "%v", err)
+			continue
+		}
+		hostConfig.Devices = append(hostConfig.Devices, dev)
+	}
+
+	// 资源限制
+	if req.CPUs < 0 {
+		errs.add("cpus", "CPU 数量不能为负数")
+	} else if req.CPUs > 0 {
+		hostConfig.NanoCPUs = int64(req.CPUs * 1e9)
+	}
+	if req.CPUShares < 0 {
+		errs.add("cpu_shares", "CPU 权重不能为负数")
+	}
+	hostConfig.CPUShares = req.CPUShares
+	if req.CpusetCpus != "" && !cpusetPattern.MatchString(req.CpusetCpus) {
+		errs.add("cpuset_cpus", "格式应为 0-3 或 0,2")
+	}
+	hostConfig.CpusetCpus = req.CpusetCpus
+
+	hostConfig.Memory = parseByteSizeField(&errs, "memory", req.Memory, false)
+	if hostConfig.Memory > 0 && hostConfig.Memory < 6*1024*1024 {
+		errs.add("memory", "内存限制不能小于 6MB")
+	}
+	hostConfig.MemoryReservation = parseByteSizeField(&errs, "memory_reservation", req.MemoryReservation, false)
+	if hostConfig.Memory > 0 && hostConfig.MemoryReservation > hostConfig.Memory {
+		errs.add("memory_reservation", "软限制不能大于内存限制")
+	}
+	hostConfig.MemorySwap = parseByteSizeField(&errs, "memory_swap", req.MemorySwap, true)
+	if hostConfig.MemorySwap > 0 {
+		if hostConfig.Memory == 0 {
+			errs.add("memory_swap", "设置 swap 时必须同时设置内存限制")
+		} else if hostConfig.MemorySwap < hostConfig.Memory {
+			errs.add("memory_swap", "swap 总量不能小于内存限制")
+		}
+	}
+	if req.PidsLimit != nil {
+		if *req.PidsLimit == 0 || *req.PidsLimit < -1 {
+			errs.add("pids_limit", "进程数限制应为正数，-1 表示不限制")
+		}
+		hostConfig.PidsLimit = req.PidsLimit
+	}
+	hostConfig.ShmSize = parseByteSizeField(&errs, "shm_size", req.ShmSize, false)
+
+	for i, u := range req.Ulimits {
+		ul, err := units.ParseUlimit(u)
+		if err != nil {
+			errs.add(fmt.Sprintf("ulimits[%d]", i), "%v", err)
+			continue
+		}
+		hostConfig.Ulimits = append(hostConfig.Ulimits, ul)
+	}
+
+	// 健康检查
+	if hc := req.Healthcheck; hc != nil {
+		health, herrs := buildHealthConfig(hc)
+		errs = append(errs, herrs...)
+		config.Healthcheck = health
+	}
+
+	// 权限
+	for _, list := range []struct {
+		field string
+		caps  []string
+	}{{"cap_add", req.CapAdd}, {"cap_drop", req.CapDrop}} {
+		for i, cp := range list.caps {
+			if strings.EqualFold(cp, "ALL") {
+				continue
+			}
+			if !capabilityPattern.MatchString(cp) {
+				errs.add(fmt.Sprintf("%s[%d]", list.field, i), "无效的 capability: %s", cp)
+			}
+		}
+	}
+	hostConfig.CapAdd = req.CapAdd
+	hostConfig.CapDrop = req.CapDrop
+
+	// 日志
+	if req.LogDriver != "" && !logDriverPattern.MatchString(req.LogDriver) {
+		errs.add("log_driver", "日志驱动名称不合法")
+	}
+	if len(req.LogOptions) > 0 && req.LogDriver == "none" {
+		errs.add("log_options", "日志驱动为 none 时不能设置日志选项")
+	}
+	hostConfig.LogConfig = container.LogConfig{Type: req.LogDriver, Config: req.LogOptions}
+
+	// DNS 与 hosts
+	for i, d := range req.DNS {
+		if net.ParseIP(d) == nil {
+			errs.add(fmt.Sprintf("dns[%d]", i), "不是合法的 IP 地址: %s", d)
+		}
+	}
+	hostConfig.DNS = req.DNS
+	hostConfig.DNSSearch = req.DNSSearch
+	hostConfig.DNSOptions = req.DNSOptions
+	for i, h := range req.ExtraHosts {
+		host, ip, ok := strings.Cut(h, ":")
+		if !ok {
+			host, ip, ok = strings.Cut(h, "=")
+		}
+		if !ok || host == "" || (ip != "host-gateway" && net.ParseIP(ip) == nil) {
+			errs.add(fmt.Sprintf("extra_hosts[%d]", i), "格式应为 主机名:IP")
+			continue
+		}
+		hostConfig.ExtraHosts = append(hostConfig.ExtraHosts, host+":"+ip)
+	}
+
+	// tmpfs
+	for i, t := range req.Tmpfs {
+		dst, opts, _ := strings.Cut(t, ":")
+		if !path.IsAbs(dst) {
+			errs.add(fmt.Sprintf("tmpfs[%d]", i), "tmpfs 挂载点必须是绝对路径")
+			continue
+		}
+		if hostConfig.Tmpfs == nil {
+			hostConfig.Tmpfs = make(map[string]string)
+		}
+		hostConfig.Tmpfs[dst] = opts
+	}
+
+	// 网络
+	spec := &containerCreateSpec{Name: strings.TrimPrefix(req.Name, "/"), Config: config, HostConfig: hostConfig}
+	mode := strings.TrimSpace(req.NetworkMode)
+	if len(req.Networks) > 0 {
+		if mode == "host" || mode == "none" || strings.HasPrefix(mode, "container:") {
+			errs.add("networks", "网络模式为 %s 时不能再接入其它网络", mode)
+		}
+		seen := make(map[string]bool)
+		for i, n := range req.Networks {
+			field := fmt.Sprintf("networks[%d]", i)
+			name := strings.TrimSpace(n.Name)
+			if name == "" {
+				errs.add(field+".name", "网络名称不能为空")
+				continue
+			}
+			if seen[name] {
+				errs.add(field+".name", "重复的网络: %s", name)
+			}
+			seen[name] = true
+			isDefault := name == "bridge" || name == "host" || name == "none"
+			if n.IPv4Address != "" {
+				if ip := net.ParseIP(n.IPv4Address); ip == nil || ip.To4() == nil {
+					errs.add(field+".ipv4_address", "不是合法的 IPv4 地址")
+				} else if isDefault {
+					errs.add(field+".ipv4_address", "默认网络不支持指定静态 IP")
+				}
+			}
+			if n.IPv6Address != "" {
+				if ip := net.ParseIP(n.IPv6Address); ip == nil || ip.To4() != nil {
+					errs.add(field+".ipv6_address", "不是合法的 IPv6 地址")
+				} else if isDefault {
+					errs.add(field+".ipv6_address", "默认网络不支持指定静态 IP")
+				}
+			}
+			if len(n.Aliases) > 0 && isDefault {
+				errs.add(field+".aliases", "默认网络不支持别名")
+			}
+		}
+		if mode == "" {
+			hostConfig.NetworkMode = container.NetworkMode(strings.TrimSpace(req.Networks[0].Name))
+		}
+		primary := string(hostConfig.NetworkMode)
+		spec.Networking = &network.NetworkingConfig{EndpointsConfig: map[string]*network.EndpointSettings{}}
+		for _, n := range req.Networks {
+			if strings.TrimSpace(n.Name) == primary {
+				spec.Networking.EndpointsConfig[primary] = endpointSettingsFor(n)
+			} else {
+				spec.ExtraNetworks = append(spec.ExtraNetworks, n)
+			}
+		}
+	}
+
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	return spec, nil
+}
+
+func endpointSettingsFor(n NetworkAttachment) *network.EndpointSettings {
+	ep := &network.EndpointSettings{Aliases: n.Aliases}
+	if n.IPv4Address != "" || n.IPv6Address != "" {
+		ep.IPAMConfig = &network.EndpointIPAMConfig{IPv4Address: n.IPv4Address, IPv6Address: n.IPv6Address}
+	}
+	return ep
+}
+
+// parseByteSizeField 解析 "512m" 形式的大小；allowUnlimited 时允许 "-1"
+func parseByteSizeField(errs *fieldErrors, field string, raw string, allowUnlimited bool) int64 {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return 0
+	}
+	if allowUnlimited && raw == "-1" {
+		return -1
+	}
+	v, err := units.RAMInBytes(raw)
+	if err != nil || v < 0 {
+		errs.add(field, "大小格式错误，例如 512m、1g")
+		return 0
+	}
+	return v
+}
+
+func parseDeviceMapping(d string) (container.DeviceMapping, error) {
+	parts := strings.Split(d, ":")
+	dev := container.DeviceMapping{PathOnHost: parts[0], CgroupPermissions: "rwm"}
+	switch len(parts) {
+	case 1:
+		dev.PathInContainer = parts[0]
+	case 2:
+		// 第二段可能是权限（/dev/sda:rw）
+		if isDevicePermissions(parts[1]) {
+			dev.PathInContainer = parts[0]
+			dev.CgroupPermissions = parts[1]
+		} else {
+			dev.PathInContainer = parts[1]
+		}
+	case 3:
+		dev.PathInContainer = parts[1]
+		dev.CgroupPermissions = parts[2]
+	default:
+		return dev, fmt.Errorf("设备格式应为 宿主机路径[:容器路径][:权限]")
+	}
+	if !path.IsAbs(dev.PathOnHost) || !path.IsAbs(dev.PathInContainer) {
+		return dev, fmt.Errorf("设备路径必须是绝对路径")
+	}
+	if !isDevicePermissions(dev.CgroupPermissions) {
+		return dev, fmt.Errorf("设备权限只能由 r、w、m 组成")
+	}
+	return dev, nil
+}
+
+func isDevicePermissions(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, ch := range s {
+		if ch != 'r' && ch != 'w' && ch != 'm' {
+			return false
+		}
+	}
+	return true
+}
+
+func buildHealthConfig(hc *HealthcheckSpec) (*container.HealthConfig, fieldErrors) {
+	var errs fieldErrors
+	if hc.Disable {
+		return &container.HealthConfig{Test: []string{"NONE"}}, nil
+	}
+	health := &container.HealthConfig{Retries: hc.Retries}
+	switch {
+	case len(hc.Test) == 0:
+		errs.add("healthcheck.test", "健康检查命令不能为空")
+	case len(hc.Test) == 1 && hc.Test[0] != "NONE":
+		health.Test = []string{"CMD-SHELL", hc.Test[0]}
+	case hc.Test[0] == "CMD" || hc.Test[0] == "CMD-SHELL" || hc.Test[0] == "NONE":
+		health.Test = hc.Test
+	default:
+		errs.add("healthcheck.test", "第一个元素必须是 CMD、CMD-SHELL 或 NONE")
+	}
+	if hc.Retries < 0 {
+		errs.add("healthcheck.retries", "重试次数不能为负数")
+	}
+	for _, d := range []struct {
+		field string
+		raw   string
+		dst   *time.Duration
+	}{
+		{"healthcheck.interval", hc.Interval, &health.Interval},
+		{"healthcheck.timeout", hc.Timeout, &health.Timeout},
+		{"healthcheck.start_period", hc.StartPeriod, &health.StartPeriod},
+	} {
+		if strings.TrimSpace(d.raw) == "" {
+			continue
+		}
+		v, err := time.ParseDuration(strings.TrimSpace(d.raw))
+		if err != nil || v < 0 {
+			errs.add(d.field, "时长格式错误，例如 30s、1m")
+			continue
+		}
+		// Docker 要求非零时长至少 1ms
+		if v > 0 && v < time.Millisecond {
+			errs.add(d.field, "时长不能小于 1ms")
+			continue
+		}
+		*d.dst = v
+	}
+	return health, errs
+}
+
+// createContainer 创建容器
+func createContainer(c *gin.Context) {
+	var req CreateContainerRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, "无效的请求参数", err)
+		return
+	}
+	if strings.TrimSpace(req.RunCommand) != "" {
+		parsed, _, err := parseDockerRunCommand(req.RunCommand)
+		if err != nil {
+			respondFieldErrors(c, fieldErrors{{Field: "run_command", Message: err.Error()}})
+			return
+		}
+		req = *parsed
+	}
+
+	spec, errs := buildContainerCreateSpec(&req)
+	if len(errs) > 0 {
+		respondFieldErrors(c, errs)
+		return
+	}
+
+	cli, ok := getDockerClient(c)
+	if !ok {
+		return
+	}
+	defer cli.Close()
+
+	ctx := context.Background()
+	// 镜像不存在时由调用方先拉取（/images/pull），这里不隐式拉取
+	resp, err := cli.ContainerCreate(ctx, spec.Config, spec.HostConfig, spec.Networking, nil, spec.Name)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "创建容器失败", err)
+		return
+	}
+
+	// 接入其余网络，失败时回滚已创建的容器
+	for _, n := range spec.ExtraNetworks {
+		if err := cli.NetworkConnect(ctx, strings.TrimSpace(n.Name), resp.ID, endpointSettingsFor(n)); err != nil {
+			_ = cli.ContainerRemove(ctx, resp.ID, types.ContainerRemoveOptions{Force: true})
+			respondError(c, http.StatusInternalServerError, fmt.Sprintf("接入网络 %s 失败", n.Name), err)
+			return
+		}
+	}
+
+	started := false
+	if req.Start {
+		if err := cli.ContainerStart(ctx, resp.ID, types.ContainerStartOptions{}); err != nil {
+			c.JSON(http.StatusOK, gin.H{"id": resp.ID, "message": "容器已创建，但启动失败", "warnings": resp.Warnings, "startError": err.Error()})
+			return
+		}
+		started = true
+	}
+
+	c.JSON(http.StatusOK, gin.H{"id": resp.ID, "message": "容器创建成功", "warnings": resp.Warnings, "started": started})
+}
+
+// parseRunCommand 将 docker run 命令解析为创建参数，供前端回填表单
+func parseRunCommand(c *gin.Context) {
+	var req struct {
+		Command string `json:"command"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, "无效的请求参数", err)
+		return
+	}
+	spec, warnings, err := parseDockerRunCommand(req.Command)
+	if err != nil {
+		respondFieldErrors(c, fieldErrors{{Field: "command", Message: err.Error()}})
+		return
+	}
+	resp := gin.H{"spec": spec, "warnings": warnings}
+	if _, errs := buildContainerCreateSpec(spec); len(errs) > 0 {
+		resp["fields"] = errs
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// splitShellWords 按 shell 规则切分命令行：支持单双引号、反斜杠转义与续行
+func splitShellWords(s string) ([]string, error) {
+	words := make([]string, 0)
+	var cur strings.Builder
+	inWord := false
+	var quote rune
+	escaped := false
+	for _, r := range s {
+		switch {
+		case escaped:
+			escaped = false
+			if r == '\n' {
+				continue
+			}
+			cur.WriteRune(r)
+			inWord = true
+		case quote == '\'':
+			if r == '\'' {
+				quote = 0
+			} else {
+				cur.WriteRune(r)
+			}
+		case quote == '"':
+			switch r {
+			case '"':
+				quote = 0
+			case '\\':
+				escaped = true
+			default:
+				cur.WriteRune(r)
+			}
+		case r == '\\':
+			escaped = true
+		case r == '\'' || r == '"':
+			quote = r
+			inWord = true
+		case r == ' ' || r == '\t' || r == '\n' || r == '\r':
+			if inWord {
+				words = append(words, cur.String())
+				cur.Reset()
+				inWord = false
+			}
+		default:
+			cur.WriteRune(r)
+			inWord = true
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("引号未闭合")
+	}
+	if inWord {
+		words = append(words, cur.String())
+	}
+	return words, nil
+}
+
+// runFlag docker run 参数描述：takesValue 表示需要参数值
+type runFlag struct {
+	takesValue bool
+	apply      func(req *CreateContainerRequest, v string, st *runParseState) error
+}
+
+type runParseState struct {
+	health   HealthcheckSpec
+	hasHC    bool
+	ip, ip6  string
+	aliases  []string
+	networks []NetworkAttachment
+	warnings []string
+}
+
+// addRunNetwork 处理 --network，可多次指定；支持 name=xxx,alias=xxx,ip=xxx,ip6=xxx 的完整写法
+func addRunNetwork(_ *CreateContainerRequest, v string, st *runParseState) error {
+	v = strings.TrimSpace(v)
+	if !strings.Contains(v, "=") {
+		st.networks = append(st.networks, NetworkAttachment{Name: v})
+		return nil
+	}
+	var n NetworkAttachment
+	for _, part := range strings.Split(v, ",") {
+		key, val, _ := strings.Cut(strings.TrimSpace(part), "=")
+		switch strings.ToLower(key) {
+		case "name":
+			n.Name = val
+		case "alias":
+			n.Aliases = append(n.Aliases, val)
+		case "ip":
+			n.IPv4Address = val
+		case "ip6":
+			n.IPv6Address = val
+		default:
+			st.warnings = append(st.warnings, fmt.Sprintf("--network 的 %s 选项暂不支持，已忽略", key))
+		}
+	}
+	if n.Name == "" {
+		return fmt.Errorf("--network 缺少 name: %s", v)
+	}
+	st.networks = append(st.networks, n)
+	return nil
+}
+
+func appendTo(field func(*CreateContainerRequest) *[]string) func(*CreateContainerRequest, string, *runParseState) error {
+	return func(req *CreateContainerRequest, v string, _ *runParseState) error {
+		p := field(req)
+		*p = append(*p, v)
+		return nil
+	}
+}
+
+func setString(field func(*CreateContainerRequest) *string) func(*CreateContainerRequest, string, *runParseState) error {
+	return func(req *CreateContainerRequest, v string, _ *runParseState) error {
+		*field(req) = v
+		return nil
+	}
+}
+
+func setBool(field func(*CreateContainerRequest) *bool) func(*CreateContainerRequest, string, *runParseState) error {
+	return func(req *CreateContainerRequest, v string, _ *runParseState) error {
+		b, err := parseRunBool(v)
+		*field(req) = b
+		return err
+	}
+}
+
+func ignoreFlag(note string) func(*CreateContainerRequest, string, *runParseState) error {
+	return func(_ *CreateContainerRequest, _ string, st *runParseState) error {
+		if note != "" {
+			st.warnings = append(st.warnings, note)
+		}
+		return nil
+	}
+}
+
+func parseRunBool(v string) (bool, error) {
+	if v == "" {
+		return true, nil
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		return false, fmt.Errorf("无效的布尔值: %s", v)
+	}
+	return b, nil
+}
+
+func keyValueInto(field func(*CreateContainerRequest) *map[string]string) func(*CreateContainerRequest, string, *runParseState) error {
+	return func(req *CreateContainerRequest, v string, _ *runParseState) error {
+		m := field(req)
+		if *m == nil {
+			*m = make(map[string]string)
+		}
+		k, val, _ := strings.Cut(v, "=")
+		(*m)[k] = val
+		return nil
+	}
+}
+
+func healthDuration(field func(*HealthcheckSpec) *string) func(*CreateContainerRequest, string, *runParseState) error {
+	return func(_ *CreateContainerRequest, v string, st *runParseState) error {
+		st.hasHC = true
+		*field(&st.health) = v
+		return nil
+	}
+}
+
+var dockerRunFlags map[string]runFlag
+
+// dockerRunShortFlags 单字母参数与长参数的对应关系
+var dockerRunShortFlags = map[string]string{
+	"p": "publish", "e": "env", "v": "volume", "l": "label", "m": "memory", "c": "cpu-shares",
+	"w": "workdir", "u": "user", "h": "hostname", "d": "detach", "i": "interactive", "t": "tty",
+}
+
+func init() {
+	dockerRunFlags = map[string]runFlag{
+		"name":    {true, setString(func(r *CreateContainerRequest) *string { return &r.Name })},
+		"publish": {true, appendTo(func(r *CreateContainerRequest) *[]string { return &r.Ports })},
+		"env":     {true, appendTo(func(r *CreateContainerRequest) *[]string { return &r.Env })},
+		"volume":  {true, appendTo(func(r *CreateContainerRequest) *[]string { return &r.Volumes })},
+		"mount":   {true, applyMountFlag},
+		"network": {true, addRunNetwork},
+		"net":     {true, addRunNetwork},
+		"network-alias": {true, func(_ *CreateContainerRequest, v string, st *runParseState) error {
+			st.aliases = append(st.aliases, v)
+			return nil
+		}},
+		"net-alias": {true, func(_ *CreateContainerRequest, v string, st *runParseState) error {
+			st.aliases = append(st.aliases, v)
+			return nil
+		}},
+		"ip":  {true, func(_ *CreateContainerRequest, v string, st *runParseState) error { st.ip = v; return nil }},
+		"ip6": {true, func(_ *CreateContainerRequest, v string, st *runParseState) error { st.ip6 = v; return nil }},
+		"restart": {true, func(r *CreateContainerRequest, v string, _ *runParseState) error {
+			name, retries, ok := strings.Cut(v, ":")
+			r.RestartPolicy = name
+			if ok {
+				n, err := strconv.Atoi(retries)
+				if err != nil {
+					return fmt.Errorf("无效的重启重试次数: %s", retries)
+				}
+				r.RestartMaxRetries = n
+			}
+			return nil
+		}},
+		"entrypoint": {true, func(r *CreateContainerRequest, v string, _ *runParseState) error {
+			r.Entrypoint = []string{v}
+			return nil
+		}},
+		"privileged":  {false, setBool(func(r *CreateContainerRequest) *bool { return &r.Privileged })},
+		"read-only":   {false, setBool(func(r *CreateContainerRequest) *bool { return &r.ReadOnly })},
+		"tty":         {false, setBool(func(r *CreateContainerRequest) *bool { return &r.Tty })},
+		"interactive": {false, setBool(func(r *CreateContainerRequest) *bool { return &r.OpenStdin })},
+		"init": {false, func(r *CreateContainerRequest, v string, _ *runParseState) error {
+			b, err := parseRunBool(v)
+			r.Init = &b
+			return err
+		}},
+		"device":       {true, appendTo(func(r *CreateContainerRequest) *[]string { return &r.Devices })},
+		"label":        {true, keyValueInto(func(r *CreateContainerRequest) *map[string]string { return &r.Labels })},
+		"workdir":      {true, setString(func(r *CreateContainerRequest) *string { return &r.WorkingDir })},
+		"user":         {true, setString(func(r *CreateContainerRequest) *string { return &r.User })},
+		"hostname":     {true, setString(func(r *CreateContainerRequest) *string { return &r.Hostname })},
+		"cpuset-cpus":  {true, setString(func(r *CreateContainerRequest) *string { return &r.CpusetCpus })},
+		"memory":       {true, setString(func(r *CreateContainerRequest) *string { return &r.Memory })},
+		"memory-swap":  {true, setString(func(r *CreateContainerRequest) *string { return &r.MemorySwap })},
+		"shm-size":     {true, setString(func(r *CreateContainerRequest) *string { return &r.ShmSize })},
+		"log-driver":   {true, setString(func(r *CreateContainerRequest) *string { return &r.LogDriver })},
+		"log-opt":      {true, keyValueInto(func(r *CreateContainerRequest) *map[string]string { return &r.LogOptions })},
+		"cap-add":      {true, appendTo(func(r *CreateContainerRequest) *[]string { return &r.CapAdd })},
+		"cap-drop":     {true, appendTo(func(r *CreateContainerRequest) *[]string { return &r.CapDrop })},
+		"security-opt": {true, appendTo(func(r *CreateContainerRequest) *[]string { return &r.SecurityOpt })},
+		"ulimit":       {true, appendTo(func(r *CreateContainerRequest) *[]string { return &r.Ulimits })},
+		"dns":          {true, appendTo(func(r *CreateContainerRequest) *[]string { return &r.DNS })},
+		"dns-search":   {true, appendTo(func(r *CreateContainerRequest) *[]string { return &r.DNSSearch })},
+		"dns-option":   {true, appendTo(func(r *CreateContainerRequest) *[]string { return &r.DNSOptions })},
+		"add-host":     {true, appendTo(func(r *CreateContainerRequest) *[]string { return &r.ExtraHosts })},
+		"tmpfs":        {true, appendTo(func(r *CreateContainerRequest) *[]string { return &r.Tmpfs })},
+		"memory-reservation": {true, setString(func(r *CreateContainerRequest) *string {
+			return &r.MemoryReservation
+		})},
+		"cpus": {true, func(r *CreateContainerRequest, v string, _ *runParseState) error {
+			f, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				return fmt.Errorf("无效的 CPU 数量: %s", v)
+			}
+			r.CPUs = f
+			return nil
+		}},
+		"cpu-shares": {true, func(r *CreateContainerRequest, v string, _ *runParseState) error {
+			n, err := strconv.ParseInt(v, 10, 64)
+			if err != nil {
+				return fmt.Errorf("无效的 CPU 权重: %s", v)
+			}
+			r.CPUShares = n
+			return nil
+		}},
+		"pids-limit": {true, func(r *CreateContainerRequest, v string, _ *runParseState) error {
+			n, err := strconv.ParseInt(v, 10, 64)
+			if err != nil {
+				return fmt.Errorf("无效的进程数限制: %s", v)
+			}
+			r.PidsLimit = &n
+			return nil
+		}},
+		"health-cmd": {true, func(_ *CreateContainerRequest, v string, st *runParseState) error {
+			st.hasHC = true
+			st.health.Test = []string{"CMD-SHELL", v}
+			return nil
+		}},
+		"health-interval":     {true, healthDuration(func(h *HealthcheckSpec) *string { return &h.Interval })},
+		"health-timeout":      {true, healthDuration(func(h *HealthcheckSpec) *string { return &h.Timeout })},
+		"health-start-period": {true, healthDuration(func(h *HealthcheckSpec) *string { return &h.StartPeriod })},
+		"health-retries": {true, func(_ *CreateContainerRequest, v string, st *runParseState) error {
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				return fmt.Errorf("无效的健康检查重试次数: %s", v)
+			}
+			st.hasHC = true
+			st.health.Retries = n
+			return nil
+		}},
+		"no-healthcheck": {false, func(_ *CreateContainerRequest, v string, st *runParseState) error {
+			b, err := parseRunBool(v)
+			if b {
+				st.hasHC = true
+				st.health.Disable = true
+			}
+			return err
+		}},
+		"detach":   {false, ignoreFlag("")},
+		"rm":       {false, ignoreFlag("--rm 已忽略：面板创建的容器不会在退出后自动删除")},
+		"pull":     {true, ignoreFlag("--pull 已忽略：请先在镜像页面拉取镜像")},
+		"platform": {true, ignoreFlag("--platform 已忽略")},
+		"env-file": {true, ignoreFlag("--env-file 已忽略：无法读取本地文件，请直接填写环境变量")},
+		"expose": {true, func(r *CreateContainerRequest, v string, st *runParseState) error {
+			st.warnings = append(st.warnings, "--expose 仅声明端口，未做映射: "+v)
+			return nil
+		}},
+	}
+}
+
+// applyMountFlag 将 --mount type=bind|volume|tmpfs,... 转换为 volumes / tmpfs
+func applyMountFlag(req *CreateContainerRequest, v string, st *runParseState) error {
+	opts := make(map[string]string)
+	readOnly := false
+	for _, part := range strings.Split(v, ",") {
+		k, val, ok := strings.Cut(part, "=")
+		k = strings.TrimSpace(k)
+		if !ok && (k == "readonly" || k == "ro") {
+			readOnly = true
+			continue
+		}
+		opts[k] = val
+	}
+	if b, err := strconv.ParseBool(opts["readonly"]); err == nil && b {
+		readOnly = true
+	}
+	if b, err := strconv.ParseBool(opts["ro"]); err == nil && b {
+		readOnly = true
+	}
+	src := opts["source"]
+	if src == "" {
+		src = opts["src"]
+	}
+	dst := opts["target"]
+	if dst == "" {
+		dst = opts["destination"]
+	}
+	if dst == "" {
+		dst = opts["dst"]
+	}
+	if dst == "" {
+		return fmt.Errorf("--mount 缺少 target")
+	}
+	switch opts["type"] {
+	case "tmpfs":
+		tmp := dst
+		if size := opts["tmpfs-size"]; size != "" {
+			tmp += ":size=" + size
+		}
+		req.Tmpfs = append(req.Tmpfs, tmp)
+	case "bind", "volume", "":
+		if src == "" {
+			st.warnings = append(st.warnings, "匿名卷已忽略: "+dst)
+			return nil
+		}
+		bind := src + ":" + dst
+		if readOnly {
+			bind += ":ro"
+		}
+		req.Volumes = append(req.Volumes, bind)
+	default:
+		return fmt.Errorf("不支持的挂载类型: %s", opts["type"])
+	}
+	return nil
+}
+
+// applyRunNetworks 汇总 --network 与 --ip / --ip6 / --network-alias：只有一个普通网络时作为网络模式，
+// 多个网络或带有地址、别名时生成 Networks，独立的 --ip 等参数作用于第一个网络
+func applyRunNetworks(req *CreateContainerRequest, st *runParseState) error {
+	nets := st.networks
+	if len(nets) > 1 {
+		for _, n := range nets {
+			if n.Name == "host" || n.Name == "none" || strings.HasPrefix(n.Name, "container:") {
+				return fmt.Errorf("网络模式 %s 不能与其它网络同时使用", n.Name)
+			}
+		}
+	}
+	plain := len(nets) == 1 && nets[0].IPv4Address == "" && nets[0].IPv6Address == "" && len(nets[0].Aliases) == 0
+	if st.ip == "" && st.ip6 == "" && len(st.aliases) == 0 && (len(nets) == 0 || plain) {
+		if plain {
+			req.NetworkMode = nets[0].Name
+		}
+		return nil
+	}
+	if len(nets) == 0 {
+		nets = []NetworkAttachment{{Name: "bridge"}}
+	}
+	if nets[0].Name == "default" {
+		nets[0].Name = "bridge"
+	}
+	if st.ip != "" {
+		nets[0].IPv4Address = st.ip
+	}
+	if st.ip6 != "" {
+		nets[0].IPv6Address = st.ip6
+	}
+	nets[0].Aliases = append(nets[0].Aliases, st.aliases...)
+	req.Networks = nets
+	req.NetworkMode = ""
+	return nil
+}
+
+// parseDockerRunCommand 解析 "docker run ..." 命令行为创建参数
+func parseDockerRunCommand(cmdline string) (*CreateContainerRequest, []string, error) {
+	words, err := splitShellWords(cmdline)
+	if err != nil {
+		return nil, nil, err
+	}
+	// 去掉 sudo / docker / container / run 前缀
+	for len(words) > 0 && (words[0] == "sudo" || words[0] == "docker" || words[0] == "container" || words[0] == "run") {
+		words = words[1:]
+	}
+	if len(words) == 0 {
+		return nil, nil, fmt.Errorf("命令中缺少镜像名")
+	}
+
+	req := &CreateContainerRequest{Start: true}
+	st := &runParseState{}
+	apply := func(name string, value string, hasValue bool, next func() (string, bool)) error {
+		flag, ok := dockerRunFlags[name]
+		if !ok {
+			return fmt.Errorf("不支持的参数: --%s", name)
+		}
+		if flag.takesValue && !hasValue {
+			v, ok := next()
+			if !ok {
+				return fmt.Errorf("参数 --%s 缺少值", name)
+			}
+			value = v
+		}
+		if err := flag.apply(req, value, st); err != nil {
+			return fmt.Errorf("--%s: %v", name, err)
+		}
+		return nil
+	}
+
+	i := 0
+	next := func() (string, bool) {
+		if i >= len(words) {
+			return "", false
+		}
+		v := words[i]
+		i++
+		return v, true
+	}
+	for i < len(words) {
+		w := words[i]
+		if !strings.HasPrefix(w, "-") || w == "-" {
+			break
+		}
+		i++
+		if w == "--" {
+			break
+		}
+		if strings.HasPrefix(w, "--") {
+			name, value, hasValue := strings.Cut(w[2:], "=")
+			if err := apply(name, value, hasValue, next); err != nil {
+				return nil, nil, err
+			}
+			continue
+		}
+		// 短参数：-it、-p80:80、-e KEY=VAL
+		short := w[1:]
+		for j := 0; j < len(short); j++ {
+			long, ok := dockerRunShortFlags[string(short[j])]
+			if !ok {
+				return nil, nil, fmt.Errorf("不支持的参数: -%c", short[j])
+			}
+			if dockerRunFlags[long].takesValue {
+				rest := strings.TrimPrefix(short[j+1:], "=")
+				if err := apply(long, rest, rest != "", next); err != nil {
+					return nil, nil, err
+				}
+				break
+			}
+			if err := apply(long, "", false, next); err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+
+	if i >= len(words) {
+		return nil, nil, fmt.Errorf("命令中缺少镜像名")
+	}
+	req.Image = words[i]
+	if rest := words[i+1:]; len(rest) > 0 {
+		req.Command = append([]string{}, rest...)
+	}
+
+	if st.hasHC {
+		hc := st.health
+		req.Healthcheck = &hc
+	}
+	if err := applyRunNetworks(req, st); err != nil {
+		return nil, nil, err
+	}
+	return req, st.warnings, nil
+}
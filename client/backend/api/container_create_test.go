@@ -0,0 +1,140 @@
+package api
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseDockerRunCommand(t *testing.T) {
+	cmdline := `sudo docker run -d --name web -p 8080:80 -p127.0.0.1:8443:443/tcp \
+  -e TZ=Asia/Shanghai -e "GREETING=hello world" -v /data:/usr/share/nginx/html:ro \
+  --restart on-failure:3 --cpus 1.5 -m 512m --pids-limit=200 \
+  --health-cmd 'curl -f http://localhost/ || exit 1' --health-interval 30s --health-retries 3 \
+  --cap-add NET_ADMIN --device /dev/fuse --ulimit nofile=1024:2048 \
+  --log-driver json-file --log-opt max-size=10m --network appnet --ip 172.20.0.10 --network-alias web \
+  --dns 1.1.1.1 --add-host db:10.0.0.5 --tmpfs /run:size=64m -l app=web --rm -it \
+  --mount type=bind,source=/etc/app,target=/etc/app,readonly \
+  nginx:1.25 nginx -g 'daemon off;'`
+
+	req, warnings, err := parseDockerRunCommand(cmdline)
+	if err != nil {
+		t.Fatalf("parseDockerRunCommand: %v", err)
+	}
+	if req.Image != "nginx:1.25" || !reflect.DeepEqual(req.Command, []string{"nginx", "-g", "daemon off;"}) {
+		t.Fatalf("image/command = %q %q", req.Image, req.Command)
+	}
+	if req.Name != "web" || !req.Start || !req.Tty || !req.OpenStdin {
+		t.Fatalf("unexpected basic fields: %+v", req)
+	}
+	if !reflect.DeepEqual(req.Ports, []string{"8080:80", "127.0.0.1:8443:443/tcp"}) {
+		t.Fatalf("ports = %q", req.Ports)
+	}
+	if !reflect.DeepEqual(req.Env, []string{"TZ=Asia/Shanghai", "GREETING=hello world"}) {
+		t.Fatalf("env = %q", req.Env)
+	}
+	if !reflect.DeepEqual(req.Volumes, []string{"/data:/usr/share/nginx/html:ro", "/etc/app:/etc/app:ro"}) {
+		t.Fatalf("volumes = %q", req.Volumes)
+	}
+	if req.RestartPolicy != "on-failure" || req.RestartMaxRetries != 3 || req.CPUs != 1.5 || req.Memory != "512m" || *req.PidsLimit != 200 {
+		t.Fatalf("unexpected limits: %+v", req)
+	}
+	if req.Healthcheck == nil || req.Healthcheck.Test[1] != "curl -f http://localhost/ || exit 1" || req.Healthcheck.Retries != 3 {
+		t.Fatalf("healthcheck = %+v", req.Healthcheck)
+	}
+	if len(req.Networks) != 1 || req.Networks[0].Name != "appnet" || req.Networks[0].IPv4Address != "172.20.0.10" || req.Networks[0].Aliases[0] != "web" {
+		t.Fatalf("networks = %+v", req.Networks)
+	}
+	if req.Labels["app"] != "web" || req.LogOptions["max-size"] != "10m" {
+		t.Fatalf("labels/log options = %v %v", req.Labels, req.LogOptions)
+	}
+	if len(warnings) != 1 || !strings.Contains(warnings[0], "--rm") {
+		t.Fatalf("warnings = %q", warnings)
+	}
+
+	spec, errs := buildContainerCreateSpec(req)
+	if len(errs) > 0 {
+		t.Fatalf("unexpected field errors: %+v", errs)
+	}
+	hc := spec.HostConfig
+	if hc.NanoCPUs != 1500000000 || hc.Memory != 512*1024*1024 || hc.RestartPolicy.MaximumRetryCount != 3 {
+		t.Fatalf("unexpected host config: %+v", hc)
+	}
+	if string(hc.NetworkMode) != "appnet" || spec.Networking.EndpointsConfig["appnet"].IPAMConfig.IPv4Address != "172.20.0.10" {
+		t.Fatalf("unexpected networking: %+v", spec.Networking)
+	}
+	if spec.Config.Healthcheck.Interval != 30*time.Second || hc.Tmpfs["/run"] != "size=64m" || len(hc.Ulimits) != 1 || len(hc.Devices) != 1 {
+		t.Fatalf("unexpected config: %+v %+v", spec.Config.Healthcheck, hc)
+	}
+}
+
+func TestParseDockerRunCommandErrors(t *testing.T) {
+	for _, cmd := range []string{
+		"docker run",
+		"docker run -d",
+		"docker run --bogus x nginx",
+		"docker run -e 'unterminated nginx",
+		"docker run --name",
+		"docker run --network host --network appnet nginx",
+		"docker run --network alias=web nginx",
+	} {
+		if _, _, err := parseDockerRunCommand(cmd); err == nil {
+			t.Errorf("expected error for %q", cmd)
+		}
+	}
+}
+
+func TestParseDockerRunCommandMultipleNetworks(t *testing.T) {
+	req, _, err := parseDockerRunCommand("docker run --network appnet --network name=backend,alias=api,ip=10.0.1.5 --network-alias web nginx")
+	if err != nil {
+		t.Fatalf("parseDockerRunCommand: %v", err)
+	}
+	want := []NetworkAttachment{
+		{Name: "appnet", Aliases: []string{"web"}},
+		{Name: "backend", IPv4Address: "10.0.1.5", Aliases: []string{"api"}},
+	}
+	if req.NetworkMode != "" || !reflect.DeepEqual(req.Networks, want) {
+		t.Fatalf("networkMode=%q networks=%+v", req.NetworkMode, req.Networks)
+	}
+
+	req, _, err = parseDockerRunCommand("docker run --net host nginx")
+	if err != nil || req.NetworkMode != "host" || len(req.Networks) != 0 {
+		t.Fatalf("single network: %+v %v", req, err)
+	}
+}
+
+func TestBuildContainerCreateSpecFieldErrors(t *testing.T) {
+	pids := int64(0)
+	req := &CreateContainerRequest{
+		Name:              "-bad",
+		Ports:             []string{"99999:80"},
+		Volumes:           []string{"/data:relative"},
+		RestartPolicy:     "always",
+		RestartMaxRetries: 2,
+		Memory:            "1k",
+		MemorySwap:        "1g",
+		PidsLimit:         &pids,
+		Healthcheck:       &HealthcheckSpec{Test: []string{"BAD", "x"}, Interval: "soon"},
+		CapAdd:            []string{"net admin"},
+		DNS:               []string{"not-an-ip"},
+		ExtraHosts:        []string{"db"},
+		Tmpfs:             []string{"run"},
+		NetworkMode:       "host",
+		Networks:          []NetworkAttachment{{Name: "bridge", IPv4Address: "10.0.0.300", Aliases: []string{"x"}}},
+	}
+	_, errs := buildContainerCreateSpec(req)
+	got := make(map[string]bool)
+	for _, e := range errs {
+		got[e.Field] = true
+	}
+	for _, f := range []string{
+		"image", "name", "ports[0]", "volumes[0]", "restart_max_retries", "memory", "pids_limit",
+		"healthcheck.test", "healthcheck.interval", "cap_add[0]", "dns[0]", "extra_hosts[0]", "tmpfs[0]",
+		"networks", "networks[0].ipv4_address", "networks[0].aliases",
+	} {
+		if !got[f] {
+			t.Errorf("missing field error for %s (got %+v)", f, errs)
+		}
+	}
+}
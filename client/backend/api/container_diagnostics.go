@@ -0,0 +1,78 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"dockerpanel/backend/pkg/database"
+
+	"github.com/gin-gonic/gin"
+)
+
+// listContainerDiagnostics 列出最近的崩溃循环 / OOM 诊断记录（?limit=）
+func listContainerDiagnostics(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	list, err := database.ListContainerDiagnostics("", limit)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "获取诊断记录失败", err)
+		return
+	}
+	c.JSON(http.StatusOK, list)
+}
+
+// listContainerDiagnosticsByContainer 列出单个容器的诊断记录
+func listContainerDiagnosticsByContainer(c *gin.Context) {
+	cli, ok := getDockerClient(c)
+	if !ok {
+		return
+	}
+	defer cli.Close()
+
+	// 统一使用完整 ID 查询，兼容传入名称或短 ID
+	containerID := c.Param("id")
+	if info, err := cli.ContainerInspect(c.Request.Context(), containerID); err == nil {
+		containerID = info.ID
+	}
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	list, err := database.ListContainerDiagnostics(containerID, limit)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "获取诊断记录失败", err)
+		return
+	}
+	c.JSON(http.StatusOK, list)
+}
+
+// getContainerDiagnostic 获取单条诊断记录，包含采集到的日志
+func getContainerDiagnostic(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, "无效的诊断记录 ID", err)
+		return
+	}
+	d, err := database.GetContainerDiagnostic(id)
+	if errors.Is(err, sql.ErrNoRows) {
+		respondError(c, http.StatusNotFound, "诊断记录不存在", nil)
+		return
+	}
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "获取诊断记录失败", err)
+		return
+	}
+	c.JSON(http.StatusOK, d)
+}
+
+// deleteContainerDiagnostic 删除诊断记录
+func deleteContainerDiagnostic(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, "无效的诊断记录 ID", err)
+		return
+	}
+	if err := database.DeleteContainerDiagnostic(id); err != nil {
+		respondError(c, http.StatusInternalServerError, "删除诊断记录失败", err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "诊断记录已删除"})
+}
@@ -0,0 +1,589 @@
+package api
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"dockerpanel/backend/pkg/docker"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/client"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// containerFileListLimit 单次列目录返回的最大条目数
+	containerFileListLimit = 2000
+	// containerFileListScanBytes / containerFileListScanHeaders 列目录时最多读取的归档字节数与条目数。
+	// CopyFromContainer 只能按子树打包，子目录的内容会夹在第一级条目之间，超出预算后停止读取并标记为截断
+	containerFileListScanBytes   = 64 << 20
+	containerFileListScanHeaders = 20000
+	// containerFileEditMaxBytes 在线编辑的文本文件大小上限
+	containerFileEditMaxBytes = 1 << 20
+	// containerUploadMaxBytes 单次上传的总大小上限
+	containerUploadMaxBytes = 1 << 30
+)
+
+// containerFileEntry 容器内的文件或目录
+type containerFileEntry struct {
+	Name       string    `json:"name"`
+	Path       string    `json:"path"`
+	Type       string    `json:"type"` // file / dir / symlink / other
+	Size       int64     `json:"size"`
+	Mode       string    `json:"mode"`
+	ModTime    time.Time `json:"modTime"`
+	LinkTarget string    `json:"linkTarget,omitempty"`
+	UID        int       `json:"uid"`
+	GID        int       `json:"gid"`
+}
+
+// cleanContainerPath 规范化容器内的绝对路径
+func cleanContainerPath(raw string) (string, bool) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" || strings.ContainsRune(raw, 0) {
+		return "", false
+	}
+	if !strings.HasPrefix(raw, "/") {
+		return "", false
+	}
+	return path.Clean(raw), true
+}
+
+func tarEntryType(hdr *tar.Header) string {
+	switch hdr.Typeflag {
+	case tar.TypeDir:
+		return "dir"
+	case tar.TypeReg:
+		return "file"
+	case tar.TypeSymlink, tar.TypeLink:
+		return "symlink"
+	default:
+		return "other"
+	}
+}
+
+// countingReader 记录已读取的字节数
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// listTarDirectory 从 CopyFromContainer 返回的归档中提取 dir 的直接子项。
+// 归档内的路径以目录名为第一级，例如请求 /etc 时为 "etc/passwd"；请求 / 时没有这一级。
+// 归档按路径顺序输出，子目录的全部内容紧跟在该子目录之后，因此读取量超过 maxBytes
+// 或扫描条目超过 maxHeaders 时直接停止，返回已得到的部分并标记截断。
+func listTarDirectory(r io.Reader, dir string, limit int, maxBytes int64, maxHeaders int) ([]containerFileEntry, bool, error) {
+	cr := &countingReader{r: r}
+	tr := tar.NewReader(cr)
+	entries := make([]containerFileEntry, 0)
+	truncated := false
+	for scanned := 0; ; scanned++ {
+		if scanned >= maxHeaders || cr.n > maxBytes {
+			truncated = true
+			break
+		}
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, false, err
+		}
+		rel := strings.Trim(strings.TrimPrefix(hdr.Name, "./"), "/")
+		if dir != "/" {
+			_, rest, ok := strings.Cut(rel, "/")
+			if !ok {
+				continue // 目录自身
+			}
+			rel = rest
+		}
+		if rel == "" || strings.Contains(rel, "/") {
+			continue
+		}
+		if len(entries) >= limit {
+			truncated = true
+			break
+		}
+		entries = append(entries, containerFileEntry{
+			Name:       rel,
+			Path:       path.Join(dir, rel),
+			Type:       tarEntryType(hdr),
+			Size:       hdr.Size,
+			Mode:       hdr.FileInfo().Mode().String(),
+			ModTime:    hdr.ModTime,
+			LinkTarget: hdr.Linkname,
+			UID:        hdr.Uid,
+			GID:        hdr.Gid,
+		})
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if (entries[i].Type == "dir") != (entries[j].Type == "dir") {
+			return entries[i].Type == "dir"
+		}
+		return entries[i].Name < entries[j].Name
+	})
+	return entries, truncated, nil
+}
+
+// writeTarAsZip 将 tar 流转换为 zip 写出，符号链接按 zip 约定以链接目标作为内容
+func writeTarAsZip(w io.Writer, r io.Reader) error {
+	tr := tar.NewReader(r)
+	zw := zip.NewWriter(w)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		switch hdr.Typeflag {
+		case tar.TypeDir, tar.TypeReg, tar.TypeSymlink:
+		default:
+			continue
+		}
+		zh, err := zip.FileInfoHeader(hdr.FileInfo())
+		if err != nil {
+			return err
+		}
+		zh.Name = strings.TrimPrefix(hdr.Name, "/")
+		if hdr.Typeflag == tar.TypeDir {
+			zh.Name = strings.TrimSuffix(zh.Name, "/") + "/"
+		} else {
+			zh.Method = zip.Deflate
+		}
+		fw, err := zw.CreateHeader(zh)
+		if err != nil {
+			return err
+		}
+		switch hdr.Typeflag {
+		case tar.TypeSymlink:
+			_, err = io.WriteString(fw, hdr.Linkname)
+		case tar.TypeReg:
+			_, err = io.Copy(fw, tr)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+// tarFileMode 将 os.FileMode 转换为 tar 头中的权限位（含 setuid/setgid/sticky）
+func tarFileMode(m os.FileMode) int64 {
+	mode := int64(m.Perm())
+	if m&os.ModeSetuid != 0 {
+		mode |= 04000
+	}
+	if m&os.ModeSetgid != 0 {
+		mode |= 02000
+	}
+	if m&os.ModeSticky != 0 {
+		mode |= 01000
+	}
+	return mode
+}
+
+// singleFileTar 构造只包含一个文件的 tar，用于 CopyToContainer
+func singleFileTar(hdr tar.Header, content []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	hdr.Typeflag = tar.TypeReg
+	hdr.Size = int64(len(content))
+	if err := tw.WriteHeader(&hdr); err != nil {
+		return nil, err
+	}
+	if _, err := tw.Write(content); err != nil {
+		return nil, err
+	}
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// containerPathFromQuery 校验请求中的容器路径，并处理自身容器保护
+func containerPathFromQuery(c *gin.Context, raw string) (string, bool) {
+	if forbidIfSelfContainer(c, c.Param("id")) {
+		return "", false
+	}
+	p, ok := cleanContainerPath(raw)
+	if !ok {
+		respondError(c, http.StatusBadRequest, "路径必须是容器内的绝对路径", nil)
+		return "", false
+	}
+	return p, true
+}
+
+func respondContainerPathError(c *gin.Context, err error) {
+	if client.IsErrNotFound(err) {
+		respondError(c, http.StatusNotFound, "容器或路径不存在", err)
+		return
+	}
+	respondError(c, http.StatusInternalServerError, "读取容器文件失败", err)
+}
+
+// listContainerFiles 列出容器内目录（容器停止时同样可用）
+func listContainerFiles(c *gin.Context) {
+	dir, ok := containerPathFromQuery(c, c.DefaultQuery("path", "/"))
+	if !ok {
+		return
+	}
+
+	cli, ok := getDockerClient(c)
+	if !ok {
+		return
+	}
+	defer cli.Close()
+
+	ctx := c.Request.Context()
+	id := c.Param("id")
+	stat, err := cli.ContainerStatPath(ctx, id, dir)
+	if err != nil {
+		respondContainerPathError(c, err)
+		return
+	}
+	// 指向目录的符号链接按目标目录列出
+	if stat.Mode&os.ModeSymlink != 0 && stat.LinkTarget != "" {
+		dir = path.Clean(stat.LinkTarget)
+		if stat, err = cli.ContainerStatPath(ctx, id, dir); err != nil {
+			respondContainerPathError(c, err)
+			return
+		}
+	}
+	if !stat.Mode.IsDir() {
+		respondError(c, http.StatusBadRequest, "该路径不是目录", nil)
+		return
+	}
+
+	rc, _, err := cli.CopyFromContainer(ctx, id, dir)
+	if err != nil {
+		respondContainerPathError(c, err)
+		return
+	}
+	entries, truncated, err := listTarDirectory(rc, dir, containerFileListLimit, containerFileListScanBytes, containerFileListScanHeaders)
+	// 提前关闭以中止剩余归档的传输
+	rc.Close()
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "解析目录内容失败", err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"path":      dir,
+		"entries":   entries,
+		"truncated": truncated,
+	})
+}
+
+// downloadContainerFiles 下载容器内的文件或目录（?format=tar|zip）
+func downloadContainerFiles(c *gin.Context) {
+	p, ok := containerPathFromQuery(c, c.Query("path"))
+	if !ok {
+		return
+	}
+	format := strings.ToLower(c.DefaultQuery("format", "tar"))
+	if format != "tar" && format != "zip" {
+		respondError(c, http.StatusBadRequest, "format 仅支持 tar / zip", nil)
+		return
+	}
+
+	cli, ok := getDockerClient(c)
+	if !ok {
+		return
+	}
+	defer cli.Close()
+
+	rc, _, err := cli.CopyFromContainer(c.Request.Context(), c.Param("id"), p)
+	if err != nil {
+		respondContainerPathError(c, err)
+		return
+	}
+	defer rc.Close()
+
+	base := path.Base(p)
+	if base == "/" {
+		base = "rootfs"
+	}
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", base+"."+format))
+	if format == "tar" {
+		c.Header("Content-Type", "application/x-tar")
+		if _, err := io.Copy(c.Writer, rc); err != nil {
+			log.Printf("下载容器文件中断: %v", err)
+		}
+		return
+	}
+	c.Header("Content-Type", "application/zip")
+	if err := writeTarAsZip(c.Writer, rc); err != nil {
+		log.Printf("下载容器文件中断: %v", err)
+	}
+}
+
+// uploadContainerFiles 上传文件到容器内目录（multipart 字段 files；extract=true 时上传的 tar 会被解包）
+func uploadContainerFiles(c *gin.Context) {
+	dir, ok := containerPathFromQuery(c, c.Query("path"))
+	if !ok {
+		return
+	}
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, containerUploadMaxBytes)
+	form, err := c.MultipartForm()
+	if err != nil {
+		respondError(c, http.StatusBadRequest, "读取上传文件失败（单次上传不超过 1GB）", err)
+		return
+	}
+	files := append(form.File["files"], form.File["file"]...)
+	if len(files) == 0 {
+		respondError(c, http.StatusBadRequest, "请选择要上传的文件", nil)
+		return
+	}
+	extract := c.Query("extract") == "true"
+	if extract && (len(files) != 1 || !strings.HasSuffix(strings.ToLower(files[0].Filename), ".tar")) {
+		respondError(c, http.StatusBadRequest, "解包上传仅支持单个 .tar 文件", nil)
+		return
+	}
+
+	cli, ok := getDockerClient(c)
+	if !ok {
+		return
+	}
+	defer cli.Close()
+
+	ctx := c.Request.Context()
+	id := c.Param("id")
+	stat, err := cli.ContainerStatPath(ctx, id, dir)
+	if err != nil {
+		respondContainerPathError(c, err)
+		return
+	}
+	if !stat.Mode.IsDir() {
+		respondError(c, http.StatusBadRequest, "目标路径不是目录", nil)
+		return
+	}
+
+	var content io.Reader
+	if extract {
+		f, err := files[0].Open()
+		if err != nil {
+			respondError(c, http.StatusBadRequest, "读取上传文件失败", err)
+			return
+		}
+		defer f.Close()
+		content = f
+	} else {
+		pr, pw := io.Pipe()
+		go func() { pw.CloseWithError(writeUploadTar(pw, files)) }()
+		defer pr.Close()
+		content = pr
+	}
+
+	if err := cli.CopyToContainer(ctx, id, dir, content, types.CopyToContainerOptions{}); err != nil {
+		respondError(c, http.StatusInternalServerError, "上传文件到容器失败", err)
+		return
+	}
+	names := make([]string, 0, len(files))
+	for _, f := range files {
+		names = append(names, path.Base(f.Filename))
+	}
+	log.Printf("上传文件到容器: container=%s dir=%s files=%v", id, dir, names)
+	c.JSON(http.StatusOK, gin.H{"message": "上传成功", "path": dir, "files": names})
+}
+
+// writeUploadTar 将 multipart 上传的文件打包为 tar，文件名只保留最后一级
+func writeUploadTar(w io.Writer, files []*multipart.FileHeader) error {
+	tw := tar.NewWriter(w)
+	now := time.Now()
+	for _, fh := range files {
+		name := path.Base(strings.ReplaceAll(fh.Filename, "\\", "/"))
+		if name == "." || name == "/" || name == ".." {
+			return fmt.Errorf("文件名不合法: %s", fh.Filename)
+		}
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: fh.Size, ModTime: now, Typeflag: tar.TypeReg}); err != nil {
+			return err
+		}
+		f, err := fh.Open()
+		if err != nil {
+			return err
+		}
+		_, err = io.Copy(tw, f)
+		f.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return tw.Close()
+}
+
+// readContainerFile 读取容器内单个文件的 tar 头与内容（不超过 limit 字节）
+func readContainerFile(ctx context.Context, cli *docker.Client, id string, p string, limit int64) (*tar.Header, []byte, error) {
+	rc, _, err := cli.CopyFromContainer(ctx, id, p)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rc.Close()
+	tr := tar.NewReader(rc)
+	hdr, err := tr.Next()
+	if err != nil {
+		return nil, nil, err
+	}
+	if hdr.Size > limit {
+		return hdr, nil, fmt.Errorf("文件超过 %d 字节", limit)
+	}
+	data, err := io.ReadAll(io.LimitReader(tr, limit))
+	return hdr, data, err
+}
+
+// getContainerFileContent 读取容器内小型文本文件用于在线编辑
+func getContainerFileContent(c *gin.Context) {
+	p, ok := containerPathFromQuery(c, c.Query("path"))
+	if !ok {
+		return
+	}
+
+	cli, ok := getDockerClient(c)
+	if !ok {
+		return
+	}
+	defer cli.Close()
+
+	ctx := c.Request.Context()
+	id := c.Param("id")
+	stat, err := cli.ContainerStatPath(ctx, id, p)
+	if err != nil {
+		respondContainerPathError(c, err)
+		return
+	}
+	if stat.LinkTarget != "" && stat.LinkTarget != p {
+		respondError(c, http.StatusBadRequest, fmt.Sprintf("该路径是符号链接，请编辑目标文件 %s", stat.LinkTarget), nil)
+		return
+	}
+	if !stat.Mode.IsRegular() {
+		respondError(c, http.StatusBadRequest, "仅支持编辑普通文件", nil)
+		return
+	}
+	if stat.Size > containerFileEditMaxBytes {
+		respondError(c, http.StatusRequestEntityTooLarge, "文件过大，在线编辑仅支持 1MB 以内的文本文件，请使用下载", nil)
+		return
+	}
+
+	hdr, data, err := readContainerFile(ctx, cli, id, p, containerFileEditMaxBytes)
+	if err != nil {
+		respondContainerPathError(c, err)
+		return
+	}
+	if bytes.IndexByte(data, 0) >= 0 || !utf8.Valid(data) {
+		respondError(c, http.StatusUnsupportedMediaType, "二进制或非 UTF-8 文件不支持在线编辑", nil)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"path":    p,
+		"content": string(data),
+		"size":    stat.Size,
+		"mode":    stat.Mode.String(),
+		"mtime":   stat.Mtime,
+		"uid":     hdr.Uid,
+		"gid":     hdr.Gid,
+	})
+}
+
+type saveContainerFileRequest struct {
+	Path    string     `json:"path"`
+	Content string     `json:"content"`
+	Mtime   *time.Time `json:"mtime"`  // 读取时的修改时间，不一致说明文件已被修改
+	Create  bool       `json:"create"` // 文件不存在时创建
+}
+
+// saveContainerFileContent 写回在线编辑的文本文件，保留原文件的权限与属主
+func saveContainerFileContent(c *gin.Context) {
+	var req saveContainerFileRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, "无效的请求参数", err)
+		return
+	}
+	p, ok := containerPathFromQuery(c, req.Path)
+	if !ok {
+		return
+	}
+	if p == "/" {
+		respondError(c, http.StatusBadRequest, "路径必须指向文件", nil)
+		return
+	}
+	if len(req.Content) > containerFileEditMaxBytes {
+		respondError(c, http.StatusRequestEntityTooLarge, "内容超过 1MB，请改用上传", nil)
+		return
+	}
+
+	cli, ok := getDockerClient(c)
+	if !ok {
+		return
+	}
+	defer cli.Close()
+
+	ctx := c.Request.Context()
+	id := c.Param("id")
+	hdr := tar.Header{Name: path.Base(p), Mode: 0644, ModTime: time.Now()}
+	stat, err := cli.ContainerStatPath(ctx, id, p)
+	switch {
+	case err == nil:
+		if stat.LinkTarget != "" && stat.LinkTarget != p {
+			respondError(c, http.StatusBadRequest, fmt.Sprintf("该路径是符号链接，请编辑目标文件 %s", stat.LinkTarget), nil)
+			return
+		}
+		if !stat.Mode.IsRegular() {
+			respondError(c, http.StatusBadRequest, "仅支持编辑普通文件", nil)
+			return
+		}
+		if req.Mtime != nil && !req.Mtime.Equal(stat.Mtime) {
+			respondError(c, http.StatusConflict, "文件已被修改，请重新加载后再保存", nil)
+			return
+		}
+		// 只需要原文件的 tar 头（属主信息 stat 中没有）
+		orig, _, err := readContainerFile(ctx, cli, id, p, 0)
+		if orig == nil {
+			respondContainerPathError(c, err)
+			return
+		}
+		hdr.Mode = tarFileMode(stat.Mode)
+		hdr.Uid, hdr.Gid = orig.Uid, orig.Gid
+		hdr.Uname, hdr.Gname = orig.Uname, orig.Gname
+	case client.IsErrNotFound(err) && req.Create:
+	default:
+		respondContainerPathError(c, err)
+		return
+	}
+
+	archive, err := singleFileTar(hdr, []byte(req.Content))
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "打包文件失败", err)
+		return
+	}
+	if err := cli.CopyToContainer(ctx, id, path.Dir(p), bytes.NewReader(archive), types.CopyToContainerOptions{}); err != nil {
+		respondError(c, http.StatusInternalServerError, "写入容器文件失败", err)
+		return
+	}
+
+	newStat, err := cli.ContainerStatPath(ctx, id, p)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{"message": "保存成功", "path": p})
+		return
+	}
+	log.Printf("在线编辑容器文件: container=%s path=%s size=%d", id, p, len(req.Content))
+	c.JSON(http.StatusOK, gin.H{"message": "保存成功", "path": p, "size": newStat.Size, "mtime": newStat.Mtime})
+}
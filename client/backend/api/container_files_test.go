@@ -0,0 +1,155 @@
+package api
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"io"
+	"os"
+	"testing"
+)
+
+func buildTestTar(t *testing.T, headers []tar.Header, bodies map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, h := range headers {
+		body := bodies[h.Name]
+		h.Size = int64(len(body))
+		if err := tw.WriteHeader(&h); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(body)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func fixtureEtcTar(t *testing.T) []byte {
+	return buildTestTar(t, []tar.Header{
+		{Name: "etc/", Typeflag: tar.TypeDir, Mode: 0755},
+		{Name: "etc/passwd", Typeflag: tar.TypeReg, Mode: 0644},
+		{Name: "etc/nginx/", Typeflag: tar.TypeDir, Mode: 0755},
+		{Name: "etc/nginx/nginx.conf", Typeflag: tar.TypeReg, Mode: 0644},
+		{Name: "etc/localtime", Typeflag: tar.TypeSymlink, Linkname: "/usr/share/zoneinfo/UTC", Mode: 0777},
+	}, map[string]string{"etc/passwd": "root:x:0:0::/root:/bin/sh\n", "etc/nginx/nginx.conf": "events {}\n"})
+}
+
+func TestListTarDirectory(t *testing.T) {
+	entries, truncated, err := listTarDirectory(bytes.NewReader(fixtureEtcTar(t)), "/etc", 100, 1<<20, 100)
+	if err != nil || truncated {
+		t.Fatalf("listTarDirectory: %v truncated=%v", err, truncated)
+	}
+	if len(entries) != 3 {
+		t.Fatalf("entries = %+v", entries)
+	}
+	if entries[0].Name != "nginx" || entries[0].Type != "dir" || entries[0].Path != "/etc/nginx" {
+		t.Fatalf("directories should come first: %+v", entries[0])
+	}
+	if entries[1].Name != "localtime" || entries[1].Type != "symlink" || entries[1].LinkTarget != "/usr/share/zoneinfo/UTC" {
+		t.Fatalf("unexpected symlink entry: %+v", entries[1])
+	}
+	if entries[2].Name != "passwd" || entries[2].Size != 26 || entries[2].Mode != "-rw-r--r--" {
+		t.Fatalf("unexpected file entry: %+v", entries[2])
+	}
+
+	if entries, truncated, _ := listTarDirectory(bytes.NewReader(fixtureEtcTar(t)), "/etc", 1, 1<<20, 100); !truncated || len(entries) != 1 {
+		t.Fatalf("expected truncation, got %d entries", len(entries))
+	}
+
+	root := buildTestTar(t, []tar.Header{
+		{Name: "bin/", Typeflag: tar.TypeDir, Mode: 0755},
+		{Name: "bin/sh", Typeflag: tar.TypeReg, Mode: 0755},
+		{Name: "etc/", Typeflag: tar.TypeDir, Mode: 0755},
+	}, nil)
+	entries, _, _ = listTarDirectory(bytes.NewReader(root), "/", 100, 1<<20, 100)
+	if len(entries) != 2 || entries[0].Path != "/bin" || entries[1].Path != "/etc" {
+		t.Fatalf("root entries = %+v", entries)
+	}
+
+	// 扫描预算用尽时停止读取
+	entries, truncated, _ = listTarDirectory(bytes.NewReader(root), "/", 100, 1<<20, 2)
+	if !truncated || len(entries) != 1 || entries[0].Path != "/bin" {
+		t.Fatalf("header budget: truncated=%v entries=%+v", truncated, entries)
+	}
+}
+
+func TestTarFileMode(t *testing.T) {
+	if got := tarFileMode(0755 | os.ModeSetuid); got != 04755 {
+		t.Fatalf("setuid: %o", got)
+	}
+	if got := tarFileMode(0644 | os.ModeSetgid | os.ModeSticky); got != 03644 {
+		t.Fatalf("setgid+sticky: %o", got)
+	}
+	if got := tarFileMode(0600 | os.ModeDir); got != 0600 {
+		t.Fatalf("plain: %o", got)
+	}
+}
+
+func TestWriteTarAsZip(t *testing.T) {
+	var buf bytes.Buffer
+	if err := writeTarAsZip(&buf, bytes.NewReader(fixtureEtcTar(t))); err != nil {
+		t.Fatalf("writeTarAsZip: %v", err)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := make(map[string]*zip.File)
+	for _, f := range zr.File {
+		files[f.Name] = f
+	}
+	if len(files) != 5 || files["etc/nginx/"] == nil {
+		t.Fatalf("zip entries = %v", files)
+	}
+	rc, err := files["etc/nginx/nginx.conf"].Open()
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := io.ReadAll(rc)
+	rc.Close()
+	if string(data) != "events {}\n" {
+		t.Fatalf("content = %q", data)
+	}
+	if files["etc/localtime"].Mode()&0o7777 != 0o777 || files["etc/localtime"].Mode().Type() == 0 {
+		t.Fatalf("symlink mode = %v", files["etc/localtime"].Mode())
+	}
+}
+
+func TestCleanContainerPath(t *testing.T) {
+	cases := map[string]string{
+		"/etc/../etc/nginx/": "/etc/nginx",
+		" /":                 "/",
+		"/a//b":              "/a/b",
+	}
+	for in, want := range cases {
+		if got, ok := cleanContainerPath(in); !ok || got != want {
+			t.Errorf("cleanContainerPath(%q) = %q, %v", in, got, ok)
+		}
+	}
+	for _, bad := range []string{"", "etc/passwd", "/etc\x00"} {
+		if _, ok := cleanContainerPath(bad); ok {
+			t.Errorf("cleanContainerPath(%q) should fail", bad)
+		}
+	}
+}
+
+func TestSingleFileTar(t *testing.T) {
+	data, err := singleFileTar(tar.Header{Name: "app.conf", Mode: 0600, Uid: 101, Gid: 101}, []byte("listen 80;\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr := tar.NewReader(bytes.NewReader(data))
+	hdr, err := tr.Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(tr)
+	if hdr.Name != "app.conf" || hdr.Mode != 0600 || hdr.Uid != 101 || hdr.Size != 11 || string(body) != "listen 80;\n" {
+		t.Fatalf("unexpected tar entry: %+v %q", hdr, body)
+	}
+}
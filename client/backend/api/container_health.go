@@ -0,0 +1,371 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"dockerpanel/backend/pkg/database"
+	"dockerpanel/backend/pkg/docker"
+	"dockerpanel/backend/pkg/system"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// autohealLabel 容器需显式设置 tradis.autoheal=true 才会被自动重启
+	autohealLabel = "tradis.autoheal"
+	// autohealThresholdLabel 连续失败多少次健康检查后重启，默认 autohealDefaultThreshold
+	autohealThresholdLabel   = "tradis.autoheal.threshold"
+	autohealDefaultThreshold = 3
+	autohealPollInterval     = 15 * time.Second
+	autohealBaseBackoff      = 30 * time.Second
+	autohealMaxBackoff       = 30 * time.Minute
+	// autohealMaxAttempts 连续重启仍未恢复健康时放弃，需手动重置
+	autohealMaxAttempts = 10
+
+	healthOutputMaxLen = 4096
+)
+
+// autohealState 单个容器的自动修复状态，容器恢复 healthy 后清零
+type autohealState struct {
+	Attempts    int       `json:"attempts"`
+	LastRestart time.Time `json:"lastRestart"`
+	NextAllowed time.Time `json:"nextAllowed"`
+	GaveUp      bool      `json:"gaveUp"`
+}
+
+type healthWatcher struct {
+	mu     sync.Mutex
+	states map[string]*autohealState
+}
+
+var containerHealthWatcher = &healthWatcher{states: make(map[string]*autohealState)}
+
+// autohealConfigFromLabels 读取容器的自动修复开关与阈值
+func autohealConfigFromLabels(labels map[string]string) (bool, int) {
+	enabled, _ := strconv.ParseBool(strings.TrimSpace(labels[autohealLabel]))
+	threshold := autohealDefaultThreshold
+	if n, err := strconv.Atoi(strings.TrimSpace(labels[autohealThresholdLabel])); err == nil && n > 0 {
+		threshold = n
+	}
+	return enabled, threshold
+}
+
+// autohealBackoff 第 attempts 次重启后需要等待的时间，指数增长并封顶
+func autohealBackoff(attempts int) time.Duration {
+	d := autohealBaseBackoff
+	for i := 1; i < attempts && d < autohealMaxBackoff; i++ {
+		d *= 2
+	}
+	if d > autohealMaxBackoff {
+		d = autohealMaxBackoff
+	}
+	return d
+}
+
+// decide 判断是否应当重启：返回 restart 表示立即重启，giveUp 表示本次刚刚达到放弃条件
+func (w *healthWatcher) decide(id string, streak int, threshold int, now time.Time) (restart bool, giveUp bool) {
+	if streak < threshold {
+		return false, false
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	st := w.states[id]
+	if st == nil {
+		st = &autohealState{}
+		w.states[id] = st
+	}
+	if st.GaveUp || now.Before(st.NextAllowed) {
+		return false, false
+	}
+	if st.Attempts >= autohealMaxAttempts {
+		st.GaveUp = true
+		return false, true
+	}
+	return true, false
+}
+
+func (w *healthWatcher) recordRestart(id string, now time.Time) autohealState {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	st := w.states[id]
+	if st == nil {
+		st = &autohealState{}
+		w.states[id] = st
+	}
+	st.Attempts++
+	st.LastRestart = now
+	st.NextAllowed = now.Add(autohealBackoff(st.Attempts))
+	return *st
+}
+
+func (w *healthWatcher) reset(id string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	delete(w.states, id)
+}
+
+func (w *healthWatcher) snapshot(id string) autohealState {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if st := w.states[id]; st != nil {
+		return *st
+	}
+	return autohealState{}
+}
+
+// lastHealthOutput 返回最近一次健康检查的退出码与输出（截断）
+func lastHealthOutput(h *types.Health) (int, string) {
+	if h == nil || len(h.Log) == 0 {
+		return 0, ""
+	}
+	last := h.Log[len(h.Log)-1]
+	out := strings.TrimSpace(last.Output)
+	if len(out) > healthOutputMaxLen {
+		out = out[:healthOutputMaxLen] + "..."
+	}
+	return last.ExitCode, out
+}
+
+// StartHealthWatcher 启动健康状态监听与自动修复
+func StartHealthWatcher() {
+	go watchHealthEvents()
+	go runAutohealLoop()
+}
+
+// watchHealthEvents 订阅 health_status 事件记录状态变化，连接断开后自动重连
+func watchHealthEvents() {
+	retry := 5 * time.Second
+	for {
+		if err := consumeHealthEvents(); err != nil {
+			log.Printf("健康状态事件监听中断: %v，%s 后重试", err, retry)
+		}
+		time.Sleep(retry)
+	}
+}
+
+func consumeHealthEvents() error {
+	cli, err := docker.NewDockerClient()
+	if err != nil {
+		return err
+	}
+	defer cli.Close()
+
+	ctx := context.Background()
+	msgs, errs := cli.Events(ctx, types.EventsOptions{
+		Filters: filters.NewArgs(
+			filters.Arg("type", "container"),
+			filters.Arg("event", "health_status"),
+			filters.Arg("event", "destroy"),
+		),
+	})
+	for {
+		select {
+		case ev := <-msgs:
+			action := ev.Action
+			id := ev.Actor.ID
+			if action == "destroy" {
+				containerHealthWatcher.reset(id)
+				_ = database.DeleteContainerHealthLogs(id)
+				continue
+			}
+			if !strings.HasPrefix(action, "health_status") {
+				continue
+			}
+			status := strings.TrimSpace(strings.TrimPrefix(action, "health_status:"))
+			recordHealthTransition(ctx, cli, id, ev.Actor.Attributes["name"], status)
+		case err := <-errs:
+			return err
+		}
+	}
+}
+
+func recordHealthTransition(ctx context.Context, cli *docker.Client, id string, name string, status string) {
+	entry := &database.ContainerHealthLog{ContainerID: id, ContainerName: name, Status: status, Action: "transition"}
+	if info, err := cli.ContainerInspect(ctx, id); err == nil && info.State != nil && info.State.Health != nil {
+		entry.FailingStreak = info.State.Health.FailingStreak
+		entry.ExitCode, entry.Output = lastHealthOutput(info.State.Health)
+	}
+	if err := database.AddContainerHealthLog(entry); err != nil {
+		log.Printf("写入健康记录失败: %v", err)
+	}
+
+	switch status {
+	case "healthy":
+		containerHealthWatcher.reset(id)
+	case "unhealthy":
+		system.LogSimpleEvent("warning", fmt.Sprintf("容器 %s 健康检查失败: %s", name, entry.Output))
+	}
+}
+
+// runAutohealLoop 定期检查开启了自动修复且处于 unhealthy 的容器
+func runAutohealLoop() {
+	ticker := time.NewTicker(autohealPollInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		if err := autohealOnce(context.Background()); err != nil {
+			log.Printf("自动修复检查失败: %v", err)
+		}
+	}
+}
+
+func autohealOnce(ctx context.Context) error {
+	cli, err := docker.NewDockerClient()
+	if err != nil {
+		return err
+	}
+	defer cli.Close()
+
+	list, err := cli.ContainerList(ctx, types.ContainerListOptions{
+		Filters: filters.NewArgs(
+			filters.Arg("label", autohealLabel),
+			filters.Arg("health", "unhealthy"),
+		),
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, ct := range list {
+		name := containerDisplayName(ct)
+		if isSelfOrProtectedContainer(ct.ID, name, ct.Image, ct.Labels) {
+			continue
+		}
+		enabled, threshold := autohealConfigFromLabels(ct.Labels)
+		if !enabled {
+			continue
+		}
+		info, err := cli.ContainerInspect(ctx, ct.ID)
+		if err != nil || info.State == nil || info.State.Health == nil {
+			continue
+		}
+		streak := info.State.Health.FailingStreak
+		exitCode, output := lastHealthOutput(info.State.Health)
+
+		restart, giveUp := containerHealthWatcher.decide(ct.ID, streak, threshold, time.Now())
+		if giveUp {
+			_ = database.AddContainerHealthLog(&database.ContainerHealthLog{
+				ContainerID: ct.ID, ContainerName: name, Status: "unhealthy", Action: "autoheal_giveup",
+				FailingStreak: streak, ExitCode: exitCode, Output: output,
+			})
+			_ = database.SaveNotification(&database.Notification{
+				Type:    "error",
+				Message: fmt.Sprintf("容器 %s 已自动重启 %d 次仍不健康，已停止自动修复", name, autohealMaxAttempts),
+			})
+			continue
+		}
+		if !restart {
+			continue
+		}
+
+		timeout := 10
+		rerr := cli.ContainerRestart(ctx, ct.ID, container.StopOptions{Timeout: &timeout})
+		st := containerHealthWatcher.recordRestart(ct.ID, time.Now())
+		entry := &database.ContainerHealthLog{
+			ContainerID: ct.ID, ContainerName: name, Status: "unhealthy", Action: "autoheal",
+			FailingStreak: streak, ExitCode: exitCode, Output: output,
+		}
+		if rerr != nil {
+			entry.Action = "autoheal_failed"
+			entry.Output = strings.TrimSpace(output + "\n重启失败: " + rerr.Error())
+			_ = database.SaveNotification(&database.Notification{
+				Type:    "error",
+				Message: fmt.Sprintf("自动重启不健康的容器 %s 失败: %v", name, rerr),
+			})
+		} else {
+			log.Printf("已自动重启不健康的容器: %s（连续失败 %d 次，第 %d 次重启）", name, streak, st.Attempts)
+			_ = database.SaveNotification(&database.Notification{
+				Type:    "warning",
+				Message: fmt.Sprintf("容器 %s 连续 %d 次健康检查失败，已自动重启（第 %d 次）", name, streak, st.Attempts),
+			})
+		}
+		_ = database.AddContainerHealthLog(entry)
+	}
+	return nil
+}
+
+// getContainerHealth 返回容器当前健康状态、Docker 保留的最近检查输出、历史记录与自动修复状态
+func getContainerHealth(c *gin.Context) {
+	id := c.Param("id")
+	cli, ok := getDockerClient(c)
+	if !ok {
+		return
+	}
+	defer cli.Close()
+
+	info, err := cli.ContainerInspect(c.Request.Context(), id)
+	if err != nil {
+		respondError(c, http.StatusNotFound, "容器不存在", err)
+		return
+	}
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	history, err := database.ListContainerHealthLogs(info.ID, limit)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "获取健康记录失败", err)
+		return
+	}
+
+	var labels map[string]string
+	if info.Config != nil {
+		labels = info.Config.Labels
+	}
+	enabled, threshold := autohealConfigFromLabels(labels)
+	result := gin.H{
+		"status":        "none",
+		"failingStreak": 0,
+		"checks":        []types.HealthcheckResult{},
+		"history":       history,
+		"autoheal": gin.H{
+			"enabled":   enabled,
+			"threshold": threshold,
+			"state":     containerHealthWatcher.snapshot(info.ID),
+		},
+	}
+	if info.State != nil && info.State.Health != nil {
+		result["status"] = info.State.Health.Status
+		result["failingStreak"] = info.State.Health.FailingStreak
+		result["checks"] = info.State.Health.Log
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+// listHealthEvents 列出所有容器最近的健康状态变化与自动修复记录
+func listHealthEvents(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	list, err := database.ListContainerHealthLogs("", limit)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "获取健康记录失败", err)
+		return
+	}
+	c.JSON(http.StatusOK, list)
+}
+
+// resetContainerAutoheal 清除自动修复的退避与放弃状态
+func resetContainerAutoheal(c *gin.Context) {
+	id := c.Param("id")
+	if forbidIfSelfContainer(c, id) {
+		return
+	}
+	cli, ok := getDockerClient(c)
+	if !ok {
+		return
+	}
+	defer cli.Close()
+
+	info, err := cli.ContainerInspect(c.Request.Context(), id)
+	if err != nil {
+		respondError(c, http.StatusNotFound, "容器不存在", err)
+		return
+	}
+	containerHealthWatcher.reset(info.ID)
+	c.JSON(http.StatusOK, gin.H{"message": "自动修复状态已重置"})
+}
@@ -0,0 +1,77 @@
+package api
+
+import (
+	"testing"
+	"time"
+
+	"github.com/docker/docker/api/types"
+)
+
+func TestAutohealConfigFromLabels(t *testing.T) {
+	if enabled, threshold := autohealConfigFromLabels(map[string]string{"tradis.autoheal": "true", "tradis.autoheal.threshold": "5"}); !enabled || threshold != 5 {
+		t.Fatalf("got enabled=%v threshold=%d", enabled, threshold)
+	}
+	if enabled, threshold := autohealConfigFromLabels(map[string]string{"tradis.autoheal": "yes", "tradis.autoheal.threshold": "0"}); enabled || threshold != autohealDefaultThreshold {
+		t.Fatalf("got enabled=%v threshold=%d", enabled, threshold)
+	}
+}
+
+func TestAutohealBackoff(t *testing.T) {
+	if autohealBackoff(1) != 30*time.Second || autohealBackoff(3) != 2*time.Minute {
+		t.Fatalf("backoff(1)=%s backoff(3)=%s", autohealBackoff(1), autohealBackoff(3))
+	}
+	if autohealBackoff(20) != autohealMaxBackoff {
+		t.Fatalf("backoff should be capped, got %s", autohealBackoff(20))
+	}
+}
+
+func TestHealthWatcherDecide(t *testing.T) {
+	w := &healthWatcher{states: make(map[string]*autohealState)}
+	now := time.Now()
+
+	if restart, _ := w.decide("c1", 2, 3, now); restart {
+		t.Fatalf("streak below threshold should not restart")
+	}
+	if restart, _ := w.decide("c1", 3, 3, now); !restart {
+		t.Fatalf("streak at threshold should restart")
+	}
+	st := w.recordRestart("c1", now)
+	if st.Attempts != 1 || !st.NextAllowed.Equal(now.Add(30*time.Second)) {
+		t.Fatalf("state = %+v", st)
+	}
+	if restart, _ := w.decide("c1", 5, 3, now.Add(10*time.Second)); restart {
+		t.Fatalf("restart within backoff window")
+	}
+	if restart, _ := w.decide("c1", 5, 3, now.Add(31*time.Second)); !restart {
+		t.Fatalf("restart after backoff window")
+	}
+
+	for i := 1; i < autohealMaxAttempts; i++ {
+		w.recordRestart("c1", now)
+	}
+	restart, giveUp := w.decide("c1", 5, 3, now.Add(24*time.Hour))
+	if restart || !giveUp {
+		t.Fatalf("should give up after %d attempts", autohealMaxAttempts)
+	}
+	if _, giveUp := w.decide("c1", 5, 3, now.Add(48*time.Hour)); giveUp {
+		t.Fatalf("give up should only be reported once")
+	}
+
+	w.reset("c1")
+	if restart, _ := w.decide("c1", 3, 3, now); !restart {
+		t.Fatalf("reset should clear state")
+	}
+}
+
+func TestLastHealthOutput(t *testing.T) {
+	code, out := lastHealthOutput(&types.Health{Log: []*types.HealthcheckResult{
+		{ExitCode: 0, Output: "ok"},
+		{ExitCode: 1, Output: "curl: (7) Failed to connect\n"},
+	}})
+	if code != 1 || out != "curl: (7) Failed to connect" {
+		t.Fatalf("got %d %q", code, out)
+	}
+	if code, out := lastHealthOutput(nil); code != 0 || out != "" {
+		t.Fatalf("nil health = %d %q", code, out)
+	}
+}
@@ -0,0 +1,355 @@
+package api
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/pkg/stdcopy"
+	"github.com/gin-gonic/gin"
+)
+
+// containerTopArgs 传给宿主机 ps 的参数；-e 必须保留，否则 Docker 只能过滤到当前终端的进程
+var containerTopArgs = []string{"-eo", "pid,ppid,user,pcpu,pmem,rss,etime,stat,args"}
+
+// hostProcRoot 读取进程命名空间 PID 时使用的 /proc 路径，面板以容器运行时可挂载宿主机 /proc 并通过 HOST_PROC 指定
+func hostProcRoot() string {
+	if v := strings.TrimSpace(os.Getenv("HOST_PROC")); v != "" {
+		return v
+	}
+	return "/proc"
+}
+
+// containerProcess 容器内的一个进程（PID 为宿主机视角，NSPid 为容器内视角，无法确定时为 0）
+type containerProcess struct {
+	PID     int     `json:"pid"`
+	PPID    int     `json:"ppid"`
+	NSPid   int     `json:"nsPid,omitempty"`
+	User    string  `json:"user"`
+	CPU     float64 `json:"cpu"`
+	Memory  float64 `json:"memory"`
+	RSS     int64   `json:"rss"` // 字节
+	Elapsed string  `json:"elapsed"`
+	State   string  `json:"state"`
+	Command string  `json:"command"`
+	IsMain  bool    `json:"isMain"`
+}
+
+// parseContainerTop 按标题列解析 ContainerTop 结果，兼容自定义参数与 Docker 默认的 ps -ef 输出
+func parseContainerTop(top container.ContainerTopOKBody) []containerProcess {
+	col := make(map[string]int, len(top.Titles))
+	for i, t := range top.Titles {
+		col[strings.ToUpper(strings.TrimSpace(t))] = i
+	}
+	get := func(row []string, names ...string) string {
+		for _, n := range names {
+			if i, ok := col[n]; ok && i < len(row) {
+				return strings.TrimSpace(row[i])
+			}
+		}
+		return ""
+	}
+
+	procs := make([]containerProcess, 0, len(top.Processes))
+	for _, row := range top.Processes {
+		pid, err := strconv.Atoi(get(row, "PID"))
+		if err != nil {
+			continue
+		}
+		p := containerProcess{
+			PID:     pid,
+			User:    get(row, "USER", "UID"),
+			Elapsed: get(row, "ELAPSED", "TIME"),
+			State:   get(row, "STAT", "S"),
+			Command: get(row, "COMMAND", "CMD", "ARGS"),
+		}
+		p.PPID, _ = strconv.Atoi(get(row, "PPID"))
+		p.CPU, _ = strconv.ParseFloat(get(row, "%CPU", "C"), 64)
+		p.Memory, _ = strconv.ParseFloat(get(row, "%MEM"), 64)
+		if kb, err := strconv.ParseInt(get(row, "RSS"), 10, 64); err == nil {
+			p.RSS = kb * 1024
+		}
+		procs = append(procs, p)
+	}
+	sort.Slice(procs, func(i, j int) bool { return procs[i].PID < procs[j].PID })
+	return procs
+}
+
+// readNSPid 从 /proc/<pid>/status 的 NSpid 行读取进程在最内层 PID 命名空间中的 PID。
+// NSpid 第一列必须等于请求的宿主机 PID：面板运行在容器中且未挂载宿主机 /proc 时，
+// 同号 PID 对应的是面板自身命名空间中的无关进程，此时返回错误以拒绝发送信号。
+func readNSPid(procRoot string, pid int) (int, error) {
+	data, err := os.ReadFile(filepath.Join(procRoot, strconv.Itoa(pid), "status"))
+	if err != nil {
+		return 0, err
+	}
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	for sc.Scan() {
+		line := sc.Text()
+		if !strings.HasPrefix(line, "NSpid:") {
+			continue
+		}
+		fields := strings.Fields(strings.TrimPrefix(line, "NSpid:"))
+		if len(fields) == 0 {
+			break
+		}
+		if fields[0] != strconv.Itoa(pid) {
+			return 0, fmt.Errorf("进程 %d 不在当前 PID 命名空间中，请挂载宿主机 /proc 并设置 HOST_PROC", pid)
+		}
+		return strconv.Atoi(fields[len(fields)-1])
+	}
+	return 0, fmt.Errorf("进程 %d 没有 NSpid 信息", pid)
+}
+
+// processSignals 允许发送给容器内进程的信号
+var processSignals = map[string]bool{
+	"HUP": true, "INT": true, "QUIT": true, "KILL": true, "USR1": true,
+	"USR2": true, "TERM": true, "STOP": true, "CONT": true,
+}
+
+// normalizeProcessSignal 校验信号名并返回不带 SIG 前缀的大写形式，空值默认 TERM
+func normalizeProcessSignal(raw string) (string, error) {
+	s := strings.ToUpper(strings.TrimSpace(raw))
+	if s == "" {
+		return "TERM", nil
+	}
+	s = strings.TrimPrefix(s, "SIG")
+	if !processSignals[s] {
+		return "", fmt.Errorf("不支持的信号: %s", raw)
+	}
+	return s, nil
+}
+
+// loadContainerProcesses 获取容器进程列表并补充容器内 PID 与主进程标记
+func loadContainerProcesses(ctx context.Context, cli interface {
+	ContainerTop(context.Context, string, []string) (container.ContainerTopOKBody, error)
+}, info types.ContainerJSON) ([]containerProcess, error) {
+	top, err := cli.ContainerTop(ctx, info.ID, containerTopArgs)
+	if err != nil {
+		// 部分运行时（如 Windows 或精简版 ps）不支持自定义参数，退回默认输出
+		top, err = cli.ContainerTop(ctx, info.ID, nil)
+		if err != nil {
+			return nil, err
+		}
+	}
+	procs := parseContainerTop(top)
+	hostPidMode := info.HostConfig != nil && info.HostConfig.PidMode.IsHost()
+	root := hostProcRoot()
+	for i := range procs {
+		if info.State != nil && procs[i].PID == info.State.Pid {
+			procs[i].IsMain = true
+		}
+		if hostPidMode {
+			procs[i].NSPid = procs[i].PID
+		} else if ns, err := readNSPid(root, procs[i].PID); err == nil {
+			procs[i].NSPid = ns
+		}
+	}
+	return procs, nil
+}
+
+// listContainerProcesses 列出容器内进程（基于 ContainerTop，不依赖容器内的 ps）
+func listContainerProcesses(c *gin.Context) {
+	id := c.Param("id")
+	if forbidIfSelfContainer(c, id) {
+		return
+	}
+
+	cli, ok := getDockerClient(c)
+	if !ok {
+		return
+	}
+	defer cli.Close()
+
+	ctx := c.Request.Context()
+	info, err := cli.ContainerInspect(ctx, id)
+	if err != nil {
+		respondError(c, http.StatusNotFound, "容器不存在", err)
+		return
+	}
+	if info.State == nil || !info.State.Running {
+		respondError(c, http.StatusConflict, "容器未运行", nil)
+		return
+	}
+	procs, err := loadContainerProcesses(ctx, cli, info)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "获取容器进程失败", err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"processes": procs})
+}
+
+// streamContainerProcesses 以 SSE 定时推送容器进程列表（?interval=秒，1-30，默认 2）
+func streamContainerProcesses(c *gin.Context) {
+	id := c.Param("id")
+	if forbidIfSelfContainer(c, id) {
+		return
+	}
+
+	interval := 2 * time.Second
+	if raw := strings.TrimSpace(c.Query("interval")); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 1 || n > 30 {
+			respondError(c, http.StatusBadRequest, "interval 取值范围为 1-30 秒", err)
+			return
+		}
+		interval = time.Duration(n) * time.Second
+	}
+
+	cli, ok := getDockerClientSSE(c)
+	if !ok {
+		return
+	}
+	defer cli.Close()
+
+	setSSEHeaders(c)
+	nextID := sseNextIDFromLastEventID(c)
+	ctx := c.Request.Context()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		info, err := cli.ContainerInspect(ctx, id)
+		if err != nil {
+			sseWriteJSONEvent(c, nextID, "error", gin.H{"error": "获取容器信息失败"})
+			return
+		}
+		if info.State == nil || !info.State.Running {
+			sseWriteJSONEvent(c, nextID, "end", gin.H{"message": "容器已停止"})
+			return
+		}
+		procs, err := loadContainerProcesses(ctx, cli, info)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			sseWriteJSONEvent(c, nextID, "error", gin.H{"error": "获取容器进程失败"})
+			return
+		}
+		sseWriteJSONEvent(c, nextID, "", gin.H{"processes": procs, "time": time.Now().Unix()})
+		nextID++
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// ProcessSignalRequest 向容器内进程发送信号
+type ProcessSignalRequest struct {
+	Signal string `json:"signal"`
+}
+
+// signalContainerProcess 向指定进程发送信号：主进程走 ContainerKill，其余进程在容器内执行 kill
+func signalContainerProcess(c *gin.Context) {
+	id := c.Param("id")
+	if forbidIfSelfContainer(c, id) {
+		return
+	}
+	pid, err := strconv.Atoi(c.Param("pid"))
+	if err != nil || pid <= 0 {
+		respondError(c, http.StatusBadRequest, "无效的进程 PID", err)
+		return
+	}
+	var req ProcessSignalRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, "无效的请求参数", err)
+		return
+	}
+	sig, err := normalizeProcessSignal(req.Signal)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+
+	cli, ok := getDockerClient(c)
+	if !ok {
+		return
+	}
+	defer cli.Close()
+
+	ctx := context.Background()
+	info, err := cli.ContainerInspect(ctx, id)
+	if err != nil {
+		respondError(c, http.StatusNotFound, "容器不存在", err)
+		return
+	}
+	if info.State == nil || !info.State.Running {
+		respondError(c, http.StatusConflict, "容器未运行", nil)
+		return
+	}
+
+	if pid == info.State.Pid {
+		if err := cli.ContainerKill(ctx, id, sig); err != nil {
+			respondError(c, http.StatusInternalServerError, "发送信号失败", err)
+			return
+		}
+		log.Printf("已向容器主进程发送信号: container=%s pid=%d signal=%s", id, pid, sig)
+		c.JSON(http.StatusOK, gin.H{"message": "信号已发送", "pid": pid, "signal": sig, "method": "kill"})
+		return
+	}
+
+	procs, err := loadContainerProcesses(ctx, cli, info)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "获取容器进程失败", err)
+		return
+	}
+	var target *containerProcess
+	for i := range procs {
+		if procs[i].PID == pid {
+			target = &procs[i]
+			break
+		}
+	}
+	if target == nil {
+		respondError(c, http.StatusNotFound, "进程不存在或不属于该容器", nil)
+		return
+	}
+	if target.NSPid <= 0 {
+		respondError(c, http.StatusUnprocessableEntity, "无法确定进程在容器内的 PID，请为面板挂载宿主机 /proc 并设置 HOST_PROC", nil)
+		return
+	}
+
+	execResp, err := cli.ContainerExecCreate(ctx, id, types.ExecConfig{
+		Cmd:          []string{"kill", "-s", sig, strconv.Itoa(target.NSPid)},
+		AttachStdout: true,
+		AttachStderr: true,
+	})
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "创建 exec 失败", err)
+		return
+	}
+	attach, err := cli.ContainerExecAttach(ctx, execResp.ID, types.ExecStartCheck{})
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "执行 kill 失败，镜像中可能没有 kill 命令", err)
+		return
+	}
+	var out bytes.Buffer
+	_, _ = stdcopy.StdCopy(&out, &out, attach.Reader)
+	attach.Close()
+
+	inspect, err := cli.ContainerExecInspect(ctx, execResp.ID)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "获取 kill 执行结果失败", err)
+		return
+	}
+	if inspect.ExitCode != 0 {
+		respondError(c, http.StatusInternalServerError, "发送信号失败", fmt.Errorf("kill 退出码 %d: %s", inspect.ExitCode, strings.TrimSpace(out.String())))
+		return
+	}
+	log.Printf("已向容器进程发送信号: container=%s pid=%d nspid=%d signal=%s", id, pid, target.NSPid, sig)
+	c.JSON(http.StatusOK, gin.H{"message": "信号已发送", "pid": pid, "nsPid": target.NSPid, "signal": sig, "method": "exec"})
+}
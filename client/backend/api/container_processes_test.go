@@ -0,0 +1,82 @@
+package api
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/docker/docker/api/types/container"
+)
+
+func TestParseContainerTop(t *testing.T) {
+	procs := parseContainerTop(container.ContainerTopOKBody{
+		Titles: []string{"PID", "PPID", "USER", "%CPU", "%MEM", "RSS", "ELAPSED", "STAT", "COMMAND"},
+		Processes: [][]string{
+			{"4312", "4290", "101", "0.5", "1.2", "20480", "01:02:03", "S", "nginx: worker process"},
+			{"4290", "4270", "root", "0.0", "0.3", "4096", "01:02:05", "Ss", "nginx: master process nginx -g daemon off;"},
+			{"bad", "", "", "", "", "", "", "", ""},
+		},
+	})
+	if len(procs) != 2 {
+		t.Fatalf("procs = %+v", procs)
+	}
+	if procs[0].PID != 4290 || procs[0].User != "root" || procs[0].State != "Ss" {
+		t.Fatalf("processes should be sorted by pid: %+v", procs[0])
+	}
+	w := procs[1]
+	if w.PPID != 4290 || w.CPU != 0.5 || w.Memory != 1.2 || w.RSS != 20480*1024 || w.Command != "nginx: worker process" {
+		t.Fatalf("unexpected worker: %+v", w)
+	}
+
+	// Docker 默认的 ps -ef 输出
+	procs = parseContainerTop(container.ContainerTopOKBody{
+		Titles:    []string{"UID", "PID", "PPID", "C", "STIME", "TTY", "TIME", "CMD"},
+		Processes: [][]string{{"root", "77", "50", "3", "10:00", "?", "00:00:01", "/app/server"}},
+	})
+	if len(procs) != 1 || procs[0].User != "root" || procs[0].CPU != 3 || procs[0].Command != "/app/server" || procs[0].Elapsed != "00:00:01" {
+		t.Fatalf("ps -ef procs = %+v", procs)
+	}
+}
+
+func TestReadNSPid(t *testing.T) {
+	root := t.TempDir()
+	write := func(pid, body string) {
+		if err := os.MkdirAll(filepath.Join(root, pid), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(root, pid, "status"), []byte(body), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("4312", "Name:\tnginx\nPid:\t4312\nNSpid:\t4312\t7\n")
+	write("10", "Name:\told\nPid:\t10\n")
+	// 面板自身命名空间中的同号进程：NSpid 第一列与请求的宿主机 PID 不一致
+	write("4313", "Name:\tsh\nPid:\t4313\nNSpid:\t98113\t4313\n")
+
+	if ns, err := readNSPid(root, 4312); err != nil || ns != 7 {
+		t.Fatalf("readNSPid = %d, %v", ns, err)
+	}
+	if ns, err := readNSPid(root, 4313); err == nil || ns != 0 {
+		t.Fatalf("mismatched NSpid should fail, got %d", ns)
+	}
+	if _, err := readNSPid(root, 10); err == nil {
+		t.Fatalf("missing NSpid should fail")
+	}
+	if _, err := readNSPid(root, 99); err == nil {
+		t.Fatalf("missing process should fail")
+	}
+}
+
+func TestNormalizeProcessSignal(t *testing.T) {
+	cases := map[string]string{"": "TERM", "sigkill": "KILL", " HUP ": "HUP", "usr1": "USR1"}
+	for in, want := range cases {
+		if got, err := normalizeProcessSignal(in); err != nil || got != want {
+			t.Errorf("normalizeProcessSignal(%q) = %q, %v", in, got, err)
+		}
+	}
+	for _, bad := range []string{"9", "SEGV", "TERM; rm -rf /"} {
+		if _, err := normalizeProcessSignal(bad); err == nil {
+			t.Errorf("normalizeProcessSignal(%q) should fail", bad)
+		}
+	}
+}
@@ -0,0 +1,422 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"dockerpanel/backend/pkg/database"
+
+	"github.com/docker/docker/api/types"
+	"github.com/gin-gonic/gin"
+)
+
+// containerQueryTerm 查询语句中的一个条件，Values 之间为“或”，条件之间为“且”
+//
+// 语法示例：state:running project:media label:tier=web image:*nginx* health:unhealthy -name:test
+//   - key:v1,v2 表示任一值匹配即可；前缀 - 表示取反
+//   - name / image 不含通配符时按子串匹配，含 * ? 时按整体通配匹配
+//   - 不带 key 的词在名称、镜像、ID 中做子串匹配；值中含空格时可用双引号包裹
+type containerQueryTerm struct {
+	Key    string
+	Values []string
+	Negate bool
+}
+
+var containerQueryKeys = map[string]string{
+	"state": "state", "status": "state", "project": "project", "service": "service",
+	"label": "label", "image": "image", "name": "name", "health": "health",
+	"id": "id", "port": "port", "network": "network", "update": "update",
+}
+
+var containerSortKeys = map[string]bool{
+	"name": true, "created": true, "state": true, "image": true, "project": true, "status": true,
+}
+
+// tokenizeContainerQuery 按空白切分，双引号内的空白保留
+func tokenizeContainerQuery(q string) ([]string, error) {
+	var tokens []string
+	var cur strings.Builder
+	inQuote := false
+	hasToken := false
+	for _, r := range q {
+		switch {
+		case r == '"':
+			inQuote = !inQuote
+			hasToken = true
+		case !inQuote && (r == ' ' || r == '\t' || r == '\n'):
+			if hasToken {
+				tokens = append(tokens, cur.String())
+				cur.Reset()
+				hasToken = false
+			}
+		default:
+			cur.WriteRune(r)
+			hasToken = true
+		}
+	}
+	if inQuote {
+		return nil, fmt.Errorf("引号未闭合")
+	}
+	if hasToken {
+		tokens = append(tokens, cur.String())
+	}
+	return tokens, nil
+}
+
+// parseContainerQuery 解析容器列表查询语句
+func parseContainerQuery(q string) ([]containerQueryTerm, error) {
+	tokens, err := tokenizeContainerQuery(q)
+	if err != nil {
+		return nil, err
+	}
+	terms := make([]containerQueryTerm, 0, len(tokens))
+	for _, tok := range tokens {
+		term := containerQueryTerm{}
+		if strings.HasPrefix(tok, "-") && len(tok) > 1 {
+			term.Negate = true
+			tok = tok[1:]
+		}
+		key, value, hasKey := strings.Cut(tok, ":")
+		if !hasKey {
+			term.Values = []string{strings.ToLower(tok)}
+			terms = append(terms, term)
+			continue
+		}
+		canonical, ok := containerQueryKeys[strings.ToLower(strings.TrimSpace(key))]
+		if !ok {
+			return nil, fmt.Errorf("未知的筛选字段: %s", key)
+		}
+		term.Key = canonical
+		for _, v := range strings.Split(value, ",") {
+			if v = strings.TrimSpace(v); v != "" {
+				term.Values = append(term.Values, v)
+			}
+		}
+		if len(term.Values) == 0 {
+			return nil, fmt.Errorf("筛选字段 %s 缺少取值", key)
+		}
+		if term.Key == "port" {
+			for _, v := range term.Values {
+				if _, err := strconv.ParseUint(v, 10, 16); err != nil {
+					return nil, fmt.Errorf("端口取值不合法: %s", v)
+				}
+			}
+		}
+		terms = append(terms, term)
+	}
+	return terms, nil
+}
+
+// containerHealth 从 Status 文本中提取健康状态（healthy / unhealthy / starting / none），无需 inspect
+func containerHealth(status string) string {
+	switch {
+	case strings.Contains(status, "(unhealthy)"):
+		return "unhealthy"
+	case strings.Contains(status, "(healthy)"):
+		return "healthy"
+	case strings.Contains(status, "(health: starting)"):
+		return "starting"
+	}
+	return "none"
+}
+
+// queryGlobMatch 不区分大小写的通配匹配；不含通配符时 substring 决定是否按子串匹配
+func queryGlobMatch(pattern string, s string, substring bool) bool {
+	pattern = strings.ToLower(pattern)
+	s = strings.ToLower(s)
+	if !strings.ContainsAny(pattern, "*?") {
+		if substring {
+			return strings.Contains(s, pattern)
+		}
+		return s == pattern
+	}
+	expr := regexp.QuoteMeta(pattern)
+	expr = strings.ReplaceAll(expr, `\*`, ".*")
+	expr = strings.ReplaceAll(expr, `\?`, ".")
+	re, err := regexp.Compile("^" + expr + "$")
+	return err == nil && re.MatchString(s)
+}
+
+func containerDisplayName(ct types.Container) string {
+	if len(ct.Names) > 0 {
+		return strings.TrimPrefix(ct.Names[0], "/")
+	}
+	return ""
+}
+
+// matchContainerTerm 判断容器是否满足单个条件（不考虑取反）
+func matchContainerTerm(ct types.Container, term containerQueryTerm, updateMap map[string]bool) bool {
+	name := containerDisplayName(ct)
+	for _, v := range term.Values {
+		switch term.Key {
+		case "":
+			if queryGlobMatch(v, name, true) || queryGlobMatch(v, ct.Image, true) || (len(v) >= 4 && strings.HasPrefix(ct.ID, v)) {
+				return true
+			}
+		case "state":
+			if strings.EqualFold(ct.State, v) {
+				return true
+			}
+		case "project":
+			if queryGlobMatch(v, ct.Labels["com.docker.compose.project"], false) {
+				return true
+			}
+		case "service":
+			if queryGlobMatch(v, ct.Labels["com.docker.compose.service"], false) {
+				return true
+			}
+		case "label":
+			if matchesLabelSelectors(ct.Labels, []string{v}) {
+				return true
+			}
+		case "image":
+			if queryGlobMatch(v, ct.Image, true) {
+				return true
+			}
+		case "name":
+			if queryGlobMatch(v, name, true) {
+				return true
+			}
+		case "health":
+			if strings.EqualFold(containerHealth(ct.Status), v) {
+				return true
+			}
+		case "id":
+			if strings.HasPrefix(ct.ID, strings.ToLower(v)) {
+				return true
+			}
+		case "port":
+			port, _ := strconv.ParseUint(v, 10, 16)
+			for _, p := range ct.Ports {
+				if uint64(p.PublicPort) == port || uint64(p.PrivatePort) == port {
+					return true
+				}
+			}
+		case "network":
+			if ct.NetworkSettings != nil {
+				for n := range ct.NetworkSettings.Networks {
+					if queryGlobMatch(v, n, false) {
+						return true
+					}
+				}
+			}
+		case "update":
+			want := v == "true" || v == "yes" || v == "available"
+			if hasImageUpdate(updateMap, ct.Image) == want {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// filterContainers 返回满足全部条件的容器
+func filterContainers(list []types.Container, terms []containerQueryTerm, updateMap map[string]bool) []types.Container {
+	if len(terms) == 0 {
+		return list
+	}
+	out := make([]types.Container, 0, len(list))
+	for _, ct := range list {
+		ok := true
+		for _, term := range terms {
+			if matchContainerTerm(ct, term, updateMap) == term.Negate {
+				ok = false
+				break
+			}
+		}
+		if ok {
+			out = append(out, ct)
+		}
+	}
+	return out
+}
+
+// sortContainers 按字段排序，前缀 - 表示降序；名称作为次级排序保证结果稳定
+func sortContainers(list []types.Container, spec string) error {
+	spec = strings.TrimSpace(spec)
+	if spec == "" {
+		return nil
+	}
+	desc := strings.HasPrefix(spec, "-")
+	key := strings.ToLower(strings.TrimPrefix(spec, "-"))
+	if !containerSortKeys[key] {
+		return fmt.Errorf("不支持的排序字段: %s", key)
+	}
+	value := func(ct types.Container) string {
+		switch key {
+		case "state":
+			return ct.State
+		case "image":
+			return ct.Image
+		case "project":
+			return ct.Labels["com.docker.compose.project"]
+		case "status":
+			return containerHealth(ct.Status)
+		}
+		return containerDisplayName(ct)
+	}
+	sort.SliceStable(list, func(i, j int) bool {
+		a, b := list[i], list[j]
+		if key == "created" {
+			if a.Created != b.Created {
+				return (a.Created < b.Created) != desc
+			}
+		} else if va, vb := value(a), value(b); va != vb {
+			return (va < vb) != desc
+		}
+		return containerDisplayName(a) < containerDisplayName(b)
+	})
+	return nil
+}
+
+// paginateContainers 按页截取，pageSize 为 0 表示不分页
+func paginateContainers(list []types.Container, page int, pageSize int) []types.Container {
+	if pageSize <= 0 {
+		return list
+	}
+	if page < 1 {
+		page = 1
+	}
+	start := (page - 1) * pageSize
+	if start >= len(list) {
+		return []types.Container{}
+	}
+	end := start + pageSize
+	if end > len(list) {
+		end = len(list)
+	}
+	return list[start:end]
+}
+
+// containerListQuery 列表接口的筛选、排序与分页参数
+type containerListQuery struct {
+	Terms    []containerQueryTerm
+	Sort     string
+	Page     int
+	PageSize int
+}
+
+// parseContainerListQuery 读取 ?q=&filter=&sort=&page=&page_size=，filter 为已保存的筛选条件名称
+func parseContainerListQuery(c *gin.Context) (containerListQuery, bool) {
+	var lq containerListQuery
+	q := c.Query("q")
+	lq.Sort = c.Query("sort")
+	if name := strings.TrimSpace(c.Query("filter")); name != "" {
+		saved, err := database.GetContainerFilterByName(c.GetString("username"), name)
+		if err != nil {
+			respondError(c, http.StatusNotFound, "筛选条件不存在", err)
+			return lq, false
+		}
+		q = strings.TrimSpace(saved.Query + " " + q)
+		if lq.Sort == "" {
+			lq.Sort = saved.Sort
+		}
+	}
+
+	terms, err := parseContainerQuery(q)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, "查询语句不合法: "+err.Error(), nil)
+		return lq, false
+	}
+	lq.Terms = terms
+	if lq.Sort != "" && !containerSortKeys[strings.ToLower(strings.TrimPrefix(strings.TrimSpace(lq.Sort), "-"))] {
+		respondError(c, http.StatusBadRequest, "不支持的排序字段", nil)
+		return lq, false
+	}
+
+	if raw := strings.TrimSpace(c.Query("page_size")); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 1 || n > 500 {
+			respondError(c, http.StatusBadRequest, "page_size 取值范围为 1-500", err)
+			return lq, false
+		}
+		lq.PageSize = n
+	}
+	if raw := strings.TrimSpace(c.Query("page")); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 1 {
+			respondError(c, http.StatusBadRequest, "page 必须为正整数", err)
+			return lq, false
+		}
+		lq.Page = n
+		if lq.PageSize == 0 {
+			lq.PageSize = 50
+		}
+	} else if lq.PageSize > 0 {
+		lq.Page = 1
+	}
+	return lq, true
+}
+
+// ContainerFilterRequest 保存容器列表筛选条件
+type ContainerFilterRequest struct {
+	Name  string `json:"name"`
+	Query string `json:"query"`
+	Sort  string `json:"sort"`
+}
+
+// listContainerFilters 列出当前用户保存的筛选条件
+func listContainerFilters(c *gin.Context) {
+	list, err := database.ListContainerFilters(c.GetString("username"))
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "获取筛选条件失败", err)
+		return
+	}
+	c.JSON(http.StatusOK, list)
+}
+
+// saveContainerFilter 保存筛选条件，同名覆盖
+func saveContainerFilter(c *gin.Context) {
+	var req ContainerFilterRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, "无效的请求参数", err)
+		return
+	}
+	var errs fieldErrors
+	name := strings.TrimSpace(req.Name)
+	if name == "" || len(name) > 64 {
+		errs.add("name", "名称不能为空且不超过 64 个字符")
+	}
+	if strings.TrimSpace(req.Query) == "" {
+		errs.add("query", "查询语句不能为空")
+	} else if _, err := parseContainerQuery(req.Query); err != nil {
+		errs.add("query", "%v", err)
+	}
+	if req.Sort != "" && !containerSortKeys[strings.ToLower(strings.TrimPrefix(strings.TrimSpace(req.Sort), "-"))] {
+		errs.add("sort", "不支持的排序字段，可用 name / created / state / image / project / status")
+	}
+	if len(errs) > 0 {
+		respondFieldErrors(c, errs)
+		return
+	}
+
+	f := database.ContainerFilter{
+		Username: c.GetString("username"),
+		Name:     name,
+		Query:    strings.TrimSpace(req.Query),
+		Sort:     strings.TrimSpace(req.Sort),
+	}
+	if err := database.SaveContainerFilter(&f); err != nil {
+		respondError(c, http.StatusInternalServerError, "保存筛选条件失败", err)
+		return
+	}
+	c.JSON(http.StatusOK, f)
+}
+
+// deleteContainerFilter 删除筛选条件
+func deleteContainerFilter(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, "无效的筛选条件 ID", err)
+		return
+	}
+	if err := database.DeleteContainerFilter(c.GetString("username"), id); err != nil {
+		respondError(c, http.StatusNotFound, "筛选条件不存在", err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "筛选条件已删除"})
+}
@@ -0,0 +1,108 @@
+package api
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/network"
+)
+
+func fixtureContainerList() []types.Container {
+	return []types.Container{
+		{
+			ID: strings.Repeat("a", 64), Names: []string{"/media-jellyfin-1"}, Image: "jellyfin/jellyfin:latest", State: "running",
+			Status: "Up 3 hours (healthy)", Created: 300,
+			Labels:          map[string]string{"com.docker.compose.project": "media", "com.docker.compose.service": "jellyfin", "tier": "web"},
+			Ports:           []types.Port{{PrivatePort: 8096, PublicPort: 8096, Type: "tcp"}},
+			NetworkSettings: &types.SummaryNetworkSettings{Networks: map[string]*network.EndpointSettings{"media_default": {}}},
+		},
+		{
+			ID: strings.Repeat("b", 64), Names: []string{"/proxy"}, Image: "nginx:1.25", State: "running",
+			Status: "Up 2 days (unhealthy)", Created: 100, Labels: map[string]string{"tier": "web"},
+		},
+		{
+			ID: strings.Repeat("c", 64), Names: []string{"/old-test"}, Image: "bitnami/nginx", State: "exited",
+			Status: "Exited (0) 5 days ago", Created: 200,
+		},
+	}
+}
+
+func containerNames(list []types.Container) string {
+	names := make([]string, 0, len(list))
+	for _, ct := range list {
+		names = append(names, containerDisplayName(ct))
+	}
+	return strings.Join(names, ",")
+}
+
+func TestParseContainerQuery(t *testing.T) {
+	terms, err := parseContainerQuery(`state:running,paused -label:tier=web "old test" Status:exited`)
+	if err != nil {
+		t.Fatalf("parseContainerQuery: %v", err)
+	}
+	if len(terms) != 4 {
+		t.Fatalf("terms = %+v", terms)
+	}
+	if terms[0].Key != "state" || len(terms[0].Values) != 2 || terms[1].Key != "label" || !terms[1].Negate {
+		t.Fatalf("unexpected terms: %+v", terms[:2])
+	}
+	if terms[2].Key != "" || terms[2].Values[0] != "old test" || terms[3].Key != "state" {
+		t.Fatalf("unexpected terms: %+v", terms[2:])
+	}
+
+	for _, bad := range []string{"color:red", "state:", `name:"abc`, "port:http"} {
+		if _, err := parseContainerQuery(bad); err == nil {
+			t.Errorf("parseContainerQuery(%q) should fail", bad)
+		}
+	}
+}
+
+func TestFilterContainers(t *testing.T) {
+	cases := map[string]string{
+		"":                             "media-jellyfin-1,proxy,old-test",
+		"state:running":                "media-jellyfin-1,proxy",
+		"project:media label:tier=web": "media-jellyfin-1",
+		"image:*nginx*":                "proxy,old-test",
+		"image:nginx*":                 "proxy",
+		"health:unhealthy":             "proxy",
+		"health:none":                  "old-test",
+		"-state:exited port:8096":      "media-jellyfin-1",
+		"network:media_*":              "media-jellyfin-1",
+		"OLD":                          "old-test",
+		"id:bbbb":                      "proxy",
+	}
+	for q, want := range cases {
+		terms, err := parseContainerQuery(q)
+		if err != nil {
+			t.Fatalf("parseContainerQuery(%q): %v", q, err)
+		}
+		if got := containerNames(filterContainers(fixtureContainerList(), terms, nil)); got != want {
+			t.Errorf("filter %q = %s, want %s", q, got, want)
+		}
+	}
+}
+
+func TestSortAndPaginateContainers(t *testing.T) {
+	list := fixtureContainerList()
+	if err := sortContainers(list, "-created"); err != nil {
+		t.Fatal(err)
+	}
+	if got := containerNames(list); got != "media-jellyfin-1,old-test,proxy" {
+		t.Fatalf("-created = %s", got)
+	}
+	_ = sortContainers(list, "state")
+	if got := containerNames(list); got != "old-test,media-jellyfin-1,proxy" {
+		t.Fatalf("state = %s", got)
+	}
+	if err := sortContainers(list, "size"); err == nil {
+		t.Fatalf("unknown sort key should fail")
+	}
+
+	if got := containerNames(paginateContainers(list, 2, 2)); got != "proxy" {
+		t.Fatalf("page 2 = %s", got)
+	}
+	if got := paginateContainers(list, 5, 2); len(got) != 0 {
+		t.Fatalf("page past end = %v", got)
+	}
+}
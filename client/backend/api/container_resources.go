@@ -0,0 +1,357 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/gin-gonic/gin"
+	"gopkg.in/yaml.v3"
+)
+
+// ContainerResourceUpdateRequest 在线调整容器资源限制与重启策略，未提供的字段保持不变
+type ContainerResourceUpdateRequest struct {
+	CPUShares         *int64   `json:"cpu_shares"`
+	CPUs              *float64 `json:"cpus"`       // 等价于 --cpus，与 cpu_quota 互斥
+	CPUQuota          *int64   `json:"cpu_quota"`  // 微秒
+	CPUPeriod         *int64   `json:"cpu_period"` // 微秒
+	Memory            *string  `json:"memory"`     // 例如 "512m"
+	MemoryReservation *string  `json:"memory_reservation"`
+	MemorySwap        *string  `json:"memory_swap"` // "-1" 表示不限制
+	PidsLimit         *int64   `json:"pids_limit"`  // -1 表示不限制
+	RestartPolicy     *string  `json:"restart_policy"`
+	RestartMaxRetries *int     `json:"restart_max_retries"`
+
+	// WriteCompose 对 compose 管理的容器，同时写回项目 compose 文件，保证下次 up 时仍然生效
+	WriteCompose bool `json:"write_compose"`
+}
+
+// buildContainerUpdateConfig 校验并转换为 ContainerUpdate 参数
+func buildContainerUpdateConfig(req *ContainerResourceUpdateRequest) (container.UpdateConfig, fieldErrors) {
+	var errs fieldErrors
+	var cfg container.UpdateConfig
+	changed := false
+
+	if req.CPUShares != nil {
+		changed = true
+		if *req.CPUShares <= 0 {
+			errs.add("cpu_shares", "CPU 权重必须大于 0（0 不会修改当前设置）")
+		}
+		cfg.CPUShares = *req.CPUShares
+	}
+	if req.CPUs != nil {
+		changed = true
+		if *req.CPUs <= 0 {
+			errs.add("cpus", "CPU 数量必须大于 0（0 不会修改当前限制）")
+		}
+		if req.CPUQuota != nil {
+			errs.add("cpus", "cpus 与 cpu_quota 不能同时设置")
+		}
+		cfg.NanoCPUs = int64(*req.CPUs * 1e9)
+	}
+	if req.CPUQuota != nil {
+		changed = true
+		if *req.CPUQuota != -1 && *req.CPUQuota < 1000 {
+			errs.add("cpu_quota", "CPU 配额不能小于 1000 微秒，-1 表示不限制")
+		}
+		cfg.CPUQuota = *req.CPUQuota
+	}
+	if req.CPUPeriod != nil {
+		changed = true
+		if *req.CPUPeriod < 1000 || *req.CPUPeriod > 1000000 {
+			errs.add("cpu_period", "CPU 周期应在 1000~1000000 微秒之间")
+		}
+		cfg.CPUPeriod = *req.CPUPeriod
+	}
+	if req.Memory != nil {
+		changed = true
+		cfg.Memory = parseByteSizeField(&errs, "memory", *req.Memory, false)
+		if cfg.Memory == 0 {
+			errs.add("memory", "内存限制必须大于 0（0 不会修改当前限制）")
+		} else if cfg.Memory < 6*1024*1024 {
+			errs.add("memory", "内存限制不能小于 6MB")
+		}
+	}
+	if req.MemoryReservation != nil {
+		changed = true
+		cfg.MemoryReservation = parseByteSizeField(&errs, "memory_reservation", *req.MemoryReservation, false)
+		if cfg.MemoryReservation == 0 {
+			errs.add("memory_reservation", "软限制必须大于 0（0 不会修改当前设置）")
+		} else if cfg.Memory > 0 && cfg.MemoryReservation > cfg.Memory {
+			errs.add("memory_reservation", "软限制不能大于内存限制")
+		}
+	}
+	if req.MemorySwap != nil {
+		changed = true
+		cfg.MemorySwap = parseByteSizeField(&errs, "memory_swap", *req.MemorySwap, true)
+		if cfg.MemorySwap == 0 {
+			errs.add("memory_swap", "swap 总量必须大于 0，-1 表示不限制（0 不会修改当前设置）")
+		} else if cfg.MemorySwap > 0 && cfg.Memory > 0 && cfg.MemorySwap < cfg.Memory {
+			errs.add("memory_swap", "swap 总量不能小于内存限制")
+		}
+	}
+	if req.PidsLimit != nil {
+		changed = true
+		if *req.PidsLimit == 0 || *req.PidsLimit < -1 {
+			errs.add("pids_limit", "进程数限制应为正数，-1 表示不限制")
+		}
+		v := *req.PidsLimit
+		cfg.PidsLimit = &v
+	}
+	if req.RestartPolicy != nil || req.RestartMaxRetries != nil {
+		changed = true
+		name := ""
+		if req.RestartPolicy != nil {
+			name = *req.RestartPolicy
+		}
+		if !validRestartPolicies[name] || name == "" {
+			errs.add("restart_policy", "重启策略仅支持 no / always / unless-stopped / on-failure")
+		}
+		retries := 0
+		if req.RestartMaxRetries != nil {
+			retries = *req.RestartMaxRetries
+			if retries < 0 {
+				errs.add("restart_max_retries", "重试次数不能为负数")
+			} else if retries > 0 && name != "on-failure" {
+				errs.add("restart_max_retries", "仅 on-failure 策略支持最大重试次数")
+			}
+		}
+		cfg.RestartPolicy = container.RestartPolicy{Name: name, MaximumRetryCount: retries}
+	}
+
+	if !changed {
+		errs.add("", "没有需要更新的字段")
+	}
+	return cfg, errs
+}
+
+// updateContainerResources 使用 ContainerUpdate 在线调整运行中容器的资源限制与重启策略
+func updateContainerResources(c *gin.Context) {
+	id := c.Param("id")
+	if forbidIfSelfContainer(c, id) {
+		return
+	}
+	var req ContainerResourceUpdateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, "无效的请求参数", err)
+		return
+	}
+	cfg, errs := buildContainerUpdateConfig(&req)
+	if len(errs) > 0 {
+		respondFieldErrors(c, errs)
+		return
+	}
+
+	cli, ok := getDockerClient(c)
+	if !ok {
+		return
+	}
+	defer cli.Close()
+
+	ctx := context.Background()
+	inspect, err := cli.ContainerInspect(ctx, id)
+	if err != nil {
+		respondError(c, http.StatusNotFound, "容器不存在", err)
+		return
+	}
+
+	resp, err := cli.ContainerUpdate(ctx, inspect.ID, cfg)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "更新容器资源限制失败", err)
+		return
+	}
+
+	result := gin.H{"message": "容器资源限制已更新", "warnings": resp.Warnings}
+	if req.WriteCompose {
+		result["compose"] = writeResourceUpdateToCompose(inspect.Config.Labels, &req)
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+// writeResourceUpdateToCompose 将变更写回容器所属 compose 项目，返回写回结果（不影响已生效的在线更新）
+func writeResourceUpdateToCompose(labels map[string]string, req *ContainerResourceUpdateRequest) gin.H {
+	project := strings.TrimSpace(labels["com.docker.compose.project"])
+	service := strings.TrimSpace(labels["com.docker.compose.service"])
+	if project == "" || service == "" {
+		return gin.H{"updated": false, "error": "该容器不是由 compose 创建的"}
+	}
+	if isSelfProjectName(project) {
+		return gin.H{"updated": false, "error": "禁止修改自身项目"}
+	}
+	projectDir := filepath.Join(getProjectsBaseDir(), project)
+	composePath, err := findComposeFile(projectDir)
+	if err != nil {
+		return gin.H{"updated": false, "error": "未在项目目录中找到 compose 文件"}
+	}
+	content, err := os.ReadFile(composePath)
+	if err != nil {
+		return gin.H{"updated": false, "error": err.Error()}
+	}
+	out, keys, err := applyResourceUpdateToCompose(content, service, req)
+	if err != nil {
+		return gin.H{"updated": false, "error": err.Error()}
+	}
+	if err := os.WriteFile(composePath, out, 0644); err != nil {
+		return gin.H{"updated": false, "error": err.Error()}
+	}
+	log.Printf("容器资源变更已写回 compose: project=%s service=%s keys=%v", project, service, keys)
+	return gin.H{"updated": true, "file": filepath.Base(composePath), "service": service, "keys": keys}
+}
+
+// applyResourceUpdateToCompose 在 yaml.Node 上修改指定服务，保留原有注释与字段顺序。
+// 服务已使用 deploy.resources / deploy.restart_policy 时改写对应位置，避免与顶层字段冲突。
+func applyResourceUpdateToCompose(content []byte, service string, req *ContainerResourceUpdateRequest) ([]byte, []string, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(content, &doc); err != nil {
+		return nil, nil, fmt.Errorf("解析 compose 文件失败: %v", err)
+	}
+	if len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return nil, nil, fmt.Errorf("compose 文件格式不正确")
+	}
+	svc := mappingGetValue(mappingGetValue(doc.Content[0], "services"), service)
+	if svc == nil || svc.Kind != yaml.MappingNode {
+		return nil, nil, fmt.Errorf("compose 文件中未找到服务 %s", service)
+	}
+
+	keys := make([]string, 0)
+	set := func(node *yaml.Node, path string, key string, value string, tag string) {
+		mappingSetScalar(node, key, value, tag)
+		keys = append(keys, path)
+	}
+
+	deploy := mappingGetValue(svc, "deploy")
+	resources := mappingGetValue(deploy, "resources")
+	limits := mappingGetValue(resources, "limits")
+	reservations := mappingGetValue(resources, "reservations")
+
+	if req.CPUs != nil {
+		v := strconv.FormatFloat(*req.CPUs, 'f', -1, 64)
+		if limits != nil && limits.Kind == yaml.MappingNode {
+			set(limits, "deploy.resources.limits.cpus", "cpus", v, "!!str")
+		} else {
+			tag := "!!float"
+			if !strings.Contains(v, ".") {
+				tag = "!!int"
+			}
+			set(svc, "cpus", "cpus", v, tag)
+		}
+	}
+	if req.CPUShares != nil {
+		set(svc, "cpu_shares", "cpu_shares", strconv.FormatInt(*req.CPUShares, 10), "!!int")
+	}
+	if req.CPUQuota != nil {
+		set(svc, "cpu_quota", "cpu_quota", strconv.FormatInt(*req.CPUQuota, 10), "!!int")
+	}
+	if req.CPUPeriod != nil {
+		set(svc, "cpu_period", "cpu_period", strconv.FormatInt(*req.CPUPeriod, 10), "!!int")
+	}
+	if req.Memory != nil {
+		v := strings.ToLower(strings.TrimSpace(*req.Memory))
+		if limits != nil && limits.Kind == yaml.MappingNode {
+			set(limits, "deploy.resources.limits.memory", "memory", v, "!!str")
+		} else {
+			set(svc, "mem_limit", "mem_limit", v, "!!str")
+		}
+	}
+	if req.MemoryReservation != nil {
+		v := strings.ToLower(strings.TrimSpace(*req.MemoryReservation))
+		if reservations != nil && reservations.Kind == yaml.MappingNode {
+			set(reservations, "deploy.resources.reservations.memory", "memory", v, "!!str")
+		} else {
+			set(svc, "mem_reservation", "mem_reservation", v, "!!str")
+		}
+	}
+	if req.MemorySwap != nil {
+		v := strings.ToLower(strings.TrimSpace(*req.MemorySwap))
+		// compose 以 RAMInBytes 解析字符串大小，不接受负数；-1 需写成整数
+		tag := "!!str"
+		if v == "-1" {
+			tag = "!!int"
+		}
+		set(svc, "memswap_limit", "memswap_limit", v, tag)
+	}
+	if req.PidsLimit != nil {
+		v := strconv.FormatInt(*req.PidsLimit, 10)
+		if limits != nil && limits.Kind == yaml.MappingNode && mappingGetValue(limits, "pids") != nil {
+			set(limits, "deploy.resources.limits.pids", "pids", v, "!!int")
+		} else {
+			set(svc, "pids_limit", "pids_limit", v, "!!int")
+		}
+	}
+	if req.RestartPolicy != nil {
+		name := *req.RestartPolicy
+		retries := 0
+		if req.RestartMaxRetries != nil {
+			retries = *req.RestartMaxRetries
+		}
+		if rp := mappingGetValue(deploy, "restart_policy"); rp != nil && rp.Kind == yaml.MappingNode {
+			condition := "any"
+			switch name {
+			case "no":
+				condition = "none"
+			case "on-failure":
+				condition = "on-failure"
+			}
+			set(rp, "deploy.restart_policy.condition", "condition", condition, "!!str")
+			if retries > 0 {
+				set(rp, "deploy.restart_policy.max_attempts", "max_attempts", strconv.Itoa(retries), "!!int")
+			}
+		} else {
+			v := name
+			if name == "on-failure" && retries > 0 {
+				v = fmt.Sprintf("on-failure:%d", retries)
+			}
+			set(svc, "restart", "restart", v, "!!str")
+		}
+	}
+
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(&doc); err != nil {
+		_ = enc.Close()
+		return nil, nil, err
+	}
+	_ = enc.Close()
+	return buf.Bytes(), keys, nil
+}
+
+// mappingSetScalar 设置映射节点中的标量值，键不存在时追加到末尾
+func mappingSetScalar(node *yaml.Node, key string, value string, tag string) {
+	var style yaml.Style
+	if tag == "!!str" && yamlAmbiguousString(value) {
+		style = yaml.DoubleQuotedStyle
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Kind == yaml.ScalarNode && node.Content[i].Value == key {
+			v := node.Content[i+1]
+			v.Kind = yaml.ScalarNode
+			v.Tag = tag
+			v.Value = value
+			v.Style = style
+			v.Content = nil
+			return
+		}
+	}
+	node.Content = append(node.Content,
+		&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key},
+		&yaml.Node{Kind: yaml.ScalarNode, Tag: tag, Value: value, Style: style},
+	)
+}
+
+// yamlAmbiguousString 判断字符串不加引号时是否可能被解析为布尔值/数字（兼容 YAML 1.1 的 yes/no/on/off）
+func yamlAmbiguousString(value string) bool {
+	switch strings.ToLower(value) {
+	case "", "y", "n", "yes", "no", "on", "off", "true", "false", "null", "~":
+		return true
+	}
+	_, err := strconv.ParseFloat(value, 64)
+	return err == nil
+}
@@ -0,0 +1,152 @@
+package api
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/compose-spec/compose-go/v2/loader"
+	composetypes "github.com/compose-spec/compose-go/v2/types"
+)
+
+func TestBuildContainerUpdateConfig(t *testing.T) {
+	cpus := 1.5
+	mem := "512m"
+	swap := "-1"
+	pids := int64(100)
+	policy := "on-failure"
+	retries := 5
+	cfg, errs := buildContainerUpdateConfig(&ContainerResourceUpdateRequest{
+		CPUs: &cpus, Memory: &mem, MemorySwap: &swap, PidsLimit: &pids,
+		RestartPolicy: &policy, RestartMaxRetries: &retries,
+	})
+	if len(errs) > 0 {
+		t.Fatalf("unexpected field errors: %+v", errs)
+	}
+	if cfg.NanoCPUs != 1500000000 || cfg.Memory != 512*1024*1024 || cfg.MemorySwap != -1 || *cfg.PidsLimit != 100 {
+		t.Fatalf("unexpected resources: %+v", cfg.Resources)
+	}
+	if cfg.RestartPolicy.Name != "on-failure" || cfg.RestartPolicy.MaximumRetryCount != 5 {
+		t.Fatalf("unexpected restart policy: %+v", cfg.RestartPolicy)
+	}
+
+	if _, errs := buildContainerUpdateConfig(&ContainerResourceUpdateRequest{}); len(errs) == 0 {
+		t.Fatalf("empty request should be rejected")
+	}
+
+	quota := int64(10)
+	small := "1m"
+	zero := int64(0)
+	always := "always"
+	_, errs = buildContainerUpdateConfig(&ContainerResourceUpdateRequest{
+		CPUs: &cpus, CPUQuota: &quota, Memory: &small, PidsLimit: &zero,
+		RestartPolicy: &always, RestartMaxRetries: &retries,
+	})
+	got := make(map[string]bool)
+	for _, e := range errs {
+		got[e.Field] = true
+	}
+	for _, f := range []string{"cpus", "cpu_quota", "memory", "pids_limit", "restart_max_retries"} {
+		if !got[f] {
+			t.Errorf("missing field error for %s (got %+v)", f, errs)
+		}
+	}
+
+	// ContainerUpdate 视 0 为不修改，需拒绝以免写回 compose 后与容器不一致
+	zeroCPUs := 0.0
+	zeroMem := "0"
+	_, errs = buildContainerUpdateConfig(&ContainerResourceUpdateRequest{
+		CPUs: &zeroCPUs, CPUShares: &zero, CPUPeriod: &zero, Memory: &zeroMem, MemoryReservation: &zeroMem, MemorySwap: &zeroMem,
+	})
+	got = make(map[string]bool)
+	for _, e := range errs {
+		got[e.Field] = true
+	}
+	for _, f := range []string{"cpus", "cpu_shares", "cpu_period", "memory", "memory_reservation", "memory_swap"} {
+		if !got[f] {
+			t.Errorf("missing zero-value error for %s (got %+v)", f, errs)
+		}
+	}
+}
+
+func TestApplyResourceUpdateToCompose(t *testing.T) {
+	content := []byte(`services:
+  web:
+    image: nginx
+    # 内存限制
+    mem_limit: 256m
+  worker:
+    image: busybox
+    deploy:
+      resources:
+        limits:
+          cpus: "0.5"
+      restart_policy:
+        condition: any
+`)
+	cpus := 2.0
+	mem := "1G"
+	no := "no"
+	out, keys, err := applyResourceUpdateToCompose(content, "web", &ContainerResourceUpdateRequest{
+		CPUs: &cpus, Memory: &mem, RestartPolicy: &no,
+	})
+	if err != nil {
+		t.Fatalf("applyResourceUpdateToCompose: %v", err)
+	}
+	if !reflect.DeepEqual(keys, []string{"cpus", "mem_limit", "restart"}) {
+		t.Fatalf("keys = %q", keys)
+	}
+	s := string(out)
+	for _, want := range []string{"# 内存限制", "mem_limit: 1g", "cpus: 2", `restart: "no"`} {
+		if !strings.Contains(s, want) {
+			t.Errorf("output missing %q:\n%s", want, s)
+		}
+	}
+
+	policy := "on-failure"
+	retries := 3
+	out, keys, err = applyResourceUpdateToCompose(content, "worker", &ContainerResourceUpdateRequest{
+		CPUs: &cpus, Memory: &mem, RestartPolicy: &policy, RestartMaxRetries: &retries,
+	})
+	if err != nil {
+		t.Fatalf("applyResourceUpdateToCompose: %v", err)
+	}
+	want := []string{
+		"deploy.resources.limits.cpus", "deploy.resources.limits.memory",
+		"deploy.restart_policy.condition", "deploy.restart_policy.max_attempts",
+	}
+	if !reflect.DeepEqual(keys, want) {
+		t.Fatalf("keys = %q", keys)
+	}
+	s = string(out)
+	if strings.Contains(s, "mem_limit: 1g\n    deploy") || !strings.Contains(s, "condition: on-failure") || !strings.Contains(s, "max_attempts: 3") {
+		t.Errorf("unexpected output:\n%s", s)
+	}
+
+	swap := "-1"
+	out, _, err = applyResourceUpdateToCompose(content, "web", &ContainerResourceUpdateRequest{Memory: &mem, MemorySwap: &swap})
+	if err != nil {
+		t.Fatalf("applyResourceUpdateToCompose: %v", err)
+	}
+	project, err := loader.LoadWithContext(context.Background(), composetypes.ConfigDetails{
+		WorkingDir:  t.TempDir(),
+		ConfigFiles: []composetypes.ConfigFile{{Filename: "docker-compose.yml", Content: out}},
+	}, func(o *loader.Options) {
+		o.SetProjectName("demo", true)
+	})
+	if err != nil {
+		t.Fatalf("compose failed to load written file: %v\n%s", err, out)
+	}
+	web, err := project.GetService("web")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if web.MemSwapLimit != -1 || web.MemLimit != 1024*1024*1024 {
+		t.Fatalf("memswap_limit=%d mem_limit=%d", web.MemSwapLimit, web.MemLimit)
+	}
+
+	if _, _, err := applyResourceUpdateToCompose(content, "missing", &ContainerResourceUpdateRequest{CPUs: &cpus}); err == nil {
+		t.Fatalf("expected error for missing service")
+	}
+}
@@ -0,0 +1,171 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/docker/distribution/reference"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/gin-gonic/gin"
+)
+
+// containerDiffItem 容器可写层中的一项文件变更
+type containerDiffItem struct {
+	Path string `json:"path"`
+	Kind string `json:"kind"` // added / modified / deleted
+}
+
+var containerChangeKinds = map[container.ChangeType]string{
+	container.ChangeAdd:    "added",
+	container.ChangeModify: "modified",
+	container.ChangeDelete: "deleted",
+}
+
+// summarizeContainerDiff 按类型与路径前缀过滤变更，返回排序后的列表及各类型数量（数量不受过滤影响）
+func summarizeContainerDiff(changes []container.FilesystemChange, kind string, prefix string) ([]containerDiffItem, map[string]int) {
+	summary := map[string]int{"added": 0, "modified": 0, "deleted": 0}
+	prefix = strings.TrimSpace(prefix)
+	if prefix != "" {
+		prefix = path.Clean("/" + prefix)
+	}
+
+	items := make([]containerDiffItem, 0, len(changes))
+	for _, ch := range changes {
+		k := containerChangeKinds[ch.Kind]
+		summary[k]++
+		if kind != "" && k != kind {
+			continue
+		}
+		if prefix != "" && prefix != "/" && ch.Path != prefix && !strings.HasPrefix(ch.Path, prefix+"/") {
+			continue
+		}
+		items = append(items, containerDiffItem{Path: ch.Path, Kind: k})
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].Path < items[j].Path })
+	return items, summary
+}
+
+// getContainerDiff 列出容器相对镜像的文件系统变更（?kind=added|modified|deleted&prefix=/etc）
+func getContainerDiff(c *gin.Context) {
+	kind := strings.TrimSpace(c.Query("kind"))
+	if kind != "" && kind != "added" && kind != "modified" && kind != "deleted" {
+		respondError(c, http.StatusBadRequest, "kind 仅支持 added / modified / deleted", nil)
+		return
+	}
+
+	cli, ok := getDockerClient(c)
+	if !ok {
+		return
+	}
+	defer cli.Close()
+
+	changes, err := cli.ContainerDiff(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "获取容器文件变更失败", err)
+		return
+	}
+	items, summary := summarizeContainerDiff(changes, kind, c.Query("prefix"))
+	c.JSON(http.StatusOK, gin.H{
+		"changes": items,
+		"summary": summary,
+		"total":   len(changes),
+	})
+}
+
+// CommitContainerRequest 将容器提交为新镜像
+type CommitContainerRequest struct {
+	Repo    string   `json:"repo"`
+	Tag     string   `json:"tag"`
+	Message string   `json:"message"`
+	Author  string   `json:"author"`
+	Changes []string `json:"changes"` // Dockerfile 指令，例如 "ENV DEBUG=1"、"CMD [\"sh\"]"
+	Pause   *bool    `json:"pause"`   // 提交期间暂停容器，默认开启
+}
+
+// commitChangeInstructions docker commit --change 支持的指令
+var commitChangeInstructions = map[string]bool{
+	"CMD": true, "ENTRYPOINT": true, "ENV": true, "EXPOSE": true, "LABEL": true,
+	"ONBUILD": true, "USER": true, "VOLUME": true, "WORKDIR": true, "STOPSIGNAL": true, "HEALTHCHECK": true,
+}
+
+// buildCommitOptions 校验提交参数并返回规范化的镜像引用
+func buildCommitOptions(req *CommitContainerRequest) (types.ContainerCommitOptions, fieldErrors) {
+	var errs fieldErrors
+	opts := types.ContainerCommitOptions{
+		Comment: strings.TrimSpace(req.Message),
+		Author:  strings.TrimSpace(req.Author),
+		Pause:   req.Pause == nil || *req.Pause,
+	}
+
+	repo := strings.TrimSpace(req.Repo)
+	tag := strings.TrimSpace(req.Tag)
+	if repo == "" {
+		errs.add("repo", "镜像仓库名不能为空")
+	} else {
+		ref := repo
+		if tag != "" {
+			ref += ":" + tag
+		}
+		named, err := reference.ParseNormalizedNamed(ref)
+		if err != nil {
+			errs.add("repo", "镜像名称不合法: %v", err)
+		} else if _, ok := named.(reference.Digested); ok {
+			errs.add("repo", "提交的镜像不能指定 digest")
+		} else {
+			opts.Reference = reference.FamiliarString(reference.TagNameOnly(named))
+		}
+	}
+
+	for i, ch := range req.Changes {
+		ch = strings.TrimSpace(ch)
+		instr, _, _ := strings.Cut(ch, " ")
+		if ch == "" || !commitChangeInstructions[strings.ToUpper(instr)] {
+			errs.add(fmt.Sprintf("changes[%d]", i), "不支持的指令，可用 CMD / ENTRYPOINT / ENV / EXPOSE / LABEL / USER / VOLUME / WORKDIR 等")
+			continue
+		}
+		opts.Changes = append(opts.Changes, ch)
+	}
+	return opts, errs
+}
+
+// commitContainer 将容器当前文件系统提交为带标签的新镜像
+func commitContainer(c *gin.Context) {
+	id := c.Param("id")
+	if forbidIfSelfContainer(c, id) {
+		return
+	}
+	var req CommitContainerRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, "无效的请求参数", err)
+		return
+	}
+	opts, errs := buildCommitOptions(&req)
+	if len(errs) > 0 {
+		respondFieldErrors(c, errs)
+		return
+	}
+
+	cli, ok := getDockerClient(c)
+	if !ok {
+		return
+	}
+	defer cli.Close()
+
+	resp, err := cli.ContainerCommit(context.Background(), id, opts)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "提交镜像失败", err)
+		return
+	}
+	log.Printf("容器已提交为镜像: container=%s image=%s id=%s", id, opts.Reference, resp.ID)
+	c.JSON(http.StatusOK, gin.H{
+		"message": "镜像提交成功",
+		"id":      resp.ID,
+		"image":   opts.Reference,
+	})
+}
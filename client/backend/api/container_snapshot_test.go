@@ -0,0 +1,62 @@
+package api
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/docker/docker/api/types/container"
+)
+
+func TestSummarizeContainerDiff(t *testing.T) {
+	changes := []container.FilesystemChange{
+		{Kind: container.ChangeModify, Path: "/etc"},
+		{Kind: container.ChangeAdd, Path: "/etc/nginx/conf.d/app.conf"},
+		{Kind: container.ChangeDelete, Path: "/etc/motd"},
+		{Kind: container.ChangeAdd, Path: "/etcd-data"},
+		{Kind: container.ChangeAdd, Path: "/tmp/x"},
+	}
+	items, summary := summarizeContainerDiff(changes, "", "etc/")
+	want := []containerDiffItem{
+		{Path: "/etc", Kind: "modified"},
+		{Path: "/etc/motd", Kind: "deleted"},
+		{Path: "/etc/nginx/conf.d/app.conf", Kind: "added"},
+	}
+	if !reflect.DeepEqual(items, want) {
+		t.Fatalf("items = %+v", items)
+	}
+	if !reflect.DeepEqual(summary, map[string]int{"added": 3, "modified": 1, "deleted": 1}) {
+		t.Fatalf("summary = %v", summary)
+	}
+	if items, _ := summarizeContainerDiff(changes, "added", ""); len(items) != 3 || items[0].Path != "/etc/nginx/conf.d/app.conf" {
+		t.Fatalf("added items = %+v", items)
+	}
+}
+
+func TestBuildCommitOptions(t *testing.T) {
+	opts, errs := buildCommitOptions(&CommitContainerRequest{
+		Repo:    "debug/web",
+		Message: " broken state ",
+		Changes: []string{"ENV DEBUG=1", `cmd ["sh"]`},
+	})
+	if len(errs) > 0 {
+		t.Fatalf("unexpected field errors: %+v", errs)
+	}
+	if opts.Reference != "debug/web:latest" || opts.Comment != "broken state" || !opts.Pause || len(opts.Changes) != 2 {
+		t.Fatalf("unexpected options: %+v", opts)
+	}
+
+	noPause := false
+	opts, _ = buildCommitOptions(&CommitContainerRequest{Repo: "registry.local:5000/team/app", Tag: "snap-1", Pause: &noPause})
+	if opts.Reference != "registry.local:5000/team/app:snap-1" || opts.Pause {
+		t.Fatalf("unexpected options: %+v", opts)
+	}
+
+	_, errs = buildCommitOptions(&CommitContainerRequest{Repo: "Bad Repo", Changes: []string{"RUN rm -rf /"}})
+	got := make(map[string]bool)
+	for _, e := range errs {
+		got[e.Field] = true
+	}
+	if !got["repo"] || !got["changes[0]"] {
+		t.Fatalf("field errors = %+v", errs)
+	}
+}
@@ -80,6 +80,15 @@ func RegisterImageRoutes(r *gin.RouterGroup) {
 		group.POST("/tag", tagImage)
 		group.GET("/export/:id", exportImage)
 		group.POST("/import", importImage)
+		group.GET("/bundle/export/:project", exportImageBundle)
+		group.POST("/bundle/import", importImageBundle)
+		group.POST("/scan/advisories/import", importVulnAdvisories)
+		group.GET("/scan/advisories", getVulnAdvisoryStats)
+		group.GET("/scan/summary", listImageScanSummary)
+		group.POST("/scan/all", scanAllImages)
+		group.GET("/scan/:id", getImageScan)
+		group.POST("/scan/:id", scanImage)
+		group.GET("/sbom/:id", getImageSBOM)
 		group.POST("/prune", pruneImages)
 	}
 }
@@ -665,7 +674,27 @@ func listImages(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, images)
+	// 附带漏洞扫描摘要（未扫描的镜像不带 vulnCounts）
+	scans := loadImageScanSummaries()
+	items := make([]imageListItem, 0, len(images))
+	for _, img := range images {
+		item := imageListItem{ImageSummary: img}
+		if s, ok := scans[img.ID]; ok {
+			counts := vulnCountsOf(s)
+			item.VulnCounts = &counts
+			item.HasCriticalCVEs = s.Critical > 0
+		}
+		items = append(items, item)
+	}
+
+	c.JSON(http.StatusOK, items)
+}
+
+// imageListItem 镜像列表项：在 Docker 原始字段基础上附加漏洞扫描摘要
+type imageListItem struct {
+	types.ImageSummary
+	HasCriticalCVEs bool             `json:"hasCriticalCves"`
+	VulnCounts      *imageVulnCounts `json:"vulnCounts,omitempty"`
 }
 
 // 删除镜像
@@ -773,10 +802,11 @@ func exportImage(c *gin.Context) {
 }
 
 type imageUpdateInfo struct {
-	RepoTag      string `json:"repoTag"`
-	LocalDigest  string `json:"localDigest"`
-	RemoteDigest string `json:"remoteDigest"`
-	Notified     bool   `json:"notified"`
+	RepoTag         string `json:"repoTag"`
+	LocalDigest     string `json:"localDigest"`
+	RemoteDigest    string `json:"remoteDigest"`
+	Notified        bool   `json:"notified"`
+	HasCriticalCVEs bool   `json:"hasCriticalCves"`
 }
 
 type imageUpdateCheckResult struct {
@@ -854,6 +884,7 @@ func runImageUpdateCheck(ctx context.Context, force bool) (imageUpdateCheckResul
 
 	var updates []imageUpdateInfo
 	remoteErrors := 0
+	scans := loadImageScanSummaries()
 	writeErrors := 0
 
 	for _, img := range images {
@@ -928,9 +959,10 @@ func runImageUpdateCheck(ctx context.Context, force bool) (imageUpdateCheckResul
 				continue
 			}
 			updates = append(updates, imageUpdateInfo{
-				RepoTag:      tag,
-				LocalDigest:  localDigest,
-				RemoteDigest: remoteDigest,
+				RepoTag:         tag,
+				LocalDigest:     localDigest,
+				RemoteDigest:    remoteDigest,
+				HasCriticalCVEs: scans[img.ID].Critical > 0,
 			})
 
 			if err := database.SaveImageUpdate(&database.ImageUpdate{
@@ -1080,13 +1112,15 @@ func listStoredImageUpdates(c *gin.Context) {
 		respondError(c, http.StatusInternalServerError, "获取镜像更新记录失败", err)
 		return
 	}
+	scans := loadImageScanSummaries()
 	var updates []imageUpdateInfo
 	for _, item := range items {
 		updates = append(updates, imageUpdateInfo{
-			RepoTag:      item.RepoTag,
-			LocalDigest:  item.LocalDigest,
-			RemoteDigest: item.RemoteDigest,
-			Notified:     item.Notified,
+			RepoTag:         item.RepoTag,
+			LocalDigest:     item.LocalDigest,
+			RemoteDigest:    item.RemoteDigest,
+			Notified:        item.Notified,
+			HasCriticalCVEs: scans[item.ImageID].Critical > 0,
 		})
 	}
 	c.JSON(http.StatusOK, gin.H{"updates": updates})
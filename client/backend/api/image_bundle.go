@@ -0,0 +1,501 @@
+package api
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"dockerpanel/backend/pkg/docker"
+	"dockerpanel/backend/pkg/system"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	imageBundleVersion      = 1
+	imageBundleManifestName = "manifest.json"
+	imageBundleImagesName   = "images.tar"
+)
+
+// imageBundleImage 描述离线包内的单个镜像（ID 即镜像配置摘要，用于导入后校验）
+type imageBundleImage struct {
+	Ref         string   `json:"ref"`
+	ID          string   `json:"id"`
+	RepoDigests []string `json:"repoDigests,omitempty"`
+	Size        int64    `json:"size"`
+}
+
+// imageBundleManifest 离线包清单，与 images.tar 一起打包为 tar.gz
+type imageBundleManifest struct {
+	Version      int                `json:"version"`
+	Project      string             `json:"project"`
+	CreatedAt    time.Time          `json:"createdAt"`
+	Images       []imageBundleImage `json:"images"`
+	ImagesSHA256 string             `json:"imagesSha256"`
+	ImagesSize   int64              `json:"imagesSize"`
+}
+
+// collectProjectImageRefs 汇总 compose 项目涉及的全部镜像（compose 文件 + 项目容器）
+func collectProjectImageRefs(ctx context.Context, cli *docker.Client, projectName string) []string {
+	seen := make(map[string]struct{})
+	refs := make([]string, 0)
+	push := func(ref string) {
+		ref = strings.TrimSpace(ref)
+		if ref == "" {
+			return
+		}
+		if _, ok := seen[ref]; ok {
+			return
+		}
+		seen[ref] = struct{}{}
+		refs = append(refs, ref)
+	}
+
+	projectDir := filepath.Join(getProjectsBaseDir(), projectName)
+	for _, img := range extractComposeImagesFromProject(projectDir) {
+		push(img)
+	}
+
+	// build 类型的服务没有 image 字段，从项目容器补充
+	containers, err := cli.ContainerList(ctx, types.ContainerListOptions{
+		All:     true,
+		Filters: filters.NewArgs(filters.Arg("label", "com.docker.compose.project="+projectName)),
+	})
+	if err == nil {
+		for _, ctr := range containers {
+			if strings.HasPrefix(ctr.Image, "sha256:") {
+				continue
+			}
+			push(ctr.Image)
+		}
+	}
+
+	sort.Strings(refs)
+	return refs
+}
+
+// exportImageBundle 将 compose 项目的全部镜像导出为一个带清单的压缩离线包
+func exportImageBundle(c *gin.Context) {
+	name, ok := validateComposeProjectName(c.Param("project"))
+	if !ok {
+		respondError(c, http.StatusBadRequest, "项目名不合法：仅支持小写字母/数字，且可包含 _ -，并以字母或数字开头", nil)
+		return
+	}
+	if forbidIfSelfProject(c, name) {
+		return
+	}
+
+	cli, ok := getDockerClient(c)
+	if !ok {
+		return
+	}
+	defer cli.Close()
+
+	ctx := c.Request.Context()
+	refs := collectProjectImageRefs(ctx, cli, name)
+	if len(refs) == 0 {
+		respondError(c, http.StatusNotFound, "项目中未找到任何镜像", nil)
+		return
+	}
+
+	manifest := imageBundleManifest{
+		Version:   imageBundleVersion,
+		Project:   name,
+		CreatedAt: time.Now().UTC(),
+		Images:    make([]imageBundleImage, 0, len(refs)),
+	}
+	missing := make([]string, 0)
+	for _, ref := range refs {
+		inspect, _, err := cli.ImageInspectWithRaw(ctx, ref)
+		if err != nil {
+			missing = append(missing, ref)
+			continue
+		}
+		manifest.Images = append(manifest.Images, imageBundleImage{
+			Ref:         ref,
+			ID:          inspect.ID,
+			RepoDigests: inspect.RepoDigests,
+			Size:        inspect.Size,
+		})
+	}
+	if len(missing) > 0 {
+		respondErrorWithDetail(c, http.StatusBadRequest, "部分镜像在本地不存在，请先拉取或构建", strings.Join(missing, ", "))
+		return
+	}
+
+	// 先落盘 images.tar 以计算摘要与大小，再写入最终压缩包
+	imagesFile, err := os.CreateTemp("", "tradis-bundle-images-*.tar")
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "创建临时文件失败", err)
+		return
+	}
+	defer os.Remove(imagesFile.Name())
+	defer imagesFile.Close()
+
+	reader, err := cli.ImageSave(ctx, refs)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "导出镜像失败", err)
+		return
+	}
+	hasher := sha256.New()
+	size, err := io.Copy(io.MultiWriter(imagesFile, hasher), reader)
+	reader.Close()
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "写入临时文件失败", err)
+		return
+	}
+	manifest.ImagesSHA256 = hex.EncodeToString(hasher.Sum(nil))
+	manifest.ImagesSize = size
+
+	manifestData, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "生成清单失败", err)
+		return
+	}
+	if _, err := imagesFile.Seek(0, io.SeekStart); err != nil {
+		respondError(c, http.StatusInternalServerError, "读取临时文件失败", err)
+		return
+	}
+
+	fileName := fmt.Sprintf("%s-images-%s.tar.gz", name, time.Now().Format("20060102-150405"))
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s", fileName))
+	c.Header("Content-Type", "application/gzip")
+
+	log.Printf("导出项目镜像离线包: project=%s images=%v", name, refs)
+
+	gw := gzip.NewWriter(c.Writer)
+	tw := tar.NewWriter(gw)
+	if err := writeTarEntry(tw, imageBundleManifestName, int64(len(manifestData)), strings.NewReader(string(manifestData))); err != nil {
+		log.Printf("写入离线包清单失败: %v", err)
+		return
+	}
+	if err := writeTarEntry(tw, imageBundleImagesName, size, imagesFile); err != nil {
+		log.Printf("写入离线包镜像失败: %v", err)
+		return
+	}
+	if err := tw.Close(); err != nil {
+		log.Printf("关闭离线包失败: %v", err)
+		return
+	}
+	_ = gw.Close()
+}
+
+func writeTarEntry(tw *tar.Writer, name string, size int64, r io.Reader) error {
+	hdr := &tar.Header{
+		Name:    name,
+		Mode:    0644,
+		Size:    size,
+		ModTime: time.Now(),
+	}
+	if err := tw.WriteHeader(hdr); err != nil {
+		return err
+	}
+	_, err := io.CopyN(tw, r, size)
+	return err
+}
+
+// readImageBundle 解析离线包：读取清单并将 images.tar 解压到临时文件，同时校验摘要
+func readImageBundle(bundlePath string) (imageBundleManifest, string, error) {
+	var manifest imageBundleManifest
+
+	f, err := os.Open(bundlePath)
+	if err != nil {
+		return manifest, "", err
+	}
+	defer f.Close()
+
+	gr, err := gzip.NewReader(f)
+	if err != nil {
+		return manifest, "", fmt.Errorf("离线包不是有效的 gzip 文件: %v", err)
+	}
+	defer gr.Close()
+
+	imagesPath := ""
+	imagesSum := ""
+	var imagesSize int64
+	foundManifest := false
+	cleanup := func() {
+		if imagesPath != "" {
+			_ = os.Remove(imagesPath)
+		}
+	}
+
+	tr := tar.NewReader(gr)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			cleanup()
+			return manifest, "", fmt.Errorf("读取离线包失败: %v", err)
+		}
+		switch filepath.Base(hdr.Name) {
+		case imageBundleManifestName:
+			data, err := io.ReadAll(io.LimitReader(tr, 4*1024*1024))
+			if err != nil {
+				cleanup()
+				return manifest, "", fmt.Errorf("读取清单失败: %v", err)
+			}
+			if err := json.Unmarshal(data, &manifest); err != nil {
+				cleanup()
+				return manifest, "", fmt.Errorf("解析清单失败: %v", err)
+			}
+			foundManifest = true
+		case imageBundleImagesName:
+			out, err := os.CreateTemp("", "tradis-bundle-load-*.tar")
+			if err != nil {
+				cleanup()
+				return manifest, "", err
+			}
+			imagesPath = out.Name()
+			hasher := sha256.New()
+			imagesSize, err = io.Copy(io.MultiWriter(out, hasher), tr)
+			out.Close()
+			if err != nil {
+				cleanup()
+				return manifest, "", fmt.Errorf("解压镜像失败: %v", err)
+			}
+			imagesSum = hex.EncodeToString(hasher.Sum(nil))
+		}
+	}
+
+	if !foundManifest {
+		cleanup()
+		return manifest, "", errors.New("离线包缺少 manifest.json")
+	}
+	if manifest.Version != imageBundleVersion {
+		cleanup()
+		return manifest, "", fmt.Errorf("不支持的离线包版本: %d", manifest.Version)
+	}
+	if imagesPath == "" {
+		return manifest, "", errors.New("离线包缺少 images.tar")
+	}
+	if manifest.ImagesSHA256 == "" {
+		cleanup()
+		return manifest, "", errors.New("离线包清单缺少 imagesSha256，无法校验镜像数据")
+	}
+	if manifest.ImagesSize != imagesSize {
+		cleanup()
+		return manifest, "", fmt.Errorf("镜像数据大小校验失败: 期望 %d, 实际 %d", manifest.ImagesSize, imagesSize)
+	}
+	if !strings.EqualFold(manifest.ImagesSHA256, imagesSum) {
+		cleanup()
+		return manifest, "", fmt.Errorf("镜像数据校验失败: 期望 %s, 实际 %s", manifest.ImagesSHA256, imagesSum)
+	}
+	return manifest, imagesPath, nil
+}
+
+// readImageTarConfigIDs 读取 docker save 归档中 manifest.json 记录的镜像配置摘要（即镜像 ID）
+func readImageTarConfigIDs(r io.Reader) (map[string]bool, error) {
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil, errors.New("images.tar 中缺少 manifest.json")
+		}
+		if err != nil {
+			return nil, err
+		}
+		if strings.TrimPrefix(hdr.Name, "./") != "manifest.json" {
+			continue
+		}
+		var entries []struct {
+			Config string `json:"Config"`
+		}
+		if err := json.NewDecoder(io.LimitReader(tr, 4*1024*1024)).Decode(&entries); err != nil {
+			return nil, fmt.Errorf("解析 images.tar 清单失败: %v", err)
+		}
+		ids := make(map[string]bool, len(entries))
+		for _, e := range entries {
+			// 旧格式为 <hex>.json，OCI 布局为 blobs/sha256/<hex>
+			hexID := strings.TrimSuffix(filepath.Base(e.Config), ".json")
+			if hexID != "" {
+				ids["sha256:"+hexID] = true
+			}
+		}
+		return ids, nil
+	}
+}
+
+// isDigestImageRef 判断镜像引用是否按摘要指定（repo@sha256:...）
+func isDigestImageRef(ref string) bool {
+	return strings.Contains(ref, "@")
+}
+
+// retagImageRef 将镜像引用改写到目标仓库前缀下（保留原仓库路径与标签）。
+// 摘要引用无法作为标签使用：带标签时保留标签，否则以 sha256-<摘要> 作为标签
+func retagImageRef(ref string, registry string) string {
+	registry = strings.TrimRight(strings.TrimSpace(registry), "/")
+	if registry == "" {
+		return ref
+	}
+	var name, tag string
+	if before, digest, ok := strings.Cut(ref, "@"); ok {
+		name = before
+		if lastColon := strings.LastIndex(before, ":"); lastColon > strings.LastIndex(before, "/") {
+			name, tag = before[:lastColon], before[lastColon+1:]
+		}
+		if tag == "" {
+			tag = strings.Replace(digest, ":", "-", 1)
+		}
+	} else {
+		name, tag = splitImageRef(ref)
+	}
+	if host := imageHostFromName(name); host != "" {
+		name = strings.TrimPrefix(name, host+"/")
+	}
+	return registry + "/" + name + ":" + tag
+}
+
+type imageBundleImportResult struct {
+	Ref      string `json:"ref"`
+	ID       string `json:"id"`
+	Verified bool   `json:"verified"`
+	Tagged   string `json:"tagged,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+// importImageBundle 导入离线包：校验摘要、加载镜像、按清单校验镜像 ID 并补齐标签
+func importImageBundle(c *gin.Context) {
+	file, err := c.FormFile("file")
+	if err != nil {
+		respondError(c, http.StatusBadRequest, "获取上传文件失败", err)
+		return
+	}
+	registry := strings.TrimSpace(c.PostForm("registry"))
+
+	tempFile, err := os.CreateTemp("", "tradis-bundle-*.tar.gz")
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "创建临时文件失败", err)
+		return
+	}
+	defer os.Remove(tempFile.Name())
+	defer tempFile.Close()
+
+	src, err := file.Open()
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "打开上传文件失败", err)
+		return
+	}
+	_, err = io.Copy(tempFile, src)
+	src.Close()
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "保存上传文件失败", err)
+		return
+	}
+	tempFile.Close()
+
+	manifest, imagesPath, err := readImageBundle(tempFile.Name())
+	if err != nil {
+		respondError(c, http.StatusBadRequest, "离线包校验失败", err)
+		return
+	}
+	defer os.Remove(imagesPath)
+
+	cli, ok := getDockerClient(c)
+	if !ok {
+		return
+	}
+	defer cli.Close()
+
+	ctx := context.Background()
+	imagesFile, err := os.Open(imagesPath)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "读取临时文件失败", err)
+		return
+	}
+	defer imagesFile.Close()
+
+	// 镜像 ID 是配置内容的摘要，以 images.tar 自身记录的配置为准校验清单中的 ID
+	loadedIDs, err := readImageTarConfigIDs(imagesFile)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, "离线包校验失败", err)
+		return
+	}
+	if _, err := imagesFile.Seek(0, io.SeekStart); err != nil {
+		respondError(c, http.StatusInternalServerError, "读取临时文件失败", err)
+		return
+	}
+
+	resp, err := cli.ImageLoad(ctx, imagesFile, true)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "导入镜像失败", err)
+		return
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+
+	results := make([]imageBundleImportResult, 0, len(manifest.Images))
+	failed := 0
+	for _, img := range manifest.Images {
+		res := imageBundleImportResult{Ref: img.Ref, ID: img.ID}
+
+		if !loadedIDs[img.ID] {
+			res.Error = "清单记录的镜像 ID 不在镜像数据中"
+			failed++
+			results = append(results, res)
+			continue
+		}
+		if _, _, ierr := cli.ImageInspectWithRaw(ctx, img.ID); ierr != nil {
+			res.Error = "导入后未找到镜像: " + ierr.Error()
+			failed++
+			results = append(results, res)
+			continue
+		}
+		res.Verified = true
+
+		// 标签缺失或指向了其他镜像时，重新打标签（摘要引用无法在本地打标签）
+		if !isDigestImageRef(img.Ref) {
+			if current, _, terr := cli.ImageInspectWithRaw(ctx, img.Ref); terr != nil || current.ID != img.ID {
+				if err := cli.ImageTag(ctx, img.ID, img.Ref); err != nil {
+					res.Error = "重新打标签失败: " + err.Error()
+					failed++
+					results = append(results, res)
+					continue
+				}
+			}
+		}
+
+		if registry != "" {
+			target := retagImageRef(img.Ref, registry)
+			if err := cli.ImageTag(ctx, img.ID, target); err != nil {
+				res.Error = "打标签到目标仓库失败: " + err.Error()
+				failed++
+				results = append(results, res)
+				continue
+			}
+			res.Tagged = target
+		}
+		results = append(results, res)
+	}
+
+	if failed > 0 {
+		system.LogSimpleEvent("warning", fmt.Sprintf("镜像离线包导入完成: 项目 %s, 镜像 %d, 失败 %d", manifest.Project, len(manifest.Images), failed))
+	} else {
+		system.LogSimpleEvent("success", fmt.Sprintf("镜像离线包导入完成: 项目 %s, 镜像 %d", manifest.Project, len(manifest.Images)))
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":   "离线包导入完成",
+		"project":   manifest.Project,
+		"createdAt": manifest.CreatedAt,
+		"images":    results,
+		"failed":    failed,
+	})
+}
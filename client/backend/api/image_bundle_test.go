@@ -0,0 +1,116 @@
+package api
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeTestBundle(t *testing.T, manifest imageBundleManifest, images string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "bundle.tar.gz")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	gw := gzip.NewWriter(f)
+	tw := tar.NewWriter(gw)
+	data, _ := json.Marshal(manifest)
+	if err := writeTarEntry(tw, imageBundleManifestName, int64(len(data)), strings.NewReader(string(data))); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeTarEntry(tw, imageBundleImagesName, int64(len(images)), strings.NewReader(images)); err != nil {
+		t.Fatal(err)
+	}
+	_ = tw.Close()
+	_ = gw.Close()
+	return path
+}
+
+func TestReadImageBundleVerifiesDigest(t *testing.T) {
+	images := "fake-image-tar"
+	sum := "f9069c6f63defcbf747ebccf902906d87922df49eb2aaec1360bff335097b18f"
+	size := int64(len(images))
+
+	bad := writeTestBundle(t, imageBundleManifest{Version: imageBundleVersion, Project: "demo", ImagesSHA256: strings.Repeat("0", 64), ImagesSize: size}, images)
+	if _, _, err := readImageBundle(bad); err == nil || !strings.Contains(err.Error(), "校验失败") {
+		t.Fatalf("expected digest mismatch, got %v", err)
+	}
+
+	noDigest := writeTestBundle(t, imageBundleManifest{Version: imageBundleVersion, Project: "demo", ImagesSize: size}, images)
+	if _, _, err := readImageBundle(noDigest); err == nil || !strings.Contains(err.Error(), "imagesSha256") {
+		t.Fatalf("expected missing digest error, got %v", err)
+	}
+
+	badSize := writeTestBundle(t, imageBundleManifest{Version: imageBundleVersion, Project: "demo", ImagesSHA256: sum, ImagesSize: size + 1}, images)
+	if _, _, err := readImageBundle(badSize); err == nil || !strings.Contains(err.Error(), "大小") {
+		t.Fatalf("expected size mismatch, got %v", err)
+	}
+
+	ok := writeTestBundle(t, imageBundleManifest{Version: imageBundleVersion, Project: "demo", ImagesSHA256: sum, ImagesSize: size}, images)
+	m, imagesPath, err := readImageBundle(ok)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.Remove(imagesPath)
+	if m.Project != "demo" {
+		t.Fatalf("unexpected project: %q", m.Project)
+	}
+	data, _ := os.ReadFile(imagesPath)
+	if string(data) != images {
+		t.Fatalf("unexpected images content: %q", string(data))
+	}
+
+	unsupported := writeTestBundle(t, imageBundleManifest{Version: 99}, images)
+	if _, _, err := readImageBundle(unsupported); err == nil {
+		t.Fatalf("expected version error")
+	}
+}
+
+func TestReadImageTarConfigIDs(t *testing.T) {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	data := `[{"Config":"` + strings.Repeat("a", 64) + `.json","RepoTags":["nginx:1"]},{"Config":"blobs/sha256/` + strings.Repeat("b", 64) + `"}]`
+	if err := writeTarEntry(tw, "manifest.json", int64(len(data)), strings.NewReader(data)); err != nil {
+		t.Fatal(err)
+	}
+	_ = tw.Close()
+
+	ids, err := readImageTarConfigIDs(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ids) != 2 || !ids["sha256:"+strings.Repeat("a", 64)] || !ids["sha256:"+strings.Repeat("b", 64)] {
+		t.Fatalf("ids = %v", ids)
+	}
+	if _, err := readImageTarConfigIDs(strings.NewReader("")); err == nil {
+		t.Fatalf("expected error for archive without manifest")
+	}
+}
+
+func TestRetagImageRef(t *testing.T) {
+	cases := map[string]string{
+		"nginx":                                   "registry.local:5000/nginx:latest",
+		"library/redis:7":                         "registry.local:5000/library/redis:7",
+		"ghcr.io/owner/app:1.2":                   "registry.local:5000/owner/app:1.2",
+		"localhost:5000/team/svc:latest":          "registry.local:5000/team/svc:latest",
+		"nginx@sha256:" + strings.Repeat("a", 64): "registry.local:5000/nginx:sha256-" + strings.Repeat("a", 64),
+		"ghcr.io/owner/app:1.2@sha256:" + strings.Repeat("b", 64): "registry.local:5000/owner/app:1.2",
+		"localhost:5000/svc@sha256:" + strings.Repeat("c", 64):    "registry.local:5000/svc:sha256-" + strings.Repeat("c", 64),
+	}
+	for in, want := range cases {
+		if got := retagImageRef(in, "registry.local:5000/"); got != want {
+			t.Fatalf("retagImageRef(%q)=%q want %q", in, got, want)
+		}
+	}
+	if got := retagImageRef("nginx:1", ""); got != "nginx:1" {
+		t.Fatalf("empty registry should keep ref, got %q", got)
+	}
+}
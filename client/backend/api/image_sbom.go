@@ -0,0 +1,255 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"dockerpanel/backend/pkg/docker"
+	"dockerpanel/backend/pkg/sbom"
+	"dockerpanel/backend/pkg/scan"
+
+	"github.com/gin-gonic/gin"
+)
+
+// projectSBOMDir compose 项目目录下存放 SBOM 的子目录
+const projectSBOMDir = "sbom"
+
+var sbomFileNameSanitizer = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// buildImageInventory 导出镜像并解析系统包与语言锁文件
+func buildImageInventory(ctx context.Context, cli *docker.Client, ref string) (sbom.Inventory, error) {
+	inv := sbom.Inventory{Created: time.Now()}
+	inspect, _, err := cli.ImageInspectWithRaw(ctx, ref)
+	if err != nil {
+		return inv, err
+	}
+	files, err := extractImageFiles(ctx, cli, inspect.ID, scan.IsInventoryFile)
+	if err != nil {
+		return inv, err
+	}
+	info, pkgs, notes := scan.ReadOSPackages(files)
+	for _, n := range notes {
+		log.Printf("生成 SBOM image=%s: %s", inspect.ID, n)
+	}
+
+	name := ref
+	if strings.HasPrefix(ref, "sha256:") || strings.HasPrefix(inspect.ID, "sha256:"+ref) {
+		name = inspect.ID
+		if len(inspect.RepoTags) > 0 {
+			name = inspect.RepoTags[0]
+		}
+	}
+	inv.Subject = sbom.Subject{
+		Name:        name,
+		ImageID:     inspect.ID,
+		RepoDigests: inspect.RepoDigests,
+	}
+	inv.OS = info
+	inv.Packages = append(pkgs, scan.ReadLanguagePackages(files)...)
+	return inv, nil
+}
+
+func sbomFileName(subject string, format string) string {
+	base := strings.Trim(sbomFileNameSanitizer.ReplaceAllString(subject, "_"), "_")
+	if base == "" {
+		base = "image"
+	}
+	return fmt.Sprintf("%s.%s.json", base, format)
+}
+
+func writeSBOMDownload(c *gin.Context, format string, inv sbom.Inventory, subject string) {
+	data, err := sbom.Generate(format, inv)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "生成 SBOM 失败", err)
+		return
+	}
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s", sbomFileName(subject, format)))
+	c.Data(http.StatusOK, "application/json", data)
+}
+
+// getImageSBOM 下载镜像 SBOM（?format=spdx|cyclonedx）
+func getImageSBOM(c *gin.Context) {
+	format := sbom.NormalizeFormat(c.Query("format"))
+	if format == "" {
+		respondError(c, http.StatusBadRequest, "不支持的 SBOM 格式，可选 spdx / cyclonedx", nil)
+		return
+	}
+	id := strings.TrimSpace(c.Param("id"))
+	if id == "" {
+		respondError(c, http.StatusBadRequest, "镜像ID不能为空", nil)
+		return
+	}
+
+	cli, ok := getDockerClient(c)
+	if !ok {
+		return
+	}
+	defer cli.Close()
+
+	inv, err := buildImageInventory(c.Request.Context(), cli, id)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "读取镜像软件包失败", err)
+		return
+	}
+	writeSBOMDownload(c, format, inv, inv.Subject.Name)
+}
+
+// getContainerSBOM 下载运行中容器所用镜像的 SBOM（容器可写层中的变更不计入）
+func getContainerSBOM(c *gin.Context) {
+	format := sbom.NormalizeFormat(c.Query("format"))
+	if format == "" {
+		respondError(c, http.StatusBadRequest, "不支持的 SBOM 格式，可选 spdx / cyclonedx", nil)
+		return
+	}
+
+	cli, ok := getDockerClient(c)
+	if !ok {
+		return
+	}
+	defer cli.Close()
+
+	ctx := c.Request.Context()
+	ctr, err := cli.ContainerInspect(ctx, c.Param("id"))
+	if err != nil {
+		respondError(c, http.StatusNotFound, "容器不存在", err)
+		return
+	}
+	inv, err := buildImageInventory(ctx, cli, ctr.Image)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "读取镜像软件包失败", err)
+		return
+	}
+	if ctr.Config != nil && ctr.Config.Image != "" {
+		inv.Subject.Name = ctr.Config.Image
+	}
+	inv.Subject.Container = strings.TrimPrefix(ctr.Name, "/")
+	writeSBOMDownload(c, format, inv, inv.Subject.Container)
+}
+
+// generateProjectSBOM 为 compose 项目的全部镜像生成 SBOM 并保存到项目目录的 sbom/ 下
+func generateProjectSBOM(c *gin.Context) {
+	name, ok := validateComposeProjectName(c.Param("name"))
+	if !ok {
+		respondError(c, http.StatusBadRequest, "项目名不合法：仅支持小写字母/数字，且可包含 _ -，并以字母或数字开头", nil)
+		return
+	}
+	if forbidIfSelfProject(c, name) {
+		return
+	}
+	format := sbom.NormalizeFormat(c.Query("format"))
+	if format == "" {
+		respondError(c, http.StatusBadRequest, "不支持的 SBOM 格式，可选 spdx / cyclonedx", nil)
+		return
+	}
+
+	projectDir := filepath.Join(getProjectsBaseDir(), name)
+	if _, err := os.Stat(projectDir); err != nil {
+		respondError(c, http.StatusNotFound, "项目不存在", err)
+		return
+	}
+
+	cli, ok := getDockerClient(c)
+	if !ok {
+		return
+	}
+	defer cli.Close()
+
+	ctx := c.Request.Context()
+	refs := collectProjectImageRefs(ctx, cli, name)
+	if len(refs) == 0 {
+		respondError(c, http.StatusNotFound, "项目中未找到任何镜像", nil)
+		return
+	}
+
+	outDir := filepath.Join(projectDir, projectSBOMDir)
+	if err := os.MkdirAll(outDir, 0755); err != nil {
+		respondError(c, http.StatusInternalServerError, "创建 SBOM 目录失败", err)
+		return
+	}
+
+	files := make([]string, 0, len(refs))
+	failed := make([]gin.H, 0)
+	for _, ref := range refs {
+		inv, err := buildImageInventory(ctx, cli, ref)
+		if err == nil {
+			var data []byte
+			data, err = sbom.Generate(format, inv)
+			if err == nil {
+				fileName := sbomFileName(ref, format)
+				err = os.WriteFile(filepath.Join(outDir, fileName), data, 0644)
+				if err == nil {
+					files = append(files, fileName)
+					continue
+				}
+			}
+		}
+		failed = append(failed, gin.H{"image": ref, "error": err.Error()})
+	}
+
+	log.Printf("生成项目 SBOM: project=%s format=%s files=%v", name, format, files)
+	c.JSON(http.StatusOK, gin.H{
+		"message": "SBOM 已生成",
+		"files":   files,
+		"failed":  failed,
+	})
+}
+
+// listProjectSBOM 列出 compose 项目已保存的 SBOM 文件
+func listProjectSBOM(c *gin.Context) {
+	name, ok := validateComposeProjectName(c.Param("name"))
+	if !ok {
+		respondError(c, http.StatusBadRequest, "项目名不合法：仅支持小写字母/数字，且可包含 _ -，并以字母或数字开头", nil)
+		return
+	}
+
+	entries, err := os.ReadDir(filepath.Join(getProjectsBaseDir(), name, projectSBOMDir))
+	if err != nil && !os.IsNotExist(err) {
+		respondError(c, http.StatusInternalServerError, "读取 SBOM 目录失败", err)
+		return
+	}
+	items := make([]gin.H, 0, len(entries))
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), ".json") {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+		items = append(items, gin.H{
+			"name":    e.Name(),
+			"size":    info.Size(),
+			"modTime": info.ModTime(),
+		})
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i]["name"].(string) < items[j]["name"].(string) })
+	c.JSON(http.StatusOK, gin.H{"files": items})
+}
+
+// downloadProjectSBOM 下载 compose 项目中保存的某个 SBOM 文件
+func downloadProjectSBOM(c *gin.Context) {
+	name, ok := validateComposeProjectName(c.Param("name"))
+	if !ok {
+		respondError(c, http.StatusBadRequest, "项目名不合法：仅支持小写字母/数字，且可包含 _ -，并以字母或数字开头", nil)
+		return
+	}
+	fileName := c.Param("file")
+	if fileName != filepath.Base(fileName) || !strings.HasSuffix(fileName, ".json") {
+		respondError(c, http.StatusBadRequest, "文件名不合法", nil)
+		return
+	}
+	path := filepath.Join(getProjectsBaseDir(), name, projectSBOMDir, fileName)
+	if _, err := os.Stat(path); err != nil {
+		respondError(c, http.StatusNotFound, "SBOM 文件不存在", err)
+		return
+	}
+	c.FileAttachment(path, fileName)
+}
@@ -0,0 +1,418 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"dockerpanel/backend/pkg/database"
+	"dockerpanel/backend/pkg/docker"
+	"dockerpanel/backend/pkg/scan"
+	"dockerpanel/backend/pkg/settings"
+
+	"github.com/docker/docker/api/types"
+	"github.com/gin-gonic/gin"
+)
+
+// vulnDBRevisionKey 漏洞库版本号，每次导入后更新，用于判断扫描缓存是否过期
+const vulnDBRevisionKey = "vuln_db_revision"
+
+// vulnSupportedEcosystems 仅导入系统包相关的生态，其它语言生态的公告在当前扫描中用不到
+var vulnSupportedEcosystems = []string{"Debian", "Ubuntu", "Alpine", "Rocky Linux", "AlmaLinux"}
+
+// imageScanResult 扫描接口的返回结构
+type imageScanResult struct {
+	ImageID    string       `json:"imageId"`
+	DBRevision string       `json:"dbRevision"`
+	ScannedAt  time.Time    `json:"scannedAt"`
+	Cached     bool         `json:"cached"`
+	Stale      bool         `json:"stale"`
+	Report     *scan.Report `json:"report"`
+}
+
+// imageVulnCounts 镜像列表中展示的漏洞计数摘要
+type imageVulnCounts struct {
+	Critical int `json:"critical"`
+	High     int `json:"high"`
+	Medium   int `json:"medium"`
+	Low      int `json:"low"`
+	Unknown  int `json:"unknown"`
+}
+
+func isSupportedVulnEcosystem(eco string) bool {
+	base := eco
+	if i := strings.Index(eco, ":"); i > 0 {
+		base = eco[:i]
+	}
+	for _, s := range vulnSupportedEcosystems {
+		if base == s {
+			return true
+		}
+	}
+	return false
+}
+
+// importVulnAdvisories 导入离线漏洞库（OSV JSON / JSON Lines / zip 全量包）
+func importVulnAdvisories(c *gin.Context) {
+	file, header, err := c.Request.FormFile("file")
+	if err != nil {
+		respondError(c, http.StatusBadRequest, "获取上传文件失败", err)
+		return
+	}
+	defer file.Close()
+
+	advisories, err := scan.ParseOSV(file)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, "解析漏洞库失败", err)
+		return
+	}
+
+	records := make([]database.VulnAdvisoryRecord, 0, len(advisories))
+	skipped := 0
+	for _, a := range advisories {
+		if !isSupportedVulnEcosystem(a.Ecosystem) {
+			skipped++
+			continue
+		}
+		data, err := json.Marshal(a)
+		if err != nil {
+			skipped++
+			continue
+		}
+		records = append(records, database.VulnAdvisoryRecord{
+			AdvisoryID: a.ID,
+			Ecosystem:  a.Ecosystem,
+			Package:    a.Package,
+			Severity:   a.Severity,
+			DataJSON:   string(data),
+		})
+	}
+	if len(records) == 0 {
+		respondError(c, http.StatusBadRequest, "文件中没有可用的系统包漏洞公告", nil)
+		return
+	}
+
+	n, err := database.ImportVulnAdvisories(records, c.PostForm("mode") == "replace")
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "写入漏洞库失败", err)
+		return
+	}
+
+	revision := strconv.FormatInt(time.Now().UnixNano(), 10)
+	if err := settings.SetValue(vulnDBRevisionKey, revision); err != nil {
+		log.Printf("更新漏洞库版本失败: %v", err)
+	}
+
+	log.Printf("导入漏洞库: file=%s imported=%d skipped=%d", header.Filename, n, skipped)
+	c.JSON(http.StatusOK, gin.H{
+		"message":  "漏洞库导入成功",
+		"imported": n,
+		"skipped":  skipped,
+		"revision": revision,
+	})
+}
+
+// getVulnAdvisoryStats 查看漏洞库概况
+func getVulnAdvisoryStats(c *gin.Context) {
+	stats, err := database.GetVulnAdvisoryStats()
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "获取漏洞库信息失败", err)
+		return
+	}
+	revision, _ := settings.GetValue(vulnDBRevisionKey)
+	total := 0
+	for _, s := range stats {
+		total += s.Count
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"revision":   revision,
+		"total":      total,
+		"ecosystems": stats,
+	})
+}
+
+// scanImageByID 扫描单个镜像；force=false 时若缓存与当前漏洞库版本一致则直接返回缓存
+func scanImageByID(ctx context.Context, cli *docker.Client, ref string, force bool) (*imageScanResult, error) {
+	inspect, _, err := cli.ImageInspectWithRaw(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+	revision, _ := settings.GetValue(vulnDBRevisionKey)
+
+	if !force {
+		if cached, err := database.GetImageScan(inspect.ID); err == nil && cached != nil && cached.DBRevision == revision {
+			var report scan.Report
+			if err := json.Unmarshal([]byte(cached.ResultJSON), &report); err == nil {
+				return &imageScanResult{
+					ImageID:    inspect.ID,
+					DBRevision: cached.DBRevision,
+					ScannedAt:  cached.ScannedAt,
+					Cached:     true,
+					Report:     &report,
+				}, nil
+			}
+		}
+	}
+
+	files, err := extractImageFiles(ctx, cli, inspect.ID, scan.IsOSPackageFile)
+	if err != nil {
+		return nil, err
+	}
+
+	info, pkgs, notes := scan.ReadOSPackages(files)
+	if info.Ecosystem() == "" {
+		notes = append(notes, fmt.Sprintf("未识别或暂不支持的发行版: %s %s", info.ID, info.VersionID))
+	}
+	if len(pkgs) == 0 {
+		notes = append(notes, "未在镜像中找到系统包数据库")
+	}
+	if revision == "" {
+		notes = append(notes, "尚未导入漏洞库，请先导入离线漏洞库后重新扫描")
+	}
+
+	raws, err := database.GetVulnAdvisoriesByPackages(scan.LookupNames(pkgs))
+	if err != nil {
+		return nil, fmt.Errorf("读取漏洞库失败: %v", err)
+	}
+	advisories := make([]scan.Advisory, 0, len(raws))
+	for _, raw := range raws {
+		var a scan.Advisory
+		if err := json.Unmarshal([]byte(raw), &a); err == nil {
+			advisories = append(advisories, a)
+		}
+	}
+
+	report := scan.Match(info, pkgs, advisories)
+	report.Notes = append(report.Notes, notes...)
+
+	data, err := json.Marshal(report)
+	if err != nil {
+		return nil, err
+	}
+	rec := database.ImageScanRecord{
+		ImageID:    inspect.ID,
+		DBRevision: revision,
+		Critical:   report.Counts[scan.SeverityCritical],
+		High:       report.Counts[scan.SeverityHigh],
+		Medium:     report.Counts[scan.SeverityMedium],
+		Low:        report.Counts[scan.SeverityLow],
+		Unknown:    report.Counts[scan.SeverityUnknown],
+		ResultJSON: string(data),
+	}
+	if err := database.SaveImageScan(rec); err != nil {
+		log.Printf("保存镜像扫描结果失败 image=%s: %v", inspect.ID, err)
+	}
+
+	return &imageScanResult{
+		ImageID:    inspect.ID,
+		DBRevision: revision,
+		ScannedAt:  time.Now(),
+		Report:     &report,
+	}, nil
+}
+
+// extractImageFiles 通过 ImageSave 导出镜像并提取合并文件系统中 match 命中的文件
+func extractImageFiles(ctx context.Context, cli *docker.Client, imageID string, match func(string) bool) (scan.ImageFiles, error) {
+	reader, err := cli.ImageSave(ctx, []string{imageID})
+	if err != nil {
+		return nil, fmt.Errorf("导出镜像失败: %v", err)
+	}
+	defer reader.Close()
+	return scan.ExtractImageFiles(reader, match)
+}
+
+// scanImage 触发单个镜像扫描（?force=1 忽略缓存）
+func scanImage(c *gin.Context) {
+	id := strings.TrimSpace(c.Param("id"))
+	if id == "" {
+		respondError(c, http.StatusBadRequest, "镜像ID不能为空", nil)
+		return
+	}
+	force := c.Query("force") == "1" || c.Query("force") == "true"
+
+	cli, ok := getDockerClient(c)
+	if !ok {
+		return
+	}
+	defer cli.Close()
+
+	result, err := scanImageByID(c.Request.Context(), cli, id, force)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "扫描镜像失败", err)
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+// getImageScan 获取镜像的扫描缓存，不触发扫描
+func getImageScan(c *gin.Context) {
+	id := strings.TrimSpace(c.Param("id"))
+	if id == "" {
+		respondError(c, http.StatusBadRequest, "镜像ID不能为空", nil)
+		return
+	}
+
+	cli, ok := getDockerClient(c)
+	if !ok {
+		return
+	}
+	defer cli.Close()
+
+	inspect, _, err := cli.ImageInspectWithRaw(c.Request.Context(), id)
+	if err != nil {
+		respondError(c, http.StatusNotFound, "镜像不存在", err)
+		return
+	}
+	cached, err := database.GetImageScan(inspect.ID)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "获取扫描结果失败", err)
+		return
+	}
+	if cached == nil {
+		respondError(c, http.StatusNotFound, "该镜像尚未扫描", nil)
+		return
+	}
+	var report scan.Report
+	if err := json.Unmarshal([]byte(cached.ResultJSON), &report); err != nil {
+		respondError(c, http.StatusInternalServerError, "解析扫描结果失败", err)
+		return
+	}
+	revision, _ := settings.GetValue(vulnDBRevisionKey)
+	c.JSON(http.StatusOK, imageScanResult{
+		ImageID:    inspect.ID,
+		DBRevision: cached.DBRevision,
+		ScannedAt:  cached.ScannedAt,
+		Cached:     true,
+		Stale:      cached.DBRevision != revision,
+		Report:     &report,
+	})
+}
+
+// listImageScanSummary 返回所有已扫描镜像的漏洞计数
+func listImageScanSummary(c *gin.Context) {
+	summaries, err := database.GetImageScanSummaries()
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "获取扫描摘要失败", err)
+		return
+	}
+	revision, _ := settings.GetValue(vulnDBRevisionKey)
+	items := make([]gin.H, 0, len(summaries))
+	for _, s := range summaries {
+		items = append(items, gin.H{
+			"imageId":         s.ImageID,
+			"scannedAt":       s.ScannedAt,
+			"stale":           s.DBRevision != revision,
+			"hasCriticalCves": s.Critical > 0,
+			"counts":          vulnCountsOf(s),
+		})
+	}
+	c.JSON(http.StatusOK, gin.H{"revision": revision, "items": items})
+}
+
+// scanAllImages 以任务形式扫描全部本地镜像，进度可通过 /compose/tasks/:id/events 订阅
+func scanAllImages(c *gin.Context) {
+	force := c.Query("force") == "1" || c.Query("force") == "true"
+	taskID := fmt.Sprintf("%d", time.Now().UnixNano())
+	_ = database.UpsertTask(taskID, "image_scan", "pending")
+
+	go runImageScanTask(taskID, force)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "镜像扫描任务已提交",
+		"taskId":  taskID,
+	})
+}
+
+func runImageScanTask(taskID string, force bool) {
+	seq := int64(0)
+	appendLog := func(logType string, message string) {
+		seq++
+		_ = database.AppendTaskLogWithSeq(taskID, seq, time.Now(), logType, message)
+	}
+	_ = database.UpsertTask(taskID, "image_scan", "running")
+
+	cli, err := docker.NewDockerClient()
+	if err != nil {
+		appendLog("error", "连接 Docker 失败: "+err.Error())
+		_ = database.FinishTask(taskID, "error", nil, err.Error())
+		return
+	}
+	defer cli.Close()
+
+	ctx := context.Background()
+	images, err := cli.ImageList(ctx, types.ImageListOptions{})
+	if err != nil {
+		appendLog("error", "获取镜像列表失败: "+err.Error())
+		_ = database.FinishTask(taskID, "error", nil, err.Error())
+		return
+	}
+
+	scanned, failed := 0, 0
+	critical := make([]string, 0)
+	for i, img := range images {
+		name := img.ID
+		if len(img.RepoTags) > 0 && img.RepoTags[0] != "<none>:<none>" {
+			name = img.RepoTags[0]
+		}
+		appendLog("info", fmt.Sprintf("[%d/%d] 扫描 %s", i+1, len(images), name))
+		result, err := scanImageByID(ctx, cli, img.ID, force)
+		if err != nil {
+			failed++
+			appendLog("error", fmt.Sprintf("扫描 %s 失败: %v", name, err))
+			continue
+		}
+		scanned++
+		counts := result.Report.Counts
+		appendLog("info", fmt.Sprintf("%s: 严重 %d / 高危 %d / 中危 %d / 低危 %d",
+			name, counts[scan.SeverityCritical], counts[scan.SeverityHigh], counts[scan.SeverityMedium], counts[scan.SeverityLow]))
+		if result.Report.HasCritical() {
+			critical = append(critical, name)
+		}
+	}
+
+	status := "success"
+	errStr := ""
+	if failed > 0 && scanned == 0 {
+		status = "error"
+		errStr = "全部镜像扫描失败"
+	}
+	appendLog("info", fmt.Sprintf("扫描完成：成功 %d，失败 %d，存在严重漏洞 %d", scanned, failed, len(critical)))
+	_ = database.FinishTask(taskID, status, gin.H{
+		"scanned":  scanned,
+		"failed":   failed,
+		"critical": critical,
+	}, errStr)
+
+	if len(critical) > 0 {
+		_ = database.SaveNotification(&database.Notification{
+			Type:    "warning",
+			Message: fmt.Sprintf("%d 个镜像存在严重漏洞：%s", len(critical), strings.Join(critical, "、")),
+			Read:    false,
+		})
+	}
+}
+
+func vulnCountsOf(r database.ImageScanRecord) imageVulnCounts {
+	return imageVulnCounts{
+		Critical: r.Critical,
+		High:     r.High,
+		Medium:   r.Medium,
+		Low:      r.Low,
+		Unknown:  r.Unknown,
+	}
+}
+
+// loadImageScanSummaries 读取扫描摘要，失败时返回空表（不影响调用方主流程）
+func loadImageScanSummaries() map[string]database.ImageScanRecord {
+	summaries, err := database.GetImageScanSummaries()
+	if err != nil {
+		log.Printf("读取镜像扫描摘要失败: %v", err)
+		return map[string]database.ImageScanRecord{}
+	}
+	return summaries
+}
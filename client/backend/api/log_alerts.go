@@ -0,0 +1,601 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"dockerpanel/backend/pkg/database"
+	"dockerpanel/backend/pkg/docker"
+
+	"github.com/docker/docker/api/types"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	logAlertReconcileEvery  = 15 * time.Second
+	logAlertDefaultWindow   = 300
+	logAlertDefaultCooldown = 600
+	logAlertExcerptLines    = 5
+	logAlertExcerptLineMax  = 300
+)
+
+// compiledLogAlert 预编译的告警规则
+type compiledLogAlert struct {
+	rule     database.LogAlertRule
+	pattern  *regexp.Regexp
+	filter   logEntryFilter
+	window   time.Duration
+	cooldown time.Duration
+}
+
+func trimStringList(list []string) []string {
+	out := make([]string, 0, len(list))
+	for _, s := range list {
+		if s = strings.TrimSpace(s); s != "" {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// defaultLogAlertRule 请求解析前的默认值：冷却时间 0 表示不冷却，只有请求中未提供时才使用默认值
+func defaultLogAlertRule() database.LogAlertRule {
+	return database.LogAlertRule{Enabled: true, CooldownSeconds: logAlertDefaultCooldown}
+}
+
+// normalizeLogAlertRule 去除空白并填充默认值
+func normalizeLogAlertRule(r *database.LogAlertRule) {
+	r.Name = strings.TrimSpace(r.Name)
+	r.Pattern = strings.TrimSpace(r.Pattern)
+	r.Containers = trimStringList(r.Containers)
+	r.Projects = trimStringList(r.Projects)
+	r.Services = trimStringList(r.Services)
+	r.Labels = trimStringList(r.Labels)
+	r.Levels = trimStringList(r.Levels)
+	r.Fields = trimStringList(r.Fields)
+	if r.Threshold == 0 {
+		r.Threshold = 1
+	}
+	if r.WindowSeconds == 0 {
+		r.WindowSeconds = logAlertDefaultWindow
+	}
+}
+
+// compileLogAlertRule 校验并编译规则
+func compileLogAlertRule(r database.LogAlertRule) (*compiledLogAlert, fieldErrors) {
+	var errs fieldErrors
+	if r.Name == "" {
+		errs.add("name", "规则名称不能为空")
+	}
+	a := &compiledLogAlert{
+		rule:     r,
+		window:   time.Duration(r.WindowSeconds) * time.Second,
+		cooldown: time.Duration(r.CooldownSeconds) * time.Second,
+	}
+	if r.Pattern != "" {
+		var err error
+		if a.pattern, err = regexp.Compile(r.Pattern); err != nil {
+			errs.add("pattern", "正则表达式不合法: %v", err)
+		}
+	}
+	a.filter = newLogEntryFilter(r.Levels, r.Fields, "", &errs)
+	if r.Pattern == "" && len(r.Levels) == 0 && len(r.Fields) == 0 {
+		errs.add("pattern", "至少需要设置正则、级别或字段条件之一")
+	}
+	for i, sel := range r.Labels {
+		if key, _, _ := strings.Cut(sel, "="); strings.TrimSpace(key) == "" {
+			errs.add(fmt.Sprintf("labels[%d]", i), "标签选择器格式应为 key 或 key=value")
+		}
+	}
+	if r.Threshold < 1 || r.Threshold > 10000 {
+		errs.add("threshold", "触发次数取值范围为 1-10000")
+	}
+	if r.WindowSeconds < 10 || r.WindowSeconds > 86400 {
+		errs.add("windowSeconds", "统计窗口取值范围为 10-86400 秒")
+	}
+	if r.CooldownSeconds < 0 || r.CooldownSeconds > 86400 {
+		errs.add("cooldownSeconds", "冷却时间取值范围为 0-86400 秒")
+	}
+	return a, errs
+}
+
+// appliesTo 规则是否作用于该容器；按名称、项目、服务与标签匹配，容器重建后仍然生效
+func (a *compiledLogAlert) appliesTo(name string, labels map[string]string) bool {
+	r := &a.rule
+	if len(r.Containers) > 0 {
+		matched := false
+		for _, p := range r.Containers {
+			if queryGlobMatch(p, name, false) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	if len(r.Projects) > 0 && !containsString(r.Projects, labels["com.docker.compose.project"]) {
+		return false
+	}
+	if len(r.Services) > 0 && !containsString(r.Services, labels["com.docker.compose.service"]) {
+		return false
+	}
+	return matchesLabelSelectors(labels, r.Labels)
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+func (a *compiledLogAlert) matches(e *logEntry) bool {
+	if !a.filter.match(e) {
+		return false
+	}
+	return a.pattern == nil || a.pattern.MatchString(e.Message)
+}
+
+// logAlertSourceKey 计数的归属：compose 服务按 项目/服务 计数（包含所有副本与重建后的容器），其余按容器名
+func logAlertSourceKey(name string, labels map[string]string) string {
+	project := labels["com.docker.compose.project"]
+	service := labels["com.docker.compose.service"]
+	if project != "" && service != "" {
+		return project + "/" + service
+	}
+	return name
+}
+
+type logAlertCounter struct {
+	hits       []time.Time
+	excerpt    []string
+	lastFired  time.Time
+	suppressed int
+}
+
+// logAlertFiring 一次需要发送的告警
+type logAlertFiring struct {
+	Count      int
+	Suppressed int
+	Excerpt    []string
+}
+
+type logAlertCounterKey struct {
+	rule   int64
+	source string
+}
+
+// logAlertTracker 维护每条规则、每个来源的滑动窗口计数与冷却状态
+type logAlertTracker struct {
+	mu       sync.Mutex
+	counters map[logAlertCounterKey]*logAlertCounter
+}
+
+func newLogAlertTracker() *logAlertTracker {
+	return &logAlertTracker{counters: make(map[logAlertCounterKey]*logAlertCounter)}
+}
+
+// observe 记录一次匹配，达到阈值且不在冷却期内时返回告警；冷却期内的匹配只计数，在下一次告警中体现
+func (t *logAlertTracker) observe(a *compiledLogAlert, source string, e *logEntry) *logAlertFiring {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	key := logAlertCounterKey{rule: a.rule.ID, source: source}
+	c := t.counters[key]
+	if c == nil {
+		c = &logAlertCounter{}
+		t.counters[key] = c
+	}
+	if !c.lastFired.IsZero() && e.Time.Sub(c.lastFired) < a.cooldown {
+		c.suppressed++
+		return nil
+	}
+
+	cutoff := e.Time.Add(-a.window)
+	kept := c.hits[:0]
+	for _, ts := range c.hits {
+		if ts.After(cutoff) {
+			kept = append(kept, ts)
+		}
+	}
+	c.hits = append(kept, e.Time)
+
+	line := e.Message
+	if len(line) > logAlertExcerptLineMax {
+		line = line[:logAlertExcerptLineMax] + "..."
+	}
+	// 摘录只保留窗口内最近的几行
+	c.excerpt = append(c.excerpt, line)
+	keep := len(c.hits)
+	if keep > logAlertExcerptLines {
+		keep = logAlertExcerptLines
+	}
+	if len(c.excerpt) > keep {
+		c.excerpt = c.excerpt[len(c.excerpt)-keep:]
+	}
+
+	if len(c.hits) < a.rule.Threshold {
+		return nil
+	}
+	f := &logAlertFiring{Count: len(c.hits), Suppressed: c.suppressed, Excerpt: c.excerpt}
+	c.hits = nil
+	c.excerpt = nil
+	c.suppressed = 0
+	c.lastFired = e.Time
+	return f
+}
+
+// forget 清除规则的计数，规则修改或删除后调用
+func (t *logAlertTracker) forget(ruleID int64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for key := range t.counters {
+		if key.rule == ruleID {
+			delete(t.counters, key)
+		}
+	}
+}
+
+type logAlertTail struct {
+	cancel context.CancelFunc
+}
+
+// logAlertEngine 跟随被规则覆盖的运行中容器的日志，并按规则评估每一行
+type logAlertEngine struct {
+	mu       sync.Mutex
+	rules    []*compiledLogAlert
+	gen      int
+	tails    map[string]*logAlertTail
+	lastSeen map[string]time.Time // 容器 ID -> 最后处理的日志时间，重新跟随时从此处继续
+	started  time.Time
+	tracker  *logAlertTracker
+}
+
+var logAlerts = &logAlertEngine{
+	tails:    make(map[string]*logAlertTail),
+	lastSeen: make(map[string]time.Time),
+	tracker:  newLogAlertTracker(),
+}
+
+// StartLogAlertEngine 加载日志告警规则并定期同步需要跟随的容器
+func StartLogAlertEngine() {
+	logAlerts.mu.Lock()
+	logAlerts.started = time.Now()
+	logAlerts.mu.Unlock()
+	logAlerts.reload()
+
+	go func() {
+		ticker := time.NewTicker(logAlertReconcileEvery)
+		defer ticker.Stop()
+		for range ticker.C {
+			logAlerts.reconcile()
+		}
+	}()
+}
+
+// reload 从数据库重新加载启用的规则
+func (le *logAlertEngine) reload() {
+	list, err := database.ListLogAlertRules()
+	if err != nil {
+		log.Printf("加载日志告警规则失败: %v", err)
+		return
+	}
+	var rules []*compiledLogAlert
+	for _, r := range list {
+		if !r.Enabled {
+			continue
+		}
+		a, errs := compileLogAlertRule(r)
+		if len(errs) > 0 {
+			log.Printf("日志告警规则 %s 无效，已跳过: %s", r.Name, errs[0].Message)
+			continue
+		}
+		rules = append(rules, a)
+	}
+	le.mu.Lock()
+	le.rules = rules
+	le.gen++
+	le.mu.Unlock()
+	go le.reconcile()
+}
+
+func (le *logAlertEngine) anyRuleApplies(name string, labels map[string]string) bool {
+	for _, a := range le.rules {
+		if a.appliesTo(name, labels) {
+			return true
+		}
+	}
+	return false
+}
+
+// reconcile 为被规则覆盖的运行中容器启动日志跟随，停止不再需要的跟随
+func (le *logAlertEngine) reconcile() {
+	le.mu.Lock()
+	hasRules := len(le.rules) > 0
+	le.mu.Unlock()
+
+	want := make(map[string]types.Container)
+	existing := make(map[string]bool)
+	if hasRules {
+		cli, err := docker.NewDockerClient()
+		if err != nil {
+			log.Printf("日志告警连接 Docker 失败: %v", err)
+			return
+		}
+		list, err := cli.ContainerList(context.Background(), types.ContainerListOptions{All: true})
+		cli.Close()
+		if err != nil {
+			log.Printf("日志告警获取容器列表失败: %v", err)
+			return
+		}
+		le.mu.Lock()
+		for _, ct := range list {
+			existing[ct.ID] = true
+			name := containerDisplayName(ct)
+			if ct.State != "running" || isSelfOrProtectedContainer(ct.ID, name, ct.Image, ct.Labels) {
+				continue
+			}
+			if le.anyRuleApplies(name, ct.Labels) {
+				want[ct.ID] = ct
+			}
+		}
+		le.mu.Unlock()
+	}
+
+	le.mu.Lock()
+	defer le.mu.Unlock()
+	for id, tail := range le.tails {
+		if _, ok := want[id]; !ok {
+			tail.cancel()
+			delete(le.tails, id)
+		}
+	}
+	for id := range le.lastSeen {
+		if hasRules && !existing[id] {
+			delete(le.lastSeen, id)
+		}
+	}
+	for id, ct := range want {
+		if _, ok := le.tails[id]; ok {
+			continue
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		tail := &logAlertTail{cancel: cancel}
+		le.tails[id] = tail
+		go le.follow(ctx, tail, ct)
+	}
+}
+
+// applicableRules 返回作用于该容器的规则，规则未变化时复用缓存
+func (le *logAlertEngine) applicableRules(gen int, cached []*compiledLogAlert, name string, labels map[string]string) ([]*compiledLogAlert, int) {
+	le.mu.Lock()
+	defer le.mu.Unlock()
+	if gen == le.gen {
+		return cached, gen
+	}
+	var rules []*compiledLogAlert
+	for _, a := range le.rules {
+		if a.appliesTo(name, labels) {
+			rules = append(rules, a)
+		}
+	}
+	return rules, le.gen
+}
+
+// follow 跟随单个容器的日志直到容器停止或被取消
+func (le *logAlertEngine) follow(ctx context.Context, tail *logAlertTail, ct types.Container) {
+	name := containerDisplayName(ct)
+	defer func() {
+		tail.cancel()
+		le.mu.Lock()
+		if le.tails[ct.ID] == tail {
+			delete(le.tails, ct.ID)
+		}
+		le.mu.Unlock()
+	}()
+
+	// 只评估新产生的日志：从容器创建或引擎启动（取较晚者）开始，重新跟随时从上次处理的位置继续
+	le.mu.Lock()
+	since := time.Unix(ct.Created, 0)
+	if since.Before(le.started) {
+		since = le.started
+	}
+	if last, ok := le.lastSeen[ct.ID]; ok && !last.Before(since) {
+		since = last.Add(time.Nanosecond)
+	}
+	le.mu.Unlock()
+
+	cli, err := docker.NewDockerClient()
+	if err != nil {
+		return
+	}
+	defer cli.Close()
+	info, err := cli.ContainerInspect(ctx, ct.ID)
+	if err != nil {
+		return
+	}
+	src := containerLogSource(info)
+	streams, err := openLogStreams(ctx, cli, []logSource{src}, types.ContainerLogsOptions{Follow: true, Since: dockerLogTime(since)})
+	if err != nil {
+		if ctx.Err() == nil {
+			log.Printf("日志告警跟随容器 %s 失败: %v", name, err)
+		}
+		return
+	}
+	defer streams[0].Close()
+
+	source := logAlertSourceKey(name, ct.Labels)
+	gen := -1
+	var rules []*compiledLogAlert
+	_ = decodeContainerLogs(streams[0], src, func(e *logEntry) bool {
+		if ctx.Err() != nil {
+			return false
+		}
+		rules, gen = le.applicableRules(gen, rules, name, ct.Labels)
+		for _, a := range rules {
+			if !a.matches(e) {
+				continue
+			}
+			if f := le.tracker.observe(a, source, e); f != nil {
+				go fireLogAlert(a.rule, src, ct.Labels["com.docker.compose.project"], f)
+			}
+		}
+		le.mu.Lock()
+		le.lastSeen[ct.ID] = e.Time
+		le.mu.Unlock()
+		return true
+	})
+}
+
+// logAlertMessage 生成通知文本，附带最近一条匹配的日志
+func logAlertMessage(r database.LogAlertRule, src logSource, f *logAlertFiring) string {
+	var b strings.Builder
+	if r.Threshold <= 1 {
+		fmt.Fprintf(&b, "日志告警「%s」：容器 %s 出现匹配的日志", r.Name, src.Name)
+	} else {
+		fmt.Fprintf(&b, "日志告警「%s」：容器 %s 在 %s 内匹配 %d 次", r.Name, src.Name,
+			time.Duration(r.WindowSeconds)*time.Second, f.Count)
+	}
+	if f.Suppressed > 0 {
+		fmt.Fprintf(&b, "（冷却期内另有 %d 次匹配）", f.Suppressed)
+	}
+	if n := len(f.Excerpt); n > 0 {
+		b.WriteString("：")
+		b.WriteString(f.Excerpt[n-1])
+	}
+	return b.String()
+}
+
+func fireLogAlert(r database.LogAlertRule, src logSource, project string, f *logAlertFiring) {
+	msg := logAlertMessage(r, src, f)
+	log.Print(msg)
+	_ = database.SaveNotification(&database.Notification{Type: "warning", Message: msg})
+	if err := database.AddLogAlertEvent(&database.LogAlertEvent{
+		RuleID:        r.ID,
+		RuleName:      r.Name,
+		ContainerName: src.Name,
+		Project:       project,
+		Service:       src.Service,
+		MatchCount:    f.Count,
+		Suppressed:    f.Suppressed,
+		Excerpt:       strings.Join(f.Excerpt, "\n"),
+	}); err != nil {
+		log.Printf("保存日志告警记录失败: %v", err)
+	}
+}
+
+// listLogAlertRules 列出日志告警规则
+func listLogAlertRules(c *gin.Context) {
+	list, err := database.ListLogAlertRules()
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "获取日志告警规则失败", err)
+		return
+	}
+	c.JSON(http.StatusOK, list)
+}
+
+// bindLogAlertRule 将请求中的规则合并到 base 并校验：新建时 base 为默认规则，修改时为已保存的规则，
+// 请求中未提供的字段（如 enabled）保持 base 的值
+func bindLogAlertRule(c *gin.Context, base database.LogAlertRule) (database.LogAlertRule, bool) {
+	r := base
+	if err := c.ShouldBindJSON(&r); err != nil {
+		respondError(c, http.StatusBadRequest, "无效的请求参数", err)
+		return r, false
+	}
+	r.ID = base.ID
+	normalizeLogAlertRule(&r)
+	_, errs := compileLogAlertRule(r)
+	if r.Name != "" {
+		if existing, err := database.GetLogAlertRuleByName(r.Name); err == nil && existing.ID != r.ID {
+			errs.add("name", "规则名称已存在")
+		}
+	}
+	if len(errs) > 0 {
+		respondFieldErrors(c, errs)
+		return r, false
+	}
+	return r, true
+}
+
+// createLogAlertRule 新建日志告警规则
+func createLogAlertRule(c *gin.Context) {
+	r, ok := bindLogAlertRule(c, defaultLogAlertRule())
+	if !ok {
+		return
+	}
+	if err := database.SaveLogAlertRule(&r); err != nil {
+		respondError(c, http.StatusInternalServerError, "保存日志告警规则失败", err)
+		return
+	}
+	logAlerts.reload()
+	c.JSON(http.StatusOK, r)
+}
+
+// updateLogAlertRule 修改日志告警规则，修改后重新开始计数
+func updateLogAlertRule(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, "无效的规则 ID", err)
+		return
+	}
+	stored, err := database.GetLogAlertRule(id)
+	if err != nil {
+		respondError(c, http.StatusNotFound, "日志告警规则不存在", err)
+		return
+	}
+	r, ok := bindLogAlertRule(c, stored)
+	if !ok {
+		return
+	}
+	if err := database.SaveLogAlertRule(&r); err != nil {
+		respondError(c, http.StatusInternalServerError, "保存日志告警规则失败", err)
+		return
+	}
+	logAlerts.tracker.forget(id)
+	logAlerts.reload()
+	c.JSON(http.StatusOK, r)
+}
+
+// deleteLogAlertRule 删除日志告警规则及其告警记录
+func deleteLogAlertRule(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, "无效的规则 ID", err)
+		return
+	}
+	if err := database.DeleteLogAlertRule(id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			respondError(c, http.StatusNotFound, "日志告警规则不存在", nil)
+			return
+		}
+		respondError(c, http.StatusInternalServerError, "删除日志告警规则失败", err)
+		return
+	}
+	logAlerts.tracker.forget(id)
+	logAlerts.reload()
+	c.JSON(http.StatusOK, gin.H{"message": "日志告警规则已删除"})
+}
+
+// listLogAlertEvents 列出最近触发的日志告警（?rule=&limit=）
+func listLogAlertEvents(c *gin.Context) {
+	ruleID, _ := strconv.ParseInt(c.Query("rule"), 10, 64)
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	list, err := database.ListLogAlertEvents(ruleID, limit)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "获取日志告警记录失败", err)
+		return
+	}
+	c.JSON(http.StatusOK, list)
+}
@@ -0,0 +1,159 @@
+package api
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"dockerpanel/backend/pkg/database"
+)
+
+func mustCompileLogAlert(t *testing.T, r database.LogAlertRule) *compiledLogAlert {
+	t.Helper()
+	normalizeLogAlertRule(&r)
+	a, errs := compileLogAlertRule(r)
+	if len(errs) > 0 {
+		t.Fatalf("compile %+v: %v", r, errs)
+	}
+	return a
+}
+
+func TestCompileLogAlertRuleValidation(t *testing.T) {
+	r := database.LogAlertRule{Name: " ", Pattern: "(", Levels: []string{"loud"}, Threshold: -1, WindowSeconds: 5, Labels: []string{"=x"}}
+	normalizeLogAlertRule(&r)
+	_, errs := compileLogAlertRule(r)
+	fields := map[string]bool{}
+	for _, e := range errs {
+		fields[e.Field] = true
+	}
+	for _, want := range []string{"name", "pattern", "level", "threshold", "windowSeconds", "labels[0]"} {
+		if !fields[want] {
+			t.Errorf("missing error for %s: %v", want, errs)
+		}
+	}
+
+	r = database.LogAlertRule{Name: "empty"}
+	normalizeLogAlertRule(&r)
+	if _, errs := compileLogAlertRule(r); len(errs) != 1 || errs[0].Field != "pattern" {
+		t.Fatalf("rule without conditions: %v", errs)
+	}
+	if r.Threshold != 1 || r.WindowSeconds != logAlertDefaultWindow {
+		t.Fatalf("defaults not applied: %+v", r)
+	}
+
+	// 未提供冷却时间时使用默认值，显式的 0 表示不冷却
+	r = defaultLogAlertRule()
+	if err := json.Unmarshal([]byte(`{"name":"a","levels":["error"]}`), &r); err != nil {
+		t.Fatal(err)
+	}
+	normalizeLogAlertRule(&r)
+	if r.CooldownSeconds != logAlertDefaultCooldown {
+		t.Fatalf("default cooldown: %d", r.CooldownSeconds)
+	}
+	r = defaultLogAlertRule()
+	if err := json.Unmarshal([]byte(`{"name":"a","levels":["error"],"cooldownSeconds":0}`), &r); err != nil {
+		t.Fatal(err)
+	}
+	normalizeLogAlertRule(&r)
+	if _, errs := compileLogAlertRule(r); len(errs) > 0 || r.CooldownSeconds != 0 {
+		t.Fatalf("explicit zero cooldown: %d %v", r.CooldownSeconds, errs)
+	}
+
+	// 修改时以已保存的规则为基础，未提供 enabled 时不会重新启用已停用的规则
+	stored := database.LogAlertRule{ID: 3, Name: "a", Levels: []string{"error"}, CooldownSeconds: 60}
+	r = stored
+	if err := json.Unmarshal([]byte(`{"name":"a","levels":["warn"]}`), &r); err != nil {
+		t.Fatal(err)
+	}
+	if r.Enabled || r.CooldownSeconds != 60 || r.Levels[0] != "warn" {
+		t.Fatalf("update should keep stored fields: %+v", r)
+	}
+}
+
+func TestLogAlertAppliesTo(t *testing.T) {
+	a := mustCompileLogAlert(t, database.LogAlertRule{
+		Name: "api errors", Levels: []string{"error"},
+		Projects: []string{"shop"}, Services: []string{"api", "worker"}, Labels: []string{"tier=backend"},
+	})
+	labels := map[string]string{
+		"com.docker.compose.project": "shop",
+		"com.docker.compose.service": "api",
+		"tier":                       "backend",
+	}
+	if !a.appliesTo("shop-api-1", labels) {
+		t.Fatal("expected rule to apply")
+	}
+	labels["com.docker.compose.service"] = "web"
+	if a.appliesTo("shop-web-1", labels) {
+		t.Fatal("service mismatch should not apply")
+	}
+
+	byName := mustCompileLogAlert(t, database.LogAlertRule{Name: "panics", Pattern: `panic:`, Containers: []string{"shop-*"}})
+	if !byName.appliesTo("shop-api-2", nil) || byName.appliesTo("blog", nil) {
+		t.Fatal("container glob mismatch")
+	}
+	if logAlertSourceKey("shop-api-2", map[string]string{"com.docker.compose.project": "shop", "com.docker.compose.service": "api"}) != "shop/api" {
+		t.Fatal("compose containers should be keyed by service")
+	}
+}
+
+func TestLogAlertMatches(t *testing.T) {
+	a := mustCompileLogAlert(t, database.LogAlertRule{Name: "db", Pattern: `(?i)connection refused`, Fields: []string{"component=db"}})
+	src := logSource{Name: "api"}
+	if !a.matches(newLogEntry(src, "stderr", time.Now(), `{"level":"error","component":"db","msg":"Connection refused"}`)) {
+		t.Fatal("expected match")
+	}
+	if a.matches(newLogEntry(src, "stderr", time.Now(), `{"level":"error","component":"cache","msg":"connection refused"}`)) {
+		t.Fatal("field condition should fail")
+	}
+}
+
+func TestLogAlertTrackerThresholdAndCooldown(t *testing.T) {
+	a := mustCompileLogAlert(t, database.LogAlertRule{
+		ID: 7, Name: "errors", Levels: []string{"error"}, Threshold: 3, WindowSeconds: 60, CooldownSeconds: 300,
+	})
+	tr := newLogAlertTracker()
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	hit := func(sec int, msg string) *logAlertFiring {
+		return tr.observe(a, "shop/api", &logEntry{Time: base.Add(time.Duration(sec) * time.Second), Message: msg})
+	}
+
+	// 超出窗口的匹配不累计
+	if hit(0, "a") != nil || hit(30, "b") != nil || hit(100, "c") != nil || hit(110, "d") != nil {
+		t.Fatal("fired before reaching threshold within window")
+	}
+	f := hit(120, "e")
+	if f == nil || f.Count != 3 || strings.Join(f.Excerpt, "") != "cde" {
+		t.Fatalf("expected firing with 3 matches, got %+v", f)
+	}
+
+	// 冷却期内只计数
+	for i := 0; i < 5; i++ {
+		if hit(130+i, "x") != nil {
+			t.Fatal("fired during cooldown")
+		}
+	}
+	if hit(430, "p") != nil || hit(431, "q") != nil {
+		t.Fatal("fired before threshold after cooldown")
+	}
+	f = hit(432, "r")
+	if f == nil || f.Suppressed != 5 {
+		t.Fatalf("expected suppressed count 5, got %+v", f)
+	}
+
+	tr.forget(7)
+	if len(tr.counters) != 0 {
+		t.Fatal("forget should drop counters")
+	}
+}
+
+func TestLogAlertMessage(t *testing.T) {
+	r := database.LogAlertRule{Name: "errors", Threshold: 20, WindowSeconds: 300}
+	msg := logAlertMessage(r, logSource{Name: "shop-api-1"}, &logAlertFiring{Count: 20, Suppressed: 2, Excerpt: []string{"x", "last line"}})
+	for _, want := range []string{"errors", "shop-api-1", "5m0s", "20 次", "另有 2 次", "last line"} {
+		if !strings.Contains(msg, want) {
+			t.Errorf("message %q missing %q", msg, want)
+		}
+	}
+}
@@ -0,0 +1,452 @@
+package api
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"dockerpanel/backend/pkg/docker"
+	"dockerpanel/backend/pkg/logarchive"
+	"dockerpanel/backend/pkg/settings"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/pkg/stdcopy"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	logArchiveSettingKey     = "log_archive_config"
+	logArchiveReconcileEvery = 15 * time.Second
+	logArchiveFlushEvery     = 2 * time.Second
+	logArchivePruneEvery     = time.Hour
+	logArchiveMaxLineBytes   = 64 * 1024
+)
+
+// LogArchiveConfig 日志归档配置：选中的容器为 containers、projects、labels 三者的并集
+type LogArchiveConfig struct {
+	Enabled       bool     `json:"enabled"`
+	Containers    []string `json:"containers"` // 容器名称
+	Projects      []string `json:"projects"`   // compose 项目名
+	Labels        []string `json:"labels"`     // 标签选择器，"key" 或 "key=value"，需同时满足
+	RetentionDays int      `json:"retentionDays"`
+	MaxFileMB     int      `json:"maxFileMB"`
+}
+
+func (cfg LogArchiveConfig) storeOptions() logarchive.Options {
+	return logarchive.Options{MaxFileBytes: int64(cfg.MaxFileMB) * 1024 * 1024, RetentionDays: cfg.RetentionDays}
+}
+
+// selects 判断容器是否需要归档
+func (cfg LogArchiveConfig) selects(name string, labels map[string]string) bool {
+	if !cfg.Enabled {
+		return false
+	}
+	for _, n := range cfg.Containers {
+		if strings.TrimPrefix(strings.TrimSpace(n), "/") == name {
+			return true
+		}
+	}
+	project := labels["com.docker.compose.project"]
+	for _, p := range cfg.Projects {
+		if project != "" && strings.TrimSpace(p) == project {
+			return true
+		}
+	}
+	return len(cfg.Labels) > 0 && matchesLabelSelectors(labels, cfg.Labels)
+}
+
+func loadLogArchiveConfig() LogArchiveConfig {
+	var cfg LogArchiveConfig
+	raw, err := settings.GetValue(logArchiveSettingKey)
+	if err != nil || strings.TrimSpace(raw) == "" {
+		return cfg
+	}
+	_ = json.Unmarshal([]byte(raw), &cfg)
+	return cfg
+}
+
+// logArchiveCollector 为选中的容器维护日志跟随协程
+type logArchiveCollector struct {
+	mu    sync.Mutex
+	store *logarchive.Store
+	cfg   LogArchiveConfig
+	tails map[string]*archiveTail // key: 容器 ID
+	// drained 已停止且日志已采集完的容器，值为其 FinishedAt；容器再次运行并停止后会重新采集
+	drained map[string]string
+}
+
+type archiveTail struct {
+	cancel context.CancelFunc
+}
+
+var archiveCollector = &logArchiveCollector{tails: make(map[string]*archiveTail), drained: make(map[string]string)}
+
+func logArchiveRoot() string {
+	return filepath.Join(settings.GetDataDir(), "logarchive")
+}
+
+// StartLogArchiveCollector 启动日志归档采集（未启用时仅定期检查配置）
+func StartLogArchiveCollector() {
+	cfg := loadLogArchiveConfig()
+	archiveCollector.mu.Lock()
+	archiveCollector.cfg = cfg
+	archiveCollector.store = logarchive.NewStore(logArchiveRoot(), cfg.storeOptions())
+	archiveCollector.mu.Unlock()
+
+	go func() {
+		reconcile := time.NewTicker(logArchiveReconcileEvery)
+		flush := time.NewTicker(logArchiveFlushEvery)
+		prune := time.NewTicker(logArchivePruneEvery)
+		defer reconcile.Stop()
+		defer flush.Stop()
+		defer prune.Stop()
+
+		archiveCollector.reconcile()
+		for {
+			select {
+			case <-reconcile.C:
+				archiveCollector.reconcile()
+			case <-flush.C:
+				if err := archiveCollector.store.Flush(); err != nil {
+					log.Printf("日志归档写入失败: %v", err)
+				}
+			case <-prune.C:
+				if n, err := archiveCollector.store.Prune(time.Now()); err != nil {
+					log.Printf("清理过期日志归档失败: %v", err)
+				} else if n > 0 {
+					log.Printf("已清理 %d 个过期日志归档文件", n)
+				}
+			}
+		}
+	}()
+}
+
+// applyConfig 更新配置并立即重新选择容器
+func (lc *logArchiveCollector) applyConfig(cfg LogArchiveConfig) {
+	lc.mu.Lock()
+	lc.cfg = cfg
+	if lc.store != nil {
+		lc.store.SetOptions(cfg.storeOptions())
+	}
+	lc.mu.Unlock()
+	go lc.reconcile()
+}
+
+// reconcile 对比容器与配置，启动新的跟随、停止不再需要的跟随。
+// 包含已停止的容器：两次检查之间启动又退出的容器也能补采剩余日志
+func (lc *logArchiveCollector) reconcile() {
+	lc.mu.Lock()
+	cfg := lc.cfg
+	lc.mu.Unlock()
+
+	want := make(map[string]types.Container)
+	exists := make(map[string]bool)
+	if cfg.Enabled {
+		cli, err := docker.NewDockerClient()
+		if err != nil {
+			log.Printf("日志归档连接 Docker 失败: %v", err)
+			return
+		}
+		list, err := cli.ContainerList(context.Background(), types.ContainerListOptions{All: true})
+		if err != nil {
+			cli.Close()
+			log.Printf("日志归档获取容器列表失败: %v", err)
+			return
+		}
+		for _, ct := range list {
+			exists[ct.ID] = true
+			name := containerDisplayName(ct)
+			if isSelfOrProtectedContainer(ct.ID, name, ct.Image, ct.Labels) {
+				continue
+			}
+			if !cfg.selects(name, ct.Labels) {
+				continue
+			}
+			if ct.State != "running" && lc.isDrained(cli, ct.ID) {
+				continue
+			}
+			want[ct.ID] = ct
+		}
+		cli.Close()
+	}
+
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+	for id := range lc.drained {
+		if !exists[id] {
+			delete(lc.drained, id)
+		}
+	}
+	for id, tail := range lc.tails {
+		if _, ok := want[id]; !ok {
+			tail.cancel()
+			delete(lc.tails, id)
+		}
+	}
+	for id, ct := range want {
+		if _, ok := lc.tails[id]; ok {
+			continue
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		tail := &archiveTail{cancel: cancel}
+		lc.tails[id] = tail
+		go lc.follow(ctx, tail, ct)
+	}
+}
+
+// isDrained 判断已停止的容器自上次停止后的日志是否已采集完
+func (lc *logArchiveCollector) isDrained(cli *docker.Client, id string) bool {
+	lc.mu.Lock()
+	finished, ok := lc.drained[id]
+	lc.mu.Unlock()
+	if !ok {
+		return false
+	}
+	info, err := cli.ContainerInspect(context.Background(), id)
+	return err == nil && info.State != nil && !info.State.Running && info.State.FinishedAt == finished
+}
+
+// markDrained 日志流自然结束且容器已停止时记录，避免每次检查都重新读取
+func (lc *logArchiveCollector) markDrained(cli *docker.Client, id string) {
+	info, err := cli.ContainerInspect(context.Background(), id)
+	if err != nil || info.State == nil || info.State.Running {
+		return
+	}
+	lc.mu.Lock()
+	lc.drained[id] = info.State.FinishedAt
+	lc.mu.Unlock()
+}
+
+// follow 跟随单个容器的日志直到容器停止或被取消，从归档中最后一条日志之后继续；
+// 容器已停止时读取剩余日志后结束
+func (lc *logArchiveCollector) follow(ctx context.Context, tail *archiveTail, ct types.Container) {
+	name := containerDisplayName(ct)
+	project := ct.Labels["com.docker.compose.project"]
+	defer func() {
+		tail.cancel()
+		lc.mu.Lock()
+		// 只移除自己的记录，避免误删同一容器新启动的跟随
+		if lc.tails[ct.ID] == tail {
+			delete(lc.tails, ct.ID)
+		}
+		lc.mu.Unlock()
+		_ = lc.store.CloseSource(project, name)
+	}()
+
+	cli, err := docker.NewDockerClient()
+	if err != nil {
+		return
+	}
+	defer cli.Close()
+
+	info, err := cli.ContainerInspect(ctx, ct.ID)
+	if err != nil {
+		return
+	}
+	opts := types.ContainerLogsOptions{ShowStdout: true, ShowStderr: true, Follow: true, Timestamps: true}
+	if last := lc.store.LastTime(project, name); !last.IsZero() {
+		next := last.Add(time.Nanosecond)
+		opts.Since = fmt.Sprintf("%d.%09d", next.Unix(), next.Nanosecond())
+	} else {
+		opts.Tail = "1000"
+	}
+	rc, err := cli.ContainerLogs(ctx, ct.ID, opts)
+	if err != nil {
+		log.Printf("日志归档读取容器 %s 日志失败: %v", name, err)
+		return
+	}
+	defer rc.Close()
+
+	base := logarchive.Record{ContainerID: ct.ID, Container: name, Project: project}
+	defer func() {
+		if ctx.Err() == nil {
+			lc.markDrained(cli, ct.ID)
+		}
+	}()
+	if info.Config != nil && info.Config.Tty {
+		lc.consume(rc, base, "stdout")
+		return
+	}
+
+	outR, outW := io.Pipe()
+	errR, errW := io.Pipe()
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() { defer wg.Done(); lc.consume(outR, base, "stdout") }()
+	go func() { defer wg.Done(); lc.consume(errR, base, "stderr") }()
+	_, _ = stdcopy.StdCopy(outW, errW, rc)
+	_ = outW.Close()
+	_ = errW.Close()
+	wg.Wait()
+}
+
+func (lc *logArchiveCollector) consume(r io.Reader, base logarchive.Record, stream string) {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 0, 64*1024), logArchiveMaxLineBytes)
+	for sc.Scan() {
+		rec := base
+		rec.Stream = stream
+		rec.Time, rec.Message = splitDockerTimestamp(sc.Text())
+		if strings.TrimSpace(rec.Message) == "" {
+			continue
+		}
+		rec.Level = logarchive.DetectLevel(rec.Message)
+		if err := lc.store.Append(rec); err != nil {
+			log.Printf("写入日志归档失败: %v", err)
+			break
+		}
+	}
+	// 超长行或写入失败会导致提前结束，继续读取以免阻塞 stdcopy
+	_, _ = io.Copy(io.Discard, r)
+}
+
+// splitDockerTimestamp 拆分 Timestamps=true 时每行开头的 RFC3339Nano 时间
+func splitDockerTimestamp(line string) (time.Time, string) {
+	line = strings.TrimRight(line, "\r")
+	if ts, rest, ok := strings.Cut(line, " "); ok {
+		if t, err := time.Parse(time.RFC3339Nano, ts); err == nil {
+			return t, rest
+		}
+	}
+	return time.Now(), line
+}
+
+// getLogArchiveConfig 获取日志归档配置与当前正在采集的容器数
+func getLogArchiveConfig(c *gin.Context) {
+	archiveCollector.mu.Lock()
+	cfg := archiveCollector.cfg
+	active := len(archiveCollector.tails)
+	archiveCollector.mu.Unlock()
+	c.JSON(http.StatusOK, gin.H{"config": cfg, "active": active})
+}
+
+// updateLogArchiveConfig 保存日志归档配置并立即生效
+func updateLogArchiveConfig(c *gin.Context) {
+	var cfg LogArchiveConfig
+	if err := c.ShouldBindJSON(&cfg); err != nil {
+		respondError(c, http.StatusBadRequest, "无效的请求参数", err)
+		return
+	}
+	var errs fieldErrors
+	if cfg.RetentionDays < 0 || cfg.RetentionDays > 3650 {
+		errs.add("retentionDays", "保留天数取值范围为 0-3650，0 表示默认 %d 天", logarchive.DefaultRetentionDays)
+	}
+	if cfg.MaxFileMB < 0 || cfg.MaxFileMB > 1024 {
+		errs.add("maxFileMB", "单个文件大小取值范围为 0-1024 MB，0 表示默认值")
+	}
+	for i, sel := range cfg.Labels {
+		if key, _, _ := strings.Cut(strings.TrimSpace(sel), "="); strings.TrimSpace(key) == "" {
+			errs.add(fmt.Sprintf("labels[%d]", i), "标签选择器格式应为 key 或 key=value")
+		}
+	}
+	if len(errs) > 0 {
+		respondFieldErrors(c, errs)
+		return
+	}
+
+	raw, _ := json.Marshal(cfg)
+	if err := settings.SetValue(logArchiveSettingKey, string(raw)); err != nil {
+		respondError(c, http.StatusInternalServerError, "保存日志归档配置失败", err)
+		return
+	}
+	archiveCollector.applyConfig(cfg)
+	c.JSON(http.StatusOK, gin.H{"message": "日志归档配置已保存", "config": cfg})
+}
+
+// listLogArchiveSources 列出已归档的容器及占用空间
+func listLogArchiveSources(c *gin.Context) {
+	if archiveCollector.store == nil {
+		c.JSON(http.StatusOK, []logarchive.Source{})
+		return
+	}
+	list, err := archiveCollector.store.Sources()
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "获取日志归档失败", err)
+		return
+	}
+	c.JSON(http.StatusOK, list)
+}
+
+// parseArchiveTime 支持 RFC3339 与 Unix 秒
+func parseArchiveTime(raw string) (time.Time, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return time.Time{}, nil
+	}
+	if n, err := strconv.ParseInt(raw, 10, 64); err == nil {
+		return time.Unix(n, 0), nil
+	}
+	return time.Parse(time.RFC3339, raw)
+}
+
+func splitQueryList(c *gin.Context, key string) []string {
+	var out []string
+	for _, raw := range c.QueryArray(key) {
+		for _, v := range strings.Split(raw, ",") {
+			if v = strings.TrimSpace(v); v != "" {
+				out = append(out, v)
+			}
+		}
+	}
+	return out
+}
+
+// searchLogArchive 检索归档日志
+// ?since=&until=（RFC3339 或 Unix 秒）&container=a,b&project=&level=error,warn&stream=stderr&q=文本&regex=表达式&limit=
+func searchLogArchive(c *gin.Context) {
+	if archiveCollector.store == nil {
+		c.JSON(http.StatusOK, logarchive.Result{Records: []logarchive.Record{}})
+		return
+	}
+	var errs fieldErrors
+	q := logarchive.Query{
+		Containers: splitQueryList(c, "container"),
+		Projects:   splitQueryList(c, "project"),
+		Levels:     splitQueryList(c, "level"),
+		Streams:    splitQueryList(c, "stream"),
+		Text:       c.Query("q"),
+	}
+	var err error
+	if q.Since, err = parseArchiveTime(c.Query("since")); err != nil {
+		errs.add("since", "时间格式应为 RFC3339 或 Unix 秒")
+	}
+	if q.Until, err = parseArchiveTime(c.Query("until")); err != nil {
+		errs.add("until", "时间格式应为 RFC3339 或 Unix 秒")
+	}
+	if expr := c.Query("regex"); expr != "" {
+		if q.Regex, err = regexp.Compile(expr); err != nil {
+			errs.add("regex", "正则表达式不合法: %v", err)
+		}
+	}
+	if raw := c.Query("limit"); raw != "" {
+		if q.Limit, err = strconv.Atoi(raw); err != nil || q.Limit < 1 || q.Limit > logarchive.MaxSearchLimit {
+			errs.add("limit", "limit 取值范围为 1-%d", logarchive.MaxSearchLimit)
+		}
+	}
+	for _, lv := range q.Levels {
+		if logarchive.NormalizeLevel(lv) == "" && strings.ToLower(lv) != "none" {
+			errs.add("level", "不支持的级别 %s，可用 error / warn / info / debug / none", lv)
+		}
+	}
+	if len(errs) > 0 {
+		respondFieldErrors(c, errs)
+		return
+	}
+
+	res, err := archiveCollector.store.Search(q)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "检索日志归档失败", err)
+		return
+	}
+	c.JSON(http.StatusOK, res)
+}
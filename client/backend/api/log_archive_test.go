@@ -0,0 +1,45 @@
+package api
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLogArchiveConfigSelects(t *testing.T) {
+	cfg := LogArchiveConfig{
+		Enabled:    true,
+		Containers: []string{"/proxy"},
+		Projects:   []string{"media"},
+		Labels:     []string{"logs.archive=true"},
+	}
+	cases := []struct {
+		name   string
+		labels map[string]string
+		want   bool
+	}{
+		{"proxy", nil, true},
+		{"media-jellyfin-1", map[string]string{"com.docker.compose.project": "media"}, true},
+		{"worker", map[string]string{"logs.archive": "true"}, true},
+		{"worker", map[string]string{"logs.archive": "false"}, false},
+		{"other", map[string]string{"com.docker.compose.project": "shop"}, false},
+	}
+	for _, tc := range cases {
+		if got := cfg.selects(tc.name, tc.labels); got != tc.want {
+			t.Errorf("selects(%s, %v) = %v, want %v", tc.name, tc.labels, got, tc.want)
+		}
+	}
+	cfg.Enabled = false
+	if cfg.selects("proxy", nil) {
+		t.Fatalf("disabled config should select nothing")
+	}
+}
+
+func TestSplitDockerTimestamp(t *testing.T) {
+	ts, msg := splitDockerTimestamp("2024-05-01T23:04:05.123456789Z GET / 200\r")
+	if msg != "GET / 200" || !ts.Equal(time.Date(2024, 5, 1, 23, 4, 5, 123456789, time.UTC)) {
+		t.Fatalf("got %v %q", ts, msg)
+	}
+	if _, msg := splitDockerTimestamp("no timestamp here"); msg != "no timestamp here" {
+		t.Fatalf("msg = %q", msg)
+	}
+}
@@ -0,0 +1,786 @@
+package api
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"dockerpanel/backend/pkg/docker"
+	"dockerpanel/backend/pkg/logarchive"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/pkg/stdcopy"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	logStreamMaxLineBytes = 64 * 1024
+	logDefaultTail        = "200"
+	logMaxTail            = 5000
+	// logMergeDelay 合并多个容器的实时日志时，最多为尚未产生输出的容器等待的时长
+	logMergeDelay         = 300 * time.Millisecond
+	logExportDefaultRange = 24 * time.Hour
+	logExportMaxRange     = 31 * 24 * time.Hour
+)
+
+var errLogReaderStopped = errors.New("log reader stopped")
+
+// logEntry 解析后的一行日志
+type logEntry struct {
+	Time      time.Time      `json:"time"`
+	Container string         `json:"container"`
+	Service   string         `json:"service,omitempty"`
+	Stream    string         `json:"stream"`
+	Message   string         `json:"message"`
+	Format    string         `json:"format,omitempty"` // json / logfmt，纯文本为空
+	Level     string         `json:"level,omitempty"`
+	Msg       string         `json:"msg,omitempty"` // 结构化日志中的 msg / message 字段
+	Fields    map[string]any `json:"fields,omitempty"`
+
+	prefix   string
+	received time.Time
+}
+
+// logSource 一个日志来源容器
+type logSource struct {
+	ID      string `json:"id"`
+	Name    string `json:"name"`
+	Service string `json:"service,omitempty"`
+	Prefix  string `json:"prefix"`
+	TTY     bool   `json:"tty"`
+}
+
+var (
+	structuredLevelKeys   = []string{"level", "lvl", "severity", "loglevel", "log.level", "@l"}
+	structuredMessageKeys = []string{"msg", "message", "@m"}
+)
+
+func newLogEntry(src logSource, stream string, at time.Time, message string) *logEntry {
+	e := &logEntry{
+		Time:      at,
+		Container: src.Name,
+		Service:   src.Service,
+		Stream:    stream,
+		Message:   message,
+		prefix:    src.Prefix,
+	}
+	e.Format, e.Fields = parseStructuredLog(message)
+	for _, key := range structuredLevelKeys {
+		if v, ok := lookupLogField(e.Fields, key); ok {
+			if lv := logarchive.NormalizeLevel(logFieldString(v)); lv != "" {
+				e.Level = lv
+				break
+			}
+		}
+	}
+	for _, key := range structuredMessageKeys {
+		if v, ok := e.Fields[key].(string); ok {
+			e.Msg = v
+			break
+		}
+	}
+	if e.Level == "" {
+		e.Level = logarchive.DetectLevel(message)
+	}
+	return e
+}
+
+// parseStructuredLog 识别 JSON 对象与 logfmt 格式的日志行，其余返回空
+func parseStructuredLog(message string) (string, map[string]any) {
+	s := strings.TrimSpace(message)
+	if strings.HasPrefix(s, "{") && strings.HasSuffix(s, "}") {
+		dec := json.NewDecoder(strings.NewReader(s))
+		dec.UseNumber()
+		var fields map[string]any
+		if dec.Decode(&fields) == nil && len(fields) > 0 {
+			return "json", fields
+		}
+	}
+	if fields := parseLogfmt(s); len(fields) >= 2 {
+		return "logfmt", fields
+	}
+	return "", nil
+}
+
+// parseLogfmt 解析 key=value key2="quoted value" 形式，任意一段不是 key=value 时返回 nil，避免把普通文本误判为 logfmt
+func parseLogfmt(s string) map[string]any {
+	fields := make(map[string]any)
+	for i := 0; i < len(s); {
+		if s[i] == ' ' || s[i] == '\t' {
+			i++
+			continue
+		}
+		start := i
+		for i < len(s) && s[i] != '=' && s[i] != ' ' && s[i] != '\t' && s[i] != '"' {
+			i++
+		}
+		if i == start || i >= len(s) || s[i] != '=' {
+			return nil
+		}
+		key := s[start:i]
+		i++ // '='
+
+		if i < len(s) && s[i] == '"' {
+			end := i + 1
+			for end < len(s) && s[end] != '"' {
+				if s[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(s) {
+				return nil
+			}
+			value, err := strconv.Unquote(s[i : end+1])
+			if err != nil {
+				return nil
+			}
+			fields[key] = value
+			i = end + 1
+			if i < len(s) && s[i] != ' ' && s[i] != '\t' {
+				return nil
+			}
+			continue
+		}
+
+		start = i
+		for i < len(s) && s[i] != ' ' && s[i] != '\t' {
+			i++
+		}
+		fields[key] = s[start:i]
+	}
+	return fields
+}
+
+// lookupLogField 查找字段，支持以 . 访问嵌套的 JSON 对象（如 http.status）
+func lookupLogField(fields map[string]any, key string) (any, bool) {
+	if fields == nil {
+		return nil, false
+	}
+	if v, ok := fields[key]; ok {
+		return v, true
+	}
+	head, rest, ok := strings.Cut(key, ".")
+	if !ok {
+		return nil, false
+	}
+	nested, ok := fields[head].(map[string]any)
+	if !ok {
+		return nil, false
+	}
+	return lookupLogField(nested, rest)
+}
+
+func logFieldString(v any) string {
+	switch t := v.(type) {
+	case string:
+		return t
+	case json.Number:
+		return t.String()
+	case nil:
+		return "null"
+	default:
+		b, _ := json.Marshal(t)
+		return string(b)
+	}
+}
+
+// logEntryFilter 按级别、字段与文本过滤日志
+type logEntryFilter struct {
+	levels map[string]bool
+	fields []logFieldMatch
+	text   string
+}
+
+type logFieldMatch struct {
+	key      string
+	value    string
+	hasValue bool
+}
+
+// parseLogEntryFilter ?level=error,warn&field=key=value&field=key&q=文本
+func parseLogEntryFilter(c *gin.Context, errs *fieldErrors) logEntryFilter {
+	return newLogEntryFilter(splitQueryList(c, "level"), c.QueryArray("field"), c.Query("q"), errs)
+}
+
+// newLogEntryFilter 由级别列表、key / key=value 字段条件与文本构造过滤器，非法条件记录到 errs
+func newLogEntryFilter(levels []string, fields []string, text string, errs *fieldErrors) logEntryFilter {
+	f := logEntryFilter{text: strings.ToLower(strings.TrimSpace(text))}
+	for _, lv := range levels {
+		norm := logarchive.NormalizeLevel(lv)
+		if norm == "" && strings.ToLower(lv) == "none" {
+			norm = "none"
+		}
+		if norm == "" {
+			errs.add("level", "不支持的级别 %s，可用 error / warn / info / debug / none", lv)
+			continue
+		}
+		if f.levels == nil {
+			f.levels = make(map[string]bool)
+		}
+		f.levels[norm] = true
+	}
+	for _, raw := range fields {
+		key, value, hasValue := strings.Cut(strings.TrimSpace(raw), "=")
+		if key = strings.TrimSpace(key); key == "" {
+			errs.add("field", "字段过滤格式应为 key 或 key=value")
+			continue
+		}
+		f.fields = append(f.fields, logFieldMatch{key: key, value: value, hasValue: hasValue})
+	}
+	return f
+}
+
+func (f logEntryFilter) match(e *logEntry) bool {
+	if f.levels != nil {
+		lv := e.Level
+		if lv == "" {
+			lv = "none"
+		}
+		if !f.levels[lv] {
+			return false
+		}
+	}
+	for _, fm := range f.fields {
+		v, ok := lookupLogField(e.Fields, fm.key)
+		if !ok || (fm.hasValue && logFieldString(v) != fm.value) {
+			return false
+		}
+	}
+	if f.text != "" && !strings.Contains(strings.ToLower(e.Message), f.text) {
+		return false
+	}
+	return true
+}
+
+// normalizeLogTail 规范化 tail 参数：all 或 0-logMaxTail 的整数，非法值回退为默认值
+func normalizeLogTail(raw string) string {
+	raw = strings.ToLower(strings.TrimSpace(raw))
+	if raw == "all" {
+		return raw
+	}
+	n, err := strconv.Atoi(raw)
+	if err != nil || n < 0 {
+		return logDefaultTail
+	}
+	if n > logMaxTail {
+		n = logMaxTail
+	}
+	return strconv.Itoa(n)
+}
+
+// dockerLogTime 转换为 Docker 日志接口接受的 Unix 时间戳（秒.纳秒）
+func dockerLogTime(t time.Time) string {
+	return fmt.Sprintf("%d.%09d", t.Unix(), t.Nanosecond())
+}
+
+// logLineWriter 将 stdcopy 的输出按行切分；stdcopy 按帧顺序同步写入，因此 stdout/stderr 的先后顺序得以保留
+type logLineWriter struct {
+	stream string
+	buf    []byte
+	emit   func(stream string, line string) bool
+}
+
+func (w *logLineWriter) Write(p []byte) (int, error) {
+	w.buf = append(w.buf, p...)
+	for {
+		idx := bytes.IndexByte(w.buf, '\n')
+		if idx < 0 {
+			break
+		}
+		line := string(w.buf[:idx])
+		w.buf = w.buf[idx+1:]
+		if !w.emit(w.stream, line) {
+			return 0, errLogReaderStopped
+		}
+	}
+	if len(w.buf) > logStreamMaxLineBytes {
+		line := string(w.buf)
+		w.buf = w.buf[:0]
+		if !w.emit(w.stream, line) {
+			return 0, errLogReaderStopped
+		}
+	}
+	return len(p), nil
+}
+
+func (w *logLineWriter) flush() {
+	if len(w.buf) > 0 {
+		_ = w.emit(w.stream, string(w.buf))
+		w.buf = nil
+	}
+}
+
+// decodeContainerLogs 解析带时间戳的容器日志流并逐行回调，回调返回 false 时停止
+func decodeContainerLogs(rc io.Reader, src logSource, emit func(*logEntry) bool) error {
+	stopped := false
+	onLine := func(stream string, line string) bool {
+		if stopped {
+			return false
+		}
+		at, msg := splitDockerTimestamp(line)
+		if strings.TrimSpace(msg) == "" {
+			return true
+		}
+		if !emit(newLogEntry(src, stream, at, msg)) {
+			stopped = true
+		}
+		return !stopped
+	}
+	stdout := &logLineWriter{stream: "stdout", emit: onLine}
+	stderr := &logLineWriter{stream: "stderr", emit: onLine}
+
+	var err error
+	if src.TTY {
+		_, err = io.Copy(stdout, rc)
+	} else {
+		_, err = stdcopy.StdCopy(stdout, stderr, rc)
+	}
+	stdout.flush()
+	stderr.flush()
+	if stopped || errors.Is(err, errLogReaderStopped) {
+		return nil
+	}
+	return err
+}
+
+// openLogStreams 先打开所有容器的日志流，任一失败时全部关闭，保证出错时仍能返回正常的错误响应
+func openLogStreams(ctx context.Context, cli *docker.Client, sources []logSource, opts types.ContainerLogsOptions) ([]io.ReadCloser, error) {
+	opts.ShowStdout = true
+	opts.ShowStderr = true
+	opts.Timestamps = true
+	streams := make([]io.ReadCloser, 0, len(sources))
+	for _, src := range sources {
+		rc, err := cli.ContainerLogs(ctx, src.ID, opts)
+		if err != nil {
+			for _, s := range streams {
+				_ = s.Close()
+			}
+			return nil, fmt.Errorf("%s: %w", src.Name, err)
+		}
+		streams = append(streams, rc)
+	}
+	return streams, nil
+}
+
+// containerLogSource 单个容器的日志来源
+func containerLogSource(info types.ContainerJSON) logSource {
+	src := logSource{ID: info.ID, Name: strings.TrimPrefix(info.Name, "/")}
+	if info.Config != nil {
+		src.TTY = info.Config.Tty
+		src.Service = info.Config.Labels["com.docker.compose.service"]
+	}
+	src.Prefix = src.Name
+	return src
+}
+
+// listProjectLogSources 列出 compose 项目的容器，services 非空时只保留指定服务
+func listProjectLogSources(ctx context.Context, cli *docker.Client, project string, services []string) ([]logSource, error) {
+	list, err := cli.ContainerList(ctx, types.ContainerListOptions{
+		All:     true,
+		Filters: filters.NewArgs(filters.Arg("label", "com.docker.compose.project="+project)),
+	})
+	if err != nil {
+		return nil, err
+	}
+	wanted := make(map[string]bool, len(services))
+	for _, s := range services {
+		wanted[s] = true
+	}
+
+	var sources []logSource
+	perService := make(map[string]int)
+	for _, ct := range list {
+		name := containerDisplayName(ct)
+		if isSelfOrProtectedContainer(ct.ID, name, ct.Image, ct.Labels) {
+			continue
+		}
+		service := ct.Labels["com.docker.compose.service"]
+		if len(wanted) > 0 && !wanted[service] {
+			continue
+		}
+		src := logSource{ID: ct.ID, Name: name, Service: service}
+		if info, err := cli.ContainerInspect(ctx, ct.ID); err == nil && info.Config != nil {
+			src.TTY = info.Config.Tty
+		}
+		perService[service]++
+		sources = append(sources, src)
+	}
+	// 与 docker compose logs 一致：以服务名作为前缀，同一服务有多个副本时使用容器名区分
+	for i := range sources {
+		sources[i].Prefix = sources[i].Service
+		if sources[i].Prefix == "" || perService[sources[i].Service] > 1 {
+			sources[i].Prefix = sources[i].Name
+		}
+	}
+	sort.Slice(sources, func(i, j int) bool { return sources[i].Prefix < sources[j].Prefix })
+	return sources, nil
+}
+
+// mergeLogEntries 对有限的多个按时间有序的日志来源做多路归并，emit 返回 false 时停止
+func mergeLogEntries(sources []<-chan *logEntry, emit func(*logEntry) bool) {
+	heads := make([]*logEntry, len(sources))
+	for i, ch := range sources {
+		heads[i] = <-ch
+	}
+	for {
+		best := -1
+		for i, h := range heads {
+			if h != nil && (best < 0 || h.Time.Before(heads[best].Time)) {
+				best = i
+			}
+		}
+		if best < 0 || !emit(heads[best]) {
+			return
+		}
+		heads[best] = <-sources[best]
+	}
+}
+
+// logMerger 合并持续输出的多个日志来源：每次取时间最早的一行，
+// 仅当其它仍在运行的来源都已有待输出的行，或该行已等待超过 delay 时才输出
+type logMerger struct {
+	queues [][]*logEntry
+	done   []bool
+	delay  time.Duration
+}
+
+func newLogMerger(n int, delay time.Duration) *logMerger {
+	return &logMerger{queues: make([][]*logEntry, n), done: make([]bool, n), delay: delay}
+}
+
+func (m *logMerger) push(src int, e *logEntry) {
+	m.queues[src] = append(m.queues[src], e)
+}
+
+func (m *logMerger) finish(src int) {
+	m.done[src] = true
+}
+
+// pop 返回下一行可输出的日志，没有时返回 nil
+func (m *logMerger) pop(now time.Time) *logEntry {
+	best := -1
+	for i, q := range m.queues {
+		if len(q) > 0 && (best < 0 || q[0].Time.Before(m.queues[best][0].Time)) {
+			best = i
+		}
+	}
+	if best < 0 {
+		return nil
+	}
+	head := m.queues[best][0]
+	if now.Sub(head.received) < m.delay {
+		for i, q := range m.queues {
+			if i != best && !m.done[i] && len(q) == 0 {
+				return nil
+			}
+		}
+	}
+	m.queues[best][0] = nil
+	m.queues[best] = m.queues[best][1:]
+	return head
+}
+
+// drained 所有来源均已结束且没有待输出的日志
+func (m *logMerger) drained() bool {
+	for i, q := range m.queues {
+		if !m.done[i] || len(q) > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func logPrefixWidth(sources []logSource) int {
+	width := 0
+	for _, s := range sources {
+		if len(s.Prefix) > width {
+			width = len(s.Prefix)
+		}
+	}
+	return width
+}
+
+// streamLogEntries 以 SSE 推送一个或多个容器的日志；format=text 时推送带服务前缀的文本行，否则推送解析后的 JSON
+func streamLogEntries(c *gin.Context, cli *docker.Client, sources []logSource) {
+	var errs fieldErrors
+	filter := parseLogEntryFilter(c, &errs)
+	opts := types.ContainerLogsOptions{Follow: true, Tail: normalizeLogTail(c.DefaultQuery("tail", logDefaultTail))}
+	if raw := c.Query("since"); raw != "" {
+		since, err := parseArchiveTime(raw)
+		if err != nil {
+			errs.add("since", "时间格式应为 RFC3339 或 Unix 秒")
+		} else {
+			opts.Since = dockerLogTime(since)
+		}
+	}
+	if len(errs) > 0 {
+		respondFieldErrors(c, errs)
+		return
+	}
+	textMode := c.Query("format") == "text"
+
+	ctx := c.Request.Context()
+	streams, err := openLogStreams(ctx, cli, sources, opts)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "获取容器日志失败", err)
+		return
+	}
+
+	type sourcedEntry struct {
+		src   int
+		entry *logEntry
+	}
+	in := make(chan sourcedEntry, 256)
+	for i := range sources {
+		go func(i int, rc io.ReadCloser) {
+			defer rc.Close()
+			_ = decodeContainerLogs(rc, sources[i], func(e *logEntry) bool {
+				select {
+				case in <- sourcedEntry{src: i, entry: e}:
+					return true
+				case <-ctx.Done():
+					return false
+				}
+			})
+			select {
+			case in <- sourcedEntry{src: i}:
+			case <-ctx.Done():
+			}
+		}(i, streams[i])
+	}
+
+	setSSEHeaders(c)
+	nextID := sseNextIDFromLastEventID(c)
+	sseWriteJSONEvent(c, nextID, "sources", sources)
+	nextID++
+
+	width := logPrefixWidth(sources)
+	merger := newLogMerger(len(sources), logMergeDelay)
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case it := <-in:
+			if it.entry == nil {
+				merger.finish(it.src)
+			} else {
+				it.entry.received = time.Now()
+				merger.push(it.src, it.entry)
+			}
+		case <-ticker.C:
+		}
+		for e := merger.pop(time.Now()); e != nil; e = merger.pop(time.Now()) {
+			if !filter.match(e) {
+				continue
+			}
+			if textMode {
+				sseWriteStringEvent(c, nextID, "message", fmt.Sprintf("%-*s | %s", width, e.prefix, e.Message))
+			} else {
+				sseWriteJSONEvent(c, nextID, "log", e)
+			}
+			nextID++
+		}
+		if merger.drained() {
+			sseWriteStringEvent(c, nextID, "end", "")
+			return
+		}
+	}
+}
+
+// logExportRequest 日志导出参数
+type logExportRequest struct {
+	since  time.Time
+	until  time.Time
+	jsonl  bool
+	filter logEntryFilter
+}
+
+// parseLogExportRequest ?since=&until=（RFC3339 或 Unix 秒，默认最近 24 小时）&format=text|jsonl 以及过滤参数
+func parseLogExportRequest(c *gin.Context, now time.Time) (logExportRequest, fieldErrors) {
+	var errs fieldErrors
+	req := logExportRequest{until: now}
+	var err error
+	if raw := c.Query("until"); raw != "" {
+		if req.until, err = parseArchiveTime(raw); err != nil {
+			errs.add("until", "时间格式应为 RFC3339 或 Unix 秒")
+		}
+	}
+	req.since = req.until.Add(-logExportDefaultRange)
+	if raw := c.Query("since"); raw != "" {
+		if req.since, err = parseArchiveTime(raw); err != nil {
+			errs.add("since", "时间格式应为 RFC3339 或 Unix 秒")
+		}
+	}
+	if len(errs) == 0 {
+		if !req.since.Before(req.until) {
+			errs.add("since", "开始时间必须早于结束时间")
+		} else if req.until.Sub(req.since) > logExportMaxRange {
+			errs.add("since", "单次导出的时间范围不能超过 %d 天", int(logExportMaxRange/(24*time.Hour)))
+		}
+	}
+	switch c.DefaultQuery("format", "text") {
+	case "text":
+	case "jsonl":
+		req.jsonl = true
+	default:
+		errs.add("format", "导出格式仅支持 text 或 jsonl")
+	}
+	req.filter = parseLogEntryFilter(c, &errs)
+	return req, errs
+}
+
+// writeLogExport 按时间归并多个容器在指定时间范围内的日志，以 gzip 附件输出
+func writeLogExport(c *gin.Context, cli *docker.Client, sources []logSource, baseName string, req logExportRequest) {
+	ctx, cancel := context.WithCancel(c.Request.Context())
+	defer cancel()
+
+	streams, err := openLogStreams(ctx, cli, sources, types.ContainerLogsOptions{
+		Since: dockerLogTime(req.since),
+		Until: dockerLogTime(req.until),
+	})
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "获取容器日志失败", err)
+		return
+	}
+
+	chans := make([]<-chan *logEntry, len(sources))
+	for i := range sources {
+		ch := make(chan *logEntry, 256)
+		chans[i] = ch
+		go func(src logSource, rc io.ReadCloser) {
+			defer close(ch)
+			defer rc.Close()
+			_ = decodeContainerLogs(rc, src, func(e *logEntry) bool {
+				select {
+				case ch <- e:
+					return true
+				case <-ctx.Done():
+					return false
+				}
+			})
+		}(sources[i], streams[i])
+	}
+
+	ext := "log"
+	if req.jsonl {
+		ext = "jsonl"
+	}
+	filename := fmt.Sprintf("%s-logs-%s.%s.gz", baseName, req.since.UTC().Format("20060102T150405Z"), ext)
+	c.Header("Content-Type", "application/gzip")
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	c.Status(http.StatusOK)
+
+	gz := gzip.NewWriter(c.Writer)
+	w := bufio.NewWriter(gz)
+	enc := json.NewEncoder(w)
+	width := logPrefixWidth(sources)
+	mergeLogEntries(chans, func(e *logEntry) bool {
+		if !req.filter.match(e) {
+			return true
+		}
+		if req.jsonl {
+			return enc.Encode(e) == nil
+		}
+		_, err := fmt.Fprintf(w, "%s %-*s | %s\n", e.Time.UTC().Format(time.RFC3339Nano), width, e.prefix, e.Message)
+		return err == nil
+	})
+	_ = w.Flush()
+	_ = gz.Close()
+}
+
+// exportContainerLogs 导出单个容器指定时间范围内的日志
+func exportContainerLogs(c *gin.Context) {
+	id := c.Param("id")
+	if forbidIfSelfContainer(c, id) {
+		return
+	}
+	req, errs := parseLogExportRequest(c, time.Now())
+	if len(errs) > 0 {
+		respondFieldErrors(c, errs)
+		return
+	}
+
+	cli, ok := getDockerClient(c)
+	if !ok {
+		return
+	}
+	defer cli.Close()
+
+	info, err := cli.ContainerInspect(c.Request.Context(), id)
+	if err != nil {
+		respondError(c, http.StatusNotFound, "容器不存在", err)
+		return
+	}
+	src := containerLogSource(info)
+	writeLogExport(c, cli, []logSource{src}, src.Name, req)
+}
+
+// projectLogSources 校验项目名并列出项目容器（?service=a,b 过滤服务），失败时已写入响应
+func projectLogSources(c *gin.Context, cli *docker.Client) (string, []logSource, bool) {
+	name, ok := validateComposeProjectName(c.Param("name"))
+	if !ok {
+		respondError(c, http.StatusBadRequest, "项目名不合法：仅支持小写字母/数字，且可包含 _ -，并以字母或数字开头", nil)
+		return "", nil, false
+	}
+	if forbidIfSelfProject(c, name) {
+		return "", nil, false
+	}
+	sources, err := listProjectLogSources(c.Request.Context(), cli, name, splitQueryList(c, "service"))
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "获取项目容器失败", err)
+		return "", nil, false
+	}
+	if len(sources) == 0 {
+		respondError(c, http.StatusNotFound, "项目没有可查看日志的容器", nil)
+		return "", nil, false
+	}
+	return name, sources, true
+}
+
+// exportComposeLogs 导出整个 compose 项目的日志，各服务按时间归并并带服务前缀
+func exportComposeLogs(c *gin.Context) {
+	req, errs := parseLogExportRequest(c, time.Now())
+	if len(errs) > 0 {
+		respondFieldErrors(c, errs)
+		return
+	}
+
+	cli, ok := getDockerClient(c)
+	if !ok {
+		return
+	}
+	defer cli.Close()
+
+	name, sources, ok := projectLogSources(c, cli)
+	if !ok {
+		return
+	}
+	writeLogExport(c, cli, sources, name, req)
+}
+
+// getComposeMergedLogs 以 SSE 推送 compose 项目各服务按时间交错合并后的日志
+func getComposeMergedLogs(c *gin.Context) {
+	cli, ok := getDockerClient(c)
+	if !ok {
+		return
+	}
+	defer cli.Close()
+
+	if _, sources, ok := projectLogSources(c, cli); ok {
+		streamLogEntries(c, cli, sources)
+	}
+}
@@ -0,0 +1,165 @@
+package api
+
+import (
+	"bytes"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/pkg/stdcopy"
+	"github.com/gin-gonic/gin"
+)
+
+func TestParseStructuredLog(t *testing.T) {
+	format, fields := parseStructuredLog(`{"level":"WARN","msg":"slow query","http":{"status":503},"ms":1200}`)
+	if format != "json" || fields["msg"] != "slow query" {
+		t.Fatalf("json: format=%q fields=%v", format, fields)
+	}
+	if v, ok := lookupLogField(fields, "http.status"); !ok || logFieldString(v) != "503" {
+		t.Fatalf("nested field = %v, %v", v, ok)
+	}
+
+	format, fields = parseStructuredLog(`time=2024-01-02T03:04:05Z level=error msg="connect failed: \"db\"" retry=3`)
+	if format != "logfmt" || fields["msg"] != `connect failed: "db"` || fields["retry"] != "3" {
+		t.Fatalf("logfmt: format=%q fields=%v", format, fields)
+	}
+
+	for _, plain := range []string{
+		"GET /health 200 1.2ms",
+		"listening on port=8080",
+		`a=1 b="unterminated`,
+		"{not json}",
+	} {
+		if format, _ := parseStructuredLog(plain); format != "" {
+			t.Errorf("%q detected as %s", plain, format)
+		}
+	}
+}
+
+func TestNewLogEntryLevel(t *testing.T) {
+	src := logSource{Name: "api-1", Service: "api", Prefix: "api"}
+	cases := map[string]string{
+		`{"severity":"CRITICAL","message":"boom"}`: "error",
+		`level=debug msg=tick`:                     "debug",
+		`2024/01/02 [WARN] disk almost full`:       "warn",
+		`plain text`:                               "",
+	}
+	for msg, want := range cases {
+		if got := newLogEntry(src, "stdout", time.Now(), msg).Level; got != want {
+			t.Errorf("%q level = %q, want %q", msg, got, want)
+		}
+	}
+	if e := newLogEntry(src, "stdout", time.Now(), `{"level":"info","msg":"started"}`); e.Msg != "started" {
+		t.Fatalf("msg = %q", e.Msg)
+	}
+}
+
+func TestLogEntryFilter(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest("GET", "/?level=error,none&field=http.status=503&field=trace_id&q=FAIL", nil)
+	var errs fieldErrors
+	f := parseLogEntryFilter(c, &errs)
+	if len(errs) > 0 {
+		t.Fatalf("unexpected errors: %v", errs)
+	}
+	src := logSource{Name: "web"}
+	match := newLogEntry(src, "stdout", time.Now(), `{"level":"error","msg":"upstream failed","trace_id":"x","http":{"status":503}}`)
+	if !f.match(match) {
+		t.Fatal("expected match")
+	}
+	for _, msg := range []string{
+		`{"level":"info","msg":"upstream failed","trace_id":"x","http":{"status":503}}`,
+		`{"level":"error","msg":"upstream failed","http":{"status":503}}`,
+		`{"level":"error","msg":"upstream failed","trace_id":"x","http":{"status":502}}`,
+		`{"level":"error","msg":"ok","trace_id":"x","http":{"status":503}}`,
+	} {
+		if f.match(newLogEntry(src, "stdout", time.Now(), msg)) {
+			t.Errorf("unexpected match: %s", msg)
+		}
+	}
+
+	c, _ = gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest("GET", "/?level=loud&field==x", nil)
+	errs = nil
+	parseLogEntryFilter(c, &errs)
+	if len(errs) != 2 {
+		t.Fatalf("errors = %v", errs)
+	}
+}
+
+func TestNormalizeLogTail(t *testing.T) {
+	cases := map[string]string{"": "200", "all": "all", "50": "50", "-1": "200", "x": "200", "999999": "5000"}
+	for in, want := range cases {
+		if got := normalizeLogTail(in); got != want {
+			t.Errorf("normalizeLogTail(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestDecodeContainerLogsKeepsStreamOrder(t *testing.T) {
+	var raw bytes.Buffer
+	stdout := stdcopy.NewStdWriter(&raw, stdcopy.Stdout)
+	stderr := stdcopy.NewStdWriter(&raw, stdcopy.Stderr)
+	_, _ = stdout.Write([]byte("2024-01-01T00:00:01Z first\n"))
+	_, _ = stderr.Write([]byte("2024-01-01T00:00:02Z level=error msg=second\n"))
+	_, _ = stdout.Write([]byte("2024-01-01T00:00:03Z third"))
+
+	var got []string
+	err := decodeContainerLogs(&raw, logSource{Name: "web"}, func(e *logEntry) bool {
+		got = append(got, e.Stream+":"+e.Message)
+		return true
+	})
+	want := "stdout:first|stderr:level=error msg=second|stdout:third"
+	if err != nil || strings.Join(got, "|") != want {
+		t.Fatalf("got %v, %v", got, err)
+	}
+}
+
+func TestMergeLogEntries(t *testing.T) {
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	feed := func(prefix string, secs ...int) <-chan *logEntry {
+		ch := make(chan *logEntry, len(secs))
+		for _, s := range secs {
+			ch <- &logEntry{Time: base.Add(time.Duration(s) * time.Second), prefix: prefix}
+		}
+		close(ch)
+		return ch
+	}
+	var got []string
+	mergeLogEntries([]<-chan *logEntry{feed("a", 1, 4, 5), feed("b", 2, 3, 6), feed("c")}, func(e *logEntry) bool {
+		got = append(got, e.prefix)
+		return true
+	})
+	if strings.Join(got, "") != "abbaab" {
+		t.Fatalf("order = %v", got)
+	}
+}
+
+func TestLogMergerWaitsForSlowSource(t *testing.T) {
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	now := base.Add(time.Minute)
+	m := newLogMerger(2, time.Second)
+	m.push(0, &logEntry{Time: base.Add(2 * time.Second), prefix: "a", received: now})
+
+	if e := m.pop(now); e != nil {
+		t.Fatal("should wait for source 1")
+	}
+	m.push(1, &logEntry{Time: base.Add(time.Second), prefix: "b", received: now})
+	if e := m.pop(now); e == nil || e.prefix != "b" {
+		t.Fatalf("expected b, got %+v", e)
+	}
+	if e := m.pop(now); e != nil {
+		t.Fatal("source 1 drained, should wait again")
+	}
+	if e := m.pop(now.Add(2 * time.Second)); e == nil || e.prefix != "a" {
+		t.Fatalf("expected a after delay, got %+v", e)
+	}
+
+	m.finish(0)
+	m.finish(1)
+	if !m.drained() {
+		t.Fatal("expected drained")
+	}
+}
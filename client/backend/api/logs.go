@@ -97,9 +97,10 @@ func getContainerLogsEvents(c *gin.Context) {
 		return
 	}
 
-	tail := strings.TrimSpace(c.DefaultQuery("tail", "200"))
-	if tail == "" {
-		tail = "200"
+	// format=json 时推送解析后的结构化日志，支持按级别与字段过滤
+	if c.Query("format") == "json" {
+		streamLogEntries(c, cli, []logSource{containerLogSource(inspect)})
+		return
 	}
 
 	options := types.ContainerLogsOptions{
@@ -107,7 +108,7 @@ func getContainerLogsEvents(c *gin.Context) {
 		ShowStderr: true,
 		Follow:     true,
 		Timestamps: false,
-		Tail:       tail,
+		Tail:       normalizeLogTail(c.DefaultQuery("tail", logDefaultTail)),
 	}
 
 	logs, err := cli.ContainerLogs(ctx, id, options)
@@ -0,0 +1,564 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"dockerpanel/backend/pkg/cronexpr"
+	"dockerpanel/backend/pkg/database"
+	"dockerpanel/backend/pkg/docker"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/pkg/stdcopy"
+	"github.com/docker/go-units"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	scheduleTickEvery      = 15 * time.Second
+	scheduleDefaultTimeout = 30 * time.Minute
+	scheduleMaxTimeout     = 24 * 60 * 60 // 秒
+	scheduleExecMaxOutput  = 64 << 10
+	schedulePreviewCount   = 5
+)
+
+// scheduledJobActions 支持的操作及其说明
+var scheduledJobActions = map[string]string{
+	"compose_start":     "启动项目",
+	"compose_stop":      "停止项目",
+	"compose_restart":   "重启项目",
+	"compose_update":    "更新项目",
+	"container_start":   "启动容器",
+	"container_stop":    "停止容器",
+	"container_restart": "重启容器",
+	"container_exec":    "在容器中执行命令",
+	"image_prune":       "清理未使用的镜像",
+}
+
+var errScheduledJobRunning = errors.New("定时任务正在执行中")
+
+// scheduledJobTaskType 每个定时任务的执行记录使用独立的任务类型，便于按任务查询历史
+func scheduledJobTaskType(id int64) string {
+	return fmt.Sprintf("scheduled_job:%d", id)
+}
+
+type scheduledNextRun struct {
+	expr string
+	at   time.Time
+}
+
+// jobScheduler 定期检查启用的定时任务并在到期时执行；面板停止期间错过的执行不会补跑
+type jobScheduler struct {
+	mu      sync.Mutex
+	next    map[int64]scheduledNextRun
+	running map[int64]bool
+}
+
+var scheduler = &jobScheduler{
+	next:    make(map[int64]scheduledNextRun),
+	running: make(map[int64]bool),
+}
+
+// StartScheduler 启动定时任务调度
+func StartScheduler() {
+	go func() {
+		ticker := time.NewTicker(scheduleTickEvery)
+		defer ticker.Stop()
+		scheduler.tick(time.Now())
+		for now := range ticker.C {
+			scheduler.tick(now)
+		}
+	}()
+}
+
+// due 根据启用的任务更新各自的下次执行时间，返回已到期的任务
+func (s *jobScheduler) due(jobs []database.ScheduledJob, now time.Time) []database.ScheduledJob {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var due []database.ScheduledJob
+	active := make(map[int64]bool, len(jobs))
+	for _, j := range jobs {
+		if !j.Enabled {
+			continue
+		}
+		sched, err := cronexpr.Parse(j.CronExpr)
+		if err != nil {
+			continue
+		}
+		active[j.ID] = true
+		entry, ok := s.next[j.ID]
+		if !ok || entry.expr != j.CronExpr {
+			s.next[j.ID] = scheduledNextRun{expr: j.CronExpr, at: sched.Next(now)}
+			continue
+		}
+		if entry.at.IsZero() || now.Before(entry.at) {
+			continue
+		}
+		due = append(due, j)
+		s.next[j.ID] = scheduledNextRun{expr: j.CronExpr, at: sched.Next(now)}
+	}
+	for id := range s.next {
+		if !active[id] {
+			delete(s.next, id)
+		}
+	}
+	return due
+}
+
+func (s *jobScheduler) tick(now time.Time) {
+	jobs, err := database.ListScheduledJobs()
+	if err != nil {
+		log.Printf("加载定时任务失败: %v", err)
+		return
+	}
+	for _, j := range s.due(jobs, now) {
+		if _, err := startScheduledJob(j, "定时"); err != nil {
+			log.Printf("定时任务 %s 未执行: %v", j.Name, err)
+		}
+	}
+}
+
+// nextRunAt 返回调度器记录的下次执行时间
+func (s *jobScheduler) nextRunAt(j database.ScheduledJob) *time.Time {
+	if !j.Enabled {
+		return nil
+	}
+	s.mu.Lock()
+	entry, ok := s.next[j.ID]
+	s.mu.Unlock()
+	if !ok || entry.expr != j.CronExpr {
+		sched, err := cronexpr.Parse(j.CronExpr)
+		if err != nil {
+			return nil
+		}
+		entry.at = sched.Next(time.Now())
+	}
+	if entry.at.IsZero() {
+		return nil
+	}
+	return &entry.at
+}
+
+func (s *jobScheduler) isRunning(id int64) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.running[id]
+}
+
+// startScheduledJob 以任务形式执行一次定时任务，进度可通过 /compose/tasks/:id/events 查看；同一任务不会并发执行
+func startScheduledJob(j database.ScheduledJob, trigger string) (string, error) {
+	scheduler.mu.Lock()
+	if scheduler.running[j.ID] {
+		scheduler.mu.Unlock()
+		return "", errScheduledJobRunning
+	}
+	scheduler.running[j.ID] = true
+	scheduler.mu.Unlock()
+
+	taskType := scheduledJobTaskType(j.ID)
+	taskID := fmt.Sprintf("%d", time.Now().UnixNano())
+	startedAt := time.Now()
+	_ = database.UpsertTask(taskID, taskType, "pending")
+	_ = database.MarkScheduledJobRun(j.ID, startedAt, "running", taskID, "")
+
+	go func() {
+		defer func() {
+			scheduler.mu.Lock()
+			delete(scheduler.running, j.ID)
+			scheduler.mu.Unlock()
+		}()
+		seq := int64(0)
+		appendLog := func(level string, message string) {
+			if level == "" {
+				level = "info"
+				if strings.Contains(message, "error") || strings.Contains(message, "Error") {
+					level = "error"
+				}
+			}
+			seq++
+			_ = database.AppendTaskLogWithSeq(taskID, seq, time.Now(), level, message)
+		}
+		_ = database.UpsertTask(taskID, taskType, "running")
+		appendLog("info", fmt.Sprintf("开始执行定时任务 %s：%s %s（触发方式：%s）", j.Name, scheduledJobActions[j.Action], j.Target, trigger))
+
+		timeout := scheduleDefaultTimeout
+		if j.TimeoutSeconds > 0 {
+			timeout = time.Duration(j.TimeoutSeconds) * time.Second
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+		if err := executeScheduledJob(ctx, j, appendLog); err != nil {
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				err = fmt.Errorf("执行超时（%s）: %w", timeout, err)
+			}
+			appendLog("error", err.Error())
+			_ = database.FinishTask(taskID, "error", gin.H{"jobId": j.ID, "trigger": trigger}, err.Error())
+			_ = database.MarkScheduledJobRun(j.ID, startedAt, "error", taskID, err.Error())
+			_ = database.SaveNotification(&database.Notification{
+				Type:    "error",
+				Message: fmt.Sprintf("定时任务 %s 执行失败：%s", j.Name, err.Error()),
+			})
+			return
+		}
+		appendLog("success", "定时任务执行完成")
+		_ = database.FinishTask(taskID, "success", gin.H{"jobId": j.ID, "trigger": trigger}, "")
+		_ = database.MarkScheduledJobRun(j.ID, startedAt, "success", taskID, "")
+	}()
+	return taskID, nil
+}
+
+// executeScheduledJob 执行定时任务的操作
+func executeScheduledJob(ctx context.Context, j database.ScheduledJob, logf func(level, msg string)) error {
+	switch j.Action {
+	case "compose_start", "compose_stop", "compose_restart", "compose_update":
+		return executeScheduledComposeJob(ctx, j, logf)
+	case "image_prune":
+		cli, err := docker.NewDockerClient()
+		if err != nil {
+			return err
+		}
+		defer cli.Close()
+		report, err := cli.ImagesPrune(ctx, filters.Args{})
+		if err != nil {
+			return fmt.Errorf("清理镜像失败: %w", err)
+		}
+		logf("info", fmt.Sprintf("已删除 %d 个镜像层，释放 %s", len(report.ImagesDeleted), units.HumanSize(float64(report.SpaceReclaimed))))
+		return nil
+	}
+
+	cli, err := docker.NewDockerClient()
+	if err != nil {
+		return err
+	}
+	defer cli.Close()
+	info, err := cli.ContainerInspect(ctx, j.Target)
+	if err != nil {
+		return fmt.Errorf("获取容器 %s 失败: %w", j.Target, err)
+	}
+	if info.Config != nil && isSelfOrProtectedContainer(info.ID, info.Name, info.Config.Image, info.Config.Labels) {
+		return errors.New("容器化部署模式下，禁止管理自身容器")
+	}
+	switch j.Action {
+	case "container_start":
+		return cli.ContainerStart(ctx, info.ID, types.ContainerStartOptions{})
+	case "container_stop":
+		timeout := 2
+		return cli.ContainerStop(ctx, info.ID, container.StopOptions{Timeout: &timeout})
+	case "container_restart":
+		return cli.ContainerRestart(ctx, info.ID, container.StopOptions{})
+	case "container_exec":
+		return executeScheduledExec(ctx, cli, info.ID, j.Command, logf)
+	}
+	return fmt.Errorf("不支持的操作: %s", j.Action)
+}
+
+func executeScheduledComposeJob(ctx context.Context, j database.ScheduledJob, logf func(level, msg string)) error {
+	if isSelfProjectName(j.Target) {
+		return errors.New("容器化部署模式下，禁止管理自身项目")
+	}
+	projectDir := filepath.Join(getProjectsBaseDir(), j.Target)
+	if !dirExists(projectDir) {
+		return fmt.Errorf("项目 %s 不存在", j.Target)
+	}
+	mu := composeProjectLock(j.Target)
+	if !mu.TryLock() {
+		return errComposeProjectBusy
+	}
+	defer mu.Unlock()
+
+	output := func(line string) { logf("", line) }
+	switch j.Action {
+	case "compose_start":
+		return runComposeStreamLines(ctx, projectDir, []string{"compose", "up", "-d"}, output)
+	case "compose_stop":
+		return runComposeStreamLines(ctx, projectDir, []string{"compose", "stop", "-t", "2"}, output)
+	case "compose_restart":
+		return runComposeStreamLines(ctx, projectDir, []string{"compose", "restart", "-t", "2"}, output)
+	default:
+		return runComposeUpdate(ctx, projectDir, nil, logf)
+	}
+}
+
+// limitedBuffer 只保留前 max 字节的输出
+type limitedBuffer struct {
+	bytes.Buffer
+	max       int
+	truncated bool
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if room := b.max - b.Len(); room < len(p) {
+		b.truncated = true
+		if room > 0 {
+			b.Buffer.Write(p[:room])
+		}
+		return len(p), nil
+	}
+	return b.Buffer.Write(p)
+}
+
+// executeScheduledExec 在容器中执行命令并将输出写入任务日志，命令退出码非 0 视为失败
+func executeScheduledExec(ctx context.Context, cli *docker.Client, id string, cmd []string, logf func(level, msg string)) error {
+	execResp, err := cli.ContainerExecCreate(ctx, id, types.ExecConfig{
+		Cmd:          cmd,
+		AttachStdout: true,
+		AttachStderr: true,
+	})
+	if err != nil {
+		return fmt.Errorf("创建 exec 失败: %w", err)
+	}
+	attach, err := cli.ContainerExecAttach(ctx, execResp.ID, types.ExecStartCheck{})
+	if err != nil {
+		return fmt.Errorf("执行命令失败: %w", err)
+	}
+	out := &limitedBuffer{max: scheduleExecMaxOutput}
+	done := make(chan struct{})
+	go func() {
+		_, _ = stdcopy.StdCopy(out, out, attach.Reader)
+		close(done)
+	}()
+	select {
+	case <-done:
+		attach.Close()
+	case <-ctx.Done():
+		// 关闭连接只会停止读取输出，容器内的进程不会被终止
+		attach.Close()
+		<-done
+		return ctx.Err()
+	}
+
+	for _, line := range strings.Split(strings.TrimRight(out.String(), "\n"), "\n") {
+		if line != "" {
+			logf("info", line)
+		}
+	}
+	if out.truncated {
+		logf("warning", fmt.Sprintf("输出超过 %d KB，已截断", scheduleExecMaxOutput>>10))
+	}
+	inspect, err := cli.ContainerExecInspect(ctx, execResp.ID)
+	if err != nil {
+		return fmt.Errorf("获取执行结果失败: %w", err)
+	}
+	if inspect.ExitCode != 0 {
+		return fmt.Errorf("命令退出码 %d", inspect.ExitCode)
+	}
+	return nil
+}
+
+type scheduledJobRequest struct {
+	Name           string   `json:"name"`
+	Action         string   `json:"action"`
+	Target         string   `json:"target"`
+	Command        []string `json:"command"`
+	Cron           string   `json:"cron"`
+	Enabled        *bool    `json:"enabled"`
+	TimeoutSeconds int      `json:"timeoutSeconds"`
+}
+
+// applyScheduledJobRequest 校验请求并写入定时任务配置
+func applyScheduledJobRequest(j *database.ScheduledJob, req scheduledJobRequest) fieldErrors {
+	var errs fieldErrors
+	j.Name = strings.TrimSpace(req.Name)
+	if j.Name == "" {
+		errs.add("name", "名称不能为空")
+	} else if len([]rune(j.Name)) > 100 {
+		errs.add("name", "名称不能超过 100 个字符")
+	}
+
+	j.CronExpr = strings.TrimSpace(req.Cron)
+	if _, err := cronexpr.Parse(j.CronExpr); err != nil {
+		errs.add("cron", "%s", err.Error())
+	}
+
+	j.Action = strings.TrimSpace(req.Action)
+	j.Target = strings.TrimSpace(req.Target)
+	j.Command = []string{}
+	switch {
+	case scheduledJobActions[j.Action] == "":
+		errs.add("action", "不支持的操作: %q", req.Action)
+	case strings.HasPrefix(j.Action, "compose_"):
+		if name, ok := validateComposeProjectName(j.Target); !ok {
+			errs.add("target", "项目名不合法")
+		} else if isSelfProjectName(name) {
+			errs.add("target", "容器化部署模式下，禁止管理自身项目")
+		}
+	case strings.HasPrefix(j.Action, "container_"):
+		if j.Target == "" || strings.ContainsAny(j.Target, " \t/") {
+			errs.add("target", "请填写容器名称或 ID")
+		}
+		if j.Action == "container_exec" {
+			if len(req.Command) == 0 || strings.TrimSpace(req.Command[0]) == "" {
+				errs.add("command", "命令不能为空")
+			} else {
+				j.Command = req.Command
+			}
+		}
+	case j.Action == "image_prune":
+		j.Target = ""
+	}
+
+	if req.TimeoutSeconds < 0 || req.TimeoutSeconds > scheduleMaxTimeout {
+		errs.add("timeoutSeconds", "超时时间需在 0-%d 秒之间", scheduleMaxTimeout)
+	}
+	j.TimeoutSeconds = req.TimeoutSeconds
+	if req.Enabled != nil {
+		j.Enabled = *req.Enabled
+	}
+	return errs
+}
+
+type scheduledJobResponse struct {
+	database.ScheduledJob
+	NextRunAt *time.Time `json:"nextRunAt"`
+	Running   bool       `json:"running"`
+}
+
+func newScheduledJobResponse(j database.ScheduledJob) scheduledJobResponse {
+	return scheduledJobResponse{ScheduledJob: j, NextRunAt: scheduler.nextRunAt(j), Running: scheduler.isRunning(j.ID)}
+}
+
+// lookupScheduledJob 解析路径中的定时任务 ID，失败时已写入响应
+func lookupScheduledJob(c *gin.Context) (database.ScheduledJob, bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, "无效的定时任务 ID", err)
+		return database.ScheduledJob{}, false
+	}
+	j, err := database.GetScheduledJob(id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			respondError(c, http.StatusNotFound, "定时任务不存在", nil)
+		} else {
+			respondError(c, http.StatusInternalServerError, "获取定时任务失败", err)
+		}
+		return database.ScheduledJob{}, false
+	}
+	return j, true
+}
+
+func listScheduledJobs(c *gin.Context) {
+	list, err := database.ListScheduledJobs()
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "获取定时任务失败", err)
+		return
+	}
+	out := make([]scheduledJobResponse, 0, len(list))
+	for _, j := range list {
+		out = append(out, newScheduledJobResponse(j))
+	}
+	c.JSON(http.StatusOK, gin.H{"jobs": out, "actions": scheduledJobActions})
+}
+
+func createScheduledJob(c *gin.Context) {
+	var req scheduledJobRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, "无效的请求数据", err)
+		return
+	}
+	j := database.ScheduledJob{Enabled: true}
+	if errs := applyScheduledJobRequest(&j, req); len(errs) > 0 {
+		respondFieldErrors(c, errs)
+		return
+	}
+	if strings.HasPrefix(j.Action, "container_") && isSelfContainerID(j.Target) {
+		respondError(c, http.StatusForbidden, "容器化部署模式下，禁止管理自身容器", nil)
+		return
+	}
+	if err := database.SaveScheduledJob(&j); err != nil {
+		respondError(c, http.StatusInternalServerError, "保存定时任务失败", err)
+		return
+	}
+	c.JSON(http.StatusOK, newScheduledJobResponse(j))
+}
+
+func updateScheduledJob(c *gin.Context) {
+	j, ok := lookupScheduledJob(c)
+	if !ok {
+		return
+	}
+	var req scheduledJobRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, "无效的请求数据", err)
+		return
+	}
+	if errs := applyScheduledJobRequest(&j, req); len(errs) > 0 {
+		respondFieldErrors(c, errs)
+		return
+	}
+	if strings.HasPrefix(j.Action, "container_") && isSelfContainerID(j.Target) {
+		respondError(c, http.StatusForbidden, "容器化部署模式下，禁止管理自身容器", nil)
+		return
+	}
+	if err := database.SaveScheduledJob(&j); err != nil {
+		respondError(c, http.StatusInternalServerError, "保存定时任务失败", err)
+		return
+	}
+	c.JSON(http.StatusOK, newScheduledJobResponse(j))
+}
+
+func deleteScheduledJob(c *gin.Context) {
+	j, ok := lookupScheduledJob(c)
+	if !ok {
+		return
+	}
+	if err := database.DeleteScheduledJob(j.ID); err != nil {
+		respondError(c, http.StatusInternalServerError, "删除定时任务失败", err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "定时任务已删除"})
+}
+
+// runScheduledJobNow 立即执行一次定时任务（不影响下次计划时间）
+func runScheduledJobNow(c *gin.Context) {
+	j, ok := lookupScheduledJob(c)
+	if !ok {
+		return
+	}
+	taskID, err := startScheduledJob(j, "手动")
+	if err != nil {
+		respondError(c, http.StatusConflict, err.Error(), nil)
+		return
+	}
+	c.JSON(http.StatusAccepted, gin.H{
+		"message":    "任务已提交",
+		"taskId":     taskID,
+		"eventsPath": "/api/compose/tasks/" + taskID + "/events",
+	})
+}
+
+// listScheduledJobRuns 列出定时任务的执行记录
+func listScheduledJobRuns(c *gin.Context) {
+	j, ok := lookupScheduledJob(c)
+	if !ok {
+		return
+	}
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	list, err := database.ListTasks([]string{scheduledJobTaskType(j.ID)}, nil, limit)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "获取执行记录失败", err)
+		return
+	}
+	c.JSON(http.StatusOK, list)
+}
+
+// previewSchedule 校验 cron 表达式并返回接下来的几次执行时间
+func previewSchedule(c *gin.Context) {
+	sched, err := cronexpr.Parse(c.Query("cron"))
+	if err != nil {
+		respondError(c, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"cron": sched.String(), "nextRuns": sched.NextN(time.Now(), schedulePreviewCount)})
+}
@@ -0,0 +1,100 @@
+package api
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"dockerpanel/backend/pkg/database"
+)
+
+func TestApplyScheduledJobRequest(t *testing.T) {
+	var j database.ScheduledJob
+	errs := applyScheduledJobRequest(&j, scheduledJobRequest{
+		Name: " nightly restart ", Action: "compose_restart", Target: "shop", Cron: "0 4 * * *",
+	})
+	if len(errs) > 0 || j.Name != "nightly restart" || j.Target != "shop" || j.CronExpr != "0 4 * * *" {
+		t.Fatalf("unexpected result: %+v %+v", j, errs)
+	}
+
+	j = database.ScheduledJob{}
+	errs = applyScheduledJobRequest(&j, scheduledJobRequest{
+		Name: "prune", Action: "image_prune", Target: "ignored", Cron: "@weekly",
+	})
+	if len(errs) > 0 || j.Target != "" {
+		t.Fatalf("image_prune: %+v %+v", j, errs)
+	}
+
+	j = database.ScheduledJob{}
+	errs = applyScheduledJobRequest(&j, scheduledJobRequest{
+		Name: "cache", Action: "container_exec", Target: "redis", Command: []string{"redis-cli", "BGSAVE"}, Cron: "@hourly",
+	})
+	if len(errs) > 0 || strings.Join(j.Command, " ") != "redis-cli BGSAVE" {
+		t.Fatalf("container_exec: %+v %+v", j, errs)
+	}
+
+	errs = applyScheduledJobRequest(&j, scheduledJobRequest{
+		Action: "container_exec", Target: "a b", Cron: "61 * * * *", TimeoutSeconds: -1,
+	})
+	fields := make(map[string]bool)
+	for _, e := range errs {
+		fields[e.Field] = true
+	}
+	for _, f := range []string{"name", "cron", "target", "command", "timeoutSeconds"} {
+		if !fields[f] {
+			t.Fatalf("missing error for %s: %+v", f, errs)
+		}
+	}
+
+	errs = applyScheduledJobRequest(&j, scheduledJobRequest{Name: "x", Action: "system_reboot", Cron: "@daily"})
+	if len(errs) != 1 || errs[0].Field != "action" {
+		t.Fatalf("unknown action: %+v", errs)
+	}
+}
+
+func TestJobSchedulerDue(t *testing.T) {
+	s := &jobScheduler{next: make(map[int64]scheduledNextRun), running: make(map[int64]bool)}
+	jobs := []database.ScheduledJob{
+		{ID: 1, Name: "hourly", CronExpr: "0 * * * *", Enabled: true},
+		{ID: 2, Name: "disabled", CronExpr: "* * * * *", Enabled: false},
+	}
+	start := time.Date(2026, 10, 16, 10, 59, 30, 0, time.UTC)
+
+	// 第一次只计算下次执行时间，不会补跑
+	if due := s.due(jobs, start); len(due) != 0 {
+		t.Fatalf("first tick should not run jobs: %+v", due)
+	}
+	if due := s.due(jobs, start.Add(15*time.Second)); len(due) != 0 {
+		t.Fatalf("job ran early: %+v", due)
+	}
+	due := s.due(jobs, start.Add(45*time.Second))
+	if len(due) != 1 || due[0].ID != 1 {
+		t.Fatalf("expected hourly job to be due: %+v", due)
+	}
+	if due := s.due(jobs, start.Add(60*time.Second)); len(due) != 0 {
+		t.Fatalf("job ran twice in the same hour: %+v", due)
+	}
+	if want := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC); !s.next[1].at.Equal(want) {
+		t.Fatalf("next run: %s", s.next[1].at)
+	}
+
+	// 修改表达式后重新计算，删除的任务不再保留
+	jobs[0].CronExpr = "30 * * * *"
+	s.due(jobs, start.Add(2*time.Minute))
+	if want := time.Date(2026, 10, 16, 11, 30, 0, 0, time.UTC); !s.next[1].at.Equal(want) {
+		t.Fatalf("next run after change: %s", s.next[1].at)
+	}
+	s.due(nil, start.Add(3*time.Minute))
+	if len(s.next) != 0 {
+		t.Fatalf("stale entries: %+v", s.next)
+	}
+}
+
+func TestLimitedBuffer(t *testing.T) {
+	b := &limitedBuffer{max: 8}
+	_, _ = b.Write([]byte("hello "))
+	_, _ = b.Write([]byte("world"))
+	if b.String() != "hello wo" || !b.truncated {
+		t.Fatalf("buffer: %q truncated=%v", b.String(), b.truncated)
+	}
+}
@@ -35,6 +35,13 @@ func RegisterSystemRoutes(r *gin.RouterGroup) {
 		group.POST("/notifications/read", markNotificationsRead)
 		group.POST("/navigation/rebuild", rebuildNavigation)
 		group.POST("/volume-backup/rebuild", rebuildVolumeBackup)
+		group.GET("/schedules", listScheduledJobs)
+		group.POST("/schedules", createScheduledJob)
+		group.GET("/schedules/preview", previewSchedule)
+		group.PUT("/schedules/:id", updateScheduledJob)
+		group.DELETE("/schedules/:id", deleteScheduledJob)
+		group.POST("/schedules/:id/run", runScheduledJobNow)
+		group.GET("/schedules/:id/runs", listScheduledJobRuns)
 	}
 }
 
@@ -318,6 +325,7 @@ func getContainersStats() ([]gin.H, error) {
 		if err := json.NewDecoder(containerStats.Body).Decode(&statsJSON); err != nil {
 			continue
 		}
+		system.RecordContainerStats(container.ID, statsJSON)
 
 		// 计算CPU使用率
 		cpuDelta := float64(statsJSON.CPUStats.CPUUsage.TotalUsage - statsJSON.PreCPUStats.CPUUsage.TotalUsage)
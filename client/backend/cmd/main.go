@@ -111,6 +111,11 @@ func main() {
 	api.StartImageUpdateScheduler()
 	api.InitClientVersionFromEnv()
 	api.StartVersionMonitor()
+	api.StartHealthWatcher()
+	api.StartLogArchiveCollector()
+	api.StartLogAlertEngine()
+	api.StartGitStackSyncer()
+	api.StartScheduler()
 
 	noisyPaths := map[string]struct{}{
 		"/api/settings/global": {},
@@ -159,7 +164,8 @@ func main() {
 	r.Use(cors.New(config))
 
 	// Register API routes
-	api.RegisterAuthRoutes(r) // 注册认证路由
+	api.RegisterAuthRoutes(r)    // 注册认证路由
+	api.RegisterWebhookRoutes(r) // 注册外部回调路由（令牌校验，不经过登录认证）
 
 	// 创建一个 API 组，用于需要认证的路由
 	// 注意：WebSocket 连接可能需要特殊的认证处理（Query Param），这里暂时通过 Header 认证
@@ -194,6 +200,8 @@ func main() {
 	go system.ProcessContainerDiscovery()
 	// 启动容器事件监听
 	go system.WatchContainerEvents()
+	// 启动容器资源采样（崩溃诊断使用）
+	system.StartContainerStatsSampler()
 
 	// 静态文件服务
 	// 1. 静态资源 (assets) - 对应 dist/assets 目录
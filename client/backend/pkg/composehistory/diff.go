@@ -0,0 +1,172 @@
+package composehistory
+
+import (
+	"fmt"
+	"strings"
+)
+
+// maxDiffCells 去掉公共首尾后剩余部分做 LCS 的规模上限，超过时整体视为替换
+const maxDiffCells = 1 << 20
+
+// DiffStats 差异统计
+type DiffStats struct {
+	Added   int `json:"added"`
+	Removed int `json:"removed"`
+}
+
+type diffOp struct {
+	kind byte // ' ' 相同，'-' 删除，'+' 新增
+	line string
+}
+
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
+
+// diffLines 基于最长公共子序列计算逐行编辑序列
+func diffLines(a, b []string) []diffOp {
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	ops := make([]diffOp, 0, len(a)+len(b))
+	for _, l := range a[:prefix] {
+		ops = append(ops, diffOp{' ', l})
+	}
+	ma, mb := a[prefix:len(a)-suffix], b[prefix:len(b)-suffix]
+	n, m := len(ma), len(mb)
+	if n*m > maxDiffCells {
+		for _, l := range ma {
+			ops = append(ops, diffOp{'-', l})
+		}
+		for _, l := range mb {
+			ops = append(ops, diffOp{'+', l})
+		}
+	} else {
+		// lcs[i][j] 为 ma[i:] 与 mb[j:] 的 LCS 长度
+		lcs := make([][]int32, n+1)
+		for i := range lcs {
+			lcs[i] = make([]int32, m+1)
+		}
+		for i := n - 1; i >= 0; i-- {
+			for j := m - 1; j >= 0; j-- {
+				if ma[i] == mb[j] {
+					lcs[i][j] = lcs[i+1][j+1] + 1
+				} else if lcs[i+1][j] >= lcs[i][j+1] {
+					lcs[i][j] = lcs[i+1][j]
+				} else {
+					lcs[i][j] = lcs[i][j+1]
+				}
+			}
+		}
+		i, j := 0, 0
+		for i < n && j < m {
+			switch {
+			case ma[i] == mb[j]:
+				ops = append(ops, diffOp{' ', ma[i]})
+				i++
+				j++
+			case lcs[i+1][j] >= lcs[i][j+1]:
+				ops = append(ops, diffOp{'-', ma[i]})
+				i++
+			default:
+				ops = append(ops, diffOp{'+', mb[j]})
+				j++
+			}
+		}
+		for ; i < n; i++ {
+			ops = append(ops, diffOp{'-', ma[i]})
+		}
+		for ; j < m; j++ {
+			ops = append(ops, diffOp{'+', mb[j]})
+		}
+	}
+	for _, l := range a[len(a)-suffix:] {
+		ops = append(ops, diffOp{' ', l})
+	}
+	return ops
+}
+
+// Unified 生成 from 到 to 的 unified diff 文本，context 为每处变更前后保留的上下文行数；内容相同时返回空串
+func Unified(from, to, fromName, toName string, context int) (string, DiffStats) {
+	var stats DiffStats
+	if from == to {
+		return "", stats
+	}
+	if context < 0 {
+		context = 0
+	}
+	ops := diffLines(splitLines(from), splitLines(to))
+
+	// aPos/bPos[i] 为第 i 个操作之前在两侧已经过的行数
+	aPos := make([]int, len(ops)+1)
+	bPos := make([]int, len(ops)+1)
+	var changes []int
+	for i, op := range ops {
+		aPos[i+1], bPos[i+1] = aPos[i], bPos[i]
+		if op.kind != '+' {
+			aPos[i+1]++
+		}
+		if op.kind != '-' {
+			bPos[i+1]++
+		}
+		switch op.kind {
+		case '+':
+			stats.Added++
+			changes = append(changes, i)
+		case '-':
+			stats.Removed++
+			changes = append(changes, i)
+		}
+	}
+	if len(changes) == 0 {
+		// 仅末尾换行不同
+		return "", stats
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "--- %s\n+++ %s\n", fromName, toName)
+	for k := 0; k < len(changes); {
+		first, last := changes[k], changes[k]
+		k++
+		// 两处变更之间的相同行不超过两倍上下文时合并为一个区块
+		for k < len(changes) && changes[k]-last-1 <= 2*context {
+			last = changes[k]
+			k++
+		}
+		start := first - context
+		if start < 0 {
+			start = 0
+		}
+		end := last + 1 + context
+		if end > len(ops) {
+			end = len(ops)
+		}
+		aCount, bCount := aPos[end]-aPos[start], bPos[end]-bPos[start]
+		fmt.Fprintf(&sb, "@@ -%s +%s @@\n", hunkRange(aPos[start], aCount), hunkRange(bPos[start], bCount))
+		for _, op := range ops[start:end] {
+			sb.WriteByte(op.kind)
+			sb.WriteString(op.line)
+			sb.WriteByte('\n')
+		}
+	}
+	return sb.String(), stats
+}
+
+func hunkRange(start, count int) string {
+	if count == 0 {
+		return fmt.Sprintf("%d,0", start)
+	}
+	if count == 1 {
+		return fmt.Sprintf("%d", start+1)
+	}
+	return fmt.Sprintf("%d,%d", start+1, count)
+}
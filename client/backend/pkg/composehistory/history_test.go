@@ -0,0 +1,73 @@
+package composehistory
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestStorePutGetAndGC(t *testing.T) {
+	s := NewStore(t.TempDir())
+	h1, err := s.Put([]byte("services: {}\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	h2, _ := s.Put([]byte("FOO=1\n"))
+	if again, _ := s.Put([]byte("services: {}\n")); again != h1 {
+		t.Fatal("same content should map to the same hash")
+	}
+	if got, err := s.Get(h1); err != nil || string(got) != "services: {}\n" {
+		t.Fatalf("get: %q %v", got, err)
+	}
+	if _, err := s.Get("../../etc/passwd"); err != ErrInvalidHash {
+		t.Fatalf("invalid hash should be rejected, got %v", err)
+	}
+
+	// 内容被篡改时拒绝返回
+	if err := os.WriteFile(s.objectPath(h2), []byte("FOO=2\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Get(h2); err == nil {
+		t.Fatal("corrupted object should fail verification")
+	}
+
+	n, err := s.GC(map[string]bool{h1: true})
+	if err != nil || n != 1 {
+		t.Fatalf("gc removed %d, %v", n, err)
+	}
+	if _, err := s.Get(h1); err != nil {
+		t.Fatal("kept object should survive gc")
+	}
+	if _, err := os.Stat(filepath.Dir(s.objectPath(h2))); h1[:2] != h2[:2] && !os.IsNotExist(err) {
+		t.Fatal("empty object dir should be removed")
+	}
+}
+
+func TestUnified(t *testing.T) {
+	from := "a\nb\nc\nd\ne\nf\ng\nh\ni\nj\nk\nl\n"
+	to := "a\nB\nc\nd\ne\nf\ng\nh\ni\nj\nk\nl\nm\n"
+	got, stats := Unified(from, to, "v1", "v2", 1)
+	want := "--- v1\n+++ v2\n" +
+		"@@ -1,3 +1,3 @@\n a\n-b\n+B\n c\n" +
+		"@@ -12 +12,2 @@\n l\n+m\n"
+	if got != want {
+		t.Fatalf("unexpected diff:\n%s\nwant:\n%s", got, want)
+	}
+	if stats.Added != 2 || stats.Removed != 1 {
+		t.Fatalf("stats: %+v", stats)
+	}
+
+	// 相邻变更合并为一个区块
+	got, _ = Unified("a\nb\nc\nd\n", "a\nX\nc\nY\n", "x", "y", 1)
+	if want := "--- x\n+++ y\n@@ -1,4 +1,4 @@\n a\n-b\n+X\n c\n-d\n+Y\n"; got != want {
+		t.Fatalf("merged hunk:\n%s", got)
+	}
+
+	got, _ = Unified("", "x: 1\n", "/dev/null", "new", 3)
+	if want := "--- /dev/null\n+++ new\n@@ -0,0 +1 @@\n+x: 1\n"; got != want {
+		t.Fatalf("from empty:\n%s", got)
+	}
+	if got, _ := Unified("same\n", "same\n", "a", "b", 3); got != "" {
+		t.Fatal("identical content should produce empty diff")
+	}
+}
@@ -0,0 +1,128 @@
+// Package composehistory 保存 Compose 项目配置文件的历史版本内容，并提供版本间的文本差异
+//
+// 文件内容按 SHA-256 寻址存放在 <root>/objects/<前两位>/<完整哈希>，相同内容只存一份；
+// 版本元数据（作者、说明、引用的哈希）由调用方记录在数据库中。
+package composehistory
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ErrInvalidHash 哈希格式不合法
+var ErrInvalidHash = errors.New("invalid object hash")
+
+// Store 内容寻址的对象存储
+type Store struct {
+	root string
+}
+
+// NewStore 创建以 root 为根目录的存储，目录在首次写入时创建
+func NewStore(root string) *Store {
+	return &Store{root: root}
+}
+
+// Hash 计算内容的对象哈希
+func Hash(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+func validHash(hash string) bool {
+	if len(hash) != sha256.Size*2 {
+		return false
+	}
+	_, err := hex.DecodeString(hash)
+	return err == nil && strings.ToLower(hash) == hash
+}
+
+func (s *Store) objectPath(hash string) string {
+	return filepath.Join(s.root, "objects", hash[:2], hash)
+}
+
+// Put 写入内容并返回其哈希，内容已存在时不重复写入
+func (s *Store) Put(content []byte) (string, error) {
+	hash := Hash(content)
+	path := s.objectPath(hash)
+	if _, err := os.Stat(path); err == nil {
+		return hash, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return "", err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), hash+".tmp*")
+	if err != nil {
+		return "", err
+	}
+	_, werr := tmp.Write(content)
+	cerr := tmp.Close()
+	if werr == nil {
+		werr = cerr
+	}
+	if werr == nil {
+		werr = os.Rename(tmp.Name(), path)
+	}
+	if werr != nil {
+		_ = os.Remove(tmp.Name())
+		return "", werr
+	}
+	return hash, nil
+}
+
+// Get 读取哈希对应的内容
+func (s *Store) Get(hash string) ([]byte, error) {
+	if !validHash(hash) {
+		return nil, ErrInvalidHash
+	}
+	content, err := os.ReadFile(s.objectPath(hash))
+	if err != nil {
+		return nil, err
+	}
+	if Hash(content) != hash {
+		return nil, fmt.Errorf("object %s is corrupted", hash)
+	}
+	return content, nil
+}
+
+// GC 删除不在 keep 中的对象，返回删除的数量
+func (s *Store) GC(keep map[string]bool) (int, error) {
+	dirs, err := os.ReadDir(filepath.Join(s.root, "objects"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, nil
+		}
+		return 0, err
+	}
+	removed := 0
+	for _, d := range dirs {
+		if !d.IsDir() {
+			continue
+		}
+		dir := filepath.Join(s.root, "objects", d.Name())
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			return removed, err
+		}
+		left := len(entries)
+		for _, e := range entries {
+			// 非对象文件（如写入中的临时文件）保留
+			if keep[e.Name()] || !validHash(e.Name()) {
+				continue
+			}
+			if err := os.Remove(filepath.Join(dir, e.Name())); err != nil {
+				return removed, err
+			}
+			left--
+			removed++
+		}
+		if left == 0 {
+			_ = os.Remove(dir)
+		}
+	}
+	return removed, nil
+}
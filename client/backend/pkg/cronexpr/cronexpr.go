@@ -0,0 +1,233 @@
+// Package cronexpr 解析 cron 表达式并计算下一次触发时间
+//
+// 支持标准五段式（分 时 日 月 周），字段可使用 *、?、列表（1,2）、范围（1-5）、步长（*/15、1-10/2）以及月份、星期的英文缩写；
+// 星期中 0 与 7 均表示周日。另支持 @yearly、@monthly、@weekly、@daily、@hourly 等别名与 "@every <时长>"。
+// 与常见实现一致，日与周同时受限时两者满足其一即可触发。时间按传入时间所在的时区计算。
+package cronexpr
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule 解析后的 cron 表达式
+type Schedule struct {
+	expr    string
+	every   time.Duration
+	minute  uint64
+	hour    uint64
+	dom     uint64
+	month   uint64
+	dow     uint64
+	domStar bool
+	dowStar bool
+}
+
+type fieldSpec struct {
+	name     string
+	min, max int
+	names    map[string]int
+}
+
+var (
+	minuteField = fieldSpec{name: "分钟", min: 0, max: 59}
+	hourField   = fieldSpec{name: "小时", min: 0, max: 23}
+	domField    = fieldSpec{name: "日", min: 1, max: 31}
+	monthField  = fieldSpec{name: "月", min: 1, max: 12, names: map[string]int{
+		"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+		"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+	}}
+	dowField = fieldSpec{name: "星期", min: 0, max: 7, names: map[string]int{
+		"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+	}}
+)
+
+var aliases = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// 向后最多查找的年数，用于 2 月 30 日之类永远不会触发的表达式
+const maxSearchYears = 5
+
+// Parse 解析 cron 表达式
+func Parse(expr string) (*Schedule, error) {
+	raw := strings.TrimSpace(expr)
+	if raw == "" {
+		return nil, errors.New("cron 表达式不能为空")
+	}
+	spec := raw
+	if strings.HasPrefix(raw, "@") {
+		lower := strings.ToLower(raw)
+		if strings.HasPrefix(lower, "@every ") {
+			d, err := time.ParseDuration(strings.TrimSpace(raw[len("@every "):]))
+			if err != nil {
+				return nil, fmt.Errorf("无效的间隔: %v", err)
+			}
+			if d < time.Minute {
+				return nil, errors.New("@every 间隔不能小于 1 分钟")
+			}
+			return &Schedule{expr: raw, every: d}, nil
+		}
+		alias, ok := aliases[lower]
+		if !ok {
+			return nil, fmt.Errorf("不支持的别名: %s", raw)
+		}
+		spec = alias
+	}
+
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("cron 表达式需要 5 个字段（分 时 日 月 周），实际为 %d 个", len(fields))
+	}
+	s := &Schedule{expr: raw}
+	var err error
+	if s.minute, _, err = parseField(fields[0], minuteField); err != nil {
+		return nil, err
+	}
+	if s.hour, _, err = parseField(fields[1], hourField); err != nil {
+		return nil, err
+	}
+	if s.dom, s.domStar, err = parseField(fields[2], domField); err != nil {
+		return nil, err
+	}
+	if s.month, _, err = parseField(fields[3], monthField); err != nil {
+		return nil, err
+	}
+	if s.dow, s.dowStar, err = parseField(fields[4], dowField); err != nil {
+		return nil, err
+	}
+	// 周日既可写作 0 也可写作 7
+	if s.dow&(1<<7) != 0 {
+		s.dow |= 1
+	}
+	return s, nil
+}
+
+// parseField 解析单个字段为位集合，star 表示字段不受限（* 或 ?）
+func parseField(field string, spec fieldSpec) (bits uint64, star bool, err error) {
+	for _, part := range strings.Split(field, ",") {
+		if part == "" {
+			return 0, false, fmt.Errorf("%s字段格式错误: %q", spec.name, field)
+		}
+		rangePart, step := part, 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			rangePart = part[:i]
+			step, err = strconv.Atoi(part[i+1:])
+			if err != nil || step <= 0 {
+				return 0, false, fmt.Errorf("%s字段步长无效: %q", spec.name, part)
+			}
+		}
+		lo, hi := spec.min, spec.max
+		switch {
+		case rangePart == "*" || rangePart == "?":
+			if step == 1 && len(field) == len(part) {
+				star = true
+			}
+		case strings.Contains(rangePart, "-"):
+			bounds := strings.SplitN(rangePart, "-", 2)
+			if lo, err = parseValue(bounds[0], spec); err != nil {
+				return 0, false, err
+			}
+			if hi, err = parseValue(bounds[1], spec); err != nil {
+				return 0, false, err
+			}
+			if lo > hi {
+				return 0, false, fmt.Errorf("%s字段范围无效: %q", spec.name, part)
+			}
+		default:
+			if lo, err = parseValue(rangePart, spec); err != nil {
+				return 0, false, err
+			}
+			hi = lo
+			// "5/10" 表示从 5 开始每 10 个单位
+			if step > 1 {
+				hi = spec.max
+			}
+		}
+		for v := lo; v <= hi; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+	return bits, star, nil
+}
+
+func parseValue(s string, spec fieldSpec) (int, error) {
+	if v, ok := spec.names[strings.ToLower(s)]; ok {
+		return v, nil
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil || v < spec.min || v > spec.max {
+		return 0, fmt.Errorf("%s字段取值无效: %q（范围 %d-%d）", spec.name, s, spec.min, spec.max)
+	}
+	return v, nil
+}
+
+// String 返回原始表达式
+func (s *Schedule) String() string {
+	return s.expr
+}
+
+func has(bits uint64, v int) bool {
+	return bits&(1<<uint(v)) != 0
+}
+
+func (s *Schedule) dayMatches(t time.Time) bool {
+	domOK := has(s.dom, t.Day())
+	dowOK := has(s.dow, int(t.Weekday()))
+	if s.domStar || s.dowStar {
+		return domOK && dowOK
+	}
+	return domOK || dowOK
+}
+
+// Next 返回严格晚于 t 的下一次触发时间（精确到分钟），不会触发时返回零值
+func (s *Schedule) Next(t time.Time) time.Time {
+	if s.every > 0 {
+		return t.Add(s.every).Truncate(time.Second)
+	}
+	loc := t.Location()
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(maxSearchYears, 0, 0)
+	for t.Before(limit) {
+		if !has(s.month, int(t.Month())) {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, loc)
+			continue
+		}
+		if !s.dayMatches(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc)
+			continue
+		}
+		if !has(s.hour, t.Hour()) {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, loc)
+			continue
+		}
+		if !has(s.minute, t.Minute()) {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+// NextN 返回 t 之后的 n 次触发时间
+func (s *Schedule) NextN(t time.Time, n int) []time.Time {
+	out := make([]time.Time, 0, n)
+	for i := 0; i < n; i++ {
+		t = s.Next(t)
+		if t.IsZero() {
+			break
+		}
+		out = append(out, t)
+	}
+	return out
+}
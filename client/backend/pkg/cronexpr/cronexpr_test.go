@@ -0,0 +1,65 @@
+package cronexpr
+
+import (
+	"testing"
+	"time"
+)
+
+func mustParse(t *testing.T, expr string) *Schedule {
+	t.Helper()
+	s, err := Parse(expr)
+	if err != nil {
+		t.Fatalf("Parse(%q): %v", expr, err)
+	}
+	return s
+}
+
+func TestNext(t *testing.T) {
+	loc := time.UTC
+	// 2026-10-16 是周五
+	from := time.Date(2026, 10, 16, 10, 17, 42, 0, loc)
+	cases := []struct {
+		expr string
+		want time.Time
+	}{
+		{"0 4 * * *", time.Date(2026, 10, 17, 4, 0, 0, 0, loc)},
+		{"*/15 * * * *", time.Date(2026, 10, 16, 10, 30, 0, 0, loc)},
+		{"0 * * * *", time.Date(2026, 10, 16, 11, 0, 0, 0, loc)},
+		{"0 20 * * sat,sun", time.Date(2026, 10, 17, 20, 0, 0, 0, loc)},
+		{"0 0 * * 7", time.Date(2026, 10, 18, 0, 0, 0, 0, loc)},
+		{"30 9 1-5 * *", time.Date(2026, 11, 1, 9, 30, 0, 0, loc)},
+		{"0 3 * JAN mon", time.Date(2027, 1, 4, 3, 0, 0, 0, loc)},
+		{"0 0 13 * fri", time.Date(2026, 10, 23, 0, 0, 0, 0, loc)}, // 日与周满足其一
+		{"5/20 10 * * *", time.Date(2026, 10, 16, 10, 25, 0, 0, loc)},
+		{"@weekly", time.Date(2026, 10, 18, 0, 0, 0, 0, loc)},
+		{"@every 90m", time.Date(2026, 10, 16, 11, 47, 42, 0, loc)},
+	}
+	for _, tc := range cases {
+		if got := mustParse(t, tc.expr).Next(from); !got.Equal(tc.want) {
+			t.Errorf("%s: got %s, want %s", tc.expr, got, tc.want)
+		}
+	}
+}
+
+func TestNextNeverFires(t *testing.T) {
+	if got := mustParse(t, "0 0 30 2 *").Next(time.Now()); !got.IsZero() {
+		t.Fatalf("Feb 30 should never fire, got %s", got)
+	}
+}
+
+func TestNextN(t *testing.T) {
+	from := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	got := mustParse(t, "0 12 * * 1-5").NextN(from, 3)
+	if len(got) != 3 || got[0].Day() != 1 || got[1].Day() != 2 || got[2].Day() != 5 {
+		t.Fatalf("NextN: %v", got)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, expr := range []string{"", "* * * *", "60 * * * *", "* 24 * * *", "* * 0 * *", "5-1 * * * *",
+		"*/0 * * * *", "* * * foo *", "@often", "@every 10s", "1,,2 * * * *"} {
+		if _, err := Parse(expr); err == nil {
+			t.Errorf("Parse(%q) should fail", expr)
+		}
+	}
+}
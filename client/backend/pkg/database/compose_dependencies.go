@@ -0,0 +1,51 @@
+package database
+
+// ComposeDependency 手动声明的项目依赖：Project 依赖 DependsOn（启动时 DependsOn 先启动）
+type ComposeDependency struct {
+	Project   string `json:"project"`
+	DependsOn string `json:"dependsOn"`
+}
+
+// ListComposeDependencies 列出全部手动声明的项目依赖
+func ListComposeDependencies() ([]ComposeDependency, error) {
+	rows, err := GetDB().Query(`SELECT project, depends_on FROM compose_dependencies ORDER BY project, depends_on`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	list := make([]ComposeDependency, 0)
+	for rows.Next() {
+		var d ComposeDependency
+		if err := rows.Scan(&d.Project, &d.DependsOn); err != nil {
+			return nil, err
+		}
+		list = append(list, d)
+	}
+	return list, rows.Err()
+}
+
+// SetComposeDependencies 替换项目的依赖列表
+func SetComposeDependencies(project string, dependsOn []string) error {
+	tx, err := GetDB().Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM compose_dependencies WHERE project = ?`, project); err != nil {
+		return err
+	}
+	for _, dep := range dependsOn {
+		if _, err := tx.Exec(`INSERT OR IGNORE INTO compose_dependencies (project, depends_on, created_at)
+            VALUES (?, ?, CURRENT_TIMESTAMP)`, project, dep); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// DeleteComposeDependencies 删除项目自身的依赖以及其他项目对它的依赖
+func DeleteComposeDependencies(project string) error {
+	_, err := GetDB().Exec(`DELETE FROM compose_dependencies WHERE project = ? OR depends_on = ?`, project, project)
+	return err
+}
@@ -0,0 +1,60 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// ComposeProjectOptions 项目级的 compose 命令参数：多个配置文件（基础 + 覆盖）、启用的 profile 与多个 env 文件。
+// 路径均相对于项目目录，按顺序传给 docker compose
+type ComposeProjectOptions struct {
+	Project      string    `json:"project"`
+	ComposeFiles []string  `json:"composeFiles"`
+	Profiles     []string  `json:"profiles"`
+	EnvFiles     []string  `json:"envFiles"`
+	UpdatedAt    time.Time `json:"updatedAt"`
+}
+
+// IsEmpty 未配置任何参数时项目沿用默认的配置文件查找与 .env
+func (o ComposeProjectOptions) IsEmpty() bool {
+	return len(o.ComposeFiles) == 0 && len(o.Profiles) == 0 && len(o.EnvFiles) == 0
+}
+
+// GetComposeProjectOptions 获取项目的 compose 参数，未配置时返回空配置
+func GetComposeProjectOptions(project string) (ComposeProjectOptions, error) {
+	o := ComposeProjectOptions{Project: project, ComposeFiles: []string{}, Profiles: []string{}, EnvFiles: []string{}}
+	var files, profiles, envFiles string
+	err := GetDB().QueryRow(`SELECT COALESCE(compose_files, ''), COALESCE(profiles, ''), COALESCE(env_files, ''), updated_at
+        FROM compose_project_options WHERE project = ?`, project).Scan(&files, &profiles, &envFiles, &o.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return o, nil
+	}
+	if err != nil {
+		return o, err
+	}
+	o.ComposeFiles = decodeStringList(files)
+	o.Profiles = decodeStringList(profiles)
+	o.EnvFiles = decodeStringList(envFiles)
+	return o, nil
+}
+
+// SaveComposeProjectOptions 保存项目的 compose 参数，全部为空时删除配置
+func SaveComposeProjectOptions(o *ComposeProjectOptions) error {
+	if o.IsEmpty() {
+		return DeleteComposeProjectOptions(o.Project)
+	}
+	o.UpdatedAt = time.Now()
+	_, err := GetDB().Exec(`INSERT INTO compose_project_options (project, compose_files, profiles, env_files, updated_at)
+        VALUES (?, ?, ?, ?, ?)
+        ON CONFLICT(project) DO UPDATE SET compose_files = excluded.compose_files, profiles = excluded.profiles,
+        env_files = excluded.env_files, updated_at = excluded.updated_at`,
+		o.Project, encodeStringList(o.ComposeFiles), encodeStringList(o.Profiles), encodeStringList(o.EnvFiles), o.UpdatedAt)
+	return err
+}
+
+// DeleteComposeProjectOptions 删除项目的 compose 参数
+func DeleteComposeProjectOptions(project string) error {
+	_, err := GetDB().Exec(`DELETE FROM compose_project_options WHERE project = ?`, project)
+	return err
+}
@@ -0,0 +1,149 @@
+package database
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// composeVersionKeep 每个项目保留的历史版本数
+const composeVersionKeep = 100
+
+// ComposeVersion Compose 项目配置的一个历史版本，文件内容按哈希保存在版本存储中
+type ComposeVersion struct {
+	ID          int64             `json:"id"`
+	Project     string            `json:"project"`
+	Version     int               `json:"version"` // 项目内递增的版本号
+	ComposeFile string            `json:"composeFile"`
+	ComposeHash string            `json:"composeHash"`
+	EnvHash     string            `json:"envHash"` // 为空表示该版本没有 .env
+	Author      string            `json:"author"`
+	Message     string            `json:"message"`
+	Source      string            `json:"source"`               // baseline / save_yaml / save_env / deploy / rollback
+	ExtraFiles  map[string]string `json:"extraFiles,omitempty"` // 覆盖 compose 文件与额外 env 文件：相对路径 -> 内容哈希
+	CreatedAt   time.Time         `json:"createdAt"`
+}
+
+const composeVersionColumns = `id, project, version, COALESCE(compose_file, ''), COALESCE(compose_hash, ''),
+        COALESCE(env_hash, ''), COALESCE(author, ''), COALESCE(message, ''), COALESCE(source, ''), COALESCE(extra_files, ''), created_at`
+
+func scanComposeVersion(row rowScanner) (ComposeVersion, error) {
+	var v ComposeVersion
+	var extra string
+	err := row.Scan(&v.ID, &v.Project, &v.Version, &v.ComposeFile, &v.ComposeHash, &v.EnvHash,
+		&v.Author, &v.Message, &v.Source, &extra, &v.CreatedAt)
+	v.ExtraFiles = decodeExtraFiles(extra)
+	return v, err
+}
+
+func encodeExtraFiles(files map[string]string) string {
+	if len(files) == 0 {
+		return ""
+	}
+	b, _ := json.Marshal(files)
+	return string(b)
+}
+
+func decodeExtraFiles(raw string) map[string]string {
+	if raw == "" {
+		return nil
+	}
+	files := map[string]string{}
+	_ = json.Unmarshal([]byte(raw), &files)
+	return files
+}
+
+// AddComposeVersion 追加一个版本（自动分配版本号），并裁剪该项目最旧的版本
+func AddComposeVersion(v *ComposeVersion) error {
+	if v.CreatedAt.IsZero() {
+		v.CreatedAt = time.Now()
+	}
+	tx, err := GetDB().Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := tx.QueryRow(`SELECT COALESCE(MAX(version), 0) + 1 FROM compose_versions WHERE project = ?`,
+		v.Project).Scan(&v.Version); err != nil {
+		return err
+	}
+	res, err := tx.Exec(`INSERT INTO compose_versions (project, version, compose_file, compose_hash, env_hash,
+        author, message, source, extra_files, created_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		v.Project, v.Version, v.ComposeFile, v.ComposeHash, v.EnvHash, v.Author, v.Message, v.Source,
+		encodeExtraFiles(v.ExtraFiles), v.CreatedAt)
+	if err != nil {
+		return err
+	}
+	v.ID, _ = res.LastInsertId()
+	if _, err := tx.Exec(`DELETE FROM compose_versions WHERE project = ? AND version <= ?`,
+		v.Project, v.Version-composeVersionKeep); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// GetComposeVersion 获取项目的指定版本，不存在时返回 sql.ErrNoRows
+func GetComposeVersion(project string, version int) (ComposeVersion, error) {
+	return scanComposeVersion(GetDB().QueryRow(`SELECT `+composeVersionColumns+` FROM compose_versions
+        WHERE project = ? AND version = ?`, project, version))
+}
+
+// GetLatestComposeVersion 获取项目最新的版本，不存在时返回 sql.ErrNoRows
+func GetLatestComposeVersion(project string) (ComposeVersion, error) {
+	return scanComposeVersion(GetDB().QueryRow(`SELECT `+composeVersionColumns+` FROM compose_versions
+        WHERE project = ? ORDER BY version DESC LIMIT 1`, project))
+}
+
+// ListComposeVersions 按版本号倒序列出项目的历史版本
+func ListComposeVersions(project string, limit int) ([]ComposeVersion, error) {
+	if limit <= 0 || limit > composeVersionKeep {
+		limit = composeVersionKeep
+	}
+	rows, err := GetDB().Query(`SELECT `+composeVersionColumns+` FROM compose_versions
+        WHERE project = ? ORDER BY version DESC LIMIT ?`, project, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	list := make([]ComposeVersion, 0)
+	for rows.Next() {
+		v, err := scanComposeVersion(rows)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, v)
+	}
+	return list, rows.Err()
+}
+
+// DeleteComposeVersions 删除项目的全部历史版本
+func DeleteComposeVersions(project string) error {
+	_, err := GetDB().Exec(`DELETE FROM compose_versions WHERE project = ?`, project)
+	return err
+}
+
+// ListComposeVersionHashes 返回所有版本引用的内容哈希
+func ListComposeVersionHashes() (map[string]bool, error) {
+	rows, err := GetDB().Query(`SELECT COALESCE(compose_hash, ''), COALESCE(env_hash, ''), COALESCE(extra_files, '')
+        FROM compose_versions`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	hashes := make(map[string]bool)
+	for rows.Next() {
+		var composeHash, envHash, extra string
+		if err := rows.Scan(&composeHash, &envHash, &extra); err != nil {
+			return nil, err
+		}
+		for _, h := range []string{composeHash, envHash} {
+			if h != "" {
+				hashes[h] = true
+			}
+		}
+		for _, h := range decodeExtraFiles(extra) {
+			hashes[h] = true
+		}
+	}
+	return hashes, rows.Err()
+}
@@ -0,0 +1,116 @@
+package database
+
+import (
+	"database/sql"
+	"time"
+)
+
+// ComposeWebhook 项目的部署回调：外部系统持令牌调用后拉取镜像并重新部署。令牌只保存哈希
+type ComposeWebhook struct {
+	ID              int64      `json:"id"`
+	Project         string     `json:"project"`
+	Name            string     `json:"name"`
+	TokenHash       string     `json:"-"`
+	HMACSecret      string     `json:"-"` // 非空时要求请求体带签名
+	Enabled         bool       `json:"enabled"`
+	TriggerCount    int        `json:"triggerCount"`
+	LastTriggeredAt *time.Time `json:"lastTriggeredAt"`
+	LastTaskID      string     `json:"lastTaskId"`
+	CreatedAt       time.Time  `json:"createdAt"`
+}
+
+const composeWebhookColumns = `id, project, COALESCE(name, ''), token_hash, COALESCE(hmac_secret, ''), enabled,
+        trigger_count, last_triggered_at, COALESCE(last_task_id, ''), created_at`
+
+func scanComposeWebhook(row rowScanner) (ComposeWebhook, error) {
+	var w ComposeWebhook
+	var enabled int
+	var lastTriggered sql.NullTime
+	err := row.Scan(&w.ID, &w.Project, &w.Name, &w.TokenHash, &w.HMACSecret, &enabled, &w.TriggerCount,
+		&lastTriggered, &w.LastTaskID, &w.CreatedAt)
+	w.Enabled = enabled != 0
+	w.LastTriggeredAt = nullTimePtr(lastTriggered)
+	return w, err
+}
+
+// ListComposeWebhooks 列出项目的部署回调
+func ListComposeWebhooks(project string) ([]ComposeWebhook, error) {
+	rows, err := GetDB().Query(`SELECT `+composeWebhookColumns+` FROM compose_webhooks WHERE project = ? ORDER BY id`, project)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	list := make([]ComposeWebhook, 0)
+	for rows.Next() {
+		w, err := scanComposeWebhook(rows)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, w)
+	}
+	return list, rows.Err()
+}
+
+// GetComposeWebhook 获取项目的指定回调，不存在时返回 sql.ErrNoRows
+func GetComposeWebhook(project string, id int64) (ComposeWebhook, error) {
+	return scanComposeWebhook(GetDB().QueryRow(`SELECT `+composeWebhookColumns+` FROM compose_webhooks
+        WHERE project = ? AND id = ?`, project, id))
+}
+
+// GetComposeWebhookByToken 按项目与令牌哈希查找回调，不存在时返回 sql.ErrNoRows
+func GetComposeWebhookByToken(project, tokenHash string) (ComposeWebhook, error) {
+	return scanComposeWebhook(GetDB().QueryRow(`SELECT `+composeWebhookColumns+` FROM compose_webhooks
+        WHERE project = ? AND token_hash = ?`, project, tokenHash))
+}
+
+// SaveComposeWebhook ID 为 0 时新建，否则更新名称、令牌、签名密钥与启用状态，保存后回填数据库中的内容
+func SaveComposeWebhook(w *ComposeWebhook) error {
+	if w.ID == 0 {
+		res, err := GetDB().Exec(`INSERT INTO compose_webhooks (project, name, token_hash, hmac_secret, enabled, created_at)
+            VALUES (?, ?, ?, ?, ?, CURRENT_TIMESTAMP)`, w.Project, w.Name, w.TokenHash, w.HMACSecret, boolToInt(w.Enabled))
+		if err != nil {
+			return err
+		}
+		w.ID, _ = res.LastInsertId()
+	} else {
+		res, err := GetDB().Exec(`UPDATE compose_webhooks SET name = ?, token_hash = ?, hmac_secret = ?, enabled = ?
+            WHERE project = ? AND id = ?`, w.Name, w.TokenHash, w.HMACSecret, boolToInt(w.Enabled), w.Project, w.ID)
+		if err != nil {
+			return err
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			return sql.ErrNoRows
+		}
+	}
+	saved, err := GetComposeWebhook(w.Project, w.ID)
+	if err != nil {
+		return err
+	}
+	*w = saved
+	return nil
+}
+
+// MarkComposeWebhookTriggered 记录一次触发及其任务
+func MarkComposeWebhookTriggered(id int64, taskID string) error {
+	_, err := GetDB().Exec(`UPDATE compose_webhooks SET trigger_count = trigger_count + 1, last_triggered_at = ?,
+        last_task_id = ? WHERE id = ?`, time.Now(), taskID, id)
+	return err
+}
+
+// DeleteComposeWebhook 删除项目的指定回调
+func DeleteComposeWebhook(project string, id int64) error {
+	res, err := GetDB().Exec(`DELETE FROM compose_webhooks WHERE project = ? AND id = ?`, project, id)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// DeleteComposeWebhooks 删除项目的全部回调
+func DeleteComposeWebhooks(project string) error {
+	_, err := GetDB().Exec(`DELETE FROM compose_webhooks WHERE project = ?`, project)
+	return err
+}
@@ -0,0 +1,102 @@
+package database
+
+import "time"
+
+// containerDiagnosticKeep 全局保留的诊断记录条数
+const containerDiagnosticKeep = 500
+
+// ContainerDiagnostic 容器崩溃循环或 OOM 时自动采集的诊断信息
+type ContainerDiagnostic struct {
+	ID             int64     `json:"id"`
+	ContainerID    string    `json:"containerId"`
+	ContainerName  string    `json:"containerName"`
+	Image          string    `json:"image"`
+	Kind           string    `json:"kind"` // oom / crashloop
+	ExitCode       int       `json:"exitCode"`
+	OOMKilled      bool      `json:"oomKilled"`
+	RestartCount   int       `json:"restartCount"`
+	DieCount       int       `json:"dieCount"`
+	MemoryLimit    int64     `json:"memoryLimit"`
+	MemoryUsage    int64     `json:"memoryUsage"`
+	MemoryMaxUsage int64     `json:"memoryMaxUsage"`
+	Logs           string    `json:"logs,omitempty"`
+	DetailsJSON    string    `json:"details,omitempty"`
+	CreatedAt      time.Time `json:"createdAt"`
+}
+
+const containerDiagnosticColumns = `id, container_id, COALESCE(container_name, ''), COALESCE(image, ''), kind, exit_code, oom_killed,
+        restart_count, die_count, memory_limit, memory_usage, memory_max_usage, COALESCE(logs, ''), COALESCE(details_json, ''), created_at`
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanContainerDiagnostic(row rowScanner) (ContainerDiagnostic, error) {
+	var d ContainerDiagnostic
+	var oom int
+	err := row.Scan(&d.ID, &d.ContainerID, &d.ContainerName, &d.Image, &d.Kind, &d.ExitCode, &oom,
+		&d.RestartCount, &d.DieCount, &d.MemoryLimit, &d.MemoryUsage, &d.MemoryMaxUsage, &d.Logs, &d.DetailsJSON, &d.CreatedAt)
+	d.OOMKilled = oom != 0
+	return d, err
+}
+
+// SaveContainerDiagnostic 保存诊断记录，并裁剪最旧的记录
+func SaveContainerDiagnostic(d *ContainerDiagnostic) error {
+	if d.CreatedAt.IsZero() {
+		d.CreatedAt = time.Now()
+	}
+	res, err := GetDB().Exec(`INSERT INTO container_diagnostics (container_id, container_name, image, kind, exit_code, oom_killed,
+        restart_count, die_count, memory_limit, memory_usage, memory_max_usage, logs, details_json, created_at)
+        VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		d.ContainerID, d.ContainerName, d.Image, d.Kind, d.ExitCode, boolToInt(d.OOMKilled),
+		d.RestartCount, d.DieCount, d.MemoryLimit, d.MemoryUsage, d.MemoryMaxUsage, d.Logs, d.DetailsJSON, d.CreatedAt)
+	if err != nil {
+		return err
+	}
+	d.ID, _ = res.LastInsertId()
+	_, err = GetDB().Exec(`DELETE FROM container_diagnostics WHERE id NOT IN (
+        SELECT id FROM container_diagnostics ORDER BY id DESC LIMIT ?)`, containerDiagnosticKeep)
+	return err
+}
+
+// ListContainerDiagnostics 按时间倒序列出诊断记录（不含日志正文），containerID 为空时返回全部
+func ListContainerDiagnostics(containerID string, limit int) ([]ContainerDiagnostic, error) {
+	if limit <= 0 || limit > 500 {
+		limit = 50
+	}
+	query := `SELECT ` + containerDiagnosticColumns + ` FROM container_diagnostics`
+	args := []any{}
+	if containerID != "" {
+		query += ` WHERE container_id = ?`
+		args = append(args, containerID)
+	}
+	query += ` ORDER BY id DESC LIMIT ?`
+	args = append(args, limit)
+
+	rows, err := GetDB().Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	list := make([]ContainerDiagnostic, 0)
+	for rows.Next() {
+		d, err := scanContainerDiagnostic(rows)
+		if err != nil {
+			return nil, err
+		}
+		d.Logs = ""
+		list = append(list, d)
+	}
+	return list, rows.Err()
+}
+
+// GetContainerDiagnostic 获取单条诊断记录（含日志）
+func GetContainerDiagnostic(id int64) (ContainerDiagnostic, error) {
+	return scanContainerDiagnostic(GetDB().QueryRow(`SELECT `+containerDiagnosticColumns+` FROM container_diagnostics WHERE id = ?`, id))
+}
+
+// DeleteContainerDiagnostic 删除单条诊断记录
+func DeleteContainerDiagnostic(id int64) error {
+	_, err := GetDB().Exec(`DELETE FROM container_diagnostics WHERE id = ?`, id)
+	return err
+}
@@ -0,0 +1,74 @@
+package database
+
+import (
+	"database/sql"
+	"time"
+)
+
+// ContainerFilter 用户保存的容器列表筛选条件
+type ContainerFilter struct {
+	ID        int64     `json:"id"`
+	Username  string    `json:"-"`
+	Name      string    `json:"name"`
+	Query     string    `json:"query"`
+	Sort      string    `json:"sort"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// ListContainerFilters 列出用户保存的筛选条件，按名称排序
+func ListContainerFilters(username string) ([]ContainerFilter, error) {
+	rows, err := GetDB().Query(`SELECT id, username, name, query, COALESCE(sort, ''), created_at, updated_at
+        FROM container_filters WHERE username = ? ORDER BY name`, username)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	list := make([]ContainerFilter, 0)
+	for rows.Next() {
+		var f ContainerFilter
+		if err := rows.Scan(&f.ID, &f.Username, &f.Name, &f.Query, &f.Sort, &f.CreatedAt, &f.UpdatedAt); err != nil {
+			return nil, err
+		}
+		list = append(list, f)
+	}
+	return list, rows.Err()
+}
+
+// GetContainerFilterByName 按名称获取用户的筛选条件，不存在时返回 sql.ErrNoRows
+func GetContainerFilterByName(username string, name string) (ContainerFilter, error) {
+	var f ContainerFilter
+	err := GetDB().QueryRow(`SELECT id, username, name, query, COALESCE(sort, ''), created_at, updated_at
+        FROM container_filters WHERE username = ? AND name = ?`, username, name).
+		Scan(&f.ID, &f.Username, &f.Name, &f.Query, &f.Sort, &f.CreatedAt, &f.UpdatedAt)
+	return f, err
+}
+
+// SaveContainerFilter 保存筛选条件，同一用户下同名条件覆盖更新
+func SaveContainerFilter(f *ContainerFilter) error {
+	_, err := GetDB().Exec(`INSERT INTO container_filters (username, name, query, sort, created_at, updated_at)
+        VALUES (?, ?, ?, ?, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
+        ON CONFLICT(username, name) DO UPDATE SET query=excluded.query, sort=excluded.sort, updated_at=CURRENT_TIMESTAMP`,
+		f.Username, f.Name, f.Query, f.Sort)
+	if err != nil {
+		return err
+	}
+	saved, err := GetContainerFilterByName(f.Username, f.Name)
+	if err != nil {
+		return err
+	}
+	*f = saved
+	return nil
+}
+
+// DeleteContainerFilter 删除用户的筛选条件
+func DeleteContainerFilter(username string, id int64) error {
+	res, err := GetDB().Exec(`DELETE FROM container_filters WHERE id = ? AND username = ?`, id, username)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+package database
+
+import "time"
+
+// containerHealthLogKeep 每个容器保留的健康记录条数
+const containerHealthLogKeep = 200
+
+// ContainerHealthLog 容器健康状态变化或自动修复记录
+type ContainerHealthLog struct {
+	ID            int64     `json:"id"`
+	ContainerID   string    `json:"containerId"`
+	ContainerName string    `json:"containerName"`
+	Status        string    `json:"status"` // healthy / unhealthy / starting
+	Action        string    `json:"action"` // transition / autoheal / autoheal_failed / autoheal_giveup
+	FailingStreak int       `json:"failingStreak"`
+	ExitCode      int       `json:"exitCode"`
+	Output        string    `json:"output"`
+	CreatedAt     time.Time `json:"createdAt"`
+}
+
+// AddContainerHealthLog 写入一条健康记录，并裁剪该容器的旧记录
+func AddContainerHealthLog(l *ContainerHealthLog) error {
+	if l.CreatedAt.IsZero() {
+		l.CreatedAt = time.Now()
+	}
+	res, err := GetDB().Exec(`INSERT INTO container_health_logs (container_id, container_name, status, action, failing_streak, exit_code, output, created_at)
+        VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		l.ContainerID, l.ContainerName, l.Status, l.Action, l.FailingStreak, l.ExitCode, l.Output, l.CreatedAt)
+	if err != nil {
+		return err
+	}
+	l.ID, _ = res.LastInsertId()
+	_, err = GetDB().Exec(`DELETE FROM container_health_logs WHERE container_id = ? AND id NOT IN (
+        SELECT id FROM container_health_logs WHERE container_id = ? ORDER BY id DESC LIMIT ?)`,
+		l.ContainerID, l.ContainerID, containerHealthLogKeep)
+	return err
+}
+
+// ListContainerHealthLogs 按时间倒序列出健康记录，containerID 为空时返回全部容器
+func ListContainerHealthLogs(containerID string, limit int) ([]ContainerHealthLog, error) {
+	if limit <= 0 || limit > 1000 {
+		limit = 100
+	}
+	query := `SELECT id, container_id, COALESCE(container_name, ''), status, action, failing_streak, exit_code, COALESCE(output, ''), created_at
+        FROM container_health_logs`
+	args := []any{}
+	if containerID != "" {
+		query += ` WHERE container_id = ?`
+		args = append(args, containerID)
+	}
+	query += ` ORDER BY id DESC LIMIT ?`
+	args = append(args, limit)
+
+	rows, err := GetDB().Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	list := make([]ContainerHealthLog, 0)
+	for rows.Next() {
+		var l ContainerHealthLog
+		if err := rows.Scan(&l.ID, &l.ContainerID, &l.ContainerName, &l.Status, &l.Action, &l.FailingStreak, &l.ExitCode, &l.Output, &l.CreatedAt); err != nil {
+			return nil, err
+		}
+		list = append(list, l)
+	}
+	return list, rows.Err()
+}
+
+// DeleteContainerHealthLogs 删除容器的全部健康记录（容器销毁时调用）
+func DeleteContainerHealthLogs(containerID string) error {
+	_, err := GetDB().Exec(`DELETE FROM container_health_logs WHERE container_id = ?`, containerID)
+	return err
+}
@@ -514,6 +514,266 @@ func createTables() error {
 		return err
 	}
 
+	_, err = db.Exec(`
+	    CREATE TABLE IF NOT EXISTS vuln_advisories (
+	        id INTEGER PRIMARY KEY AUTOINCREMENT,
+	        advisory_id TEXT NOT NULL,
+	        ecosystem TEXT NOT NULL,
+	        package TEXT NOT NULL,
+	        severity TEXT,
+	        data_json TEXT,
+	        updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	        UNIQUE(advisory_id, ecosystem, package)
+	    );
+	`)
+	if err != nil {
+		return err
+	}
+	_, _ = db.Exec(`CREATE INDEX IF NOT EXISTS idx_vuln_advisories_package ON vuln_advisories(package)`)
+
+	_, err = db.Exec(`
+	    CREATE TABLE IF NOT EXISTS image_scans (
+	        image_id TEXT PRIMARY KEY,
+	        db_revision TEXT,
+	        critical INTEGER DEFAULT 0,
+	        high INTEGER DEFAULT 0,
+	        medium INTEGER DEFAULT 0,
+	        low INTEGER DEFAULT 0,
+	        unknown INTEGER DEFAULT 0,
+	        result_json TEXT,
+	        scanned_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	    );
+	`)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec(`
+	    CREATE TABLE IF NOT EXISTS container_filters (
+	        id INTEGER PRIMARY KEY AUTOINCREMENT,
+	        username TEXT NOT NULL,
+	        name TEXT NOT NULL,
+	        query TEXT NOT NULL,
+	        sort TEXT,
+	        created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	        updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	        UNIQUE(username, name)
+	    );
+	`)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec(`
+	    CREATE TABLE IF NOT EXISTS container_health_logs (
+	        id INTEGER PRIMARY KEY AUTOINCREMENT,
+	        container_id TEXT NOT NULL,
+	        container_name TEXT,
+	        status TEXT NOT NULL,
+	        action TEXT NOT NULL,
+	        failing_streak INTEGER DEFAULT 0,
+	        exit_code INTEGER DEFAULT 0,
+	        output TEXT,
+	        created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	    );
+	`)
+	if err != nil {
+		return err
+	}
+	_, _ = db.Exec(`CREATE INDEX IF NOT EXISTS idx_container_health_logs_container ON container_health_logs(container_id, id)`)
+
+	_, err = db.Exec(`
+	    CREATE TABLE IF NOT EXISTS container_diagnostics (
+	        id INTEGER PRIMARY KEY AUTOINCREMENT,
+	        container_id TEXT NOT NULL,
+	        container_name TEXT,
+	        image TEXT,
+	        kind TEXT NOT NULL,
+	        exit_code INTEGER DEFAULT 0,
+	        oom_killed INTEGER DEFAULT 0,
+	        restart_count INTEGER DEFAULT 0,
+	        die_count INTEGER DEFAULT 0,
+	        memory_limit INTEGER DEFAULT 0,
+	        memory_usage INTEGER DEFAULT 0,
+	        memory_max_usage INTEGER DEFAULT 0,
+	        logs TEXT,
+	        details_json TEXT,
+	        created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	    );
+	`)
+	if err != nil {
+		return err
+	}
+	_, _ = db.Exec(`CREATE INDEX IF NOT EXISTS idx_container_diagnostics_container ON container_diagnostics(container_id, id)`)
+
+	_, err = db.Exec(`
+	    CREATE TABLE IF NOT EXISTS log_alert_rules (
+	        id INTEGER PRIMARY KEY AUTOINCREMENT,
+	        name TEXT NOT NULL UNIQUE,
+	        enabled INTEGER DEFAULT 1,
+	        containers TEXT,
+	        projects TEXT,
+	        services TEXT,
+	        labels TEXT,
+	        pattern TEXT,
+	        levels TEXT,
+	        fields TEXT,
+	        threshold INTEGER DEFAULT 1,
+	        window_seconds INTEGER DEFAULT 300,
+	        cooldown_seconds INTEGER DEFAULT 600,
+	        created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	        updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	    );
+	`)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec(`
+	    CREATE TABLE IF NOT EXISTS log_alert_events (
+	        id INTEGER PRIMARY KEY AUTOINCREMENT,
+	        rule_id INTEGER NOT NULL,
+	        rule_name TEXT,
+	        container_name TEXT,
+	        project TEXT,
+	        service TEXT,
+	        match_count INTEGER DEFAULT 0,
+	        suppressed INTEGER DEFAULT 0,
+	        excerpt TEXT,
+	        created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	    );
+	`)
+	if err != nil {
+		return err
+	}
+	_, _ = db.Exec(`CREATE INDEX IF NOT EXISTS idx_log_alert_events_rule ON log_alert_events(rule_id, id)`)
+
+	_, err = db.Exec(`
+	    CREATE TABLE IF NOT EXISTS compose_versions (
+	        id INTEGER PRIMARY KEY AUTOINCREMENT,
+	        project TEXT NOT NULL,
+	        version INTEGER NOT NULL,
+	        compose_file TEXT,
+	        compose_hash TEXT,
+	        env_hash TEXT,
+	        author TEXT,
+	        message TEXT,
+	        source TEXT,
+	        extra_files TEXT,
+	        created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	        UNIQUE(project, version)
+	    );
+	`)
+	if err != nil {
+		return err
+	}
+	if err := ensureTableColumns("compose_versions", []columnSpec{
+		{Name: "extra_files", AddColumnSQL: "extra_files TEXT"},
+	}); err != nil {
+		return err
+	}
+
+	_, err = db.Exec(`
+	    CREATE TABLE IF NOT EXISTS git_stacks (
+	        project TEXT PRIMARY KEY,
+	        repo_url TEXT NOT NULL,
+	        branch TEXT NOT NULL,
+	        compose_path TEXT,
+	        username TEXT,
+	        password TEXT,
+	        auto_sync INTEGER DEFAULT 1,
+	        sync_interval INTEGER DEFAULT 300,
+	        webhook_token TEXT,
+	        webhook_token_hash TEXT,
+	        deployed_commit TEXT,
+	        deployed_at DATETIME,
+	        last_sync_at DATETIME,
+	        last_sync_status TEXT,
+	        last_sync_error TEXT,
+	        failed_commit TEXT,
+	        created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	        updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	    );
+	`)
+	if err != nil {
+		return err
+	}
+	if err := ensureTableColumns("git_stacks", []columnSpec{
+		{Name: "failed_commit", AddColumnSQL: "failed_commit TEXT"},
+		{Name: "webhook_token_hash", AddColumnSQL: "webhook_token_hash TEXT"},
+	}); err != nil {
+		return err
+	}
+	if err := migrateGitStackWebhookTokens(); err != nil {
+		return err
+	}
+
+	_, err = db.Exec(`
+	    CREATE TABLE IF NOT EXISTS compose_webhooks (
+	        id INTEGER PRIMARY KEY AUTOINCREMENT,
+	        project TEXT NOT NULL,
+	        name TEXT,
+	        token_hash TEXT NOT NULL UNIQUE,
+	        hmac_secret TEXT,
+	        enabled INTEGER DEFAULT 1,
+	        trigger_count INTEGER DEFAULT 0,
+	        last_triggered_at DATETIME,
+	        last_task_id TEXT,
+	        created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	    );
+	`)
+	if err != nil {
+		return err
+	}
+	_, _ = db.Exec(`CREATE INDEX IF NOT EXISTS idx_compose_webhooks_project ON compose_webhooks(project)`)
+
+	_, err = db.Exec(`
+	    CREATE TABLE IF NOT EXISTS compose_project_options (
+	        project TEXT PRIMARY KEY,
+	        compose_files TEXT,
+	        profiles TEXT,
+	        env_files TEXT,
+	        updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	    );
+	`)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec(`
+	    CREATE TABLE IF NOT EXISTS compose_dependencies (
+	        project TEXT NOT NULL,
+	        depends_on TEXT NOT NULL,
+	        created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	        PRIMARY KEY (project, depends_on)
+	    );
+	`)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec(`
+	    CREATE TABLE IF NOT EXISTS scheduled_jobs (
+	        id INTEGER PRIMARY KEY AUTOINCREMENT,
+	        name TEXT NOT NULL,
+	        action TEXT NOT NULL,
+	        target TEXT,
+	        command TEXT,
+	        cron_expr TEXT NOT NULL,
+	        enabled INTEGER DEFAULT 1,
+	        timeout_seconds INTEGER DEFAULT 0,
+	        last_run_at DATETIME,
+	        last_status TEXT,
+	        last_task_id TEXT,
+	        last_error TEXT,
+	        created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	        updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	    );
+	`)
+	if err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -0,0 +1,163 @@
+package database
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"time"
+)
+
+// GitStack 以 Git 仓库为来源的 Compose 项目
+type GitStack struct {
+	Project          string `json:"project"`
+	RepoURL          string `json:"repoUrl"`
+	Branch           string `json:"branch"`
+	ComposePath      string `json:"composePath"`
+	Username         string `json:"username"`
+	Password         string `json:"-"`
+	AutoSync         bool   `json:"autoSync"`
+	SyncInterval     int    `json:"syncInterval"` // 秒
+	WebhookTokenHash string `json:"-"`            // Webhook 令牌的 sha256，明文只在生成时返回一次
+	// 同步状态
+	DeployedCommit string     `json:"deployedCommit"`
+	DeployedAt     *time.Time `json:"deployedAt"`
+	LastSyncAt     *time.Time `json:"lastSyncAt"`
+	LastSyncStatus string     `json:"lastSyncStatus"` // success / unchanged / error
+	LastSyncError  string     `json:"lastSyncError"`
+	FailedCommit   string     `json:"failedCommit"` // 最近一次同步失败的提交，成功后清空
+	CreatedAt      time.Time  `json:"createdAt"`
+	UpdatedAt      time.Time  `json:"updatedAt"`
+}
+
+const gitStackColumns = `project, repo_url, branch, COALESCE(compose_path, ''), COALESCE(username, ''),
+        COALESCE(password, ''), auto_sync, sync_interval, COALESCE(webhook_token_hash, ''), COALESCE(deployed_commit, ''),
+        deployed_at, last_sync_at, COALESCE(last_sync_status, ''), COALESCE(last_sync_error, ''), COALESCE(failed_commit, ''), created_at, updated_at`
+
+func nullTimePtr(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	v := t.Time
+	return &v
+}
+
+func scanGitStack(row rowScanner) (GitStack, error) {
+	var s GitStack
+	var autoSync int
+	var deployedAt, lastSyncAt sql.NullTime
+	err := row.Scan(&s.Project, &s.RepoURL, &s.Branch, &s.ComposePath, &s.Username, &s.Password, &autoSync,
+		&s.SyncInterval, &s.WebhookTokenHash, &s.DeployedCommit, &deployedAt, &lastSyncAt, &s.LastSyncStatus,
+		&s.LastSyncError, &s.FailedCommit, &s.CreatedAt, &s.UpdatedAt)
+	s.AutoSync = autoSync != 0
+	s.DeployedAt = nullTimePtr(deployedAt)
+	s.LastSyncAt = nullTimePtr(lastSyncAt)
+	return s, err
+}
+
+// ListGitStacks 列出全部 Git 来源项目
+func ListGitStacks() ([]GitStack, error) {
+	rows, err := GetDB().Query(`SELECT ` + gitStackColumns + ` FROM git_stacks ORDER BY project`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	list := make([]GitStack, 0)
+	for rows.Next() {
+		s, err := scanGitStack(rows)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, s)
+	}
+	return list, rows.Err()
+}
+
+// GetGitStack 获取项目的 Git 来源，不存在时返回 sql.ErrNoRows
+func GetGitStack(project string) (GitStack, error) {
+	return scanGitStack(GetDB().QueryRow(`SELECT `+gitStackColumns+` FROM git_stacks WHERE project = ?`, project))
+}
+
+// SaveGitStack 新建或更新项目的来源配置（不修改同步状态），保存后回填数据库中的内容
+func SaveGitStack(s *GitStack) error {
+	_, err := GetDB().Exec(`INSERT INTO git_stacks (project, repo_url, branch, compose_path, username, password,
+        auto_sync, sync_interval, webhook_token_hash, created_at, updated_at)
+        VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
+        ON CONFLICT(project) DO UPDATE SET repo_url = excluded.repo_url, branch = excluded.branch,
+            compose_path = excluded.compose_path, username = excluded.username, password = excluded.password,
+            auto_sync = excluded.auto_sync, sync_interval = excluded.sync_interval,
+            webhook_token_hash = excluded.webhook_token_hash, updated_at = CURRENT_TIMESTAMP`,
+		s.Project, s.RepoURL, s.Branch, s.ComposePath, s.Username, s.Password, boolToInt(s.AutoSync),
+		s.SyncInterval, s.WebhookTokenHash)
+	if err != nil {
+		return err
+	}
+	saved, err := GetGitStack(s.Project)
+	if err != nil {
+		return err
+	}
+	*s = saved
+	return nil
+}
+
+// UpdateGitStackSync 记录一次同步结果，deployedCommit 非空时同时更新已部署的提交；状态不是 error 时清空失败的提交
+func UpdateGitStackSync(project, status, errStr, deployedCommit string) error {
+	now := time.Now()
+	if deployedCommit != "" {
+		_, err := GetDB().Exec(`UPDATE git_stacks SET last_sync_at = ?, last_sync_status = ?, last_sync_error = ?,
+            deployed_commit = ?, deployed_at = ?, failed_commit = '' WHERE project = ?`, now, status, errStr, deployedCommit, now, project)
+		return err
+	}
+	_, err := GetDB().Exec(`UPDATE git_stacks SET last_sync_at = ?, last_sync_status = ?, last_sync_error = ?,
+            failed_commit = CASE WHEN ? = 'error' THEN failed_commit ELSE '' END
+        WHERE project = ?`, now, status, errStr, status, project)
+	return err
+}
+
+// MarkGitStackSyncFailed 记录同步失败，commit 为空（未取得远端提交）时保留之前记录的失败提交
+func MarkGitStackSyncFailed(project, errStr, commit string) error {
+	_, err := GetDB().Exec(`UPDATE git_stacks SET last_sync_at = ?, last_sync_status = 'error', last_sync_error = ?,
+            failed_commit = COALESCE(NULLIF(?, ''), failed_commit) WHERE project = ?`, time.Now(), errStr, commit, project)
+	return err
+}
+
+// DeleteGitStack 删除项目的 Git 来源配置
+func DeleteGitStack(project string) error {
+	res, err := GetDB().Exec(`DELETE FROM git_stacks WHERE project = ?`, project)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// migrateGitStackWebhookTokens 将旧版本明文保存的 Webhook 令牌转换为 sha256，原令牌继续有效
+func migrateGitStackWebhookTokens() error {
+	rows, err := db.Query(`SELECT project, webhook_token FROM git_stacks
+        WHERE COALESCE(webhook_token, '') != '' AND COALESCE(webhook_token_hash, '') = ''`)
+	if err != nil {
+		return err
+	}
+	tokens := make(map[string]string)
+	for rows.Next() {
+		var project, token string
+		if err := rows.Scan(&project, &token); err != nil {
+			rows.Close()
+			return err
+		}
+		tokens[project] = token
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	for project, token := range tokens {
+		sum := sha256.Sum256([]byte(token))
+		if _, err := db.Exec(`UPDATE git_stacks SET webhook_token_hash = ?, webhook_token = NULL WHERE project = ?`,
+			hex.EncodeToString(sum[:]), project); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,204 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"time"
+)
+
+// logAlertEventKeep 全局保留的日志告警记录条数
+const logAlertEventKeep = 1000
+
+// LogAlertRule 日志告警规则：作用范围内的容器日志满足匹配条件的次数在窗口内达到阈值时告警
+type LogAlertRule struct {
+	ID      int64  `json:"id"`
+	Name    string `json:"name"`
+	Enabled bool   `json:"enabled"`
+	// 作用范围，各项同时满足，为空表示不限
+	Containers []string `json:"containers"` // 容器名，支持 * ? 通配
+	Projects   []string `json:"projects"`
+	Services   []string `json:"services"`
+	Labels     []string `json:"labels"` // key 或 key=value
+	// 匹配条件，各项同时满足
+	Pattern string   `json:"pattern"` // 正则表达式
+	Levels  []string `json:"levels"`
+	Fields  []string `json:"fields"` // 结构化日志字段，key 或 key=value
+	// 触发与限流
+	Threshold       int       `json:"threshold"`
+	WindowSeconds   int       `json:"windowSeconds"`
+	CooldownSeconds int       `json:"cooldownSeconds"`
+	CreatedAt       time.Time `json:"createdAt"`
+	UpdatedAt       time.Time `json:"updatedAt"`
+}
+
+// LogAlertEvent 一次触发的日志告警
+type LogAlertEvent struct {
+	ID            int64     `json:"id"`
+	RuleID        int64     `json:"ruleId"`
+	RuleName      string    `json:"ruleName"`
+	ContainerName string    `json:"containerName"`
+	Project       string    `json:"project"`
+	Service       string    `json:"service"`
+	MatchCount    int       `json:"matchCount"`
+	Suppressed    int       `json:"suppressed"`
+	Excerpt       string    `json:"excerpt"`
+	CreatedAt     time.Time `json:"createdAt"`
+}
+
+const logAlertRuleColumns = `id, name, enabled, COALESCE(containers, ''), COALESCE(projects, ''), COALESCE(services, ''),
+        COALESCE(labels, ''), COALESCE(pattern, ''), COALESCE(levels, ''), COALESCE(fields, ''),
+        threshold, window_seconds, cooldown_seconds, created_at, updated_at`
+
+func encodeStringList(list []string) string {
+	if len(list) == 0 {
+		return ""
+	}
+	b, _ := json.Marshal(list)
+	return string(b)
+}
+
+func decodeStringList(raw string) []string {
+	list := []string{}
+	if raw != "" {
+		_ = json.Unmarshal([]byte(raw), &list)
+	}
+	return list
+}
+
+func scanLogAlertRule(row rowScanner) (LogAlertRule, error) {
+	var r LogAlertRule
+	var enabled int
+	var containers, projects, services, labels, levels, fields string
+	err := row.Scan(&r.ID, &r.Name, &enabled, &containers, &projects, &services, &labels, &r.Pattern, &levels, &fields,
+		&r.Threshold, &r.WindowSeconds, &r.CooldownSeconds, &r.CreatedAt, &r.UpdatedAt)
+	r.Enabled = enabled != 0
+	r.Containers = decodeStringList(containers)
+	r.Projects = decodeStringList(projects)
+	r.Services = decodeStringList(services)
+	r.Labels = decodeStringList(labels)
+	r.Levels = decodeStringList(levels)
+	r.Fields = decodeStringList(fields)
+	return r, err
+}
+
+// ListLogAlertRules 列出全部日志告警规则
+func ListLogAlertRules() ([]LogAlertRule, error) {
+	rows, err := GetDB().Query(`SELECT ` + logAlertRuleColumns + ` FROM log_alert_rules ORDER BY name`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	list := make([]LogAlertRule, 0)
+	for rows.Next() {
+		r, err := scanLogAlertRule(rows)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, r)
+	}
+	return list, rows.Err()
+}
+
+// GetLogAlertRule 获取规则，不存在时返回 sql.ErrNoRows
+func GetLogAlertRule(id int64) (LogAlertRule, error) {
+	return scanLogAlertRule(GetDB().QueryRow(`SELECT `+logAlertRuleColumns+` FROM log_alert_rules WHERE id = ?`, id))
+}
+
+// GetLogAlertRuleByName 按名称获取规则，不存在时返回 sql.ErrNoRows
+func GetLogAlertRuleByName(name string) (LogAlertRule, error) {
+	return scanLogAlertRule(GetDB().QueryRow(`SELECT `+logAlertRuleColumns+` FROM log_alert_rules WHERE name = ?`, name))
+}
+
+// SaveLogAlertRule ID 为 0 时新建，否则更新，保存后回填数据库中的内容
+func SaveLogAlertRule(r *LogAlertRule) error {
+	args := []any{r.Name, boolToInt(r.Enabled), encodeStringList(r.Containers), encodeStringList(r.Projects),
+		encodeStringList(r.Services), encodeStringList(r.Labels), r.Pattern, encodeStringList(r.Levels),
+		encodeStringList(r.Fields), r.Threshold, r.WindowSeconds, r.CooldownSeconds}
+	if r.ID == 0 {
+		res, err := GetDB().Exec(`INSERT INTO log_alert_rules (name, enabled, containers, projects, services, labels,
+            pattern, levels, fields, threshold, window_seconds, cooldown_seconds, created_at, updated_at)
+            VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)`, args...)
+		if err != nil {
+			return err
+		}
+		r.ID, _ = res.LastInsertId()
+	} else {
+		res, err := GetDB().Exec(`UPDATE log_alert_rules SET name = ?, enabled = ?, containers = ?, projects = ?, services = ?,
+            labels = ?, pattern = ?, levels = ?, fields = ?, threshold = ?, window_seconds = ?, cooldown_seconds = ?,
+            updated_at = CURRENT_TIMESTAMP WHERE id = ?`, append(args, r.ID)...)
+		if err != nil {
+			return err
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			return sql.ErrNoRows
+		}
+	}
+	saved, err := GetLogAlertRule(r.ID)
+	if err != nil {
+		return err
+	}
+	*r = saved
+	return nil
+}
+
+// DeleteLogAlertRule 删除规则及其告警记录
+func DeleteLogAlertRule(id int64) error {
+	res, err := GetDB().Exec(`DELETE FROM log_alert_rules WHERE id = ?`, id)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	_, err = GetDB().Exec(`DELETE FROM log_alert_events WHERE rule_id = ?`, id)
+	return err
+}
+
+// AddLogAlertEvent 记录一次告警，并裁剪最旧的记录
+func AddLogAlertEvent(e *LogAlertEvent) error {
+	if e.CreatedAt.IsZero() {
+		e.CreatedAt = time.Now()
+	}
+	res, err := GetDB().Exec(`INSERT INTO log_alert_events (rule_id, rule_name, container_name, project, service,
+        match_count, suppressed, excerpt, created_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		e.RuleID, e.RuleName, e.ContainerName, e.Project, e.Service, e.MatchCount, e.Suppressed, e.Excerpt, e.CreatedAt)
+	if err != nil {
+		return err
+	}
+	e.ID, _ = res.LastInsertId()
+	_, err = GetDB().Exec(`DELETE FROM log_alert_events WHERE id NOT IN (
+        SELECT id FROM log_alert_events ORDER BY id DESC LIMIT ?)`, logAlertEventKeep)
+	return err
+}
+
+// ListLogAlertEvents 按时间倒序列出告警记录，ruleID 为 0 时返回全部
+func ListLogAlertEvents(ruleID int64, limit int) ([]LogAlertEvent, error) {
+	if limit <= 0 || limit > 500 {
+		limit = 50
+	}
+	query := `SELECT id, rule_id, COALESCE(rule_name, ''), COALESCE(container_name, ''), COALESCE(project, ''),
+        COALESCE(service, ''), match_count, suppressed, COALESCE(excerpt, ''), created_at FROM log_alert_events`
+	args := []any{}
+	if ruleID > 0 {
+		query += ` WHERE rule_id = ?`
+		args = append(args, ruleID)
+	}
+	query += ` ORDER BY id DESC LIMIT ?`
+	args = append(args, limit)
+
+	rows, err := GetDB().Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	list := make([]LogAlertEvent, 0)
+	for rows.Next() {
+		var e LogAlertEvent
+		if err := rows.Scan(&e.ID, &e.RuleID, &e.RuleName, &e.ContainerName, &e.Project, &e.Service,
+			&e.MatchCount, &e.Suppressed, &e.Excerpt, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+		list = append(list, e)
+	}
+	return list, rows.Err()
+}
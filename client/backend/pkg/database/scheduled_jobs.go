@@ -0,0 +1,114 @@
+package database
+
+import (
+	"database/sql"
+	"time"
+)
+
+// ScheduledJob 定时任务：按 cron 表达式对项目、容器或镜像执行操作，每次执行记录为一个任务
+type ScheduledJob struct {
+	ID             int64    `json:"id"`
+	Name           string   `json:"name"`
+	Action         string   `json:"action"`
+	Target         string   `json:"target"`  // 项目名或容器 ID/名称，镜像清理时为空
+	Command        []string `json:"command"` // container_exec 执行的命令
+	CronExpr       string   `json:"cron"`
+	Enabled        bool     `json:"enabled"`
+	TimeoutSeconds int      `json:"timeoutSeconds"` // 0 表示使用默认超时
+	// 最近一次执行
+	LastRunAt  *time.Time `json:"lastRunAt"`
+	LastStatus string     `json:"lastStatus"` // running / success / error
+	LastTaskID string     `json:"lastTaskId"`
+	LastError  string     `json:"lastError"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	UpdatedAt  time.Time  `json:"updatedAt"`
+}
+
+const scheduledJobColumns = `id, name, action, COALESCE(target, ''), COALESCE(command, ''), cron_expr, enabled,
+        timeout_seconds, last_run_at, COALESCE(last_status, ''), COALESCE(last_task_id, ''), COALESCE(last_error, ''),
+        created_at, updated_at`
+
+func scanScheduledJob(row rowScanner) (ScheduledJob, error) {
+	var j ScheduledJob
+	var command string
+	var enabled int
+	var lastRun sql.NullTime
+	err := row.Scan(&j.ID, &j.Name, &j.Action, &j.Target, &command, &j.CronExpr, &enabled, &j.TimeoutSeconds,
+		&lastRun, &j.LastStatus, &j.LastTaskID, &j.LastError, &j.CreatedAt, &j.UpdatedAt)
+	j.Command = decodeStringList(command)
+	j.Enabled = enabled != 0
+	j.LastRunAt = nullTimePtr(lastRun)
+	return j, err
+}
+
+// ListScheduledJobs 列出全部定时任务
+func ListScheduledJobs() ([]ScheduledJob, error) {
+	rows, err := GetDB().Query(`SELECT ` + scheduledJobColumns + ` FROM scheduled_jobs ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	list := make([]ScheduledJob, 0)
+	for rows.Next() {
+		j, err := scanScheduledJob(rows)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, j)
+	}
+	return list, rows.Err()
+}
+
+// GetScheduledJob 获取定时任务，不存在时返回 sql.ErrNoRows
+func GetScheduledJob(id int64) (ScheduledJob, error) {
+	return scanScheduledJob(GetDB().QueryRow(`SELECT `+scheduledJobColumns+` FROM scheduled_jobs WHERE id = ?`, id))
+}
+
+// SaveScheduledJob ID 为 0 时新建，否则更新配置（不修改执行状态），保存后回填数据库中的内容
+func SaveScheduledJob(j *ScheduledJob) error {
+	now := time.Now()
+	if j.ID == 0 {
+		res, err := GetDB().Exec(`INSERT INTO scheduled_jobs (name, action, target, command, cron_expr, enabled,
+            timeout_seconds, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			j.Name, j.Action, j.Target, encodeStringList(j.Command), j.CronExpr, boolToInt(j.Enabled), j.TimeoutSeconds, now, now)
+		if err != nil {
+			return err
+		}
+		j.ID, _ = res.LastInsertId()
+	} else {
+		res, err := GetDB().Exec(`UPDATE scheduled_jobs SET name = ?, action = ?, target = ?, command = ?, cron_expr = ?,
+            enabled = ?, timeout_seconds = ?, updated_at = ? WHERE id = ?`,
+			j.Name, j.Action, j.Target, encodeStringList(j.Command), j.CronExpr, boolToInt(j.Enabled), j.TimeoutSeconds, now, j.ID)
+		if err != nil {
+			return err
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			return sql.ErrNoRows
+		}
+	}
+	saved, err := GetScheduledJob(j.ID)
+	if err != nil {
+		return err
+	}
+	*j = saved
+	return nil
+}
+
+// MarkScheduledJobRun 记录定时任务最近一次执行的状态
+func MarkScheduledJobRun(id int64, at time.Time, status, taskID, errStr string) error {
+	_, err := GetDB().Exec(`UPDATE scheduled_jobs SET last_run_at = ?, last_status = ?, last_task_id = ?, last_error = ?
+        WHERE id = ?`, at, status, taskID, errStr, id)
+	return err
+}
+
+// DeleteScheduledJob 删除定时任务
+func DeleteScheduledJob(id int64) error {
+	res, err := GetDB().Exec(`DELETE FROM scheduled_jobs WHERE id = ?`, id)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
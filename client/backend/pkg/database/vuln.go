@@ -0,0 +1,181 @@
+package database
+
+import (
+	"database/sql"
+	"strings"
+	"time"
+)
+
+// VulnAdvisoryRecord 漏洞库中的一条记录，DataJSON 为完整的公告内容
+type VulnAdvisoryRecord struct {
+	AdvisoryID string
+	Ecosystem  string
+	Package    string
+	Severity   string
+	DataJSON   string
+}
+
+// VulnEcosystemStat 按生态统计的漏洞库条目数
+type VulnEcosystemStat struct {
+	Ecosystem string `json:"ecosystem"`
+	Count     int    `json:"count"`
+}
+
+// ImageScanRecord 镜像扫描结果缓存（按镜像 ID）
+type ImageScanRecord struct {
+	ImageID    string    `json:"imageId"`
+	DBRevision string    `json:"dbRevision"`
+	Critical   int       `json:"critical"`
+	High       int       `json:"high"`
+	Medium     int       `json:"medium"`
+	Low        int       `json:"low"`
+	Unknown    int       `json:"unknown"`
+	ResultJSON string    `json:"-"`
+	ScannedAt  time.Time `json:"scannedAt"`
+}
+
+// ImportVulnAdvisories 批量导入漏洞库记录，相同 (advisory_id, ecosystem, package) 覆盖更新；
+// replace 为 true 时先清空漏洞库，清空与写入在同一事务中，导入失败时保留原有数据
+func ImportVulnAdvisories(records []VulnAdvisoryRecord, replace bool) (int, error) {
+	tx, err := GetDB().Begin()
+	if err != nil {
+		return 0, err
+	}
+	if replace {
+		if _, err := tx.Exec(`DELETE FROM vuln_advisories`); err != nil {
+			tx.Rollback()
+			return 0, err
+		}
+	}
+	stmt, err := tx.Prepare(`INSERT INTO vuln_advisories (advisory_id, ecosystem, package, severity, data_json, updated_at)
+        VALUES (?, ?, ?, ?, ?, CURRENT_TIMESTAMP)
+        ON CONFLICT(advisory_id, ecosystem, package) DO UPDATE SET severity=excluded.severity, data_json=excluded.data_json, updated_at=CURRENT_TIMESTAMP`)
+	if err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+	defer stmt.Close()
+
+	n := 0
+	for _, r := range records {
+		if _, err := stmt.Exec(r.AdvisoryID, r.Ecosystem, r.Package, r.Severity, r.DataJSON); err != nil {
+			tx.Rollback()
+			return 0, err
+		}
+		n++
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+
+// GetVulnAdvisoriesByPackages 按包名查询漏洞库，返回各条记录的 data_json
+func GetVulnAdvisoriesByPackages(names []string) ([]string, error) {
+	out := make([]string, 0)
+	// SQLite 默认变量上限为 999，分批查询
+	const batch = 500
+	for start := 0; start < len(names); start += batch {
+		end := start + batch
+		if end > len(names) {
+			end = len(names)
+		}
+		chunk := names[start:end]
+		args := make([]interface{}, len(chunk))
+		for i, n := range chunk {
+			args[i] = n
+		}
+		placeholders := strings.TrimSuffix(strings.Repeat("?,", len(chunk)), ",")
+		rows, err := GetDB().Query(`SELECT data_json FROM vuln_advisories WHERE package IN (`+placeholders+`)`, args...)
+		if err != nil {
+			return nil, err
+		}
+		for rows.Next() {
+			var data sql.NullString
+			if err := rows.Scan(&data); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			if data.Valid && data.String != "" {
+				out = append(out, data.String)
+			}
+		}
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+// GetVulnAdvisoryStats 按生态统计漏洞库条目数
+func GetVulnAdvisoryStats() ([]VulnEcosystemStat, error) {
+	rows, err := GetDB().Query(`SELECT ecosystem, COUNT(*) FROM vuln_advisories GROUP BY ecosystem ORDER BY ecosystem`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := make([]VulnEcosystemStat, 0)
+	for rows.Next() {
+		var s VulnEcosystemStat
+		if err := rows.Scan(&s.Ecosystem, &s.Count); err != nil {
+			return nil, err
+		}
+		out = append(out, s)
+	}
+	return out, rows.Err()
+}
+
+// SaveImageScan 保存镜像扫描结果
+func SaveImageScan(r ImageScanRecord) error {
+	_, err := GetDB().Exec(`INSERT INTO image_scans (image_id, db_revision, critical, high, medium, low, unknown, result_json, scanned_at)
+        VALUES (?, ?, ?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP)
+        ON CONFLICT(image_id) DO UPDATE SET db_revision=excluded.db_revision, critical=excluded.critical, high=excluded.high,
+        medium=excluded.medium, low=excluded.low, unknown=excluded.unknown, result_json=excluded.result_json, scanned_at=CURRENT_TIMESTAMP`,
+		r.ImageID, r.DBRevision, r.Critical, r.High, r.Medium, r.Low, r.Unknown, r.ResultJSON)
+	return err
+}
+
+// GetImageScan 获取单个镜像的扫描缓存，不存在时返回 nil
+func GetImageScan(imageID string) (*ImageScanRecord, error) {
+	var r ImageScanRecord
+	var rev, result sql.NullString
+	err := GetDB().QueryRow(`SELECT image_id, db_revision, critical, high, medium, low, unknown, result_json, scanned_at FROM image_scans WHERE image_id = ?`, imageID).
+		Scan(&r.ImageID, &rev, &r.Critical, &r.High, &r.Medium, &r.Low, &r.Unknown, &result, &r.ScannedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	r.DBRevision = rev.String
+	r.ResultJSON = result.String
+	return &r, nil
+}
+
+// GetImageScanSummaries 获取所有镜像扫描的计数摘要（不含完整结果），按镜像 ID 索引
+func GetImageScanSummaries() (map[string]ImageScanRecord, error) {
+	rows, err := GetDB().Query(`SELECT image_id, db_revision, critical, high, medium, low, unknown, scanned_at FROM image_scans`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := make(map[string]ImageScanRecord)
+	for rows.Next() {
+		var r ImageScanRecord
+		var rev sql.NullString
+		if err := rows.Scan(&r.ImageID, &rev, &r.Critical, &r.High, &r.Medium, &r.Low, &r.Unknown, &r.ScannedAt); err != nil {
+			return nil, err
+		}
+		r.DBRevision = rev.String
+		out[r.ImageID] = r
+	}
+	return out, rows.Err()
+}
+
+// DeleteImageScan 删除镜像扫描缓存
+func DeleteImageScan(imageID string) error {
+	_, err := GetDB().Exec(`DELETE FROM image_scans WHERE image_id = ?`, imageID)
+	return err
+}
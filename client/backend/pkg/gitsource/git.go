@@ -0,0 +1,193 @@
+// Package gitsource 通过 git 命令行克隆、拉取作为 Compose 项目来源的仓库
+//
+// 凭据通过环境变量形式的 git 配置（GIT_CONFIG_COUNT）以 HTTP 头传递，不写入仓库配置，也不出现在命令行参数中。
+package gitsource
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Auth HTTP(S) 仓库的认证信息，Password 也可以是访问令牌
+type Auth struct {
+	Username string
+	Password string
+}
+
+// Commit 提交信息
+type Commit struct {
+	Hash    string    `json:"hash"`
+	Author  string    `json:"author"`
+	Subject string    `json:"subject"`
+	Time    time.Time `json:"time"`
+}
+
+// Short 返回缩写的提交哈希
+func (c Commit) Short() string {
+	if len(c.Hash) > 8 {
+		return c.Hash[:8]
+	}
+	return c.Hash
+}
+
+var branchPattern = regexp.MustCompile(`^[A-Za-z0-9._/-]+$`)
+
+// ValidateRepoURL 仅允许 http(s) 与 file:// 仓库地址
+func ValidateRepoURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return err
+	}
+	switch u.Scheme {
+	case "http", "https":
+		if u.Host == "" {
+			return errors.New("仓库地址缺少主机名")
+		}
+		if u.User != nil {
+			return errors.New("请通过用户名与密码字段提供凭据，不要写在仓库地址中")
+		}
+	case "file":
+		if u.Host != "" && u.Host != "localhost" || !strings.HasPrefix(u.Path, "/") {
+			return errors.New("file:// 地址必须是本机的绝对路径")
+		}
+	default:
+		return errors.New("仓库地址仅支持 http、https 与 file://")
+	}
+	return nil
+}
+
+// ValidateBranch 校验分支名
+func ValidateBranch(branch string) error {
+	if branch == "" || !branchPattern.MatchString(branch) || strings.HasPrefix(branch, "-") ||
+		strings.Contains(branch, "..") || strings.HasPrefix(branch, "/") || strings.HasSuffix(branch, "/") ||
+		strings.HasSuffix(branch, ".lock") || strings.Contains(branch, "//") {
+		return fmt.Errorf("分支名不合法: %q", branch)
+	}
+	return nil
+}
+
+// gitEnv 构造执行 git 的环境变量：禁止交互提示、限制传输协议，并注入认证头
+func gitEnv(auth Auth) []string {
+	env := append(os.Environ(), "GIT_TERMINAL_PROMPT=0", "GIT_ALLOW_PROTOCOL=http:https:file", "LC_ALL=C")
+	configs := [][2]string{{"safe.directory", "*"}}
+	if auth.Username != "" || auth.Password != "" {
+		token := base64.StdEncoding.EncodeToString([]byte(auth.Username + ":" + auth.Password))
+		configs = append(configs, [2]string{"http.extraHeader", "Authorization: Basic " + token})
+	}
+	env = append(env, fmt.Sprintf("GIT_CONFIG_COUNT=%d", len(configs)))
+	for i, kv := range configs {
+		env = append(env, fmt.Sprintf("GIT_CONFIG_KEY_%d=%s", i, kv[0]), fmt.Sprintf("GIT_CONFIG_VALUE_%d=%s", i, kv[1]))
+	}
+	return env
+}
+
+func run(ctx context.Context, dir string, auth Auth, args ...string) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = dir
+	cmd.Env = gitEnv(auth)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		if msg == "" {
+			return nil, err
+		}
+		return nil, fmt.Errorf("git %s: %s", args[0], msg)
+	}
+	return stdout.Bytes(), nil
+}
+
+func remoteRef(branch string) string {
+	return "refs/remotes/origin/" + branch
+}
+
+// Clone 将仓库的指定分支克隆到 dir（dir 不能已存在内容）
+func Clone(ctx context.Context, repoURL, branch, dir string, auth Auth) error {
+	if err := ValidateRepoURL(repoURL); err != nil {
+		return err
+	}
+	if err := ValidateBranch(branch); err != nil {
+		return err
+	}
+	_, err := run(ctx, "", auth, "clone", "--branch", branch, "--single-branch", "--", repoURL, dir)
+	return err
+}
+
+// Fetch 拉取远端分支并返回其最新提交哈希，不改动工作区
+func Fetch(ctx context.Context, dir, branch string, auth Auth) (string, error) {
+	if err := ValidateBranch(branch); err != nil {
+		return "", err
+	}
+	if _, err := run(ctx, dir, auth, "fetch", "--prune", "origin", "+refs/heads/"+branch+":"+remoteRef(branch)); err != nil {
+		return "", err
+	}
+	return RevParse(ctx, dir, remoteRef(branch))
+}
+
+// RevParse 解析提交哈希
+func RevParse(ctx context.Context, dir, rev string) (string, error) {
+	out, err := run(ctx, dir, Auth{}, "rev-parse", "--verify", "--end-of-options", rev+"^{commit}")
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// Show 读取某次提交中的文件内容，文件不存在时返回 os.ErrNotExist
+func Show(ctx context.Context, dir, rev, path string) ([]byte, error) {
+	if _, err := run(ctx, dir, Auth{}, "cat-file", "-e", rev+":"+path); err != nil {
+		return nil, os.ErrNotExist
+	}
+	return run(ctx, dir, Auth{}, "cat-file", "blob", rev+":"+path)
+}
+
+// Checkout 将工作区切换到指定提交（本地分支指向该提交），已跟踪文件的本地修改会被丢弃，未跟踪的文件保留
+func Checkout(ctx context.Context, dir, branch, rev string) error {
+	if err := ValidateBranch(branch); err != nil {
+		return err
+	}
+	_, err := run(ctx, dir, Auth{}, "checkout", "--force", "-B", branch, rev)
+	return err
+}
+
+// Head 返回当前检出的提交
+func Head(ctx context.Context, dir string) (Commit, error) {
+	return CommitInfo(ctx, dir, "HEAD")
+}
+
+// CommitInfo 读取提交的作者、标题与时间
+func CommitInfo(ctx context.Context, dir, rev string) (Commit, error) {
+	out, err := run(ctx, dir, Auth{}, "log", "-1", "--format=%H%x00%an%x00%s%x00%ct", "--end-of-options", rev)
+	if err != nil {
+		return Commit{}, err
+	}
+	parts := strings.SplitN(strings.TrimSpace(string(out)), "\x00", 4)
+	if len(parts) != 4 {
+		return Commit{}, fmt.Errorf("unexpected git log output: %q", out)
+	}
+	c := Commit{Hash: parts[0], Author: parts[1], Subject: parts[2]}
+	var sec int64
+	if _, err := fmt.Sscan(parts[3], &sec); err == nil {
+		c.Time = time.Unix(sec, 0)
+	}
+	return c, nil
+}
+
+// SetRemoteURL 修改仓库 origin 的地址
+func SetRemoteURL(ctx context.Context, dir, repoURL string) error {
+	if err := ValidateRepoURL(repoURL); err != nil {
+		return err
+	}
+	_, err := run(ctx, dir, Auth{}, "remote", "set-url", "--", "origin", repoURL)
+	return err
+}
@@ -0,0 +1,122 @@
+package gitsource
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+// newBareRepo 创建本地裸仓库并通过一个工作副本推送初始提交，返回 file:// 地址与用于继续提交的函数
+func newBareRepo(t *testing.T, files map[string]string) (string, func(files map[string]string, msg string)) {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	root := t.TempDir()
+	bare := filepath.Join(root, "origin.git")
+	work := filepath.Join(root, "work")
+	git := func(dir string, args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		cmd.Env = append(os.Environ(), "GIT_AUTHOR_NAME=Dev", "GIT_AUTHOR_EMAIL=dev@example.com",
+			"GIT_COMMITTER_NAME=Dev", "GIT_COMMITTER_EMAIL=dev@example.com")
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+	git(root, "init", "--bare", "-b", "main", bare)
+	git(root, "init", "-b", "main", work)
+	commit := func(files map[string]string, msg string) {
+		for name, content := range files {
+			if err := os.WriteFile(filepath.Join(work, name), []byte(content), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		git(work, "add", "-A")
+		git(work, "commit", "-m", msg)
+		git(work, "push", bare, "main")
+	}
+	commit(files, "initial")
+	return "file://" + bare, commit
+}
+
+func TestCloneFetchCheckout(t *testing.T) {
+	repo, commit := newBareRepo(t, map[string]string{"docker-compose.yml": "services: {}\n"})
+	ctx := context.Background()
+	dir := filepath.Join(t.TempDir(), "stack")
+	if err := Clone(ctx, repo, "main", dir, Auth{}); err != nil {
+		t.Fatal(err)
+	}
+	head, err := Head(ctx, dir)
+	if err != nil || head.Subject != "initial" || head.Author != "Dev" || head.Time.IsZero() {
+		t.Fatalf("head: %+v %v", head, err)
+	}
+
+	commit(map[string]string{"docker-compose.yml": "services:\n  web: {}\n"}, "add web")
+	latest, err := Fetch(ctx, dir, "main", Auth{})
+	if err != nil || latest == head.Hash {
+		t.Fatalf("fetch should see new commit: %s %v", latest, err)
+	}
+	content, err := Show(ctx, dir, latest, "docker-compose.yml")
+	if err != nil || string(content) != "services:\n  web: {}\n" {
+		t.Fatalf("show: %q %v", content, err)
+	}
+	if _, err := Show(ctx, dir, latest, "missing.yml"); !os.IsNotExist(err) {
+		t.Fatalf("missing file should report ErrNotExist, got %v", err)
+	}
+
+	// 工作区中的本地修改被丢弃，未跟踪的 .env 保留
+	_ = os.WriteFile(filepath.Join(dir, "docker-compose.yml"), []byte("local edit\n"), 0644)
+	_ = os.WriteFile(filepath.Join(dir, ".env"), []byte("SECRET=1\n"), 0644)
+	if err := Checkout(ctx, dir, "main", latest); err != nil {
+		t.Fatal(err)
+	}
+	if b, _ := os.ReadFile(filepath.Join(dir, "docker-compose.yml")); string(b) != "services:\n  web: {}\n" {
+		t.Fatalf("working tree not updated: %q", b)
+	}
+	if b, _ := os.ReadFile(filepath.Join(dir, ".env")); string(b) != "SECRET=1\n" {
+		t.Fatal("untracked .env should survive checkout")
+	}
+	if head, _ := Head(ctx, dir); head.Hash != latest || head.Short() != latest[:8] {
+		t.Fatalf("head after checkout: %+v", head)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	for _, ok := range []string{"https://github.com/a/b.git", "http://git.local:3000/a/b", "file:///srv/git/stack.git"} {
+		if err := ValidateRepoURL(ok); err != nil {
+			t.Errorf("%s: %v", ok, err)
+		}
+	}
+	for _, bad := range []string{"ext::sh -c id", "ssh://git@host/repo", "https://user:pw@host/repo", "file://relative/path", "-upload-pack=x", "/srv/git/repo"} {
+		if err := ValidateRepoURL(bad); err == nil {
+			t.Errorf("%s should be rejected", bad)
+		}
+	}
+	for _, ok := range []string{"main", "release/1.2", "feature_x-y"} {
+		if err := ValidateBranch(ok); err != nil {
+			t.Errorf("%s: %v", ok, err)
+		}
+	}
+	for _, bad := range []string{"", "-x", "a..b", "a b", "topic.lock", "a//b", "/main"} {
+		if err := ValidateBranch(bad); err == nil {
+			t.Errorf("%q should be rejected", bad)
+		}
+	}
+}
+
+func TestGitEnvCarriesCredentialsOutOfArgs(t *testing.T) {
+	env := gitEnv(Auth{Username: "bot", Password: "s3cret"})
+	found := false
+	for _, kv := range env {
+		if kv == "GIT_CONFIG_VALUE_1=Authorization: Basic Ym90OnMzY3JldA==" {
+			found = true
+		}
+	}
+	if !found {
+		t.Fatalf("auth header not injected: %v", env[len(env)-5:])
+	}
+}
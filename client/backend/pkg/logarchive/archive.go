@@ -0,0 +1,541 @@
+// Package logarchive 将容器日志持久化为按容器分目录、按大小与日期轮转的压缩文件，并提供检索
+//
+// 目录结构：<root>/<project>/<container>/current.log 为正在写入的明文 JSONL，
+// 轮转后压缩为 <first>_<last>.log.gz（UTC 时间，格式 20060102T150405Z），文件名即时间范围，检索时据此跳过无关文件。
+// 文件名只精确到秒，已轮转日志中最后一条的精确时间另存于 last.time，供断点续采使用。
+// 容器被重建（ID 变化）时按项目与名称归档到同一目录，因此更新前后的日志可以连续检索。
+package logarchive
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	currentFileName   = "current.log"
+	lastTimeFileName  = "last.time"
+	archiveSuffix     = ".log.gz"
+	archiveTimeLayout = "20060102T150405Z"
+	standaloneProject = "_standalone"
+
+	DefaultMaxFileBytes  = 16 * 1024 * 1024
+	DefaultRetentionDays = 14
+)
+
+// Record 一行归档日志
+type Record struct {
+	Time        time.Time `json:"t"`
+	ContainerID string    `json:"c"`
+	Container   string    `json:"n"`
+	Project     string    `json:"p,omitempty"`
+	Stream      string    `json:"s"`
+	Level       string    `json:"l,omitempty"`
+	Message     string    `json:"m"`
+}
+
+// Options 轮转与保留策略
+type Options struct {
+	MaxFileBytes  int64
+	RetentionDays int
+}
+
+func (o Options) normalized() Options {
+	if o.MaxFileBytes <= 0 {
+		o.MaxFileBytes = DefaultMaxFileBytes
+	}
+	if o.RetentionDays <= 0 {
+		o.RetentionDays = DefaultRetentionDays
+	}
+	return o
+}
+
+type segment struct {
+	path  string
+	f     *os.File
+	w     *bufio.Writer
+	size  int64
+	first time.Time
+	last  time.Time
+}
+
+// Store 日志归档存储，可被多个采集协程并发写入
+type Store struct {
+	root string
+
+	mu       sync.Mutex
+	opts     Options
+	segments map[string]*segment // key: 容器目录
+}
+
+// NewStore 创建归档存储，root 不存在时在首次写入时创建
+func NewStore(root string, opts Options) *Store {
+	return &Store{root: root, opts: opts.normalized(), segments: make(map[string]*segment)}
+}
+
+// SetOptions 更新轮转与保留策略
+func (s *Store) SetOptions(opts Options) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.opts = opts.normalized()
+}
+
+var unsafePathChars = regexp.MustCompile(`[^a-zA-Z0-9_.-]+`)
+
+// sanitizeSegment 将项目名 / 容器名转换为安全的目录名
+func sanitizeSegment(name string) string {
+	name = unsafePathChars.ReplaceAllString(strings.TrimSpace(name), "_")
+	name = strings.Trim(name, ".")
+	if name == "" {
+		return "_"
+	}
+	return name
+}
+
+func (s *Store) sourceDir(project string, container string) string {
+	if strings.TrimSpace(project) == "" {
+		project = standaloneProject
+	}
+	return filepath.Join(s.root, sanitizeSegment(project), sanitizeSegment(container))
+}
+
+// Append 写入一条日志，必要时先轮转当前文件
+func (s *Store) Append(r Record) error {
+	line, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	dir := s.sourceDir(r.Project, r.Container)
+	seg, err := s.openSegment(dir)
+	if err != nil {
+		return err
+	}
+	if seg.size > 0 && (seg.size+int64(len(line)) > s.opts.MaxFileBytes || !sameUTCDay(seg.first, r.Time)) {
+		if err := s.rotate(dir, seg); err != nil {
+			return err
+		}
+		if seg, err = s.openSegment(dir); err != nil {
+			return err
+		}
+	}
+	if _, err := seg.w.Write(line); err != nil {
+		return err
+	}
+	seg.size += int64(len(line))
+	if seg.first.IsZero() {
+		seg.first = r.Time
+	}
+	if r.Time.After(seg.last) {
+		seg.last = r.Time
+	}
+	return nil
+}
+
+func sameUTCDay(a time.Time, b time.Time) bool {
+	if a.IsZero() {
+		return true
+	}
+	ay, am, ad := a.UTC().Date()
+	by, bm, bd := b.UTC().Date()
+	return ay == by && am == bm && ad == bd
+}
+
+// openSegment 打开（或续写）目录下的 current.log，调用方需持有锁
+func (s *Store) openSegment(dir string) (*segment, error) {
+	if seg := s.segments[dir]; seg != nil {
+		return seg, nil
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	path := filepath.Join(dir, currentFileName)
+	seg := &segment{path: path}
+	// 续写已有文件时恢复时间范围
+	if err := scanFile(path, func(r Record) bool {
+		if seg.first.IsZero() {
+			seg.first = r.Time
+		}
+		if r.Time.After(seg.last) {
+			seg.last = r.Time
+		}
+		return true
+	}); err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, err
+	}
+	if info, err := f.Stat(); err == nil {
+		seg.size = info.Size()
+	}
+	seg.f = f
+	seg.w = bufio.NewWriterSize(f, 64*1024)
+	s.segments[dir] = seg
+	return seg, nil
+}
+
+// rotate 压缩 current.log 为带时间范围的归档文件，调用方需持有锁
+func (s *Store) rotate(dir string, seg *segment) error {
+	if err := seg.w.Flush(); err != nil {
+		return err
+	}
+	if err := seg.f.Close(); err != nil {
+		return err
+	}
+	delete(s.segments, dir)
+
+	name := archiveFileName(seg.first, seg.last)
+	dst := filepath.Join(dir, name)
+	for i := 1; fileExists(dst); i++ {
+		dst = filepath.Join(dir, strings.TrimSuffix(name, archiveSuffix)+fmt.Sprintf("-%d", i)+archiveSuffix)
+	}
+	if err := gzipFile(seg.path, dst); err != nil {
+		return err
+	}
+	if err := os.Remove(seg.path); err != nil {
+		return err
+	}
+	return writeLastTime(dir, seg.last)
+}
+
+// writeLastTime 记录已轮转日志的最后时间（纳秒精度），只会向后推进
+func writeLastTime(dir string, t time.Time) error {
+	if t.IsZero() || !t.After(readLastTime(dir)) {
+		return nil
+	}
+	path := filepath.Join(dir, lastTimeFileName)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, []byte(t.UTC().Format(time.RFC3339Nano)), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+func readLastTime(dir string) time.Time {
+	data, err := os.ReadFile(filepath.Join(dir, lastTimeFileName))
+	if err != nil {
+		return time.Time{}
+	}
+	t, err := time.Parse(time.RFC3339Nano, strings.TrimSpace(string(data)))
+	if err != nil {
+		return time.Time{}
+	}
+	return t
+}
+
+func archiveFileName(first time.Time, last time.Time) string {
+	return first.UTC().Format(archiveTimeLayout) + "_" + last.UTC().Format(archiveTimeLayout) + archiveSuffix
+}
+
+// parseArchiveFileName 从归档文件名解析时间范围
+func parseArchiveFileName(name string) (time.Time, time.Time, bool) {
+	if !strings.HasSuffix(name, archiveSuffix) {
+		return time.Time{}, time.Time{}, false
+	}
+	base := strings.TrimSuffix(name, archiveSuffix)
+	firstRaw, lastRaw, ok := strings.Cut(base, "_")
+	if !ok {
+		return time.Time{}, time.Time{}, false
+	}
+	if i := strings.Index(lastRaw, "-"); i > 0 {
+		lastRaw = lastRaw[:i]
+	}
+	first, err1 := time.Parse(archiveTimeLayout, firstRaw)
+	last, err2 := time.Parse(archiveTimeLayout, lastRaw)
+	if err1 != nil || err2 != nil {
+		return time.Time{}, time.Time{}, false
+	}
+	// 文件名精确到秒，结束时间向上取整
+	return first, last.Add(time.Second), true
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+func gzipFile(src string, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	tmp := dst + ".tmp"
+	out, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	zw := gzip.NewWriter(out)
+	if _, err := io.Copy(zw, in); err != nil {
+		out.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		out.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := out.Close(); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, dst)
+}
+
+// Flush 将缓冲写入磁盘
+func (s *Store) Flush() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var firstErr error
+	for _, seg := range s.segments {
+		if err := seg.w.Flush(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// Close 刷新并关闭所有打开的文件
+func (s *Store) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var firstErr error
+	for dir, seg := range s.segments {
+		if err := seg.w.Flush(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+		if err := seg.f.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+		delete(s.segments, dir)
+	}
+	return firstErr
+}
+
+// CloseSource 关闭某个容器目录的写入文件（采集结束时调用，避免长期占用文件句柄）
+func (s *Store) CloseSource(project string, container string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	dir := s.sourceDir(project, container)
+	seg := s.segments[dir]
+	if seg == nil {
+		return nil
+	}
+	delete(s.segments, dir)
+	if err := seg.w.Flush(); err != nil {
+		seg.f.Close()
+		return err
+	}
+	return seg.f.Close()
+}
+
+// LastTime 返回某个容器目录中最新一条日志的时间，用于断点续采
+func (s *Store) LastTime(project string, container string) time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	dir := s.sourceDir(project, container)
+	if seg := s.segments[dir]; seg != nil && !seg.last.IsZero() {
+		return seg.last
+	}
+	last := readLastTime(dir)
+	_ = scanFile(filepath.Join(dir, currentFileName), func(r Record) bool {
+		if r.Time.After(last) {
+			last = r.Time
+		}
+		return true
+	})
+	if !last.IsZero() {
+		return last
+	}
+	// 旧版本没有 last.time 时按文件名推算：文件名精确到秒，取整秒（可能重复采集，但不会遗漏）
+	entries, _ := os.ReadDir(dir)
+	for _, e := range entries {
+		if _, l, ok := parseArchiveFileName(e.Name()); ok && l.Add(-time.Second).After(last) {
+			last = l.Add(-time.Second)
+		}
+	}
+	return last
+}
+
+// Prune 删除超过保留天数的归档文件与空目录，返回删除的文件数
+func (s *Store) Prune(now time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	cutoff := now.AddDate(0, 0, -s.opts.RetentionDays)
+
+	removed := 0
+	projects, err := os.ReadDir(s.root)
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	for _, p := range projects {
+		if !p.IsDir() {
+			continue
+		}
+		projectDir := filepath.Join(s.root, p.Name())
+		containers, _ := os.ReadDir(projectDir)
+		for _, c := range containers {
+			if !c.IsDir() {
+				continue
+			}
+			dir := filepath.Join(projectDir, c.Name())
+			files, _ := os.ReadDir(dir)
+			left := 0
+			for _, f := range files {
+				if f.Name() == lastTimeFileName {
+					continue
+				}
+				expired := false
+				if _, last, ok := parseArchiveFileName(f.Name()); ok {
+					expired = last.Before(cutoff)
+				} else if f.Name() == currentFileName && s.segments[dir] == nil {
+					// 容器长期未运行时 current.log 不会轮转，按修改时间清理
+					if info, err := f.Info(); err == nil {
+						expired = info.ModTime().Before(cutoff)
+					}
+				}
+				if expired && os.Remove(filepath.Join(dir, f.Name())) == nil {
+					removed++
+					continue
+				}
+				left++
+			}
+			if left == 0 && s.segments[dir] == nil {
+				_ = os.Remove(filepath.Join(dir, lastTimeFileName))
+				_ = os.Remove(dir)
+			}
+		}
+		_ = os.Remove(projectDir) // 非空时会失败，忽略
+	}
+	return removed, nil
+}
+
+// Source 一个已归档的容器
+type Source struct {
+	Project   string    `json:"project"`
+	Container string    `json:"container"`
+	Files     int       `json:"files"`
+	Bytes     int64     `json:"bytes"`
+	First     time.Time `json:"first"`
+	Last      time.Time `json:"last"`
+}
+
+// Sources 列出所有归档来源及其大小与时间范围
+func (s *Store) Sources() ([]Source, error) {
+	_ = s.Flush()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	list := make([]Source, 0)
+	projects, err := os.ReadDir(s.root)
+	if os.IsNotExist(err) {
+		return list, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range projects {
+		if !p.IsDir() {
+			continue
+		}
+		containers, _ := os.ReadDir(filepath.Join(s.root, p.Name()))
+		for _, c := range containers {
+			if !c.IsDir() {
+				continue
+			}
+			dir := filepath.Join(s.root, p.Name(), c.Name())
+			src := Source{Project: p.Name(), Container: c.Name()}
+			if src.Project == standaloneProject {
+				src.Project = ""
+			}
+			files, _ := os.ReadDir(dir)
+			for _, f := range files {
+				info, err := f.Info()
+				if err != nil {
+					continue
+				}
+				first, last, ok := parseArchiveFileName(f.Name())
+				if !ok && f.Name() == currentFileName {
+					if seg := s.segments[dir]; seg != nil {
+						first, last, ok = seg.first, seg.last, true
+					} else {
+						first, last = info.ModTime(), info.ModTime()
+						ok = true
+					}
+				}
+				if !ok {
+					continue
+				}
+				src.Files++
+				src.Bytes += info.Size()
+				if src.First.IsZero() || (!first.IsZero() && first.Before(src.First)) {
+					src.First = first
+				}
+				if last.After(src.Last) {
+					src.Last = last
+				}
+			}
+			if src.Files > 0 {
+				list = append(list, src)
+			}
+		}
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Project != list[j].Project {
+			return list[i].Project < list[j].Project
+		}
+		return list[i].Container < list[j].Container
+	})
+	return list, nil
+}
+
+// scanFile 逐行读取归档文件（自动识别 gzip），fn 返回 false 时停止
+func scanFile(path string, fn func(Record) bool) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var r io.Reader = f
+	if strings.HasSuffix(path, ".gz") {
+		zr, err := gzip.NewReader(f)
+		if err != nil {
+			return err
+		}
+		defer zr.Close()
+		r = zr
+	}
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+	for sc.Scan() {
+		var rec Record
+		if json.Unmarshal(sc.Bytes(), &rec) != nil {
+			continue
+		}
+		if !fn(rec) {
+			return nil
+		}
+	}
+	return sc.Err()
+}
@@ -0,0 +1,161 @@
+package logarchive
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDetectLevel(t *testing.T) {
+	cases := map[string]string{
+		`time="2024-05-01" level=error msg="db down"`:   "error",
+		`{"level":"warn","msg":"slow query"}`:           "warn",
+		`2024/05/01 12:00:00 [notice] 1#1: start`:       "info",
+		`ERROR: relation "users" does not exist`:        "error",
+		`May 01 12:00:00 app[1]: DEBUG cache miss`:      "debug",
+		`GET /healthz 200 0 errors in total`:            "",
+		`panic: runtime error: index out of range [3]`:  "error",
+		`{"severity":"CRITICAL","message":"disk full"}`: "error",
+	}
+	for msg, want := range cases {
+		if got := DetectLevel(msg); got != want {
+			t.Errorf("DetectLevel(%q) = %q, want %q", msg, got, want)
+		}
+	}
+}
+
+func TestStoreAppendRotateAndSearch(t *testing.T) {
+	root := t.TempDir()
+	s := NewStore(root, Options{MaxFileBytes: 400})
+	day1 := time.Date(2024, 5, 1, 23, 0, 0, 0, time.UTC)
+
+	// 旧容器（更新前）与重建后的新容器写入同一目录
+	for i := 0; i < 6; i++ {
+		rec := Record{Time: day1.Add(time.Duration(i) * time.Minute), ContainerID: "old", Container: "web", Project: "shop", Stream: "stdout", Message: "request ok"}
+		if i == 4 {
+			rec.Message, rec.Level, rec.Stream = "upstream timeout", "error", "stderr"
+		}
+		if err := s.Append(rec); err != nil {
+			t.Fatal(err)
+		}
+	}
+	day2 := day1.Add(2 * time.Hour)
+	for i := 0; i < 3; i++ {
+		if err := s.Append(Record{Time: day2.Add(time.Duration(i) * time.Second), ContainerID: "new", Container: "web", Project: "shop", Stream: "stdout", Message: "started"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s.Append(Record{Time: day2, ContainerID: "x", Container: "cron", Stream: "stdout", Message: "tick"}); err != nil {
+		t.Fatal(err)
+	}
+
+	files, _ := os.ReadDir(filepath.Join(root, "shop", "web"))
+	archives := 0
+	for _, f := range files {
+		if strings.HasSuffix(f.Name(), archiveSuffix) {
+			archives++
+		}
+	}
+	if archives < 2 {
+		t.Fatalf("expected size and day rotation, files = %v", files)
+	}
+
+	res, err := s.Search(Query{Projects: []string{"shop"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Records) != 9 || res.Records[0].ContainerID != "new" || res.Records[8].Time != day1 {
+		t.Fatalf("records = %d first = %+v", len(res.Records), res.Records[0])
+	}
+
+	res, _ = s.Search(Query{Levels: []string{"ERROR"}, Until: day1.Add(time.Hour)})
+	if len(res.Records) != 1 || res.Records[0].Message != "upstream timeout" {
+		t.Fatalf("level search = %+v", res.Records)
+	}
+	res, _ = s.Search(Query{Regex: regexp.MustCompile(`^start`), Since: day2})
+	if len(res.Records) != 3 {
+		t.Fatalf("regex search = %+v", res.Records)
+	}
+	res, _ = s.Search(Query{Projects: []string{""}, Text: "TICK"})
+	if len(res.Records) != 1 || res.Records[0].Container != "cron" {
+		t.Fatalf("standalone search = %+v", res.Records)
+	}
+	res, _ = s.Search(Query{Containers: []string{"web"}, Limit: 2})
+	if len(res.Records) != 2 || !res.Truncated || !res.Records[0].Time.Equal(day2.Add(2*time.Second)) {
+		t.Fatalf("limited search = %+v truncated=%v", res.Records, res.Truncated)
+	}
+
+	if last := s.LastTime("shop", "web"); !last.Equal(day2.Add(2 * time.Second)) {
+		t.Fatalf("LastTime = %v", last)
+	}
+
+	sources, err := s.Sources()
+	if err != nil || len(sources) != 2 || sources[0].Container != "cron" || sources[0].Project != "" {
+		t.Fatalf("sources = %+v, %v", sources, err)
+	}
+}
+
+func TestStoreResumeAndPrune(t *testing.T) {
+	root := t.TempDir()
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	s := NewStore(root, Options{RetentionDays: 7})
+	old := now.AddDate(0, 0, -10)
+	_ = s.Append(Record{Time: old, Container: "db", Message: "a"})
+	_ = s.Append(Record{Time: now, Container: "db", Message: "b"}) // 跨天触发轮转
+	_ = s.Close()
+
+	// 重新打开后续写，并能恢复最后时间
+	s = NewStore(root, Options{RetentionDays: 7})
+	if last := s.LastTime("", "db"); !last.Equal(now) {
+		t.Fatalf("LastTime after reopen = %v", last)
+	}
+	removed, err := s.Prune(now)
+	if err != nil || removed != 1 {
+		t.Fatalf("Prune removed %d, %v", removed, err)
+	}
+	res, _ := s.Search(Query{})
+	if len(res.Records) != 1 || res.Records[0].Message != "b" {
+		t.Fatalf("records after prune = %+v", res.Records)
+	}
+}
+
+func TestLastTimeAfterRotation(t *testing.T) {
+	root := t.TempDir()
+	s := NewStore(root, Options{})
+	last := time.Date(2024, 6, 1, 12, 0, 5, 750000000, time.UTC)
+	_ = s.Append(Record{Time: last.Add(-time.Second), Container: "api", Message: "a"})
+	_ = s.Append(Record{Time: last, Container: "api", Message: "b"})
+
+	// 只剩已轮转的文件时仍返回精确的最后时间，不能按文件名向上取整
+	dir := s.sourceDir("", "api")
+	s.mu.Lock()
+	err := s.rotate(dir, s.segments[dir])
+	s.mu.Unlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := s.LastTime("", "api"); !got.Equal(last) {
+		t.Fatalf("LastTime = %v, want %v", got, last)
+	}
+
+	// 没有 last.time 的旧归档按文件名中的整秒续采
+	if err := os.Remove(filepath.Join(dir, lastTimeFileName)); err != nil {
+		t.Fatal(err)
+	}
+	if got := s.LastTime("", "api"); !got.Equal(last.Truncate(time.Second)) {
+		t.Fatalf("LastTime without sidecar = %v", got)
+	}
+}
+
+func TestParseArchiveFileName(t *testing.T) {
+	first, last, ok := parseArchiveFileName("20240501T230000Z_20240501T230559Z-1.log.gz")
+	if !ok || first.Hour() != 23 || last.Minute() != 6 {
+		t.Fatalf("got %v %v %v", first, last, ok)
+	}
+	if _, _, ok := parseArchiveFileName("current.log"); ok {
+		t.Fatalf("current.log is not an archive")
+	}
+}
@@ -0,0 +1,55 @@
+package logarchive
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	// 结构化日志中的级别字段：level=error、"level":"warn"、severity=ERROR 等
+	structuredLevelPattern = regexp.MustCompile(`(?i)(?:^|[\s{,"])(?:level|lvl|severity|loglevel)"?\s*[=:]\s*"?([a-zA-Z]+)`)
+	// 常见文本格式：[ERROR]、ERROR:、 WARN 、<info> 等（仅匹配大写或方括号包裹，避免误判普通单词）
+	upperLevelPattern   = regexp.MustCompile(`\b(FATAL|PANIC|CRITICAL|CRIT|ERROR|ERR|WARNING|WARN|NOTICE|INFO|DEBUG|TRACE)\b`)
+	bracketLevelPattern = regexp.MustCompile(`(?i)[\[<(](fatal|panic|critical|crit|error|err|warning|warn|notice|info|debug|trace)[\]>)]`)
+	// 行首前缀：panic: / Error: / warning: 等
+	prefixLevelPattern = regexp.MustCompile(`(?i)^\s*(fatal|panic|error|warning|warn)\s*:`)
+)
+
+var levelAliases = map[string]string{
+	"fatal": "error", "panic": "error", "critical": "error", "crit": "error", "emerg": "error", "alert": "error",
+	"error": "error", "err": "error", "eror": "error",
+	"warning": "warn", "warn": "warn", "wrn": "warn",
+	"notice": "info", "info": "info", "inf": "info", "information": "info",
+	"debug": "debug", "dbg": "debug", "trace": "debug", "trc": "debug",
+}
+
+// Levels 检索时可用的日志级别
+var Levels = []string{"error", "warn", "info", "debug"}
+
+// NormalizeLevel 将级别别名归一为 error / warn / info / debug，无法识别时返回空串
+func NormalizeLevel(raw string) string {
+	return levelAliases[strings.ToLower(strings.TrimSpace(raw))]
+}
+
+// DetectLevel 根据日志内容启发式判断级别，依次尝试结构化字段、行首前缀、方括号标记与大写关键字
+func DetectLevel(message string) string {
+	head := message
+	if len(head) > 512 {
+		head = head[:512]
+	}
+	if m := structuredLevelPattern.FindStringSubmatch(head); m != nil {
+		if lv := NormalizeLevel(m[1]); lv != "" {
+			return lv
+		}
+	}
+	if m := prefixLevelPattern.FindStringSubmatch(head); m != nil {
+		return NormalizeLevel(m[1])
+	}
+	if m := bracketLevelPattern.FindStringSubmatch(head); m != nil {
+		return NormalizeLevel(m[1])
+	}
+	if m := upperLevelPattern.FindStringSubmatch(head); m != nil {
+		return NormalizeLevel(m[1])
+	}
+	return ""
+}
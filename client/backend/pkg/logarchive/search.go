@@ -0,0 +1,164 @@
+package logarchive
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	DefaultSearchLimit = 500
+	MaxSearchLimit     = 5000
+)
+
+// Query 检索条件，未设置的条件不参与过滤
+type Query struct {
+	Since      time.Time
+	Until      time.Time
+	Projects   []string
+	Containers []string
+	Levels     []string // error / warn / info / debug / none（未识别级别）
+	Streams    []string // stdout / stderr
+	Text       string   // 不区分大小写的子串
+	Regex      *regexp.Regexp
+	Limit      int
+}
+
+// Result 检索结果，按时间倒序
+type Result struct {
+	Records   []Record `json:"records"`
+	Truncated bool     `json:"truncated"`
+	Files     int      `json:"files"`
+}
+
+func toSet(values []string, normalize func(string) string) map[string]bool {
+	if len(values) == 0 {
+		return nil
+	}
+	set := make(map[string]bool, len(values))
+	for _, v := range values {
+		set[normalize(v)] = true
+	}
+	return set
+}
+
+func (q *Query) match(r *Record, levels map[string]bool, streams map[string]bool, text string) bool {
+	if !q.Since.IsZero() && r.Time.Before(q.Since) {
+		return false
+	}
+	if !q.Until.IsZero() && r.Time.After(q.Until) {
+		return false
+	}
+	if levels != nil {
+		lv := r.Level
+		if lv == "" {
+			lv = "none"
+		}
+		if !levels[lv] {
+			return false
+		}
+	}
+	if streams != nil && !streams[r.Stream] {
+		return false
+	}
+	if text != "" && !strings.Contains(strings.ToLower(r.Message), text) {
+		return false
+	}
+	if q.Regex != nil && !q.Regex.MatchString(r.Message) {
+		return false
+	}
+	return true
+}
+
+// Search 在归档中检索日志，返回最新的 Limit 条
+func (s *Store) Search(q Query) (Result, error) {
+	if q.Limit <= 0 {
+		q.Limit = DefaultSearchLimit
+	}
+	if q.Limit > MaxSearchLimit {
+		q.Limit = MaxSearchLimit
+	}
+	_ = s.Flush()
+
+	projects := toSet(q.Projects, func(v string) string {
+		if strings.TrimSpace(v) == "" {
+			return standaloneProject
+		}
+		return sanitizeSegment(v)
+	})
+	containers := toSet(q.Containers, sanitizeSegment)
+	levels := toSet(q.Levels, func(v string) string {
+		if lv := NormalizeLevel(v); lv != "" {
+			return lv
+		}
+		return strings.ToLower(strings.TrimSpace(v))
+	})
+	streams := toSet(q.Streams, func(v string) string { return strings.ToLower(strings.TrimSpace(v)) })
+	text := strings.ToLower(strings.TrimSpace(q.Text))
+
+	var res Result
+	var matches []Record
+	// 超过两倍上限时按时间裁剪，保持内存有界
+	compact := func() {
+		if len(matches) <= q.Limit {
+			return
+		}
+		sort.Slice(matches, func(i, j int) bool { return matches[i].Time.After(matches[j].Time) })
+		matches = matches[:q.Limit]
+		res.Truncated = true
+	}
+
+	projectDirs, err := os.ReadDir(s.root)
+	if os.IsNotExist(err) {
+		return Result{Records: []Record{}}, nil
+	}
+	if err != nil {
+		return res, err
+	}
+	for _, p := range projectDirs {
+		if !p.IsDir() || (projects != nil && !projects[p.Name()]) {
+			continue
+		}
+		containerDirs, _ := os.ReadDir(filepath.Join(s.root, p.Name()))
+		for _, c := range containerDirs {
+			if !c.IsDir() || (containers != nil && !containers[c.Name()]) {
+				continue
+			}
+			dir := filepath.Join(s.root, p.Name(), c.Name())
+			files, _ := os.ReadDir(dir)
+			for _, f := range files {
+				if first, last, ok := parseArchiveFileName(f.Name()); ok {
+					if (!q.Since.IsZero() && last.Before(q.Since)) || (!q.Until.IsZero() && first.After(q.Until)) {
+						continue
+					}
+				} else if f.Name() != currentFileName {
+					continue
+				}
+				res.Files++
+				err := scanFile(filepath.Join(dir, f.Name()), func(r Record) bool {
+					if q.match(&r, levels, streams, text) {
+						matches = append(matches, r)
+						if len(matches) >= 2*q.Limit {
+							compact()
+						}
+					}
+					return true
+				})
+				if err != nil && !os.IsNotExist(err) {
+					return res, err
+				}
+			}
+		}
+	}
+
+	compact()
+	sort.Slice(matches, func(i, j int) bool { return matches[i].Time.After(matches[j].Time) })
+	if matches == nil {
+		matches = []Record{}
+	}
+	res.Records = matches
+	return res, nil
+}
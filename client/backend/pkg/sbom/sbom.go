@@ -0,0 +1,414 @@
+// Package sbom 根据镜像中解析出的软件包生成 SPDX 2.3 与 CycloneDX 1.5 JSON 格式的软件物料清单。
+package sbom
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"dockerpanel/backend/pkg/scan"
+)
+
+const (
+	FormatSPDX      = "spdx"
+	FormatCycloneDX = "cyclonedx"
+
+	toolName = "tradis"
+)
+
+// Subject 描述 SBOM 的对象（镜像或容器）
+type Subject struct {
+	// Name 镜像引用或容器名，用作文档名称
+	Name        string
+	ImageID     string
+	RepoDigests []string
+	Container   string
+}
+
+// Inventory 生成 SBOM 所需的全部信息
+type Inventory struct {
+	Subject  Subject
+	OS       scan.OSInfo
+	Packages []scan.Package
+	Created  time.Time
+}
+
+// NormalizeFormat 规范化格式参数，未知格式返回空字符串
+func NormalizeFormat(v string) string {
+	switch strings.ToLower(strings.TrimSpace(v)) {
+	case "", "spdx", "spdx-json":
+		return FormatSPDX
+	case "cyclonedx", "cdx", "cyclonedx-json":
+		return FormatCycloneDX
+	}
+	return ""
+}
+
+// Generate 按格式生成 SBOM 文档
+func Generate(format string, inv Inventory) ([]byte, error) {
+	switch format {
+	case FormatSPDX:
+		return json.MarshalIndent(buildSPDX(inv), "", "  ")
+	case FormatCycloneDX:
+		return json.MarshalIndent(buildCycloneDX(inv), "", "  ")
+	}
+	return nil, fmt.Errorf("不支持的 SBOM 格式: %s", format)
+}
+
+// PURL 生成软件包的 Package URL（https://github.com/package-url/purl-spec）
+func PURL(info scan.OSInfo, p scan.Package) string {
+	distro := strings.ToLower(info.ID)
+	qualifiers := url.Values{}
+	var typ, namespace, name string
+	name = p.Name
+	switch p.Type {
+	case scan.PackageTypeDeb, scan.PackageTypeApk, scan.PackageTypeRpm:
+		typ = p.Type
+		namespace = distro
+		if p.Arch != "" {
+			qualifiers.Set("arch", p.Arch)
+		}
+		if distro != "" && info.VersionID != "" {
+			qualifiers.Set("distro", distro+"-"+info.VersionID)
+		}
+		if p.Source != "" && p.Source != p.Name && p.Type == scan.PackageTypeDeb {
+			qualifiers.Set("upstream", p.Source)
+		}
+	case scan.PackageTypeNpm:
+		typ = "npm"
+		if strings.HasPrefix(name, "@") {
+			if i := strings.Index(name, "/"); i > 0 {
+				namespace, name = name[:i], name[i+1:]
+			}
+		}
+	case scan.PackageTypePyPI:
+		typ = "pypi"
+		name = strings.ReplaceAll(strings.ToLower(name), "_", "-")
+	case scan.PackageTypeComposer, scan.PackageTypeGo:
+		typ = p.Type
+		if i := strings.LastIndex(name, "/"); i > 0 {
+			namespace, name = name[:i], name[i+1:]
+		}
+	default:
+		typ = p.Type
+	}
+
+	var b strings.Builder
+	b.WriteString("pkg:")
+	b.WriteString(typ)
+	b.WriteString("/")
+	if namespace != "" {
+		segments := strings.Split(namespace, "/")
+		for i, s := range segments {
+			segments[i] = purlEscape(s)
+		}
+		b.WriteString(strings.Join(segments, "/"))
+		b.WriteString("/")
+	}
+	b.WriteString(purlEscape(name))
+	if p.Version != "" {
+		b.WriteString("@")
+		b.WriteString(purlEscape(p.Version))
+	}
+	if len(qualifiers) > 0 {
+		b.WriteString("?")
+		b.WriteString(qualifiers.Encode())
+	}
+	return b.String()
+}
+
+// purlEscape 按 purl 规范转义路径片段（@ 必须转义，否则会与版本分隔符混淆）
+func purlEscape(s string) string {
+	return strings.ReplaceAll(url.PathEscape(s), "@", "%40")
+}
+
+// spdxLicenseExpr 粗略判断是否为合法的 SPDX 许可证表达式，非法时 SPDX 文档中记为 NOASSERTION
+var spdxLicenseExpr = regexp.MustCompile(`^\(?[A-Za-z0-9.+\-]+\)?( (AND|OR|WITH) \(?[A-Za-z0-9.+\-]+\)?)*$`)
+
+func validLicenseExpression(v string) bool {
+	return v != "" && spdxLicenseExpr.MatchString(v)
+}
+
+func newUUID() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
+
+func subjectName(s Subject) string {
+	if s.Container != "" {
+		return s.Container
+	}
+	if s.Name != "" {
+		return s.Name
+	}
+	return s.ImageID
+}
+
+type spdxDocument struct {
+	SPDXVersion       string             `json:"spdxVersion"`
+	DataLicense       string             `json:"dataLicense"`
+	SPDXID            string             `json:"SPDXID"`
+	Name              string             `json:"name"`
+	DocumentNamespace string             `json:"documentNamespace"`
+	CreationInfo      spdxCreationInfo   `json:"creationInfo"`
+	Packages          []spdxPackage      `json:"packages"`
+	Relationships     []spdxRelationship `json:"relationships"`
+}
+
+type spdxCreationInfo struct {
+	Created  string   `json:"created"`
+	Creators []string `json:"creators"`
+}
+
+type spdxPackage struct {
+	SPDXID                string            `json:"SPDXID"`
+	Name                  string            `json:"name"`
+	VersionInfo           string            `json:"versionInfo,omitempty"`
+	DownloadLocation      string            `json:"downloadLocation"`
+	FilesAnalyzed         bool              `json:"filesAnalyzed"`
+	LicenseConcluded      string            `json:"licenseConcluded"`
+	LicenseDeclared       string            `json:"licenseDeclared"`
+	CopyrightText         string            `json:"copyrightText"`
+	SourceInfo            string            `json:"sourceInfo,omitempty"`
+	PrimaryPackagePurpose string            `json:"primaryPackagePurpose,omitempty"`
+	Checksums             []spdxChecksum    `json:"checksums,omitempty"`
+	ExternalRefs          []spdxExternalRef `json:"externalRefs,omitempty"`
+}
+
+type spdxChecksum struct {
+	Algorithm     string `json:"algorithm"`
+	ChecksumValue string `json:"checksumValue"`
+}
+
+type spdxExternalRef struct {
+	ReferenceCategory string `json:"referenceCategory"`
+	ReferenceType     string `json:"referenceType"`
+	ReferenceLocator  string `json:"referenceLocator"`
+}
+
+type spdxRelationship struct {
+	SPDXElementID      string `json:"spdxElementId"`
+	RelationshipType   string `json:"relationshipType"`
+	RelatedSPDXElement string `json:"relatedSpdxElement"`
+}
+
+func buildSPDX(inv Inventory) spdxDocument {
+	name := subjectName(inv.Subject)
+	sum := sha256.Sum256([]byte(inv.Subject.ImageID + inv.Created.String()))
+	doc := spdxDocument{
+		SPDXVersion:       "SPDX-2.3",
+		DataLicense:       "CC0-1.0",
+		SPDXID:            "SPDXRef-DOCUMENT",
+		Name:              name,
+		DocumentNamespace: fmt.Sprintf("https://%s.local/spdx/%s-%s", toolName, url.PathEscape(name), hex.EncodeToString(sum[:8])),
+		CreationInfo: spdxCreationInfo{
+			Created:  inv.Created.UTC().Format(time.RFC3339),
+			Creators: []string{"Tool: " + toolName},
+		},
+		Packages:      make([]spdxPackage, 0, len(inv.Packages)+1),
+		Relationships: make([]spdxRelationship, 0, len(inv.Packages)+1),
+	}
+
+	root := spdxPackage{
+		SPDXID:                "SPDXRef-Image",
+		Name:                  name,
+		VersionInfo:           inv.Subject.ImageID,
+		DownloadLocation:      "NOASSERTION",
+		LicenseConcluded:      "NOASSERTION",
+		LicenseDeclared:       "NOASSERTION",
+		CopyrightText:         "NOASSERTION",
+		PrimaryPackagePurpose: "CONTAINER",
+	}
+	if digest := strings.TrimPrefix(inv.Subject.ImageID, "sha256:"); digest != inv.Subject.ImageID {
+		root.Checksums = []spdxChecksum{{Algorithm: "SHA256", ChecksumValue: digest}}
+	}
+	for _, d := range inv.Subject.RepoDigests {
+		root.ExternalRefs = append(root.ExternalRefs, spdxExternalRef{
+			ReferenceCategory: "PACKAGE-MANAGER",
+			ReferenceType:     "purl",
+			ReferenceLocator:  ociPURL(d),
+		})
+	}
+	doc.Packages = append(doc.Packages, root)
+	doc.Relationships = append(doc.Relationships, spdxRelationship{
+		SPDXElementID:      "SPDXRef-DOCUMENT",
+		RelationshipType:   "DESCRIBES",
+		RelatedSPDXElement: root.SPDXID,
+	})
+
+	for i, p := range inv.Packages {
+		license := "NOASSERTION"
+		if validLicenseExpression(p.License) {
+			license = p.License
+		}
+		pkg := spdxPackage{
+			SPDXID:                fmt.Sprintf("SPDXRef-Package-%d", i+1),
+			Name:                  p.Name,
+			VersionInfo:           p.Version,
+			DownloadLocation:      "NOASSERTION",
+			LicenseConcluded:      "NOASSERTION",
+			LicenseDeclared:       license,
+			CopyrightText:         "NOASSERTION",
+			PrimaryPackagePurpose: "LIBRARY",
+			ExternalRefs: []spdxExternalRef{{
+				ReferenceCategory: "PACKAGE-MANAGER",
+				ReferenceType:     "purl",
+				ReferenceLocator:  PURL(inv.OS, p),
+			}},
+		}
+		if p.Path != "" {
+			pkg.SourceInfo = "acquired package info from " + p.Path
+		}
+		doc.Packages = append(doc.Packages, pkg)
+		doc.Relationships = append(doc.Relationships, spdxRelationship{
+			SPDXElementID:      root.SPDXID,
+			RelationshipType:   "CONTAINS",
+			RelatedSPDXElement: pkg.SPDXID,
+		})
+	}
+	return doc
+}
+
+// ociPURL 将 "nginx@sha256:..." 形式的 RepoDigest 转为 pkg:oci
+func ociPURL(repoDigest string) string {
+	repo, digest, ok := strings.Cut(repoDigest, "@")
+	if !ok {
+		return "pkg:oci/" + url.PathEscape(repoDigest)
+	}
+	name := repo
+	if i := strings.LastIndex(repo, "/"); i >= 0 {
+		name = repo[i+1:]
+	}
+	q := url.Values{}
+	q.Set("repository_url", repo)
+	return "pkg:oci/" + url.PathEscape(name) + "@" + url.PathEscape(digest) + "?" + q.Encode()
+}
+
+type cdxBOM struct {
+	BOMFormat    string         `json:"bomFormat"`
+	SpecVersion  string         `json:"specVersion"`
+	SerialNumber string         `json:"serialNumber"`
+	Version      int            `json:"version"`
+	Metadata     cdxMetadata    `json:"metadata"`
+	Components   []cdxComponent `json:"components"`
+}
+
+type cdxMetadata struct {
+	Timestamp string       `json:"timestamp"`
+	Tools     cdxTools     `json:"tools"`
+	Component cdxComponent `json:"component"`
+}
+
+type cdxTools struct {
+	Components []cdxComponent `json:"components"`
+}
+
+type cdxComponent struct {
+	BOMRef     string        `json:"bom-ref,omitempty"`
+	Type       string        `json:"type"`
+	Name       string        `json:"name"`
+	Version    string        `json:"version,omitempty"`
+	PURL       string        `json:"purl,omitempty"`
+	Licenses   []cdxLicense  `json:"licenses,omitempty"`
+	Hashes     []cdxHash     `json:"hashes,omitempty"`
+	Properties []cdxProperty `json:"properties,omitempty"`
+}
+
+type cdxLicense struct {
+	Expression string          `json:"expression,omitempty"`
+	License    *cdxLicenseName `json:"license,omitempty"`
+}
+
+type cdxLicenseName struct {
+	Name string `json:"name"`
+}
+
+type cdxHash struct {
+	Alg     string `json:"alg"`
+	Content string `json:"content"`
+}
+
+type cdxProperty struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+func buildCycloneDX(inv Inventory) cdxBOM {
+	name := subjectName(inv.Subject)
+	subject := cdxComponent{
+		BOMRef:  "image",
+		Type:    "container",
+		Name:    name,
+		Version: inv.Subject.ImageID,
+	}
+	if digest := strings.TrimPrefix(inv.Subject.ImageID, "sha256:"); digest != inv.Subject.ImageID {
+		subject.Hashes = []cdxHash{{Alg: "SHA-256", Content: digest}}
+	}
+	if len(inv.Subject.RepoDigests) > 0 {
+		subject.PURL = ociPURL(inv.Subject.RepoDigests[0])
+	}
+	if inv.Subject.Container != "" && inv.Subject.Name != "" {
+		subject.Properties = append(subject.Properties, cdxProperty{Name: toolName + ":image", Value: inv.Subject.Name})
+	}
+
+	bom := cdxBOM{
+		BOMFormat:    "CycloneDX",
+		SpecVersion:  "1.5",
+		SerialNumber: "urn:uuid:" + newUUID(),
+		Version:      1,
+		Metadata: cdxMetadata{
+			Timestamp: inv.Created.UTC().Format(time.RFC3339),
+			Tools:     cdxTools{Components: []cdxComponent{{Type: "application", Name: toolName}}},
+			Component: subject,
+		},
+		Components: make([]cdxComponent, 0, len(inv.Packages)+1),
+	}
+	if inv.OS.ID != "" {
+		bom.Components = append(bom.Components, cdxComponent{
+			BOMRef:  "os",
+			Type:    "operating-system",
+			Name:    inv.OS.ID,
+			Version: inv.OS.VersionID,
+		})
+	}
+
+	seen := make(map[string]struct{}, len(inv.Packages))
+	for _, p := range inv.Packages {
+		purl := PURL(inv.OS, p)
+		if _, ok := seen[purl]; ok {
+			continue
+		}
+		seen[purl] = struct{}{}
+		comp := cdxComponent{
+			BOMRef:  purl,
+			Type:    "library",
+			Name:    p.Name,
+			Version: p.Version,
+			PURL:    purl,
+			Properties: []cdxProperty{
+				{Name: toolName + ":package:type", Value: p.Type},
+			},
+		}
+		if p.License != "" {
+			if validLicenseExpression(p.License) {
+				comp.Licenses = []cdxLicense{{Expression: p.License}}
+			} else {
+				comp.Licenses = []cdxLicense{{License: &cdxLicenseName{Name: p.License}}}
+			}
+		}
+		if p.Path != "" {
+			comp.Properties = append(comp.Properties, cdxProperty{Name: toolName + ":location", Value: p.Path})
+		}
+		bom.Components = append(bom.Components, comp)
+	}
+	return bom
+}
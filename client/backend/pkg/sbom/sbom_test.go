@@ -0,0 +1,76 @@
+package sbom
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"dockerpanel/backend/pkg/scan"
+)
+
+func TestPURL(t *testing.T) {
+	debian := scan.OSInfo{ID: "debian", VersionID: "12"}
+	cases := []struct {
+		pkg  scan.Package
+		want string
+	}{
+		{scan.Package{Name: "zlib1g", Version: "1:1.2.13.dfsg-1", Source: "zlib", Arch: "amd64", Type: scan.PackageTypeDeb},
+			"pkg:deb/debian/zlib1g@1:1.2.13.dfsg-1?arch=amd64&distro=debian-12&upstream=zlib"},
+		{scan.Package{Name: "@types/node", Version: "20.1.0", Type: scan.PackageTypeNpm}, "pkg:npm/%40types/node@20.1.0"},
+		{scan.Package{Name: "Flask_Login", Version: "0.6.3", Type: scan.PackageTypePyPI}, "pkg:pypi/flask-login@0.6.3"},
+		{scan.Package{Name: "github.com/gin-gonic/gin", Version: "v1.9.1", Type: scan.PackageTypeGo}, "pkg:golang/github.com/gin-gonic/gin@v1.9.1"},
+	}
+	for _, tc := range cases {
+		if got := PURL(debian, tc.pkg); got != tc.want {
+			t.Errorf("PURL(%s) = %s, want %s", tc.pkg.Name, got, tc.want)
+		}
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	inv := Inventory{
+		Subject: Subject{Name: "nginx:latest", ImageID: "sha256:abc", RepoDigests: []string{"nginx@sha256:def"}},
+		OS:      scan.OSInfo{ID: "debian", VersionID: "12"},
+		Packages: []scan.Package{
+			{Name: "openssl", Version: "3.0.11-1", Type: scan.PackageTypeDeb},
+			{Name: "lodash", Version: "4.17.21", License: "MIT", Type: scan.PackageTypeNpm, Path: "/app/package-lock.json"},
+			{Name: "requests", Version: "2.31.0", License: "Apache 2.0", Type: scan.PackageTypePyPI},
+		},
+		Created: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+	}
+
+	data, err := Generate(FormatSPDX, inv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var spdx spdxDocument
+	if err := json.Unmarshal(data, &spdx); err != nil {
+		t.Fatal(err)
+	}
+	if spdx.SPDXVersion != "SPDX-2.3" || len(spdx.Packages) != 4 || len(spdx.Relationships) != 4 {
+		t.Fatalf("unexpected spdx document: %s", data)
+	}
+	if spdx.Packages[2].LicenseDeclared != "MIT" || spdx.Packages[3].LicenseDeclared != "NOASSERTION" {
+		t.Fatalf("unexpected licenses: %+v", spdx.Packages)
+	}
+
+	data, err = Generate(FormatCycloneDX, inv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var bom cdxBOM
+	if err := json.Unmarshal(data, &bom); err != nil {
+		t.Fatal(err)
+	}
+	// 操作系统组件 + 3 个软件包
+	if bom.BOMFormat != "CycloneDX" || len(bom.Components) != 4 || bom.Metadata.Component.Type != "container" {
+		t.Fatalf("unexpected cyclonedx document: %s", data)
+	}
+	if bom.Components[3].Licenses[0].License == nil || bom.Components[3].Licenses[0].License.Name != "Apache 2.0" {
+		t.Fatalf("free-text license should be kept as name: %+v", bom.Components[3])
+	}
+
+	if NormalizeFormat("CDX") != FormatCycloneDX || NormalizeFormat("xml") != "" {
+		t.Fatalf("NormalizeFormat mismatch")
+	}
+}
@@ -0,0 +1,209 @@
+// Package scan 从 docker save 导出的镜像归档中读取软件包数据库，并与本地离线漏洞库进行匹配。
+package scan
+
+import (
+	"archive/tar"
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+)
+
+// maxExtractFileSize 单个被提取文件的大小上限，避免异常镜像撑爆内存
+const maxExtractFileSize = 64 * 1024 * 1024
+
+// ImageFiles 镜像合并文件系统中被选中的文件（路径不带前导斜杠）
+type ImageFiles map[string][]byte
+
+type saveManifestEntry struct {
+	Config   string   `json:"Config"`
+	RepoTags []string `json:"RepoTags"`
+	Layers   []string `json:"Layers"`
+}
+
+// ExtractImageFiles 读取 docker save 归档，按层顺序合并（处理 whiteout），返回 match 命中的文件内容。
+// 归档中 manifest.json 的位置不固定，因此先落盘到临时文件再两遍扫描。
+func ExtractImageFiles(r io.Reader, match func(name string) bool) (ImageFiles, error) {
+	tmp, err := os.CreateTemp("", "tradis-scan-*.tar")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	if _, err := io.Copy(tmp, r); err != nil {
+		return nil, fmt.Errorf("保存镜像归档失败: %v", err)
+	}
+	return ExtractImageFilesFromArchive(tmp.Name(), match)
+}
+
+// ExtractImageFilesFromArchive 与 ExtractImageFiles 相同，但直接读取已存在的归档文件。
+func ExtractImageFilesFromArchive(archivePath string, match func(name string) bool) (ImageFiles, error) {
+	layers, err := readSaveManifestLayers(archivePath)
+	if err != nil {
+		return nil, err
+	}
+
+	wanted := make(map[string]struct{}, len(layers))
+	for _, l := range layers {
+		wanted[path.Clean(l)] = struct{}{}
+	}
+
+	f, err := os.Open(archivePath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	perLayer := make(map[string]*layerChanges, len(layers))
+	// 旧版 docker save 会用符号链接复用相同的层
+	aliases := make(map[string]string)
+	tr := tar.NewReader(f)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("读取镜像归档失败: %v", err)
+		}
+		name := path.Clean(hdr.Name)
+		if _, ok := wanted[name]; !ok && !strings.HasSuffix(name, "/layer.tar") {
+			continue
+		}
+		if hdr.Typeflag == tar.TypeSymlink {
+			aliases[name] = path.Clean(path.Join(path.Dir(name), hdr.Linkname))
+			continue
+		}
+		changes, err := scanLayer(tr, match)
+		if err != nil {
+			return nil, fmt.Errorf("读取镜像层 %s 失败: %v", name, err)
+		}
+		perLayer[name] = changes
+	}
+
+	merged := make(ImageFiles)
+	for _, l := range layers {
+		name := path.Clean(l)
+		if target, ok := aliases[name]; ok {
+			name = target
+		}
+		changes := perLayer[name]
+		if changes == nil {
+			continue
+		}
+		for _, dir := range changes.opaqueDirs {
+			for k := range merged {
+				if strings.HasPrefix(k, dir+"/") {
+					delete(merged, k)
+				}
+			}
+		}
+		for _, p := range changes.deleted {
+			delete(merged, p)
+			for k := range merged {
+				if strings.HasPrefix(k, p+"/") {
+					delete(merged, k)
+				}
+			}
+		}
+		for k, v := range changes.files {
+			merged[k] = v
+		}
+	}
+	return merged, nil
+}
+
+func readSaveManifestLayers(archivePath string) ([]string, error) {
+	f, err := os.Open(archivePath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	tr := tar.NewReader(f)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("读取镜像归档失败: %v", err)
+		}
+		if path.Clean(hdr.Name) != "manifest.json" {
+			continue
+		}
+		var entries []saveManifestEntry
+		if err := json.NewDecoder(tr).Decode(&entries); err != nil {
+			return nil, fmt.Errorf("解析 manifest.json 失败: %v", err)
+		}
+		if len(entries) == 0 {
+			return nil, fmt.Errorf("manifest.json 为空")
+		}
+		return entries[0].Layers, nil
+	}
+	return nil, fmt.Errorf("镜像归档中未找到 manifest.json")
+}
+
+type layerChanges struct {
+	files      map[string][]byte
+	deleted    []string
+	opaqueDirs []string
+}
+
+// scanLayer 扫描单个层（支持未压缩或 gzip 压缩的 tar）
+func scanLayer(r io.Reader, match func(name string) bool) (*layerChanges, error) {
+	br := bufio.NewReader(r)
+	var src io.Reader = br
+	if magic, err := br.Peek(2); err == nil && magic[0] == 0x1f && magic[1] == 0x8b {
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, err
+		}
+		defer gz.Close()
+		src = gz
+	}
+
+	changes := &layerChanges{files: make(map[string][]byte)}
+	tr := tar.NewReader(src)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		name := strings.TrimPrefix(path.Clean("/"+hdr.Name), "/")
+		dir, base := path.Split(name)
+		dir = strings.TrimSuffix(dir, "/")
+
+		if base == ".wh..wh..opq" {
+			changes.opaqueDirs = append(changes.opaqueDirs, dir)
+			continue
+		}
+		if strings.HasPrefix(base, ".wh.") {
+			changes.deleted = append(changes.deleted, path.Join(dir, strings.TrimPrefix(base, ".wh.")))
+			continue
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		if match != nil && !match(name) {
+			continue
+		}
+		if hdr.Size > maxExtractFileSize {
+			continue
+		}
+		data, err := io.ReadAll(io.LimitReader(tr, maxExtractFileSize))
+		if err != nil {
+			return nil, err
+		}
+		changes.files[name] = data
+	}
+	return changes, nil
+}
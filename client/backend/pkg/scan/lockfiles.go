@@ -0,0 +1,371 @@
+package scan
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"path"
+	"sort"
+	"strings"
+)
+
+const (
+	PackageTypeNpm      = "npm"
+	PackageTypePyPI     = "pypi"
+	PackageTypeGem      = "gem"
+	PackageTypeCargo    = "cargo"
+	PackageTypeComposer = "composer"
+	PackageTypeGo       = "golang"
+)
+
+// lockfileParsers 按文件名识别的语言锁文件
+var lockfileParsers = map[string]func(data []byte) []Package{
+	"package-lock.json": parsePackageLock,
+	"yarn.lock":         parseYarnLock,
+	"requirements.txt":  parseRequirements,
+	"poetry.lock":       func(data []byte) []Package { return parseTOMLPackages(data, PackageTypePyPI) },
+	"Cargo.lock":        func(data []byte) []Package { return parseTOMLPackages(data, PackageTypeCargo) },
+	"Gemfile.lock":      parseGemfileLock,
+	"composer.lock":     parseComposerLock,
+	"go.mod":            parseGoMod,
+}
+
+// IsLockfile 判断路径是否为需要提取的语言锁文件或 Python 包元数据
+func IsLockfile(name string) bool {
+	dir, base := path.Split(name)
+	if _, ok := lockfileParsers[base]; ok {
+		// 依赖包自带的锁文件不代表实际安装内容
+		return !strings.Contains(name, "node_modules/") && !strings.Contains(name, "/vendor/")
+	}
+	return isPythonMetadata(dir, base)
+}
+
+// IsInventoryFile 生成 SBOM 时需要提取的全部文件
+func IsInventoryFile(name string) bool {
+	return IsOSPackageFile(name) || IsLockfile(name)
+}
+
+func isPythonMetadata(dir string, base string) bool {
+	dir = strings.TrimSuffix(dir, "/")
+	return (base == "METADATA" && strings.HasSuffix(dir, ".dist-info")) ||
+		(base == "PKG-INFO" && strings.HasSuffix(dir, ".egg-info"))
+}
+
+// ReadLanguagePackages 从提取出的文件中解析语言生态的依赖包，按 (类型, 名称, 版本) 去重
+func ReadLanguagePackages(files ImageFiles) []Package {
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	seen := make(map[string]struct{})
+	out := make([]Package, 0)
+	for _, name := range names {
+		dir, base := path.Split(name)
+		var list []Package
+		if parser, ok := lockfileParsers[base]; ok && IsLockfile(name) {
+			list = parser(files[name])
+		} else if isPythonMetadata(dir, base) {
+			list = parsePythonMetadata(files[name])
+		} else {
+			continue
+		}
+		for _, p := range list {
+			if p.Name == "" || p.Version == "" {
+				continue
+			}
+			key := p.Type + "|" + p.Name + "|" + p.Version
+			if _, ok := seen[key]; ok {
+				continue
+			}
+			seen[key] = struct{}{}
+			p.Path = "/" + name
+			out = append(out, p)
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		if out[i].Type != out[j].Type {
+			return out[i].Type < out[j].Type
+		}
+		if out[i].Name != out[j].Name {
+			return out[i].Name < out[j].Name
+		}
+		return out[i].Version < out[j].Version
+	})
+	return out
+}
+
+type npmLockDep struct {
+	Version      string                `json:"version"`
+	License      any                   `json:"license"`
+	Link         bool                  `json:"link"`
+	Dependencies map[string]npmLockDep `json:"dependencies"`
+}
+
+// parsePackageLock 支持 lockfileVersion 1（dependencies 嵌套）与 2/3（packages 平铺）
+func parsePackageLock(data []byte) []Package {
+	var lock struct {
+		Packages     map[string]npmLockDep `json:"packages"`
+		Dependencies map[string]npmLockDep `json:"dependencies"`
+	}
+	if err := json.Unmarshal(data, &lock); err != nil {
+		return nil
+	}
+	out := make([]Package, 0)
+	if len(lock.Packages) > 0 {
+		for key, dep := range lock.Packages {
+			i := strings.LastIndex(key, "node_modules/")
+			if i < 0 || dep.Link {
+				continue
+			}
+			out = append(out, Package{
+				Name:    key[i+len("node_modules/"):],
+				Version: dep.Version,
+				License: npmLicense(dep.License),
+				Type:    PackageTypeNpm,
+			})
+		}
+		return out
+	}
+	var walk func(deps map[string]npmLockDep)
+	walk = func(deps map[string]npmLockDep) {
+		for name, dep := range deps {
+			out = append(out, Package{Name: name, Version: dep.Version, Type: PackageTypeNpm})
+			walk(dep.Dependencies)
+		}
+	}
+	walk(lock.Dependencies)
+	return out
+}
+
+func npmLicense(v any) string {
+	switch l := v.(type) {
+	case string:
+		return l
+	case map[string]any:
+		if t, ok := l["type"].(string); ok {
+			return t
+		}
+	}
+	return ""
+}
+
+// parseYarnLock 支持 yarn v1 与 berry 格式：条目头为 "name@range, ...:"，下一级为 version
+func parseYarnLock(data []byte) []Package {
+	out := make([]Package, 0)
+	name := ""
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	for sc.Scan() {
+		line := sc.Text()
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		if !strings.HasPrefix(line, " ") {
+			name = ""
+			if !strings.HasSuffix(trimmed, ":") || strings.HasPrefix(trimmed, "__metadata") {
+				continue
+			}
+			spec := strings.TrimSuffix(trimmed, ":")
+			if i := strings.Index(spec, ","); i > 0 {
+				spec = spec[:i]
+			}
+			spec = strings.Trim(strings.TrimSpace(spec), `"`)
+			if i := strings.LastIndex(spec, "@"); i > 0 {
+				name = spec[:i]
+			}
+			continue
+		}
+		if name == "" || !strings.HasPrefix(trimmed, "version") {
+			continue
+		}
+		v := strings.TrimSpace(strings.TrimPrefix(trimmed, "version"))
+		v = strings.Trim(strings.TrimSpace(strings.TrimPrefix(v, ":")), `"`)
+		out = append(out, Package{Name: name, Version: v, Type: PackageTypeNpm})
+		name = ""
+	}
+	return out
+}
+
+// parseRequirements 只收录固定版本（name==version）的依赖
+func parseRequirements(data []byte) []Package {
+	out := make([]Package, 0)
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	for sc.Scan() {
+		line := sc.Text()
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+		if i := strings.Index(line, ";"); i >= 0 {
+			line = line[:i]
+		}
+		parts := strings.SplitN(line, "==", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		name := strings.TrimSpace(parts[0])
+		if i := strings.Index(name, "["); i > 0 {
+			name = name[:i]
+		}
+		version := strings.Fields(strings.TrimSpace(parts[1]))
+		if name == "" || len(version) == 0 {
+			continue
+		}
+		out = append(out, Package{Name: name, Version: version[0], Type: PackageTypePyPI})
+	}
+	return out
+}
+
+// parsePythonMetadata 解析 dist-info/METADATA 或 egg-info/PKG-INFO 的头部字段
+func parsePythonMetadata(data []byte) []Package {
+	p := Package{Type: PackageTypePyPI}
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	for sc.Scan() {
+		line := sc.Text()
+		if strings.TrimSpace(line) == "" {
+			break
+		}
+		k, v, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		v = strings.TrimSpace(v)
+		switch k {
+		case "Name":
+			p.Name = v
+		case "Version":
+			p.Version = v
+		case "License-Expression":
+			p.License = v
+		case "License":
+			if p.License == "" && v != "UNKNOWN" && !strings.Contains(v, "\n") {
+				p.License = v
+			}
+		}
+	}
+	return []Package{p}
+}
+
+// parseTOMLPackages 解析 poetry.lock / Cargo.lock 中的 [[package]] 段（只取 name 与 version）
+func parseTOMLPackages(data []byte, pkgType string) []Package {
+	out := make([]Package, 0)
+	var cur *Package
+	flush := func() {
+		if cur != nil {
+			out = append(out, *cur)
+		}
+		cur = nil
+	}
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if strings.HasPrefix(line, "[") {
+			flush()
+			if line == "[[package]]" {
+				cur = &Package{Type: pkgType}
+			}
+			continue
+		}
+		if cur == nil {
+			continue
+		}
+		k, v, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		v = strings.Trim(strings.TrimSpace(v), `"'`)
+		switch strings.TrimSpace(k) {
+		case "name":
+			cur.Name = v
+		case "version":
+			cur.Version = v
+		}
+	}
+	flush()
+	return out
+}
+
+// parseGemfileLock 解析 specs: 下四个空格缩进的 "name (version)" 行
+func parseGemfileLock(data []byte) []Package {
+	out := make([]Package, 0)
+	inSpecs := false
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	for sc.Scan() {
+		line := sc.Text()
+		if !strings.HasPrefix(line, " ") {
+			inSpecs = false
+			continue
+		}
+		if strings.TrimSpace(line) == "specs:" {
+			inSpecs = true
+			continue
+		}
+		if !inSpecs || !strings.HasPrefix(line, "    ") || strings.HasPrefix(line, "     ") {
+			continue
+		}
+		name, rest, ok := strings.Cut(strings.TrimSpace(line), " (")
+		if !ok {
+			continue
+		}
+		out = append(out, Package{Name: name, Version: strings.TrimSuffix(rest, ")"), Type: PackageTypeGem})
+	}
+	return out
+}
+
+func parseComposerLock(data []byte) []Package {
+	type composerPkg struct {
+		Name    string   `json:"name"`
+		Version string   `json:"version"`
+		License []string `json:"license"`
+	}
+	var lock struct {
+		Packages    []composerPkg `json:"packages"`
+		PackagesDev []composerPkg `json:"packages-dev"`
+	}
+	if err := json.Unmarshal(data, &lock); err != nil {
+		return nil
+	}
+	out := make([]Package, 0, len(lock.Packages)+len(lock.PackagesDev))
+	for _, p := range append(lock.Packages, lock.PackagesDev...) {
+		out = append(out, Package{
+			Name:    p.Name,
+			Version: p.Version,
+			License: strings.Join(p.License, " OR "),
+			Type:    PackageTypeComposer,
+		})
+	}
+	return out
+}
+
+// parseGoMod 解析 require 指令（单行与块形式）
+func parseGoMod(data []byte) []Package {
+	out := make([]Package, 0)
+	inBlock := false
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	for sc.Scan() {
+		line := sc.Text()
+		if i := strings.Index(line, "//"); i >= 0 {
+			line = line[:i]
+		}
+		line = strings.TrimSpace(line)
+		switch {
+		case line == "require (":
+			inBlock = true
+			continue
+		case inBlock && line == ")":
+			inBlock = false
+			continue
+		case strings.HasPrefix(line, "require "):
+			line = strings.TrimSpace(strings.TrimPrefix(line, "require "))
+		case !inBlock:
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			continue
+		}
+		out = append(out, Package{Name: fields[0], Version: fields[1], Type: PackageTypeGo})
+	}
+	return out
+}
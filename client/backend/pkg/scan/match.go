@@ -0,0 +1,201 @@
+package scan
+
+import (
+	"sort"
+	"strings"
+)
+
+// Finding 一个软件包命中的一条漏洞公告
+type Finding struct {
+	ID               string   `json:"id"`
+	CVE              string   `json:"cve,omitempty"`
+	Aliases          []string `json:"aliases,omitempty"`
+	Package          string   `json:"package"`
+	PackageType      string   `json:"packageType"`
+	InstalledVersion string   `json:"installedVersion"`
+	FixedVersion     string   `json:"fixedVersion,omitempty"`
+	Severity         string   `json:"severity"`
+	Score            float64  `json:"score,omitempty"`
+	Summary          string   `json:"summary,omitempty"`
+}
+
+// Report 单个镜像的扫描结果
+type Report struct {
+	OS        OSInfo         `json:"os"`
+	Ecosystem string         `json:"ecosystem"`
+	Packages  int            `json:"packages"`
+	Findings  []Finding      `json:"findings"`
+	Counts    map[string]int `json:"counts"`
+	Notes     []string       `json:"notes,omitempty"`
+}
+
+// HasCritical 是否存在严重等级漏洞
+func (r Report) HasCritical() bool {
+	return r.Counts[SeverityCritical] > 0
+}
+
+// LookupNames 返回用于查询漏洞库的包名集合（二进制包名与源码包名）
+func LookupNames(pkgs []Package) []string {
+	seen := make(map[string]struct{}, len(pkgs)*2)
+	out := make([]string, 0, len(pkgs)*2)
+	for _, p := range pkgs {
+		for _, n := range []string{p.Name, p.Source} {
+			n = strings.TrimSpace(n)
+			if n == "" {
+				continue
+			}
+			if _, ok := seen[n]; ok {
+				continue
+			}
+			seen[n] = struct{}{}
+			out = append(out, n)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+// EcosystemMatches 判断公告生态是否适用于镜像发行版（"Debian" 适用于所有 Debian 版本，"Ubuntu:22.04:LTS" 适用于 Ubuntu:22.04）
+func EcosystemMatches(advisoryEco string, osEco string) bool {
+	if osEco == "" || advisoryEco == "" {
+		return false
+	}
+	if advisoryEco == osEco || strings.HasPrefix(advisoryEco, osEco+":") {
+		return true
+	}
+	base := osEco
+	if i := strings.Index(osEco, ":"); i > 0 {
+		base = osEco[:i]
+	}
+	return advisoryEco == base
+}
+
+// Match 将软件包与公告逐一比对，生成扫描报告
+func Match(info OSInfo, pkgs []Package, advisories []Advisory) Report {
+	report := Report{
+		OS:        info,
+		Ecosystem: info.Ecosystem(),
+		Packages:  len(pkgs),
+		Findings:  make([]Finding, 0),
+		Counts:    make(map[string]int, len(Severities)),
+	}
+	for _, s := range Severities {
+		report.Counts[s] = 0
+	}
+
+	byName := make(map[string][]Advisory)
+	for _, a := range advisories {
+		if !EcosystemMatches(a.Ecosystem, report.Ecosystem) {
+			continue
+		}
+		byName[a.Package] = append(byName[a.Package], a)
+	}
+
+	seen := make(map[string]struct{})
+	for _, p := range pkgs {
+		if !IsOSPackageType(p.Type) {
+			continue
+		}
+		candidates := byName[p.Name]
+		if p.Source != "" && p.Source != p.Name {
+			candidates = append(candidates, byName[p.Source]...)
+		}
+		for _, a := range candidates {
+			affected, fixed := IsAffected(p.Type, p.Version, a)
+			if !affected {
+				continue
+			}
+			key := a.ID + "|" + p.Name
+			if _, ok := seen[key]; ok {
+				continue
+			}
+			seen[key] = struct{}{}
+
+			sev := a.Severity
+			if sev == "" {
+				sev = SeverityUnknown
+			}
+			report.Findings = append(report.Findings, Finding{
+				ID:               a.ID,
+				CVE:              cveOf(a),
+				Aliases:          a.Aliases,
+				Package:          p.Name,
+				PackageType:      p.Type,
+				InstalledVersion: p.Version,
+				FixedVersion:     fixed,
+				Severity:         sev,
+				Score:            a.Score,
+				Summary:          a.Summary,
+			})
+			report.Counts[sev]++
+		}
+	}
+
+	rank := make(map[string]int, len(Severities))
+	for i, s := range Severities {
+		rank[s] = i
+	}
+	sort.SliceStable(report.Findings, func(i, j int) bool {
+		a, b := report.Findings[i], report.Findings[j]
+		if rank[a.Severity] != rank[b.Severity] {
+			return rank[a.Severity] < rank[b.Severity]
+		}
+		if a.Package != b.Package {
+			return a.Package < b.Package
+		}
+		return a.ID < b.ID
+	})
+	return report
+}
+
+// IsAffected 判断版本是否落在公告的受影响范围内，并返回修复版本（若有）
+func IsAffected(pkgType string, version string, a Advisory) (bool, string) {
+	fixed := ""
+	for _, e := range a.Events {
+		if e.Fixed != "" {
+			fixed = e.Fixed
+			break
+		}
+	}
+	for _, v := range a.Versions {
+		if v == version {
+			return true, fixed
+		}
+	}
+
+	active := false
+	for _, e := range a.Events {
+		switch {
+		case e.Introduced != "":
+			active = e.Introduced == "0" || CompareVersions(pkgType, version, e.Introduced) >= 0
+		case e.Fixed != "":
+			if active && CompareVersions(pkgType, version, e.Fixed) < 0 {
+				return true, e.Fixed
+			}
+			active = false
+		case e.Limit != "":
+			if active && CompareVersions(pkgType, version, e.Limit) < 0 {
+				return true, fixed
+			}
+			active = false
+		case e.LastAffected != "":
+			if active && CompareVersions(pkgType, version, e.LastAffected) <= 0 {
+				return true, fixed
+			}
+			active = false
+		}
+	}
+	return active, fixed
+}
+
+func cveOf(a Advisory) string {
+	if strings.HasPrefix(a.ID, "CVE-") {
+		return a.ID
+	}
+	for _, al := range a.Aliases {
+		if strings.HasPrefix(al, "CVE-") {
+			return al
+		}
+	}
+	return ""
+}
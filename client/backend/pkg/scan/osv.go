@@ -0,0 +1,348 @@
+package scan
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"path"
+	"strconv"
+	"strings"
+)
+
+const (
+	SeverityCritical = "CRITICAL"
+	SeverityHigh     = "HIGH"
+	SeverityMedium   = "MEDIUM"
+	SeverityLow      = "LOW"
+	SeverityUnknown  = "UNKNOWN"
+)
+
+// Severities 按严重程度从高到低排列
+var Severities = []string{SeverityCritical, SeverityHigh, SeverityMedium, SeverityLow, SeverityUnknown}
+
+// RangeEvent OSV 范围事件（introduced/fixed/last_affected/limit 四选一）
+type RangeEvent struct {
+	Introduced   string `json:"introduced,omitempty"`
+	Fixed        string `json:"fixed,omitempty"`
+	LastAffected string `json:"last_affected,omitempty"`
+	Limit        string `json:"limit,omitempty"`
+}
+
+// Advisory 漏洞库中一条「公告 × 生态 × 包」记录，导入时从 OSV 条目展开
+type Advisory struct {
+	ID        string       `json:"id"`
+	Aliases   []string     `json:"aliases,omitempty"`
+	Ecosystem string       `json:"ecosystem"`
+	Package   string       `json:"package"`
+	Events    []RangeEvent `json:"events,omitempty"`
+	Versions  []string     `json:"versions,omitempty"`
+	Severity  string       `json:"severity"`
+	Score     float64      `json:"score,omitempty"`
+	Summary   string       `json:"summary,omitempty"`
+	Modified  string       `json:"modified,omitempty"`
+}
+
+type osvEntry struct {
+	ID               string          `json:"id"`
+	Summary          string          `json:"summary"`
+	Details          string          `json:"details"`
+	Aliases          []string        `json:"aliases"`
+	Modified         string          `json:"modified"`
+	Withdrawn        string          `json:"withdrawn"`
+	Severity         []osvSeverity   `json:"severity"`
+	Affected         []osvAffected   `json:"affected"`
+	DatabaseSpecific json.RawMessage `json:"database_specific"`
+}
+
+type osvSeverity struct {
+	Type  string `json:"type"`
+	Score string `json:"score"`
+}
+
+type osvAffected struct {
+	Package struct {
+		Ecosystem string `json:"ecosystem"`
+		Name      string `json:"name"`
+	} `json:"package"`
+	Severity          []osvSeverity   `json:"severity"`
+	Ranges            []osvRange      `json:"ranges"`
+	Versions          []string        `json:"versions"`
+	EcosystemSpecific json.RawMessage `json:"ecosystem_specific"`
+	DatabaseSpecific  json.RawMessage `json:"database_specific"`
+}
+
+type osvRange struct {
+	Type   string       `json:"type"`
+	Events []RangeEvent `json:"events"`
+}
+
+// ParseOSV 解析 OSV 数据：支持单条 JSON、JSON 数组、JSON Lines 以及 OSV 官方的 zip 全量包
+func ParseOSV(r io.Reader) ([]Advisory, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) >= 4 && bytes.Equal(data[:4], []byte("PK\x03\x04")) {
+		return parseOSVZip(data)
+	}
+	return parseOSVJSON(data)
+}
+
+func parseOSVZip(data []byte) ([]Advisory, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("解析 zip 失败: %v", err)
+	}
+	out := make([]Advisory, 0)
+	for _, f := range zr.File {
+		if f.FileInfo().IsDir() || !strings.EqualFold(path.Ext(f.Name), ".json") {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, err
+		}
+		content, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			return nil, err
+		}
+		list, err := parseOSVJSON(content)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", f.Name, err)
+		}
+		out = append(out, list...)
+	}
+	return out, nil
+}
+
+func parseOSVJSON(data []byte) ([]Advisory, error) {
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) == 0 {
+		return nil, nil
+	}
+
+	entries := make([]osvEntry, 0)
+	switch trimmed[0] {
+	case '[':
+		if err := json.Unmarshal(trimmed, &entries); err != nil {
+			return nil, fmt.Errorf("解析 OSV 数组失败: %v", err)
+		}
+	case '{':
+		var single osvEntry
+		if err := json.Unmarshal(trimmed, &single); err == nil {
+			entries = append(entries, single)
+			break
+		}
+		sc := bufio.NewScanner(bytes.NewReader(trimmed))
+		sc.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+		for sc.Scan() {
+			line := bytes.TrimSpace(sc.Bytes())
+			if len(line) == 0 {
+				continue
+			}
+			var e osvEntry
+			if err := json.Unmarshal(line, &e); err != nil {
+				return nil, fmt.Errorf("解析 OSV JSON Lines 失败: %v", err)
+			}
+			entries = append(entries, e)
+		}
+		if err := sc.Err(); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("无法识别的 OSV 数据格式")
+	}
+
+	out := make([]Advisory, 0, len(entries))
+	for _, e := range entries {
+		out = append(out, expandOSVEntry(e)...)
+	}
+	return out, nil
+}
+
+func expandOSVEntry(e osvEntry) []Advisory {
+	if strings.TrimSpace(e.ID) == "" || strings.TrimSpace(e.Withdrawn) != "" {
+		return nil
+	}
+	summary := strings.TrimSpace(e.Summary)
+	if summary == "" {
+		summary = strings.TrimSpace(e.Details)
+		if len(summary) > 300 {
+			summary = summary[:300]
+		}
+	}
+	baseSeverity, baseScore := severityFrom(e.Severity, e.DatabaseSpecific, nil)
+
+	out := make([]Advisory, 0, len(e.Affected))
+	for _, a := range e.Affected {
+		name := strings.TrimSpace(a.Package.Name)
+		eco := strings.TrimSpace(a.Package.Ecosystem)
+		if name == "" || eco == "" {
+			continue
+		}
+		sev, score := severityFrom(a.Severity, a.DatabaseSpecific, a.EcosystemSpecific)
+		if sev == SeverityUnknown {
+			sev, score = baseSeverity, baseScore
+		}
+
+		events := make([]RangeEvent, 0)
+		for _, r := range a.Ranges {
+			if r.Type == "GIT" {
+				continue
+			}
+			events = append(events, r.Events...)
+		}
+		if len(events) == 0 && len(a.Versions) == 0 {
+			continue
+		}
+		out = append(out, Advisory{
+			ID:        e.ID,
+			Aliases:   e.Aliases,
+			Ecosystem: eco,
+			Package:   name,
+			Events:    events,
+			Versions:  a.Versions,
+			Severity:  sev,
+			Score:     score,
+			Summary:   summary,
+			Modified:  e.Modified,
+		})
+	}
+	return out
+}
+
+// severityFrom 优先使用 CVSS v3 向量计算分数，其次使用数据库提供的文字等级
+func severityFrom(list []osvSeverity, specifics ...json.RawMessage) (string, float64) {
+	for _, s := range list {
+		if !strings.HasPrefix(s.Type, "CVSS_V3") {
+			continue
+		}
+		if score, ok := CVSS3BaseScore(s.Score); ok {
+			return SeverityFromScore(score), score
+		}
+	}
+	for _, raw := range specifics {
+		if len(raw) == 0 {
+			continue
+		}
+		var m map[string]any
+		if err := json.Unmarshal(raw, &m); err != nil {
+			continue
+		}
+		if v, ok := m["severity"].(string); ok {
+			if sev := normalizeSeverityText(v); sev != SeverityUnknown {
+				return sev, 0
+			}
+		}
+	}
+	return SeverityUnknown, 0
+}
+
+func normalizeSeverityText(v string) string {
+	switch strings.ToUpper(strings.TrimSpace(v)) {
+	case "CRITICAL":
+		return SeverityCritical
+	case "HIGH", "IMPORTANT":
+		return SeverityHigh
+	case "MEDIUM", "MODERATE":
+		return SeverityMedium
+	case "LOW", "NEGLIGIBLE":
+		return SeverityLow
+	}
+	return SeverityUnknown
+}
+
+// SeverityFromScore 按 CVSS v3 区间将分数映射为等级
+func SeverityFromScore(score float64) string {
+	switch {
+	case score >= 9.0:
+		return SeverityCritical
+	case score >= 7.0:
+		return SeverityHigh
+	case score >= 4.0:
+		return SeverityMedium
+	case score > 0:
+		return SeverityLow
+	}
+	return SeverityUnknown
+}
+
+// CVSS3BaseScore 根据 CVSS v3.x 向量计算基础分
+func CVSS3BaseScore(vector string) (float64, bool) {
+	metrics := make(map[string]string)
+	for _, part := range strings.Split(vector, "/") {
+		kv := strings.SplitN(part, ":", 2)
+		if len(kv) == 2 {
+			metrics[kv[0]] = kv[1]
+		}
+	}
+	weights := map[string]map[string]float64{
+		"AV": {"N": 0.85, "A": 0.62, "L": 0.55, "P": 0.2},
+		"AC": {"L": 0.77, "H": 0.44},
+		"UI": {"N": 0.85, "R": 0.62},
+		"C":  {"H": 0.56, "L": 0.22, "N": 0},
+		"I":  {"H": 0.56, "L": 0.22, "N": 0},
+		"A":  {"H": 0.56, "L": 0.22, "N": 0},
+	}
+	val := make(map[string]float64)
+	for k, m := range weights {
+		v, ok := m[metrics[k]]
+		if !ok {
+			return 0, false
+		}
+		val[k] = v
+	}
+	scope := metrics["S"]
+	if scope != "U" && scope != "C" {
+		return 0, false
+	}
+	changed := scope == "C"
+	var pr float64
+	switch metrics["PR"] {
+	case "N":
+		pr = 0.85
+	case "L":
+		pr = 0.62
+		if changed {
+			pr = 0.68
+		}
+	case "H":
+		pr = 0.27
+		if changed {
+			pr = 0.5
+		}
+	default:
+		return 0, false
+	}
+
+	iss := 1 - (1-val["C"])*(1-val["I"])*(1-val["A"])
+	var impact float64
+	if changed {
+		impact = 7.52*(iss-0.029) - 3.25*math.Pow(iss-0.02, 15)
+	} else {
+		impact = 6.42 * iss
+	}
+	if impact <= 0 {
+		return 0, true
+	}
+	exploitability := 8.22 * val["AV"] * val["AC"] * pr * val["UI"]
+	if changed {
+		return cvssRoundUp(math.Min(1.08*(impact+exploitability), 10)), true
+	}
+	return cvssRoundUp(math.Min(impact+exploitability, 10)), true
+}
+
+// cvssRoundUp CVSS v3.1 规范中的 Roundup：向上保留一位小数并规避浮点误差
+func cvssRoundUp(v float64) float64 {
+	i := int64(math.Round(v * 100000))
+	if i%10000 == 0 {
+		return float64(i) / 100000.0
+	}
+	f, _ := strconv.ParseFloat(strconv.FormatFloat(float64(i/10000+1)/10.0, 'f', 1, 64), 64)
+	return f
+}
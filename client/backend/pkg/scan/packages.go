@@ -0,0 +1,418 @@
+package scan
+
+import (
+	"bufio"
+	"bytes"
+	"database/sql"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+const (
+	PackageTypeDeb = "deb"
+	PackageTypeApk = "apk"
+	PackageTypeRpm = "rpm"
+)
+
+// Package 镜像内安装的软件包
+type Package struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+	Source  string `json:"source,omitempty"`
+	Arch    string `json:"arch,omitempty"`
+	License string `json:"license,omitempty"`
+	Type    string `json:"type"`
+	// Path 语言包所在的锁文件/元数据路径，系统包为空
+	Path string `json:"path,omitempty"`
+}
+
+// OSInfo 来自 /etc/os-release 的发行版信息
+type OSInfo struct {
+	ID         string `json:"id"`
+	VersionID  string `json:"versionId"`
+	PrettyName string `json:"prettyName,omitempty"`
+}
+
+var (
+	dpkgStatusPath    = "var/lib/dpkg/status"
+	dpkgStatusDirPath = "var/lib/dpkg/status.d/"
+	apkInstalledPath  = "lib/apk/db/installed"
+	rpmSQLitePaths    = []string{"var/lib/rpm/rpmdb.sqlite", "usr/lib/sysimage/rpm/rpmdb.sqlite"}
+	rpmLegacyPaths    = []string{"var/lib/rpm/Packages", "var/lib/rpm/Packages.db"}
+	osReleasePaths    = []string{"etc/os-release", "usr/lib/os-release"}
+)
+
+// IsOSPackageFile 判断路径是否为需要提取的系统包数据库或发行版信息文件
+func IsOSPackageFile(name string) bool {
+	switch name {
+	case dpkgStatusPath, apkInstalledPath:
+		return true
+	}
+	if strings.HasPrefix(name, dpkgStatusDirPath) {
+		return true
+	}
+	for _, list := range [][]string{rpmSQLitePaths, rpmLegacyPaths, osReleasePaths} {
+		for _, p := range list {
+			if name == p {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// IsOSPackageType 是否为系统包（漏洞匹配仅针对系统包）
+func IsOSPackageType(t string) bool {
+	return t == PackageTypeDeb || t == PackageTypeApk || t == PackageTypeRpm
+}
+
+// Ecosystem 返回 OSV 漏洞库中对应的生态名称（例如 Debian:12、Alpine:v3.19）
+func (o OSInfo) Ecosystem() string {
+	id := strings.ToLower(strings.TrimSpace(o.ID))
+	ver := strings.TrimSpace(o.VersionID)
+	major := ver
+	if i := strings.Index(ver, "."); i > 0 {
+		major = ver[:i]
+	}
+	switch id {
+	case "debian":
+		if major == "" {
+			return "Debian"
+		}
+		return "Debian:" + major
+	case "ubuntu":
+		return "Ubuntu:" + ver
+	case "alpine":
+		parts := strings.Split(ver, ".")
+		if len(parts) >= 2 {
+			return "Alpine:v" + parts[0] + "." + parts[1]
+		}
+		return "Alpine"
+	case "rocky":
+		return "Rocky Linux:" + major
+	case "almalinux":
+		return "AlmaLinux:" + major
+	}
+	return ""
+}
+
+// ReadOSPackages 从提取出的文件中解析发行版信息与系统包列表
+func ReadOSPackages(files ImageFiles) (OSInfo, []Package, []string) {
+	var info OSInfo
+	notes := make([]string, 0)
+	for _, p := range osReleasePaths {
+		if data, ok := files[p]; ok {
+			info = parseOSRelease(data)
+			break
+		}
+	}
+
+	pkgs := make([]Package, 0)
+	if data, ok := files[dpkgStatusPath]; ok {
+		pkgs = append(pkgs, parseDpkgStatus(data)...)
+	}
+	statusDir := make([]string, 0)
+	for name := range files {
+		if strings.HasPrefix(name, dpkgStatusDirPath) && !strings.HasSuffix(name, ".md5sums") {
+			statusDir = append(statusDir, name)
+		}
+	}
+	sort.Strings(statusDir)
+	for _, name := range statusDir {
+		pkgs = append(pkgs, parseDpkgStatus(files[name])...)
+	}
+	if data, ok := files[apkInstalledPath]; ok {
+		pkgs = append(pkgs, parseApkInstalled(data)...)
+	}
+
+	rpmFound := false
+	for _, p := range rpmSQLitePaths {
+		data, ok := files[p]
+		if !ok {
+			continue
+		}
+		rpmFound = true
+		list, err := parseRPMSQLite(data)
+		if err != nil {
+			notes = append(notes, fmt.Sprintf("解析 rpm 数据库失败: %v", err))
+			continue
+		}
+		pkgs = append(pkgs, list...)
+	}
+	if !rpmFound {
+		for _, p := range rpmLegacyPaths {
+			if _, ok := files[p]; ok {
+				notes = append(notes, "暂不支持 BerkeleyDB/NDB 格式的 rpm 数据库: /"+p)
+				break
+			}
+		}
+	}
+
+	sort.Slice(pkgs, func(i, j int) bool {
+		if pkgs[i].Name == pkgs[j].Name {
+			return pkgs[i].Version < pkgs[j].Version
+		}
+		return pkgs[i].Name < pkgs[j].Name
+	})
+	return info, pkgs, notes
+}
+
+func parseOSRelease(data []byte) OSInfo {
+	var info OSInfo
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		parts := strings.SplitN(line, "=", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		val := strings.Trim(strings.TrimSpace(parts[1]), `"'`)
+		switch strings.TrimSpace(parts[0]) {
+		case "ID":
+			info.ID = val
+		case "VERSION_ID":
+			info.VersionID = val
+		case "PRETTY_NAME":
+			info.PrettyName = val
+		}
+	}
+	return info
+}
+
+// splitParagraphs 按空行拆分 dpkg/apk 数据库的段落，返回每段 key→value（续行合并）
+func splitParagraphs(data []byte, sep string) []map[string]string {
+	out := make([]map[string]string, 0)
+	cur := make(map[string]string)
+	lastKey := ""
+	flush := func() {
+		if len(cur) > 0 {
+			out = append(out, cur)
+		}
+		cur = make(map[string]string)
+		lastKey = ""
+	}
+
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	sc.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+	for sc.Scan() {
+		line := sc.Text()
+		if strings.TrimSpace(line) == "" {
+			flush()
+			continue
+		}
+		if (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) && lastKey != "" {
+			continue
+		}
+		i := strings.Index(line, sep)
+		if i <= 0 {
+			continue
+		}
+		lastKey = line[:i]
+		cur[lastKey] = strings.TrimSpace(line[i+len(sep):])
+	}
+	flush()
+	return out
+}
+
+func parseDpkgStatus(data []byte) []Package {
+	pkgs := make([]Package, 0)
+	for _, p := range splitParagraphs(data, ":") {
+		name := p["Package"]
+		version := p["Version"]
+		if name == "" || version == "" {
+			continue
+		}
+		// status.d 下的 distroless 数据库没有 Status 字段
+		if st, ok := p["Status"]; ok && !strings.HasSuffix(st, " installed") {
+			continue
+		}
+		source := p["Source"]
+		if i := strings.Index(source, " ("); i > 0 {
+			source = source[:i]
+		}
+		pkgs = append(pkgs, Package{
+			Name:    name,
+			Version: version,
+			Source:  strings.TrimSpace(source),
+			Arch:    p["Architecture"],
+			Type:    PackageTypeDeb,
+		})
+	}
+	return pkgs
+}
+
+func parseApkInstalled(data []byte) []Package {
+	pkgs := make([]Package, 0)
+	for _, p := range splitParagraphs(data, ":") {
+		name := p["P"]
+		version := p["V"]
+		if name == "" || version == "" {
+			continue
+		}
+		pkgs = append(pkgs, Package{
+			Name:    name,
+			Version: version,
+			Source:  p["o"],
+			Arch:    p["A"],
+			License: p["L"],
+			Type:    PackageTypeApk,
+		})
+	}
+	return pkgs
+}
+
+// rpm header 中关心的 tag 与数据类型
+const (
+	rpmTagName      = 1000
+	rpmTagVersion   = 1001
+	rpmTagRelease   = 1002
+	rpmTagEpoch     = 1003
+	rpmTagLicense   = 1014
+	rpmTagArch      = 1022
+	rpmTagSourceRPM = 1044
+
+	rpmTypeInt32       = 4
+	rpmTypeString      = 6
+	rpmTypeStringArray = 8
+	rpmTypeI18NString  = 9
+)
+
+// parseRPMSQLite 读取 rpmdb.sqlite 的 Packages 表并解析其中的 header blob
+func parseRPMSQLite(data []byte) ([]Package, error) {
+	tmp, err := os.CreateTemp("", "tradis-rpmdb-*.sqlite")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return nil, err
+	}
+	tmp.Close()
+
+	db, err := sql.Open("sqlite3", "file:"+tmp.Name()+"?mode=ro&immutable=1")
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	rows, err := db.Query(`SELECT blob FROM Packages`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	pkgs := make([]Package, 0)
+	for rows.Next() {
+		var blob []byte
+		if err := rows.Scan(&blob); err != nil {
+			return nil, err
+		}
+		pkg, err := parseRPMHeader(blob)
+		if err != nil || pkg.Name == "" || pkg.Name == "gpg-pubkey" {
+			continue
+		}
+		pkgs = append(pkgs, pkg)
+	}
+	return pkgs, rows.Err()
+}
+
+func parseRPMHeader(blob []byte) (Package, error) {
+	var pkg Package
+	if len(blob) < 8 {
+		return pkg, fmt.Errorf("header 过短")
+	}
+	il := int(binary.BigEndian.Uint32(blob[0:4]))
+	dl := int(binary.BigEndian.Uint32(blob[4:8]))
+	indexEnd := 8 + il*16
+	if il <= 0 || dl < 0 || indexEnd+dl > len(blob) {
+		return pkg, fmt.Errorf("header 长度非法")
+	}
+	store := blob[indexEnd : indexEnd+dl]
+
+	readString := func(off int) string {
+		if off < 0 || off >= len(store) {
+			return ""
+		}
+		end := bytes.IndexByte(store[off:], 0)
+		if end < 0 {
+			return string(store[off:])
+		}
+		return string(store[off : off+end])
+	}
+
+	epoch := ""
+	release := ""
+	version := ""
+	for i := 0; i < il; i++ {
+		entry := blob[8+i*16 : 8+(i+1)*16]
+		tag := int32(binary.BigEndian.Uint32(entry[0:4]))
+		typ := binary.BigEndian.Uint32(entry[4:8])
+		off := int(int32(binary.BigEndian.Uint32(entry[8:12])))
+
+		isString := typ == rpmTypeString || typ == rpmTypeStringArray || typ == rpmTypeI18NString
+		switch tag {
+		case rpmTagName:
+			if isString {
+				pkg.Name = readString(off)
+			}
+		case rpmTagVersion:
+			if isString {
+				version = readString(off)
+			}
+		case rpmTagRelease:
+			if isString {
+				release = readString(off)
+			}
+		case rpmTagArch:
+			if isString {
+				pkg.Arch = readString(off)
+			}
+		case rpmTagLicense:
+			if isString {
+				pkg.License = readString(off)
+			}
+		case rpmTagSourceRPM:
+			if isString {
+				pkg.Source = sourceNameFromSRPM(readString(off))
+			}
+		case rpmTagEpoch:
+			if typ == rpmTypeInt32 && off >= 0 && off+4 <= len(store) {
+				epoch = strconv.FormatUint(uint64(binary.BigEndian.Uint32(store[off:off+4])), 10)
+			}
+		}
+	}
+
+	pkg.Version = version
+	if release != "" {
+		pkg.Version += "-" + release
+	}
+	if epoch != "" && epoch != "0" {
+		pkg.Version = epoch + ":" + pkg.Version
+	}
+	pkg.Type = PackageTypeRpm
+	return pkg, nil
+}
+
+// sourceNameFromSRPM 从 "openssl-3.0.7-24.el9.src.rpm" 中提取源码包名 "openssl"
+func sourceNameFromSRPM(srpm string) string {
+	s := strings.TrimSuffix(srpm, ".src.rpm")
+	s = strings.TrimSuffix(s, ".nosrc.rpm")
+	for i := 0; i < 2; i++ {
+		idx := strings.LastIndex(s, "-")
+		if idx <= 0 {
+			return s
+		}
+		s = s[:idx]
+	}
+	return s
+}
@@ -0,0 +1,249 @@
+package scan
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"os"
+	"testing"
+)
+
+type fixtureFile struct {
+	name string
+	body string
+}
+
+func buildTar(t *testing.T, files []fixtureFile) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, f := range files {
+		if err := tw.WriteHeader(&tar.Header{Name: f.name, Mode: 0644, Size: int64(len(f.body)), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(f.body)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func gzipBytes(t *testing.T, data []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	gw := gzip.NewWriter(&buf)
+	if _, err := gw.Write(data); err != nil {
+		t.Fatal(err)
+	}
+	if err := gw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+const fixtureDpkgStatus = `Package: openssl
+Status: install ok installed
+Architecture: amd64
+Version: 3.0.11-1~deb12u1
+
+Package: zlib1g
+Status: install ok installed
+Architecture: amd64
+Source: zlib
+Version: 1:1.2.13.dfsg-1
+
+Package: curl
+Status: install ok installed
+Architecture: amd64
+Version: 7.88.1-10
+Description: command line tool
+ multi-line description
+
+Package: removed-pkg
+Status: deinstall ok config-files
+Version: 1.0
+`
+
+// buildFixtureImage 构造一个两层的 docker save 归档：第二层（gzip）删除 status.d 中的文件
+func buildFixtureImage(t *testing.T) []byte {
+	t.Helper()
+	layer1 := buildTar(t, []fixtureFile{
+		{"etc/os-release", "PRETTY_NAME=\"Debian GNU/Linux 12 (bookworm)\"\nID=debian\nVERSION_ID=\"12\"\n"},
+		{"var/lib/dpkg/status", fixtureDpkgStatus},
+		{"var/lib/dpkg/status.d/legacy", "Package: legacy\nVersion: 0.1\n"},
+		{"usr/bin/curl", "binary"},
+	})
+	layer2 := gzipBytes(t, buildTar(t, []fixtureFile{
+		{"var/lib/dpkg/status.d/.wh.legacy", ""},
+	}))
+	manifest, _ := json.Marshal([]saveManifestEntry{{
+		Config:   "config.json",
+		RepoTags: []string{"fixture:latest"},
+		Layers:   []string{"aaa/layer.tar", "bbb/layer.tar"},
+	}})
+	return buildTar(t, []fixtureFile{
+		{"aaa/layer.tar", string(layer1)},
+		{"bbb/layer.tar", string(layer2)},
+		{"config.json", "{}"},
+		{"manifest.json", string(manifest)},
+	})
+}
+
+func loadFixtureAdvisories(t *testing.T) []Advisory {
+	t.Helper()
+	f, err := os.Open("testdata/advisories.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	list, err := ParseOSV(f)
+	if err != nil {
+		t.Fatalf("ParseOSV: %v", err)
+	}
+	return list
+}
+
+func TestExtractAndReadPackages(t *testing.T) {
+	files, err := ExtractImageFiles(bytes.NewReader(buildFixtureImage(t)), IsOSPackageFile)
+	if err != nil {
+		t.Fatalf("ExtractImageFiles: %v", err)
+	}
+	if _, ok := files["usr/bin/curl"]; ok {
+		t.Fatalf("unmatched file should not be extracted")
+	}
+	if _, ok := files["var/lib/dpkg/status.d/legacy"]; ok {
+		t.Fatalf("whiteout in upper layer should remove file")
+	}
+
+	info, pkgs, _ := ReadOSPackages(files)
+	if info.Ecosystem() != "Debian:12" {
+		t.Fatalf("ecosystem = %q", info.Ecosystem())
+	}
+	if len(pkgs) != 3 {
+		t.Fatalf("packages = %+v", pkgs)
+	}
+	for _, p := range pkgs {
+		if p.Name == "zlib1g" && p.Source != "zlib" {
+			t.Fatalf("zlib1g source = %q", p.Source)
+		}
+	}
+}
+
+func TestParseOSVAndMatch(t *testing.T) {
+	advisories := loadFixtureAdvisories(t)
+	if len(advisories) != 4 {
+		t.Fatalf("advisories = %d, withdrawn entry should be skipped", len(advisories))
+	}
+
+	files, err := ExtractImageFiles(bytes.NewReader(buildFixtureImage(t)), IsOSPackageFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	info, pkgs, _ := ReadOSPackages(files)
+	report := Match(info, pkgs, advisories)
+
+	if len(report.Findings) != 2 {
+		t.Fatalf("findings = %+v", report.Findings)
+	}
+	first := report.Findings[0]
+	if first.ID != "DSA-5000-1" || first.CVE != "CVE-2023-0001" || first.Severity != SeverityCritical || first.FixedVersion != "3.0.11-1~deb12u2" {
+		t.Fatalf("unexpected first finding: %+v", first)
+	}
+	if report.Findings[1].Package != "curl" || report.Findings[1].Severity != SeverityMedium {
+		t.Fatalf("unexpected second finding: %+v", report.Findings[1])
+	}
+	if !report.HasCritical() || report.Counts[SeverityMedium] != 1 {
+		t.Fatalf("counts = %+v", report.Counts)
+	}
+}
+
+func TestCompareVersions(t *testing.T) {
+	cases := []struct {
+		typ  string
+		a, b string
+		want int
+	}{
+		{PackageTypeDeb, "1.0~rc1", "1.0", -1},
+		{PackageTypeDeb, "1:1.0", "2.0", 1},
+		{PackageTypeDeb, "3.0.11-1~deb12u1", "3.0.11-1~deb12u2", -1},
+		{PackageTypeDeb, "7.88.1-10+deb12u5", "7.88.1-10", 1},
+		{PackageTypeRpm, "1.0-1.el9", "1.0-1.el9", 0},
+		{PackageTypeRpm, "1.10-1", "1.9-1", 1},
+		{PackageTypeRpm, "1.0~beta", "1.0", -1},
+		{PackageTypeRpm, "1.0^git1", "1.0", 1},
+		{PackageTypeApk, "3.1.4-r5", "3.1.4-r10", -1},
+		{PackageTypeApk, "1.2_rc1-r0", "1.2-r0", -1},
+		{PackageTypeApk, "1.2_p1-r0", "1.2-r0", 1},
+	}
+	for _, tc := range cases {
+		if got := CompareVersions(tc.typ, tc.a, tc.b); got != tc.want {
+			t.Errorf("CompareVersions(%s, %q, %q) = %d, want %d", tc.typ, tc.a, tc.b, got, tc.want)
+		}
+	}
+}
+
+func TestCVSS3BaseScore(t *testing.T) {
+	cases := map[string]float64{
+		"CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:H/I:H/A:H": 9.8,
+		"CVSS:3.1/AV:N/AC:L/PR:N/UI:R/S:C/C:L/I:L/A:N": 6.1,
+		"CVSS:3.1/AV:L/AC:L/PR:L/UI:N/S:U/C:H/I:N/A:N": 5.5,
+		"CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:N/I:N/A:N": 0,
+	}
+	for vec, want := range cases {
+		got, ok := CVSS3BaseScore(vec)
+		if !ok || got != want {
+			t.Errorf("CVSS3BaseScore(%s) = %v, %v; want %v", vec, got, ok, want)
+		}
+	}
+	if _, ok := CVSS3BaseScore("CVSS:3.1/AV:X"); ok {
+		t.Errorf("invalid vector should fail")
+	}
+}
+
+func TestReadLanguagePackages(t *testing.T) {
+	files := ImageFiles{
+		"app/package-lock.json":                []byte(`{"lockfileVersion":3,"packages":{"":{"name":"app"},"node_modules/@types/node":{"version":"20.1.0","license":"MIT"},"node_modules/a/node_modules/b":{"version":"1.0.0"}}}`),
+		"app/node_modules/x/package-lock.json": []byte(`{"packages":{"node_modules/ignored":{"version":"1.0.0"}}}`),
+		"srv/yarn.lock":                        []byte("# yarn lockfile v1\n\n\"lodash@^4.17.0\", lodash@^4.17.21:\n  version \"4.17.21\"\n  resolved \"x\"\n"),
+		"usr/lib/python3/site-packages/requests-2.31.0.dist-info/METADATA": []byte("Metadata-Version: 2.1\nName: requests\nVersion: 2.31.0\nLicense: Apache 2.0\n\nbody\nVersion: 9\n"),
+		"srv/Gemfile.lock": []byte("GEM\n  remote: https://rubygems.org/\n  specs:\n    rake (13.0.6)\n    rack (3.0.8)\n      base64\n\nPLATFORMS\n  ruby\n"),
+		"srv/Cargo.lock":   []byte("version = 3\n\n[[package]]\nname = \"serde\"\nversion = \"1.0.190\"\ndependencies = [\n \"serde_derive\",\n]\n"),
+		"srv/go.mod":       []byte("module example.com/app\n\nrequire golang.org/x/text v0.14.0\n\nrequire (\n\tgithub.com/gin-gonic/gin v1.9.1 // indirect\n)\n"),
+	}
+	for name := range files {
+		if !IsInventoryFile(name) && name != "app/node_modules/x/package-lock.json" {
+			t.Fatalf("%s should be an inventory file", name)
+		}
+	}
+
+	got := make(map[string]Package)
+	for _, p := range ReadLanguagePackages(files) {
+		got[p.Type+":"+p.Name] = p
+	}
+	want := map[string]string{
+		"npm:@types/node":                 "20.1.0",
+		"npm:b":                           "1.0.0",
+		"npm:lodash":                      "4.17.21",
+		"pypi:requests":                   "2.31.0",
+		"gem:rake":                        "13.0.6",
+		"gem:rack":                        "3.0.8",
+		"cargo:serde":                     "1.0.190",
+		"golang:golang.org/x/text":        "v0.14.0",
+		"golang:github.com/gin-gonic/gin": "v1.9.1",
+	}
+	if len(got) != len(want) {
+		t.Fatalf("packages = %+v", got)
+	}
+	for k, v := range want {
+		if got[k].Version != v {
+			t.Errorf("%s version = %q, want %q", k, got[k].Version, v)
+		}
+	}
+	if got["npm:@types/node"].Path != "/app/package-lock.json" || got["npm:@types/node"].License != "MIT" {
+		t.Errorf("unexpected npm package: %+v", got["npm:@types/node"])
+	}
+}
@@ -0,0 +1,274 @@
+package scan
+
+import (
+	"strconv"
+	"strings"
+)
+
+// CompareVersions 按软件包类型比较版本号，返回 -1/0/1
+func CompareVersions(pkgType string, a string, b string) int {
+	switch pkgType {
+	case PackageTypeDeb:
+		return compareDebianVersions(a, b)
+	case PackageTypeRpm:
+		return compareRPMVersions(a, b)
+	case PackageTypeApk:
+		return compareAlpineVersions(a, b)
+	}
+	return compareRPMSegments(a, b)
+}
+
+func sign(v int) int {
+	switch {
+	case v < 0:
+		return -1
+	case v > 0:
+		return 1
+	}
+	return 0
+}
+
+// splitEpoch 拆分 "epoch:rest"，没有 epoch 时返回 0
+func splitEpoch(v string) (int, string) {
+	if i := strings.Index(v, ":"); i > 0 {
+		if n, err := strconv.Atoi(v[:i]); err == nil {
+			return n, v[i+1:]
+		}
+	}
+	return 0, v
+}
+
+// compareDebianVersions 实现 dpkg 的版本比较规则（epoch:upstream-revision，~ 排在最前）
+func compareDebianVersions(a string, b string) int {
+	ea, ra := splitEpoch(strings.TrimSpace(a))
+	eb, rb := splitEpoch(strings.TrimSpace(b))
+	if ea != eb {
+		return sign(ea - eb)
+	}
+	ua, va := ra, ""
+	if i := strings.LastIndex(ra, "-"); i >= 0 {
+		ua, va = ra[:i], ra[i+1:]
+	}
+	ub, vb := rb, ""
+	if i := strings.LastIndex(rb, "-"); i >= 0 {
+		ub, vb = rb[:i], rb[i+1:]
+	}
+	if c := debianVerRevCmp(ua, ub); c != 0 {
+		return c
+	}
+	return debianVerRevCmp(va, vb)
+}
+
+func debianOrder(c byte) int {
+	switch {
+	case c >= '0' && c <= '9':
+		return 0
+	case (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z'):
+		return int(c)
+	case c == '~':
+		return -1
+	case c != 0:
+		return int(c) + 256
+	}
+	return 0
+}
+
+func isDigit(c byte) bool { return c >= '0' && c <= '9' }
+
+func debianVerRevCmp(a string, b string) int {
+	at := func(s string, i int) byte {
+		if i < len(s) {
+			return s[i]
+		}
+		return 0
+	}
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		firstDiff := 0
+		for (i < len(a) && !isDigit(a[i])) || (j < len(b) && !isDigit(b[j])) {
+			ac := debianOrder(at(a, i))
+			bc := debianOrder(at(b, j))
+			if ac != bc {
+				return sign(ac - bc)
+			}
+			i++
+			j++
+		}
+		for at(a, i) == '0' {
+			i++
+		}
+		for at(b, j) == '0' {
+			j++
+		}
+		for isDigit(at(a, i)) && isDigit(at(b, j)) {
+			if firstDiff == 0 {
+				firstDiff = int(a[i]) - int(b[j])
+			}
+			i++
+			j++
+		}
+		if isDigit(at(a, i)) {
+			return 1
+		}
+		if isDigit(at(b, j)) {
+			return -1
+		}
+		if firstDiff != 0 {
+			return sign(firstDiff)
+		}
+	}
+	return 0
+}
+
+// compareRPMVersions 比较 [epoch:]version-release
+func compareRPMVersions(a string, b string) int {
+	ea, ra := splitEpoch(strings.TrimSpace(a))
+	eb, rb := splitEpoch(strings.TrimSpace(b))
+	if ea != eb {
+		return sign(ea - eb)
+	}
+	va, rela := ra, ""
+	if i := strings.LastIndex(ra, "-"); i >= 0 {
+		va, rela = ra[:i], ra[i+1:]
+	}
+	vb, relb := rb, ""
+	if i := strings.LastIndex(rb, "-"); i >= 0 {
+		vb, relb = rb[:i], rb[i+1:]
+	}
+	if c := compareRPMSegments(va, vb); c != 0 {
+		return c
+	}
+	if rela == "" || relb == "" {
+		return 0
+	}
+	return compareRPMSegments(rela, relb)
+}
+
+func isAlpha(c byte) bool { return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') }
+
+// compareRPMSegments 实现 rpmvercmp：数字段按数值比较，字母段按字典序，~ 表示预发布，^ 表示后续快照
+func compareRPMSegments(a string, b string) int {
+	if a == b {
+		return 0
+	}
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		for i < len(a) && !isDigit(a[i]) && !isAlpha(a[i]) && a[i] != '~' && a[i] != '^' {
+			i++
+		}
+		for j < len(b) && !isDigit(b[j]) && !isAlpha(b[j]) && b[j] != '~' && b[j] != '^' {
+			j++
+		}
+
+		if (i < len(a) && a[i] == '~') || (j < len(b) && b[j] == '~') {
+			if i >= len(a) || a[i] != '~' {
+				return 1
+			}
+			if j >= len(b) || b[j] != '~' {
+				return -1
+			}
+			i++
+			j++
+			continue
+		}
+		if (i < len(a) && a[i] == '^') || (j < len(b) && b[j] == '^') {
+			if i >= len(a) {
+				return -1
+			}
+			if j >= len(b) {
+				return 1
+			}
+			if a[i] != '^' {
+				return 1
+			}
+			if b[j] != '^' {
+				return -1
+			}
+			i++
+			j++
+			continue
+		}
+		if i >= len(a) || j >= len(b) {
+			break
+		}
+
+		si, sj := i, j
+		isNum := isDigit(a[i])
+		if isNum {
+			for i < len(a) && isDigit(a[i]) {
+				i++
+			}
+			for j < len(b) && isDigit(b[j]) {
+				j++
+			}
+		} else {
+			for i < len(a) && isAlpha(a[i]) {
+				i++
+			}
+			for j < len(b) && isAlpha(b[j]) {
+				j++
+			}
+		}
+		if sj == j {
+			if isNum {
+				return 1
+			}
+			return -1
+		}
+
+		segA, segB := a[si:i], b[sj:j]
+		if isNum {
+			segA = strings.TrimLeft(segA, "0")
+			segB = strings.TrimLeft(segB, "0")
+			if len(segA) != len(segB) {
+				return sign(len(segA) - len(segB))
+			}
+		}
+		if c := strings.Compare(segA, segB); c != 0 {
+			return c
+		}
+	}
+	if i >= len(a) && j >= len(b) {
+		return 0
+	}
+	if i >= len(a) {
+		return -1
+	}
+	return 1
+}
+
+// compareAlpineVersions 将 apk 的预发布/补丁后缀映射到 rpmvercmp 语义后比较，-rN 按数值比较
+func compareAlpineVersions(a string, b string) int {
+	va, ra := splitAlpineRelease(a)
+	vb, rb := splitAlpineRelease(b)
+	if c := compareRPMSegments(normalizeAlpineSuffix(va), normalizeAlpineSuffix(vb)); c != 0 {
+		return c
+	}
+	return sign(ra - rb)
+}
+
+func splitAlpineRelease(v string) (string, int) {
+	v = strings.TrimSpace(v)
+	if i := strings.LastIndex(v, "-r"); i >= 0 {
+		if n, err := strconv.Atoi(v[i+2:]); err == nil {
+			return v[:i], n
+		}
+	}
+	return v, 0
+}
+
+var alpineSuffixReplacer = strings.NewReplacer(
+	"_alpha", "~alpha",
+	"_beta", "~beta",
+	"_pre", "~pre",
+	"_rc", "~rc",
+	"_cvs", "^cvs",
+	"_svn", "^svn",
+	"_git", "^git",
+	"_hg", "^hg",
+	"_p", "^p",
+)
+
+func normalizeAlpineSuffix(v string) string {
+	return alpineSuffixReplacer.Replace(v)
+}
@@ -0,0 +1,145 @@
+package system
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/client"
+)
+
+const (
+	// statsSampleInterval 后台采样间隔；statsSampleKeep 每个容器保留的样本数（约最近 5 分钟）
+	statsSampleInterval = 30 * time.Second
+	statsSampleKeep     = 10
+	// statsSampleExpire 容器长时间没有新样本（已停止）时丢弃其样本
+	statsSampleExpire = time.Hour
+)
+
+// statsSample 容器运行期间的一次资源使用采样，崩溃诊断时附带最近的样本
+type statsSample struct {
+	Time        time.Time `json:"time"`
+	CPUPercent  float64   `json:"cpuPercent"`
+	MemoryUsage int64     `json:"memoryUsage"`
+	MemoryLimit int64     `json:"memoryLimit"`
+	Pids        uint64    `json:"pids"`
+
+	cpuTotal    uint64
+	systemTotal uint64
+}
+
+// statsRing 按容器保存最近的资源采样
+type statsRing struct {
+	mu      sync.Mutex
+	samples map[string][]statsSample
+}
+
+func newStatsRing() *statsRing {
+	return &statsRing{samples: make(map[string][]statsSample)}
+}
+
+var containerStatsRing = newStatsRing()
+
+// record 记录一次采样；一次性采样不带 PreCPUStats，CPU 使用率按与上一样本的差值计算
+func (r *statsRing) record(id string, s types.StatsJSON, at time.Time) {
+	sample := statsSample{
+		Time:        at,
+		MemoryUsage: int64(s.MemoryStats.Usage),
+		MemoryLimit: int64(s.MemoryStats.Limit),
+		Pids:        s.PidsStats.Current,
+		cpuTotal:    s.CPUStats.CPUUsage.TotalUsage,
+		systemTotal: s.CPUStats.SystemUsage,
+	}
+	cpus := float64(s.CPUStats.OnlineCPUs)
+	if cpus == 0 {
+		cpus = float64(len(s.CPUStats.CPUUsage.PercpuUsage))
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	list := r.samples[id]
+	prevCPU, prevSystem := s.PreCPUStats.CPUUsage.TotalUsage, s.PreCPUStats.SystemUsage
+	if prevSystem == 0 && len(list) > 0 {
+		prev := list[len(list)-1]
+		prevCPU, prevSystem = prev.cpuTotal, prev.systemTotal
+	}
+	if sample.systemTotal > prevSystem && sample.cpuTotal >= prevCPU && cpus > 0 {
+		sample.CPUPercent = float64(sample.cpuTotal-prevCPU) / float64(sample.systemTotal-prevSystem) * cpus * 100
+	}
+	list = append(list, sample)
+	if len(list) > statsSampleKeep {
+		list = list[len(list)-statsSampleKeep:]
+	}
+	r.samples[id] = list
+}
+
+// recent 返回容器最近的样本（按时间顺序）
+func (r *statsRing) recent(id string) []statsSample {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]statsSample(nil), r.samples[id]...)
+}
+
+func (r *statsRing) forget(id string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.samples, id)
+}
+
+// prune 丢弃长时间没有新样本的容器
+func (r *statsRing) prune(now time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for id, list := range r.samples {
+		if len(list) == 0 || now.Sub(list[len(list)-1].Time) > statsSampleExpire {
+			delete(r.samples, id)
+		}
+	}
+}
+
+// RecordContainerStats 记录其它位置已获取的容器资源统计（如仪表盘轮询），补充后台采样
+func RecordContainerStats(id string, s types.StatsJSON) {
+	containerStatsRing.record(id, s, time.Now())
+}
+
+// StartContainerStatsSampler 定期对运行中的容器做一次性资源采样，供崩溃诊断使用
+func StartContainerStatsSampler() {
+	go func() {
+		ticker := time.NewTicker(statsSampleInterval)
+		defer ticker.Stop()
+		for range ticker.C {
+			sampleContainerStats()
+			containerStatsRing.prune(time.Now())
+		}
+	}()
+}
+
+func sampleContainerStats() {
+	cli, err := client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
+	if err != nil {
+		return
+	}
+	defer cli.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), statsSampleInterval)
+	defer cancel()
+	list, err := cli.ContainerList(ctx, types.ContainerListOptions{})
+	if err != nil {
+		log.Printf("容器资源采样失败: %v", err)
+		return
+	}
+	for _, ct := range list {
+		resp, err := cli.ContainerStatsOneShot(ctx, ct.ID)
+		if err != nil {
+			continue
+		}
+		var s types.StatsJSON
+		if json.NewDecoder(resp.Body).Decode(&s) == nil {
+			containerStatsRing.record(ct.ID, s, time.Now())
+		}
+		resp.Body.Close()
+	}
+}
@@ -0,0 +1,263 @@
+package system
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"dockerpanel/backend/pkg/database"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/events"
+	"github.com/docker/docker/client"
+	"github.com/docker/docker/pkg/stdcopy"
+	"github.com/docker/go-units"
+)
+
+const (
+	// crashLoopWindow 内非人为的退出次数达到 crashLoopThreshold 视为崩溃循环
+	crashLoopWindow    = 5 * time.Minute
+	crashLoopThreshold = 3
+	// crashReportCooldown 同一容器同类问题的最短上报间隔，避免一次事故产生多条通知
+	crashReportCooldown = 10 * time.Minute
+	// manualStopGrace kill/stop 之后的退出视为人为停止
+	manualStopGrace = 15 * time.Second
+	// oomDieGrace oom 事件与随后的 die 事件之间的最大间隔
+	oomDieGrace = 10 * time.Second
+
+	diagnosticLogTail = 100
+	diagnosticLogMax  = 64 * 1024
+)
+
+// crashIncident 检测到的一次异常
+type crashIncident struct {
+	ContainerID string
+	Kind        string // oom / crashloop
+	ExitCode    int
+	DieTimes    []time.Time
+}
+
+// crashTracker 关联同一容器的 oom / kill / die 事件
+type crashTracker struct {
+	mu         sync.Mutex
+	dies       map[string][]time.Time
+	kills      map[string]time.Time
+	ooms       map[string]time.Time
+	lastReport map[string]time.Time // key: containerID + "/" + kind
+}
+
+func newCrashTracker() *crashTracker {
+	return &crashTracker{
+		dies:       make(map[string][]time.Time),
+		kills:      make(map[string]time.Time),
+		ooms:       make(map[string]time.Time),
+		lastReport: make(map[string]time.Time),
+	}
+}
+
+var containerCrashTracker = newCrashTracker()
+
+// observe 处理一条容器事件，需要上报时返回 incident
+func (t *crashTracker) observe(action string, id string, attrs map[string]string, at time.Time) *crashIncident {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	switch action {
+	case "oom":
+		t.ooms[id] = at
+	case "kill", "stop":
+		t.kills[id] = at
+	case "destroy":
+		delete(t.dies, id)
+		delete(t.kills, id)
+		delete(t.ooms, id)
+		delete(t.lastReport, id+"/oom")
+		delete(t.lastReport, id+"/crashloop")
+	case "die":
+		exitCode, _ := strconv.Atoi(attrs["exitCode"])
+		oomAt, oom := t.ooms[id]
+		oom = oom && at.Sub(oomAt) <= oomDieGrace
+		delete(t.ooms, id)
+
+		// 人为停止（docker stop / kill / 面板操作）不计入崩溃，但 OOM 仍然上报
+		if killAt, ok := t.kills[id]; ok && at.Sub(killAt) <= manualStopGrace && !oom {
+			delete(t.kills, id)
+			return nil
+		}
+		delete(t.kills, id)
+
+		times := append(t.dies[id], at)
+		cutoff := at.Add(-crashLoopWindow)
+		kept := times[:0]
+		for _, ts := range times {
+			if ts.After(cutoff) {
+				kept = append(kept, ts)
+			}
+		}
+		t.dies[id] = kept
+
+		kind := ""
+		switch {
+		case oom:
+			kind = "oom"
+		case len(kept) >= crashLoopThreshold:
+			kind = "crashloop"
+		default:
+			return nil
+		}
+		key := id + "/" + kind
+		if last, ok := t.lastReport[key]; ok && at.Sub(last) < crashReportCooldown {
+			return nil
+		}
+		t.lastReport[key] = at
+		return &crashIncident{
+			ContainerID: id,
+			Kind:        kind,
+			ExitCode:    exitCode,
+			DieTimes:    append([]time.Time(nil), kept...),
+		}
+	}
+	return nil
+}
+
+// observeCrashEvent 处理实时事件（历史回放的事件不参与检测）
+func observeCrashEvent(event events.Message) {
+	if event.Type != "container" {
+		return
+	}
+	at := time.Unix(0, event.TimeNano)
+	if event.TimeNano == 0 {
+		at = time.Unix(event.Time, 0)
+	}
+	if event.Action == "destroy" {
+		containerStatsRing.forget(event.Actor.ID)
+	}
+	if inc := containerCrashTracker.observe(event.Action, event.Actor.ID, event.Actor.Attributes, at); inc != nil {
+		go captureCrashDiagnostic(*inc, event.Actor.Attributes["name"])
+	}
+}
+
+// captureCrashDiagnostic 采集退出码、内存限制、最近日志与资源使用，保存诊断记录并发送一条通知
+func captureCrashDiagnostic(inc crashIncident, name string) {
+	cli, err := client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
+	if err != nil {
+		log.Printf("采集容器诊断信息失败: %v", err)
+		return
+	}
+	defer cli.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	d := &database.ContainerDiagnostic{
+		ContainerID:   inc.ContainerID,
+		ContainerName: name,
+		Kind:          inc.Kind,
+		ExitCode:      inc.ExitCode,
+		DieCount:      len(inc.DieTimes),
+	}
+	details := map[string]any{"dieTimes": inc.DieTimes}
+
+	tty := false
+	if info, err := cli.ContainerInspect(ctx, inc.ContainerID); err == nil {
+		d.ContainerName = strings.TrimPrefix(info.Name, "/")
+		d.RestartCount = info.RestartCount
+		if info.Config != nil {
+			d.Image = info.Config.Image
+			tty = info.Config.Tty
+		}
+		if info.HostConfig != nil {
+			d.MemoryLimit = info.HostConfig.Memory
+			details["restartPolicy"] = info.HostConfig.RestartPolicy
+			details["memorySwap"] = info.HostConfig.MemorySwap
+			details["nanoCpus"] = info.HostConfig.NanoCPUs
+		}
+		if info.State != nil {
+			d.OOMKilled = info.State.OOMKilled
+			details["state"] = info.State.Status
+			details["error"] = info.State.Error
+			details["startedAt"] = info.State.StartedAt
+			details["finishedAt"] = info.State.FinishedAt
+		}
+	} else {
+		details["inspectError"] = err.Error()
+	}
+	if inc.Kind == "oom" {
+		d.OOMKilled = true
+	}
+
+	d.Logs = readDiagnosticLogs(ctx, cli, inc.ContainerID, tty)
+
+	// 容器退出后无法再采集资源使用，使用运行期间的后台采样
+	if samples := containerStatsRing.recent(inc.ContainerID); len(samples) > 0 {
+		last := samples[len(samples)-1]
+		d.MemoryUsage = last.MemoryUsage
+		for _, sm := range samples {
+			if sm.MemoryUsage > d.MemoryMaxUsage {
+				d.MemoryMaxUsage = sm.MemoryUsage
+			}
+		}
+		if d.MemoryLimit == 0 {
+			d.MemoryLimit = last.MemoryLimit
+		}
+		details["pids"] = last.Pids
+		details["recentStats"] = samples
+	}
+
+	if b, err := json.Marshal(details); err == nil {
+		d.DetailsJSON = string(b)
+	}
+	if err := database.SaveContainerDiagnostic(d); err != nil {
+		log.Printf("保存容器诊断信息失败: %v", err)
+	}
+
+	msg := crashNotificationMessage(d, crashLoopWindow)
+	_ = database.SaveNotification(&database.Notification{Type: "error", Message: msg})
+	LogSimpleEvent("error", msg)
+}
+
+// crashNotificationMessage 生成包含关键上下文的通知文本
+func crashNotificationMessage(d *database.ContainerDiagnostic, window time.Duration) string {
+	name := d.ContainerName
+	if name == "" && len(d.ContainerID) >= 12 {
+		name = d.ContainerID[:12]
+	}
+	limit := "未限制"
+	if d.MemoryLimit > 0 {
+		limit = units.BytesSize(float64(d.MemoryLimit))
+	}
+	if d.Kind == "oom" {
+		return fmt.Sprintf("容器 %s 因内存不足被终止（OOM，内存限制 %s，退出码 %d），已采集诊断信息 #%d", name, limit, d.ExitCode, d.ID)
+	}
+	return fmt.Sprintf("容器 %s 在 %s 内异常退出 %d 次（退出码 %d，累计重启 %d 次），疑似崩溃循环，已采集诊断信息 #%d",
+		name, window, d.DieCount, d.ExitCode, d.RestartCount, d.ID)
+}
+
+func readDiagnosticLogs(ctx context.Context, cli *client.Client, id string, tty bool) string {
+	rc, err := cli.ContainerLogs(ctx, id, types.ContainerLogsOptions{
+		ShowStdout: true,
+		ShowStderr: true,
+		Timestamps: true,
+		Tail:       strconv.Itoa(diagnosticLogTail),
+	})
+	if err != nil {
+		return "读取日志失败: " + err.Error()
+	}
+	defer rc.Close()
+
+	var buf bytes.Buffer
+	limited := io.LimitReader(rc, diagnosticLogMax)
+	if tty {
+		_, _ = io.Copy(&buf, limited)
+	} else {
+		_, _ = stdcopy.StdCopy(&buf, &buf, limited)
+	}
+	return buf.String()
+}
@@ -0,0 +1,105 @@
+package system
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"dockerpanel/backend/pkg/database"
+
+	"github.com/docker/docker/api/types"
+)
+
+func TestCrashTrackerCrashLoop(t *testing.T) {
+	tr := newCrashTracker()
+	base := time.Now()
+	die := func(offset time.Duration, code string) *crashIncident {
+		return tr.observe("die", "c1", map[string]string{"exitCode": code}, base.Add(offset))
+	}
+
+	if die(0, "1") != nil || die(time.Minute, "1") != nil {
+		t.Fatalf("two exits should not be reported")
+	}
+	inc := die(2*time.Minute, "2")
+	if inc == nil || inc.Kind != "crashloop" || inc.ExitCode != 2 || len(inc.DieTimes) != 3 {
+		t.Fatalf("incident = %+v", inc)
+	}
+	if die(3*time.Minute, "1") != nil {
+		t.Fatalf("same incident should be reported once within cooldown")
+	}
+	// 冷却期过后再次进入崩溃循环
+	if inc := die(13*time.Minute, "1"); inc != nil {
+		t.Fatalf("exits outside the window should not count: %+v", inc)
+	}
+	die(14*time.Minute, "1")
+	if inc := die(15*time.Minute, "1"); inc == nil || inc.Kind != "crashloop" {
+		t.Fatalf("expected a new crashloop incident after cooldown, got %+v", inc)
+	}
+}
+
+func TestCrashTrackerIgnoresManualStop(t *testing.T) {
+	tr := newCrashTracker()
+	base := time.Now()
+	for i := 0; i < 5; i++ {
+		at := base.Add(time.Duration(i) * time.Minute)
+		tr.observe("kill", "c1", nil, at)
+		if inc := tr.observe("die", "c1", map[string]string{"exitCode": "143"}, at.Add(2*time.Second)); inc != nil {
+			t.Fatalf("manual stop reported as crash: %+v", inc)
+		}
+	}
+}
+
+func TestCrashTrackerOOM(t *testing.T) {
+	tr := newCrashTracker()
+	now := time.Now()
+	tr.observe("oom", "c1", nil, now)
+	tr.observe("kill", "c1", nil, now)
+	inc := tr.observe("die", "c1", map[string]string{"exitCode": "137"}, now.Add(time.Second))
+	if inc == nil || inc.Kind != "oom" || inc.ExitCode != 137 {
+		t.Fatalf("incident = %+v", inc)
+	}
+
+	tr.observe("oom", "c2", nil, now)
+	if inc := tr.observe("die", "c2", nil, now.Add(time.Minute)); inc != nil {
+		t.Fatalf("stale oom should not be correlated: %+v", inc)
+	}
+}
+
+func TestCrashNotificationMessage(t *testing.T) {
+	msg := crashNotificationMessage(&database.ContainerDiagnostic{
+		ID: 7, ContainerName: "api", Kind: "oom", ExitCode: 137, MemoryLimit: 512 * 1024 * 1024,
+	}, crashLoopWindow)
+	if !strings.Contains(msg, "api") || !strings.Contains(msg, "512MiB") || !strings.Contains(msg, "#7") {
+		t.Fatalf("message = %s", msg)
+	}
+	msg = crashNotificationMessage(&database.ContainerDiagnostic{ContainerID: strings.Repeat("f", 64), Kind: "crashloop", DieCount: 4, ExitCode: 1}, crashLoopWindow)
+	if !strings.Contains(msg, "ffffffffffff") || !strings.Contains(msg, "4 次") {
+		t.Fatalf("message = %s", msg)
+	}
+}
+
+func TestStatsRingKeepsRecentSamples(t *testing.T) {
+	r := newStatsRing()
+	base := time.Now()
+	for i := 0; i < statsSampleKeep+3; i++ {
+		var s types.StatsJSON
+		s.MemoryStats.Usage = uint64(i+1) * 1024
+		s.CPUStats.OnlineCPUs = 2
+		s.CPUStats.CPUUsage.TotalUsage = uint64(i) * 500
+		s.CPUStats.SystemUsage = uint64(i) * 1000
+		r.record("c1", s, base.Add(time.Duration(i)*statsSampleInterval))
+	}
+	samples := r.recent("c1")
+	if len(samples) != statsSampleKeep || samples[len(samples)-1].MemoryUsage != int64(statsSampleKeep+3)*1024 {
+		t.Fatalf("samples = %+v", samples)
+	}
+	// 一次性采样没有 PreCPUStats，使用率按与上一样本的差值计算：500/1000 * 2 核
+	if got := samples[len(samples)-1].CPUPercent; got != 100 {
+		t.Fatalf("cpu percent = %v", got)
+	}
+
+	r.prune(base.Add(statsSampleExpire * 2))
+	if len(r.recent("c1")) != 0 {
+		t.Fatalf("stale samples should be pruned")
+	}
+}
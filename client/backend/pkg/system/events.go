@@ -141,6 +141,7 @@ func watchEvents() {
 		select {
 		case event := <-msgs:
 			processEvent(event)
+			observeCrashEvent(event)
 		case err := <-errs:
 			if err != nil {
 				fmt.Printf("Error reading docker events: %v\n", err)